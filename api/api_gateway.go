@@ -16,8 +16,12 @@ type SaoApi interface {
 	AuthNew(ctx context.Context, perms []auth.Permission) ([]byte, error)    //perm:admin
 
 	// MethodGroup: Order Job
+	// OrderStatus get the order of a data model committed through this gateway
 	OrderStatus(ctx context.Context, id string) (types.OrderInfo, error) //perm:read
-	OrderList(ctx context.Context) ([]types.OrderInfo, error)            //perm:read
+	// OrderList list the orders committed through this gateway which match the filter
+	OrderList(ctx context.Context, filter types.OrderFilter) ([]types.OrderInfo, error) //perm:read
+	// OrderAudits list the last availability audits of the orders committed through this gateway, only the under replicated ones if underReplicated
+	OrderAudits(ctx context.Context, underReplicated bool) ([]types.AuditInfo, error) //perm:read
 	// OrderFix(ctx context.Context, id string) error                       //perm:write
 
 	// MethodGroup: Shard Job
@@ -35,19 +39,76 @@ type SaoApi interface {
 	ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (apitypes.CreateResp, error) //perm:write
 	// ModelCreate create a normal data model
 	ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) //perm:write
-	// ModelLoad load an existing data model
+	// ModelCreateAsync create a normal data model, returns once the order is stored on chain without waiting for it to be readied
+	ModelCreateAsync(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) //perm:write
+	// CommitStatus get the progress of the order committing a data model
+	CommitStatus(ctx context.Context, dataId string) (apitypes.CommitStatusResp, error) //perm:read
+	// ModelEstimateFee estimate the fee charged to the owner and the gas of storing an order of size bytes for duration blocks
+	ModelEstimateFee(ctx context.Context, owner string, size uint64, duration uint64, replica int32) (apitypes.EstimateFeeResp, error) //perm:read
+	// ModelLoad load an existing data model, the sao:// links in its content are resolved up to req.ResolveDepth levels deep
 	ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) //perm:read
+	// ModelLoadMeta load the metadata of a data model, without fetching its content from the storage nodes
+	ModelLoadMeta(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) //perm:read
 	// ModelDelete delete an existing model
 	ModelDelete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (apitypes.DeleteResp, error) //perm:write
 	// ModelShowCommits list a data models' historical commits
 	ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (apitypes.ShowCommitsResp, error) //perm:read
-	// ModelUpdate update an existing data model
+	// ModelProvenance verify the JWS of each commit of a data model against the sid document version valid at the commit, the report is signed by the peer key of the gateway
+	ModelProvenance(ctx context.Context, req *types.MetadataProposal) (types.SignedProvenanceReport, error) //perm:read
+	// ModelExportCar packs the loaded models as a CARv2 file, each request loads a commit of a model
+	ModelExportCar(ctx context.Context, reqs []*types.MetadataProposal) (apitypes.ExportCarResp, error) //perm:read
+	// ModelSubscribe subscribe the order and chain update events of a data model, or of the data models of owner if dataId is empty, it requires a websocket connection
+	ModelSubscribe(ctx context.Context, dataId string, owner string) (<-chan types.ModelEvent, error) //perm:read
+	// ModelMerge three-way merge the json patch made on the commit baseCommitId of a data model with the commits made since, the merged content is returned to be committed by ModelUpdate on the latest commit
+	ModelMerge(ctx context.Context, req *types.MetadataProposal, baseCommitId string, patch []byte) (apitypes.MergeResp, error) //perm:read
+	// ModelUpdate update an existing data model by a json patch, or a file model by the binary delta committed as it is
 	ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error) //perm:write
 	// ModelRenewOrder renew a list of orders
 	ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (apitypes.RenewResp, error) //perm:write
 	// ModelUpdatePermission update an existing model's read/write permission
 	ModelUpdatePermission(ctx context.Context, req *types.PermissionProposal, isPublish bool) (apitypes.UpdatePermissionResp, error) //perm:write
-	ModelMigrate(ctx context.Context, dataIds []string) (apitypes.MigrateResp, error)                                                // perm:write
+	// ModelShareRevoke add a share link, an upload token or a delegation to the revocation list of the gateway by its nonce, signed by the issuer
+	ModelShareRevoke(ctx context.Context, req *types.ShareRevokeProposal) error //perm:write
+	// ModelPermissionHistory list the permission updates of a data model seen by the gateway, only for the owner
+	ModelPermissionHistory(ctx context.Context, req *types.MetadataProposal) (apitypes.PermissionHistoryResp, error) //perm:read
+	ModelMigrate(ctx context.Context, dataIds []string) (apitypes.MigrateResp, error)                                // perm:write
+
+	// MethodGroup: Session
+	// The Session method group contains methods for the session keys, which sign the proposals on behalf of a did.
+
+	// SessionRegister register the audience of the delegation as a session key of the issuer until it expires
+	SessionRegister(ctx context.Context, session *types.SignedDelegation) error //perm:write
+	// SessionRevoke revoke a session key by the nonce of its delegation, signed by the issuer
+	SessionRevoke(ctx context.Context, req *types.SessionRevokeProposal) error //perm:write
+	// SessionList list the unexpired session keys of the did
+	SessionList(ctx context.Context, issuer string) ([]types.SignedDelegation, error) //perm:read
+
+	// MethodGroup: Cache
+
+	// CacheStats get the model cache settings and the hit, miss and eviction counters of each account
+	CacheStats(ctx context.Context) (apitypes.CacheStatsResp, error) //perm:admin
+
+	// MethodGroup: Platform
+
+	// PlatformUsage get the models, bytes and throttled requests of the platforms sharing this gateway against their quotas
+	PlatformUsage(ctx context.Context) ([]apitypes.PlatformUsage, error) //perm:admin
+
+	// MethodGroup: Progress
+
+	// ProgressGet get the last progress event of a content uploaded to this gateway or distributed to the storage nodes
+	ProgressGet(ctx context.Context, cid string) (types.ProgressEvent, error) //perm:read
+	// ProgressSubscribe subscribe the progress events of a content, the channel is closed once all storage nodes store it, it requires a websocket connection
+	ProgressSubscribe(ctx context.Context, cid string) (<-chan types.ProgressEvent, error) //perm:read
+
+	// MethodGroup: Reputation
+
+	// ProviderReputation get the completion, load, audit and migration counters of the storage providers seen by this node
+	ProviderReputation(ctx context.Context) ([]apitypes.ProviderReputation, error) //perm:admin
+
+	// MethodGroup: Scheduler
+
+	// JobList get the periodic jobs of this node with their schedules, next run times and last results
+	JobList(ctx context.Context) ([]apitypes.JobInfo, error) //perm:admin
 
 	// MethodGroup: Common
 
@@ -57,10 +118,17 @@ type SaoApi interface {
 	GenerateToken(ctx context.Context, owner string) (apitypes.GenerateTokenResp, error) //perm:read
 	// GetHttpUrl
 	GetHttpUrl(ctx context.Context, dataId string) (apitypes.GetUrlResp, error) //perm:read
+	// GetShareUrl get the url of the http file server loading the data model of a share token
+	GetShareUrl(ctx context.Context, token string) (apitypes.GetUrlResp, error) //perm:read
+	// GetUploadUrl get the url of the http file server the file of a pre-signed upload token is put to
+	GetUploadUrl(ctx context.Context, token string) (apitypes.GetUrlResp, error) //perm:read
 	// GetIpfsUrl
 	GetIpfsUrl(ctx context.Context, cid string) (apitypes.GetUrlResp, error) //perm:read
 	// GetNodeAddress get current node's sao chain address
 	GetNodeAddress(ctx context.Context) (string, error) //perm:read
 	// GetNetPeers get current node's connected peer list
 	GetNetPeers(context.Context) ([]types.PeerInfo, error) //perm:read
+	// GetNodeStats get current node's chain height, earnings, disk usage, the streams per protocol version and the sid
+	// document cache
+	GetNodeStats(ctx context.Context) (apitypes.NodeStatsResp, error) //perm:read
 }
@@ -20,6 +20,10 @@ type SaoApiStruct struct {
 
 		AuthVerify func(p0 context.Context, p1 string) ([]auth.Permission, error) `perm:"none"`
 
+		CacheStats func(p0 context.Context) (apitypes.CacheStatsResp, error) `perm:"admin"`
+
+		CommitStatus func(p0 context.Context, p1 string) (apitypes.CommitStatusResp, error) `perm:"read"`
+
 		GenerateToken func(p0 context.Context, p1 string) (apitypes.GenerateTokenResp, error) `perm:"read"`
 
 		GetHttpUrl func(p0 context.Context, p1 string) (apitypes.GetUrlResp, error) `perm:"read"`
@@ -30,36 +34,78 @@ type SaoApiStruct struct {
 
 		GetNodeAddress func(p0 context.Context) (string, error) `perm:"read"`
 
+		GetNodeStats func(p0 context.Context) (apitypes.NodeStatsResp, error) `perm:"read"`
+
 		GetPeerInfo func(p0 context.Context) (apitypes.GetPeerInfoResp, error) `perm:"read"`
 
+		GetShareUrl func(p0 context.Context, p1 string) (apitypes.GetUrlResp, error) `perm:"read"`
+
+		GetUploadUrl func(p0 context.Context, p1 string) (apitypes.GetUrlResp, error) `perm:"read"`
+
+		JobList func(p0 context.Context) ([]apitypes.JobInfo, error) `perm:"admin"`
+
 		MigrateJobList func(p0 context.Context) ([]types.MigrateInfo, error) ``
 
 		ModelCreate func(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64, p4 []byte) (apitypes.CreateResp, error) `perm:"write"`
 
+		ModelCreateAsync func(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64, p4 []byte) (apitypes.CreateResp, error) `perm:"write"`
+
 		ModelCreateFile func(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64) (apitypes.CreateResp, error) `perm:"write"`
 
 		ModelDelete func(p0 context.Context, p1 *types.OrderTerminateProposal, p2 bool) (apitypes.DeleteResp, error) `perm:"write"`
 
+		ModelEstimateFee func(p0 context.Context, p1 string, p2 uint64, p3 uint64, p4 int32) (apitypes.EstimateFeeResp, error) `perm:"read"`
+
+		ModelExportCar func(p0 context.Context, p1 []*types.MetadataProposal) (apitypes.ExportCarResp, error) `perm:"read"`
+
 		ModelLoad func(p0 context.Context, p1 *types.MetadataProposal) (apitypes.LoadResp, error) `perm:"read"`
 
+		ModelLoadMeta func(p0 context.Context, p1 *types.MetadataProposal) (apitypes.LoadResp, error) `perm:"read"`
+
+		ModelMerge func(p0 context.Context, p1 *types.MetadataProposal, p2 string, p3 []byte) (apitypes.MergeResp, error) `perm:"read"`
+
 		ModelMigrate func(p0 context.Context, p1 []string) (apitypes.MigrateResp, error) `perm:"write"`
 
+		ModelPermissionHistory func(p0 context.Context, p1 *types.MetadataProposal) (apitypes.PermissionHistoryResp, error) `perm:"read"`
+
+		ModelProvenance func(p0 context.Context, p1 *types.MetadataProposal) (types.SignedProvenanceReport, error) `perm:"read"`
+
 		ModelRenewOrder func(p0 context.Context, p1 *types.OrderRenewProposal, p2 bool) (apitypes.RenewResp, error) `perm:"write"`
 
+		ModelShareRevoke func(p0 context.Context, p1 *types.ShareRevokeProposal) error `perm:"write"`
+
 		ModelShowCommits func(p0 context.Context, p1 *types.MetadataProposal) (apitypes.ShowCommitsResp, error) `perm:"read"`
 
+		ModelSubscribe func(p0 context.Context, p1 string, p2 string) (<-chan types.ModelEvent, error) `perm:"read"`
+
 		ModelUpdate func(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64, p4 []byte) (apitypes.UpdateResp, error) `perm:"write"`
 
 		ModelUpdatePermission func(p0 context.Context, p1 *types.PermissionProposal, p2 bool) (apitypes.UpdatePermissionResp, error) `perm:"write"`
 
+		OrderAudits func(p0 context.Context, p1 bool) ([]types.AuditInfo, error) `perm:"read"`
+
 		OrderFix func(p0 context.Context, p1 string) error `perm:"write"`
 
-		OrderList func(p0 context.Context) ([]types.OrderInfo, error) `perm:"read"`
+		OrderList func(p0 context.Context, p1 types.OrderFilter) ([]types.OrderInfo, error) `perm:"read"`
 
 		OrderStatus func(p0 context.Context, p1 string) (types.OrderInfo, error) `perm:"read"`
 
+		PlatformUsage func(p0 context.Context) ([]apitypes.PlatformUsage, error) `perm:"admin"`
+
+		ProgressGet func(p0 context.Context, p1 string) (types.ProgressEvent, error) `perm:"read"`
+
+		ProgressSubscribe func(p0 context.Context, p1 string) (<-chan types.ProgressEvent, error) `perm:"read"`
+
+		ProviderReputation func(p0 context.Context) ([]apitypes.ProviderReputation, error) `perm:"admin"`
+
 		ShardFix func(p0 context.Context, p1 uint64, p2 cid.Cid) error ``
 
+		SessionList func(p0 context.Context, p1 string) ([]types.SignedDelegation, error) `perm:"read"`
+
+		SessionRegister func(p0 context.Context, p1 *types.SignedDelegation) error `perm:"write"`
+
+		SessionRevoke func(p0 context.Context, p1 *types.SessionRevokeProposal) error `perm:"write"`
+
 		ShardList func(p0 context.Context) ([]types.ShardInfo, error) `perm:"read"`
 
 		ShardStatus func(p0 context.Context, p1 uint64, p2 cid.Cid) (types.ShardInfo, error) `perm:"read"`
@@ -91,6 +137,28 @@ func (s *SaoApiStub) AuthVerify(p0 context.Context, p1 string) ([]auth.Permissio
 	return *new([]auth.Permission), ErrNotSupported
 }
 
+func (s *SaoApiStruct) CacheStats(p0 context.Context) (apitypes.CacheStatsResp, error) {
+	if s.Internal.CacheStats == nil {
+		return *new(apitypes.CacheStatsResp), ErrNotSupported
+	}
+	return s.Internal.CacheStats(p0)
+}
+
+func (s *SaoApiStub) CacheStats(p0 context.Context) (apitypes.CacheStatsResp, error) {
+	return *new(apitypes.CacheStatsResp), ErrNotSupported
+}
+
+func (s *SaoApiStruct) CommitStatus(p0 context.Context, p1 string) (apitypes.CommitStatusResp, error) {
+	if s.Internal.CommitStatus == nil {
+		return *new(apitypes.CommitStatusResp), ErrNotSupported
+	}
+	return s.Internal.CommitStatus(p0, p1)
+}
+
+func (s *SaoApiStub) CommitStatus(p0 context.Context, p1 string) (apitypes.CommitStatusResp, error) {
+	return *new(apitypes.CommitStatusResp), ErrNotSupported
+}
+
 func (s *SaoApiStruct) GenerateToken(p0 context.Context, p1 string) (apitypes.GenerateTokenResp, error) {
 	if s.Internal.GenerateToken == nil {
 		return *new(apitypes.GenerateTokenResp), ErrNotSupported
@@ -146,6 +214,17 @@ func (s *SaoApiStub) GetNodeAddress(p0 context.Context) (string, error) {
 	return "", ErrNotSupported
 }
 
+func (s *SaoApiStruct) GetNodeStats(p0 context.Context) (apitypes.NodeStatsResp, error) {
+	if s.Internal.GetNodeStats == nil {
+		return *new(apitypes.NodeStatsResp), ErrNotSupported
+	}
+	return s.Internal.GetNodeStats(p0)
+}
+
+func (s *SaoApiStub) GetNodeStats(p0 context.Context) (apitypes.NodeStatsResp, error) {
+	return *new(apitypes.NodeStatsResp), ErrNotSupported
+}
+
 func (s *SaoApiStruct) GetPeerInfo(p0 context.Context) (apitypes.GetPeerInfoResp, error) {
 	if s.Internal.GetPeerInfo == nil {
 		return *new(apitypes.GetPeerInfoResp), ErrNotSupported
@@ -157,6 +236,39 @@ func (s *SaoApiStub) GetPeerInfo(p0 context.Context) (apitypes.GetPeerInfoResp,
 	return *new(apitypes.GetPeerInfoResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) GetShareUrl(p0 context.Context, p1 string) (apitypes.GetUrlResp, error) {
+	if s.Internal.GetShareUrl == nil {
+		return *new(apitypes.GetUrlResp), ErrNotSupported
+	}
+	return s.Internal.GetShareUrl(p0, p1)
+}
+
+func (s *SaoApiStub) GetShareUrl(p0 context.Context, p1 string) (apitypes.GetUrlResp, error) {
+	return *new(apitypes.GetUrlResp), ErrNotSupported
+}
+
+func (s *SaoApiStruct) GetUploadUrl(p0 context.Context, p1 string) (apitypes.GetUrlResp, error) {
+	if s.Internal.GetUploadUrl == nil {
+		return *new(apitypes.GetUrlResp), ErrNotSupported
+	}
+	return s.Internal.GetUploadUrl(p0, p1)
+}
+
+func (s *SaoApiStub) GetUploadUrl(p0 context.Context, p1 string) (apitypes.GetUrlResp, error) {
+	return *new(apitypes.GetUrlResp), ErrNotSupported
+}
+
+func (s *SaoApiStruct) JobList(p0 context.Context) ([]apitypes.JobInfo, error) {
+	if s.Internal.JobList == nil {
+		return *new([]apitypes.JobInfo), ErrNotSupported
+	}
+	return s.Internal.JobList(p0)
+}
+
+func (s *SaoApiStub) JobList(p0 context.Context) ([]apitypes.JobInfo, error) {
+	return *new([]apitypes.JobInfo), ErrNotSupported
+}
+
 func (s *SaoApiStruct) MigrateJobList(p0 context.Context) ([]types.MigrateInfo, error) {
 	if s.Internal.MigrateJobList == nil {
 		return *new([]types.MigrateInfo), ErrNotSupported
@@ -179,6 +291,17 @@ func (s *SaoApiStub) ModelCreate(p0 context.Context, p1 *types.MetadataProposal,
 	return *new(apitypes.CreateResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) ModelCreateAsync(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64, p4 []byte) (apitypes.CreateResp, error) {
+	if s.Internal.ModelCreateAsync == nil {
+		return *new(apitypes.CreateResp), ErrNotSupported
+	}
+	return s.Internal.ModelCreateAsync(p0, p1, p2, p3, p4)
+}
+
+func (s *SaoApiStub) ModelCreateAsync(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64, p4 []byte) (apitypes.CreateResp, error) {
+	return *new(apitypes.CreateResp), ErrNotSupported
+}
+
 func (s *SaoApiStruct) ModelCreateFile(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64) (apitypes.CreateResp, error) {
 	if s.Internal.ModelCreateFile == nil {
 		return *new(apitypes.CreateResp), ErrNotSupported
@@ -201,6 +324,28 @@ func (s *SaoApiStub) ModelDelete(p0 context.Context, p1 *types.OrderTerminatePro
 	return *new(apitypes.DeleteResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) ModelEstimateFee(p0 context.Context, p1 string, p2 uint64, p3 uint64, p4 int32) (apitypes.EstimateFeeResp, error) {
+	if s.Internal.ModelEstimateFee == nil {
+		return *new(apitypes.EstimateFeeResp), ErrNotSupported
+	}
+	return s.Internal.ModelEstimateFee(p0, p1, p2, p3, p4)
+}
+
+func (s *SaoApiStub) ModelEstimateFee(p0 context.Context, p1 string, p2 uint64, p3 uint64, p4 int32) (apitypes.EstimateFeeResp, error) {
+	return *new(apitypes.EstimateFeeResp), ErrNotSupported
+}
+
+func (s *SaoApiStruct) ModelExportCar(p0 context.Context, p1 []*types.MetadataProposal) (apitypes.ExportCarResp, error) {
+	if s.Internal.ModelExportCar == nil {
+		return *new(apitypes.ExportCarResp), ErrNotSupported
+	}
+	return s.Internal.ModelExportCar(p0, p1)
+}
+
+func (s *SaoApiStub) ModelExportCar(p0 context.Context, p1 []*types.MetadataProposal) (apitypes.ExportCarResp, error) {
+	return *new(apitypes.ExportCarResp), ErrNotSupported
+}
+
 func (s *SaoApiStruct) ModelLoad(p0 context.Context, p1 *types.MetadataProposal) (apitypes.LoadResp, error) {
 	if s.Internal.ModelLoad == nil {
 		return *new(apitypes.LoadResp), ErrNotSupported
@@ -212,6 +357,28 @@ func (s *SaoApiStub) ModelLoad(p0 context.Context, p1 *types.MetadataProposal) (
 	return *new(apitypes.LoadResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) ModelLoadMeta(p0 context.Context, p1 *types.MetadataProposal) (apitypes.LoadResp, error) {
+	if s.Internal.ModelLoadMeta == nil {
+		return *new(apitypes.LoadResp), ErrNotSupported
+	}
+	return s.Internal.ModelLoadMeta(p0, p1)
+}
+
+func (s *SaoApiStub) ModelLoadMeta(p0 context.Context, p1 *types.MetadataProposal) (apitypes.LoadResp, error) {
+	return *new(apitypes.LoadResp), ErrNotSupported
+}
+
+func (s *SaoApiStruct) ModelMerge(p0 context.Context, p1 *types.MetadataProposal, p2 string, p3 []byte) (apitypes.MergeResp, error) {
+	if s.Internal.ModelMerge == nil {
+		return *new(apitypes.MergeResp), ErrNotSupported
+	}
+	return s.Internal.ModelMerge(p0, p1, p2, p3)
+}
+
+func (s *SaoApiStub) ModelMerge(p0 context.Context, p1 *types.MetadataProposal, p2 string, p3 []byte) (apitypes.MergeResp, error) {
+	return *new(apitypes.MergeResp), ErrNotSupported
+}
+
 func (s *SaoApiStruct) ModelMigrate(p0 context.Context, p1 []string) (apitypes.MigrateResp, error) {
 	if s.Internal.ModelMigrate == nil {
 		return *new(apitypes.MigrateResp), ErrNotSupported
@@ -223,6 +390,28 @@ func (s *SaoApiStub) ModelMigrate(p0 context.Context, p1 []string) (apitypes.Mig
 	return *new(apitypes.MigrateResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) ModelPermissionHistory(p0 context.Context, p1 *types.MetadataProposal) (apitypes.PermissionHistoryResp, error) {
+	if s.Internal.ModelPermissionHistory == nil {
+		return *new(apitypes.PermissionHistoryResp), ErrNotSupported
+	}
+	return s.Internal.ModelPermissionHistory(p0, p1)
+}
+
+func (s *SaoApiStub) ModelPermissionHistory(p0 context.Context, p1 *types.MetadataProposal) (apitypes.PermissionHistoryResp, error) {
+	return *new(apitypes.PermissionHistoryResp), ErrNotSupported
+}
+
+func (s *SaoApiStruct) ModelProvenance(p0 context.Context, p1 *types.MetadataProposal) (types.SignedProvenanceReport, error) {
+	if s.Internal.ModelProvenance == nil {
+		return *new(types.SignedProvenanceReport), ErrNotSupported
+	}
+	return s.Internal.ModelProvenance(p0, p1)
+}
+
+func (s *SaoApiStub) ModelProvenance(p0 context.Context, p1 *types.MetadataProposal) (types.SignedProvenanceReport, error) {
+	return *new(types.SignedProvenanceReport), ErrNotSupported
+}
+
 func (s *SaoApiStruct) ModelRenewOrder(p0 context.Context, p1 *types.OrderRenewProposal, p2 bool) (apitypes.RenewResp, error) {
 	if s.Internal.ModelRenewOrder == nil {
 		return *new(apitypes.RenewResp), ErrNotSupported
@@ -234,6 +423,17 @@ func (s *SaoApiStub) ModelRenewOrder(p0 context.Context, p1 *types.OrderRenewPro
 	return *new(apitypes.RenewResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) ModelShareRevoke(p0 context.Context, p1 *types.ShareRevokeProposal) error {
+	if s.Internal.ModelShareRevoke == nil {
+		return ErrNotSupported
+	}
+	return s.Internal.ModelShareRevoke(p0, p1)
+}
+
+func (s *SaoApiStub) ModelShareRevoke(p0 context.Context, p1 *types.ShareRevokeProposal) error {
+	return ErrNotSupported
+}
+
 func (s *SaoApiStruct) ModelShowCommits(p0 context.Context, p1 *types.MetadataProposal) (apitypes.ShowCommitsResp, error) {
 	if s.Internal.ModelShowCommits == nil {
 		return *new(apitypes.ShowCommitsResp), ErrNotSupported
@@ -245,6 +445,17 @@ func (s *SaoApiStub) ModelShowCommits(p0 context.Context, p1 *types.MetadataProp
 	return *new(apitypes.ShowCommitsResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) ModelSubscribe(p0 context.Context, p1 string, p2 string) (<-chan types.ModelEvent, error) {
+	if s.Internal.ModelSubscribe == nil {
+		return nil, ErrNotSupported
+	}
+	return s.Internal.ModelSubscribe(p0, p1, p2)
+}
+
+func (s *SaoApiStub) ModelSubscribe(p0 context.Context, p1 string, p2 string) (<-chan types.ModelEvent, error) {
+	return nil, ErrNotSupported
+}
+
 func (s *SaoApiStruct) ModelUpdate(p0 context.Context, p1 *types.MetadataProposal, p2 *types.OrderStoreProposal, p3 uint64, p4 []byte) (apitypes.UpdateResp, error) {
 	if s.Internal.ModelUpdate == nil {
 		return *new(apitypes.UpdateResp), ErrNotSupported
@@ -267,6 +478,17 @@ func (s *SaoApiStub) ModelUpdatePermission(p0 context.Context, p1 *types.Permiss
 	return *new(apitypes.UpdatePermissionResp), ErrNotSupported
 }
 
+func (s *SaoApiStruct) OrderAudits(p0 context.Context, p1 bool) ([]types.AuditInfo, error) {
+	if s.Internal.OrderAudits == nil {
+		return *new([]types.AuditInfo), ErrNotSupported
+	}
+	return s.Internal.OrderAudits(p0, p1)
+}
+
+func (s *SaoApiStub) OrderAudits(p0 context.Context, p1 bool) ([]types.AuditInfo, error) {
+	return *new([]types.AuditInfo), ErrNotSupported
+}
+
 func (s *SaoApiStruct) OrderFix(p0 context.Context, p1 string) error {
 	if s.Internal.OrderFix == nil {
 		return ErrNotSupported
@@ -278,14 +500,14 @@ func (s *SaoApiStub) OrderFix(p0 context.Context, p1 string) error {
 	return ErrNotSupported
 }
 
-func (s *SaoApiStruct) OrderList(p0 context.Context) ([]types.OrderInfo, error) {
+func (s *SaoApiStruct) OrderList(p0 context.Context, p1 types.OrderFilter) ([]types.OrderInfo, error) {
 	if s.Internal.OrderList == nil {
 		return *new([]types.OrderInfo), ErrNotSupported
 	}
-	return s.Internal.OrderList(p0)
+	return s.Internal.OrderList(p0, p1)
 }
 
-func (s *SaoApiStub) OrderList(p0 context.Context) ([]types.OrderInfo, error) {
+func (s *SaoApiStub) OrderList(p0 context.Context, p1 types.OrderFilter) ([]types.OrderInfo, error) {
 	return *new([]types.OrderInfo), ErrNotSupported
 }
 
@@ -300,6 +522,50 @@ func (s *SaoApiStub) OrderStatus(p0 context.Context, p1 string) (types.OrderInfo
 	return *new(types.OrderInfo), ErrNotSupported
 }
 
+func (s *SaoApiStruct) PlatformUsage(p0 context.Context) ([]apitypes.PlatformUsage, error) {
+	if s.Internal.PlatformUsage == nil {
+		return *new([]apitypes.PlatformUsage), ErrNotSupported
+	}
+	return s.Internal.PlatformUsage(p0)
+}
+
+func (s *SaoApiStub) PlatformUsage(p0 context.Context) ([]apitypes.PlatformUsage, error) {
+	return *new([]apitypes.PlatformUsage), ErrNotSupported
+}
+
+func (s *SaoApiStruct) ProgressGet(p0 context.Context, p1 string) (types.ProgressEvent, error) {
+	if s.Internal.ProgressGet == nil {
+		return *new(types.ProgressEvent), ErrNotSupported
+	}
+	return s.Internal.ProgressGet(p0, p1)
+}
+
+func (s *SaoApiStub) ProgressGet(p0 context.Context, p1 string) (types.ProgressEvent, error) {
+	return *new(types.ProgressEvent), ErrNotSupported
+}
+
+func (s *SaoApiStruct) ProgressSubscribe(p0 context.Context, p1 string) (<-chan types.ProgressEvent, error) {
+	if s.Internal.ProgressSubscribe == nil {
+		return nil, ErrNotSupported
+	}
+	return s.Internal.ProgressSubscribe(p0, p1)
+}
+
+func (s *SaoApiStub) ProgressSubscribe(p0 context.Context, p1 string) (<-chan types.ProgressEvent, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *SaoApiStruct) ProviderReputation(p0 context.Context) ([]apitypes.ProviderReputation, error) {
+	if s.Internal.ProviderReputation == nil {
+		return *new([]apitypes.ProviderReputation), ErrNotSupported
+	}
+	return s.Internal.ProviderReputation(p0)
+}
+
+func (s *SaoApiStub) ProviderReputation(p0 context.Context) ([]apitypes.ProviderReputation, error) {
+	return *new([]apitypes.ProviderReputation), ErrNotSupported
+}
+
 func (s *SaoApiStruct) ShardFix(p0 context.Context, p1 uint64, p2 cid.Cid) error {
 	if s.Internal.ShardFix == nil {
 		return ErrNotSupported
@@ -311,6 +577,39 @@ func (s *SaoApiStub) ShardFix(p0 context.Context, p1 uint64, p2 cid.Cid) error {
 	return ErrNotSupported
 }
 
+func (s *SaoApiStruct) SessionList(p0 context.Context, p1 string) ([]types.SignedDelegation, error) {
+	if s.Internal.SessionList == nil {
+		return *new([]types.SignedDelegation), ErrNotSupported
+	}
+	return s.Internal.SessionList(p0, p1)
+}
+
+func (s *SaoApiStub) SessionList(p0 context.Context, p1 string) ([]types.SignedDelegation, error) {
+	return *new([]types.SignedDelegation), ErrNotSupported
+}
+
+func (s *SaoApiStruct) SessionRegister(p0 context.Context, p1 *types.SignedDelegation) error {
+	if s.Internal.SessionRegister == nil {
+		return ErrNotSupported
+	}
+	return s.Internal.SessionRegister(p0, p1)
+}
+
+func (s *SaoApiStub) SessionRegister(p0 context.Context, p1 *types.SignedDelegation) error {
+	return ErrNotSupported
+}
+
+func (s *SaoApiStruct) SessionRevoke(p0 context.Context, p1 *types.SessionRevokeProposal) error {
+	if s.Internal.SessionRevoke == nil {
+		return ErrNotSupported
+	}
+	return s.Internal.SessionRevoke(p0, p1)
+}
+
+func (s *SaoApiStub) SessionRevoke(p0 context.Context, p1 *types.SessionRevokeProposal) error {
+	return ErrNotSupported
+}
+
 func (s *SaoApiStruct) ShardList(p0 context.Context) ([]types.ShardInfo, error) {
 	if s.Internal.ShardList == nil {
 		return *new([]types.ShardInfo), ErrNotSupported
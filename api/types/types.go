@@ -1,5 +1,7 @@
 package apitypes
 
+import "time"
+
 type LoadReq struct {
 	User      string
 	KeyWord   string
@@ -16,6 +18,27 @@ type CreateResp struct {
 	Cid    string
 }
 
+type CommitStatusResp struct {
+	DataId          string
+	OrderId         uint64
+	State           string
+	Shards          int
+	CompletedShards int
+	// the order is complete, terminated or expired
+	Done    bool
+	LastErr string
+}
+
+type EstimateFeeResp struct {
+	// amount charged to the owner
+	Amount string
+	// gas of the store tx, paid by the gateway unless the client publishes the order
+	Gas uint64
+	// balance of the owner, if the owner is given
+	Balance    string
+	Sufficient bool
+}
+
 type UpdateResp struct {
 	DataId   string
 	CommitId string
@@ -24,6 +47,18 @@ type UpdateResp struct {
 	Cid      string
 }
 
+type MergeResp struct {
+	DataId string
+	Alias  string
+	// the latest commit of the model, which the merged content is committed on
+	CommitId string
+	Content  string
+	// the json patch from the latest commit to the merged content
+	Patch string
+	// json pointers of the values changed on both sides, the values of the patch are kept
+	Conflicts []string
+}
+
 type LoadResp struct {
 	DataId   string
 	Alias    string
@@ -42,6 +77,23 @@ type UpdatePermissionResp struct {
 	DataId string
 }
 
+type PermissionHistoryResp struct {
+	DataId  string
+	Changes []PermissionChange
+}
+
+// PermissionChange is a permission update of a data model, the readers and the writers are the ones after the
+// update. Granted and Revoked are the dids whose access was raised or lowered by the update.
+type PermissionChange struct {
+	Height        int64
+	TxHash        string
+	Time          int64
+	ReadonlyDids  []string
+	ReadwriteDids []string
+	Granted       []string
+	Revoked       []string
+}
+
 type RenewResp struct {
 	Results map[string]string
 }
@@ -57,6 +109,12 @@ type ShowCommitsResp struct {
 	Commits []string
 }
 
+type ExportCarResp struct {
+	Models  int
+	Commits int
+	Car     []byte
+}
+
 type GetPeerInfoResp struct {
 	PeerInfo string
 }
@@ -69,3 +127,129 @@ type GenerateTokenResp struct {
 type GetUrlResp struct {
 	Url string
 }
+
+type NodeStatsResp struct {
+	Address     string
+	ChainHeight int64
+	Balance     string
+	Reward      string
+	Disks       []DiskUsage
+	Staging     StagingUsage
+	Protocols   []ProtocolStreams
+	SidDocument SidDocumentCacheStats
+}
+
+// SidDocumentCacheStats are the counters of the cache of the sid documents the signatures are verified with since
+// the start, it is only enabled while the did txs are subscribed.
+type SidDocumentCacheStats struct {
+	Enabled   bool
+	Size      int
+	Capacity  int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Purges    uint64
+}
+
+// DiskUsage is the used percentage of the volume holding a path of the node.
+type DiskUsage struct {
+	Path        string
+	UsedPercent float64
+}
+
+// ProtocolStreams is the number of streams opened with a negotiated protocol version since the start, Direction is
+// inbound or outbound.
+type ProtocolStreams struct {
+	Protocol  string
+	Version   string
+	Direction string
+	Streams   uint64
+}
+
+// StagingUsage is the bytes staged by the owners and the uploads under the staging path against the quotas, 0 is
+// unlimited. Removed counts the orphaned files removed by the janitor since the start.
+type StagingUsage struct {
+	Path       string
+	Bytes      int64
+	Files      int
+	Quota      int64
+	OwnerQuota int64
+	Owners     []StagingOwnerUsage
+	Removed    uint64
+	// unix time of the last count of the files
+	ScannedAt int64
+}
+
+type StagingOwnerUsage struct {
+	// did of the staged shards or peer id of the uploads
+	Owner string
+	Bytes int64
+}
+
+// PlatformUsage is the models a platform committed through the gateway against its quotas, 0 is unlimited.
+// Throttled counts the requests rejected by the rate since the start.
+type PlatformUsage struct {
+	GroupId      string
+	Models       int
+	MaxModels    int
+	Bytes        int64
+	MaxBytes     int64
+	RequestRate  float64
+	Throttled    uint64
+	IsolateCache bool
+	// nil if the platform stages under the staging path of the node
+	Staging *StagingUsage
+}
+
+type CacheStatsResp struct {
+	// lru, lru+disk, redis or memcached
+	Backend      string
+	EnableCache  bool
+	Capacity     int
+	ContentLimit int
+	Caches       []CacheStats
+}
+
+// ProviderReputation are the counters of a storage provider seen by this node.
+type ProviderReputation struct {
+	Provider            string
+	Assigned            uint64
+	AssignFailures      uint64
+	Completed           uint64
+	Timeouts            uint64
+	AvgCompleteLatency  time.Duration
+	Loads               uint64
+	LoadFailures        uint64
+	Audits              uint64
+	AuditFailures       uint64
+	Migrations          uint64
+	MigrationFailures   uint64
+	AvgMigrationLatency time.Duration
+	FailureRate         float64
+	UpdatedAt           int64
+}
+
+// JobInfo is a periodic job of the node, NextRun is 0 if the job is disabled.
+type JobInfo struct {
+	Name         string
+	Enabled      bool
+	Schedule     string
+	Running      bool
+	NextRun      int64
+	LastRun      int64
+	LastDuration time.Duration
+	LastResult   string
+	LastError    string
+	Runs         uint64
+	Failures     uint64
+}
+
+// CacheStats are the counters of the model cache of an account, Size and Capacity are -1 for the remote caches.
+type CacheStats struct {
+	Account   string
+	Size      int
+	Capacity  int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
@@ -6,25 +6,59 @@ import (
 	"math/big"
 	"sao-node/types"
 
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
 	sdktypes "github.com/cosmos/cosmos-sdk/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 
+	"github.com/cosmos/go-bip39"
 	"github.com/ignite/cli/ignite/pkg/cosmosaccount"
 	"github.com/mitchellh/go-homedir"
 )
 
 const DENOM string = "sao"
 
-func newAccountRegistry(_ context.Context, repo string) (cosmosaccount.Registry, error) {
+// Account is a key in the local keyring.
+type Account struct {
+	Name    string
+	Address string
+}
+
+// keyringBackend is the cosmos keyring backend of the local keys, see SetKeyringBackend.
+var keyringBackend = cosmosaccount.KeyringTest
+
+// SetKeyringBackend selects the keyring backend used by the accounts and the chain client: "os" keeps the keys
+// in the OS keychain, "file" encrypts them with a passphrase under the keyring home, and "test" stores them
+// unencrypted under the keyring home.
+func SetKeyringBackend(backend string) error {
+	switch backend {
+	case keyring.BackendOS, keyring.BackendFile, keyring.BackendTest, keyring.BackendKWallet, keyring.BackendPass:
+		keyringBackend = cosmosaccount.KeyringBackend(backend)
+		return nil
+	default:
+		return types.Wrapf(types.ErrInvalidParameters, "unsupported keyring backend %s", backend)
+	}
+}
+
+func newAccountRegistry(ctx context.Context, repo string) (cosmosaccount.Registry, error) {
 	repoPath, err := homedir.Expand(repo)
 	if err != nil {
 		return cosmosaccount.Registry{}, err
 	}
 
-	return cosmosaccount.New(
-		cosmosaccount.WithKeyringBackend(cosmosaccount.KeyringTest),
+	registry, err := cosmosaccount.New(
+		cosmosaccount.WithKeyringBackend(keyringBackend),
 		cosmosaccount.WithHome(repoPath),
 	)
+	if err != nil {
+		return cosmosaccount.Registry{}, err
+	}
+
+	registry.Keyring, err = newKmsKeyring(ctx, registry.Keyring, repoPath)
+	if err != nil {
+		return cosmosaccount.Registry{}, err
+	}
+	return registry, nil
 }
 
 func GetAddress(ctx context.Context, repo string, name string) (string, error) {
@@ -77,6 +111,43 @@ func SignByAddress(ctx context.Context, repo string, address string, payload []b
 	return sig, nil
 }
 
+// Accounts returns the accounts in the local keyring.
+func Accounts(ctx context.Context, repo string) ([]Account, error) {
+	accountRegistry, err := newAccountRegistry(ctx, repo)
+	if err != nil {
+		return nil, types.Wrap(types.ErrListAccountsFailed, err)
+	}
+
+	accounts, err := accountRegistry.List()
+	if err != nil {
+		return nil, types.Wrap(types.ErrListAccountsFailed, err)
+	}
+
+	result := make([]Account, 0, len(accounts))
+	for _, account := range accounts {
+		address, err := account.Address(ADDRESS_PREFIX)
+		if err != nil {
+			return nil, types.Wrap(types.ErrListAccountsFailed, err)
+		}
+		result = append(result, Account{Name: account.Name, Address: address})
+	}
+	return result, nil
+}
+
+// Delete removes the account from the local keyring.
+func Delete(ctx context.Context, repo string, name string) error {
+	accountRegistry, err := newAccountRegistry(ctx, repo)
+	if err != nil {
+		return types.Wrap(types.ErrDeleteAccountFailed, err)
+	}
+
+	err = accountRegistry.DeleteByName(name)
+	if err != nil {
+		return types.Wrap(types.ErrDeleteAccountFailed, err)
+	}
+	return nil
+}
+
 func (c *ChainSvc) List(ctx context.Context, repo string) error {
 	accountRegistry, err := newAccountRegistry(ctx, repo)
 	if err != nil {
@@ -130,6 +201,19 @@ func (c *ChainSvc) ShowBalance(ctx context.Context, address string) {
 	fmt.Println("Balance:", resp.Balance.Amount, DENOM)
 }
 
+// signerAccount returns the keyring account of the signer, which is either a key name or an address, and its address.
+func (c *ChainSvc) signerAccount(signer string) (cosmosaccount.Account, string, error) {
+	account, err := c.cosmos.Account(signer)
+	if err != nil {
+		return cosmosaccount.Account{}, "", types.Wrap(types.ErrAccountNotFound, err)
+	}
+	address, err := account.Address(ADDRESS_PREFIX)
+	if err != nil {
+		return cosmosaccount.Account{}, "", types.Wrap(types.ErrGetAddressFailed, err)
+	}
+	return account, address, nil
+}
+
 func (c *ChainSvc) Send(ctx context.Context, from string, to string, amount int64) (string, error) {
 	signerAcc, err := c.cosmos.Account(from)
 	if err != nil {
@@ -145,11 +229,9 @@ func (c *ChainSvc) Send(ctx context.Context, from string, to string, amount int6
 	}
 
 	txResp, err := tx.Broadcast(ctx)
-	if err != nil {
-		return "", types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", types.Wrapf(types.ErrTxProcessFailed, "MsgStore tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	c.observeTx(sdktypes.MsgTypeURL(&banktypes.MsgSend{}), 1, txResp, err)
+	if err := txError("MsgSend", txResp, err); err != nil {
+		return "", err
 	}
 
 	return txResp.TxResponse.TxHash, nil
@@ -174,6 +256,39 @@ func Create(ctx context.Context, repo string, name string) (string, string, stri
 	return account.Name, address, mnemonic, nil
 }
 
+// Recover restores the account from the BIP-39 mnemonic, hdPath defaults to the path accounts are created with.
+func Recover(ctx context.Context, repo string, name string, mnemonic string, bip39Passphrase string, hdPath string) (string, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return "", types.Wrapf(types.ErrInvalidSecrect, "invalid mnemonic")
+	}
+	if hdPath == "" {
+		hdPath = hd.CreateHDPath(sdktypes.GetConfig().GetCoinType(), 0, 0).String()
+	}
+	if _, err := hd.NewParamsFromPath(hdPath); err != nil {
+		return "", types.Wrapf(types.ErrInvalidParameters, "invalid hd path %s: %v", hdPath, err)
+	}
+
+	accountRegistry, err := newAccountRegistry(ctx, repo)
+	if err != nil {
+		return "", types.Wrap(types.ErrImportAccountFailed, err)
+	}
+	if _, err := accountRegistry.GetByName(name); err == nil {
+		return "", types.Wrapf(types.ErrImportAccountFailed, "account %s exists already", name)
+	}
+
+	algos, _ := accountRegistry.Keyring.SupportedAlgorithms()
+	algo, err := keyring.NewSigningAlgoFromString(string(hd.Secp256k1Type), algos)
+	if err != nil {
+		return "", types.Wrap(types.ErrImportAccountFailed, err)
+	}
+	_, err = accountRegistry.Keyring.NewAccount(name, mnemonic, bip39Passphrase, hdPath, algo)
+	if err != nil {
+		return "", types.Wrap(types.ErrImportAccountFailed, err)
+	}
+
+	return GetAddress(ctx, repo, name)
+}
+
 func Import(ctx context.Context, repo string, name string, secret string, passphrase string) error {
 	accountRegistry, err := newAccountRegistry(ctx, repo)
 	if err != nil {
@@ -0,0 +1,305 @@
+package chain
+
+import (
+	"context"
+	"sao-node/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/ignite/cli/ignite/pkg/cosmosaccount"
+	"github.com/ignite/cli/ignite/pkg/cosmosclient"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// The broadcast modes of the txs whose results are not needed by the caller, such as MsgComplete.
+const (
+	// wait for the block including the tx
+	BroadcastBlock = "block"
+	// return once the tx is accepted to the mempool, its inclusion is tracked
+	BroadcastSync = "sync"
+	// return without waiting for the mempool, its inclusion is tracked
+	BroadcastAsync = "async"
+)
+
+// TxResult is the outcome of a tx broadcast without waiting for its block, Err is nil if the tx succeeded.
+type TxResult struct {
+	MsgType string
+	Hash    string
+	Height  int64
+	Err     error
+}
+
+type pendingTx struct {
+	msgType  string
+	msgCount int
+	sent     time.Time
+}
+
+// txTracker signs the txs with the next sequences of the signers and tracks the txs broadcast without waiting
+// for their blocks until they are included or time out.
+type txTracker struct {
+	mode    string
+	timeout time.Duration
+	handler func(TxResult)
+
+	lk sync.Mutex
+	// the next sequence by signer, the txs in the mempool are not counted by the chain yet
+	sequences map[string]uint64
+	pending   map[string]pendingTx
+}
+
+func newTxTracker() *txTracker {
+	return &txTracker{
+		mode:      BroadcastBlock,
+		sequences: make(map[string]uint64),
+		pending:   make(map[string]pendingTx),
+	}
+}
+
+// how long the txs broadcast without waiting for their blocks are tracked if the policy has no timeout
+const defaultTxTimeout = 2 * time.Minute
+
+// SetBroadcastPolicy sets the broadcast mode of the txs whose results are not needed by the caller, the others
+// always wait for their blocks. timeout is how long a tx is waited for to be included in a block, 0 waits until
+// the context is done in the block mode.
+func (c *ChainSvc) SetBroadcastPolicy(mode string, timeout time.Duration) error {
+	switch mode {
+	case BroadcastBlock, BroadcastSync, BroadcastAsync:
+	default:
+		return types.Wrapf(types.ErrInvalidParameters, "invalid broadcast mode %s", mode)
+	}
+	c.tracker.mode = mode
+	c.tracker.timeout = timeout
+	return nil
+}
+
+// SetTxResultHandler sets the handler of the results of the txs broadcast without waiting for their blocks, it
+// must be set before any tx is broadcast.
+func (c *ChainSvc) SetTxResultHandler(handler func(TxResult)) {
+	c.tracker.handler = handler
+}
+
+// broadcastTx broadcasts the msgs of the same type signed by the account in one tx and waits for its block, and
+// reports the result to the tx observer. The tx is simulated first to estimate its gas, a tx failing to be built
+// or rejected by the simulation is not broadcast and its error is a simulateError.
+func (c *ChainSvc) broadcastTx(ctx context.Context, account cosmosaccount.Account, msgs ...sdktypes.Msg) (cosmosclient.Response, error) {
+	msgType := sdktypes.MsgTypeURL(msgs[0])
+	txResp, err := c.broadcast(ctx, account, flags.BroadcastSync, msgs...)
+	if err != nil || txResp.TxResponse.Code != 0 {
+		c.observeTx(msgType, len(msgs), txResp, err)
+		return txResp, err
+	}
+
+	if c.tracker.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.tracker.timeout)
+		defer cancel()
+	}
+	res, err := c.cosmos.WaitForTx(ctx, txResp.TxResponse.TxHash)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = types.Wrapf(types.ErrTxNotIncluded, "tx %s in %s", txResp.TxResponse.TxHash, c.tracker.timeout)
+		}
+		c.observeTx(msgType, len(msgs), cosmosclient.Response{}, err)
+		return cosmosclient.Response{}, err
+	}
+	txResp.TxResponse = sdktypes.NewResponseResultTx(res, nil, "")
+	c.observeTx(msgType, len(msgs), txResp, nil)
+	return txResp, nil
+}
+
+// broadcastTxNoWait broadcasts the msgs like broadcastTx in the broadcast mode of the policy. Unless it is the
+// block mode, the response has no height and the result is sent to the tx result handler once the tx is included.
+func (c *ChainSvc) broadcastTxNoWait(ctx context.Context, account cosmosaccount.Account, msgs ...sdktypes.Msg) (cosmosclient.Response, error) {
+	if c.tracker.mode == BroadcastBlock {
+		return c.broadcastTx(ctx, account, msgs...)
+	}
+
+	msgType := sdktypes.MsgTypeURL(msgs[0])
+	txResp, err := c.broadcast(ctx, account, c.tracker.mode, msgs...)
+	if err != nil || txResp.TxResponse.Code != 0 {
+		c.observeTx(msgType, len(msgs), txResp, err)
+		return txResp, err
+	}
+	c.TrackTx(txResp.TxResponse.TxHash, msgType)
+	return txResp, nil
+}
+
+// TrackTx sends the result of the tx to the tx result handler once it is included, such as a tx the previous run
+// of the node broadcast without waiting for its block. A tx tracked already is not tracked again.
+func (c *ChainSvc) TrackTx(hash string, msgType string) {
+	c.tracker.lk.Lock()
+	defer c.tracker.lk.Unlock()
+	if _, ok := c.tracker.pending[hash]; !ok {
+		c.tracker.pending[hash] = pendingTx{msgType: msgType, msgCount: 1, sent: time.Now()}
+	}
+}
+
+// WaitTx waits for the block including the tx, up to the timeout of the broadcast policy.
+func (c *ChainSvc) WaitTx(ctx context.Context, hash string) (*coretypes.ResultTx, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.tracker.trackTimeout())
+	defer cancel()
+	res, err := c.cosmos.WaitForTx(ctx, hash)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, types.Wrapf(types.ErrTxNotIncluded, "tx %s in %s", hash, c.tracker.trackTimeout())
+		}
+		return nil, types.Wrap(types.ErrTxQueryFailed, err)
+	}
+	return res, nil
+}
+
+// broadcast signs the msgs with the next sequence of the account and broadcasts them in mode. A tx rejected for
+// its sequence, such as after a tx dropped from the mempool, is signed again with the sequence on chain.
+func (c *ChainSvc) broadcast(ctx context.Context, account cosmosaccount.Account, mode string, msgs ...sdktypes.Msg) (cosmosclient.Response, error) {
+	for _, msg := range msgs {
+		if err := msg.ValidateBasic(); err != nil {
+			return cosmosclient.Response{}, err
+		}
+	}
+	txService, err := c.cosmos.CreateTx(ctx, account, msgs...)
+	if err != nil {
+		return cosmosclient.Response{}, &simulateError{err: err}
+	}
+	address, err := account.Record.GetAddress()
+	if err != nil {
+		return cosmosclient.Response{}, err
+	}
+	clientCtx := c.cosmos.Context().
+		WithFromName(account.Name).
+		WithFromAddress(address).
+		WithBroadcastMode(mode)
+
+	t := c.tracker
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	for attempt := 0; ; attempt++ {
+		num, seq, err := clientCtx.AccountRetriever.GetAccountNumberSequence(clientCtx, address)
+		if err != nil {
+			return cosmosclient.Response{}, err
+		}
+		if next := t.sequences[address.String()]; next > seq {
+			seq = next
+		}
+
+		txf := c.cosmos.TxFactory.
+			WithAccountNumber(num).
+			WithSequence(seq).
+			WithGas(txService.Gas())
+		txBuilder, err := txf.BuildUnsignedTx(msgs...)
+		if err != nil {
+			return cosmosclient.Response{}, err
+		}
+		if err := tx.Sign(txf, account.Name, txBuilder, true); err != nil {
+			return cosmosclient.Response{}, err
+		}
+		txBytes, err := clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+		if err != nil {
+			return cosmosclient.Response{}, err
+		}
+		resp, err := clientCtx.BroadcastTx(txBytes)
+		if err != nil {
+			return cosmosclient.Response{}, err
+		}
+
+		if resp.Code == sdkerrors.ErrWrongSequence.ABCICode() && resp.Codespace == sdkerrors.RootCodespace {
+			delete(t.sequences, address.String())
+			if attempt == 0 {
+				log.Warnf("tx of %s rejected for the sequence %d, sign it again: %s", address, seq, resp.RawLog)
+				continue
+			}
+		} else if resp.Code == 0 {
+			t.sequences[address.String()] = seq + 1
+		}
+		return cosmosclient.Response{Codec: clientCtx.Codec, TxResponse: resp}, nil
+	}
+}
+
+func (t *txTracker) trackTimeout() time.Duration {
+	if t.timeout > 0 {
+		return t.timeout
+	}
+	return defaultTxTimeout
+}
+
+func (t *txTracker) tracking() bool {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	return len(t.pending) > 0
+}
+
+// trackTxs polls the txs broadcast without waiting for their blocks on every new block until ctx is done.
+func (c *ChainSvc) trackTxs(ctx context.Context) {
+	ticker := time.NewTicker(Blocktime)
+	defer ticker.Stop()
+	var height int64
+	for {
+		select {
+		case <-ticker.C:
+			if !c.tracker.tracking() {
+				continue
+			}
+			h, err := c.GetLastHeight(ctx)
+			if err != nil || h <= height {
+				continue
+			}
+			height = h
+			c.tracker.poll(time.Now(), func(hash string) (*coretypes.ResultTx, error) {
+				return c.GetTx(ctx, hash, 0)
+			}, c.observeTx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll looks up the pending txs, the included ones and the ones not included in time are reported to the
+// handler and no longer tracked.
+func (t *txTracker) poll(now time.Time, lookup func(hash string) (*coretypes.ResultTx, error), observe func(string, int, cosmosclient.Response, error)) {
+	t.lk.Lock()
+	if len(t.pending) == 0 {
+		t.lk.Unlock()
+		return
+	}
+	pending := make(map[string]pendingTx, len(t.pending))
+	for hash, p := range t.pending {
+		pending[hash] = p
+	}
+	t.lk.Unlock()
+
+	for hash, p := range pending {
+		result := TxResult{MsgType: p.msgType, Hash: hash}
+		res, err := lookup(hash)
+		switch {
+		case err == nil:
+			txResp := cosmosclient.Response{TxResponse: sdktypes.NewResponseResultTx(res, nil, "")}
+			result.Height = res.Height
+			result.Err = txError(p.msgType, txResp, nil)
+			observe(p.msgType, p.msgCount, txResp, nil)
+		case !strings.Contains(err.Error(), "not found"):
+			log.Warnf("look up pending tx %s: %v", hash, err)
+			continue
+		case now.Sub(p.sent) < t.trackTimeout():
+			continue
+		default:
+			result.Err = types.Wrapf(types.ErrTxNotIncluded, "tx %s in %s", hash, t.trackTimeout())
+			observe(p.msgType, p.msgCount, cosmosclient.Response{}, result.Err)
+			t.lk.Lock()
+			// the next txs of the signer take the sequence of the dropped tx again
+			t.sequences = make(map[string]uint64)
+			t.lk.Unlock()
+		}
+
+		t.lk.Lock()
+		delete(t.pending, hash)
+		t.lk.Unlock()
+		if t.handler != nil {
+			t.handler(result)
+		}
+	}
+}
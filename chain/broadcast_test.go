@@ -0,0 +1,65 @@
+package chain
+
+import (
+	"errors"
+	"sao-node/types"
+	"testing"
+	"time"
+
+	"github.com/ignite/cli/ignite/pkg/cosmosclient"
+	"github.com/stretchr/testify/require"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestTxTracker(t *testing.T) {
+	tracker := newTxTracker()
+	tracker.timeout = time.Minute
+	var results []TxResult
+	tracker.handler = func(result TxResult) {
+		results = append(results, result)
+	}
+	observed := 0
+	observe := func(string, int, cosmosclient.Response, error) {
+		observed++
+	}
+
+	sent := time.Now()
+	tracker.sequences["sao1node"] = 8
+	tracker.pending["OK"] = pendingTx{msgType: "/saonetwork.sao.sao.MsgComplete", msgCount: 1, sent: sent}
+	tracker.pending["FAILED"] = pendingTx{msgType: "/saonetwork.sao.sao.MsgComplete", msgCount: 1, sent: sent}
+	tracker.pending["PENDING"] = pendingTx{msgType: "/saonetwork.sao.sao.MsgComplete", msgCount: 1, sent: sent}
+	lookup := func(hash string) (*coretypes.ResultTx, error) {
+		switch hash {
+		case "OK":
+			return &coretypes.ResultTx{Height: 10}, nil
+		case "FAILED":
+			return &coretypes.ResultTx{Height: 11, TxResult: abcitypes.ResponseDeliverTx{Code: 5, Codespace: "sdk"}}, nil
+		default:
+			return nil, errors.New("tx (PENDING) not found")
+		}
+	}
+
+	tracker.poll(sent.Add(time.Second), lookup, observe)
+	require.Len(t, results, 2)
+	byHash := map[string]TxResult{}
+	for _, result := range results {
+		byHash[result.Hash] = result
+	}
+	require.Equal(t, TxResult{MsgType: "/saonetwork.sao.sao.MsgComplete", Hash: "OK", Height: 10}, byHash["OK"])
+	require.True(t, types.ErrInsufficientFunds.Is(byHash["FAILED"].Err))
+	require.Equal(t, 2, observed)
+	require.Len(t, tracker.pending, 1)
+
+	// dropped once it is not included in time
+	results = nil
+	tracker.poll(sent.Add(2*time.Minute), lookup, observe)
+	require.Len(t, results, 1)
+	require.Equal(t, "PENDING", results[0].Hash)
+	require.True(t, types.ErrTxNotIncluded.Is(results[0].Err))
+	require.Empty(t, tracker.pending)
+	require.Empty(t, tracker.sequences)
+
+	tracker.poll(sent.Add(3*time.Minute), lookup, observe)
+	require.Len(t, results, 1)
+}
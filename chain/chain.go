@@ -3,7 +3,9 @@ package chain
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"sao-node/types"
+	"sync"
 	"time"
 
 	coretypes "github.com/tendermint/tendermint/rpc/core/types"
@@ -18,6 +20,7 @@ import (
 	sdktypes "github.com/cosmos/cosmos-sdk/types"
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	"github.com/ignite/cli/ignite/pkg/cosmosclient"
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
@@ -36,10 +39,24 @@ type ChainSvc struct {
 	nodeClient       nodetypes.QueryClient
 	didClient        didtypes.QueryClient
 	modelClient      modeltypes.QueryClient
+	stakingClient    stakingtypes.QueryClient
 	listener         *http.HTTP
 	accountRetriever authtypes.AccountRetriever
+	sidCache         sidDocumentCache
+	txObserver       TxObserver
+
+	// gas wanted per message by the last tx of each msg type
+	gasLock   sync.Mutex
+	gasWanted map[string]uint64
+
+	// nil unless the MsgStore messages are batched
+	storeBatcher *storeBatcher
+	tracker      *txTracker
 }
 
+// TxObserver is notified of every tx broadcast by the chain service, err is nil if the tx succeeded.
+type TxObserver func(msgType string, err error)
+
 type ChainSvcApi interface {
 	Stop(ctx context.Context) error
 	GetLastHeight(ctx context.Context) (int64, error)
@@ -47,10 +64,15 @@ type ChainSvcApi interface {
 	GetBalance(ctx context.Context, address string) (sdktypes.Coins, error)
 	ShowDidInfo(ctx context.Context, did string)
 	GetSidDocument(ctx context.Context, versionId string) (*sid.SidDocument, error)
+	EnableSidDocumentCache(ctx context.Context) error
+	SidDocumentCacheStats() SidDocumentCacheStats
 	UpdateDidBinding(ctx context.Context, creator string, did string, accountId string) (string, error)
 	QueryPaymentAddress(ctx context.Context, did string) (string, error)
 	QueryMetadata(ctx context.Context, req *types.MetadataProposal, height int64) (*saotypes.QueryMetadataResponse, error)
+	QueryMetadataAs(ctx context.Context, proposal saotypes.QueryProposal, did string, height int64) (*saotypes.QueryMetadataResponse, error)
 	GetMeta(ctx context.Context, dataId string) (*modeltypes.QueryGetMetadataResponse, error)
+	ListMetadata(ctx context.Context, owner string) ([]modeltypes.Metadata, error)
+	SubscribeSaoEvents(ctx context.Context, cursor EventCursor, ch chan<- SaoEvent) error
 	UpdatePermission(ctx context.Context, signer string, proposal *types.PermissionProposal) (string, error)
 	Create(ctx context.Context, creator string) (string, error)
 	Reset(ctx context.Context, creator string, peerInfo string, status uint32) (string, error)
@@ -60,10 +82,12 @@ type ChainSvcApi interface {
 	StartStatusReporter(ctx context.Context, creator string, status uint32)
 	OrderReady(ctx context.Context, provider string, orderId uint64) (saotypes.MsgReadyResponse, string, int64, error)
 	StoreOrder(ctx context.Context, signer string, clientProposal *types.OrderStoreProposal) (saotypes.MsgStoreResponse, string, int64, error)
-	CompleteOrder(ctx context.Context, creator string, orderId uint64, cid cid.Cid, size uint64) (string, int64, error)
+	CompleteOrder(ctx context.Context, signer string, orderId uint64, cid cid.Cid, size uint64) (string, int64, error)
 	RenewOrder(ctx context.Context, creator string, orderRenewProposal types.OrderRenewProposal) (string, map[string]string, error)
 	MigrateOrder(ctx context.Context, creator string, dataIds []string) (string, map[string]string, int64, error)
+	CancelOrder(ctx context.Context, signer string, orderId uint64) (string, error)
 	GetOrder(ctx context.Context, orderId uint64) (*ordertypes.Order, error)
+	EstimateOrderFee(ctx context.Context, size uint64, duration uint64, replica int32) (sdktypes.Coin, error)
 	//SubscribeOrderComplete(ctx context.Context, orderId uint64, doneChan chan OrderCompleteResult) error
 	//UnsubscribeOrderComplete(ctx context.Context, orderId uint64) error
 	//SubscribeShardTask(ctx context.Context, nodeAddr string, shardTaskChan chan *ShardTask) error
@@ -84,18 +108,28 @@ func NewChainSvc(
 		cosmosclient.WithAddressPrefix(ADDRESS_PREFIX),
 		cosmosclient.WithNodeAddress(chainAddress),
 		cosmosclient.WithKeyringDir(keyringHome),
+		cosmosclient.WithKeyringBackend(keyringBackend),
 		cosmosclient.WithGas("auto"),
 	)
 	if err != nil {
 		return nil, types.Wrap(types.ErrCreateChainServiceFailed, err)
 	}
 
+	// keys configured in kms.toml sign txs with the external KMS
+	kr, err := newKmsKeyring(ctx, cosmos.AccountRegistry.Keyring, keyringHome)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateChainServiceFailed, err)
+	}
+	cosmos.AccountRegistry.Keyring = kr
+	cosmos.TxFactory = cosmos.TxFactory.WithKeybase(kr)
+
 	accountRetriever := authtypes.AccountRetriever{}
 	bankClient := banktypes.NewQueryClient(cosmos.Context())
 	orderClient := ordertypes.NewQueryClient(cosmos.Context())
 	nodeClient := nodetypes.NewQueryClient(cosmos.Context())
 	didClient := didtypes.NewQueryClient(cosmos.Context())
 	modelClient := modeltypes.NewQueryClient(cosmos.Context())
+	stakingClient := stakingtypes.NewQueryClient(cosmos.Context())
 
 	log.Debugf("initialize chain listener")
 	http, err := http.New(chainAddress, wsEndpoint)
@@ -110,16 +144,42 @@ func NewChainSvc(
 	// }
 	// log.Debugf("initialize chain listener3")
 
-	return &ChainSvc{
+	c := &ChainSvc{
 		cosmos:           cosmos,
 		bankClient:       bankClient,
 		orderClient:      orderClient,
 		nodeClient:       nodeClient,
 		didClient:        didClient,
 		modelClient:      modelClient,
+		stakingClient:    stakingClient,
+		gasWanted:        make(map[string]uint64),
+		tracker:          newTxTracker(),
 		listener:         http,
 		accountRetriever: accountRetriever,
-	}, nil
+	}
+	go c.trackTxs(ctx)
+	return c, nil
+}
+
+// SetTxObserver sets the observer of the tx results, it must be set before any tx is broadcast.
+func (c *ChainSvc) SetTxObserver(observer TxObserver) {
+	c.txObserver = observer
+}
+
+func (c *ChainSvc) observeTx(msgType string, msgCount int, txResp cosmosclient.Response, err error) {
+	if err == nil && txResp.TxResponse != nil && txResp.TxResponse.GasWanted > 0 {
+		c.gasLock.Lock()
+		c.gasWanted[msgType] = uint64(txResp.TxResponse.GasWanted) / uint64(msgCount)
+		c.gasLock.Unlock()
+	}
+
+	if c.txObserver == nil {
+		return
+	}
+	if err == nil && txResp.TxResponse != nil && txResp.TxResponse.Code != 0 {
+		err = fmt.Errorf("tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	}
+	c.txObserver(msgType, err)
 }
 
 func (c *ChainSvc) Stop(ctx context.Context) error {
@@ -137,6 +197,15 @@ func (c *ChainSvc) GetLastHeight(ctx context.Context) (int64, error) {
 	return c.cosmos.LatestBlockHeight(ctx)
 }
 
+// GetBlockTime returns the time of the block at the height.
+func (c *ChainSvc) GetBlockTime(ctx context.Context, height int64) (time.Time, error) {
+	block, err := c.cosmos.RPC.Block(ctx, &height)
+	if err != nil {
+		return time.Time{}, types.Wrap(types.ErrQueryNodeFailed, err)
+	}
+	return block.Block.Time, nil
+}
+
 func (c *ChainSvc) GetAccount(ctx context.Context, address string) (client.Account, error) {
 	accAddress, err := sdktypes.AccAddressFromBech32(address)
 	if err != nil {
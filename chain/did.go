@@ -14,6 +14,11 @@ import (
 )
 
 func (c *ChainSvc) GetSidDocument(ctx context.Context, versionId string) (*sid.SidDocument, error) {
+	doc, exists, generation := c.sidCache.get(versionId)
+	if exists {
+		return doc, nil
+	}
+
 	resp, err := c.didClient.SidDocument(ctx, &sidtypes.QueryGetSidDocumentRequest{VersionId: versionId})
 	if err != nil {
 		return nil, types.Wrap(types.ErrGetSidDocumentFailed, err)
@@ -29,10 +34,26 @@ func (c *ChainSvc) GetSidDocument(ctx context.Context, versionId string) (*sid.S
 		})
 	}
 
-	return &sid.SidDocument{
+	doc = &sid.SidDocument{
 		VersionId: resp.SidDocument.VersionId,
 		Keys:      keys,
-	}, nil
+	}
+	c.sidCache.put(versionId, doc, generation)
+	return doc, nil
+}
+
+// GetSidDocumentVersions returns the versions of the sid document, oldest first, as of the height, or the latest
+// height if 0. The last one is the version valid at the height.
+func (c *ChainSvc) GetSidDocumentVersions(ctx context.Context, docId string, height int64) ([]string, error) {
+	clientctx := c.cosmos.Context()
+	if height > 0 {
+		clientctx = clientctx.WithHeight(height)
+	}
+	resp, err := sidtypes.NewQueryClient(clientctx).SidDocumentVersion(ctx, &sidtypes.QueryGetSidDocumentVersionRequest{DocId: docId})
+	if err != nil {
+		return nil, types.Wrap(types.ErrGetSidDocumentFailed, err)
+	}
+	return resp.SidDocumentVersion.VersionList, nil
 }
 
 func (c *ChainSvc) UpdateDidBinding(ctx context.Context, creator string, did string, accountId string) (string, error) {
@@ -46,12 +67,9 @@ func (c *ChainSvc) UpdateDidBinding(ctx context.Context, creator string, did str
 		Did:       did,
 		AccountId: accountId,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
-	if err != nil {
-		return "", types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", types.Wrapf(types.ErrTxProcessFailed, "MsgUpdatePaymentAddress tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgUpdatePaymentAddress", txResp, err); err != nil {
+		return "", err
 	}
 	return txResp.TxResponse.TxHash, nil
 }
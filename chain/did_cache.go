@@ -0,0 +1,196 @@
+package chain
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaoNetwork/sao-did/sid"
+	didtypes "github.com/SaoNetwork/sao/x/did/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	didEventsQuery        = "tm.event='Tx' AND message.module='did'"
+	didCacheStatsInterval = 10 * time.Minute
+	// the least recently used sid documents are evicted beyond it
+	sidDocumentCacheCapacity = 4096
+)
+
+// did messages which only add new sid document versions, the cached versions stay valid after them.
+var appendOnlyDidMsgs = map[string]struct{}{
+	sdk.MsgTypeURL(&didtypes.MsgUpdate{}):               {},
+	sdk.MsgTypeURL(&didtypes.MsgBinding{}):              {},
+	sdk.MsgTypeURL(&didtypes.MsgUpdatePaymentAddress{}): {},
+}
+
+type SidDocumentCacheStats struct {
+	Enabled   bool
+	Size      int
+	Capacity  int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Purges    uint64
+}
+
+func (s SidDocumentCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// sidDocumentCache keeps the sid documents by version id. It is only used while the did events are
+// subscribed, any did tx other than the append-only ones purges it, since a reset store removes documents.
+// A purge starts a new generation, the documents queried in an older one are not cached.
+type sidDocumentCache struct {
+	lk         sync.RWMutex
+	enabled    bool
+	docs       *simplelru.LRU
+	generation uint64
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+	purges     uint64
+}
+
+// get returns the cached document of versionId and the generation of the cache, a document queried on a miss
+// is put with it.
+func (sc *sidDocumentCache) get(versionId string) (*sid.SidDocument, bool, uint64) {
+	sc.lk.Lock()
+	defer sc.lk.Unlock()
+
+	if !sc.enabled {
+		return nil, false, sc.generation
+	}
+	doc, exists := sc.docs.Get(versionId)
+	if !exists {
+		sc.misses++
+		return nil, false, sc.generation
+	}
+	sc.hits++
+	return doc.(*sid.SidDocument), true, sc.generation
+}
+
+// put caches the document queried in the generation, unless the cache is purged since.
+func (sc *sidDocumentCache) put(versionId string, doc *sid.SidDocument, generation uint64) {
+	sc.lk.Lock()
+	defer sc.lk.Unlock()
+
+	if sc.enabled && generation == sc.generation {
+		sc.docs.Add(versionId, doc)
+	}
+}
+
+func (sc *sidDocumentCache) setEnabled(enabled bool) {
+	sc.lk.Lock()
+	defer sc.lk.Unlock()
+
+	sc.enabled = enabled
+	sc.reset()
+}
+
+func (sc *sidDocumentCache) purge() {
+	sc.lk.Lock()
+	defer sc.lk.Unlock()
+
+	sc.reset()
+	sc.purges++
+}
+
+func (sc *sidDocumentCache) reset() {
+	sc.generation++
+	sc.docs, _ = simplelru.NewLRU(sidDocumentCacheCapacity, func(interface{}, interface{}) {
+		sc.evictions++
+	})
+}
+
+func (sc *sidDocumentCache) stats() SidDocumentCacheStats {
+	sc.lk.RLock()
+	defer sc.lk.RUnlock()
+
+	stats := SidDocumentCacheStats{
+		Enabled:   sc.enabled,
+		Capacity:  sidDocumentCacheCapacity,
+		Hits:      sc.hits,
+		Misses:    sc.misses,
+		Evictions: sc.evictions,
+		Purges:    sc.purges,
+	}
+	if sc.docs != nil {
+		stats.Size = sc.docs.Len()
+	}
+	return stats
+}
+
+// EnableSidDocumentCache subscribes the did module txs and caches the sid documents queried until ctx is done.
+// The cache is bypassed again once the subscription is lost.
+func (c *ChainSvc) EnableSidDocumentCache(ctx context.Context) error {
+	if !c.listener.IsRunning() {
+		err := c.listener.Start()
+		if err != nil {
+			return err
+		}
+	}
+
+	ch, err := c.listener.Subscribe(ctx, subscriber, didEventsQuery)
+	if err != nil {
+		return err
+	}
+	c.sidCache.setEnabled(true)
+	log.Info("sid document cache enabled")
+
+	go func() {
+		defer c.sidCache.setEnabled(false)
+
+		ticker := time.NewTicker(didCacheStatsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case ev, ok := <-ch:
+				if !ok {
+					log.Warn("did events subscription closed, sid document cache disabled")
+					return
+				}
+				if purgesSidDocuments(ev.Events["message.action"]) {
+					log.Debugf("purge sid document cache on %v", ev.Events["message.action"])
+					c.sidCache.purge()
+				}
+			case <-ticker.C:
+				stats := c.sidCache.stats()
+				log.Debugf("sid document cache: size=%d hits=%d misses=%d hit rate=%.2f evictions=%d purges=%d",
+					stats.Size, stats.Hits, stats.Misses, stats.HitRate(), stats.Evictions, stats.Purges)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// SidDocumentCacheStats returns the size and the hit and miss counts of the sid document cache.
+func (c *ChainSvc) SidDocumentCacheStats() SidDocumentCacheStats {
+	return c.sidCache.stats()
+}
+
+func purgesSidDocuments(actions []string) bool {
+	if len(actions) == 0 {
+		return true
+	}
+	for _, action := range actions {
+		if _, exists := appendOnlyDidMsgs[action]; exists {
+			continue
+		}
+		// txs may carry messages of other modules too
+		if strings.HasPrefix(action, "/") && !strings.Contains(action, ".did.") {
+			continue
+		}
+		return true
+	}
+	return false
+}
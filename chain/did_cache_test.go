@@ -0,0 +1,70 @@
+package chain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/SaoNetwork/sao-did/sid"
+	didtypes "github.com/SaoNetwork/sao/x/did/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSidDocumentCache(t *testing.T) {
+	var sc sidDocumentCache
+	doc := &sid.SidDocument{VersionId: "v1"}
+
+	// bypassed until enabled
+	_, _, generation := sc.get("v1")
+	sc.put("v1", doc, generation)
+	_, exists, generation := sc.get("v1")
+	require.False(t, exists)
+
+	sc.setEnabled(true)
+	_, exists, generation = sc.get("v1")
+	require.False(t, exists)
+	sc.put("v1", doc, generation)
+	cached, exists, _ := sc.get("v1")
+	require.True(t, exists)
+	require.Equal(t, doc, cached)
+
+	// a document queried before a purge is not cached after it
+	_, exists, generation = sc.get("v2")
+	require.False(t, exists)
+	sc.purge()
+	sc.put("v2", &sid.SidDocument{VersionId: "v2"}, generation)
+	_, exists, _ = sc.get("v2")
+	require.False(t, exists)
+	_, exists, _ = sc.get("v1")
+	require.False(t, exists)
+
+	stats := sc.stats()
+	require.True(t, stats.Enabled)
+	require.Zero(t, stats.Size)
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(4), stats.Misses)
+	require.Equal(t, uint64(1), stats.Purges)
+
+	// the least recently used documents are evicted beyond the capacity
+	_, _, generation = sc.get("v1")
+	for i := 0; i <= sidDocumentCacheCapacity; i++ {
+		versionId := fmt.Sprintf("v%d", i)
+		sc.put(versionId, &sid.SidDocument{VersionId: versionId}, generation)
+	}
+	stats = sc.stats()
+	require.Equal(t, sidDocumentCacheCapacity, stats.Size)
+	require.Equal(t, uint64(1), stats.Evictions)
+	_, exists, _ = sc.get("v0")
+	require.False(t, exists)
+
+	sc.setEnabled(false)
+	_, exists, _ = sc.get("v1")
+	require.False(t, exists)
+}
+
+func TestPurgesSidDocuments(t *testing.T) {
+	require.True(t, purgesSidDocuments(nil))
+	require.False(t, purgesSidDocuments([]string{sdk.MsgTypeURL(&didtypes.MsgUpdate{})}))
+	require.False(t, purgesSidDocuments([]string{sdk.MsgTypeURL(&didtypes.MsgBinding{}), "/cosmos.bank.v1beta1.MsgSend"}))
+	require.True(t, purgesSidDocuments([]string{sdk.MsgTypeURL(&didtypes.MsgUpdate{}), sdk.MsgTypeURL(&didtypes.MsgResetStore{})}))
+}
@@ -0,0 +1,422 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256r1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/mitchellh/go-homedir"
+)
+
+// KmsConfigFile in the keyring home maps key names to keys held by an external KMS.
+const KmsConfigFile = "kms.toml"
+
+const (
+	KmsBackendVault  = "vault"
+	KmsBackendGcpKms = "gcpkms"
+)
+
+const (
+	kmsRequestTimeout = 30 * time.Second
+	gcpKmsAddress     = "https://cloudkms.googleapis.com"
+	vaultTransitMount = "transit"
+)
+
+var (
+	oidSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	oidP256      = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+
+	secp256k1N, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+)
+
+type KmsConfig struct {
+	Key []KmsKey
+}
+
+type KmsKey struct {
+	// key name, used in place of a local key name, e.g. --key-name or the node account
+	Name string
+	// vault or gcpkms
+	Backend string
+	// vault server address, e.g. https://127.0.0.1:8200, empty means the public endpoint for gcpkms
+	Address string
+	// vault: transit key name, as <mount>/<key> if not mounted at transit, the key type must be ecdsa-p256.
+	// gcpkms: crypto key version resource name, the algorithm must be EC_SIGN_SECP256K1_SHA256 or EC_SIGN_P256_SHA256.
+	KeyId string
+	// name of the env var holding the vault token or the gcp access token
+	TokenEnv string
+}
+
+// kmsSigner signs with a key which never leaves the KMS.
+type kmsSigner interface {
+	// PublicKey returns the PEM encoded public key
+	PublicKey(ctx context.Context) ([]byte, error)
+	// Sign returns the DER encoded ECDSA signature of sha256(msg)
+	Sign(ctx context.Context, msg []byte) ([]byte, error)
+}
+
+type kmsKey struct {
+	signer kmsSigner
+	record *keyring.Record
+	pubKey cryptotypes.PubKey
+	order  *big.Int
+}
+
+// kmsKeyring serves the keys in kms.toml from the KMS, other keys from the local keyring.
+type kmsKeyring struct {
+	keyring.Keyring
+	keys map[string]*kmsKey
+}
+
+// newKmsKeyring wraps the local keyring if kms.toml exists in the keyring home.
+func newKmsKeyring(ctx context.Context, local keyring.Keyring, keyringHome string) (keyring.Keyring, error) {
+	home, err := homedir.Expand(keyringHome)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateAccountRegistryFailed, err)
+	}
+
+	var cfg KmsConfig
+	_, err = toml.DecodeFile(filepath.Join(home, KmsConfigFile), &cfg)
+	if os.IsNotExist(err) {
+		return local, nil
+	} else if err != nil {
+		return nil, types.Wrapf(types.ErrCreateAccountRegistryFailed, "%s: %v", KmsConfigFile, err)
+	}
+
+	kr := &kmsKeyring{
+		Keyring: local,
+		keys:    make(map[string]*kmsKey),
+	}
+	for _, k := range cfg.Key {
+		key, err := newKmsKey(ctx, k)
+		if err != nil {
+			return nil, types.Wrapf(types.ErrCreateAccountRegistryFailed, "kms key %s: %v", k.Name, err)
+		}
+		kr.keys[k.Name] = key
+	}
+	return kr, nil
+}
+
+func newKmsKey(ctx context.Context, k KmsKey) (*kmsKey, error) {
+	var signer kmsSigner
+	switch k.Backend {
+	case KmsBackendVault:
+		mount, key := vaultTransitMount, k.KeyId
+		if i := strings.LastIndex(k.KeyId, "/"); i >= 0 {
+			mount, key = k.KeyId[:i], k.KeyId[i+1:]
+		}
+		signer = &vaultSigner{
+			address:  strings.TrimRight(k.Address, "/"),
+			mount:    mount,
+			key:      key,
+			tokenEnv: k.TokenEnv,
+		}
+	case KmsBackendGcpKms:
+		address := gcpKmsAddress
+		if k.Address != "" {
+			address = strings.TrimRight(k.Address, "/")
+		}
+		signer = &gcpKmsSigner{
+			address:  address,
+			name:     k.KeyId,
+			tokenEnv: k.TokenEnv,
+		}
+	default:
+		return nil, fmt.Errorf("unknown kms backend %s", k.Backend)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, kmsRequestTimeout)
+	defer cancel()
+
+	pemBytes, err := signer.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, order, err := parseKmsPublicKey(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	record, err := keyring.NewOfflineRecord(k.Name, pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kmsKey{
+		signer: signer,
+		record: record,
+		pubKey: pubKey,
+		order:  order,
+	}, nil
+}
+
+func (kr *kmsKeyring) List() ([]*keyring.Record, error) {
+	records, err := kr.Keyring.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range kr.keys {
+		records = append(records, key.record)
+	}
+	return records, nil
+}
+
+func (kr *kmsKeyring) Key(uid string) (*keyring.Record, error) {
+	if key, exists := kr.keys[uid]; exists {
+		return key.record, nil
+	}
+	return kr.Keyring.Key(uid)
+}
+
+func (kr *kmsKeyring) KeyByAddress(address sdktypes.Address) (*keyring.Record, error) {
+	if key := kr.keyByAddress(address); key != nil {
+		return key.record, nil
+	}
+	return kr.Keyring.KeyByAddress(address)
+}
+
+func (kr *kmsKeyring) Sign(uid string, msg []byte) ([]byte, cryptotypes.PubKey, error) {
+	if key, exists := kr.keys[uid]; exists {
+		return key.sign(msg)
+	}
+	return kr.Keyring.Sign(uid, msg)
+}
+
+func (kr *kmsKeyring) SignByAddress(address sdktypes.Address, msg []byte) ([]byte, cryptotypes.PubKey, error) {
+	if key := kr.keyByAddress(address); key != nil {
+		return key.sign(msg)
+	}
+	return kr.Keyring.SignByAddress(address, msg)
+}
+
+func (kr *kmsKeyring) keyByAddress(address sdktypes.Address) *kmsKey {
+	for _, key := range kr.keys {
+		if bytes.Equal(key.pubKey.Address(), address.Bytes()) {
+			return key
+		}
+	}
+	return nil
+}
+
+// sign returns the signature in the 64 bytes r || s form with low s, as cosmos verifies it.
+func (k *kmsKey) sign(msg []byte) ([]byte, cryptotypes.PubKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsRequestTimeout)
+	defer cancel()
+
+	der, err := k.signer.Sign(ctx, msg)
+	if err != nil {
+		return nil, nil, types.Wrap(types.ErrSignedFailed, err)
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, nil, types.Wrapf(types.ErrSignedFailed, "invalid signature from kms: %v", err)
+	}
+	halfOrder := new(big.Int).Rsh(k.order, 1)
+	if sig.S.Cmp(halfOrder) > 0 {
+		sig.S = new(big.Int).Sub(k.order, sig.S)
+	}
+
+	raw := make([]byte, 64)
+	sig.R.FillBytes(raw[:32])
+	sig.S.FillBytes(raw[32:])
+	return raw, k.pubKey, nil
+}
+
+// parseKmsPublicKey parses a PEM encoded secp256k1 or P-256 public key, and returns the order of the curve.
+func parseKmsPublicKey(pemBytes []byte) (cryptotypes.PubKey, *big.Int, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, nil, fmt.Errorf("invalid PEM public key")
+	}
+
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(block.Bytes, &spki); err != nil {
+		return nil, nil, err
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curve); err != nil {
+		return nil, nil, fmt.Errorf("not an EC public key: %v", err)
+	}
+
+	point := spki.PublicKey.RightAlign()
+	if len(point) != 65 || point[0] != 4 {
+		return nil, nil, fmt.Errorf("unexpected EC point of %d bytes", len(point))
+	}
+	compressed := append([]byte{2 + point[64]&1}, point[1:33]...)
+
+	switch {
+	case curve.Equal(oidSecp256k1):
+		return &secp256k1.PubKey{Key: compressed}, secp256k1N, nil
+	case curve.Equal(oidP256):
+		// secp256r1.PubKey only unmarshals from its protobuf encoding
+		pk := &secp256r1.PubKey{}
+		if err := pk.Unmarshal(append([]byte{0x0a, byte(len(compressed))}, compressed...)); err != nil {
+			return nil, nil, err
+		}
+		return pk, elliptic.P256().Params().N, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported curve %s", curve)
+	}
+}
+
+func kmsRequest(ctx context.Context, method string, url string, header http.Header, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s %s", method, url, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, result)
+}
+
+// vaultSigner signs with the transit secrets engine of HashiCorp Vault.
+type vaultSigner struct {
+	address  string
+	mount    string
+	key      string
+	tokenEnv string
+}
+
+func (v *vaultSigner) header() http.Header {
+	header := http.Header{}
+	header.Set("X-Vault-Token", os.Getenv(v.tokenEnv))
+	return header
+}
+
+func (v *vaultSigner) PublicKey(ctx context.Context) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Type          string
+			LatestVersion int `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			}
+		}
+	}
+	err := kmsRequest(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s/keys/%s", v.address, v.mount, v.key), v.header(), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.Type != "ecdsa-p256" {
+		return nil, fmt.Errorf("vault key type %s is not supported, use ecdsa-p256", resp.Data.Type)
+	}
+
+	key, exists := resp.Data.Keys[fmt.Sprint(resp.Data.LatestVersion)]
+	if !exists {
+		return nil, fmt.Errorf("version %d of vault key %s not found", resp.Data.LatestVersion, v.key)
+	}
+	return []byte(key.PublicKey), nil
+}
+
+func (v *vaultSigner) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Signature string
+		}
+	}
+	err := kmsRequest(ctx, http.MethodPost, fmt.Sprintf("%s/v1/%s/sign/%s", v.address, v.mount, v.key), v.header(), map[string]string{
+		"input":                base64.StdEncoding.EncodeToString(msg),
+		"hash_algorithm":       "sha2-256",
+		"marshaling_algorithm": "asn1",
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// vault:v<version>:<base64 signature>
+	parts := strings.Split(resp.Data.Signature, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unexpected vault signature %s", resp.Data.Signature)
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
+
+// gcpKmsSigner signs with an asymmetric key version of Google Cloud KMS.
+type gcpKmsSigner struct {
+	address  string
+	name     string
+	tokenEnv string
+}
+
+func (g *gcpKmsSigner) header() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+os.Getenv(g.tokenEnv))
+	return header
+}
+
+func (g *gcpKmsSigner) PublicKey(ctx context.Context) ([]byte, error) {
+	var resp struct {
+		Pem string
+	}
+	err := kmsRequest(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s/publicKey", g.address, g.name), g.header(), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resp.Pem), nil
+}
+
+func (g *gcpKmsSigner) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	digest := sha256.Sum256(msg)
+
+	var resp struct {
+		Signature string
+	}
+	err := kmsRequest(ctx, http.MethodPost, fmt.Sprintf("%s/v1/%s:asymmetricSign", g.address, g.name), g.header(), map[string]interface{}{
+		"digest": map[string]string{
+			"sha256": base64.StdEncoding.EncodeToString(digest[:]),
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Signature)
+}
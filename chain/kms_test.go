@@ -0,0 +1,135 @@
+package chain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"sao-node/types"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/require"
+)
+
+var oidEcPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+// testKmsSigner signs with a local key, with the high s of the signature if highS.
+type testKmsSigner struct {
+	priv  *ecdsa.PrivateKey
+	oid   asn1.ObjectIdentifier
+	highS bool
+	der   []byte
+}
+
+func (s *testKmsSigner) PublicKey(_ context.Context) ([]byte, error) {
+	// x509 only marshals the curves of the standard library
+	params, err := asn1.Marshal(s.oid)
+	if err != nil {
+		return nil, err
+	}
+	point := elliptic.Marshal(s.priv.Curve, s.priv.X, s.priv.Y)
+	spki, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidEcPublicKey, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki}), nil
+}
+
+func (s *testKmsSigner) Sign(_ context.Context, msg []byte) ([]byte, error) {
+	if s.der != nil {
+		return s.der, nil
+	}
+	digest := sha256.Sum256(msg)
+	r, sig, err := ecdsa.Sign(rand.Reader, s.priv, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	n := s.priv.Curve.Params().N
+	if highS := sig.Cmp(new(big.Int).Rsh(n, 1)) > 0; highS != s.highS {
+		sig = new(big.Int).Sub(n, sig)
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, sig})
+}
+
+func TestKmsKeySign(t *testing.T) {
+	ctx := context.Background()
+	msg := []byte("the sign bytes of a tx")
+
+	for _, curve := range []struct {
+		name  string
+		curve elliptic.Curve
+		oid   asn1.ObjectIdentifier
+	}{
+		{name: "secp256k1", curve: btcec.S256(), oid: oidSecp256k1},
+		{name: "p256", curve: elliptic.P256(), oid: oidP256},
+	} {
+		priv, err := ecdsa.GenerateKey(curve.curve, rand.Reader)
+		require.NoError(t, err)
+		signer := &testKmsSigner{priv: priv, oid: curve.oid}
+
+		pemBytes, err := signer.PublicKey(ctx)
+		require.NoError(t, err)
+		pubKey, order, err := parseKmsPublicKey(pemBytes)
+		require.NoError(t, err, curve.name)
+		require.Equal(t, curve.curve.Params().N, order, curve.name)
+		require.Equal(t, elliptic.MarshalCompressed(curve.curve, priv.X, priv.Y), pubKey.Bytes(), curve.name)
+
+		// cosmos only accepts the signatures with low s
+		key := &kmsKey{signer: signer, pubKey: pubKey, order: order}
+		for _, highS := range []bool{false, true} {
+			signer.highS = highS
+			sig, signedBy, err := key.sign(msg)
+			require.NoError(t, err, curve.name)
+			require.Len(t, sig, 64)
+			require.Equal(t, pubKey, signedBy)
+			require.True(t, new(big.Int).SetBytes(sig[32:]).Cmp(new(big.Int).Rsh(order, 1)) <= 0, curve.name)
+			require.True(t, pubKey.VerifySignature(msg, sig), "%s high s %v", curve.name, highS)
+		}
+
+		signer.der = []byte("not a DER signature")
+		_, _, err = key.sign(msg)
+		require.True(t, types.ErrSignedFailed.Is(err))
+	}
+}
+
+func TestParseKmsPublicKey(t *testing.T) {
+	_, _, err := parseKmsPublicKey([]byte("not a PEM key"))
+	require.Error(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	_, _, err = parseKmsPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.Error(t, err)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.MarshalPKIXPublicKey(&p384.PublicKey)
+	require.NoError(t, err)
+	_, _, err = parseKmsPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.Error(t, err)
+
+	// a P-256 key of the KMS is usually marshaled by x509
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.MarshalPKIXPublicKey(&p256.PublicKey)
+	require.NoError(t, err)
+	pubKey, _, err := parseKmsPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	require.Equal(t, elliptic.MarshalCompressed(elliptic.P256(), p256.X, p256.Y), pubKey.Bytes())
+}
@@ -2,13 +2,20 @@ package chain
 
 import (
 	"context"
+	"fmt"
 	"sao-node/types"
 
 	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	nodetypes "github.com/SaoNetwork/sao/x/node/types"
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
 
 	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
 )
 
+// how many metadata are queried at once by ListMetadata
+const metadataPageSize = 100
+
 func (c *ChainSvc) GetMeta(ctx context.Context, dataId string) (*modeltypes.QueryGetMetadataResponse, error) {
 	resp, err := c.modelClient.Metadata(ctx, &modeltypes.QueryGetMetadataRequest{
 		DataId: dataId,
@@ -19,6 +26,31 @@ func (c *ChainSvc) GetMeta(ctx context.Context, dataId string) (*modeltypes.Quer
 	return resp, nil
 }
 
+// ListMetadata returns the metadata of all the models owned by owner. The chain has no index of the models by owner,
+// so the metadata of every model is paged through.
+func (c *ChainSvc) ListMetadata(ctx context.Context, owner string) ([]modeltypes.Metadata, error) {
+	var metas []modeltypes.Metadata
+	var key []byte
+	for {
+		resp, err := c.modelClient.MetadataAll(ctx, &modeltypes.QueryAllMetadataRequest{
+			Pagination: &query.PageRequest{Key: key, Limit: metadataPageSize},
+		})
+		if err != nil {
+			return nil, types.Wrap(types.ErrQueryMetadataFailed, err)
+		}
+		for _, meta := range resp.Metadata {
+			if meta.Owner == owner {
+				metas = append(metas, meta)
+			}
+		}
+
+		if resp.Pagination == nil || len(resp.Pagination.NextKey) == 0 {
+			return metas, nil
+		}
+		key = resp.Pagination.NextKey
+	}
+}
+
 func (c *ChainSvc) QueryMetadata(ctx context.Context, req *types.MetadataProposal, height int64) (*saotypes.QueryMetadataResponse, error) {
 	clientctx := c.cosmos.Context()
 	if height > 0 {
@@ -47,6 +79,91 @@ func (c *ChainSvc) QueryMetadata(ctx context.Context, req *types.MetadataProposa
 	return resp, nil
 }
 
+// QueryMetadataAs queries the metadata the same as QueryMetadata does, but checks the permission of did instead of
+// verifying the signature of the proposal, the caller should have verified that the proposal is made on behalf of did.
+func (c *ChainSvc) QueryMetadataAs(ctx context.Context, proposal saotypes.QueryProposal, did string, height int64) (*saotypes.QueryMetadataResponse, error) {
+	clientctx := c.cosmos.Context()
+	if height > 0 {
+		clientctx = clientctx.WithHeight(height)
+	}
+	modelClient := modeltypes.NewQueryClient(clientctx)
+	orderClient := ordertypes.NewQueryClient(clientctx)
+	nodeClient := nodetypes.NewQueryClient(clientctx)
+
+	dataId := proposal.Keyword
+	if proposal.KeywordType > 1 {
+		// the models are indexed by the did which owns them
+		resp, err := modelClient.Model(ctx, &modeltypes.QueryGetModelRequest{
+			Key: fmt.Sprintf("%s-%s-%s", did, proposal.Keyword, proposal.GroupId),
+		})
+		if err != nil {
+			return nil, types.Wrap(types.ErrQueryMetadataFailed, err)
+		}
+		dataId = resp.Model.Data
+	}
+
+	metaResp, err := modelClient.Metadata(ctx, &modeltypes.QueryGetMetadataRequest{DataId: dataId})
+	if err != nil {
+		return nil, types.Wrap(types.ErrQueryMetadataFailed, err)
+	}
+	meta := metaResp.Metadata
+
+	permitted := meta.Owner == did
+	for _, d := range append(meta.ReadwriteDids, meta.ReadonlyDids...) {
+		permitted = permitted || d == did
+	}
+	if !permitted {
+		return nil, types.Wrapf(types.ErrNoPermission, "%s has no permission on %s", did, dataId)
+	}
+
+	orderResp, err := orderClient.Order(ctx, &ordertypes.QueryGetOrderRequest{Id: meta.OrderId})
+	if err != nil {
+		return nil, types.Wrap(types.ErrQueryMetadataFailed, err)
+	}
+	order := orderResp.Order
+
+	shards := make(map[string]*saotypes.ShardMeta)
+	for provider, shard := range order.Shards {
+		nodeResp, err := nodeClient.Node(ctx, &nodetypes.QueryGetNodeRequest{Creator: provider})
+		if err != nil {
+			continue
+		}
+		shards[provider] = &saotypes.ShardMeta{
+			ShardId:  shard.Id,
+			Peer:     nodeResp.Node.Peer,
+			Cid:      shard.Cid,
+			Provider: provider,
+		}
+	}
+
+	return &saotypes.QueryMetadataResponse{
+		Metadata: saotypes.Metadata{
+			DataId:     meta.DataId,
+			Owner:      meta.Owner,
+			Alias:      meta.Alias,
+			GroupId:    meta.GroupId,
+			OrderId:    meta.OrderId,
+			Tags:       meta.Tags,
+			Cid:        meta.Cid,
+			Commits:    meta.Commits,
+			ExtendInfo: meta.ExtendInfo,
+			Update:     meta.Update,
+			Commit:     meta.Commit,
+			Rule:       meta.Rule,
+			Duration:   meta.Duration,
+			CreatedAt:  meta.CreatedAt,
+			Provider:   order.Provider,
+			Expire:     order.Expire,
+			Status:     order.Status,
+			Replica:    order.Replica,
+			Amount:     order.Amount,
+			Size_:      order.Size_,
+			Operation:  order.Operation,
+		},
+		Shards: shards,
+	}, nil
+}
+
 func (c *ChainSvc) UpdatePermission(ctx context.Context, signer string, proposal *types.PermissionProposal) (string, error) {
 	signerAcc, err := c.cosmos.Account(signer)
 	if err != nil {
@@ -63,13 +180,9 @@ func (c *ChainSvc) UpdatePermission(ctx context.Context, signer string, proposal
 		},
 	}
 
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
-	if err != nil {
-		return "", types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	// log.Debug("MsgStore result: ", txResp)
-	if txResp.TxResponse.Code != 0 {
-		return "", types.Wrapf(types.ErrTxProcessFailed, "MsgUpdataPermission tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgUpdataPermission", txResp, err); err != nil {
+		return "", err
 	}
 
 	return txResp.TxResponse.TxHash, nil
@@ -0,0 +1,290 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sao-node/types"
+	"time"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const saoEventsQuery = "tm.event='Tx' AND message.module='sao'"
+
+const (
+	// interval of searching the sao txs missed by the subscription, such as while the websocket reconnects
+	saoEventsReplayInterval = 30 * time.Second
+	saoEventsPageSize       = 100
+)
+
+// ModelUpdate is a data model changed on chain by a completed commit, a permission update, a renewal or a
+// termination. Alias is only set for the commits, Permission only for the permission updates.
+type ModelUpdate struct {
+	Owner      string
+	DataId     string
+	Alias      string
+	Permission *saotypes.PermissionProposal
+	Height     int64
+	TxHash     string
+}
+
+// ShardAssignment is the shards of an order assigned to the storage nodes by a MsgStore or a MsgReady tx.
+type ShardAssignment struct {
+	OrderId uint64
+	DataId  string
+	// the shard cids by the assigned storage nodes
+	Shards map[string]string
+	TxType types.AssignTxType
+	Height int64
+	TxHash string
+}
+
+// SaoEvent is what a successful sao tx changed.
+type SaoEvent struct {
+	Height      int64
+	TxHash      string
+	Updates     []ModelUpdate
+	Assignments []ShardAssignment
+}
+
+// EventCursor persists the height the sao events are sent up to, so the events of the blocks produced while the
+// node was down are replayed once it is up again.
+type EventCursor interface {
+	// Height returns the persisted height, 0 if none
+	Height(ctx context.Context) (int64, error)
+	SetHeight(ctx context.Context, height int64) error
+}
+
+// SubscribeSaoEvents sends the events of the successful sao txs to ch until ctx is done, ch is closed once the
+// subscription ends. The txs after the height of cursor are replayed first, then the blocks are searched every
+// saoEventsReplayInterval for the txs the subscription missed, a tx may be sent twice across restarts.
+func (c *ChainSvc) SubscribeSaoEvents(ctx context.Context, cursor EventCursor, ch chan<- SaoEvent) error {
+	if !c.listener.IsRunning() {
+		err := c.listener.Start()
+		if err != nil {
+			return err
+		}
+	}
+
+	synced, err := cursor.Height(ctx)
+	if err != nil {
+		return err
+	}
+	if synced == 0 {
+		// nothing to replay on the first start
+		if synced, err = c.GetLastHeight(ctx); err != nil {
+			return err
+		}
+	}
+
+	events, err := c.listener.Subscribe(ctx, subscriber, saoEventsQuery)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(ch)
+
+		// the txs sent by the subscription above the synced height
+		sent := make(map[string]int64)
+		send := func(ev SaoEvent) bool {
+			if ev.Height <= synced || sent[ev.TxHash] != 0 {
+				return true
+			}
+			sent[ev.TxHash] = ev.Height
+			select {
+			case ch <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		replay := func() bool {
+			height, err := c.GetLastHeight(ctx)
+			if err != nil {
+				log.Warnf("replay sao events: %v", err)
+				return true
+			}
+			if height <= synced {
+				return true
+			}
+			replayed, err := c.searchSaoEvents(ctx, synced, height)
+			if err != nil {
+				log.Warnf("replay sao events after height %d: %v", synced, err)
+				return true
+			}
+			for _, ev := range replayed {
+				if !send(ev) {
+					return false
+				}
+			}
+			synced = height
+			for hash, h := range sent {
+				if h <= synced {
+					delete(sent, hash)
+				}
+			}
+			if err := cursor.SetHeight(ctx, synced); err != nil {
+				log.Warnf("save sao events cursor %d: %v", synced, err)
+			}
+			return true
+		}
+
+		ticker := time.NewTicker(saoEventsReplayInterval)
+		defer ticker.Stop()
+		if !replay() {
+			return
+		}
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					log.Warn("sao events subscription closed")
+					return
+				}
+				data, ok := ev.Data.(tmtypes.EventDataTx)
+				if !ok || data.Result.Code != 0 {
+					continue
+				}
+				txHash := fmt.Sprintf("%X", tmtypes.Tx(data.Tx).Hash())
+				if !send(c.saoEvent(ctx, data.Tx, data.Result, data.Height, txHash)) {
+					return
+				}
+			case <-ticker.C:
+				if !replay() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// searchSaoEvents returns the events of the successful sao txs in the blocks after from up to to, in the order
+// of the blocks.
+func (c *ChainSvc) searchSaoEvents(ctx context.Context, from int64, to int64) ([]SaoEvent, error) {
+	query := fmt.Sprintf("%s AND tx.height>%d AND tx.height<=%d", saoEventsQuery, from, to)
+	perPage := saoEventsPageSize
+	var events []SaoEvent
+	for page, found := 1, 0; ; page++ {
+		p := page
+		result, err := c.cosmos.RPC.TxSearch(ctx, query, false, &p, &perPage, "asc")
+		if err != nil {
+			return nil, types.Wrap(types.ErrTxQueryFailed, err)
+		}
+		for _, resultTx := range result.Txs {
+			if resultTx.TxResult.Code != 0 {
+				continue
+			}
+			events = append(events, c.saoEvent(ctx, resultTx.Tx, resultTx.TxResult, resultTx.Height, resultTx.Hash.String()))
+		}
+		found += len(result.Txs)
+		if len(result.Txs) == 0 || found >= result.TotalCount {
+			return events, nil
+		}
+	}
+}
+
+func (c *ChainSvc) saoEvent(ctx context.Context, txBytes []byte, result abcitypes.ResponseDeliverTx, height int64, txHash string) SaoEvent {
+	ev := SaoEvent{Height: height, TxHash: txHash}
+
+	var txb tx.Tx
+	if err := txb.Unmarshal(txBytes); err != nil {
+		log.Warnf("decode sao tx: %v", err)
+		return ev
+	}
+	var msgData sdktypes.TxMsgData
+	if err := msgData.Unmarshal(result.Data); err != nil {
+		log.Warnf("decode sao tx %s result: %v", txHash, err)
+	}
+	response := func(i int, resp interface{ Unmarshal([]byte) error }) bool {
+		return i < len(msgData.MsgResponses) && resp.Unmarshal(msgData.MsgResponses[i].Value) == nil
+	}
+
+	for i, msg := range txb.Body.Messages {
+		switch msg.TypeUrl {
+		case sdktypes.MsgTypeURL(&saotypes.MsgStore{}):
+			var m saotypes.MsgStore
+			var resp saotypes.MsgStoreResponse
+			if err := m.Unmarshal(msg.Value); err == nil && response(i, &resp) && len(resp.Shards) > 0 {
+				ev.Assignments = append(ev.Assignments, shardAssignment(resp.OrderId, m.Proposal.DataId, resp.Shards, types.AssignTxTypeStore))
+			}
+		case sdktypes.MsgTypeURL(&saotypes.MsgReady{}):
+			var resp saotypes.MsgReadyResponse
+			if !response(i, &resp) || len(resp.Shards) == 0 {
+				continue
+			}
+			dataId := ""
+			order, err := c.GetOrder(ctx, resp.OrderId)
+			if err != nil {
+				log.Warnf("shard assignment of order %d: %v", resp.OrderId, err)
+			} else if order.Metadata != nil {
+				dataId = order.Metadata.DataId
+			}
+			ev.Assignments = append(ev.Assignments, shardAssignment(resp.OrderId, dataId, resp.Shards, types.AssignTxTypeReady))
+		case sdktypes.MsgTypeURL(&saotypes.MsgComplete{}):
+			var m saotypes.MsgComplete
+			if err := m.Unmarshal(msg.Value); err != nil {
+				continue
+			}
+			order, err := c.GetOrder(ctx, m.OrderId)
+			if err != nil {
+				log.Warnf("model update of order %d: %v", m.OrderId, err)
+				continue
+			}
+			if order.Metadata != nil {
+				ev.Updates = append(ev.Updates, ModelUpdate{
+					Owner:  order.Owner,
+					DataId: order.Metadata.DataId,
+					Alias:  order.Metadata.Alias,
+				})
+			}
+		case sdktypes.MsgTypeURL(&saotypes.MsgUpdataPermission{}):
+			var m saotypes.MsgUpdataPermission
+			if err := m.Unmarshal(msg.Value); err == nil {
+				proposal := m.Proposal
+				ev.Updates = append(ev.Updates, ModelUpdate{Owner: m.Proposal.Owner, DataId: m.Proposal.DataId, Permission: &proposal})
+			}
+		case sdktypes.MsgTypeURL(&saotypes.MsgTerminate{}):
+			var m saotypes.MsgTerminate
+			if err := m.Unmarshal(msg.Value); err == nil {
+				ev.Updates = append(ev.Updates, ModelUpdate{Owner: m.Proposal.Owner, DataId: m.Proposal.DataId})
+			}
+		case sdktypes.MsgTypeURL(&saotypes.MsgRenew{}):
+			var m saotypes.MsgRenew
+			if err := m.Unmarshal(msg.Value); err == nil {
+				for _, dataId := range m.Proposal.Data {
+					ev.Updates = append(ev.Updates, ModelUpdate{Owner: m.Proposal.Owner, DataId: dataId})
+				}
+			}
+		}
+	}
+
+	for i := range ev.Updates {
+		ev.Updates[i].Height = height
+		ev.Updates[i].TxHash = txHash
+	}
+	for i := range ev.Assignments {
+		ev.Assignments[i].Height = height
+		ev.Assignments[i].TxHash = txHash
+	}
+	return ev
+}
+
+func shardAssignment(orderId uint64, dataId string, metas map[string]*saotypes.ShardMeta, txType types.AssignTxType) ShardAssignment {
+	shards := make(map[string]string, len(metas))
+	for provider, meta := range metas {
+		if meta.Provider != "" {
+			provider = meta.Provider
+		}
+		shards[provider] = meta.Cid
+	}
+	return ShardAssignment{OrderId: orderId, DataId: dataId, Shards: shards, TxType: txType}
+}
@@ -0,0 +1,60 @@
+package chain
+
+import (
+	"context"
+	"sao-node/types"
+	"testing"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/stretchr/testify/require"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+)
+
+func TestSaoEvent(t *testing.T) {
+	anyOf := func(msg interface{ Marshal() ([]byte, error) }, typeUrl string) *codectypes.Any {
+		bs, err := msg.Marshal()
+		require.NoError(t, err)
+		return &codectypes.Any{TypeUrl: typeUrl, Value: bs}
+	}
+
+	store := &saotypes.MsgStore{Proposal: saotypes.Proposal{DataId: "data1"}}
+	terminate := &saotypes.MsgTerminate{Proposal: saotypes.TerminateProposal{Owner: "did:key:owner", DataId: "data2"}}
+	txb := tx.Tx{Body: &tx.TxBody{Messages: []*codectypes.Any{
+		anyOf(store, sdktypes.MsgTypeURL(store)),
+		anyOf(terminate, sdktypes.MsgTypeURL(terminate)),
+	}}}
+	txBytes, err := txb.Marshal()
+	require.NoError(t, err)
+
+	storeResp := &saotypes.MsgStoreResponse{OrderId: 7, Shards: map[string]*saotypes.ShardMeta{
+		"sao1node": {Cid: "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy", Provider: "sao1node"},
+	}}
+	msgData := sdktypes.TxMsgData{MsgResponses: []*codectypes.Any{
+		anyOf(storeResp, "/saonetwork.sao.sao.MsgStoreResponse"),
+		anyOf(&saotypes.MsgTerminateResponse{}, "/saonetwork.sao.sao.MsgTerminateResponse"),
+	}}
+	data, err := msgData.Marshal()
+	require.NoError(t, err)
+
+	c := &ChainSvc{}
+	ev := c.saoEvent(context.Background(), txBytes, abcitypes.ResponseDeliverTx{Data: data}, 10, "HASH")
+	require.Equal(t, []ShardAssignment{{
+		OrderId: 7,
+		DataId:  "data1",
+		Shards:  map[string]string{"sao1node": "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"},
+		TxType:  types.AssignTxTypeStore,
+		Height:  10,
+		TxHash:  "HASH",
+	}}, ev.Assignments)
+	require.Equal(t, []ModelUpdate{{Owner: "did:key:owner", DataId: "data2", Height: 10, TxHash: "HASH"}}, ev.Updates)
+
+	// the store proposals not readied in the same tx assign no shards
+	msgData.MsgResponses[0] = anyOf(&saotypes.MsgStoreResponse{OrderId: 8}, "/saonetwork.sao.sao.MsgStoreResponse")
+	data, err = msgData.Marshal()
+	require.NoError(t, err)
+	ev = c.saoEvent(context.Background(), txBytes, abcitypes.ResponseDeliverTx{Data: data}, 10, "HASH")
+	require.Empty(t, ev.Assignments)
+}
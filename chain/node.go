@@ -8,6 +8,7 @@ import (
 	"time"
 
 	nodetypes "github.com/SaoNetwork/sao/x/node/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
 )
 
 func (c *ChainSvc) Create(ctx context.Context, creator string) (string, error) {
@@ -20,12 +21,9 @@ func (c *ChainSvc) Create(ctx context.Context, creator string) (string, error) {
 		Creator: creator,
 	}
 
-	txResp, err := c.cosmos.BroadcastTx(ctx, account, msg)
-	if err != nil {
-		return "", types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", types.Wrapf(types.ErrTxProcessFailed, "MsgCreate tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, account, msg)
+	if err := txError("MsgCreate", txResp, err); err != nil {
+		return "", err
 	}
 	return txResp.TxResponse.TxHash, nil
 }
@@ -41,12 +39,9 @@ func (c *ChainSvc) Reset(ctx context.Context, creator string, peerInfo string, s
 		Peer:    peerInfo,
 		Status:  status,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, account, msg)
-	if err != nil {
-		return "", types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", types.Wrapf(types.ErrTxProcessFailed, "MsgReset tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, account, msg)
+	if err := txError("MsgReset", txResp, err); err != nil {
+		return "", err
 	}
 	return txResp.TxResponse.TxHash, nil
 }
@@ -60,12 +55,9 @@ func (c *ChainSvc) ClaimReward(ctx context.Context, creator string) (string, err
 	msg := &nodetypes.MsgClaimReward{
 		Creator: creator,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, account, msg)
-	if err != nil {
-		return "", types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", types.Wrapf(types.ErrTxProcessFailed, "MsgClaimReward tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, account, msg)
+	if err := txError("MsgClaimReward", txResp, err); err != nil {
+		return "", err
 	}
 	return txResp.TxResponse.TxHash, nil
 }
@@ -126,6 +118,18 @@ func (c *ChainSvc) ShowNodeInfo(ctx context.Context, creator string) {
 	}
 }
 
+// GetReward returns the claimable storage reward of the node.
+func (c *ChainSvc) GetReward(ctx context.Context, creator string) (sdktypes.Coin, error) {
+	resp, err := c.nodeClient.Pledge(ctx, &nodetypes.QueryGetPledgeRequest{
+		Creator: creator,
+	})
+	if err != nil {
+		return sdktypes.Coin{}, types.Wrap(types.ErrQueryNodeFailed, err)
+	}
+	reward, _ := resp.Pledge.Reward.TruncateDecimal()
+	return reward, nil
+}
+
 func (c *ChainSvc) ListNodes(ctx context.Context) ([]nodetypes.Node, error) {
 	resp, err := c.nodeClient.NodeAll(ctx, &nodetypes.QueryAllNodeRequest{Status: 0})
 	if err != nil {
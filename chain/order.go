@@ -2,11 +2,17 @@ package chain
 
 import (
 	"context"
+	"fmt"
 	"sao-node/types"
 	"time"
 
 	ordertypes "github.com/SaoNetwork/sao/x/order/types"
 	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/ignite/cli/ignite/pkg/cosmosaccount"
 	"github.com/ipfs/go-cid"
 )
 
@@ -15,6 +21,55 @@ const (
 	Blocktime  = 1 * time.Second
 )
 
+var orderStatusString = map[int32]string{
+	ordertypes.OrderPending:    "pending",
+	ordertypes.OrderInProgress: "inProgress",
+	ordertypes.OrderUnexpected: "unexpected",
+	ordertypes.OrderCompleted:  "completed",
+	ordertypes.OrderCanceled:   "canceled",
+	ordertypes.OrderExpired:    "expired",
+	ordertypes.OrderDataReady:  "dataReady",
+	ordertypes.OrderTerminated: "terminated",
+}
+
+// OrderStatusString returns the name of the status of an order on chain.
+func OrderStatusString(status int32) string {
+	if s, ok := orderStatusString[status]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown(%d)", status)
+}
+
+// price of storing a byte for a block by a replica, fixed by the chain
+var orderPrice = sdktypes.NewDecWithPrec(1, 3)
+
+// EstimateOrderFee returns the amount the chain charges the owner of an order, duration is in blocks.
+func (c *ChainSvc) EstimateOrderFee(ctx context.Context, size uint64, duration uint64, replica int32) (sdktypes.Coin, error) {
+	resp, err := c.stakingClient.Params(ctx, &stakingtypes.QueryParamsRequest{})
+	if err != nil {
+		return sdktypes.Coin{}, types.Wrap(types.ErrQueryParamsFailed, err)
+	}
+
+	if size == 0 {
+		size = 1
+	}
+	amount := orderPrice.MulInt64(int64(size)).MulInt64(int64(replica)).MulInt64(int64(duration))
+	coin, _ := sdktypes.NewDecCoinFromDec(resp.Params.BondDenom, amount).TruncateDecimal()
+	return coin, nil
+}
+
+// EstimateGas returns the gas wanted per message by the last tx of the msg type, or the default gas limit before any of them.
+// The txs can't be simulated ahead as they carry the proposals signed by the clients.
+func (c *ChainSvc) EstimateGas(msg sdktypes.Msg) uint64 {
+	c.gasLock.Lock()
+	defer c.gasLock.Unlock()
+
+	if gas, ok := c.gasWanted[sdktypes.MsgTypeURL(msg)]; ok {
+		return gas
+	}
+	return flags.DefaultGasLimit
+}
+
 type OrderCompleteResult struct {
 	Result string
 }
@@ -29,12 +84,9 @@ func (c *ChainSvc) OrderReady(ctx context.Context, provider string, orderId uint
 		OrderId: orderId,
 		Creator: provider,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
-	if err != nil {
-		return saotypes.MsgReadyResponse{}, "", -1, types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return saotypes.MsgReadyResponse{}, "", -1, types.Wrapf(types.ErrTxProcessFailed, "MsgReady tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgReady", txResp, err); err != nil {
+		return saotypes.MsgReadyResponse{}, "", -1, err
 	}
 	var readyResp saotypes.MsgReadyResponse
 	err = txResp.Decode(&readyResp)
@@ -45,15 +97,17 @@ func (c *ChainSvc) OrderReady(ctx context.Context, provider string, orderId uint
 	return readyResp, txResp.TxResponse.TxHash, txResp.TxResponse.Height, nil
 }
 
+// StoreOrder submits the client proposal signed by signer, a key name or an address. The order is readied in the
+// same tx only if signer is the provider of the proposal, otherwise the response has no shards.
 func (c *ChainSvc) StoreOrder(ctx context.Context, signer string, clientProposal *types.OrderStoreProposal) (saotypes.MsgStoreResponse, string, int64, error) {
-	signerAcc, err := c.cosmos.Account(signer)
+	signerAcc, creator, err := c.signerAccount(signer)
 	if err != nil {
-		return saotypes.MsgStoreResponse{}, "", -1, types.Wrap(types.ErrAccountNotFound, err)
+		return saotypes.MsgStoreResponse{}, "", -1, err
 	}
 
 	// TODO: Cid
 	msg := &saotypes.MsgStore{
-		Creator:  signer,
+		Creator:  creator,
 		Proposal: clientProposal.Proposal,
 		JwsSignature: saotypes.JwsSignature{
 			Protected: clientProposal.JwsSignature.Protected,
@@ -61,13 +115,16 @@ func (c *ChainSvc) StoreOrder(ctx context.Context, signer string, clientProposal
 		},
 	}
 
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
-	if err != nil {
-		return saotypes.MsgStoreResponse{}, "", -1, types.Wrap(types.ErrTxProcessFailed, err)
+	if c.storeBatcher != nil {
+		return c.submitStore(ctx, signerAcc, creator, msg)
 	}
-	// log.Debug("MsgStore result: ", txResp)
-	if txResp.TxResponse.Code != 0 {
-		return saotypes.MsgStoreResponse{}, "", -1, types.Wrapf(types.ErrTxProcessFailed, "MsgStore tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	return c.storeOrder(ctx, signerAcc, msg)
+}
+
+func (c *ChainSvc) storeOrder(ctx context.Context, signerAcc cosmosaccount.Account, msg *saotypes.MsgStore) (saotypes.MsgStoreResponse, string, int64, error) {
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgStore", txResp, err); err != nil {
+		return saotypes.MsgStoreResponse{}, "", -1, err
 	}
 	var storeResp saotypes.MsgStoreResponse
 	err = txResp.Decode(&storeResp)
@@ -77,10 +134,12 @@ func (c *ChainSvc) StoreOrder(ctx context.Context, signer string, clientProposal
 	return storeResp, txResp.TxResponse.TxHash, txResp.TxResponse.Height, nil
 }
 
-func (c *ChainSvc) CompleteOrder(ctx context.Context, creator string, orderId uint64, cid cid.Cid, size uint64) (string, int64, error) {
-	signerAcc, err := c.cosmos.Account(creator)
+// CompleteOrder completes the shard of signer, a key name or an address of the shard provider. The height is 0
+// unless the tx is broadcast in the block mode, see SetBroadcastPolicy.
+func (c *ChainSvc) CompleteOrder(ctx context.Context, signer string, orderId uint64, cid cid.Cid, size uint64) (string, int64, error) {
+	signerAcc, creator, err := c.signerAccount(signer)
 	if err != nil {
-		return "", -1, types.Wrap(types.ErrAccountNotFound, err)
+		return "", -1, err
 	}
 
 	msg := &saotypes.MsgComplete{
@@ -89,12 +148,9 @@ func (c *ChainSvc) CompleteOrder(ctx context.Context, creator string, orderId ui
 		Cid:     cid.String(),
 		Size_:   size,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
-	if err != nil {
-		return "", -1, types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", -1, types.Wrapf(types.ErrTxProcessFailed, "MsgComplete tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTxNoWait(ctx, signerAcc, msg)
+	if err := txError("MsgComplete", txResp, err); err != nil {
+		return "", -1, err
 	}
 	return txResp.TxResponse.TxHash, txResp.TxResponse.Height, nil
 }
@@ -110,12 +166,9 @@ func (c *ChainSvc) RenewOrder(ctx context.Context, creator string, orderRenewPro
 		Proposal:     orderRenewProposal.Proposal,
 		JwsSignature: orderRenewProposal.JwsSignature,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
-	if err != nil {
-		return "", nil, types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", nil, types.Wrapf(types.ErrTxProcessFailed, "MsgRenew tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgRenew", txResp, err); err != nil {
+		return "", nil, err
 	}
 	var renewResp saotypes.MsgRenewResponse
 	err = txResp.Decode(&renewResp)
@@ -134,12 +187,9 @@ func (c *ChainSvc) MigrateOrder(ctx context.Context, creator string, dataIds []s
 		Creator: creator,
 		Data:    dataIds,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
-	if err != nil {
-		return "", nil, -1, types.Wrap(types.ErrTxProcessFailed, err)
-	}
-	if txResp.TxResponse.Code != 0 {
-		return "", nil, -1, types.Wrapf(types.ErrTxProcessFailed, "MsgMigrate tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgMigrate", txResp, err); err != nil {
+		return "", nil, -1, err
 	}
 	var migrateResp saotypes.MsgMigrateResponse
 	err = txResp.Decode(&migrateResp)
@@ -160,12 +210,27 @@ func (c *ChainSvc) TerminateOrder(ctx context.Context, creator string, terminate
 		Proposal:     terminateProposal.Proposal,
 		JwsSignature: terminateProposal.JwsSignature,
 	}
-	txResp, err := c.cosmos.BroadcastTx(ctx, signerAcc, msg)
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgTerminate", txResp, err); err != nil {
+		return "", err
+	}
+	return txResp.TxResponse.TxHash, nil
+}
+
+// CancelOrder cancels an order which isn't completed in time, signer must be the creator of the order.
+func (c *ChainSvc) CancelOrder(ctx context.Context, signer string, orderId uint64) (string, error) {
+	signerAcc, creator, err := c.signerAccount(signer)
 	if err != nil {
-		return "", types.Wrap(types.ErrTxProcessFailed, err)
+		return "", err
 	}
-	if txResp.TxResponse.Code != 0 {
-		return "", types.Wrapf(types.ErrTxProcessFailed, "MsgTerminate tx hash=%s, code=%d", txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+
+	msg := &saotypes.MsgCancel{
+		Creator: creator,
+		OrderId: orderId,
+	}
+	txResp, err := c.broadcastTx(ctx, signerAcc, msg)
+	if err := txError("MsgCancel", txResp, err); err != nil {
+		return "", err
 	}
 	return txResp.TxResponse.TxHash, nil
 }
@@ -180,6 +245,40 @@ func (c *ChainSvc) GetOrder(ctx context.Context, orderId uint64) (*ordertypes.Or
 	return &queryResp.Order, nil
 }
 
+// StoreTx is a MsgStore tx on chain.
+type StoreTx struct {
+	Msg    saotypes.MsgStore
+	Hash   string
+	Height int64
+}
+
+// GetStoreTx returns the MsgStore tx which created the order, the tx is found by the new-order event of the order.
+func (c *ChainSvc) GetStoreTx(ctx context.Context, orderId uint64) (*StoreTx, error) {
+	query := fmt.Sprintf("%s.%s='%d'", ordertypes.NewOrderEventType, ordertypes.EventOrderId, orderId)
+	page, perPage := 1, 1
+	result, err := c.cosmos.RPC.TxSearch(ctx, query, false, &page, &perPage, "asc")
+	if err != nil {
+		return nil, types.Wrap(types.ErrTxQueryFailed, err)
+	}
+	for _, resultTx := range result.Txs {
+		var txb tx.Tx
+		if err := txb.Unmarshal(resultTx.Tx); err != nil {
+			return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+		for _, msg := range txb.Body.Messages {
+			if msg.TypeUrl != sdktypes.MsgTypeURL(&saotypes.MsgStore{}) {
+				continue
+			}
+			storeTx := &StoreTx{Hash: resultTx.Hash.String(), Height: resultTx.Height}
+			if err := storeTx.Msg.Unmarshal(msg.Value); err != nil {
+				return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+			}
+			return storeTx, nil
+		}
+	}
+	return nil, types.Wrapf(types.ErrTxQueryFailed, "no MsgStore tx of order %d", orderId)
+}
+
 // wsevent
 //func (cs *ChainSvc) SubscribeOrderComplete(ctx context.Context, orderId uint64, doneChan chan OrderCompleteResult) error {
 //	log.Debugf("SubscribeOrderComplete %s", QueryOrderComplete(orderId))
@@ -0,0 +1,186 @@
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sao-node/types"
+	"sync"
+	"time"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ignite/cli/ignite/pkg/cosmosaccount"
+	"github.com/ignite/cli/ignite/pkg/cosmosclient"
+)
+
+// storeBatcher bundles the MsgStore messages of a signer into one tx. The messages submitted while a batch tx
+// of the signer is broadcasting, or within the window after the first one, go into the next batch.
+type storeBatcher struct {
+	size   int
+	window time.Duration
+
+	lk      sync.Mutex
+	pending map[string][]*storeRequest
+	running map[string]bool
+}
+
+type storeRequest struct {
+	msg  *saotypes.MsgStore
+	done chan storeResult
+}
+
+type storeResult struct {
+	resp   saotypes.MsgStoreResponse
+	txHash string
+	height int64
+	err    error
+}
+
+// EnableStoreBatching bundles up to size MsgStore messages of a signer into one tx, the first message of a batch
+// waits window for the others.
+func (c *ChainSvc) EnableStoreBatching(size int, window time.Duration) {
+	c.storeBatcher = &storeBatcher{
+		size:    size,
+		window:  window,
+		pending: make(map[string][]*storeRequest),
+		running: make(map[string]bool),
+	}
+}
+
+func (c *ChainSvc) submitStore(ctx context.Context, account cosmosaccount.Account, creator string, msg *saotypes.MsgStore) (saotypes.MsgStoreResponse, string, int64, error) {
+	b := c.storeBatcher
+	req := &storeRequest{msg: msg, done: make(chan storeResult, 1)}
+
+	b.lk.Lock()
+	b.pending[creator] = append(b.pending[creator], req)
+	if !b.running[creator] {
+		b.running[creator] = true
+		go c.runStoreBatches(account, creator)
+	}
+	b.lk.Unlock()
+
+	select {
+	case res := <-req.done:
+		return res.resp, res.txHash, res.height, res.err
+	case <-ctx.Done():
+	}
+
+	// the message can only be withdrawn before its batch is broadcast
+	b.lk.Lock()
+	for i, r := range b.pending[creator] {
+		if r == req {
+			b.pending[creator] = append(b.pending[creator][:i], b.pending[creator][i+1:]...)
+			b.lk.Unlock()
+			return saotypes.MsgStoreResponse{}, "", -1, types.Wrap(types.ErrTxProcessFailed, ctx.Err())
+		}
+	}
+	b.lk.Unlock()
+	res := <-req.done
+	return res.resp, res.txHash, res.height, res.err
+}
+
+func (c *ChainSvc) runStoreBatches(account cosmosaccount.Account, creator string) {
+	b := c.storeBatcher
+	for {
+		if b.window > 0 {
+			time.Sleep(b.window)
+		}
+
+		b.lk.Lock()
+		batch := b.pending[creator]
+		if len(batch) == 0 {
+			delete(b.pending, creator)
+			delete(b.running, creator)
+			b.lk.Unlock()
+			return
+		}
+		if len(batch) > b.size {
+			batch = batch[:b.size]
+		}
+		b.pending[creator] = b.pending[creator][len(batch):]
+		b.lk.Unlock()
+
+		c.broadcastStores(context.Background(), account, batch)
+	}
+}
+
+// broadcastStores broadcasts the batch in one tx. A tx rejected before its block, or failed in it, fails all its
+// messages, so they are broadcast again one by one to fail only the invalid ones. A tx that may still be included,
+// e.g. not included in time, fails the whole batch instead, resending it would store and charge the orders twice.
+func (c *ChainSvc) broadcastStores(ctx context.Context, account cosmosaccount.Account, batch []*storeRequest) {
+	if len(batch) > 1 {
+		msgs := make([]sdktypes.Msg, len(batch))
+		for i, req := range batch {
+			msgs[i] = req.msg
+		}
+		txResp, err := c.broadcastTx(ctx, account, msgs...)
+		if err == nil && txResp.TxResponse.Code == 0 {
+			resps, err := decodeStoreResponses(txResp, len(batch))
+			for i, req := range batch {
+				res := storeResult{txHash: txResp.TxResponse.TxHash, height: txResp.TxResponse.Height, err: err}
+				if err == nil {
+					res.resp = resps[i]
+				}
+				req.done <- res
+			}
+			return
+		}
+		if !batchRejected(txResp, err) {
+			err = txError("MsgStore", txResp, err)
+			log.Warnf("MsgStore batch of %d failed: %v", len(batch), err)
+			for _, req := range batch {
+				req.done <- storeResult{height: -1, err: err}
+			}
+			return
+		}
+		if err == nil {
+			log.Warnf("MsgStore batch of %d tx hash=%s, code=%d, broadcasting them separately", len(batch), txResp.TxResponse.TxHash, txResp.TxResponse.Code)
+		} else {
+			log.Warnf("MsgStore batch of %d failed, broadcasting them separately: %v", len(batch), err)
+		}
+	}
+
+	for _, req := range batch {
+		var res storeResult
+		res.resp, res.txHash, res.height, res.err = c.storeOrder(ctx, account, req.msg)
+		req.done <- res
+	}
+}
+
+// batchRejected tells if the failed batch tx is known to apply none of its messages: it is rejected by its
+// simulation, so never broadcast, or it has the code of the error CheckTx or DeliverTx rejected it with.
+func batchRejected(txResp cosmosclient.Response, err error) bool {
+	if err != nil {
+		var simErr *simulateError
+		return errors.As(err, &simErr)
+	}
+	return txResp.TxResponse != nil && txResp.TxResponse.Code != 0
+}
+
+func decodeStoreResponses(txResp cosmosclient.Response, count int) ([]saotypes.MsgStoreResponse, error) {
+	data, err := hex.DecodeString(txResp.TxResponse.Data)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrTxProcessFailed, "failed to decode MsgStoreResponse, due to %v", err)
+	}
+	var txMsgData sdktypes.TxMsgData
+	if err := txResp.Codec.Unmarshal(data, &txMsgData); err != nil {
+		return nil, types.Wrapf(types.ErrTxProcessFailed, "failed to decode MsgStoreResponse, due to %v", err)
+	}
+
+	resps := make([]saotypes.MsgStoreResponse, count)
+	for i := range resps {
+		var value []byte
+		if len(txMsgData.Data) == count {
+			value = txMsgData.Data[i].Data
+		} else if len(txMsgData.MsgResponses) == count {
+			value = txMsgData.MsgResponses[i].Value
+		} else {
+			return nil, types.Wrapf(types.ErrTxProcessFailed, "tx hash=%s has no response of each MsgStore", txResp.TxResponse.TxHash)
+		}
+		if err := resps[i].Unmarshal(value); err != nil {
+			return nil, types.Wrapf(types.ErrTxProcessFailed, "failed to decode MsgStoreResponse, due to %v", err)
+		}
+	}
+	return resps, nil
+}
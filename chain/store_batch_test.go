@@ -0,0 +1,78 @@
+package chain
+
+import (
+	"encoding/hex"
+	"errors"
+	"sao-node/types"
+	"testing"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ignite/cli/ignite/pkg/cosmosclient"
+	"github.com/stretchr/testify/require"
+)
+
+func storeTxResponse(t *testing.T, txMsgData *sdktypes.TxMsgData) cosmosclient.Response {
+	cdc := codec.NewProtoCodec(codectypes.NewInterfaceRegistry())
+	data, err := cdc.Marshal(txMsgData)
+	require.NoError(t, err)
+	return cosmosclient.Response{
+		Codec:      cdc,
+		TxResponse: &sdktypes.TxResponse{TxHash: "hash", Data: hex.EncodeToString(data)},
+	}
+}
+
+func TestDecodeStoreResponses(t *testing.T) {
+	var values [][]byte
+	for _, orderId := range []uint64{7, 8} {
+		resp := saotypes.MsgStoreResponse{OrderId: orderId}
+		value, err := resp.Marshal()
+		require.NoError(t, err)
+		values = append(values, value)
+	}
+	msgType := "/saonetwork.sao.sao.MsgStore"
+
+	// the responses of the messages
+	msgResponses := &sdktypes.TxMsgData{}
+	for _, value := range values {
+		msgResponses.MsgResponses = append(msgResponses.MsgResponses, &codectypes.Any{TypeUrl: msgType + "Response", Value: value})
+	}
+	resps, err := decodeStoreResponses(storeTxResponse(t, msgResponses), 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), resps[0].OrderId)
+	require.Equal(t, uint64(8), resps[1].OrderId)
+
+	// the legacy data of the messages
+	legacy := &sdktypes.TxMsgData{}
+	for _, value := range values {
+		legacy.Data = append(legacy.Data, &sdktypes.MsgData{MsgType: msgType, Data: value})
+	}
+	resps, err = decodeStoreResponses(storeTxResponse(t, legacy), 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), resps[0].OrderId)
+	require.Equal(t, uint64(8), resps[1].OrderId)
+
+	// a response of each message is required
+	_, err = decodeStoreResponses(storeTxResponse(t, msgResponses), 3)
+	require.True(t, types.ErrTxProcessFailed.Is(err))
+
+	bad := storeTxResponse(t, msgResponses)
+	bad.TxResponse.Data = "not hex"
+	_, err = decodeStoreResponses(bad, 2)
+	require.True(t, types.ErrTxProcessFailed.Is(err))
+}
+
+func TestBatchRejected(t *testing.T) {
+	// not broadcast at all
+	require.True(t, batchRejected(cosmosclient.Response{}, &simulateError{err: errors.New("insufficient funds")}))
+	// rejected by CheckTx or failed in its block
+	rejected := cosmosclient.Response{TxResponse: &sdktypes.TxResponse{TxHash: "hash", Code: 5}}
+	require.True(t, batchRejected(rejected, nil))
+
+	// the tx may still be included, its messages must not be broadcast again
+	require.False(t, batchRejected(cosmosclient.Response{}, types.Wrapf(types.ErrTxNotIncluded, "tx hash in 1m0s")))
+	require.False(t, batchRejected(cosmosclient.Response{}, errors.New("connection refused")))
+	require.False(t, batchRejected(cosmosclient.Response{TxResponse: &sdktypes.TxResponse{TxHash: "hash"}}, nil))
+}
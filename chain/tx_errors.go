@@ -0,0 +1,81 @@
+package chain
+
+import (
+	"errors"
+	"sao-node/types"
+	"strings"
+
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/ignite/cli/ignite/pkg/cosmosclient"
+)
+
+// txErrors are the errors of the chain modules the users can act on, decoded to the errors of the node so they see
+// what to fix instead of the abci code of the tx.
+var txErrors = []struct {
+	chainErr *sdkerrors.Error
+	err      *sdkerrors.Error
+}{
+	{sdkerrors.ErrInsufficientFunds, types.ErrInsufficientFunds},
+	{sdkerrors.ErrInsufficientFee, types.ErrInsufficientFunds},
+	{saotypes.ErrInsufficientCoin, types.ErrInsufficientFunds},
+	{modeltypes.ErrInsufficientCoin, types.ErrInsufficientFunds},
+	{ordertypes.ErrInsufficientCoin, types.ErrInsufficientFunds},
+	{modeltypes.ErrDataIdExists, types.ErrConflictId},
+	{modeltypes.ErrModelExists, types.ErrConflictId},
+	{saotypes.ErrorNoPermission, types.ErrNoPermission},
+	{modeltypes.ErrorNoPermission, types.ErrNoPermission},
+	{modeltypes.ErrOnlyOwner, types.ErrNoPermission},
+	{saotypes.ErrorInvalidSignature, types.ErrInvalidSignature},
+}
+
+// simulateError is the error of a tx rejected by its simulation, the tx is not broadcast.
+type simulateError struct {
+	err error
+}
+
+func (e *simulateError) Error() string {
+	return e.err.Error()
+}
+
+func (e *simulateError) Unwrap() error {
+	return e.err
+}
+
+// txError returns the error of the tx of msgName broadcast by broadcastTx, nil if it succeeded. The errors of the
+// chain modules known are decoded, by their codes if the tx failed on chain or by their descriptions in the log
+// if the simulation rejected it, since the simulation only returns the log.
+func txError(msgName string, txResp cosmosclient.Response, err error) error {
+	if err != nil {
+		if decoded := decodeTxLog(msgName, err.Error()); decoded != nil {
+			return decoded
+		}
+		var simErr *simulateError
+		if errors.As(err, &simErr) {
+			return types.Wrapf(types.ErrTxSimulateFailed, "%s: %v", msgName, simErr.err)
+		}
+		return types.Wrap(types.ErrTxProcessFailed, err)
+	}
+
+	resp := txResp.TxResponse
+	if resp.Code != 0 {
+		for _, e := range txErrors {
+			if e.chainErr.Codespace() == resp.Codespace && e.chainErr.ABCICode() == resp.Code {
+				return types.Wrapf(e.err, "%s tx hash=%s rejected: %s", msgName, resp.TxHash, resp.RawLog)
+			}
+		}
+		return types.Wrapf(types.ErrTxProcessFailed, "%s tx hash=%s, code=%d", msgName, resp.TxHash, resp.Code)
+	}
+	return nil
+}
+
+func decodeTxLog(msgName string, txLog string) error {
+	for _, e := range txErrors {
+		if strings.Contains(txLog, e.chainErr.Error()) {
+			return types.Wrapf(e.err, "%s rejected: %s", msgName, txLog)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"sao-node/types"
+	"testing"
+
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ignite/cli/ignite/pkg/cosmosclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxError(t *testing.T) {
+	ok := cosmosclient.Response{TxResponse: &sdktypes.TxResponse{TxHash: "hash"}}
+	require.NoError(t, txError("MsgStore", ok, nil))
+
+	// the simulation only returns the log
+	simErr := &simulateError{err: errors.New("rpc error: code = Unknown desc = failed to execute message; message index: 0: 100sao is smaller than 200sao: insufficient funds")}
+	require.True(t, types.ErrInsufficientFunds.Is(txError("MsgStore", cosmosclient.Response{}, simErr)))
+	simErr = &simulateError{err: fmt.Errorf("failed to execute message; message index: 0: %s", modeltypes.ErrDataIdExists)}
+	require.True(t, types.ErrConflictId.Is(txError("MsgStore", cosmosclient.Response{}, simErr)))
+	simErr = &simulateError{err: errors.New("failed to execute message; message index: 0: out of gas")}
+	require.True(t, types.ErrTxSimulateFailed.Is(txError("MsgStore", cosmosclient.Response{}, simErr)))
+	require.True(t, types.ErrTxProcessFailed.Is(txError("MsgStore", cosmosclient.Response{}, errors.New("connection refused"))))
+
+	// the tx failed on chain has the code of the error
+	failed := cosmosclient.Response{TxResponse: &sdktypes.TxResponse{
+		TxHash:    "hash",
+		Codespace: modeltypes.ErrorNoPermission.Codespace(),
+		Code:      modeltypes.ErrorNoPermission.ABCICode(),
+		RawLog:    "no permission",
+	}}
+	require.True(t, types.ErrNoPermission.Is(txError("MsgUpdataPermission", failed, nil)))
+	failed.TxResponse.Codespace, failed.TxResponse.Code = "unknown", 1
+	require.True(t, types.ErrTxProcessFailed.Is(txError("MsgUpdataPermission", failed, nil)))
+}
@@ -0,0 +1,287 @@
+package client
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sao-node/types"
+	"sao-node/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveManifestName is the first entry of a directory archive, it lists the files following it.
+const ArchiveManifestName = ".sao-manifest.json"
+
+const archiveManifestVersion = 1
+
+// ArchiveEntry describes a file of a directory archive.
+type ArchiveEntry struct {
+	// slash separated path relative to the archived directory
+	Path    string
+	Size    int64
+	Mode    uint32
+	ModTime int64
+	// the cid of the file content, verified on extraction
+	Cid string
+}
+
+// ArchiveManifest lists the files of a directory archive in the order of the archive.
+type ArchiveManifest struct {
+	Version int
+	Entries []ArchiveEntry
+}
+
+// Size returns the total size of the archived files.
+func (m *ArchiveManifest) Size() int64 {
+	var size int64
+	for _, entry := range m.Entries {
+		size += entry.Size
+	}
+	return size
+}
+
+// Select returns the entries of the paths, a path selects the file itself or all the files under it. All the
+// entries are returned if no path is given.
+func (m *ArchiveManifest) Select(paths []string) ([]ArchiveEntry, error) {
+	if len(paths) == 0 {
+		return m.Entries, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, p := range paths {
+		p = strings.Trim(path.Clean(filepath.ToSlash(p)), "/")
+		found := false
+		for _, entry := range m.Entries {
+			if p == "." || p == "" || entry.Path == p || strings.HasPrefix(entry.Path, p+"/") {
+				selected[entry.Path] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, types.Wrapf(types.ErrNotFound, "no file %s in the archive", p)
+		}
+	}
+	// in the order of the archive
+	var entries []ArchiveEntry
+	for _, entry := range m.Entries {
+		if selected[entry.Path] {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// WriteArchive writes the regular files under dir as a tar archive, the manifest comes first so the files can
+// be listed without reading the whole archive. The files are archived in lexical order with their permission
+// and modification time only, archiving the same directory twice gives the same content and cid.
+func WriteArchive(w io.Writer, dir string) (*ArchiveManifest, error) {
+	manifest := &ArchiveManifest{Version: archiveManifestVersion}
+	// WalkDir visits the files in lexical order
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			log.Warnf("skip %s, only regular files are archived", p)
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		c, err := fileCid(p)
+		if err != nil {
+			return err
+		}
+		manifest.Entries = append(manifest.Entries, ArchiveEntry{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			Mode:    uint32(info.Mode().Perm()),
+			ModTime: info.ModTime().Unix(),
+			Cid:     c,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	if len(manifest.Entries) == 0 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "no file to archive under %s", dir)
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	tw := tar.NewWriter(w)
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     ArchiveManifestName,
+		Size:     int64(len(data)),
+		Mode:     0644,
+	})
+	if err == nil {
+		_, err = tw.Write(data)
+	}
+	if err != nil {
+		return nil, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+
+	for _, entry := range manifest.Entries {
+		if err := writeArchiveEntry(tw, dir, entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return manifest, nil
+}
+
+func writeArchiveEntry(tw *tar.Writer, dir string, entry ArchiveEntry) error {
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+	if err != nil {
+		return types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entry.Path,
+		Size:     entry.Size,
+		Mode:     int64(entry.Mode),
+		ModTime:  time.Unix(entry.ModTime, 0),
+	})
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	// a file changed since its cid was calculated fails here or on extraction
+	if _, err := io.CopyN(tw, file, entry.Size); err != nil {
+		return types.Wrapf(types.ErrWriteFileFailed, "archive %s: %v", entry.Path, err)
+	}
+	return nil
+}
+
+func fileCid(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	c, err := utils.CalculateCidFrom(file)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// ReadArchiveManifest reads the manifest of a directory archive, ErrInvalidContent is returned if the content
+// is not an archive written by WriteArchive.
+func ReadArchiveManifest(r io.Reader) (*ArchiveManifest, error) {
+	_, manifest, err := openArchive(r)
+	return manifest, err
+}
+
+func openArchive(r io.Reader) (*tar.Reader, *ArchiveManifest, error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil || header.Name != ArchiveManifestName {
+		return nil, nil, types.Wrapf(types.ErrInvalidContent, "not a directory archive")
+	}
+	var manifest ArchiveManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, nil, types.Wrapf(types.ErrInvalidContent, "invalid archive manifest: %v", err)
+	}
+	if manifest.Version != archiveManifestVersion {
+		return nil, nil, types.Wrapf(types.ErrUnSupport, "archive manifest version %d", manifest.Version)
+	}
+	return tr, &manifest, nil
+}
+
+// ExtractArchive extracts the files of the paths from the archive into dest, all the files if no path is given.
+// The content of every extracted file is verified against the manifest. The archive is read once, the content
+// of the other files is skipped with a seek when r is an io.Seeker.
+func ExtractArchive(r io.Reader, dest string, paths []string) ([]ArchiveEntry, error) {
+	tr, manifest, err := openArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := manifest.Select(paths)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]ArchiveEntry)
+	for _, entry := range entries {
+		selected[entry.Path] = entry
+	}
+
+	for len(selected) > 0 {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		entry, ok := selected[header.Name]
+		if !ok {
+			continue
+		}
+		if err := extractArchiveEntry(tr, dest, entry); err != nil {
+			return nil, err
+		}
+		delete(selected, header.Name)
+	}
+	if len(selected) > 0 {
+		missing := make([]string, 0, len(selected))
+		for p := range selected {
+			missing = append(missing, p)
+		}
+		sort.Strings(missing)
+		return nil, types.Wrapf(types.ErrInvalidContent, "files missing from the archive: %s", strings.Join(missing, ", "))
+	}
+	return entries, nil
+}
+
+func extractArchiveEntry(r io.Reader, dest string, entry ArchiveEntry) error {
+	// the paths come from the archive, none of them may escape dest
+	clean := path.Clean(entry.Path)
+	if clean != entry.Path || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return types.Wrapf(types.ErrInvalidPath, "%s", entry.Path)
+	}
+	p := filepath.Join(dest, filepath.FromSlash(entry.Path))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return types.Wrap(types.ErrCreateDirFailed, err)
+	}
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(entry.Mode).Perm())
+	if err != nil {
+		return types.Wrap(types.ErrCreateFileFailed, err)
+	}
+	c, err := utils.CalculateCidFrom(io.TeeReader(io.LimitReader(r, entry.Size), file))
+	if cerr := file.Close(); err == nil && cerr != nil {
+		err = types.Wrap(types.ErrCloseFileFailed, cerr)
+	}
+	if err != nil {
+		return err
+	}
+	if c.String() != entry.Cid {
+		return types.Wrapf(types.ErrInvalidContent, "%s: cid %s, expected %s", entry.Path, c, entry.Cid)
+	}
+	mtime := time.Unix(entry.ModTime, 0)
+	_ = os.Chtimes(p, mtime, mtime)
+	return nil
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":      "<html></html>",
+		"css/site.css":    "body {}",
+		"img/a/logo.svg":  "<svg></svg>",
+		"img/a/empty.txt": "",
+	}
+	for p, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, p), []byte(content), 0600))
+	}
+
+	var archive bytes.Buffer
+	manifest, err := WriteArchive(&archive, dir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Entries, 4)
+	require.Equal(t, "css/site.css", manifest.Entries[0].Path)
+	require.Equal(t, int64(len("<html></html>")+len("body {}")+len("<svg></svg>")), manifest.Size())
+
+	// the same directory gives the same archive
+	var again bytes.Buffer
+	_, err = WriteArchive(&again, dir)
+	require.NoError(t, err)
+	require.Equal(t, archive.Bytes(), again.Bytes())
+
+	read, err := ReadArchiveManifest(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, manifest, read)
+
+	dest := t.TempDir()
+	entries, err := ExtractArchive(bytes.NewReader(archive.Bytes()), dest, []string{"img/a/logo.svg", "img", "css/"})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	data, err := os.ReadFile(filepath.Join(dest, "img", "a", "logo.svg"))
+	require.NoError(t, err)
+	require.Equal(t, "<svg></svg>", string(data))
+	info, err := os.Stat(filepath.Join(dest, "css", "site.css"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	_, err = os.Stat(filepath.Join(dest, "index.html"))
+	require.True(t, os.IsNotExist(err))
+
+	_, err = ExtractArchive(bytes.NewReader(archive.Bytes()), dest, []string{"js"})
+	require.Error(t, err)
+
+	// a tampered file fails the verification
+	tampered := bytes.Replace(archive.Bytes(), []byte("body {}"), []byte("body {x"), 1)
+	_, err = ExtractArchive(bytes.NewReader(tampered), t.TempDir(), []string{"css/site.css"})
+	require.Error(t, err)
+
+	// a plain tar has no manifest
+	var plain bytes.Buffer
+	tw := tar.NewWriter(&plain)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a.txt", Size: 1, Mode: 0644}))
+	_, err = tw.Write([]byte("a"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	_, err = ReadArchiveManifest(&plain)
+	require.Error(t, err)
+}
@@ -20,13 +20,19 @@ type SaoClientConfig struct {
 	ChainAddress string
 	Gateway      string
 	Token        string
+	// keyring backend of the local keys: os, file or test
+	KeyringBackend string
 }
 
 type SaoClient struct {
 	api.SaoApi
 	chain.ChainSvcApi
-	Cfg  *SaoClientConfig
-	repo string
+	Cfg *SaoClientConfig
+	// retries of CreateWithRetry and UpdateWithRetry
+	Retry RetryPolicy
+	repo  string
+	// address of the gateway api, the subscriptions connect to it over websocket
+	gateway string
 }
 
 type SaoClientOptions struct {
@@ -131,17 +137,20 @@ func NewSaoClient(ctx context.Context, opt SaoClientOptions) (*SaoClient, func()
 		SaoApi:      gatewayApi,
 		ChainSvcApi: chainApi,
 		Cfg:         cfg,
+		Retry:       DefaultRetryPolicy(),
 		repo:        opt.Repo,
+		gateway:     opt.Gateway,
 	}, closer, nil
 }
 
 func DefaultSaoClientConfig() *SaoClientConfig {
 	return &SaoClientConfig{
-		GroupId:      utils.GenerateGroupId(),
-		KeyName:      "",
-		ChainAddress: "http://127.0.0.1:26657",
-		Gateway:      "http://127.0.0.1:5151/rpc/v0",
-		Token:        "DEFAULT_TOKEN",
+		GroupId:        utils.GenerateGroupId(),
+		KeyName:        "",
+		ChainAddress:   "http://127.0.0.1:26657",
+		Gateway:        "http://127.0.0.1:5151/rpc/v0",
+		Token:          "DEFAULT_TOKEN",
+		KeyringBackend: "test",
 	}
 }
 
@@ -0,0 +1,342 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sao-node/types"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-merkledag"
+	"github.com/multiformats/go-multihash"
+)
+
+// the progress of a download is saved next to the file, it is removed once the content is verified
+const downloadProgressSuffix = ".saodownload"
+
+type downloadChunk struct {
+	Cid  string
+	Size uint64
+	// the chunk is verified and written
+	Done bool
+}
+
+type downloadProgress struct {
+	Cid string
+	// leaf blocks of the content in order
+	Chunks []downloadChunk
+}
+
+func (p *downloadProgress) size() int64 {
+	var size int64
+	for _, chunk := range p.Chunks {
+		size += int64(chunk.Size)
+	}
+	return size
+}
+
+func (p *downloadProgress) save(path string) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+// loadDownloadProgress returns the saved progress of the content, nil if there is none or it is of another content.
+func loadDownloadProgress(path string, contentCid cid.Cid) *downloadProgress {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var progress downloadProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		log.Warnf("invalid download progress %s: %v", path, err)
+		return nil
+	}
+	if progress.Cid != contentCid.String() {
+		return nil
+	}
+	return &progress
+}
+
+const (
+	// concurrent chunk downloads from a source
+	downloadWorkersPerSource = 4
+	// a source failing this many chunks in a row is not used for the rest of the download
+	maxSourceFailures = 3
+)
+
+// Downloader fetches the contents as raw blocks from the /ipfs/<cid> paths of http file servers and ipfs
+// gateways. Every block is checked against its cid, a content stored as a unixfs file is fetched leaf by leaf
+// from all sources concurrently and the download is resumed from the written leaves after an interruption.
+type Downloader struct {
+	urls   []string
+	client *http.Client
+}
+
+// NewDownloader creates a downloader of the sources at urls, like http://127.0.0.1:8888. The first source is
+// the gateway storing the content, the unixfs root is fetched from it, the others only need to serve the
+// leaves, like the storage nodes of the replicas or the ipfs gateways.
+func NewDownloader(urls ...string) *Downloader {
+	d := &Downloader{client: &http.Client{}}
+	for _, url := range urls {
+		d.urls = append(d.urls, strings.TrimRight(url, "/"))
+	}
+	return d
+}
+
+// Download writes the content of contentCid to path, the whole content is checked against contentCid at the end.
+func (d *Downloader) Download(ctx context.Context, contentCid cid.Cid, path string) error {
+	if len(d.urls) == 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "no download source")
+	}
+
+	progressPath := path + downloadProgressSuffix
+	progress := loadDownloadProgress(progressPath, contentCid)
+	if progress == nil {
+		var chunks []downloadChunk
+		var err error
+		for _, url := range d.urls {
+			chunks, err = d.chunks(ctx, url, contentCid)
+			if err == nil {
+				break
+			}
+			log.Warnf("get chunks of %s from %s: %v", contentCid, url, err)
+		}
+		if err != nil {
+			return err
+		}
+		progress = &downloadProgress{Cid: contentCid.String(), Chunks: chunks}
+		if err := progress.save(progressPath); err != nil {
+			return err
+		}
+	} else {
+		log.Infof("resume the download of %s", contentCid)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	// the chunks not done are written again at their offsets
+	if err := file.Truncate(progress.size()); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if err := d.fetchChunks(ctx, progress, progressPath, file); err != nil {
+		return err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return types.Wrap(types.ErrReadFileFailed, err)
+	}
+	prefix := contentCid.Prefix()
+	hash, err := multihash.SumStream(file, prefix.MhType, prefix.MhLength)
+	if err != nil {
+		return types.Wrap(types.ErrReadFileFailed, err)
+	}
+	if !bytes.Equal(hash, contentCid.Hash()) {
+		// the chunks are verified, so the manifest of the gateway doesn't match the content
+		_ = os.Remove(progressPath)
+		return types.Wrapf(types.ErrInvalidCid, "the downloaded content doesn't match %s", contentCid)
+	}
+	return os.Remove(progressPath)
+}
+
+// fetchChunks downloads the chunks not done yet, the sources take the chunks from a shared queue so the faster
+// ones download more, a chunk failed by a source is put back for the others.
+func (d *Downloader) fetchChunks(ctx context.Context, progress *downloadProgress, progressPath string, file *os.File) error {
+	offsets := make([]int64, len(progress.Chunks))
+	pending := make(chan int, len(progress.Chunks))
+	remaining := 0
+	var offset int64
+	for i, chunk := range progress.Chunks {
+		offsets[i] = offset
+		offset += int64(chunk.Size)
+		if !chunk.Done {
+			pending <- i
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return nil
+	}
+
+	var lk sync.Mutex
+	var lastErr error
+	// fetch returns false if the chunk is not written, it is back in the queue then
+	fetch := func(url string, i int) bool {
+		chunk := progress.Chunks[i]
+		chunkCid, err := cid.Decode(chunk.Cid)
+		if err == nil {
+			var data []byte
+			_, data, err = d.block(ctx, url, chunkCid)
+			if err == nil && uint64(len(data)) != chunk.Size {
+				err = types.Wrapf(types.ErrInvalidCid, "chunk %s has %d bytes, %d expected", chunkCid, len(data), chunk.Size)
+			}
+			if err == nil {
+				_, err = file.WriteAt(data, offsets[i])
+			}
+		}
+
+		lk.Lock()
+		defer lk.Unlock()
+		if err == nil {
+			err = file.Sync()
+		}
+		if err != nil {
+			log.Warnf("download chunk %s from %s: %v", chunk.Cid, url, err)
+			lastErr = err
+			pending <- i
+			return false
+		}
+		progress.Chunks[i].Done = true
+		if err := progress.save(progressPath); err != nil {
+			log.Warnf("save download progress: %v", err)
+		}
+		remaining--
+		if remaining == 0 {
+			close(pending)
+		}
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for _, url := range d.urls {
+		var failures int32
+		for w := 0; w < downloadWorkersPerSource; w++ {
+			wg.Add(1)
+			go func(url string) {
+				defer wg.Done()
+				for atomic.LoadInt32(&failures) < maxSourceFailures {
+					var i int
+					var ok bool
+					select {
+					case <-ctx.Done():
+						return
+					case i, ok = <-pending:
+						if !ok {
+							return
+						}
+					}
+					if fetch(url, i) {
+						atomic.StoreInt32(&failures, 0)
+					} else if atomic.AddInt32(&failures, 1) == maxSourceFailures {
+						log.Warnf("stop downloading from %s", url)
+					}
+				}
+			}(url)
+		}
+	}
+	wg.Wait()
+
+	lk.Lock()
+	defer lk.Unlock()
+	if remaining > 0 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return lastErr
+	}
+	return nil
+}
+
+// chunks returns the leaf blocks of the content, the content is a single chunk if it is not stored as a unixfs file.
+func (d *Downloader) chunks(ctx context.Context, url string, contentCid cid.Cid) ([]downloadChunk, error) {
+	root, data, err := d.block(ctx, url, contentCid)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []downloadChunk
+	err = d.leaves(ctx, url, root, data, &chunks)
+	if err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (d *Downloader) leaves(ctx context.Context, url string, c cid.Cid, data []byte, chunks *[]downloadChunk) error {
+	switch c.Type() {
+	case cid.Raw:
+		*chunks = append(*chunks, downloadChunk{Cid: c.String(), Size: uint64(len(data))})
+		return nil
+	case cid.DagProtobuf:
+		node, err := merkledag.DecodeProtobuf(data)
+		if err != nil {
+			return types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+		if len(node.Links()) == 0 {
+			return types.Wrapf(types.ErrInvalidCid, "unixfs node %s without raw leaves", c)
+		}
+		for _, link := range node.Links() {
+			if link.Cid.Type() == cid.Raw {
+				*chunks = append(*chunks, downloadChunk{Cid: link.Cid.String(), Size: link.Size})
+				continue
+			}
+			_, child, err := d.block(ctx, url, link.Cid)
+			if err != nil {
+				return err
+			}
+			if err := d.leaves(ctx, url, link.Cid, child, chunks); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return types.Wrapf(types.ErrInvalidCid, "unsupported block %s", c)
+	}
+}
+
+// block fetches the raw block of c from the source at url and checks it, the block is the unixfs root if c is
+// a content stored as a unixfs file.
+func (d *Downloader) block(ctx context.Context, url string, c cid.Cid) (cid.Cid, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/ipfs/%s?format=raw", url, c), nil)
+	if err != nil {
+		return cid.Undef, nil, types.Wrap(types.ErrSendRequestFailed, err)
+	}
+	req.Header.Set("Accept", "application/vnd.ipld.raw")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return cid.Undef, nil, types.Wrap(types.ErrSendRequestFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return cid.Undef, nil, types.Wrapf(types.ErrFailuresResponsed, "get block %s: %s", c, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return cid.Undef, nil, types.Wrap(types.ErrReadResponseFailed, err)
+	}
+
+	blockCid := c
+	if root := resp.Header.Get("X-Ipfs-Roots"); root != "" {
+		blockCid, err = cid.Decode(root)
+		if err != nil {
+			return cid.Undef, nil, types.Wrap(types.ErrInvalidCid, err)
+		}
+	}
+	sum, err := blockCid.Prefix().Sum(data)
+	if err != nil {
+		return cid.Undef, nil, types.Wrap(types.ErrInvalidCid, err)
+	}
+	if !sum.Equals(blockCid) {
+		return cid.Undef, nil, types.Wrapf(types.ErrInvalidCid, "block %s doesn't match its content", blockCid)
+	}
+	return blockCid, data, nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sao-node/utils"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloader(t *testing.T) {
+	ctx := context.Background()
+	content := []byte(strings.Repeat("a", 4) + strings.Repeat("b", 4) + "c")
+	contentCid, err := utils.CalculateCid(content)
+	require.NoError(t, err)
+
+	blocks := make(map[string][]byte)
+	root := merkledag.NodeWithData(nil)
+	require.NoError(t, root.SetCidBuilder(merkledag.V1CidPrefix()))
+	var leaves []cid.Cid
+	for _, chunk := range [][]byte{content[:4], content[4:8], content[8:]} {
+		leaf := merkledag.NewRawNode(chunk)
+		blocks[leaf.Cid().String()] = chunk
+		leaves = append(leaves, leaf.Cid())
+		require.NoError(t, root.AddNodeLink("", leaf))
+	}
+	blocks[root.Cid().String()] = root.RawData()
+
+	var lk sync.Mutex
+	// the second leaf is not served until fixed
+	broken := true
+	serve := func(corrupt bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lk.Lock()
+			defer lk.Unlock()
+			require.Equal(t, "raw", r.URL.Query().Get("format"))
+			name := strings.TrimPrefix(r.URL.Path, "/ipfs/")
+			if name == contentCid.String() {
+				w.Header().Set("X-Ipfs-Roots", root.Cid().String())
+				name = root.Cid().String()
+			}
+			data, ok := blocks[name]
+			if !ok || (broken && name == leaves[1].String()) {
+				http.NotFound(w, r)
+				return
+			}
+			if corrupt && name != root.Cid().String() {
+				data = []byte("dddd")
+			}
+			_, _ = w.Write(data)
+		}))
+	}
+	gateway := serve(false)
+	defer gateway.Close()
+	corrupted := serve(true)
+	defer corrupted.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	require.Error(t, NewDownloader(gateway.URL+"/").Download(ctx, contentCid, path))
+	progress := loadDownloadProgress(path+downloadProgressSuffix, contentCid)
+	require.NotNil(t, progress)
+	require.Len(t, progress.Chunks, 3)
+	require.True(t, progress.Chunks[0].Done)
+	require.False(t, progress.Chunks[1].Done)
+	require.True(t, progress.Chunks[2].Done)
+
+	// resumed with a source corrupting every chunk, dropped after its failures
+	lk.Lock()
+	broken = false
+	lk.Unlock()
+	require.NoError(t, NewDownloader(corrupted.URL, gateway.URL).Download(ctx, contentCid, path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+	_, err = os.Stat(path + downloadProgressSuffix)
+	require.True(t, os.IsNotExist(err))
+}
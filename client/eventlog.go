@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	apitypes "sao-node/api/types"
+	"sao-node/node/model/eventlog"
+	"sao-node/types"
+	"sao-node/utils"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+// CreateLog creates a log model named name, an append-only list of json entries compacted by the policy, see
+// eventlog. The alias of the model is the name with the log prefix.
+func (s *Sdk) CreateLog(ctx context.Context, name string, policy eventlog.Policy, opts ModelOptions) (apitypes.CreateResp, error) {
+	segment, err := eventlog.New(policy)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	content, err := segment.Marshal()
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	opts.Alias = eventlog.Alias(name)
+	return s.CreateJSON(ctx, json.RawMessage(content), opts)
+}
+
+// AppendLog appends the entries to the log model and returns the sequence number of the first one. The commit only
+// holds the entries appended, unless it compacts the log by its policy.
+func (s *Sdk) AppendLog(ctx context.Context, keyword string, entries []json.RawMessage, opts ModelOptions) (apitypes.UpdateResp, uint64, error) {
+	latest, segment, version, err := s.latestSegment(ctx, keyword)
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	next, err := eventlog.Append(segment, version, entries, s.segmentLoader(ctx, latest.DataId))
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	content, err := next.Marshal()
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	patch, err := utils.GeneratePatch(latest.Content, string(content))
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	resp, err := s.updateLatest(ctx, latest, []byte(patch), opts)
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	return resp, segment.End(), nil
+}
+
+// ReadLog returns the entries of the log model from the sequence number from, limit entries at most or all if limit
+// is 0, and the sequence number of the first entry returned, which is later than from if the entries before are
+// dropped by the compactions.
+func (s *Sdk) ReadLog(ctx context.Context, keyword string, from uint64, limit int) ([]json.RawMessage, uint64, error) {
+	latest, segment, version, err := s.latestSegment(ctx, keyword)
+	if err != nil {
+		return nil, 0, err
+	}
+	return eventlog.Read(segment, version, from, limit, s.segmentLoader(ctx, latest.DataId))
+}
+
+func (s *Sdk) latestSegment(ctx context.Context, keyword string) (apitypes.LoadResp, *eventlog.Segment, int, error) {
+	latest, err := s.LoadLatest(ctx, keyword)
+	if err != nil {
+		return apitypes.LoadResp{}, nil, 0, err
+	}
+	if !eventlog.IsLog(latest.Alias) {
+		return apitypes.LoadResp{}, nil, 0, types.Wrapf(types.ErrInvalidParameters, "%s is not a log model", keyword)
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(latest.Version, "v"))
+	if err != nil {
+		return apitypes.LoadResp{}, nil, 0, types.Wrapf(types.ErrInvalidVersion, "invalid version %s", latest.Version)
+	}
+	segment, err := eventlog.Parse([]byte(latest.Content))
+	if err != nil {
+		return apitypes.LoadResp{}, nil, 0, err
+	}
+	return latest, segment, version, nil
+}
+
+// segmentLoader loads the segments of the versions of the log model.
+func (s *Sdk) segmentLoader(ctx context.Context, dataId string) eventlog.Loader {
+	return func(version int) (*eventlog.Segment, error) {
+		gatewayAddress, err := s.gateway(ctx)
+		if err != nil {
+			return nil, err
+		}
+		request, err := BuildQueryRequest(ctx, s.did, saotypes.QueryProposal{
+			Owner:   s.did.Id,
+			Keyword: dataId,
+			GroupId: s.GroupId,
+			Version: fmt.Sprintf("v%d", version),
+		}, s.client, gatewayAddress)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.client.ModelLoad(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return eventlog.Parse([]byte(resp.Content))
+	}
+}
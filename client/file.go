@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	apitypes "sao-node/api/types"
+	"sao-node/node/model/filedelta"
+	"sao-node/types"
+	"sao-node/utils"
+)
+
+// UpdateFile commits content as the new file of the file model, only the delta from the file of the latest commit
+// is transferred and stored, see filedelta. base is the file of the latest commit, the content loaded is used if it
+// is nil, which the gateway doesn't return for the large files. The whole file is committed instead if the base isn't
+// known, the delta isn't much smaller or the version commits whole files. It returns the size committed.
+func (s *Sdk) UpdateFile(ctx context.Context, keyword string, base []byte, content []byte, opts ModelOptions) (apitypes.UpdateResp, int, error) {
+	if len(content) == 0 {
+		return apitypes.UpdateResp{}, 0, types.Wrapf(types.ErrInvalidParameters, "empty file")
+	}
+	latest, err := s.LoadLatest(ctx, keyword)
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	if !filedelta.IsFile(latest.Alias) {
+		return apitypes.UpdateResp{}, 0, types.Wrapf(types.ErrInvalidParameters, "%s is not a file model", keyword)
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(latest.Version, "v"))
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, types.Wrapf(types.ErrInvalidVersion, "invalid version %s", latest.Version)
+	}
+
+	if base == nil {
+		base = []byte(latest.Content)
+	}
+	if len(base) > 0 && !filedelta.FullDue(version+1) {
+		baseCid, err := utils.CalculateCid(base)
+		if err != nil {
+			return apitypes.UpdateResp{}, 0, err
+		}
+		if baseCid.String() != latest.Cid {
+			return apitypes.UpdateResp{}, 0, types.Wrapf(types.ErrInvalidCid, "the base is not the file of the latest commit %s", latest.CommitId)
+		}
+	}
+	delta, err := filedelta.Commit(base, latest.CommitId, version+1, content)
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	// the delta is both the patch and the content committed
+	resp, err := s.updateContent(ctx, latest, delta, delta, opts)
+	if err != nil {
+		return apitypes.UpdateResp{}, 0, err
+	}
+	return resp, len(delta), nil
+}
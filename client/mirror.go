@@ -0,0 +1,303 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"sort"
+	"strings"
+	"sync"
+
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+const (
+	// the index of the mirror, the content of the commits is kept in a directory per model next to it
+	mirrorIndexFile = "mirror.json"
+	// the file of the latest content in the directory of a model
+	mirrorLatestFile = "latest"
+)
+
+// MirrorFilter selects the models mirrored, a model is selected if it is of one of the groups and has one of the
+// tags, an empty list selects all.
+type MirrorFilter struct {
+	GroupIds []string
+	Tags     []string
+}
+
+func (f MirrorFilter) selects(meta modeltypes.Metadata) bool {
+	return (len(f.GroupIds) == 0 || containsAny(f.GroupIds, meta.GroupId)) &&
+		(len(f.Tags) == 0 || containsAny(f.Tags, meta.Tags...))
+}
+
+// MirroredModel is a model mirrored, Commits are the ids of its commits mirrored, oldest first.
+type MirroredModel struct {
+	DataId  string
+	Alias   string
+	GroupId string
+	Tags    []string
+	Commits []string
+}
+
+// Mirror is a local copy of the commits of the models of a did. The content of a commit is the file named by the
+// commit id in the directory of the model, named by the data id, so the local applications can read it as is, or
+// through the http handler of the mirror.
+type Mirror struct {
+	dir string
+
+	lk     sync.RWMutex
+	models map[string]*MirroredModel
+}
+
+// OpenMirror opens the mirror in dir, it is created if it doesn't exist.
+func OpenMirror(dir string) (*Mirror, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, types.Wrap(types.ErrCreateDirFailed, err)
+	}
+
+	m := &Mirror{
+		dir:    dir,
+		models: make(map[string]*MirroredModel),
+	}
+	data, err := os.ReadFile(filepath.Join(dir, mirrorIndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	if err := json.Unmarshal(data, &m.models); err != nil {
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "mirror index: %v", err)
+	}
+	return m, nil
+}
+
+// Pull mirrors the commits of the models selected made since the last pull, it returns how many commits are
+// pulled. The commits pulled before an error are kept.
+func (m *Mirror) Pull(ctx context.Context, sdk *Sdk, filter MirrorFilter) (int, error) {
+	metas, err := sdk.client.ListMetadata(ctx, sdk.Did())
+	if err != nil {
+		return 0, err
+	}
+
+	pulled := 0
+	for _, meta := range metas {
+		if !filter.selects(meta) {
+			continue
+		}
+		n, err := m.pullModel(ctx, sdk, meta)
+		pulled += n
+		if err != nil {
+			return pulled, err
+		}
+	}
+	return pulled, nil
+}
+
+func (m *Mirror) pullModel(ctx context.Context, sdk *Sdk, meta modeltypes.Metadata) (int, error) {
+	m.lk.RLock()
+	mirrored := make(map[string]bool)
+	if model, exists := m.models[meta.DataId]; exists {
+		for _, commitId := range model.Commits {
+			mirrored[commitId] = true
+		}
+	}
+	m.lk.RUnlock()
+
+	modelDir := filepath.Join(m.dir, meta.DataId)
+	if err := os.MkdirAll(modelDir, 0755); err != nil {
+		return 0, types.Wrap(types.ErrCreateDirFailed, err)
+	}
+
+	// the commits are kept in the order of the chain, the ones replaced by force updates are dropped
+	var commits []string
+	pulled := 0
+	for _, commit := range meta.Commits {
+		commitInfo, err := types.ParseMetaCommit(commit)
+		if err != nil {
+			return pulled, err
+		}
+		if !mirrored[commitInfo.CommitId] {
+			err = m.pullCommit(ctx, sdk, meta, commitInfo.CommitId)
+			if err != nil {
+				if saveErr := m.saveModel(meta, commits); saveErr != nil {
+					log.Warnf("save mirror of %s error: %v", meta.DataId, saveErr)
+				}
+				return pulled, err
+			}
+			pulled++
+		}
+		commits = append(commits, commitInfo.CommitId)
+	}
+
+	if len(commits) > 0 {
+		latest, err := os.ReadFile(filepath.Join(modelDir, commits[len(commits)-1]))
+		if err != nil {
+			return pulled, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		if err := writeFileAtomic(filepath.Join(modelDir, mirrorLatestFile), latest); err != nil {
+			return pulled, err
+		}
+	}
+	return pulled, m.saveModel(meta, commits)
+}
+
+func (m *Mirror) pullCommit(ctx context.Context, sdk *Sdk, meta modeltypes.Metadata, commitId string) error {
+	content, err := sdk.loadCommit(ctx, meta, commitId)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(m.dir, meta.DataId, commitId), content)
+}
+
+func (m *Mirror) saveModel(meta modeltypes.Metadata, commits []string) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	m.models[meta.DataId] = &MirroredModel{
+		DataId:  meta.DataId,
+		Alias:   meta.Alias,
+		GroupId: meta.GroupId,
+		Tags:    meta.Tags,
+		Commits: commits,
+	}
+	data, err := json.MarshalIndent(m.models, "", "  ")
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return writeFileAtomic(filepath.Join(m.dir, mirrorIndexFile), data)
+}
+
+// Models returns the models mirrored, ordered by data id.
+func (m *Mirror) Models() []MirroredModel {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+
+	models := make([]MirroredModel, 0, len(m.models))
+	for _, model := range m.models {
+		models = append(models, *model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].DataId < models[j].DataId
+	})
+	return models
+}
+
+// Content returns the content of the commit of the model mirrored, the latest one if commitId is empty. keyword is
+// the data id or the alias of the model.
+func (m *Mirror) Content(keyword string, commitId string) ([]byte, error) {
+	m.lk.RLock()
+	model := m.models[keyword]
+	if model == nil {
+		for _, mm := range m.models {
+			if mm.Alias == keyword {
+				model = mm
+				break
+			}
+		}
+	}
+	var known bool
+	if model != nil {
+		for _, c := range model.Commits {
+			known = known || c == commitId
+		}
+	}
+	m.lk.RUnlock()
+
+	if model == nil || len(model.Commits) == 0 {
+		return nil, types.Wrapf(types.ErrNotFound, "model %s is not mirrored", keyword)
+	}
+	name := mirrorLatestFile
+	if commitId != "" {
+		if !known {
+			return nil, types.Wrapf(types.ErrNotFound, "commit %s of model %s is not mirrored", commitId, keyword)
+		}
+		name = commitId
+	}
+	content, err := os.ReadFile(filepath.Join(m.dir, model.DataId, name))
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return content, nil
+}
+
+// ServeHTTP serves the mirror to the local applications, GET /models lists the models, GET /models/<keyword>
+// returns the latest content of a model and GET /models/<keyword>/<commit id> the content of a commit.
+func (m *Mirror) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "models" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(m.Models())
+		return
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "models" {
+		http.NotFound(w, r)
+		return
+	}
+
+	commitId := ""
+	if len(parts) == 3 {
+		commitId = parts[2]
+	}
+	content, err := m.Content(parts[1], commitId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(content)
+}
+
+// loadCommit loads the content of the commit of the model.
+func (s *Sdk) loadCommit(ctx context.Context, meta modeltypes.Metadata, commitId string) ([]byte, error) {
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := BuildQueryRequest(ctx, s.did, saotypes.QueryProposal{
+		Owner:    s.did.Id,
+		Keyword:  meta.DataId,
+		GroupId:  meta.GroupId,
+		CommitId: commitId,
+	}, s.client, gatewayAddress)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.ModelLoad(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resp.Content), nil
+}
+
+func containsAny(list []string, values ...string) bool {
+	for _, v := range values {
+		for _, l := range list {
+			if l == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
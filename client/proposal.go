@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"sao-node/chain"
+	"sao-node/types"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+// how many blocks a query request is valid for
+const queryValidBlocks = 200
+
+// BuildOrderProposal signs the proposal of a model order by the did, the proposals of the public models, owned by
+// "all", are not signed.
+func BuildOrderProposal(didManager *saodid.DidManager, proposal saotypes.Proposal) (*types.OrderStoreProposal, error) {
+	if proposal.Owner == "all" {
+		return &types.OrderStoreProposal{
+			Proposal: proposal,
+		}, nil
+	}
+
+	signature, err := sign(didManager, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &types.OrderStoreProposal{
+		Proposal:     proposal,
+		JwsSignature: signature,
+	}, nil
+}
+
+// BuildQueryRequest signs the query proposal by the did, the request is valid on the gateway for the next blocks
+// only.
+func BuildQueryRequest(ctx context.Context, didManager *saodid.DidManager, proposal saotypes.QueryProposal, chainSvc chain.ChainSvcApi, gatewayAddress string) (*types.MetadataProposal, error) {
+	lastHeight, err := chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		return nil, types.Wrap(types.ErrQueryHeightFailed, err)
+	}
+
+	peerInfo, err := chainSvc.GetNodePeer(ctx, gatewayAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal.LastValidHeight = uint64(lastHeight + queryValidBlocks)
+	proposal.Gateway = peerInfo
+
+	if proposal.Owner == "all" {
+		return &types.MetadataProposal{
+			Proposal: proposal,
+		}, nil
+	}
+
+	signature, err := sign(didManager, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &types.MetadataProposal{
+		Proposal:     proposal,
+		JwsSignature: signature,
+	}, nil
+}
+
+// BuildRenewProposal signs the proposal renewing the orders of the models by the did.
+func BuildRenewProposal(didManager *saodid.DidManager, proposal saotypes.RenewProposal) (*types.OrderRenewProposal, error) {
+	signature, err := sign(didManager, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &types.OrderRenewProposal{
+		Proposal:     proposal,
+		JwsSignature: signature,
+	}, nil
+}
+
+// BuildPermissionProposal signs the proposal updating the permission of a model by the did.
+func BuildPermissionProposal(didManager *saodid.DidManager, proposal saotypes.PermissionProposal) (*types.PermissionProposal, error) {
+	signature, err := sign(didManager, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &types.PermissionProposal{
+		Proposal:     proposal,
+		JwsSignature: signature,
+	}, nil
+}
+
+type marshaler interface {
+	Marshal() ([]byte, error)
+}
+
+func sign(didManager *saodid.DidManager, proposal marshaler) (saotypes.JwsSignature, error) {
+	proposalBytes, err := proposal.Marshal()
+	if err != nil {
+		return saotypes.JwsSignature{}, types.Wrap(types.ErrMarshalFailed, err)
+	}
+
+	jws, err := didManager.CreateJWS(proposalBytes)
+	if err != nil {
+		return saotypes.JwsSignature{}, types.Wrap(types.ErrCreateJwsFailed, err)
+	}
+	return saotypes.JwsSignature{
+		Protected: jws.Signatures[0].Protected,
+		Signature: jws.Signatures[0].Signature,
+	}, nil
+}
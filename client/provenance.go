@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"strings"
+
+	"sao-node/types"
+)
+
+// Provenance returns the provenance report of the model made by the gateway, which verifies the JWS of each
+// commit. The report must be signed by a peer the gateway has registered on chain.
+func (s *Sdk) Provenance(ctx context.Context, keyword string) (*types.ProvenanceReport, error) {
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := s.queryRequest(ctx, gatewayAddress, keyword)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := s.client.ModelProvenance(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.OpenProvenanceReport(ctx, &signed)
+}
+
+// OpenProvenanceReport verifies the signature of the report and that its peer is registered on chain by the
+// gateway the report is of.
+func (sc *SaoClient) OpenProvenanceReport(ctx context.Context, signed *types.SignedProvenanceReport) (*types.ProvenanceReport, error) {
+	report, err := signed.Open()
+	if err != nil {
+		return nil, err
+	}
+	peerInfo, err := sc.GetNodePeer(ctx, report.Gateway)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(peerInfo, signed.PeerId) {
+		return nil, types.Wrapf(types.ErrUnregisteredPeer, "node=%s, peer=%s", report.Gateway, signed.PeerId)
+	}
+	return report, nil
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	QueuedCreate = "create"
+	QueuedUpdate = "update"
+
+	// the directory of the queue in the client repo
+	queueDir = "queue"
+	// how long the gateway and the chain are given to respond before the client is considered offline
+	reachTimeout = 10 * time.Second
+)
+
+// QueuedOp is a create or an update of a model queued while the gateway or the chain was unreachable, its content
+// or patch is staged next to it in the queue.
+type QueuedOp struct {
+	Id      string
+	Kind    string
+	Owner   string
+	GroupId string
+	// the data id or the alias of the model updated
+	Keyword string
+	// the commit the update is made on, the update conflicts once the model has another commit
+	BaseCommitId string
+	Options      ModelOptions
+	QueuedAt     time.Time
+	// why the op failed while the gateway was reachable, it is kept in the queue until forced or dropped
+	Error string
+}
+
+// Queue is the operations waiting in the client repo for the gateway and the chain to be reachable again.
+type Queue struct {
+	lk  sync.Mutex
+	dir string
+}
+
+// OpenQueue opens the queue in the client repo.
+func OpenQueue(repo string) (*Queue, error) {
+	repoPath, err := homedir.Expand(repo)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidRepoPath, "path=%s, %v", repo, err)
+	}
+	dir := filepath.Join(repoPath, queueDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, types.Wrap(types.ErrCreateDirFailed, err)
+	}
+	return &Queue{dir: dir}, nil
+}
+
+// Push queues the op with its content or patch, the id of the op is set.
+func (q *Queue) Push(op *QueuedOp, data []byte) error {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	op.QueuedAt = time.Now()
+	// ordered by name as queued
+	for n := op.QueuedAt.UnixNano(); ; n++ {
+		op.Id = fmt.Sprintf("%020d", n)
+		if _, err := os.Stat(q.opPath(op.Id)); os.IsNotExist(err) {
+			break
+		}
+	}
+	if err := os.WriteFile(q.dataPath(op.Id), data, 0644); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return q.save(op)
+}
+
+// List returns the queued ops, oldest first.
+func (q *Queue) List() ([]QueuedOp, error) {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	var ops []QueuedOp
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		var op QueuedOp
+		if err := json.Unmarshal(data, &op); err != nil {
+			return nil, types.Wrapf(types.ErrUnMarshalFailed, "queued op %s: %v", entry.Name(), err)
+		}
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].Id < ops[j].Id
+	})
+	return ops, nil
+}
+
+// Data returns the content or the patch staged with the op.
+func (q *Queue) Data(id string) ([]byte, error) {
+	data, err := os.ReadFile(q.dataPath(id))
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return data, nil
+}
+
+// Remove drops the op and its staged content from the queue.
+func (q *Queue) Remove(id string) error {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	if err := os.Remove(q.opPath(id)); err != nil {
+		if os.IsNotExist(err) {
+			return types.Wrapf(types.ErrNotFound, "queued op %s", id)
+		}
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if err := os.Remove(q.dataPath(id)); err != nil && !os.IsNotExist(err) {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+func (q *Queue) markFailed(op QueuedOp, err error) error {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	op.Error = err.Error()
+	return q.save(&op)
+}
+
+func (q *Queue) save(op *QueuedOp) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return writeFileAtomic(q.opPath(op.Id), data)
+}
+
+func (q *Queue) opPath(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+func (q *Queue) dataPath(id string) string {
+	return filepath.Join(q.dir, id+".data")
+}
+
+// SyncResult is the outcome of a queued op synced, Err is nil if it was sent.
+type SyncResult struct {
+	Op     QueuedOp
+	DataId string
+	// the commit made by the op
+	CommitId string
+	// json pointers of the values the op and the commits made since its base commit changed both, if merged
+	Conflicts []string
+	Err       error
+}
+
+// Reachable returns an error if the gateway or the chain doesn't respond.
+func (sc *SaoClient) Reachable(ctx context.Context) error {
+	if sc.ChainSvcApi == nil {
+		return types.Wrapf(types.ErrInvalidParameters, "the client is not connected to the chain")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reachTimeout)
+	defer cancel()
+
+	if _, err := sc.GetNodeAddress(ctx); err != nil {
+		return err
+	}
+	if _, err := sc.GetLastHeight(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Sync sends the ops of the did queued, oldest first, and removes them from the queue once sent. It stops as soon
+// as the gateway or the chain is unreachable again, the rest is kept for the next sync. The ops failing otherwise,
+// such as the updates of the models committed since their base commits, are marked failed and skipped by the next
+// syncs unless force is set, in which case the patches are applied to the latest commits. The updates queued with
+// Options.Merge are three-way merged with the commits made since their base commits instead.
+func (s *Sdk) Sync(ctx context.Context, q *Queue, force bool) ([]SyncResult, error) {
+	ops, err := q.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SyncResult
+	for _, op := range ops {
+		if op.Owner != s.did.Id || (op.Error != "" && !force) {
+			continue
+		}
+
+		result := s.syncOp(ctx, q, op, force)
+		results = append(results, result)
+		if result.Err == nil {
+			if err := q.Remove(op.Id); err != nil {
+				return results, err
+			}
+			continue
+		}
+		if err := s.client.Reachable(ctx); err != nil {
+			return results, err
+		}
+		if err := q.markFailed(op, result.Err); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (s *Sdk) syncOp(ctx context.Context, q *Queue, op QueuedOp, force bool) SyncResult {
+	result := SyncResult{Op: op}
+	data, err := q.Data(op.Id)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	sdk := &Sdk{client: s.client, did: s.did, GroupId: op.GroupId}
+	switch op.Kind {
+	case QueuedCreate:
+		resp, err := sdk.CreateJSON(ctx, data, op.Options)
+		result.DataId, result.CommitId, result.Err = resp.DataId, resp.DataId, err
+	case QueuedUpdate:
+		latest, err := sdk.LoadLatest(ctx, op.Keyword)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if op.BaseCommitId != "" && op.BaseCommitId != latest.CommitId && op.Options.Merge {
+			resp, conflicts, err := sdk.MergeWithPatch(ctx, latest.DataId, op.BaseCommitId, data, op.Options)
+			result.DataId, result.CommitId, result.Conflicts, result.Err = resp.DataId, resp.CommitId, conflicts, err
+			return result
+		}
+		if op.BaseCommitId != "" && op.BaseCommitId != latest.CommitId && !force {
+			result.Err = types.Wrapf(types.ErrCommitConflict, "model %s is at commit %s, the update is made on %s", latest.DataId, latest.CommitId, op.BaseCommitId)
+			return result
+		}
+		resp, err := sdk.updateLatest(ctx, latest, data, op.Options)
+		result.DataId, result.CommitId, result.Err = resp.DataId, resp.CommitId, err
+	default:
+		result.Err = types.Wrapf(types.ErrInvalidParameters, "unknown queued op %s", op.Kind)
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	apitypes "sao-node/api/types"
+	"sao-node/types"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc"
+)
+
+// RetryPolicy is how the calls to the gateway failing on the way are retried, such as on a connection reset or a
+// timeout. The proposals are signed once and resent as is, so a retry can't order the same model or commit twice.
+type RetryPolicy struct {
+	// attempts of a call, it is not retried if 1 or less
+	Attempts int
+	// wait before the first retry, doubled for each of the next ones up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   3,
+		Backoff:    time.Second,
+		MaxBackoff: 10 * time.Second,
+	}
+}
+
+// IsTransient returns whether the error occurred on the way to or back from the gateway, in which case the gateway
+// may or may not have handled the call.
+func IsTransient(err error) bool {
+	var clientErr *jsonrpc.ErrClient
+	var connErr *jsonrpc.RPCConnectionError
+	return errors.As(err, &clientErr) || errors.As(err, &connErr)
+}
+
+// do calls call until it succeeds, fails for good or the attempts run out. Before a retry, landed checks whether the
+// previous call was handled by the gateway, the call is not sent again if so.
+func (p RetryPolicy) do(ctx context.Context, name string, call func() error, landed func() bool) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || !IsTransient(err) || attempt >= p.Attempts {
+			return err
+		}
+		log.Warnf("%s failed on attempt %d, retry in %s: %v", name, attempt, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+
+		if landed() {
+			log.Infof("%s was handled by the gateway before it failed", name)
+			return nil
+		}
+	}
+}
+
+// CreateWithRetry creates the model as ModelCreate, retrying the transient errors. A retry first loads the model by
+// req, the query request of its data id, and returns it if it is created already.
+func (sc *SaoClient) CreateWithRetry(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) {
+	var resp apitypes.CreateResp
+	err := sc.Retry.do(ctx, "create "+clientProposal.Proposal.DataId, func() error {
+		var err error
+		resp, err = sc.ModelCreate(ctx, req, clientProposal, orderId, content)
+		return err
+	}, func() bool {
+		model, err := sc.ModelLoad(ctx, req)
+		if err != nil || model.DataId != clientProposal.Proposal.DataId {
+			return false
+		}
+		resp = apitypes.CreateResp{
+			DataId: model.DataId,
+			Alias:  model.Alias,
+			Cid:    model.Cid,
+		}
+		return true
+	})
+	return resp, err
+}
+
+// UpdateWithRetry commits the update as ModelUpdate, retrying the transient errors. A retry first loads the latest
+// commit of the model by req and returns it if it is the commit of the update already.
+func (sc *SaoClient) UpdateWithRetry(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error) {
+	commitIds := strings.Split(clientProposal.Proposal.CommitId, "|")
+	commitId := commitIds[len(commitIds)-1]
+
+	var resp apitypes.UpdateResp
+	err := sc.Retry.do(ctx, "update "+clientProposal.Proposal.DataId, func() error {
+		var err error
+		resp, err = sc.ModelUpdate(ctx, req, clientProposal, orderId, patch)
+		return err
+	}, func() bool {
+		model, err := sc.ModelLoad(ctx, req)
+		if err != nil || model.CommitId != commitId {
+			return false
+		}
+		resp = apitypes.UpdateResp{
+			DataId:   model.DataId,
+			CommitId: model.CommitId,
+			Alias:    model.Alias,
+			Cid:      model.Cid,
+		}
+		return true
+	})
+	return resp, err
+}
@@ -0,0 +1,278 @@
+//go:build linux || darwin || freebsd
+
+// Package saofs exposes the models of a did as the files of a FUSE directory. The chain can't list the models
+// of a did, so a model appears in the directory once it is looked up by its alias or dataId, its content is
+// loaded at the first lookup. The written files are stored on flush, the new files are created as models and
+// the existing ones are updated with patches.
+package saofs
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("saofs")
+
+type Model struct {
+	DataId   string
+	Alias    string
+	CommitId string
+	Content  []byte
+}
+
+// Models loads and stores the models of a did, name is an alias or a dataId.
+type Models interface {
+	Load(ctx context.Context, name string) (*Model, error)
+	Create(ctx context.Context, name string, content []byte) (*Model, error)
+	Update(ctx context.Context, model *Model, content []byte) (*Model, error)
+	Delete(ctx context.Context, model *Model) error
+}
+
+type FS struct {
+	root *Dir
+}
+
+func New(models Models) *FS {
+	return &FS{
+		root: &Dir{
+			models: models,
+			files:  make(map[string]*File),
+		},
+	}
+}
+
+func (f *FS) Root() (fs.Node, error) {
+	return f.root, nil
+}
+
+// Mount serves the models at mountpoint until ctx is done or the directory is unmounted.
+func Mount(ctx context.Context, mountpoint string, models Models) error {
+	c, err := fuse.Mount(mountpoint, fuse.FSName("sao"), fuse.Subtype("saofs"))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := fuse.Unmount(mountpoint); err != nil {
+				log.Warnf("unmount %s: %v", mountpoint, err)
+			}
+		case <-done:
+		}
+	}()
+
+	err = fs.Serve(c, New(models))
+	if err != nil {
+		return err
+	}
+	<-c.Ready
+	return c.MountError
+}
+
+// Dir is the directory of the models looked up so far.
+type Dir struct {
+	models Models
+
+	mu    sync.Mutex
+	files map[string]*File
+}
+
+var _ fs.NodeStringLookuper = (*Dir)(nil)
+var _ fs.HandleReadDirAller = (*Dir)(nil)
+var _ fs.NodeCreater = (*Dir)(nil)
+var _ fs.NodeRemover = (*Dir)(nil)
+
+func (d *Dir) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Inode = 1
+	a.Mode = os.ModeDir | 0755
+	return nil
+}
+
+func (d *Dir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if f, ok := d.files[name]; ok {
+		return f, nil
+	}
+	model, err := d.models.Load(ctx, name)
+	if err != nil {
+		log.Debugf("load %s: %v", name, err)
+		return nil, fuse.ENOENT
+	}
+	f := &File{dir: d, name: name, model: model, data: model.Content}
+	d.files[name] = f
+	return f, nil
+}
+
+func (d *Dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dirents := make([]fuse.Dirent, 0, len(d.files))
+	for name := range d.files {
+		dirents = append(dirents, fuse.Dirent{Name: name, Type: fuse.DT_File})
+	}
+	sort.Slice(dirents, func(i, j int) bool {
+		return dirents[i].Name < dirents[j].Name
+	})
+	return dirents, nil
+}
+
+// Create adds a file created as a model on the first flush with content, the models can't be empty.
+func (d *Dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.files[req.Name]; ok {
+		return nil, nil, fuse.EEXIST
+	}
+	f := &File{dir: d, name: req.Name}
+	d.files[req.Name] = f
+	return f, f, nil
+}
+
+func (d *Dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, ok := d.files[req.Name]
+	if !ok {
+		return fuse.ENOENT
+	}
+	if model := f.current(); model != nil {
+		if err := d.models.Delete(ctx, model); err != nil {
+			log.Errorf("delete %s: %v", req.Name, err)
+			return fuse.Errno(syscall.EIO)
+		}
+	}
+	delete(d.files, req.Name)
+	return nil
+}
+
+// File is a model, nil until a created file is stored. It is also the handle of its opens, the content is shared.
+type File struct {
+	dir  *Dir
+	name string
+
+	mu    sync.Mutex
+	model *Model
+	data  []byte
+	dirty bool
+}
+
+var _ fs.NodeOpener = (*File)(nil)
+var _ fs.NodeSetattrer = (*File)(nil)
+var _ fs.NodeFsyncer = (*File)(nil)
+var _ fs.HandleReadAller = (*File)(nil)
+var _ fs.HandleWriter = (*File)(nil)
+var _ fs.HandleFlusher = (*File)(nil)
+
+func (f *File) current() *Model {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.model
+}
+
+func (f *File) Attr(ctx context.Context, a *fuse.Attr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a.Mode = 0644
+	a.Size = uint64(len(f.data))
+	return nil
+}
+
+func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	if req.Flags&fuse.OpenTruncate != 0 {
+		f.mu.Lock()
+		f.data = nil
+		f.dirty = true
+		f.mu.Unlock()
+	}
+	// the content changes with the model commits
+	resp.Flags |= fuse.OpenDirectIO
+	return f, nil
+}
+
+func (f *File) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req.Valid.Size() && req.Size != uint64(len(f.data)) {
+		data := make([]byte, req.Size)
+		copy(data, f.data)
+		f.data = data
+		f.dirty = true
+	}
+	resp.Attr.Mode = 0644
+	resp.Attr.Size = uint64(len(f.data))
+	return nil
+}
+
+func (f *File) ReadAll(ctx context.Context) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]byte(nil), f.data...), nil
+}
+
+func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	end := int(req.Offset) + len(req.Data)
+	if end > len(f.data) {
+		data := make([]byte, end)
+		copy(data, f.data)
+		f.data = data
+	}
+	copy(f.data[req.Offset:], req.Data)
+	f.dirty = true
+	resp.Size = len(req.Data)
+	return nil
+}
+
+func (f *File) Flush(ctx context.Context, req *fuse.FlushRequest) error {
+	return f.store(ctx)
+}
+
+func (f *File) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
+	return f.store(ctx)
+}
+
+// store writes the content back, a created file is kept in memory until it has content.
+func (f *File) store(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.dirty || len(f.data) == 0 {
+		return nil
+	}
+
+	var model *Model
+	var err error
+	if f.model == nil {
+		model, err = f.dir.models.Create(ctx, f.name, f.data)
+	} else {
+		model, err = f.dir.models.Update(ctx, f.model, f.data)
+	}
+	if err != nil {
+		log.Errorf("store %s: %v", f.name, err)
+		return fuse.Errno(syscall.EIO)
+	}
+	f.model = model
+	f.dirty = false
+	return nil
+}
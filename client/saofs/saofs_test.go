@@ -0,0 +1,94 @@
+//go:build linux || darwin || freebsd
+
+package saofs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"bazil.org/fuse"
+	"github.com/stretchr/testify/require"
+)
+
+type memModels struct {
+	models  map[string]*Model
+	updates int
+}
+
+func (m *memModels) Load(ctx context.Context, name string) (*Model, error) {
+	model, ok := m.models[name]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	return model, nil
+}
+
+func (m *memModels) Create(ctx context.Context, name string, content []byte) (*Model, error) {
+	model := &Model{DataId: "data-" + name, Alias: name, CommitId: "data-" + name, Content: content}
+	m.models[name] = model
+	return model, nil
+}
+
+func (m *memModels) Update(ctx context.Context, model *Model, content []byte) (*Model, error) {
+	m.updates++
+	updated := &Model{DataId: model.DataId, Alias: model.Alias, CommitId: fmt.Sprintf("commit-%d", m.updates), Content: content}
+	m.models[model.Alias] = updated
+	return updated, nil
+}
+
+func (m *memModels) Delete(ctx context.Context, model *Model) error {
+	delete(m.models, model.Alias)
+	return nil
+}
+
+func TestFS(t *testing.T) {
+	ctx := context.Background()
+	models := &memModels{models: map[string]*Model{
+		"profile": {DataId: "data-profile", Alias: "profile", CommitId: "data-profile", Content: []byte(`{"name":"sao"}`)},
+	}}
+	root, err := New(models).Root()
+	require.NoError(t, err)
+	dir := root.(*Dir)
+
+	// the models are listed after the lookup
+	dirents, err := dir.ReadDirAll(ctx)
+	require.NoError(t, err)
+	require.Empty(t, dirents)
+	_, err = dir.Lookup(ctx, "missing")
+	require.Equal(t, fuse.ENOENT, err)
+
+	node, err := dir.Lookup(ctx, "profile")
+	require.NoError(t, err)
+	f := node.(*File)
+	data, err := f.ReadAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"sao"}`, string(data))
+
+	// truncate and rewrite, stored on flush
+	require.NoError(t, f.Setattr(ctx, &fuse.SetattrRequest{Valid: fuse.SetattrSize, Size: 0}, &fuse.SetattrResponse{}))
+	require.NoError(t, f.Write(ctx, &fuse.WriteRequest{Data: []byte(`{"name":"saofs"}`)}, &fuse.WriteResponse{}))
+	require.Equal(t, `{"name":"sao"}`, string(models.models["profile"].Content))
+	require.NoError(t, f.Flush(ctx, &fuse.FlushRequest{}))
+	require.Equal(t, `{"name":"saofs"}`, string(models.models["profile"].Content))
+	require.Equal(t, 1, models.updates)
+	require.NoError(t, f.Flush(ctx, &fuse.FlushRequest{}))
+	require.Equal(t, 1, models.updates)
+
+	// an empty created file is not stored
+	_, h, err := dir.Create(ctx, &fuse.CreateRequest{Name: "note"}, &fuse.CreateResponse{})
+	require.NoError(t, err)
+	require.NoError(t, h.(*File).Flush(ctx, &fuse.FlushRequest{}))
+	require.NotContains(t, models.models, "note")
+	require.NoError(t, h.(*File).Write(ctx, &fuse.WriteRequest{Data: []byte(`{}`)}, &fuse.WriteResponse{}))
+	require.NoError(t, h.(*File).Flush(ctx, &fuse.FlushRequest{}))
+	require.Contains(t, models.models, "note")
+
+	dirents, err = dir.ReadDirAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []fuse.Dirent{{Name: "note", Type: fuse.DT_File}, {Name: "profile", Type: fuse.DT_File}}, dirents)
+
+	require.NoError(t, dir.Remove(ctx, &fuse.RemoveRequest{Name: "profile"}))
+	require.NotContains(t, models.models, "profile")
+	require.Equal(t, fuse.ENOENT, dir.Remove(ctx, &fuse.RemoveRequest{Name: "profile"}))
+}
@@ -0,0 +1,262 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	apitypes "sao-node/api/types"
+	"sao-node/node/model/schema/migration"
+	"sao-node/node/model/schema/registry"
+	"sao-node/node/model/schema/validator"
+	"sao-node/types"
+	"sao-node/utils"
+
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// PublishSchema publishes the version of the schema to the registry and returns the data id of its model. The
+// schema must be a valid json schema, a published version is never replaced. The first did publishing a schema owns
+// its name, the others can't publish any version of it.
+func (s *Sdk) PublishSchema(ctx context.Context, name string, version string, schema []byte, opts ModelOptions) (string, error) {
+	if err := registry.ValidateName(name); err != nil {
+		return "", err
+	}
+	version, err := registry.CanonicalVersion(version)
+	if err != nil {
+		return "", err
+	}
+	if _, err := validator.NewDataModelValidator(name, string(schema), ""); err != nil {
+		return "", types.Wrap(types.ErrInvalidSchema, err)
+	}
+
+	index, owner, err := s.SchemaIndex(ctx, name)
+	exists := err == nil
+	if err != nil && !types.ErrNotFound.Is(err) {
+		return "", err
+	}
+	if exists && owner != s.did.Id {
+		return "", types.Wrapf(types.ErrConflictName, "schema %s is owned by %s", name, owner)
+	}
+	if _, ok := index.Versions[version]; ok {
+		return "", types.Wrapf(types.ErrConflictId, "schema %s@%s is published already", name, version)
+	}
+
+	versionOpts := opts
+	versionOpts.DataId = ""
+	versionOpts.Alias = registry.VersionAlias(name, version)
+	created, err := s.CreateJSON(ctx, json.RawMessage(schema), versionOpts)
+	if err != nil {
+		return "", err
+	}
+
+	index.Versions[version] = created.DataId
+	indexOpts := opts
+	indexOpts.DataId = registry.IndexDataId(name)
+	indexOpts.Alias = registry.IndexAlias(name)
+	if exists {
+		_, err = s.UpdateJSON(ctx, indexOpts.DataId, index, indexOpts)
+	} else {
+		_, err = s.CreateJSON(ctx, index, indexOpts)
+	}
+	if err != nil {
+		return "", err
+	}
+	return created.DataId, nil
+}
+
+// SchemaIndex returns the index of the published versions of the schema and the did owning it, the error is
+// ErrNotFound if no version is published.
+func (s *Sdk) SchemaIndex(ctx context.Context, name string) (registry.Index, string, error) {
+	if err := registry.ValidateName(name); err != nil {
+		return registry.Index{}, "", err
+	}
+	dataId := registry.IndexDataId(name)
+	meta, err := s.client.GetMeta(ctx, dataId)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return registry.NewIndex(name), "", types.Wrapf(types.ErrNotFound, "schema %s", name)
+		}
+		return registry.Index{}, "", types.Wrap(types.ErrQueryMetadataFailed, err)
+	}
+
+	resp, err := s.LoadShared(ctx, dataId)
+	if err != nil {
+		return registry.Index{}, "", err
+	}
+	index := registry.NewIndex(name)
+	if err := json.Unmarshal([]byte(resp.Content), &index); err != nil || index.Name != name {
+		return registry.Index{}, "", types.Wrapf(types.ErrInvalidSchema, "invalid index of schema %s", name)
+	}
+	if index.Versions == nil {
+		index.Versions = make(map[string]string)
+	}
+	return index, meta.Metadata.Owner, nil
+}
+
+// LoadSchema loads the published schema version the reference resolves to, see registry.ParseRef, and returns the
+// version resolved.
+func (s *Sdk) LoadSchema(ctx context.Context, ref string) (string, apitypes.LoadResp, error) {
+	name, query, ok := registry.ParseRef(ref)
+	if !ok {
+		return "", apitypes.LoadResp{}, types.Wrapf(types.ErrInvalidParameters, "invalid schema reference %s, name@version expected", ref)
+	}
+	index, _, err := s.SchemaIndex(ctx, name)
+	if err != nil {
+		return "", apitypes.LoadResp{}, err
+	}
+	version, dataId, err := index.Resolve(query)
+	if err != nil {
+		return "", apitypes.LoadResp{}, err
+	}
+	resp, err := s.LoadShared(ctx, dataId)
+	return version, resp, err
+}
+
+// ListSchemas returns the names of the schemas owned by the did, in alphabetical order.
+func (s *Sdk) ListSchemas(ctx context.Context, owner string) ([]string, error) {
+	metas, err := s.client.ListMetadata(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, meta := range metas {
+		// the index models, not the models of the versions
+		if strings.HasPrefix(meta.Alias, registry.AliasPrefix) && !strings.Contains(meta.Alias, "@") {
+			names = append(names, strings.TrimPrefix(meta.Alias, registry.AliasPrefix))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SchemaMigration is the outcome of upgrading a model to a new version of its schema, CommitId is the upgraded
+// commit, empty in a dry run or if Err is not nil.
+type SchemaMigration struct {
+	DataId   string
+	Alias    string
+	CommitId string
+	Err      error
+}
+
+// MigrateSchema upgrades the models referring to the schema version from resolves to in their @context, by
+// name@version or by data id, to the version to resolves to. Each model is transformed, checked against the new
+// version and committed, its tags, rule and extend info are kept. The models of the did on the platform of the Sdk
+// are looked up unless dataIds is set, nothing is committed in a dry run. A model failing to upgrade doesn't stop
+// the others, its error is in its result.
+func (s *Sdk) MigrateSchema(ctx context.Context, from string, to string, transform migration.Transform, dataIds []string, dryRun bool, opts ModelOptions) ([]SchemaMigration, error) {
+	fromName, fromQuery, ok := registry.ParseRef(from)
+	if !ok {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid schema reference %s, name@version expected", from)
+	}
+	fromIndex, _, err := s.SchemaIndex(ctx, fromName)
+	if err != nil {
+		return nil, err
+	}
+	fromVersion, fromDataId, err := fromIndex.Resolve(fromQuery)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, toSchema, err := s.LoadSchema(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	toName, _, _ := registry.ParseRef(to)
+	toRef := toName + "@" + toVersion
+	if toRef == fromName+"@"+fromVersion {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "%s and %s are the same version", from, to)
+	}
+
+	match := func(ref string) bool {
+		if ref == fromDataId {
+			return true
+		}
+		name, query, ok := registry.ParseRef(ref)
+		if !ok || name != fromName {
+			return false
+		}
+		version, _, err := fromIndex.Resolve(query)
+		return err == nil && version == fromVersion
+	}
+
+	metas, err := s.migrationCandidates(ctx, dataIds)
+	if err != nil {
+		return nil, err
+	}
+	var results []SchemaMigration
+	for _, meta := range metas {
+		latest, err := s.LoadLatest(ctx, meta.DataId)
+		if err != nil {
+			results = append(results, SchemaMigration{DataId: meta.DataId, Alias: meta.Alias, Err: err})
+			continue
+		}
+		if !migration.References([]byte(latest.Content), match) {
+			continue
+		}
+
+		result := SchemaMigration{DataId: latest.DataId, Alias: latest.Alias}
+		result.CommitId, result.Err = s.migrateModel(ctx, latest, meta, transform, match, toRef, toSchema.Content, dryRun, opts)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *Sdk) migrateModel(ctx context.Context, latest apitypes.LoadResp, meta modeltypes.Metadata, transform migration.Transform, match func(string) bool, toRef string, toSchema string, dryRun bool, opts ModelOptions) (string, error) {
+	upgraded, err := migration.Upgrade(ctx, []byte(latest.Content), transform, match, toRef)
+	if err != nil {
+		return "", err
+	}
+	v, err := validator.NewDataModelValidator(latest.Alias, toSchema, "")
+	if err != nil {
+		return "", types.Wrap(types.ErrInvalidSchema, err)
+	}
+	if err := v.Validate(jsoniter.Get(upgraded)); err != nil {
+		return "", err
+	}
+	if dryRun {
+		return "", nil
+	}
+
+	patch, err := utils.GeneratePatch(latest.Content, string(upgraded))
+	if err != nil {
+		return "", err
+	}
+	opts.Tags = meta.Tags
+	opts.Rule = meta.Rule
+	opts.ExtendInfo = meta.ExtendInfo
+	resp, err := s.updateLatest(ctx, latest, []byte(patch), opts)
+	if err != nil {
+		return "", err
+	}
+	return resp.CommitId, nil
+}
+
+// migrationCandidates returns the metadata of the models, or of the models of the did on the platform of the Sdk
+// other than the registry ones if dataIds is empty.
+func (s *Sdk) migrationCandidates(ctx context.Context, dataIds []string) ([]modeltypes.Metadata, error) {
+	if len(dataIds) > 0 {
+		metas := make([]modeltypes.Metadata, 0, len(dataIds))
+		for _, dataId := range dataIds {
+			resp, err := s.client.GetMeta(ctx, dataId)
+			if err != nil {
+				return nil, types.Wrap(types.ErrQueryMetadataFailed, err)
+			}
+			metas = append(metas, resp.Metadata)
+		}
+		return metas, nil
+	}
+
+	all, err := s.client.ListMetadata(ctx, s.did.Id)
+	if err != nil {
+		return nil, err
+	}
+	var metas []modeltypes.Metadata
+	for _, meta := range all {
+		if meta.GroupId == s.GroupId && !strings.HasPrefix(meta.Alias, registry.AliasPrefix) {
+			metas = append(metas, meta)
+		}
+	}
+	return metas, nil
+}
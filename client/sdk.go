@@ -0,0 +1,360 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	apitypes "sao-node/api/types"
+	"sao-node/chain"
+	"sao-node/types"
+	"sao-node/utils"
+	"sync"
+	"time"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+// ModelOptions are the options of the models created or updated by a Sdk.
+type ModelOptions struct {
+	// data id of a new model, generated if empty
+	DataId string
+	// alias of a new model, the cid of its content if empty
+	Alias      string
+	Tags       []string
+	Rule       string
+	ExtendInfo string
+	// how many days to store the model
+	Duration int
+	Replica  int
+	// how many epochs to wait for the content to be stored
+	Timeout int
+	// the queued updates made on stale commits are merged with the commits made since, instead of failing
+	Merge bool
+}
+
+func DefaultModelOptions() ModelOptions {
+	return ModelOptions{
+		Duration: 365,
+		Replica:  1,
+		Timeout:  60,
+	}
+}
+
+// how many times MergeWithPatch merges an update committed concurrently
+const maxMergeAttempts = 3
+
+// Sdk manages the models of a did through the gateway of the client, the proposals of the did are built and signed
+// by its methods, so Go applications don't need to.
+type Sdk struct {
+	client *SaoClient
+	did    *saodid.DidManager
+	// platform of the models, the group id of the client config by default
+	GroupId string
+
+	lk             sync.Mutex
+	gatewayAddress string
+}
+
+// NewSdk returns the Sdk of the did on the client, the client must be connected to the chain, which the proposals
+// are checked against.
+func NewSdk(client *SaoClient, didManager *saodid.DidManager) (*Sdk, error) {
+	if client.ChainSvcApi == nil {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "the client is not connected to the chain")
+	}
+	return &Sdk{
+		client:  client,
+		did:     didManager,
+		GroupId: client.Cfg.GroupId,
+	}, nil
+}
+
+// Did returns the did owning the models.
+func (s *Sdk) Did() string {
+	return s.did.Id
+}
+
+// CreateJSON creates a model of the content, which is marshaled to json unless it is []byte or json.RawMessage
+// already.
+func (s *Sdk) CreateJSON(ctx context.Context, content interface{}, opts ModelOptions) (apitypes.CreateResp, error) {
+	data, err := marshalContent(content)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	contentCid, err := utils.CalculateCid(data)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
+	dataId := opts.DataId
+	if dataId == "" {
+		dataId = utils.GenerateDataId(s.did.Id + s.GroupId)
+	}
+	proposal := s.proposal(gatewayAddress, opts)
+	proposal.DataId = dataId
+	proposal.Alias = opts.Alias
+	proposal.Cid = contentCid.String()
+	proposal.CommitId = dataId
+	proposal.Size_ = uint64(len(data))
+	if proposal.Alias == "" {
+		proposal.Alias = proposal.Cid
+	}
+
+	orderProposal, err := BuildOrderProposal(s.did, proposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	request, err := s.queryRequest(ctx, gatewayAddress, dataId)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	return s.client.CreateWithRetry(ctx, request, orderProposal, 0, data)
+}
+
+// LoadLatest loads the latest commit of the model, keyword is the data id or the alias of the model.
+func (s *Sdk) LoadLatest(ctx context.Context, keyword string) (apitypes.LoadResp, error) {
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+	request, err := s.queryRequest(ctx, gatewayAddress, keyword)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+	return s.client.ModelLoad(ctx, request)
+}
+
+// LoadResolved loads the latest commit of the model like LoadLatest, with the {"$ref": "sao://<dataId>"} links in its
+// content replaced by the contents of the linked models up to depth levels deep by the gateway.
+func (s *Sdk) LoadResolved(ctx context.Context, keyword string, depth int) (apitypes.LoadResp, error) {
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+	request, err := s.queryRequest(ctx, gatewayAddress, keyword)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+	request.ResolveDepth = depth
+	return s.client.ModelLoad(ctx, request)
+}
+
+// LoadShared loads the latest commit of a model of any owner by its data id, such as a published schema.
+func (s *Sdk) LoadShared(ctx context.Context, dataId string) (apitypes.LoadResp, error) {
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+	request, err := BuildQueryRequest(ctx, s.did, saotypes.QueryProposal{
+		Owner:   "all",
+		Keyword: dataId,
+	}, s.client, gatewayAddress)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+	return s.client.ModelLoad(ctx, request)
+}
+
+// UpdateWithPatch applies the json patch to the latest commit of the model and commits the result. The tags, the
+// rule and the extend info of the model are replaced by the ones of opts.
+func (s *Sdk) UpdateWithPatch(ctx context.Context, keyword string, patch []byte, opts ModelOptions) (apitypes.UpdateResp, error) {
+	latest, err := s.LoadLatest(ctx, keyword)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	return s.updateLatest(ctx, latest, patch, opts)
+}
+
+// UpdateJSON commits the content as the new commit of the model, the patch from the latest commit is generated.
+func (s *Sdk) UpdateJSON(ctx context.Context, keyword string, content interface{}, opts ModelOptions) (apitypes.UpdateResp, error) {
+	data, err := marshalContent(content)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	latest, err := s.LoadLatest(ctx, keyword)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	patch, err := utils.GeneratePatch(latest.Content, string(data))
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	return s.updateLatest(ctx, latest, []byte(patch), opts)
+}
+
+// MergeWithPatch commits the json patch made on the commit baseCommitId of the model, the gateway three-way merges
+// it with the commits made since instead of failing, the values changed on both sides are set by the patch and
+// returned as conflicts. The merge is done again if the model is committed once more before the merged content.
+func (s *Sdk) MergeWithPatch(ctx context.Context, keyword string, baseCommitId string, patch []byte, opts ModelOptions) (apitypes.UpdateResp, []string, error) {
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return apitypes.UpdateResp{}, nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		request, err := s.queryRequest(ctx, gatewayAddress, keyword)
+		if err != nil {
+			return apitypes.UpdateResp{}, nil, err
+		}
+		merged, err := s.client.ModelMerge(ctx, request, baseCommitId, patch)
+		if err != nil {
+			return apitypes.UpdateResp{}, nil, err
+		}
+		if merged.Patch == "[]" {
+			// the patch changes nothing on the latest commit
+			return apitypes.UpdateResp{DataId: merged.DataId, Alias: merged.Alias, CommitId: merged.CommitId}, merged.Conflicts, nil
+		}
+
+		latest := apitypes.LoadResp{DataId: merged.DataId, Alias: merged.Alias, CommitId: merged.CommitId}
+		resp, err := s.updateContent(ctx, latest, []byte(merged.Content), []byte(merged.Patch), opts)
+		if err == nil || attempt >= maxMergeAttempts {
+			return resp, merged.Conflicts, err
+		}
+		if current, loadErr := s.LoadLatest(ctx, merged.DataId); loadErr != nil || current.CommitId == merged.CommitId {
+			// failed for another reason than a commit made meanwhile
+			return resp, merged.Conflicts, err
+		}
+	}
+}
+
+func (s *Sdk) updateLatest(ctx context.Context, latest apitypes.LoadResp, patch []byte, opts ModelOptions) (apitypes.UpdateResp, error) {
+	// the gateway applies the patch the same, the cid and the size of the result are signed beforehand
+	content, err := utils.ApplyPatch([]byte(latest.Content), patch)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	return s.updateContent(ctx, latest, content, patch, opts)
+}
+
+// updateContent commits content, the result of the patch on the latest commit.
+func (s *Sdk) updateContent(ctx context.Context, latest apitypes.LoadResp, content []byte, patch []byte, opts ModelOptions) (apitypes.UpdateResp, error) {
+	contentCid, err := utils.CalculateCid(content)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+
+	proposal := s.proposal(gatewayAddress, opts)
+	proposal.DataId = latest.DataId
+	proposal.Alias = latest.Alias
+	proposal.Cid = contentCid.String()
+	proposal.CommitId = latest.CommitId + "|" + utils.GenerateCommitId(s.did.Id+s.GroupId)
+	proposal.Size_ = uint64(len(content))
+
+	orderProposal, err := BuildOrderProposal(s.did, proposal)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	request, err := s.queryRequest(ctx, gatewayAddress, latest.DataId)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	return s.client.UpdateWithRetry(ctx, request, orderProposal, 0, patch)
+}
+
+// Grant sets the dids which can read and the dids which can read and write the model, the dids not listed lose
+// their access.
+func (s *Sdk) Grant(ctx context.Context, dataId string, readonlyDids []string, readwriteDids []string) error {
+	request, err := BuildPermissionProposal(s.did, saotypes.PermissionProposal{
+		Owner:         s.did.Id,
+		DataId:        dataId,
+		ReadonlyDids:  readonlyDids,
+		ReadwriteDids: readwriteDids,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.ModelUpdatePermission(ctx, request, true)
+	return err
+}
+
+// Renew renews the orders of the models for days, it returns the result of each model by data id.
+func (s *Sdk) Renew(ctx context.Context, dataIds []string, days int, timeout int) (map[string]string, error) {
+	request, err := BuildRenewProposal(s.did, saotypes.RenewProposal{
+		Owner:    s.did.Id,
+		Duration: blocksOfDays(days),
+		Timeout:  int32(timeout),
+		Data:     dataIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.ModelRenewOrder(ctx, request, true)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// gateway returns the node address of the gateway the client is connected to, it is queried once.
+func (s *Sdk) gateway(ctx context.Context) (string, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.gatewayAddress == "" {
+		address, err := s.client.GetNodeAddress(ctx)
+		if err != nil {
+			return "", err
+		}
+		s.gatewayAddress = address
+	}
+	return s.gatewayAddress, nil
+}
+
+func (s *Sdk) proposal(gatewayAddress string, opts ModelOptions) saotypes.Proposal {
+	return saotypes.Proposal{
+		Owner:      s.did.Id,
+		Provider:   gatewayAddress,
+		GroupId:    s.GroupId,
+		Duration:   blocksOfDays(opts.Duration),
+		Replica:    int32(opts.Replica),
+		Timeout:    int32(opts.Timeout),
+		Tags:       opts.Tags,
+		Rule:       opts.Rule,
+		Operation:  1,
+		ExtendInfo: opts.ExtendInfo,
+	}
+}
+
+func (s *Sdk) queryRequest(ctx context.Context, gatewayAddress string, keyword string) (*types.MetadataProposal, error) {
+	proposal := saotypes.QueryProposal{
+		Owner:   s.did.Id,
+		Keyword: keyword,
+		GroupId: s.GroupId,
+	}
+	if !utils.IsDataId(keyword) {
+		proposal.KeywordType = 2
+	}
+	return BuildQueryRequest(ctx, s.did, proposal, s.client, gatewayAddress)
+}
+
+func blocksOfDays(days int) uint64 {
+	return uint64(time.Duration(60*60*24*days) * time.Second / chain.Blocktime)
+}
+
+func marshalContent(content interface{}) ([]byte, error) {
+	var data []byte
+	switch c := content.(type) {
+	case []byte:
+		data = c
+	case json.RawMessage:
+		data = c
+	default:
+		var err error
+		data, err = json.Marshal(content)
+		if err != nil {
+			return nil, types.Wrap(types.ErrMarshalFailed, err)
+		}
+	}
+	if !json.Valid(data) {
+		return nil, types.Wrapf(types.ErrInvalidContent, "the content is not json")
+	}
+	return data, nil
+}
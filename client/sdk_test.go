@@ -0,0 +1,762 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"sao-node/api"
+	apitypes "sao-node/api/types"
+	"sao-node/chain"
+	"sao-node/node/model/eventlog"
+	"sao-node/node/model/filedelta"
+	"sao-node/types"
+	"sao-node/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saokey "github.com/SaoNetwork/sao-did/key"
+	saodidtypes "github.com/SaoNetwork/sao-did/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	"github.com/dvsekhvalnov/jose2go/base64url"
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type sdkChain struct {
+	chain.ChainSvcApi
+	metas []modeltypes.Metadata
+	// peer info of the nodes, "peerInfo" if empty
+	peerInfo string
+}
+
+func (c sdkChain) ListMetadata(context.Context, string) ([]modeltypes.Metadata, error) {
+	return c.metas, nil
+}
+
+func (sdkChain) GetLastHeight(context.Context) (int64, error) {
+	return 100, nil
+}
+
+func (c sdkChain) GetNodePeer(context.Context, string) (string, error) {
+	if c.peerInfo != "" {
+		return c.peerInfo, nil
+	}
+	return "peerInfo", nil
+}
+
+// sdkGateway keeps the latest commit of a single model
+type sdkGateway struct {
+	api.SaoApi
+	latest apitypes.LoadResp
+	// contents of the commits by commit id
+	commits map[string]string
+	// the commits by version
+	versions []apitypes.LoadResp
+	order    *types.OrderStoreProposal
+	revoked  *types.ShareRevokeProposal
+	offline  bool
+	// calls failing on the way to the gateway, then calls whose responses are lost on the way back
+	unsent, lost int
+	calls        int
+}
+
+func (g *sdkGateway) transientError(sent bool) error {
+	if !sent && g.unsent > 0 {
+		g.unsent--
+		return &jsonrpc.ErrClient{}
+	}
+	if sent && g.lost > 0 {
+		g.lost--
+		return &jsonrpc.ErrClient{}
+	}
+	return nil
+}
+
+func (g *sdkGateway) GetNodeAddress(context.Context) (string, error) {
+	if g.offline {
+		return "", types.ErrSendRequestFailed
+	}
+	return "gateway", nil
+}
+
+func (g *sdkGateway) ModelCreate(_ context.Context, _ *types.MetadataProposal, orderProposal *types.OrderStoreProposal, _ uint64, content []byte) (apitypes.CreateResp, error) {
+	if g.offline {
+		return apitypes.CreateResp{}, types.ErrSendRequestFailed
+	}
+	g.calls++
+	if err := g.transientError(false); err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	if g.latest.DataId == orderProposal.Proposal.DataId {
+		return apitypes.CreateResp{}, types.ErrConflictId
+	}
+	g.order = orderProposal
+	g.latest = apitypes.LoadResp{
+		DataId:   orderProposal.Proposal.DataId,
+		Alias:    orderProposal.Proposal.Alias,
+		CommitId: orderProposal.Proposal.CommitId,
+		Version:  "v0",
+		Content:  string(content),
+	}
+	g.commits = map[string]string{g.latest.CommitId: g.latest.Content}
+	g.versions = []apitypes.LoadResp{g.latest}
+	if err := g.transientError(true); err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	return apitypes.CreateResp{DataId: g.latest.DataId, Alias: g.latest.Alias}, nil
+}
+
+func (g *sdkGateway) ModelLoad(_ context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	if req.Proposal.Keyword != g.latest.DataId && req.Proposal.Keyword != g.latest.Alias {
+		return apitypes.LoadResp{}, types.ErrNotFound
+	}
+	if req.Proposal.Version != "" {
+		version, err := strconv.Atoi(strings.TrimPrefix(req.Proposal.Version, "v"))
+		if err != nil || version >= len(g.versions) {
+			return apitypes.LoadResp{}, types.ErrInvalidVersion
+		}
+		return g.versions[version], nil
+	}
+	return g.latest, nil
+}
+
+func (g *sdkGateway) ModelLoadMeta(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	latest, err := g.ModelLoad(ctx, req)
+	latest.Content = ""
+	return latest, err
+}
+
+func (g *sdkGateway) ModelShareRevoke(_ context.Context, req *types.ShareRevokeProposal) error {
+	g.revoked = req
+	return nil
+}
+
+func (g *sdkGateway) ModelUpdate(_ context.Context, _ *types.MetadataProposal, orderProposal *types.OrderStoreProposal, _ uint64, patch []byte) (apitypes.UpdateResp, error) {
+	g.calls++
+	if err := g.transientError(false); err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	commitIds := strings.Split(orderProposal.Proposal.CommitId, "|")
+	if commitIds[0] != g.latest.CommitId {
+		return apitypes.UpdateResp{}, types.ErrInvalidCommitInfo
+	}
+	g.order = orderProposal
+	var content []byte
+	if filedelta.IsDelta(patch) {
+		// the delta is made on the file committed, which isn't loaded with the large files
+		var file bytes.Buffer
+		header, err := filedelta.Resolve(strings.NewReader(g.commits[commitIds[0]]), patch, &file)
+		if err != nil {
+			return apitypes.UpdateResp{}, err
+		}
+		content = file.Bytes()
+		g.latest.Cid = header.Cid
+	} else {
+		var err error
+		content, err = utils.ApplyPatch([]byte(g.latest.Content), patch)
+		if err != nil {
+			return apitypes.UpdateResp{}, err
+		}
+	}
+	g.latest.Content = string(content)
+	g.latest.CommitId = commitIds[1]
+	g.latest.Version = fmt.Sprintf("v%d", len(g.versions))
+	g.commits[g.latest.CommitId] = g.latest.Content
+	g.versions = append(g.versions, g.latest)
+	if err := g.transientError(true); err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	return apitypes.UpdateResp{DataId: g.latest.DataId, CommitId: g.latest.CommitId}, nil
+}
+
+func (g *sdkGateway) ModelMerge(_ context.Context, _ *types.MetadataProposal, baseCommitId string, patch []byte) (apitypes.MergeResp, error) {
+	base, ok := g.commits[baseCommitId]
+	if !ok {
+		return apitypes.MergeResp{}, types.ErrInvalidCommitInfo
+	}
+	ours, err := utils.ApplyPatch([]byte(base), patch)
+	if err != nil {
+		return apitypes.MergeResp{}, err
+	}
+	merged, conflicts, err := utils.MergeJSON([]byte(base), ours, []byte(g.latest.Content))
+	if err != nil {
+		return apitypes.MergeResp{}, err
+	}
+	mergePatch, err := utils.GeneratePatch(g.latest.Content, string(merged))
+	if err != nil {
+		return apitypes.MergeResp{}, err
+	}
+	return apitypes.MergeResp{
+		DataId:    g.latest.DataId,
+		Alias:     g.latest.Alias,
+		CommitId:  g.latest.CommitId,
+		Content:   string(merged),
+		Patch:     mergePatch,
+		Conflicts: conflicts,
+	}, nil
+}
+
+func newDidManager(t *testing.T) saodid.DidManager {
+	secret, err := hex.DecodeString("a3709843cbd4e72d7215512e28385123b44eab5e27f36001d74ee1cff671502d")
+	require.NoError(t, err)
+	provider, err := saokey.NewSecp256k1Provider(secret)
+	require.NoError(t, err)
+	didManager := saodid.NewDidManager(provider, saokey.NewKeyResolver())
+	_, err = didManager.Authenticate([]string{}, "")
+	require.NoError(t, err)
+	return didManager
+}
+
+func TestSdk(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+
+	_, err := NewSdk(&SaoClient{Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.Error(t, err)
+
+	gateway := &sdkGateway{}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+
+	opts := DefaultModelOptions()
+	opts.Alias = "profile"
+	created, err := sdk.CreateJSON(ctx, map[string]string{"name": "sao"}, opts)
+	require.NoError(t, err)
+	require.Equal(t, "profile", created.Alias)
+	require.Equal(t, created.DataId, gateway.order.Proposal.CommitId)
+	require.Equal(t, "gateway", gateway.order.Proposal.Provider)
+
+	// the order proposal is signed by the did
+	proposalBytes, err := gateway.order.Proposal.Marshal()
+	require.NoError(t, err)
+	_, err = didManager.VerifyJWS(saodidtypes.GeneralJWS{
+		Payload:    base64url.Encode(proposalBytes),
+		Signatures: []saodidtypes.JwsSignature{saodidtypes.JwsSignature(gateway.order.JwsSignature)},
+	})
+	require.NoError(t, err)
+
+	updated, err := sdk.UpdateWithPatch(ctx, "profile", []byte(`[{"op":"add","path":"/age","value":1}]`), opts)
+	require.NoError(t, err)
+	require.Contains(t, gateway.order.Proposal.CommitId, created.DataId+"|")
+	contentCid, err := utils.CalculateCid([]byte(gateway.latest.Content))
+	require.NoError(t, err)
+	require.Equal(t, contentCid.String(), gateway.order.Proposal.Cid)
+	require.Equal(t, uint64(len(gateway.latest.Content)), gateway.order.Proposal.Size_)
+
+	latest, err := sdk.LoadLatest(ctx, created.DataId)
+	require.NoError(t, err)
+	require.Equal(t, updated.CommitId, latest.CommitId)
+	require.JSONEq(t, `{"name":"sao","age":1}`, latest.Content)
+
+	_, err = sdk.UpdateJSON(ctx, "profile", map[string]interface{}{"name": "sao", "age": 2}, opts)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"sao","age":2}`, gateway.latest.Content)
+
+	_, err = sdk.CreateJSON(ctx, []byte("not json"), opts)
+	require.Error(t, err)
+}
+
+func TestQueueSync(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	gateway := &sdkGateway{offline: true}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+	queue, err := OpenQueue(t.TempDir())
+	require.NoError(t, err)
+
+	opts := DefaultModelOptions()
+	opts.Alias = "notes"
+	push := func(op QueuedOp, data string) {
+		op.Owner, op.GroupId, op.Options = didManager.Id, sdk.GroupId, opts
+		require.NoError(t, queue.Push(&op, []byte(data)))
+	}
+	push(QueuedOp{Kind: QueuedCreate}, `{"notes":[]}`)
+	push(QueuedOp{Kind: QueuedUpdate, Keyword: "notes"}, `[{"op":"add","path":"/notes/-","value":"a"}]`)
+	push(QueuedOp{Kind: QueuedUpdate, Keyword: "notes", BaseCommitId: "stale"}, `[{"op":"add","path":"/notes/-","value":"b"}]`)
+
+	// still offline, nothing is sent or marked
+	results, err := sdk.Sync(ctx, queue, false)
+	require.Error(t, err)
+	require.Len(t, results, 1)
+	ops, err := queue.List()
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+	require.Empty(t, ops[0].Error)
+
+	gateway.offline = false
+	results, err = sdk.Sync(ctx, queue, false)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+	require.True(t, types.ErrCommitConflict.Is(results[2].Err))
+	require.JSONEq(t, `{"notes":["a"]}`, gateway.latest.Content)
+
+	// the conflict is kept until forced
+	ops, err = queue.List()
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	require.NotEmpty(t, ops[0].Error)
+	results, err = sdk.Sync(ctx, queue, false)
+	require.NoError(t, err)
+	require.Empty(t, results)
+
+	results, err = sdk.Sync(ctx, queue, true)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	require.JSONEq(t, `{"notes":["a","b"]}`, gateway.latest.Content)
+	ops, err = queue.List()
+	require.NoError(t, err)
+	require.Empty(t, ops)
+}
+
+func TestMergeWithPatch(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	gateway := &sdkGateway{}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+	opts := DefaultModelOptions()
+
+	created, err := sdk.CreateJSON(ctx, map[string]interface{}{"title": "a", "tags": []string{}}, opts)
+	require.NoError(t, err)
+	_, err = sdk.UpdateWithPatch(ctx, created.DataId, []byte(`[{"op":"add","path":"/tags/-","value":"x"}]`), opts)
+	require.NoError(t, err)
+
+	// made on the first commit, merged with the second
+	updated, conflicts, err := sdk.MergeWithPatch(ctx, created.DataId, created.DataId, []byte(`[{"op":"replace","path":"/title","value":"b"},{"op":"add","path":"/tags/-","value":"y"}]`), opts)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+	require.Equal(t, gateway.latest.CommitId, updated.CommitId)
+	require.JSONEq(t, `{"title":"b","tags":["x","y"]}`, gateway.latest.Content)
+
+	// the title is changed on both sides, the patch wins
+	_, conflicts, err = sdk.MergeWithPatch(ctx, created.DataId, created.DataId, []byte(`[{"op":"replace","path":"/title","value":"c"}]`), opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/title"}, conflicts)
+	require.JSONEq(t, `{"title":"c","tags":["x","y"]}`, gateway.latest.Content)
+
+	// the queued updates on stale commits are merged by the sync
+	queue, err := OpenQueue(t.TempDir())
+	require.NoError(t, err)
+	opts.Merge = true
+	require.NoError(t, queue.Push(&QueuedOp{
+		Kind:         QueuedUpdate,
+		Owner:        didManager.Id,
+		GroupId:      sdk.GroupId,
+		Keyword:      created.DataId,
+		BaseCommitId: created.DataId,
+		Options:      opts,
+	}, []byte(`[{"op":"add","path":"/tags/-","value":"z"}]`)))
+	results, err := sdk.Sync(ctx, queue, false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	require.JSONEq(t, `{"title":"c","tags":["x","y","z"]}`, gateway.latest.Content)
+}
+
+func TestOpenProvenanceReport(t *testing.T) {
+	ctx := context.Background()
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(key)
+	require.NoError(t, err)
+
+	signed, err := types.SignProvenanceReport(&types.ProvenanceReport{
+		DataId:  "6f1e9f3e-0a6b-11ee-be56-0242ac120002",
+		Gateway: "gateway",
+		Commits: []types.CommitProvenance{{CommitId: "c0", Verified: true}},
+	}, key)
+	require.NoError(t, err)
+
+	sc := &SaoClient{ChainSvcApi: sdkChain{peerInfo: "/ip4/127.0.0.1/tcp/5153/p2p/" + id.String()}}
+	report, err := sc.OpenProvenanceReport(ctx, signed)
+	require.NoError(t, err)
+	require.True(t, report.Verified())
+
+	// signed by a peer the gateway didn't register
+	_, err = (&SaoClient{ChainSvcApi: sdkChain{}}).OpenProvenanceReport(ctx, signed)
+	require.True(t, types.ErrUnregisteredPeer.Is(err))
+
+	signed.Report = []byte(strings.Replace(string(signed.Report), `"Verified":true`, `"Verified":false`, 1))
+	_, err = sc.OpenProvenanceReport(ctx, signed)
+	require.True(t, types.ErrInvalidSignature.Is(err))
+}
+
+func TestShare(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	gateway := &sdkGateway{}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+	opts := DefaultModelOptions()
+	opts.Alias = "profile"
+	created, err := sdk.CreateJSON(ctx, map[string]string{"name": "sao"}, opts)
+	require.NoError(t, err)
+
+	token, err := sdk.Share(ctx, "profile", "", "v0", time.Hour)
+	require.NoError(t, err)
+	req, err := types.DecodeShareToken(token)
+	require.NoError(t, err)
+	delegation := req.Delegation.Delegation
+	require.Equal(t, didManager.Id, delegation.Issuer)
+	require.Equal(t, req.Proposal.Owner, delegation.Audience)
+	require.NoError(t, delegation.Allows(types.CapModelLoad, created.DataId, time.Now()))
+	require.Error(t, delegation.Allows(types.CapModelUpdate, created.DataId, time.Now()))
+	require.Error(t, delegation.Allows(types.CapModelLoad, created.DataId, time.Now().Add(2*time.Hour)))
+	require.Equal(t, created.DataId, req.Proposal.Keyword)
+	require.Equal(t, "v0", req.Proposal.Version)
+	require.Equal(t, uint64(100+3600), req.Proposal.LastValidHeight)
+
+	// the delegation is signed by the did, the query by the throwaway did:key
+	delegationBytes, err := delegation.Marshal()
+	require.NoError(t, err)
+	_, err = didManager.VerifyJWS(saodidtypes.GeneralJWS{
+		Payload:    base64url.Encode(delegationBytes),
+		Signatures: []saodidtypes.JwsSignature{saodidtypes.JwsSignature(req.Delegation.JwsSignature)},
+	})
+	require.NoError(t, err)
+	holder, err := saodid.NewDidManagerWithDid(req.Proposal.Owner, nil)
+	require.NoError(t, err)
+	proposalBytes, err := req.Proposal.Marshal()
+	require.NoError(t, err)
+	_, err = holder.VerifyJWS(saodidtypes.GeneralJWS{
+		Payload:    base64url.Encode(proposalBytes),
+		Signatures: []saodidtypes.JwsSignature{saodidtypes.JwsSignature(req.JwsSignature)},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sdk.RevokeShare(ctx, token))
+	require.Equal(t, delegation.Nonce, gateway.revoked.Proposal.Nonce)
+	require.Equal(t, delegation.Expiration, gateway.revoked.Proposal.Expiration)
+
+	_, err = sdk.Share(ctx, "profile", "", "", 0)
+	require.True(t, types.ErrInvalidParameters.Is(err))
+}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	gateway := &sdkGateway{}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+	opts := DefaultModelOptions()
+
+	created, err := sdk.CreateLog(ctx, "chat", eventlog.Policy{CompactEvery: 4}, opts)
+	require.NoError(t, err)
+	require.Equal(t, "log_chat", created.Alias)
+
+	for i := 0; i < 5; i++ {
+		_, first, err := sdk.AppendLog(ctx, "log_chat", []json.RawMessage{json.RawMessage(fmt.Sprintf(`{"n":%d}`, 2*i)), json.RawMessage(fmt.Sprintf(`{"n":%d}`, 2*i+1))}, opts)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2*i), first)
+		// the gateway checks the same
+		require.NoError(t, eventlog.Check([]byte(gateway.versions[i].Content), []byte(gateway.latest.Content), i+1))
+	}
+	// the fourth append compacts the log
+	segment, err := eventlog.Parse([]byte(gateway.latest.Content))
+	require.NoError(t, err)
+	require.Equal(t, 4, segment.Base)
+	require.Len(t, segment.Entries, 2)
+
+	entries, first, err := sdk.ReadLog(ctx, created.DataId, 3, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), first)
+	data, err := json.Marshal(entries)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"n":3},{"n":4},{"n":5},{"n":6}]`, string(data))
+
+	_, err = sdk.CreateJSON(ctx, map[string]string{"name": "sao"}, opts)
+	require.NoError(t, err)
+	_, _, err = sdk.ReadLog(ctx, gateway.latest.DataId, 0, 0)
+	require.True(t, types.ErrInvalidParameters.Is(err))
+}
+
+func TestUpdateFile(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	gateway := &sdkGateway{}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+	opts := DefaultModelOptions()
+
+	file := make([]byte, 256*1024)
+	_, err = rand.Read(file)
+	require.NoError(t, err)
+	fileCid, err := utils.CalculateCid(file)
+	require.NoError(t, err)
+	gateway.latest = apitypes.LoadResp{DataId: "data", Alias: "file_asset.bin", CommitId: "c0", Version: "v0", Cid: fileCid.String(), Content: string(file)}
+	gateway.commits = map[string]string{"c0": string(file)}
+	gateway.versions = []apitypes.LoadResp{gateway.latest}
+
+	update := func(base []byte, content []byte) int {
+		_, size, err := sdk.UpdateFile(ctx, "file_asset.bin", base, content, opts)
+		require.NoError(t, err)
+		require.Equal(t, string(content), gateway.latest.Content)
+		contentCid, err := utils.CalculateCid(content)
+		require.NoError(t, err)
+		require.Equal(t, contentCid.String(), gateway.latest.Cid)
+		// the delta is committed as it is
+		require.Equal(t, uint64(size), gateway.order.Proposal.Size_)
+		return size
+	}
+
+	file = append(append([]byte(nil), file...), "appended"...)
+	require.Less(t, update(nil, file), 1024)
+
+	// the large files aren't loaded, the whole file is committed without a base
+	gateway.latest.Content = ""
+	changed := append([]byte(nil), file...)
+	copy(changed[1000:], "changed")
+	require.Greater(t, update(nil, changed), len(changed)/2)
+	gateway.latest.Content = ""
+	_, _, err = sdk.UpdateFile(ctx, "file_asset.bin", file, file, opts)
+	require.True(t, types.ErrInvalidCid.Is(err))
+	copy(file, changed)
+	copy(file[2000:], "changed again")
+	require.Less(t, update(changed, file), 1024)
+
+	// the versions of filedelta.FullEvery hold the whole file
+	gateway.latest.Version = fmt.Sprintf("v%d", filedelta.FullEvery-1)
+	copy(file[3000:], "changed")
+	require.Greater(t, update(nil, file), len(file)/2)
+
+	_, err = sdk.CreateJSON(ctx, map[string]string{"name": "sao"}, opts)
+	require.NoError(t, err)
+	_, _, err = sdk.UpdateFile(ctx, gateway.latest.DataId, nil, file, opts)
+	require.True(t, types.ErrInvalidParameters.Is(err))
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	gateway := &sdkGateway{}
+	client := &SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}
+	client.Retry = RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	sdk, err := NewSdk(client, &didManager)
+	require.NoError(t, err)
+	opts := DefaultModelOptions()
+
+	// the create is resent once it failed on the way
+	gateway.unsent = 1
+	created, err := sdk.CreateJSON(ctx, map[string]int{"v": 1}, opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, gateway.calls)
+	require.Equal(t, created.DataId, gateway.latest.DataId)
+
+	// the update is handled but its response lost, it is not resent
+	gateway.calls, gateway.lost = 0, 1
+	updated, err := sdk.UpdateJSON(ctx, created.DataId, map[string]int{"v": 2}, opts)
+	require.NoError(t, err)
+	require.Equal(t, 1, gateway.calls)
+	require.Equal(t, gateway.latest.CommitId, updated.CommitId)
+	require.JSONEq(t, `{"v":2}`, gateway.latest.Content)
+
+	// the attempts run out
+	gateway.calls, gateway.unsent = 0, 3
+	_, err = sdk.UpdateJSON(ctx, created.DataId, map[string]int{"v": 3}, opts)
+	require.True(t, IsTransient(err))
+	require.Equal(t, 3, gateway.calls)
+
+	// the errors of the gateway are not retried
+	gateway.calls = 0
+	_, err = client.CreateWithRetry(ctx, &types.MetadataProposal{}, gateway.order, 0, []byte(`{"v":1}`))
+	require.True(t, types.ErrConflictId.Is(err))
+	require.Equal(t, 1, gateway.calls)
+}
+
+// eventGateway sends an event of each order state of the model subscribed
+type eventGateway struct {
+	api.SaoApi
+}
+
+func (eventGateway) ModelSubscribe(ctx context.Context, dataId string, owner string) (<-chan types.ModelEvent, error) {
+	events := make(chan types.ModelEvent)
+	go func() {
+		defer close(events)
+		for _, state := range []types.OrderState{types.OrderStateStaged, types.OrderStateReady, types.OrderStateComplete} {
+			select {
+			case events <- types.ModelEvent{Kind: types.ModelEventOrder, DataId: dataId, Owner: owner, State: state}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.Register("Sao", eventGateway{})
+	server := httptest.NewServer(rpcServer)
+	defer server.Close()
+
+	client := &SaoClient{Cfg: DefaultSaoClientConfig(), gateway: server.URL}
+	_, err := client.Subscribe(ctx, "", "", func(types.ModelEvent) {})
+	require.Error(t, err)
+
+	received := make(chan types.ModelEvent, 3)
+	sub, err := client.Subscribe(ctx, "data", "", func(event types.ModelEvent) {
+		received <- event
+	})
+	require.NoError(t, err)
+	<-sub.Done()
+	sub.Close()
+	require.Len(t, received, 3)
+	for _, state := range []types.OrderState{types.OrderStateStaged, types.OrderStateReady, types.OrderStateComplete} {
+		event := <-received
+		require.Equal(t, "data", event.DataId)
+		require.Equal(t, state, event.State)
+	}
+
+	// the sdk subscribes to the models of its did
+	didManager := newDidManager(t)
+	client.ChainSvcApi = sdkChain{}
+	sdk, err := NewSdk(client, &didManager)
+	require.NoError(t, err)
+	completed := make(chan types.ModelEvent, 3)
+	sub, err = sdk.Subscribe(ctx, "", func(event types.ModelEvent) {
+		if event.Completed() {
+			completed <- event
+		}
+	})
+	require.NoError(t, err)
+	event := <-completed
+	sub.Close()
+	require.Equal(t, didManager.Id, event.Owner)
+}
+
+// mirrorGateway serves the content of the commits by commit id
+type mirrorGateway struct {
+	api.SaoApi
+	contents map[string]string
+	loads    int
+}
+
+func (g *mirrorGateway) GetNodeAddress(context.Context) (string, error) {
+	return "gateway", nil
+}
+
+func (g *mirrorGateway) ModelLoad(_ context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	g.loads++
+	content, exists := g.contents[req.Proposal.CommitId]
+	if !exists {
+		return apitypes.LoadResp{}, types.ErrNotFound
+	}
+	return apitypes.LoadResp{DataId: req.Proposal.Keyword, CommitId: req.Proposal.CommitId, Content: content}, nil
+}
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	dir := t.TempDir()
+
+	gateway := &mirrorGateway{contents: map[string]string{"c1": `{"v":1}`, "c2": `{"v":2}`, "c3": `{"v":3}`, "o1": `{}`}}
+	notes := modeltypes.Metadata{DataId: "notes", Alias: "my_notes", GroupId: "app", Tags: []string{"sync"}, Commits: []string{"c1\x1a1"}}
+	other := modeltypes.Metadata{DataId: "other", GroupId: "app", Commits: []string{"o1\x1a1"}}
+	sdkChain := sdkChain{metas: []modeltypes.Metadata{notes, other}}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+	filter := MirrorFilter{GroupIds: []string{"app"}, Tags: []string{"sync"}}
+
+	mirror, err := OpenMirror(dir)
+	require.NoError(t, err)
+	pulled, err := mirror.Pull(ctx, sdk, filter)
+	require.NoError(t, err)
+	require.Equal(t, 1, pulled)
+
+	// only the new commits are pulled, by a mirror reopened
+	notes.Commits = append(notes.Commits, "c2\x1a2", "c3\x1a3")
+	sdkChain.metas[0] = notes
+	mirror, err = OpenMirror(dir)
+	require.NoError(t, err)
+	gateway.loads = 0
+	pulled, err = mirror.Pull(ctx, sdk, filter)
+	require.NoError(t, err)
+	require.Equal(t, 2, pulled)
+	require.Equal(t, 2, gateway.loads)
+
+	models := mirror.Models()
+	require.Len(t, models, 1)
+	require.Equal(t, []string{"c1", "c2", "c3"}, models[0].Commits)
+	content, err := mirror.Content("my_notes", "")
+	require.NoError(t, err)
+	require.Equal(t, `{"v":3}`, string(content))
+	content, err = mirror.Content("notes", "c1")
+	require.NoError(t, err)
+	require.Equal(t, `{"v":1}`, string(content))
+	_, err = mirror.Content("other", "")
+	require.Error(t, err)
+
+	server := httptest.NewServer(mirror)
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL + "/models/my_notes/c2")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, `{"v":2}`, string(body))
+	resp, err = server.Client().Get(server.URL + "/models/notes/o1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, 404, resp.StatusCode)
+}
+
+func TestPresignUpload(t *testing.T) {
+	ctx := context.Background()
+	didManager := newDidManager(t)
+	gateway := &sdkGateway{}
+	sdk, err := NewSdk(&SaoClient{SaoApi: gateway, ChainSvcApi: sdkChain{}, Cfg: DefaultSaoClientConfig()}, &didManager)
+	require.NoError(t, err)
+
+	contentCid, err := utils.CalculateCid([]byte("hello"))
+	require.NoError(t, err)
+	token, err := sdk.PresignUpload(ctx, contentCid.String(), 5, "a.txt", DefaultModelOptions(), time.Hour)
+	require.NoError(t, err)
+	upload, err := types.DecodeUploadToken(token)
+	require.NoError(t, err)
+	order := upload.Order.Proposal
+	require.Equal(t, didManager.Id, order.Owner)
+	require.Equal(t, types.Type_Prefix_File+"a.txt", order.Alias)
+	require.Equal(t, contentCid.String(), order.Cid)
+	require.Equal(t, uint64(5), order.Size_)
+
+	// the delegation of creating the model only, to the did:key signing the query
+	req := upload.Request
+	delegation := req.Delegation.Delegation
+	require.Equal(t, didManager.Id, delegation.Issuer)
+	require.Equal(t, req.Proposal.Owner, delegation.Audience)
+	require.Equal(t, order.DataId, req.Proposal.Keyword)
+	require.NoError(t, delegation.Allows(types.CapModelCreate, order.DataId, time.Now()))
+	require.Error(t, delegation.Allows(types.CapModelLoad, order.DataId, time.Now()))
+	require.Error(t, delegation.Allows(types.CapModelCreate, order.DataId, time.Now().Add(2*time.Hour)))
+
+	orderBytes, err := order.Marshal()
+	require.NoError(t, err)
+	_, err = didManager.VerifyJWS(saodidtypes.GeneralJWS{
+		Payload:    base64url.Encode(orderBytes),
+		Signatures: []saodidtypes.JwsSignature{saodidtypes.JwsSignature(upload.Order.JwsSignature)},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sdk.RevokeUpload(ctx, token))
+	require.Equal(t, delegation.Nonce, gateway.revoked.Proposal.Nonce)
+
+	_, err = sdk.PresignUpload(ctx, contentCid.String(), 0, "a.txt", DefaultModelOptions(), time.Hour)
+	require.True(t, types.ErrInvalidParameters.Is(err))
+	_, err = sdk.PresignUpload(ctx, "cid", 5, "a.txt", DefaultModelOptions(), time.Hour)
+	require.True(t, types.ErrInvalidCid.Is(err))
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"sao-node/chain"
+	"sao-node/types"
+	"sao-node/utils"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saokey "github.com/SaoNetwork/sao-did/key"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Share returns the token of a share link loading the model until expire, whoever holds the token doesn't need a
+// DID. The link loads the commit of commitId, or the version like v1, or the latest commit if both are empty. The
+// token is the query request of a throwaway did:key, which the did delegates CapModelLoad on the model to, see
+// types.EncodeShareToken.
+func (s *Sdk) Share(ctx context.Context, keyword string, commitId string, version string, expire time.Duration) (string, error) {
+	if expire <= 0 {
+		return "", types.Wrapf(types.ErrInvalidParameters, "invalid expiration %s", expire)
+	}
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// the delegation is of the data id, the alias is resolved by the owner
+	dataId := keyword
+	if !utils.IsDataId(keyword) {
+		request, err := s.queryRequest(ctx, gatewayAddress, keyword)
+		if err != nil {
+			return "", err
+		}
+		meta, err := s.client.ModelLoadMeta(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		dataId = meta.DataId
+	}
+
+	holder, err := throwawayDid()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	delegation := types.Delegation{
+		Issuer:       s.did.Id,
+		Audience:     holder.Id,
+		Capabilities: []string{types.CapModelLoad},
+		Resources:    []string{dataId},
+		NotBefore:    now.Unix(),
+		Expiration:   now.Add(expire).Unix(),
+		Nonce:        uuid.NewV4().String(),
+	}
+	delegationSignature, err := sign(s.did, &delegation)
+	if err != nil {
+		return "", err
+	}
+
+	lastHeight, err := s.client.GetLastHeight(ctx)
+	if err != nil {
+		return "", types.Wrap(types.ErrQueryHeightFailed, err)
+	}
+	peerInfo, err := s.client.GetNodePeer(ctx, gatewayAddress)
+	if err != nil {
+		return "", err
+	}
+	// the storage nodes check the height the request is valid until, the gateway checks the expiration
+	proposal := saotypes.QueryProposal{
+		Owner:           holder.Id,
+		Keyword:         dataId,
+		GroupId:         s.GroupId,
+		LastValidHeight: uint64(lastHeight) + uint64(expire/chain.Blocktime),
+		Gateway:         peerInfo,
+		CommitId:        commitId,
+		Version:         version,
+	}
+	signature, err := sign(holder, &proposal)
+	if err != nil {
+		return "", err
+	}
+
+	return types.EncodeShareToken(&types.MetadataProposal{
+		Proposal:     proposal,
+		JwsSignature: signature,
+		Delegation: &types.SignedDelegation{
+			Delegation:   delegation,
+			JwsSignature: delegationSignature,
+		},
+	})
+}
+
+// RevokeShare adds the share of the token to the revocation list of the gateway, the link can't load the model
+// from the gateway any more.
+func (s *Sdk) RevokeShare(ctx context.Context, token string) error {
+	req, err := types.DecodeShareToken(token)
+	if err != nil {
+		return err
+	}
+	return s.RevokeDelegation(ctx, &req.Delegation.Delegation)
+}
+
+// RevokeDelegation revokes the delegation of a share, an upload or another did by its nonce at the gateway, the
+// delegation must be issued by the did.
+func (s *Sdk) RevokeDelegation(ctx context.Context, delegation *types.Delegation) error {
+	if delegation.Issuer != s.did.Id {
+		return types.Wrapf(types.ErrInvalidDelegation, "delegated by %s, not %s", delegation.Issuer, s.did.Id)
+	}
+
+	revoke := types.ShareRevoke{
+		Issuer:     delegation.Issuer,
+		Nonce:      delegation.Nonce,
+		Expiration: delegation.Expiration,
+	}
+	signature, err := sign(s.did, &revoke)
+	if err != nil {
+		return err
+	}
+	return s.client.ModelShareRevoke(ctx, &types.ShareRevokeProposal{
+		Proposal:     revoke,
+		JwsSignature: signature,
+	})
+}
+
+// throwawayDid returns the did:key of a random secret, which is never stored.
+func throwawayDid() (*saodid.DidManager, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, types.Wrap(types.ErrCreateProviderFailed, err)
+	}
+	provider, err := saokey.NewSecp256k1Provider(secret)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateProviderFailed, err)
+	}
+	didManager := saodid.NewDidManager(provider, saokey.NewKeyResolver())
+	_, err = didManager.Authenticate([]string{}, "")
+	if err != nil {
+		return nil, types.Wrap(types.ErrAuthenticateFailed, err)
+	}
+	return &didManager, nil
+}
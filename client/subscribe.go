@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	apiclient "sao-node/api/client"
+	"sao-node/types"
+	"strings"
+	"sync"
+)
+
+// ModelSubscription delivers the events of the models subscribed by SaoClient.Subscribe.
+type ModelSubscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Done is closed once the subscription ends, when closed or when the connection to the gateway is lost.
+func (s *ModelSubscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the subscription and waits for the call of f in progress, it must not be called from f.
+func (s *ModelSubscription) Close() {
+	s.once.Do(s.cancel)
+	<-s.done
+}
+
+// Subscribe calls f with the events of the model dataId, or of the models of owner if dataId is empty, in the
+// order the gateway sends them, until ctx is done or the subscription is closed. The order events tell when the
+// commits are stored, so the applications don't need to poll the commit status. The events are sent over a
+// websocket connection of its own to the gateway.
+func (sc *SaoClient) Subscribe(ctx context.Context, dataId string, owner string, f func(types.ModelEvent)) (*ModelSubscription, error) {
+	if dataId == "" && owner == "" {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "dataId or owner is required")
+	}
+	gateway := sc.gateway
+	if gateway == "" || gateway == "none" {
+		gateway = sc.Cfg.Gateway
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	// the subscriptions are only served over websocket, http://... becomes ws://... and https://... wss://...
+	gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, strings.Replace(gateway, "http", "ws", 1), sc.Cfg.Token)
+	if err != nil {
+		cancel()
+		return nil, types.Wrap(types.ErrCreateApiServiceFailed, err)
+	}
+	events, err := gatewayApi.ModelSubscribe(ctx, dataId, owner)
+	if err != nil {
+		closer()
+		cancel()
+		return nil, err
+	}
+
+	sub := &ModelSubscription{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(sub.done)
+		defer closer()
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				f(event)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Subscribe calls f with the events of the model dataId, or of all the models of the did if dataId is empty, see
+// SaoClient.Subscribe.
+func (s *Sdk) Subscribe(ctx context.Context, dataId string, f func(types.ModelEvent)) (*ModelSubscription, error) {
+	owner := ""
+	if dataId == "" {
+		owner = s.did.Id
+	}
+	return s.client.Subscribe(ctx, dataId, owner, f)
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"sao-node/chain"
+	"sao-node/types"
+	"sao-node/utils"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/ipfs/go-cid"
+	uuid "github.com/satori/go.uuid"
+)
+
+// PresignUpload returns the token of an upload creating the file model of the content of contentCid and size, so
+// an application server can hand the upload to an end user, whose browser puts the bytes to the gateway without a
+// DID until expire, see types.EncodeUploadToken. The model is named fileName and created with opts, the gateway
+// rejects the bytes of any other content.
+func (s *Sdk) PresignUpload(ctx context.Context, contentCid string, size uint64, fileName string, opts ModelOptions, expire time.Duration) (string, error) {
+	if expire <= 0 {
+		return "", types.Wrapf(types.ErrInvalidParameters, "invalid expiration %s", expire)
+	}
+	if _, err := cid.Decode(contentCid); err != nil {
+		return "", types.Wrap(types.ErrInvalidCid, err)
+	}
+	if size == 0 {
+		return "", types.Wrapf(types.ErrInvalidParameters, "the content is empty")
+	}
+	gatewayAddress, err := s.gateway(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	dataId := opts.DataId
+	if dataId == "" {
+		dataId = utils.GenerateDataId(s.did.Id + s.GroupId)
+	}
+	proposal := s.proposal(gatewayAddress, opts)
+	proposal.DataId = dataId
+	proposal.Alias = opts.Alias
+	if fileName != "" {
+		proposal.Alias = types.Type_Prefix_File + fileName
+	}
+	proposal.Cid = contentCid
+	proposal.CommitId = dataId
+	proposal.Size_ = size
+	if proposal.Alias == "" {
+		proposal.Alias = proposal.Cid
+	}
+	orderProposal, err := BuildOrderProposal(s.did, proposal)
+	if err != nil {
+		return "", err
+	}
+
+	holder, err := throwawayDid()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	delegation := types.Delegation{
+		Issuer:       s.did.Id,
+		Audience:     holder.Id,
+		Capabilities: []string{types.CapModelCreate},
+		Resources:    []string{dataId},
+		NotBefore:    now.Unix(),
+		Expiration:   now.Add(expire).Unix(),
+		Nonce:        uuid.NewV4().String(),
+	}
+	delegationSignature, err := sign(s.did, &delegation)
+	if err != nil {
+		return "", err
+	}
+
+	lastHeight, err := s.client.GetLastHeight(ctx)
+	if err != nil {
+		return "", types.Wrap(types.ErrQueryHeightFailed, err)
+	}
+	peerInfo, err := s.client.GetNodePeer(ctx, gatewayAddress)
+	if err != nil {
+		return "", err
+	}
+	query := saotypes.QueryProposal{
+		Owner:           holder.Id,
+		Keyword:         dataId,
+		GroupId:         s.GroupId,
+		LastValidHeight: uint64(lastHeight) + uint64(expire/chain.Blocktime),
+		Gateway:         peerInfo,
+	}
+	signature, err := sign(holder, &query)
+	if err != nil {
+		return "", err
+	}
+
+	return types.EncodeUploadToken(&types.UploadToken{
+		Request: types.MetadataProposal{
+			Proposal:     query,
+			JwsSignature: signature,
+			Delegation: &types.SignedDelegation{
+				Delegation:   delegation,
+				JwsSignature: delegationSignature,
+			},
+		},
+		Order: *orderProposal,
+	})
+}
+
+// RevokeUpload adds the upload of the token to the revocation list of the gateway, the file can't be uploaded to
+// the gateway any more.
+func (s *Sdk) RevokeUpload(ctx context.Context, token string) error {
+	upload, err := types.DecodeUploadToken(token)
+	if err != nil {
+		return err
+	}
+	return s.RevokeDelegation(ctx, &upload.Request.Delegation.Delegation)
+}
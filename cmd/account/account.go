@@ -10,6 +10,7 @@ import (
 	"strings"
 	"syscall"
 
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
 	"github.com/labstack/gommon/log"
 	"github.com/mitchellh/go-homedir"
 	"github.com/urfave/cli/v2"
@@ -22,8 +23,10 @@ var AccountCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		listCmd,
 		createCmd,
+		balanceCmd,
 		sendCmd,
 		importCmd,
+		recoverCmd,
 		exportCmd,
 	},
 }
@@ -165,18 +168,64 @@ var exportCmd = &cli.Command{
 	},
 }
 
+var balanceCmd = &cli.Command{
+	Name:      "balance",
+	Usage:     "show the balance of an account",
+	ArgsUsage: "[account name or address]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  cliutil.FlagKeyName,
+			Usage: "account name, used if no argument is given",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		account := cctx.Args().First()
+		if account == "" {
+			account = cctx.String(cliutil.FlagKeyName)
+		}
+		if account == "" {
+			return types.Wrapf(types.ErrInvalidParameters, "must provide an account name or address")
+		}
+		address, err := resolveAddress(cctx, account)
+		if err != nil {
+			return err
+		}
+
+		chainSvc, err := newChainSvc(cctx)
+		if err != nil {
+			return err
+		}
+
+		coins, err := chainSvc.GetBalance(ctx, address)
+		if err != nil {
+			return types.Wrap(types.ErrGetBalanceFailed, err)
+		}
+		fmt.Println("Address:", address)
+		if coins.IsZero() {
+			fmt.Println("Balance:", 0, chain.DENOM)
+		}
+		for _, coin := range coins {
+			fmt.Println("Balance:", coin.Amount, coin.Denom)
+		}
+
+		return nil
+	},
+}
+
 var sendCmd = &cli.Command{
 	Name:  "send",
 	Usage: "send SAO tokens from one account to another",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:     "from",
-			Usage:    "the original account to spend tokens",
-			Required: true,
+			Usage:    "the original account name to spend tokens, --signer by default",
+			Required: false,
 		},
 		&cli.StringFlag{
 			Name:     "to",
-			Usage:    "the target account to received tokens",
+			Usage:    "the target account name or address to received tokens",
 			Required: true,
 		},
 		&cli.Int64Flag{
@@ -188,39 +237,77 @@ var sendCmd = &cli.Command{
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
 
-		repoPath := cctx.String("repo")
-		if repoPath == "" {
-			if cctx.App.Name == "saoclient" {
-				repoPath = "~/.sao-cli"
-			} else if cctx.App.Name == "saonode" {
-				repoPath = "~/.sao-node"
-			} else {
-				return types.Wrapf(types.ErrInvalidBinaryName, ", Name=%s", cctx.App.Name)
-			}
+		from := cctx.String("from")
+		if from == "" {
+			from = cliutil.Signer
 		}
-
-		chainAddress, err := cliutil.GetChainAddress(cctx, repoPath, cctx.App.Name)
+		if from == "" {
+			return types.Wrapf(types.ErrInvalidParameters, "no --from or --signer specified")
+		}
+		amount := cctx.Int64("amount")
+		if amount <= 0 {
+			return types.Wrapf(types.ErrInvalidParameters, "invalid amount %d", amount)
+		}
+		to, err := resolveAddress(cctx, cctx.String("to"))
 		if err != nil {
-			log.Warn(err)
+			return err
+		}
+		fromAddress, err := chain.GetAddress(ctx, cliutil.KeyringHome, from)
+		if err != nil {
+			return err
 		}
 
-		chain, err := chain.NewChainSvc(ctx, chainAddress, "/websocket", cliutil.KeyringHome)
+		chainSvc, err := newChainSvc(cctx)
 		if err != nil {
 			return err
 		}
-		from := cctx.String("from")
-		to := cctx.String("to")
-		amount := cctx.Int64("amount")
-		txHash, err := chain.Send(ctx, from, to, amount)
+
+		coins, err := chainSvc.GetBalance(ctx, fromAddress)
+		if err != nil {
+			return types.Wrap(types.ErrGetBalanceFailed, err)
+		}
+		if coins.AmountOf(chain.DENOM).LT(sdktypes.NewInt(amount)) {
+			return types.Wrapf(types.ErrInvalidParameters, "insufficient balance of %s: %s%s", from, coins.AmountOf(chain.DENOM), chain.DENOM)
+		}
+
+		txHash, err := chainSvc.Send(ctx, from, to, amount)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("%d stakes has been transferred from %s to %s, txHash=%s\n", amount, from, to, txHash)
+		fmt.Printf("%d %s has been transferred from %s to %s, txHash=%s\n", amount, chain.DENOM, from, to, txHash)
 
 		return nil
 	},
 }
 
+// resolveAddress returns the address of the account, which is either an address or a name in local keystore.
+func resolveAddress(cctx *cli.Context, account string) (string, error) {
+	if strings.HasPrefix(account, chain.ADDRESS_PREFIX+"1") {
+		return account, nil
+	}
+	return chain.GetAddress(cctx.Context, cliutil.KeyringHome, account)
+}
+
+func newChainSvc(cctx *cli.Context) (*chain.ChainSvc, error) {
+	repoPath := cctx.String("repo")
+	if repoPath == "" {
+		if cctx.App.Name == "saoclient" {
+			repoPath = "~/.sao-cli"
+		} else if cctx.App.Name == "saonode" {
+			repoPath = "~/.sao-node"
+		} else {
+			return nil, types.Wrapf(types.ErrInvalidBinaryName, ", Name=%s", cctx.App.Name)
+		}
+	}
+
+	chainAddress, err := cliutil.GetChainAddress(cctx, repoPath, cctx.App.Name)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	return chain.NewChainSvc(cctx.Context, chainAddress, "/websocket", cliutil.KeyringHome)
+}
+
 var importCmd = &cli.Command{
 	Name: "import",
 	Flags: []cli.Flag{
@@ -285,3 +372,54 @@ var importCmd = &cli.Command{
 		return nil
 	},
 }
+
+var recoverCmd = &cli.Command{
+	Name:  "recover",
+	Usage: "recover an account from the mnemonic printed when it was created",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     cliutil.FlagKeyName,
+			Usage:    "account name to recover as",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "hd-path",
+			Usage: "BIP-44 HD path of the key, e.g. m/44'/118'/0'/0/0, defaults to the path accounts are created with",
+		},
+		&cli.BoolFlag{
+			Name:  "bip39-passphrase",
+			Usage: "ask for the BIP-39 passphrase used along with the mnemonic",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		name := cctx.String(cliutil.FlagKeyName)
+
+		fmt.Print("Enter mnemonic:")
+		mnemonic, err := term.ReadPassword(syscall.Stdin)
+		fmt.Println()
+		if err != nil {
+			return types.Wrap(types.ErrInvalidSecrect, err)
+		}
+
+		var passphrase []byte
+		if cctx.Bool("bip39-passphrase") {
+			fmt.Print("Enter BIP-39 passphrase:")
+			passphrase, err = term.ReadPassword(syscall.Stdin)
+			fmt.Println()
+			if err != nil {
+				return types.Wrap(types.ErrInvalidPassphrase, err)
+			}
+		}
+
+		address, err := chain.Recover(ctx, cliutil.KeyringHome, name, strings.Join(strings.Fields(string(mnemonic)), " "), string(passphrase), cctx.String("hd-path"))
+		if err != nil {
+			return err
+		}
+		fmt.Println("Account:", name)
+		fmt.Println("Address:", address)
+
+		return nil
+	},
+}
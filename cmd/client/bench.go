@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sao-node/chain"
+	"sao-node/client"
+	cliutil "sao-node/cmd"
+	"sao-node/types"
+	"sao-node/utils"
+	"sort"
+	"sync"
+	"time"
+
+	did "github.com/SaoNetwork/sao-did"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	workloadCreate = "create"
+	workloadLoad   = "load"
+	workloadUpdate = "update"
+)
+
+var benchCmd = &cli.Command{
+	Name:  "bench",
+	Usage: "run create/load/update workloads against the gateway and report the throughput and latencies",
+	UsageText: "the workloads run in the order given, the loads and the updates use the models created by the create workload, " +
+		"or a model per worker created beforehand if it is not run. The models are real orders paid by the did, " +
+		"so keep --count and --duration small out of the test networks.",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "workload",
+			Usage: "workloads to run: create, load and update",
+			Value: cli.NewStringSlice(workloadCreate, workloadLoad, workloadUpdate),
+		},
+		&cli.IntFlag{
+			Name:  "count",
+			Usage: "requests of each workload",
+			Value: 20,
+		},
+		&cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "requests in flight",
+			Value: 4,
+		},
+		&cli.IntFlag{
+			Name:  "size",
+			Usage: "size of the model contents in bytes",
+			Value: 256,
+		},
+		&cli.IntFlag{
+			Name:  "duration",
+			Usage: "how many days to store the models",
+			Value: 1,
+		},
+		&cli.IntFlag{
+			Name:  "replica",
+			Usage: "how many copies to store",
+			Value: DEFAULT_REPLICA,
+		},
+		&cli.IntFlag{
+			Name:  "delay",
+			Usage: "how many epochs to wait for the content to be completed storing",
+			Value: 1 * 60,
+		},
+		flagPriority,
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the results in JSON",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		workloads := cctx.StringSlice("workload")
+		for _, w := range workloads {
+			if w != workloadCreate && w != workloadLoad && w != workloadUpdate {
+				return types.Wrapf(types.ErrInvalidParameters, "unknown workload %s", w)
+			}
+		}
+		count := cctx.Int("count")
+		concurrency := cctx.Int("concurrency")
+		if count <= 0 || concurrency <= 0 {
+			return types.Wrapf(types.ErrInvalidParameters, "--count and --concurrency should be positive")
+		}
+		size := cctx.Int("size")
+		if size < 64 {
+			return types.Wrapf(types.ErrInvalidParameters, "--size should be at least 64 bytes")
+		}
+		priority, err := getPriority(cctx)
+		if err != nil {
+			return err
+		}
+
+		saoClient, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, saoClient.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		gatewayAddress, err := saoClient.GetNodeAddress(ctx)
+		if err != nil {
+			return err
+		}
+		groupId := cctx.String("platform")
+		if groupId == "" {
+			groupId = saoClient.Cfg.GroupId
+		}
+
+		b := &bench{
+			client:         saoClient,
+			didManager:     didManager,
+			gatewayAddress: gatewayAddress,
+			groupId:        groupId,
+			size:           size,
+			duration:       cctx.Int("duration"),
+			replica:        cctx.Int("replica"),
+			delay:          cctx.Int("delay"),
+			priority:       priority,
+		}
+
+		var results []*benchStats
+		for _, w := range workloads {
+			if w != workloadCreate && len(b.models) == 0 {
+				fmt.Fprintf(os.Stderr, "creating %d models for the %s workload...\n", concurrency, w)
+				stats := runWorkload(ctx, "", concurrency, concurrency, b.create)
+				if len(b.models) == 0 {
+					if stats.lastErr != nil {
+						return stats.lastErr
+					}
+					return ctx.Err()
+				}
+			}
+
+			fmt.Fprintf(os.Stderr, "running %d %s requests...\n", count, w)
+			switch w {
+			case workloadCreate:
+				results = append(results, runWorkload(ctx, w, count, concurrency, b.create))
+			case workloadLoad:
+				results = append(results, runWorkload(ctx, w, count, concurrency, b.load))
+			case workloadUpdate:
+				results = append(results, runWorkload(ctx, w, count, concurrency, b.update))
+			}
+		}
+
+		if cctx.Bool("json") {
+			reports := make([]benchReport, len(results))
+			for i, stats := range results {
+				reports[i] = stats.report()
+			}
+			data, err := json.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				return types.Wrap(types.ErrMarshalFailed, err)
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Workload"),
+			tablewriter.Col("Requests"),
+			tablewriter.Col("Errors"),
+			tablewriter.Col("Req/s"),
+			tablewriter.Col("P50"),
+			tablewriter.Col("P90"),
+			tablewriter.Col("P99"),
+			tablewriter.Col("Max"),
+			tablewriter.NewLineCol("LastErr"),
+		)
+		for _, stats := range results {
+			r := stats.report()
+			tw.Write(map[string]interface{}{
+				"Workload": r.Workload,
+				"Requests": r.Requests,
+				"Errors":   r.Errors,
+				"Req/s":    fmt.Sprintf("%.2f", r.Throughput),
+				"P50":      r.P50,
+				"P90":      r.P90,
+				"P99":      r.P99,
+				"Max":      r.Max,
+				"LastErr":  r.LastErr,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
+
+// benchModel is a model created by the bench, its updates are serialized as each one is based on the last commit.
+type benchModel struct {
+	lk       sync.Mutex
+	dataId   string
+	alias    string
+	commitId string
+	content  []byte
+	version  int
+}
+
+type bench struct {
+	client         *client.SaoClient
+	didManager     *did.DidManager
+	gatewayAddress string
+	groupId        string
+	size           int
+	duration       int
+	replica        int
+	delay          int
+	priority       types.Priority
+
+	lk     sync.Mutex
+	models []*benchModel
+}
+
+// content returns a JSON model of the configured size.
+func (b *bench) content(i int, version int) ([]byte, error) {
+	head := fmt.Sprintf(`{"bench":%d,"version":%d,"data":"`, i, version)
+	n := b.size - len(head) - 2
+	pad := make([]byte, (n+1)/2)
+	if _, err := rand.Read(pad); err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	data := hex.EncodeToString(pad)[:n]
+	return []byte(head + data + `"}`), nil
+}
+
+func (b *bench) proposal(dataId string, commitId string, alias string, contentCid string, size int) saotypes.Proposal {
+	return saotypes.Proposal{
+		DataId:    dataId,
+		Owner:     b.didManager.Id,
+		Provider:  b.gatewayAddress,
+		GroupId:   b.groupId,
+		Duration:  uint64(time.Duration(60*60*24*b.duration) * time.Second / chain.Blocktime),
+		Replica:   int32(b.replica),
+		Timeout:   int32(b.delay),
+		Alias:     alias,
+		Cid:       contentCid,
+		CommitId:  commitId,
+		Size_:     uint64(size),
+		Operation: 1,
+	}
+}
+
+func (b *bench) create(ctx context.Context, i int) error {
+	content, err := b.content(i, 0)
+	if err != nil {
+		return err
+	}
+	contentCid, err := utils.CalculateCid(content)
+	if err != nil {
+		return err
+	}
+
+	dataId := utils.GenerateDataId(b.didManager.Id + b.groupId)
+	clientProposal, err := buildClientProposal(ctx, b.didManager, b.proposal(dataId, dataId, contentCid.String(), contentCid.String(), len(content)), b.client)
+	if err != nil {
+		return err
+	}
+	clientProposal.Priority = b.priority
+	request, err := buildQueryRequest(ctx, b.didManager, saotypes.QueryProposal{
+		Owner:   b.didManager.Id,
+		Keyword: dataId,
+	}, b.client, b.gatewayAddress)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.client.ModelCreate(ctx, request, clientProposal, 0, content)
+	if err != nil {
+		return err
+	}
+
+	b.lk.Lock()
+	b.models = append(b.models, &benchModel{dataId: resp.DataId, alias: resp.Alias, commitId: dataId, content: content})
+	b.lk.Unlock()
+	return nil
+}
+
+func (b *bench) model(i int) *benchModel {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	return b.models[i%len(b.models)]
+}
+
+func (b *bench) load(ctx context.Context, i int) error {
+	m := b.model(i)
+	request, err := buildQueryRequest(ctx, b.didManager, saotypes.QueryProposal{
+		Owner:   b.didManager.Id,
+		Keyword: m.dataId,
+		GroupId: b.groupId,
+	}, b.client, b.gatewayAddress)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.client.ModelLoad(ctx, request)
+	return err
+}
+
+func (b *bench) update(ctx context.Context, i int) error {
+	m := b.model(i)
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	target, err := b.content(i, m.version+1)
+	if err != nil {
+		return err
+	}
+	patch, err := utils.GeneratePatch(string(m.content), string(target))
+	if err != nil {
+		return err
+	}
+	targetCid, err := utils.CalculateCid(target)
+	if err != nil {
+		return err
+	}
+
+	commitId := m.commitId + "|" + utils.GenerateCommitId(b.didManager.Id+b.groupId)
+	clientProposal, err := buildClientProposal(ctx, b.didManager, b.proposal(m.dataId, commitId, m.alias, targetCid.String(), len(target)), b.client)
+	if err != nil {
+		return err
+	}
+	clientProposal.Priority = b.priority
+	request, err := buildQueryRequest(ctx, b.didManager, saotypes.QueryProposal{
+		Owner:   b.didManager.Id,
+		Keyword: m.dataId,
+		GroupId: b.groupId,
+	}, b.client, b.gatewayAddress)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.client.ModelUpdate(ctx, request, clientProposal, 0, []byte(patch))
+	if err != nil {
+		return err
+	}
+	m.commitId = resp.CommitId
+	m.content = target
+	m.version++
+	return nil
+}
+
+type benchStats struct {
+	workload  string
+	latencies []time.Duration
+	errors    int
+	lastErr   error
+	elapsed   time.Duration
+}
+
+// runWorkload runs count requests with op, by concurrency workers.
+func runWorkload(ctx context.Context, workload string, count int, concurrency int, op func(ctx context.Context, i int) error) *benchStats {
+	stats := &benchStats{workload: workload}
+	var lk sync.Mutex
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				begin := time.Now()
+				err := op(ctx, i)
+				latency := time.Since(begin)
+
+				lk.Lock()
+				if err != nil {
+					stats.errors++
+					stats.lastErr = err
+				} else {
+					stats.latencies = append(stats.latencies, latency)
+				}
+				lk.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	stats.elapsed = time.Since(start)
+
+	sort.Slice(stats.latencies, func(i, j int) bool {
+		return stats.latencies[i] < stats.latencies[j]
+	})
+	return stats
+}
+
+// percentile returns the latency below which p percent of the successful requests completed.
+func (s *benchStats) percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(s.latencies))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(s.latencies) {
+		i = len(s.latencies) - 1
+	}
+	return s.latencies[i]
+}
+
+type benchReport struct {
+	Workload string
+	Requests int
+	Errors   int
+	// successful requests per second
+	Throughput float64
+	P50        time.Duration
+	P90        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+	LastErr    string `json:",omitempty"`
+}
+
+func (s *benchStats) report() benchReport {
+	r := benchReport{
+		Workload: s.workload,
+		Requests: len(s.latencies) + s.errors,
+		Errors:   s.errors,
+		P50:      s.percentile(50),
+		P90:      s.percentile(90),
+		P99:      s.percentile(99),
+		Max:      s.percentile(100),
+	}
+	if s.elapsed > 0 {
+		r.Throughput = float64(len(s.latencies)) / s.elapsed.Seconds()
+	}
+	if s.lastErr != nil {
+		r.LastErr = s.lastErr.Error()
+	}
+	return r
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sao-node/chain"
+	cliutil "sao-node/cmd"
+	"sao-node/types"
+	"sao-node/utils"
+	"sao-node/utils/car"
+	"time"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/urfave/cli/v2"
+)
+
+var exportCarCmd = &cli.Command{
+	Name:      "export-car",
+	Usage:     "export data models with all commits as a CARv2 file",
+	UsageText: "the file can be imported by import-car or read by the Filecoin/IPFS tools.",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:     "keyword",
+			Usage:    "data model's alias or dataId, repeat it to export several models",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "path of the car file",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "delegation",
+			Usage:    "export on behalf of the issuer with the delegation file created by 'did delegate'",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		groupId := cctx.String("platform")
+		if groupId == "" {
+			groupId = client.Cfg.GroupId
+		}
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		delegation, err := readDelegation(cctx, didManager.Id)
+		if err != nil {
+			return err
+		}
+
+		gatewayAddress, err := client.GetNodeAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		// a signed load request for every commit of the models
+		var requests []*types.MetadataProposal
+		for _, keyword := range cctx.StringSlice("keyword") {
+			proposal := saotypes.QueryProposal{
+				Owner:   didManager.Id,
+				Keyword: keyword,
+				GroupId: groupId,
+			}
+			if !utils.IsDataId(keyword) {
+				proposal.KeywordType = 2
+			}
+
+			request, err := buildQueryRequest(ctx, didManager, proposal, client, gatewayAddress)
+			if err != nil {
+				return err
+			}
+			request.Delegation = delegation
+			commits, err := client.ModelShowCommits(ctx, request)
+			if err != nil {
+				return err
+			}
+
+			for _, commit := range commits.Commits {
+				commitInfo, err := types.ParseMetaCommit(commit)
+				if err != nil {
+					return types.Wrapf(types.ErrInvalidCommitInfo, "invalid commit information: %s", commit)
+				}
+				proposal.Keyword = commits.DataId
+				proposal.KeywordType = 0
+				proposal.CommitId = commitInfo.CommitId
+				request, err := buildQueryRequest(ctx, didManager, proposal, client, gatewayAddress)
+				if err != nil {
+					return err
+				}
+				request.Delegation = delegation
+				requests = append(requests, request)
+			}
+		}
+
+		resp, err := client.ModelExportCar(ctx, requests)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(cctx.String("output"), resp.Car, 0644)
+		if err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+
+		fmt.Printf("%d models with %d commits exported to %s.\r\n", resp.Models, resp.Commits, cctx.String("output"))
+		return nil
+	},
+}
+
+var importCarCmd = &cli.Command{
+	Name:      "import-car",
+	Usage:     "create data models from a CAR file created by export-car",
+	UsageText: "each model is created with the content of its latest commit, the history is kept in the car file only.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Usage:    "path of the car file",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:     "duration",
+			Usage:    "how many days do you want to store the data",
+			Value:    DEFAULT_DURATION,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "delay",
+			Usage:    "how many epochs to wait for the content to be completed storing",
+			Value:    1 * 60,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "replica",
+			Usage:    "how many copies to store",
+			Value:    DEFAULT_REPLICA,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		file, err := os.Open(cctx.String("input"))
+		if err != nil {
+			return types.Wrap(types.ErrOpenFileFailed, err)
+		}
+		defer file.Close()
+		owner, models, err := car.Read(file)
+		if err != nil {
+			return err
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		if owner != didManager.Id {
+			fmt.Printf("the models of %s are imported as %s.\r\n", owner, didManager.Id)
+		}
+
+		gatewayAddress, err := client.GetNodeAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		imported := 0
+		for _, m := range models {
+			if len(m.Commits) == 0 {
+				continue
+			}
+			content := m.Commits[len(m.Commits)-1].Content
+
+			groupId := m.GroupId
+			if cctx.IsSet("platform") {
+				groupId = cctx.String("platform")
+			}
+			contentCid, err := utils.CalculateCid(content)
+			if err != nil {
+				return err
+			}
+
+			dataId := utils.GenerateDataId(didManager.Id + groupId)
+			proposal := saotypes.Proposal{
+				DataId:    dataId,
+				Owner:     didManager.Id,
+				Provider:  gatewayAddress,
+				GroupId:   groupId,
+				Duration:  uint64(time.Duration(60*60*24*cctx.Int("duration")) * time.Second / chain.Blocktime),
+				Replica:   int32(cctx.Int("replica")),
+				Timeout:   int32(cctx.Int("delay")),
+				Alias:     m.Alias,
+				Cid:       contentCid.String(),
+				CommitId:  dataId,
+				Size_:     uint64(len(content)),
+				Operation: 1,
+			}
+			clientProposal, err := buildClientProposal(ctx, didManager, proposal, client)
+			if err != nil {
+				return err
+			}
+
+			request, err := buildQueryRequest(ctx, didManager, saotypes.QueryProposal{Owner: didManager.Id, Keyword: dataId}, client, gatewayAddress)
+			if err != nil {
+				return err
+			}
+
+			resp, err := client.CreateWithRetry(ctx, request, clientProposal, 0, content)
+			if err != nil {
+				fmt.Printf("import %s failed: %v\r\n", m.DataId, err)
+				continue
+			}
+			fmt.Printf("%s imported, alias: %s, data id: %s\r\n", m.DataId, resp.Alias, resp.DataId)
+			imported++
+		}
+
+		fmt.Printf("%d of %d models imported.\r\n", imported, len(models))
+		return nil
+	},
+}
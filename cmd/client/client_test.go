@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	saoclient "sao-node/client"
 	"sao-node/utils"
+	"sao-node/utils/car"
 	"testing"
+	"time"
 
 	saodid "github.com/SaoNetwork/sao-did"
 	saokey "github.com/SaoNetwork/sao-did/key"
@@ -81,3 +85,53 @@ func getSidDocFunc() func(versionId string) (*sid.SidDocument, error) {
 		}, nil
 	}
 }
+
+func TestBenchStats(t *testing.T) {
+	ctx := context.TODO()
+	stats := runWorkload(ctx, "load", 100, 8, func(_ context.Context, i int) error {
+		if i%10 == 0 {
+			return fmt.Errorf("failed %d", i)
+		}
+		time.Sleep(time.Duration(i%5) * time.Millisecond)
+		return nil
+	})
+	report := stats.report()
+	require.Equal(t, 100, report.Requests)
+	require.Equal(t, 10, report.Errors)
+	require.NotEmpty(t, report.LastErr)
+	require.LessOrEqual(t, report.P50, report.P90)
+	require.LessOrEqual(t, report.P99, report.Max)
+	require.Equal(t, stats.latencies[len(stats.latencies)-1], report.Max)
+
+	b := &bench{size: 100}
+	content, err := b.content(12, 3)
+	require.NoError(t, err)
+	require.Len(t, content, 100)
+	require.True(t, json.Valid(content))
+}
+
+func TestReplayCommits(t *testing.T) {
+	steps, err := replayCommits([]car.Commit{
+		{CommitId: "1", Content: []byte(`{"a":1}`)},
+		{CommitId: "2", Content: []byte(`{"a":2,"b":"x"}`)},
+		{CommitId: "3", Content: []byte(`{"a":2,"b":"x"}`)},
+		{CommitId: "4", Content: []byte(`{"b":"y"}`)},
+	})
+	require.NoError(t, err)
+	require.Len(t, steps, 3)
+	require.Nil(t, steps[0].Patch)
+	require.Equal(t, []byte(`{"a":1}`), steps[0].Content)
+
+	for i := 1; i < len(steps); i++ {
+		content, err := utils.ApplyPatch(steps[i-1].Content, steps[i].Patch)
+		require.NoError(t, err)
+		require.Equal(t, steps[i].Content, content)
+		contentCid, err := utils.CalculateCid(content)
+		require.NoError(t, err)
+		require.Equal(t, contentCid, steps[i].Cid)
+	}
+	require.JSONEq(t, `{"b":"y"}`, string(steps[2].Content))
+
+	_, err = replayCommits([]car.Commit{{Content: []byte("plain")}, {Content: []byte("text")}})
+	require.Error(t, err)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	saoclient "sao-node/client"
+	cliutil "sao-node/cmd"
+	"sao-node/types"
+	"time"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli/v2"
+)
+
+var delegationFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     cliutil.FlagKeyName,
+		Usage:    "sao chain key name of the issuer did",
+		Required: false,
+	},
+	&cli.StringSliceFlag{
+		Name:  "capability",
+		Usage: fmt.Sprintf("capabilities to grant, %s, %s, %s, %s or %s", types.CapModelLoad, types.CapModelCommits, types.CapModelCreate, types.CapModelUpdate, types.CapAll),
+		Value: cli.NewStringSlice(types.CapModelLoad),
+	},
+	&cli.StringSliceFlag{
+		Name:  "resource",
+		Usage: "dataIds or aliases the capabilities apply to, * for all",
+		Value: cli.NewStringSlice(types.CapAll),
+	},
+	&cli.DurationFlag{
+		Name:  "duration",
+		Usage: "how long the delegation is valid",
+		Value: 24 * time.Hour,
+	},
+}
+
+var didDelegateCmd = &cli.Command{
+	Name:      "delegate",
+	Usage:     "grant another did the capabilities on your data models",
+	UsageText: "the delegation is written to --output, the audience passes it with --delegation to act on your behalf.",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "audience",
+			Usage:    "the did to delegate to",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "file to write the delegation to",
+			Value: "delegation.json",
+		},
+	}, delegationFlags...),
+	Action: func(cctx *cli.Context) error {
+		opt := saoclient.SaoClientOptions{
+			Repo:        cctx.String(FlagClientRepo),
+			Gateway:     "none",
+			ChainAddr:   cliutil.ChainAddress,
+			KeyringHome: cliutil.KeyringHome,
+		}
+		saoclient, closer, err := saoclient.NewSaoClient(cctx.Context, opt)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, saoclient.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		signed, err := signDelegation(cctx, didManager, cctx.String("audience"))
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(signed, "", "  ")
+		if err != nil {
+			return types.Wrap(types.ErrMarshalFailed, err)
+		}
+
+		err = os.WriteFile(cctx.String("output"), data, 0600)
+		if err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+
+		delegation := signed.Delegation
+		fmt.Printf("Delegated %v on %v to %s until %s, written to %s.\n", delegation.Capabilities, delegation.Resources,
+			delegation.Audience, time.Unix(delegation.Expiration, 0).Format(time.RFC3339), cctx.String("output"))
+		return nil
+	},
+}
+
+var didRevokeCmd = &cli.Command{
+	Name:      "revoke",
+	Usage:     "revoke a delegation created by 'did delegate'",
+	ArgsUsage: "<delegation file>",
+	UsageText: "the nonce of the delegation is added to the revocation list of the gateway, other gateways still accept it until it expires",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     cliutil.FlagKeyName,
+			Usage:    "sao chain key name of the issuer did",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		if cctx.NArg() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "the delegation file to revoke is required")
+		}
+
+		data, err := os.ReadFile(cctx.Args().First())
+		if err != nil {
+			return types.Wrap(types.ErrReadFileFailed, err)
+		}
+		var signed types.SignedDelegation
+		err = json.Unmarshal(data, &signed)
+		if err != nil {
+			return types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		if err := sdk.RevokeDelegation(ctx, &signed.Delegation); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked the delegation to %s.\n", signed.Delegation.Audience)
+		return nil
+	},
+}
+
+var didSessionCmd = &cli.Command{
+	Name:  "session",
+	Usage: "session keys which sign proposals on behalf of your did",
+	Subcommands: []*cli.Command{
+		didSessionCreateCmd,
+		didSessionRevokeCmd,
+		didSessionListCmd,
+	},
+}
+
+type sessionKeyFile struct {
+	// hex encoded secp256k1 secret of the session did:key
+	Secret  string
+	Session types.SignedDelegation
+}
+
+var didSessionCreateCmd = &cli.Command{
+	Name:      "create",
+	Usage:     "generate a session key and register it to the gateway",
+	UsageText: "the session key is written to --output, keep it as safe as the capabilities it is granted, the requests it signs set Session to your did.",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "file to write the session key to",
+			Value: "session.json",
+		},
+	}, delegationFlags...),
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		if err != nil {
+			return types.Wrap(types.ErrCreateProviderFailed, err)
+		}
+		sessionManager, err := cliutil.NewDidManagerWithSecret(secret)
+		if err != nil {
+			return err
+		}
+
+		signed, err := signDelegation(cctx, didManager, sessionManager.Id)
+		if err != nil {
+			return err
+		}
+
+		err = client.SessionRegister(ctx, signed)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(sessionKeyFile{
+			Secret:  hex.EncodeToString(secret),
+			Session: *signed,
+		}, "", "  ")
+		if err != nil {
+			return types.Wrap(types.ErrMarshalFailed, err)
+		}
+		err = os.WriteFile(cctx.String("output"), data, 0600)
+		if err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+
+		fmt.Printf("Registered session key %s until %s, written to %s.\n", sessionManager.Id,
+			time.Unix(signed.Delegation.Expiration, 0).Format(time.RFC3339), cctx.String("output"))
+		return nil
+	},
+}
+
+var didSessionRevokeCmd = &cli.Command{
+	Name:  "revoke",
+	Usage: "revoke a session key",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     cliutil.FlagKeyName,
+			Usage:    "sao chain key name of the issuer did",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "session",
+			Usage:    "the did of the session key",
+			Required: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		sessions, err := client.SessionList(ctx, didManager.Id)
+		if err != nil {
+			return err
+		}
+		var session *types.SignedDelegation
+		for i := range sessions {
+			if sessions[i].Delegation.Audience == cctx.String("session") {
+				session = &sessions[i]
+			}
+		}
+		if session == nil {
+			return types.Wrapf(types.ErrNotFound, "%s is not a session key of %s", cctx.String("session"), didManager.Id)
+		}
+
+		proposal := types.SessionRevoke{
+			Issuer:     didManager.Id,
+			Audience:   session.Delegation.Audience,
+			Nonce:      session.Delegation.Nonce,
+			Expiration: time.Now().Add(time.Minute).Unix(),
+		}
+		payload, err := proposal.Marshal()
+		if err != nil {
+			return types.Wrap(types.ErrMarshalFailed, err)
+		}
+		jws, err := didManager.CreateJWS(payload)
+		if err != nil {
+			return types.Wrap(types.ErrCreateJwsFailed, err)
+		}
+
+		err = client.SessionRevoke(ctx, &types.SessionRevokeProposal{
+			Proposal: proposal,
+			JwsSignature: saotypes.JwsSignature{
+				Protected: jws.Signatures[0].Protected,
+				Signature: jws.Signatures[0].Signature,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked session key %s.\n", proposal.Audience)
+		return nil
+	},
+}
+
+var didSessionListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list the session keys registered to the gateway",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     cliutil.FlagKeyName,
+			Usage:    "sao chain key name of the issuer did",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		sessions, err := client.SessionList(ctx, didManager.Id)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range sessions {
+			fmt.Printf("%s\texpires %s\t%v on %v\n", s.Delegation.Audience,
+				time.Unix(s.Delegation.Expiration, 0).Format(time.RFC3339), s.Delegation.Capabilities, s.Delegation.Resources)
+		}
+		return nil
+	},
+}
+
+// signDelegation signs the delegation to the audience with the capabilities given by the flags.
+func signDelegation(cctx *cli.Context, didManager *saodid.DidManager, audience string) (*types.SignedDelegation, error) {
+	if cctx.Duration("duration") <= 0 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid duration")
+	}
+
+	now := time.Now()
+	delegation := types.Delegation{
+		Issuer:       didManager.Id,
+		Audience:     audience,
+		Capabilities: cctx.StringSlice("capability"),
+		Resources:    cctx.StringSlice("resource"),
+		NotBefore:    now.Unix(),
+		Expiration:   now.Add(cctx.Duration("duration")).Unix(),
+		Nonce:        uuid.NewV4().String(),
+	}
+
+	payload, err := delegation.Marshal()
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	jws, err := didManager.CreateJWS(payload)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateJwsFailed, err)
+	}
+
+	return &types.SignedDelegation{
+		Delegation: delegation,
+		JwsSignature: saotypes.JwsSignature{
+			Protected: jws.Signatures[0].Protected,
+			Signature: jws.Signatures[0].Signature,
+		},
+	}, nil
+}
+
+// readDelegation reads the delegation file given by --delegation, it must be delegated to the did.
+func readDelegation(cctx *cli.Context, did string) (*types.SignedDelegation, error) {
+	if !cctx.IsSet("delegation") {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(cctx.String("delegation"))
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	var delegation types.SignedDelegation
+	err = json.Unmarshal(data, &delegation)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	if delegation.Delegation.Audience != did {
+		return nil, types.Wrapf(types.ErrInvalidDelegation, "delegated to %s, not %s", delegation.Delegation.Audience, did)
+	}
+	return &delegation, nil
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"sao-node/chain"
 	saoclient "sao-node/client"
 	cliutil "sao-node/cmd"
 	"sao-node/types"
+	"strings"
+
+	"github.com/fatih/color"
 
 	"github.com/tendermint/tendermint/libs/json"
 	"github.com/urfave/cli/v2"
@@ -17,6 +23,11 @@ var didCmd = &cli.Command{
 		didCreateCmd,
 		didShowInfoCmd,
 		didSignCmd,
+		didListCmd,
+		didDeleteCmd,
+		didDelegateCmd,
+		didRevokeCmd,
+		didSessionCmd,
 	},
 }
 
@@ -150,3 +161,113 @@ var didSignCmd = &cli.Command{
 		return nil
 	},
 }
+
+var didListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list the dids of the accounts in local keystore",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		opt := saoclient.SaoClientOptions{
+			Repo:        cctx.String(FlagClientRepo),
+			Gateway:     "none",
+			ChainAddr:   cliutil.ChainAddress,
+			KeyringHome: cliutil.KeyringHome,
+		}
+		saoclient, closer, err := saoclient.NewSaoClient(ctx, opt)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		accounts, err := chain.Accounts(ctx, cliutil.KeyringHome)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			didManager, _, err := cliutil.GetDidManagerByKey(ctx, account.Name)
+			if err != nil {
+				fmt.Printf("Key %s: %v\n", account.Name, err)
+				continue
+			}
+
+			paymentAddress, err := saoclient.QueryPaymentAddress(ctx, didManager.Id)
+			if err != nil {
+				paymentAddress = "not bound"
+			}
+
+			defaultMark := ""
+			if account.Name == saoclient.Cfg.KeyName {
+				defaultMark = " (default)"
+			}
+			fmt.Printf("Key:             %s%s\n", account.Name, defaultMark)
+			fmt.Printf("Address:         %s\n", account.Address)
+			fmt.Printf("DID:             %s\n", didManager.Id)
+			fmt.Printf("Payment Address: %s\n", paymentAddress)
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+var didDeleteCmd = &cli.Command{
+	Name:      "delete",
+	Usage:     "delete the key of a did from local keystore",
+	UsageText: "the did binding on chain is kept, the did can be recovered by importing the key again.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     cliutil.FlagKeyName,
+			Usage:    "sao chain key name which the did is generated on",
+			Required: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		keyName := cctx.String(cliutil.FlagKeyName)
+
+		opt := saoclient.SaoClientOptions{
+			Repo:        cctx.String(FlagClientRepo),
+			Gateway:     "none",
+			ChainAddr:   "none",
+			KeyringHome: cliutil.KeyringHome,
+		}
+		saoclient, closer, err := saoclient.NewSaoClient(ctx, opt)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, address, err := cliutil.GetDidManagerByKey(ctx, keyName)
+		if err != nil {
+			return err
+		}
+
+		console := color.New(color.FgRed, color.Bold)
+		console.Printf("It'll delete key %s (%s) of %s from local keystore, export it first if you still need it. Confirm with 'yes' :", keyName, address, didManager.Id)
+		reader := bufio.NewReader(os.Stdin)
+		indata, err := reader.ReadBytes('\n')
+		if err != nil {
+			return types.Wrap(types.ErrInvalidParameters, err)
+		}
+		if strings.ToLower(strings.TrimSpace(string(indata))) != "yes" {
+			return nil
+		}
+
+		err = chain.Delete(ctx, cliutil.KeyringHome, keyName)
+		if err != nil {
+			return err
+		}
+
+		if saoclient.Cfg.KeyName == keyName {
+			saoclient.Cfg.KeyName = ""
+			err = saoclient.SaveConfig(saoclient.Cfg)
+			if err != nil {
+				return types.Wrap(types.ErrWriteConfigFailed, err)
+			}
+			fmt.Println("it was the default key, run 'recover' to choose another one.")
+		}
+
+		fmt.Printf("Deleted DID %s.\n", didManager.Id)
+		return nil
+	},
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"mime"
+	"net/url"
 	"os"
 	"path/filepath"
+	apiclient "sao-node/api/client"
 	"sao-node/chain"
 	saoclient "sao-node/client"
 	cliutil "sao-node/cmd"
@@ -13,6 +18,7 @@ import (
 	"time"
 
 	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/ipfs/go-cid"
 	"github.com/urfave/cli/v2"
@@ -23,8 +29,12 @@ var fileCmd = &cli.Command{
 	Usage: "file management",
 	Subcommands: []*cli.Command{
 		createFileCmd,
+		updateFileCmd,
 		uploadCmd,
 		downloadCmd,
+		progressCmd,
+		presignCmd,
+		unpresignCmd,
 	},
 }
 
@@ -81,6 +91,12 @@ var createFileCmd = &cli.Command{
 			Value:    "",
 			Required: false,
 		},
+		&cli.StringFlag{
+			Name:     "content-type",
+			Usage:    "mime type of the file, detected from the file name and the local file if not set",
+			Required: false,
+		},
+		flagPriority,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -98,7 +114,14 @@ var createFileCmd = &cli.Command{
 		replicas := cctx.Int("replica")
 		delay := cctx.Int("delay")
 
-		extendInfo := cctx.String("extend-info")
+		contentType := cctx.String("content-type")
+		if contentType == "" {
+			contentType = detectContentType(cctx.String("file-name"))
+		} else if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return types.Wrapf(types.ErrInvalidParameters, "invalid content-type %s", contentType)
+		}
+		// the content type goes into the signed proposal, the gateway serves the file with it
+		extendInfo := utils.SetContentType(cctx.String("extend-info"), contentType)
 		if len(extendInfo) > 1024 {
 			return types.Wrapf(types.ErrInvalidParameters, "extend-info should no longer than 1024 characters")
 		}
@@ -151,6 +174,10 @@ var createFileCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
+		clientProposal.Priority, err = getPriority(cctx)
+		if err != nil {
+			return err
+		}
 
 		var orderId uint64 = 0
 		if clientPublish {
@@ -180,6 +207,93 @@ var createFileCmd = &cli.Command{
 	},
 }
 
+var updateFileCmd = &cli.Command{
+	Name:      "update",
+	Usage:     "commit a new version of a file model, only the delta from the latest version is transferred and stored",
+	UsageText: "the delta is made on the latest version, which is loaded from the gateway if it is small enough, or given by --base. The whole file is committed if the latest version isn't known, the delta isn't much smaller, or every 16 versions so a load resolves a few deltas at most.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "keyword",
+			Usage:    "file model's alias or dataId",
+			Required: true,
+		},
+		&cli.PathFlag{
+			Name:     "file",
+			Usage:    "local path of the new version",
+			Required: true,
+		},
+		&cli.PathFlag{
+			Name:  "base",
+			Usage: "local path of the latest version, which the delta is made on",
+		},
+		&cli.IntFlag{
+			Name:  "duration",
+			Usage: "how many days do you want to store the data.",
+			Value: DEFAULT_DURATION,
+		},
+		&cli.IntFlag{
+			Name:  "replica",
+			Usage: "how many copies to store.",
+			Value: DEFAULT_REPLICA,
+		},
+		&cli.StringSliceFlag{
+			Name: "tags",
+		},
+		&cli.StringFlag{
+			Name:  "extend-info",
+			Usage: "extend information for the model",
+		},
+		&cli.StringFlag{
+			Name:  "content-type",
+			Usage: "mime type of the file, detected from the file name and the local file if not set",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		content, err := os.ReadFile(cctx.Path("file"))
+		if err != nil {
+			return types.Wrap(types.ErrReadFileFailed, err)
+		}
+		var base []byte
+		if cctx.IsSet("base") {
+			base, err = os.ReadFile(cctx.Path("base"))
+			if err != nil {
+				return types.Wrap(types.ErrReadFileFailed, err)
+			}
+		}
+
+		contentType := cctx.String("content-type")
+		if contentType == "" {
+			contentType = detectContentType(cctx.Path("file"))
+		} else if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return types.Wrapf(types.ErrInvalidParameters, "invalid content-type %s", contentType)
+		}
+		opts := saoclient.DefaultModelOptions()
+		opts.Duration = cctx.Int("duration")
+		opts.Replica = cctx.Int("replica")
+		opts.Tags = cctx.StringSlice("tags")
+		opts.ExtendInfo = utils.SetContentType(cctx.String("extend-info"), contentType)
+		if len(opts.ExtendInfo) > 1024 {
+			return types.Wrapf(types.ErrInvalidParameters, "extend-info should no longer than 1024 characters")
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		resp, size, err := sdk.UpdateFile(ctx, cctx.String("keyword"), base, content, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("file name: %s, data id: %s, commit id: %s, %s committed for %s\r\n", resp.Alias, resp.DataId, resp.CommitId,
+			humanize.IBytes(uint64(size)), humanize.IBytes(uint64(len(content))))
+		return nil
+	},
+}
+
 var uploadCmd = &cli.Command{
 	Name:  "upload",
 	Usage: "upload file(s) to storage network",
@@ -194,6 +308,12 @@ var uploadCmd = &cli.Command{
 			Usage:    "remote multiaddr",
 			Required: true,
 		},
+		&cli.BoolFlag{
+			Name:     "dir",
+			Usage:    "upload the directory at --filepath as a single archive, create the model of the archive with the printed cid and extract its files with file download --extract",
+			Value:    false,
+			Required: false,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -204,6 +324,11 @@ var uploadCmd = &cli.Command{
 			return types.Wrapf(types.ErrInvalidParameters, "invalid multiaddr: %s", multiaddr)
 		}
 		peerId := strings.Split(multiaddr, "/p2p/")[1]
+		repo := cctx.String(FlagClientRepo)
+
+		if cctx.Bool("dir") {
+			return uploadDir(ctx, repo, multiaddr, peerId, fpath)
+		}
 
 		var files []string
 		err := filepath.Walk(fpath, func(path string, info os.FileInfo, err error) error {
@@ -224,7 +349,6 @@ var uploadCmd = &cli.Command{
 			return types.Wrap(types.ErrInvalidParameters, err)
 		}
 
-		repo := cctx.String(FlagClientRepo)
 		for _, file := range files {
 			c := saoclient.DoTransport(ctx, repo, multiaddr, peerId, file)
 			if c != cid.Undef {
@@ -257,6 +381,39 @@ var downloadCmd = &cli.Command{
 			Usage:    "file commitId",
 			Required: false,
 		},
+		&cli.BoolFlag{
+			Name:     "resume",
+			Usage:    "download chunk by chunk from the http file server of the gateway storing the file, verifying every chunk and resuming an interrupted download",
+			Value:    false,
+			Required: false,
+		},
+		&cli.StringSliceFlag{
+			Name:     "sources",
+			Usage:    "with --resume, more http file servers or ipfs gateways serving the chunks, like the storage nodes of the replicas, the chunks are downloaded from all of them concurrently",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "extract",
+			Usage:    "extract the files of a directory uploaded with file upload --dir",
+			Value:    false,
+			Required: false,
+		},
+		&cli.StringSliceFlag{
+			Name:     "paths",
+			Usage:    "with --extract, the files or directories of the archive to extract, all of them if not set",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "list",
+			Usage:    "with --extract, list the files of the archive instead of extracting them",
+			Value:    false,
+			Required: false,
+		},
+		&cli.PathFlag{
+			Name:     "output",
+			Usage:    "with --extract, the directory to extract the files to, the file name without .tar by default",
+			Required: false,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -312,6 +469,25 @@ var downloadCmd = &cli.Command{
 				return err
 			}
 
+			if cctx.Bool("resume") {
+				path, err := resumableDownload(ctx, client, request, cctx.StringSlice("sources"))
+				if err != nil {
+					return err
+				}
+				if cctx.Bool("extract") {
+					file, err := os.Open(path)
+					if err != nil {
+						return types.Wrap(types.ErrOpenFileFailed, err)
+					}
+					err = extractDownload(cctx, file, path)
+					file.Close()
+					if err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
 			resp, err := client.ModelLoad(ctx, request)
 			if err != nil {
 				return err
@@ -335,6 +511,17 @@ var downloadCmd = &cli.Command{
 			console.Println(resp.Cid)
 
 			path := filepath.Join("./", resp.Alias)
+			if cctx.Bool("extract") {
+				if resp.Content == "" {
+					return types.Wrapf(types.ErrInvalidContent, "%s is not returned by the gateway, download it with --resume", resp.DataId)
+				}
+				err = extractDownload(cctx, strings.NewReader(resp.Content), path)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
 			file, err := os.Create(path)
 			if err != nil {
 				return err
@@ -350,3 +537,310 @@ var downloadCmd = &cli.Command{
 		return nil
 	},
 }
+
+// resumableDownload downloads the file of the request from the /ipfs path of the gateway http file server and
+// the sources, the progress is kept next to the file until its content is verified.
+func resumableDownload(ctx context.Context, client *saoclient.SaoClient, request *types.MetadataProposal, sources []string) (string, error) {
+	res, err := client.QueryMetadata(ctx, request, 0)
+	if err != nil {
+		return "", types.Wrap(types.ErrQueryMetadataFailed, err)
+	}
+	// the replicas of a file are the whole content of the queried commit
+	contentCid := res.Metadata.Cid
+	for _, shard := range res.Shards {
+		contentCid = shard.Cid
+		break
+	}
+	c, err := cid.Decode(contentCid)
+	if err != nil {
+		return "", types.Wrap(types.ErrInvalidCid, err)
+	}
+
+	httpUrl, err := client.GetHttpUrl(ctx, res.Metadata.DataId)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(httpUrl.Url)
+	if err != nil {
+		return "", types.Wrap(types.ErrGetHttpUrlFaild, err)
+	}
+
+	path := filepath.Join("./", res.Metadata.Alias)
+	fmt.Printf("downloading %s (%s) to %s\r\n", res.Metadata.DataId, c, path)
+	err = saoclient.NewDownloader(append([]string{u.Scheme + "://" + u.Host}, sources...)...).Download(ctx, c, path)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("file downloaded to %s\r\n", path)
+	return path, nil
+}
+
+var progressCmd = &cli.Command{
+	Name:      "progress",
+	Usage:     "show the upload and distribution progress of a file",
+	UsageText: "saoclient file progress <cid>, it follows the progress through a websocket connection to the gateway until all storage nodes store the file.",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "usage: saoclient file progress <cid>")
+		}
+		contentCid := cctx.Args().First()
+
+		opt := saoclient.SaoClientOptions{
+			Repo:      cctx.String(FlagClientRepo),
+			Gateway:   "none",
+			ChainAddr: "none",
+		}
+		client, closer, err := saoclient.NewSaoClient(cctx.Context, opt)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		// the subscriptions are only served over websocket, http://... becomes ws://... and https://... wss://...
+		gateway := cliutil.Gateway
+		if gateway == "" || gateway == "none" {
+			gateway = client.Cfg.Gateway
+		}
+		gateway = strings.Replace(gateway, "http", "ws", 1)
+		gatewayApi, wsCloser, err := apiclient.NewGatewayApi(cctx.Context, gateway, client.Cfg.Token)
+		if err != nil {
+			return types.Wrap(types.ErrCreateApiServiceFailed, err)
+		}
+		defer wsCloser()
+
+		events, err := gatewayApi.ProgressSubscribe(cctx.Context, contentCid)
+		if err != nil {
+			return err
+		}
+		var phase types.ProgressPhase
+		for event := range events {
+			if phase != "" && event.Phase != phase {
+				fmt.Println()
+			}
+			phase = event.Phase
+			fmt.Printf("\r%-12s %s %6.2f%% %d/%d chunks %s/%s", event.Phase, progressBar(event.Percent, 30), event.Percent,
+				event.Chunk, event.Chunks, humanize.IBytes(event.Bytes), humanize.IBytes(event.Total))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+func progressBar(percent float64, width int) string {
+	filled := int(percent / 100 * float64(width))
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+// detectContentType returns the mime type of the file at path, the file is sniffed if the extension is unknown
+// and the file exists.
+func detectContentType(path string) string {
+	var head []byte
+	if file, err := os.Open(path); err == nil {
+		head = make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		head = head[:n]
+		file.Close()
+	}
+	return utils.DetectContentType(path, head)
+}
+
+var presignCmd = &cli.Command{
+	Name:      "presign",
+	Usage:     "create a link uploading a file of the cid and the size without a did until it expires",
+	UsageText: "the link holds the signed proposal of creating the file model, anyone with the link can PUT the content of the cid to it once, until it expires or is revoked by 'file unpresign'",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "file-name",
+			Usage:    "name of the file model",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "cid",
+			Usage:    "cid of the content to upload",
+			Required: true,
+		},
+		&cli.Uint64Flag{
+			Name:     "size",
+			Usage:    "size of the content to upload in bytes",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "duration",
+			Usage: "how many days do you want to store the data.",
+			Value: DEFAULT_DURATION,
+		},
+		&cli.IntFlag{
+			Name:  "delay",
+			Usage: "how many epochs to wait for the file ready",
+			Value: 1 * 60,
+		},
+		&cli.IntFlag{
+			Name:  "replica",
+			Usage: "how many copies to store.",
+			Value: DEFAULT_REPLICA,
+		},
+		&cli.StringSliceFlag{
+			Name: "tags",
+		},
+		&cli.StringFlag{
+			Name:  "content-type",
+			Usage: "mime type of the file, detected from the file name if not set",
+		},
+		&cli.DurationFlag{
+			Name:  "expire",
+			Usage: "how long the link is valid for",
+			Value: time.Hour,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		contentType := cctx.String("content-type")
+		if contentType == "" {
+			contentType = utils.DetectContentType(cctx.String("file-name"), nil)
+		} else if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return types.Wrapf(types.ErrInvalidParameters, "invalid content-type %s", contentType)
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		sdk, err := saoclient.NewSdk(client, didManager)
+		if err != nil {
+			return err
+		}
+		if groupId := cctx.String("platform"); groupId != "" {
+			sdk.GroupId = groupId
+		}
+
+		opts := saoclient.DefaultModelOptions()
+		opts.Duration = cctx.Int("duration")
+		opts.Timeout = cctx.Int("delay")
+		opts.Replica = cctx.Int("replica")
+		opts.Tags = cctx.StringSlice("tags")
+		opts.ExtendInfo = utils.SetContentType("", contentType)
+		token, err := sdk.PresignUpload(ctx, cctx.String("cid"), cctx.Uint64("size"), cctx.String("file-name"), opts, cctx.Duration("expire"))
+		if err != nil {
+			return err
+		}
+		uploadUrl, err := client.GetUploadUrl(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		console := color.New(color.FgMagenta, color.Bold)
+		fmt.Printf("Upload by PUT until %s.\r\n", time.Now().Add(cctx.Duration("expire")).Format(time.RFC3339))
+		fmt.Print("  HTTP Link : ")
+		console.Println(uploadUrl.Url)
+		fmt.Print("  Token     : ")
+		console.Println(token)
+		return nil
+	},
+}
+
+var unpresignCmd = &cli.Command{
+	Name:      "unpresign",
+	Usage:     "revoke a link created by 'file presign'",
+	ArgsUsage: "<link or token>",
+	UsageText: "the link is added to the revocation list of the gateway, other gateways still accept it until it expires",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		if cctx.NArg() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "the link or the token to revoke is required")
+		}
+		token := cctx.Args().First()
+		if i := strings.LastIndex(token, "/upload/"); i >= 0 {
+			token, _, _ = strings.Cut(token[i+len("/upload/"):], "?")
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		if err := sdk.RevokeUpload(ctx, token); err != nil {
+			return err
+		}
+		fmt.Println("The link is revoked.")
+		return nil
+	},
+}
+
+// uploadDir uploads the directory as a single archive, the files are listed in the manifest at the beginning of
+// the archive.
+func uploadDir(ctx context.Context, repo string, multiaddr string, peerId string, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return types.Wrapf(types.ErrInvalidParameters, "%s is not a directory", dir)
+	}
+	name := filepath.Base(filepath.Clean(dir)) + ".tar"
+
+	archive, err := os.CreateTemp("", "sao-*-"+name)
+	if err != nil {
+		return types.Wrap(types.ErrCreateFileFailed, err)
+	}
+	defer os.Remove(archive.Name())
+	manifest, err := saoclient.WriteArchive(archive, dir)
+	if cerr := archive.Close(); err == nil && cerr != nil {
+		err = types.Wrap(types.ErrCloseFileFailed, cerr)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("archived %d files (%s) of %s\r\n", len(manifest.Entries), humanize.IBytes(uint64(manifest.Size())), dir)
+
+	c := saoclient.DoTransport(ctx, repo, multiaddr, peerId, archive.Name())
+	if c == cid.Undef {
+		return types.Wrapf(types.ErrSendRequestFailed, "failed to upload the directory [%s], please try again", dir)
+	}
+	fmt.Printf("directory [%s] successfully uploaded, CID is %s.\r\n", dir, c.String())
+	fmt.Printf("create its model with: saoclient file create --file-name %s --cid %s\r\n", name, c.String())
+	return nil
+}
+
+// extractDownload lists or extracts the files of the downloaded archive.
+func extractDownload(cctx *cli.Context, archive io.Reader, path string) error {
+	if cctx.Bool("list") {
+		manifest, err := saoclient.ReadArchiveManifest(archive)
+		if err != nil {
+			return err
+		}
+		entries, err := manifest.Select(cctx.StringSlice("paths"))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s %10s %s %s\r\n", os.FileMode(entry.Mode), humanize.IBytes(uint64(entry.Size)),
+				time.Unix(entry.ModTime, 0).Format(time.RFC3339), entry.Path)
+		}
+		return nil
+	}
+
+	dest := cctx.String("output")
+	if dest == "" {
+		dest = strings.TrimSuffix(path, ".tar")
+	}
+	if dest == path {
+		return types.Wrapf(types.ErrInvalidParameters, "the files of %s can not be extracted to the archive itself, set --output", path)
+	}
+	entries, err := saoclient.ExtractArchive(archive, dest, cctx.StringSlice("paths"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fmt.Printf("extracted %s\r\n", filepath.Join(dest, filepath.FromSlash(entry.Path)))
+	}
+	fmt.Printf("%d files extracted to %s\r\n", len(entries), dest)
+	return nil
+}
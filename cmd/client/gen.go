@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	apitypes "sao-node/api/types"
+	"sao-node/gen/modelgen"
+	"sao-node/node/model/schema/registry"
+	"sao-node/types"
+	"sao-node/utils"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+var genCmd = &cli.Command{
+	Name:      "gen",
+	Usage:     "generate the Go types of the data models of a schema",
+	UsageText: "the types are generated with the helpers validating the models against the schema and creating, loading and updating them by the Go SDK of the client package.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "schema",
+			Usage:    "dataId of the schema model, or name@version of a published schema",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "type",
+			Usage:    "name of the Go type of the models",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "path of the Go file generated",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "package",
+			Usage:    "package of the Go file, the name of the output directory by default",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		schemaDataId := cctx.String("schema")
+		_, _, isRef := registry.ParseRef(schemaDataId)
+		if !isRef && !utils.IsDataId(schemaDataId) {
+			return types.Wrapf(types.ErrInvalidDataId, "invalid schema dataId %s", schemaDataId)
+		}
+		output := cctx.String("output")
+		pkg := cctx.String("package")
+		if pkg == "" {
+			abs, err := filepath.Abs(filepath.Dir(output))
+			if err != nil {
+				return types.Wrap(types.ErrInvalidParameters, err)
+			}
+			pkg = strings.ReplaceAll(filepath.Base(abs), "-", "_")
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		var schema apitypes.LoadResp
+		if isRef {
+			_, schema, err = sdk.LoadSchema(ctx, schemaDataId)
+			schemaDataId = schema.DataId
+		} else {
+			schema, err = sdk.LoadLatest(ctx, schemaDataId)
+		}
+		if err != nil {
+			return err
+		}
+
+		code, err := modelgen.Generate(modelgen.Options{
+			Package:      pkg,
+			Type:         cctx.String("type"),
+			SchemaDataId: schemaDataId,
+		}, []byte(schema.Content))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, code, 0644); err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+
+		fmt.Printf("%s of schema %s generated in %s.\r\n", cctx.String("type"), schemaDataId, output)
+		return nil
+	},
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	saoclient "sao-node/client"
+	"sao-node/node/model/eventlog"
+	"sao-node/types"
+
+	"github.com/urfave/cli/v2"
+)
+
+var logCmd = &cli.Command{
+	Name:      "log",
+	Usage:     "append-only log models, such as chat logs and telemetry",
+	UsageText: "each append commits the new entries only, the entries are read by their sequence numbers from 0. The logs are compacted by their policies, so a read doesn't look back over many commits.",
+	Subcommands: []*cli.Command{
+		logCreateCmd,
+		logAppendCmd,
+		logReadCmd,
+	},
+}
+
+var logCreateCmd = &cli.Command{
+	Name:  "create",
+	Usage: "create a log model",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "name",
+			Usage:    "name of the log, the alias of the model is log_<name>",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "compact-every",
+			Usage: "compact the log every n commits, the commit holds all the entries retained then. 0 never compacts",
+		},
+		&cli.Uint64Flag{
+			Name:  "retain",
+			Usage: "how many of the latest entries a compaction keeps, 0 keeps all",
+		},
+		&cli.IntFlag{
+			Name:     "duration",
+			Usage:    "how many days do you want to store the log",
+			Value:    DEFAULT_DURATION,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "replica",
+			Usage:    "how many copies to store",
+			Value:    DEFAULT_REPLICA,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		opts := saoclient.DefaultModelOptions()
+		opts.Duration = cctx.Int("duration")
+		opts.Replica = cctx.Int("replica")
+		resp, err := sdk.CreateLog(ctx, cctx.String("name"), eventlog.Policy{
+			CompactEvery: cctx.Int("compact-every"),
+			Retain:       cctx.Uint64("retain"),
+		}, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("log created, alias: %s, dataId: %s\r\n", resp.Alias, resp.DataId)
+		return nil
+	},
+}
+
+var logAppendCmd = &cli.Command{
+	Name:  "append",
+	Usage: "append json entries to a log model",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "keyword",
+			Usage:    "log model's alias or dataId",
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name:  "entry",
+			Usage: "json entry to append, can be repeated",
+		},
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "file of the json entries to append, one per line, - for the standard input",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		var entries []json.RawMessage
+		for _, entry := range cctx.StringSlice("entry") {
+			entries = append(entries, json.RawMessage(entry))
+		}
+		if cctx.IsSet("file") {
+			lines, err := readLines(cctx.String("file"))
+			if err != nil {
+				return err
+			}
+			entries = append(entries, lines...)
+		}
+		if len(entries) == 0 {
+			return types.Wrapf(types.ErrInvalidParameters, "either --entry or --file is required")
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		resp, first, err := sdk.AppendLog(ctx, cctx.String("keyword"), entries, saoclient.DefaultModelOptions())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("appended entries %d to %d, commit id: %s\r\n", first, first+uint64(len(entries))-1, resp.CommitId)
+		return nil
+	},
+}
+
+var logReadCmd = &cli.Command{
+	Name:  "read",
+	Usage: "print the entries of a log model by sequence number, one per line",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "keyword",
+			Usage:    "log model's alias or dataId",
+			Required: true,
+		},
+		&cli.Uint64Flag{
+			Name:  "from",
+			Usage: "sequence number of the first entry, the entries dropped by the compactions are skipped",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "how many entries to read at most, 0 reads all",
+			Value: 100,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		entries, first, err := sdk.ReadLog(ctx, cctx.String("keyword"), cctx.Uint64("from"), cctx.Int("limit"))
+		if err != nil {
+			return err
+		}
+		for i, entry := range entries {
+			fmt.Printf("%d\t%s\r\n", first+uint64(i), entry)
+		}
+		return nil
+	},
+}
+
+// readLines reads the non-empty lines of the file, or of the standard input for -.
+func readLines(path string) ([]json.RawMessage, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var lines []json.RawMessage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) > 0 {
+			lines = append(lines, json.RawMessage(append([]byte(nil), line...)))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return lines, nil
+}
@@ -14,6 +14,7 @@ import (
 	cliutil "sao-node/cmd"
 	"sao-node/cmd/account"
 	"sao-node/types"
+	"sao-node/utils/redact"
 	"strings"
 
 	"cosmossdk.io/math"
@@ -42,6 +43,13 @@ var flagPlatform = &cli.StringFlag{
 	Required: false,
 }
 
+var flagPriority = &cli.StringFlag{
+	Name:     "priority",
+	Usage:    "how soon the gateway and the storage nodes process the order: interactive, normal or archival",
+	Value:    types.PriorityNormal.String(),
+	Required: false,
+}
+
 func getSaoClient(cctx *cli.Context) (*client.SaoClient, func(), error) {
 	opt := client.SaoClientOptions{
 		Repo:        cctx.String(FlagClientRepo),
@@ -53,7 +61,15 @@ func getSaoClient(cctx *cli.Context) (*client.SaoClient, func(), error) {
 	return client.NewSaoClient(cctx.Context, opt)
 }
 
-func before(_ *cli.Context) error {
+func getPriority(cctx *cli.Context) (types.Priority, error) {
+	return types.ParsePriority(cctx.String(flagPriority.Name))
+}
+
+func before(cctx *cli.Context) error {
+	if err := redact.Install(); err != nil {
+		return err
+	}
+
 	// by default, do not print any log for client.
 	_ = logging.SetLogLevel("saoclient", "TRACE")
 	_ = logging.SetLogLevel("chain", "TRACE")
@@ -65,7 +81,7 @@ func before(_ *cli.Context) error {
 		_ = logging.SetLogLevel("transport-client", "DEBUG")
 	}
 
-	return nil
+	return cliutil.SetupKeyring(cctx, cctx.String(FlagClientRepo), cctx.App.Name)
 }
 
 func main() {
@@ -82,6 +98,8 @@ func main() {
 			flagPlatform,
 			cliutil.FlagVeryVerbose,
 			cliutil.FlagKeyringHome,
+			cliutil.FlagKeyringBackend,
+			cliutil.FlagSigner,
 		},
 		Commands: []*cli.Command{
 			initCmd,
@@ -89,7 +107,15 @@ func main() {
 			netCmd,
 			modelCmd,
 			fileCmd,
+			mountCmd,
 			didCmd,
+			benchCmd,
+			snapshotCmd,
+			queueCmd,
+			syncCmd,
+			genCmd,
+			schemaCmd,
+			logCmd,
 			account.AccountCmd,
 			cliutil.GenerateDocCmd,
 		},
@@ -7,17 +7,21 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	apitypes "sao-node/api/types"
 	"sao-node/chain"
+	saoclient "sao-node/client"
 	cliutil "sao-node/cmd"
 	"sao-node/types"
 	"sao-node/utils"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	did "github.com/SaoNetwork/sao-did"
 	saotypes "github.com/SaoNetwork/sao/x/sao/types"
 	"github.com/fatih/color"
+	"github.com/filecoin-project/lotus/lib/tablewriter"
 	"github.com/ipfs/go-cid"
 	"github.com/urfave/cli/v2"
 )
@@ -31,14 +35,23 @@ var modelCmd = &cli.Command{
 		patchGenCmd,
 		updateCmd,
 		updatePermissionCmd,
+		permissionHistoryCmd,
 		loadCmd,
 		deleteCmd,
 		commitsCmd,
+		provenanceCmd,
+		shareCmd,
+		unshareCmd,
 		listCmd,
 		renewCmd,
 		statusCmd,
 		metaCmd,
 		orderCmd,
+		ordersCmd,
+		commitStatusCmd,
+		watchCmd,
+		exportCarCmd,
+		importCarCmd,
 	},
 }
 
@@ -87,6 +100,7 @@ var createCmd = &cli.Command{
 		},
 		&cli.StringFlag{
 			Name:     "rule",
+			Usage:    "the rule of the model, a CEL expression of new and old contents prefixed by cel: is checked on updates, e.g. cel:new.balance >= old.balance",
 			Value:    "",
 			Required: false,
 		},
@@ -102,11 +116,25 @@ var createCmd = &cli.Command{
 			Value:    "",
 			Required: false,
 		},
+		flagPriority,
 		&cli.BoolFlag{
 			Name:     "public",
 			Value:    false,
 			Required: false,
 		},
+		&cli.BoolFlag{
+			Name:     "estimate",
+			Usage:    "show the estimated fee and gas of the order only, the model is not created",
+			Value:    false,
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "async",
+			Usage:    "return once the order is stored on chain, check the progress by commit-status",
+			Value:    false,
+			Required: false,
+		},
+		flagQueue,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -130,7 +158,11 @@ var createCmd = &cli.Command{
 			return types.Wrapf(types.ErrInvalidParameters, "extend-info should no longer than 1024 characters")
 		}
 
-		client, closer, err := getSaoClient(cctx)
+		if cctx.Bool(flagQueue.Name) && (isPublic || clientPublish || cctx.Bool("estimate") || cctx.Bool("async")) {
+			return types.Wrapf(types.ErrInvalidParameters, "--queue can't be used with --public, --client-publish, --estimate or --async")
+		}
+
+		client, closer, err := getQueueingSaoClient(cctx)
 		if err != nil {
 			return err
 		}
@@ -155,6 +187,23 @@ var createCmd = &cli.Command{
 			return err
 		}
 
+		queued, err := queueIfOffline(cctx, client, didManager, saoclient.QueuedOp{
+			Kind:    saoclient.QueuedCreate,
+			GroupId: groupId,
+			Options: saoclient.ModelOptions{
+				Alias:      cctx.String("name"),
+				Tags:       cctx.StringSlice("tags"),
+				Rule:       cctx.String("rule"),
+				ExtendInfo: extendInfo,
+				Duration:   duration,
+				Replica:    replicas,
+				Timeout:    delay,
+			},
+		}, content)
+		if err != nil || queued {
+			return err
+		}
+
 		gatewayAddress, err := client.GetNodeAddress(ctx)
 		if err != nil {
 			return err
@@ -183,6 +232,18 @@ var createCmd = &cli.Command{
 			proposal.Alias = proposal.Cid
 		}
 
+		if cctx.Bool("estimate") {
+			fee, err := client.ModelEstimateFee(ctx, didManager.Id, proposal.Size_, proposal.Duration, proposal.Replica)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("fee: %s, gas: %d, balance: %s\r\n", fee.Amount, fee.Gas, fee.Balance)
+			if !fee.Sufficient {
+				fmt.Println("the balance is insufficient for the order")
+			}
+			return nil
+		}
+
 		queryProposal := saotypes.QueryProposal{
 			Owner:   didManager.Id,
 			Keyword: dataId,
@@ -197,6 +258,10 @@ var createCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
+		clientProposal.Priority, err = getPriority(cctx)
+		if err != nil {
+			return err
+		}
 
 		var orderId uint64 = 0
 		if clientPublish {
@@ -212,7 +277,16 @@ var createCmd = &cli.Command{
 			return err
 		}
 
-		resp, err := client.ModelCreate(ctx, request, clientProposal, orderId, content)
+		if cctx.Bool("async") {
+			resp, err := client.ModelCreateAsync(ctx, request, clientProposal, orderId, content)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("alias: %s, data id: %s, wait for the commit by: saoclient model watch --until-complete %s\r\n", resp.Alias, resp.DataId, resp.DataId)
+			return nil
+		}
+
+		resp, err := client.CreateWithRetry(ctx, request, clientProposal, orderId, content)
 		if err != nil {
 			return err
 		}
@@ -247,6 +321,23 @@ var loadCmd = &cli.Command{
 			Usage:    "dump data model content to ./<dataid>.json",
 			Required: false,
 		},
+		&cli.StringFlag{
+			Name:     "delegation",
+			Usage:    "load on behalf of the issuer with the delegation file created by 'did delegate'",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "meta-only",
+			Value:    false,
+			Usage:    "load the metadata only, the content is not fetched by the gateway",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "resolve-depth",
+			Value:    0,
+			Usage:    "how many levels of the {\"$ref\": \"sao://<dataId>\"} links in the content to replace with the linked models",
+			Required: false,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -300,8 +391,23 @@ var loadCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
+		request.Delegation, err = readDelegation(cctx, didManager.Id)
+		if err != nil {
+			return err
+		}
+		request.ResolveDepth = cctx.Int("resolve-depth")
 
-		resp, err := client.ModelLoad(ctx, request)
+		metaOnly := cctx.Bool("meta-only")
+		if metaOnly && cctx.Bool("dump") {
+			return types.Wrapf(types.ErrInvalidParameters, "--dump can't be used with --meta-only")
+		}
+
+		var resp apitypes.LoadResp
+		if metaOnly {
+			resp, err = client.ModelLoadMeta(ctx, request)
+		} else {
+			resp, err = client.ModelLoad(ctx, request)
+		}
 		if err != nil {
 			return err
 		}
@@ -328,6 +434,10 @@ var loadCmd = &cli.Command{
 			return types.Wrap(types.ErrInvalidAlias, err)
 		}
 
+		if metaOnly {
+			return nil
+		}
+
 		if len(resp.Content) == 0 || match {
 			fmt.Print("  SAO Link  : ")
 			console.Println("sao://" + resp.DataId)
@@ -442,28 +552,19 @@ var renewCmd = &cli.Command{
 			Data:     dataIds,
 		}
 
-		proposalBytes, err := proposal.Marshal()
-		if err != nil {
-			return types.Wrap(types.ErrMarshalFailed, err)
-		}
-
-		jws, err := didManager.CreateJWS(proposalBytes)
+		clientProposal, err := saoclient.BuildRenewProposal(didManager, proposal)
 		if err != nil {
-			return types.Wrap(types.ErrCreateJwsFailed, err)
-		}
-		clientProposal := types.OrderRenewProposal{
-			Proposal:     proposal,
-			JwsSignature: saotypes.JwsSignature(jws.Signatures[0]),
+			return err
 		}
 
 		var results map[string]string
 		if clientPublish {
-			_, results, err = client.RenewOrder(ctx, signer, clientProposal)
+			_, results, err = client.RenewOrder(ctx, signer, *clientProposal)
 			if err != nil {
 				return err
 			}
 		} else {
-			res, err := client.ModelRenewOrder(ctx, &clientProposal, !clientPublish)
+			res, err := client.ModelRenewOrder(ctx, clientProposal, !clientPublish)
 			if err != nil {
 				return err
 			}
@@ -511,6 +612,12 @@ var statusCmd = &cli.Command{
 			Usage:    "data model's dataId list",
 			Required: true,
 		},
+		&cli.IntFlag{
+			Name:     "concurrency",
+			Usage:    "how many data models to query at the same time",
+			Value:    8,
+			Required: false,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -519,6 +626,10 @@ var statusCmd = &cli.Command{
 			return types.Wrapf(types.ErrInvalidParameters, "must provide --data-ids")
 		}
 		dataIds := cctx.StringSlice("data-ids")
+		concurrency := cctx.Int("concurrency")
+		if concurrency <= 0 {
+			return types.Wrapf(types.ErrInvalidParameters, "--concurrency should be positive")
+		}
 
 		client, closer, err := getSaoClient(cctx)
 		if err != nil {
@@ -536,51 +647,62 @@ var statusCmd = &cli.Command{
 			return err
 		}
 
-		states := ""
-		for _, dataId := range dataIds {
-			proposal := saotypes.QueryProposal{
-				Owner:   didManager.Id,
-				Keyword: dataId,
-			}
-
-			request, err := buildQueryRequest(ctx, didManager, proposal, client, gatewayAddress)
-			if err != nil {
-				return err
-			}
+		currentHeight, err := client.GetLastHeight(ctx)
+		if err != nil {
+			return err
+		}
 
-			res, err := client.QueryMetadata(ctx, request, 0)
-			if err != nil {
-				if len(states) > 0 {
-					states = fmt.Sprintf("%s\n[%s]: %s", states, dataId, err.Error())
-				} else {
-					states = fmt.Sprintf("[%s]: %s", dataId, err.Error())
+		consoleOK := color.New(color.FgGreen, color.Bold)
+		consoleWarn := color.New(color.FgHiRed, color.Bold)
+
+		// the states are printed in the order of the data ids
+		states := make([]string, len(dataIds))
+		failed := 0
+		var lk sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for i, dataId := range dataIds {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, dataId string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				proposal := saotypes.QueryProposal{
+					Owner:   didManager.Id,
+					Keyword: dataId,
 				}
-			} else {
-				duration := res.Metadata.Duration
-				currentHeight, err := client.GetLastHeight(ctx)
-				if err != nil {
-					return err
+				request, err := buildQueryRequest(ctx, didManager, proposal, client, gatewayAddress)
+				var res *saotypes.QueryMetadataResponse
+				if err == nil {
+					res, err = client.QueryMetadata(ctx, request, 0)
 				}
-				stored := uint64(currentHeight) - res.Metadata.CreatedAt
-				if len(states) > 0 {
-					states = states + "\n"
+				if err != nil {
+					lk.Lock()
+					failed++
+					lk.Unlock()
+					states[i] = fmt.Sprintf("[%s]: %s", dataId, err.Error())
+					return
 				}
-				consoleOK := color.New(color.FgGreen, color.Bold)
-				consoleWarn := color.New(color.FgHiRed, color.Bold)
 
-				var leftHeight uint64
+				duration := res.Metadata.Duration
+				stored := uint64(currentHeight) - res.Metadata.CreatedAt
 				if duration >= stored {
-					leftHeight = duration - stored
-					states = fmt.Sprintf("%s[%s]: expired in %s heights", states, dataId, consoleOK.Sprintf("%d", leftHeight))
+					states[i] = fmt.Sprintf("[%s]: expired in %s heights", dataId, consoleOK.Sprintf("%d", duration-stored))
 				} else {
-					leftHeight = stored - duration
-					states = fmt.Sprintf("%s[%s]: expired %s heights ago", states, dataId, consoleWarn.Sprintf("%d", leftHeight))
+					states[i] = fmt.Sprintf("[%s]: expired %s heights ago", dataId, consoleWarn.Sprintf("%d", stored-duration))
 				}
-			}
+			}(i, dataId)
 		}
+		wg.Wait()
 
-		fmt.Println(states)
+		fmt.Println(strings.Join(states, "\n"))
 
+		if failed > 0 {
+			return types.Wrapf(types.ErrQueryMetadataFailed, "%d of %d data models failed", failed, len(dataIds))
+		}
 		return nil
 	},
 }
@@ -745,6 +867,189 @@ var orderCmd = &cli.Command{
 	},
 }
 
+var ordersCmd = &cli.Command{
+	Name:      "orders",
+	Usage:     "list the orders committed through the gateway",
+	UsageText: "saoclient model orders [dataId], lists the orders of the client did by default, or shows the order of the dataId.",
+	Flags:     cliutil.OrderFilterFlags,
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		filter, err := cliutil.GetOrderFilter(cctx)
+		if err != nil {
+			return err
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		if cctx.Args().Len() > 0 {
+			order, err := client.OrderStatus(ctx, cctx.Args().First())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("DataId: %s\n", order.DataId)
+			fmt.Printf("Owner: %s\n", order.Owner)
+			fmt.Printf("Cid: %s\n", order.Cid)
+			fmt.Printf("OrderId: %d\n", order.OrderId)
+			fmt.Printf("OrderHash: %s\n", order.OrderHash)
+			fmt.Printf("OrderHeight: %d\n", order.OrderHeight)
+			fmt.Printf("ExpireHeight: %d\n", order.ExpireHeight)
+			fmt.Printf("State: %s\n", order.State)
+			if order.State == types.OrderStateExpired {
+				fmt.Printf("ChainStatus: %s\n", chain.OrderStatusString(int32(order.ChainStatus)))
+			}
+			fmt.Printf("Tries: %d\n", order.Tries)
+			if order.Republishes > 0 {
+				fmt.Printf("Republishes: %d\n", order.Republishes)
+			}
+			if order.LastErr != "" {
+				fmt.Printf("LastErr: %s\n", order.LastErr)
+			}
+			fmt.Println("Shards: ")
+			for node, shard := range order.Shards {
+				fmt.Printf("Provider: %s, ShardId: %d, Cid: %s, State: %s\n", node, shard.ShardId, shard.Cid, shard.State)
+			}
+			return nil
+		}
+
+		if !cctx.IsSet("owner") {
+			didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+			if err != nil {
+				return err
+			}
+			filter.Owner = didManager.Id
+		}
+		orders, err := client.OrderList(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("DataId"),
+			tablewriter.Col("OrderId"),
+			tablewriter.Col("State"),
+			tablewriter.Col("Height"),
+			tablewriter.NewLineCol("LastErr"),
+		)
+		for _, order := range orders {
+			tw.Write(map[string]interface{}{
+				"DataId":  order.DataId,
+				"OrderId": order.OrderId,
+				"State":   order.State,
+				"Height":  order.OrderHeight,
+				"LastErr": order.LastErr,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
+
+var commitStatusCmd = &cli.Command{
+	Name:      "commit-status",
+	Usage:     "check the progress of committing a data model",
+	UsageText: "saoclient model commit-status <dataId>",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		if cctx.Args().Len() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "usage: saoclient model commit-status <dataId>")
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		status, err := client.CommitStatus(ctx, cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("data id: %s, order id: %d, state: %s, shards completed: %d/%d\r\n",
+			status.DataId, status.OrderId, status.State, status.CompletedShards, status.Shards)
+		if status.LastErr != "" {
+			fmt.Printf("last error: %s\r\n", status.LastErr)
+		}
+		return nil
+	},
+}
+
+var watchCmd = &cli.Command{
+	Name:      "watch",
+	Usage:     "print the order and update events of a data model, or of the data models of the did",
+	UsageText: "saoclient model watch [dataId]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:     "until-complete",
+			Usage:    "return once an order of the data model completes",
+			Value:    false,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		if cctx.Args().Len() > 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "usage: saoclient model watch [dataId]")
+		}
+		dataId := cctx.Args().First()
+		untilComplete := cctx.Bool("until-complete")
+		if untilComplete && dataId == "" {
+			return types.Wrapf(types.ErrInvalidParameters, "--until-complete requires a dataId")
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		owner := ""
+		if dataId == "" {
+			didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+			if err != nil {
+				return err
+			}
+			owner = didManager.Id
+		}
+
+		completed := make(chan struct{}, 1)
+		sub, err := client.Subscribe(ctx, dataId, owner, func(event types.ModelEvent) {
+			switch event.Kind {
+			case types.ModelEventOrder:
+				fmt.Printf("%s order, data id: %s, order id: %d, state: %s\r\n",
+					time.Unix(event.Time, 0).Format(time.RFC3339), event.DataId, event.OrderId, event.State)
+			case types.ModelEventUpdate:
+				fmt.Printf("%s update, data id: %s, alias: %s, height: %d, tx: %s\r\n",
+					time.Unix(event.Time, 0).Format(time.RFC3339), event.DataId, event.Alias, event.Height, event.TxHash)
+			}
+			if untilComplete && event.Completed() {
+				select {
+				case completed <- struct{}{}:
+				default:
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		defer sub.Close()
+
+		select {
+		case <-completed:
+			return nil
+		case <-sub.Done():
+			return types.Wrapf(types.ErrSendRequestFailed, "the connection to the gateway is lost")
+		case <-ctx.Done():
+			return nil
+		}
+	},
+}
+
 var deleteCmd = &cli.Command{
 	Name:  "delete",
 	Usage: "delete data model",
@@ -821,6 +1126,11 @@ var commitsCmd = &cli.Command{
 			Usage:    "data model's alias, dataId or tag",
 			Required: true,
 		},
+		&cli.StringFlag{
+			Name:     "delegation",
+			Usage:    "list commits on behalf of the issuer with the delegation file created by 'did delegate'",
+			Required: false,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -865,6 +1175,10 @@ var commitsCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
+		request.Delegation, err = readDelegation(cctx, didManager.Id)
+		if err != nil {
+			return err
+		}
 
 		resp, err := client.ModelShowCommits(ctx, request)
 		if err != nil {
@@ -896,6 +1210,211 @@ var commitsCmd = &cli.Command{
 	},
 }
 
+var provenanceCmd = &cli.Command{
+	Name:      "provenance",
+	Usage:     "verify who made each commit of a data model and when",
+	UsageText: "the gateway verifies the JWS of the order of each commit against the sid document version valid at the commit, and signs the report by its peer key",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "keyword",
+			Usage:    "data model's alias, dataId or tag",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "delegation",
+			Usage:    "verify the commits on behalf of the issuer with the delegation file created by 'did delegate'",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "write the signed report to the file, which can be verified without the gateway",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		keyword := cctx.String("keyword")
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		groupId := cctx.String("platform")
+		if groupId == "" {
+			groupId = client.Cfg.GroupId
+		}
+
+		proposal := saotypes.QueryProposal{
+			Owner:   didManager.Id,
+			Keyword: keyword,
+			GroupId: groupId,
+		}
+		if !utils.IsDataId(keyword) {
+			proposal.KeywordType = 2
+		}
+
+		gatewayAddress, err := client.GetNodeAddress(ctx)
+		if err != nil {
+			return err
+		}
+		request, err := buildQueryRequest(ctx, didManager, proposal, client, gatewayAddress)
+		if err != nil {
+			return err
+		}
+		request.Delegation, err = readDelegation(cctx, didManager.Id)
+		if err != nil {
+			return err
+		}
+
+		signed, err := client.ModelProvenance(ctx, request)
+		if err != nil {
+			return err
+		}
+		report, err := client.OpenProvenanceReport(ctx, &signed)
+		if err != nil {
+			return err
+		}
+		if output := cctx.String("output"); output != "" {
+			data, err := json.MarshalIndent(signed, "", "  ")
+			if err != nil {
+				return types.Wrap(types.ErrMarshalFailed, err)
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return types.Wrap(types.ErrWriteFileFailed, err)
+			}
+		}
+
+		fmt.Printf("data id: %s, alias: %s, owner: %s\r\n", report.DataId, report.Alias, report.Owner)
+		fmt.Printf("reported by gateway %s (peer %s) at height %d\r\n", report.Gateway, signed.PeerId, report.Height)
+		tw := tablewriter.New(
+			tablewriter.Col("Version"),
+			tablewriter.Col("Commit"),
+			tablewriter.Col("Height"),
+			tablewriter.Col("Time"),
+			tablewriter.Col("Signer"),
+			tablewriter.Col("Verified"),
+			tablewriter.NewLineCol("Error"),
+		)
+		for _, commit := range report.Commits {
+			signer := commit.Signer
+			if commit.SidVersion != "" {
+				signer += "?versionId=" + commit.SidVersion
+			}
+			tw.Write(map[string]interface{}{
+				"Version":  commit.Version,
+				"Commit":   commit.CommitId,
+				"Height":   commit.Height,
+				"Time":     commit.Time.Format(time.RFC3339),
+				"Signer":   signer,
+				"Verified": commit.Verified,
+				"Error":    commit.Error,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
+
+var shareCmd = &cli.Command{
+	Name:      "share",
+	Usage:     "create a link loading a data model without a did until it expires",
+	UsageText: "the link holds a signed delegation of loading the data model, anyone with the link can load the version shared until it expires or is revoked by 'model unshare'",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "keyword",
+			Usage:    "data model's alias or dataId",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "version",
+			Usage:    "data model's version, the latest commit when the link is used if neither --version nor --commit-id is given",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "commit-id",
+			Usage:    "data model's commitId",
+			Required: false,
+		},
+		&cli.DurationFlag{
+			Name:  "expire",
+			Usage: "how long the link is valid for",
+			Value: 24 * time.Hour,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		sdk, err := saoclient.NewSdk(client, didManager)
+		if err != nil {
+			return err
+		}
+		if groupId := cctx.String("platform"); groupId != "" {
+			sdk.GroupId = groupId
+		}
+
+		token, err := sdk.Share(ctx, cctx.String("keyword"), cctx.String("commit-id"), cctx.String("version"), cctx.Duration("expire"))
+		if err != nil {
+			return err
+		}
+		shareUrl, err := client.GetShareUrl(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		console := color.New(color.FgMagenta, color.Bold)
+		fmt.Printf("Shared until %s.\r\n", time.Now().Add(cctx.Duration("expire")).Format(time.RFC3339))
+		fmt.Print("  HTTP Link : ")
+		console.Println(shareUrl.Url)
+		fmt.Print("  Token     : ")
+		console.Println(token)
+		return nil
+	},
+}
+
+var unshareCmd = &cli.Command{
+	Name:      "unshare",
+	Usage:     "revoke a link created by 'model share'",
+	ArgsUsage: "<link or token>",
+	UsageText: "the link is added to the revocation list of the gateway, other gateways still accept it until it expires",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		if cctx.NArg() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "the link or the token to revoke is required")
+		}
+		token := cctx.Args().First()
+		if i := strings.LastIndex(token, "/share/"); i >= 0 {
+			token, _, _ = strings.Cut(token[i+len("/share/"):], "?")
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		if err := sdk.RevokeShare(ctx, token); err != nil {
+			return err
+		}
+		fmt.Println("The link is revoked.")
+		return nil
+	},
+}
+
 var updateCmd = &cli.Command{
 	Name:      "update",
 	Usage:     "update an existing data model",
@@ -930,12 +1449,19 @@ var updateCmd = &cli.Command{
 			Value:    false,
 			Required: false,
 		},
+		&cli.BoolFlag{
+			Name:     "merge",
+			Usage:    "three-way merge the patch made on --commit-id with the commits made since instead of failing, --cid and --size are of the unmerged content",
+			Value:    false,
+			Required: false,
+		},
 		&cli.StringSliceFlag{
 			Name:     "tags",
 			Required: false,
 		},
 		&cli.StringFlag{
 			Name:     "rule",
+			Usage:    "the rule of the model, a CEL expression of new and old contents prefixed by cel: is checked on updates, e.g. cel:new.balance >= old.balance",
 			Value:    "",
 			Required: false,
 		},
@@ -970,6 +1496,8 @@ var updateCmd = &cli.Command{
 			Usage:    "extend information for the model",
 			Required: false,
 		},
+		flagPriority,
+		flagQueue,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -1003,7 +1531,15 @@ var updateCmd = &cli.Command{
 		duration := cctx.Int("duration")
 		replicas := cctx.Int("replica")
 		delay := cctx.Int("delay")
-		client, closer, err := getSaoClient(cctx)
+		if cctx.Bool(flagQueue.Name) && (clientPublish || cctx.Bool("force")) {
+			return types.Wrapf(types.ErrInvalidParameters, "--queue can't be used with --client-publish or --force")
+		}
+		merge := cctx.Bool("merge")
+		if merge && (clientPublish || cctx.Bool("force")) {
+			return types.Wrapf(types.ErrInvalidParameters, "--merge can't be used with --client-publish or --force")
+		}
+
+		client, closer, err := getQueueingSaoClient(cctx)
 		if err != nil {
 			return err
 		}
@@ -1020,6 +1556,26 @@ var updateCmd = &cli.Command{
 			return err
 		}
 
+		opts := saoclient.ModelOptions{
+			Tags:       cctx.StringSlice("tags"),
+			Rule:       cctx.String("rule"),
+			ExtendInfo: extendInfo,
+			Duration:   duration,
+			Replica:    replicas,
+			Timeout:    delay,
+			Merge:      merge,
+		}
+		queued, err := queueIfOffline(cctx, client, didManager, saoclient.QueuedOp{
+			Kind:         saoclient.QueuedUpdate,
+			GroupId:      groupId,
+			Keyword:      keyword,
+			BaseCommitId: commitId,
+			Options:      opts,
+		}, patch)
+		if err != nil || queued {
+			return err
+		}
+
 		gatewayAddress, err := client.GetNodeAddress(ctx)
 		if err != nil {
 			return err
@@ -1045,6 +1601,23 @@ var updateCmd = &cli.Command{
 			return err
 		}
 
+		if merge && res.Metadata.Commit != commitId {
+			sdk, err := saoclient.NewSdk(client, didManager)
+			if err != nil {
+				return err
+			}
+			sdk.GroupId = groupId
+			resp, conflicts, err := sdk.MergeWithPatch(ctx, res.Metadata.DataId, commitId, patch, opts)
+			if err != nil {
+				return err
+			}
+			for _, conflict := range conflicts {
+				fmt.Printf("conflict at %s, the value of the patch is kept.\r\n", conflict)
+			}
+			fmt.Printf("alias: %s, data id: %s, commit id: %s.\r\n", resp.Alias, resp.DataId, resp.CommitId)
+			return nil
+		}
+
 		force := cctx.Bool("force")
 
 		operation := uint32(1)
@@ -1075,6 +1648,10 @@ var updateCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
+		clientProposal.Priority, err = getPriority(cctx)
+		if err != nil {
+			return err
+		}
 
 		var orderId uint64 = 0
 		if clientPublish {
@@ -1085,7 +1662,7 @@ var updateCmd = &cli.Command{
 			orderId = resp.OrderId
 		}
 
-		resp, err := client.ModelUpdate(ctx, request, clientProposal, orderId, patch)
+		resp, err := client.UpdateWithRetry(ctx, request, clientProposal, orderId, patch)
 		if err != nil {
 			return err
 		}
@@ -1142,22 +1719,9 @@ var updatePermissionCmd = &cli.Command{
 			ReadwriteDids: cctx.StringSlice("readwrite-dids"),
 		}
 
-		proposalBytes, err := proposal.Marshal()
-		if err != nil {
-			return types.Wrap(types.ErrMarshalFailed, err)
-		}
-
-		jws, err := didManager.CreateJWS(proposalBytes)
+		request, err := saoclient.BuildPermissionProposal(didManager, proposal)
 		if err != nil {
-			return types.Wrap(types.ErrCreateJwsFailed, err)
-		}
-
-		request := &types.PermissionProposal{
-			Proposal: proposal,
-			JwsSignature: saotypes.JwsSignature{
-				Protected: jws.Signatures[0].Protected,
-				Signature: jws.Signatures[0].Signature,
-			},
+			return err
 		}
 
 		if clientPublish {
@@ -1177,6 +1741,96 @@ var updatePermissionCmd = &cli.Command{
 	},
 }
 
+var permissionHistoryCmd = &cli.Command{
+	Name:  "permission-history",
+	Usage: "list the permission updates of a data model seen by the gateway",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "data-id",
+			Usage:    "data model's dataId",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "did",
+			Usage:    "only list the updates granting or revoking the access of the did",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		dataId := cctx.String("data-id")
+		if !utils.IsDataId(dataId) {
+			return types.Wrapf(types.ErrInvalidParameters, "invalid --data-id %s", dataId)
+		}
+		did := cctx.String("did")
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		gatewayAddress, err := client.GetNodeAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		proposal := saotypes.QueryProposal{
+			Owner:   didManager.Id,
+			Keyword: dataId,
+		}
+		request, err := buildQueryRequest(ctx, didManager, proposal, client, gatewayAddress)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.ModelPermissionHistory(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Height"),
+			tablewriter.Col("Time"),
+			tablewriter.Col("Granted"),
+			tablewriter.Col("Revoked"),
+			tablewriter.Col("Readonly"),
+			tablewriter.Col("Readwrite"),
+			tablewriter.Col("Tx"),
+		)
+		for _, change := range resp.Changes {
+			if did != "" && !containsDid(change.Granted, did) && !containsDid(change.Revoked, did) {
+				continue
+			}
+			tw.Write(map[string]interface{}{
+				"Height":    change.Height,
+				"Time":      time.Unix(change.Time, 0).Format(time.RFC3339),
+				"Granted":   strings.Join(change.Granted, ","),
+				"Revoked":   strings.Join(change.Revoked, ","),
+				"Readonly":  strings.Join(change.ReadonlyDids, ","),
+				"Readwrite": strings.Join(change.ReadwriteDids, ","),
+				"Tx":        change.TxHash,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
+
+func containsDid(dids []string, did string) bool {
+	for _, d := range dids {
+		if d == did {
+			return true
+		}
+	}
+	return false
+}
+
 var patchGenCmd = &cli.Command{
 	Name:      "patch-gen",
 	Usage:     "generate data model patch",
@@ -1257,65 +1911,9 @@ var patchGenCmd = &cli.Command{
 }
 
 func buildClientProposal(_ context.Context, didManager *did.DidManager, proposal saotypes.Proposal, _ chain.ChainSvcApi) (*types.OrderStoreProposal, error) {
-	if proposal.Owner == "all" {
-		return &types.OrderStoreProposal{
-			Proposal: proposal,
-		}, nil
-	}
-
-	proposalBytes, err := proposal.Marshal()
-	if err != nil {
-		return nil, types.Wrap(types.ErrMarshalFailed, err)
-	}
-
-	jws, err := didManager.CreateJWS(proposalBytes)
-	if err != nil {
-		return nil, types.Wrap(types.ErrCreateJwsFailed, err)
-	}
-	return &types.OrderStoreProposal{
-		Proposal: proposal,
-		JwsSignature: saotypes.JwsSignature{
-			Protected: jws.Signatures[0].Protected,
-			Signature: jws.Signatures[0].Signature,
-		},
-	}, nil
+	return saoclient.BuildOrderProposal(didManager, proposal)
 }
 
 func buildQueryRequest(ctx context.Context, didManager *did.DidManager, proposal saotypes.QueryProposal, chain chain.ChainSvcApi, gatewayAddress string) (*types.MetadataProposal, error) {
-	lastHeight, err := chain.GetLastHeight(ctx)
-	if err != nil {
-		return nil, types.Wrap(types.ErrQueryHeightFailed, err)
-	}
-
-	peerInfo, err := chain.GetNodePeer(ctx, gatewayAddress)
-	if err != nil {
-		return nil, err
-	}
-
-	proposal.LastValidHeight = uint64(lastHeight + 200)
-	proposal.Gateway = peerInfo
-
-	if proposal.Owner == "all" {
-		return &types.MetadataProposal{
-			Proposal: proposal,
-		}, nil
-	}
-
-	proposalBytes, err := proposal.Marshal()
-	if err != nil {
-		return nil, types.Wrap(types.ErrMarshalFailed, err)
-	}
-
-	jws, err := didManager.CreateJWS(proposalBytes)
-	if err != nil {
-		return nil, types.Wrap(types.ErrCreateJwsFailed, err)
-	}
-
-	return &types.MetadataProposal{
-		Proposal: proposal,
-		JwsSignature: saotypes.JwsSignature{
-			Protected: jws.Signatures[0].Protected,
-			Signature: jws.Signatures[0].Signature,
-		},
-	}, nil
+	return saoclient.BuildQueryRequest(ctx, didManager, proposal, chain, gatewayAddress)
 }
@@ -0,0 +1,232 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sao-node/chain"
+	"sao-node/client"
+	"sao-node/client/saofs"
+	cliutil "sao-node/cmd"
+	"sao-node/types"
+	"sao-node/utils"
+	"syscall"
+	"time"
+
+	did "github.com/SaoNetwork/sao-did"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/urfave/cli/v2"
+)
+
+var mountCmd = &cli.Command{
+	Name:      "mount",
+	Usage:     "mount the data models as files",
+	UsageText: "saoclient mount <mountpoint>, a model is listed after it is accessed by its alias or dataId, written files are stored when closed.",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:     "duration",
+			Usage:    "how many days do you want to store the created models",
+			Value:    DEFAULT_DURATION,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "delay",
+			Usage:    "how many epochs to wait for the content to be completed storing",
+			Value:    1 * 60,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "replica",
+			Usage:    "how many copies to store",
+			Value:    DEFAULT_REPLICA,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "usage: saoclient mount <mountpoint>")
+		}
+		mountpoint := cctx.Args().First()
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		groupId := cctx.String("platform")
+		if groupId == "" {
+			groupId = client.Cfg.GroupId
+		}
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		gatewayAddress, err := client.GetNodeAddress(cctx.Context)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(cctx.Context, os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		fmt.Printf("models of %s mounted at %s, press Ctrl+C to unmount.\r\n", didManager.Id, mountpoint)
+		return saofs.Mount(ctx, mountpoint, &mountModels{
+			client:         client,
+			didManager:     didManager,
+			gatewayAddress: gatewayAddress,
+			groupId:        groupId,
+			duration:       cctx.Int("duration"),
+			replica:        cctx.Int("replica"),
+			delay:          cctx.Int("delay"),
+		})
+	},
+}
+
+// mountModels implements saofs.Models with the proposals signed by the did of the client.
+type mountModels struct {
+	client         *client.SaoClient
+	didManager     *did.DidManager
+	gatewayAddress string
+	groupId        string
+	duration       int
+	replica        int
+	delay          int
+}
+
+func (m *mountModels) queryRequest(ctx context.Context, keyword string) (*types.MetadataProposal, error) {
+	proposal := saotypes.QueryProposal{
+		Owner:   m.didManager.Id,
+		Keyword: keyword,
+		GroupId: m.groupId,
+	}
+	if !utils.IsDataId(keyword) {
+		proposal.KeywordType = 2
+	}
+	return buildQueryRequest(ctx, m.didManager, proposal, m.client, m.gatewayAddress)
+}
+
+func (m *mountModels) proposal(dataId string, alias string, commitId string, content []byte) (saotypes.Proposal, error) {
+	contentCid, err := utils.CalculateCid(content)
+	if err != nil {
+		return saotypes.Proposal{}, err
+	}
+	return saotypes.Proposal{
+		DataId:    dataId,
+		Owner:     m.didManager.Id,
+		Provider:  m.gatewayAddress,
+		GroupId:   m.groupId,
+		Duration:  uint64(time.Duration(60*60*24*m.duration) * time.Second / chain.Blocktime),
+		Replica:   int32(m.replica),
+		Timeout:   int32(m.delay),
+		Alias:     alias,
+		Cid:       contentCid.String(),
+		CommitId:  commitId,
+		Size_:     uint64(len(content)),
+		Operation: 1,
+	}, nil
+}
+
+func (m *mountModels) Load(ctx context.Context, name string) (*saofs.Model, error) {
+	request, err := m.queryRequest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.ModelLoad(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &saofs.Model{
+		DataId:   resp.DataId,
+		Alias:    resp.Alias,
+		CommitId: resp.CommitId,
+		Content:  []byte(resp.Content),
+	}, nil
+}
+
+func (m *mountModels) Create(ctx context.Context, name string, content []byte) (*saofs.Model, error) {
+	dataId := utils.GenerateDataId(m.didManager.Id + m.groupId)
+	proposal, err := m.proposal(dataId, name, dataId, content)
+	if err != nil {
+		return nil, err
+	}
+	clientProposal, err := buildClientProposal(ctx, m.didManager, proposal, m.client)
+	if err != nil {
+		return nil, err
+	}
+	request, err := m.queryRequest(ctx, dataId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.client.CreateWithRetry(ctx, request, clientProposal, 0, content)
+	if err != nil {
+		return nil, err
+	}
+	return &saofs.Model{
+		DataId:   resp.DataId,
+		Alias:    resp.Alias,
+		CommitId: dataId,
+		Content:  append([]byte(nil), content...),
+	}, nil
+}
+
+func (m *mountModels) Update(ctx context.Context, model *saofs.Model, content []byte) (*saofs.Model, error) {
+	patch, err := utils.GeneratePatch(string(model.Content), string(content))
+	if err != nil {
+		return nil, err
+	}
+	commitId := model.CommitId + "|" + utils.GenerateCommitId(m.didManager.Id+m.groupId)
+	proposal, err := m.proposal(model.DataId, model.Alias, commitId, content)
+	if err != nil {
+		return nil, err
+	}
+	clientProposal, err := buildClientProposal(ctx, m.didManager, proposal, m.client)
+	if err != nil {
+		return nil, err
+	}
+	request, err := m.queryRequest(ctx, model.DataId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.client.UpdateWithRetry(ctx, request, clientProposal, 0, []byte(patch))
+	if err != nil {
+		return nil, err
+	}
+	return &saofs.Model{
+		DataId:   resp.DataId,
+		Alias:    resp.Alias,
+		CommitId: resp.CommitId,
+		Content:  append([]byte(nil), content...),
+	}, nil
+}
+
+func (m *mountModels) Delete(ctx context.Context, model *saofs.Model) error {
+	proposal := saotypes.TerminateProposal{
+		Owner:  m.didManager.Id,
+		DataId: model.DataId,
+	}
+	proposalBytes, err := proposal.Marshal()
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	jws, err := m.didManager.CreateJWS(proposalBytes)
+	if err != nil {
+		return types.Wrap(types.ErrCreateJwsFailed, err)
+	}
+
+	_, err = m.client.ModelDelete(ctx, &types.OrderTerminateProposal{
+		Proposal: proposal,
+		JwsSignature: saotypes.JwsSignature{
+			Protected: jws.Signatures[0].Protected,
+			Signature: jws.Signatures[0].Signature,
+		},
+	}, true)
+	return err
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import (
+	"runtime"
+	"sao-node/types"
+
+	"github.com/urfave/cli/v2"
+)
+
+var mountCmd = &cli.Command{
+	Name:  "mount",
+	Usage: "mount the data models as files",
+	Action: func(cctx *cli.Context) error {
+		return types.Wrapf(types.ErrInvalidParameters, "FUSE is not supported on %s", runtime.GOOS)
+	},
+}
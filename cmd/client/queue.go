@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	saoclient "sao-node/client"
+	cliutil "sao-node/cmd"
+	"sao-node/types"
+	"time"
+
+	did "github.com/SaoNetwork/sao-did"
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+var flagQueue = &cli.BoolFlag{
+	Name:     "queue",
+	Usage:    "queue the operation in the client repo if the gateway or the chain is unreachable, it is sent by 'queue sync'",
+	Value:    false,
+	Required: false,
+}
+
+var queueCmd = &cli.Command{
+	Name:      "queue",
+	Usage:     "operations queued while the gateway or the chain was unreachable",
+	UsageText: "model create and update queue their operations with --queue when offline.",
+	Subcommands: []*cli.Command{
+		queueListCmd,
+		queueSyncCmd,
+		queueDropCmd,
+	},
+}
+
+var queueListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list the queued operations",
+	Action: func(cctx *cli.Context) error {
+		queue, err := saoclient.OpenQueue(cctx.String(FlagClientRepo))
+		if err != nil {
+			return err
+		}
+		ops, err := queue.List()
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Id"),
+			tablewriter.Col("Kind"),
+			tablewriter.Col("Keyword"),
+			tablewriter.Col("BaseCommit"),
+			tablewriter.Col("Queued"),
+			tablewriter.NewLineCol("Error"),
+		)
+		for _, op := range ops {
+			tw.Write(map[string]interface{}{
+				"Id":         op.Id,
+				"Kind":       op.Kind,
+				"Keyword":    op.Keyword,
+				"BaseCommit": op.BaseCommitId,
+				"Queued":     op.QueuedAt.Format(time.RFC3339),
+				"Error":      op.Error,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
+
+var queueSyncCmd = &cli.Command{
+	Name:      "sync",
+	Usage:     "send the queued operations of the did, oldest first",
+	UsageText: "the updates of the models committed since they were queued are reported as conflicts and kept in the queue.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:     "force",
+			Usage:    "retry the failed operations, the conflicting patches are applied to the latest commits",
+			Value:    false,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		queue, err := saoclient.OpenQueue(cctx.String(FlagClientRepo))
+		if err != nil {
+			return err
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		sdk, err := saoclient.NewSdk(client, didManager)
+		if err != nil {
+			return err
+		}
+
+		results, err := sdk.Sync(ctx, queue, cctx.Bool("force"))
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Printf("%s %s failed: %v\r\n", result.Op.Kind, result.Op.Id, result.Err)
+				continue
+			}
+			fmt.Printf("%s %s sent, data id: %s, commit id: %s\r\n", result.Op.Kind, result.Op.Id, result.DataId, result.CommitId)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d queued operations synced.\r\n", len(results))
+		return nil
+	},
+}
+
+var queueDropCmd = &cli.Command{
+	Name:      "drop",
+	Usage:     "drop queued operations",
+	ArgsUsage: "<id>...",
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() == 0 {
+			return types.Wrapf(types.ErrInvalidParameters, "no queued operation given")
+		}
+
+		queue, err := saoclient.OpenQueue(cctx.String(FlagClientRepo))
+		if err != nil {
+			return err
+		}
+		for _, id := range cctx.Args().Slice() {
+			if err := queue.Remove(id); err != nil {
+				return err
+			}
+			fmt.Printf("%s dropped.\r\n", id)
+		}
+		return nil
+	},
+}
+
+// getQueueingSaoClient returns the client of a command queueing its operation with --queue, the client is not
+// connected to the chain if the chain is unreachable.
+func getQueueingSaoClient(cctx *cli.Context) (*saoclient.SaoClient, func(), error) {
+	client, closer, err := getSaoClient(cctx)
+	if err == nil || !cctx.Bool(flagQueue.Name) {
+		return client, closer, err
+	}
+
+	fmt.Printf("the chain is unreachable: %v\r\n", err)
+	return saoclient.NewSaoClient(cctx.Context, saoclient.SaoClientOptions{
+		Repo:        cctx.String(FlagClientRepo),
+		Gateway:     cliutil.Gateway,
+		ChainAddr:   "none",
+		KeyName:     cctx.String(cliutil.FlagKeyName),
+		KeyringHome: cliutil.KeyringHome,
+	})
+}
+
+// queueIfOffline queues the operation with --queue if the gateway or the chain is unreachable, it returns whether
+// the operation is queued.
+func queueIfOffline(cctx *cli.Context, client *saoclient.SaoClient, didManager *did.DidManager, op saoclient.QueuedOp, data []byte) (bool, error) {
+	if !cctx.Bool(flagQueue.Name) {
+		return false, nil
+	}
+	err := client.Reachable(cctx.Context)
+	if err == nil {
+		return false, nil
+	}
+	fmt.Printf("the gateway or the chain is unreachable: %v\r\n", err)
+
+	queue, err := saoclient.OpenQueue(cctx.String(FlagClientRepo))
+	if err != nil {
+		return false, err
+	}
+	op.Owner = didManager.Id
+	if err := queue.Push(&op, data); err != nil {
+		return false, err
+	}
+	fmt.Printf("the %s is queued as %s, send it by: saoclient queue sync\r\n", op.Kind, op.Id)
+	return true, nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	saoclient "sao-node/client"
+	cliutil "sao-node/cmd"
+	"sao-node/node/model/schema/migration"
+	"sao-node/node/model/schema/registry"
+	"sao-node/types"
+
+	"github.com/urfave/cli/v2"
+)
+
+var schemaCmd = &cli.Command{
+	Name:      "schema",
+	Usage:     "publish and look up the json schemas of the registry",
+	UsageText: "the published schemas are referred to by name@version in the @context of the data models, such as person@1.2.0, person@1.2 or person@latest for the latest release.",
+	Subcommands: []*cli.Command{
+		schemaPublishCmd,
+		schemaListCmd,
+		schemaGetCmd,
+		schemaMigrateCmd,
+	},
+}
+
+var schemaPublishCmd = &cli.Command{
+	Name:      "publish",
+	Usage:     "publish a version of a json schema",
+	UsageText: "the first did publishing a schema owns its name, a published version can't be replaced.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "name",
+			Usage:    "name of the schema, letters, digits, '.', '_' and '-'",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "version",
+			Usage:    "semantic version of the schema, MAJOR.MINOR.PATCH",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "content",
+			Usage:    "the json schema",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "file",
+			Usage:    "path of the json schema file, instead of --content",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "duration",
+			Usage:    "how many days do you want to store the schema",
+			Value:    DEFAULT_DURATION,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "replica",
+			Usage:    "how many copies to store",
+			Value:    DEFAULT_REPLICA,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		var schema []byte
+		switch {
+		case cctx.IsSet("file"):
+			content, err := os.ReadFile(cctx.String("file"))
+			if err != nil {
+				return types.Wrap(types.ErrReadFileFailed, err)
+			}
+			schema = content
+		case cctx.IsSet("content"):
+			schema = []byte(cctx.String("content"))
+		default:
+			return types.Wrapf(types.ErrInvalidParameters, "either --content or --file is required")
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		opts := saoclient.DefaultModelOptions()
+		opts.Duration = cctx.Int("duration")
+		opts.Replica = cctx.Int("replica")
+		name := cctx.String("name")
+		dataId, err := sdk.PublishSchema(ctx, name, cctx.String("version"), schema, opts)
+		if err != nil {
+			return err
+		}
+		version, _ := registry.CanonicalVersion(cctx.String("version"))
+		fmt.Printf("schema %s@%s published, dataId: %s\r\n", name, version, dataId)
+		return nil
+	},
+}
+
+var schemaListCmd = &cli.Command{
+	Name:      "list",
+	Usage:     "list the versions of a schema, or the schemas published by a did",
+	UsageText: "without --name, the schemas published by --owner, the did of the client by default, are listed.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "name",
+			Usage:    "name of the schema",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "owner",
+			Usage:    "did publishing the schemas",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		if cctx.IsSet("name") {
+			index, owner, err := sdk.SchemaIndex(ctx, cctx.String("name"))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("schema %s owned by %s\r\n", index.Name, owner)
+			for _, version := range index.Sorted() {
+				fmt.Printf("  %-16s %s\r\n", version, index.Versions[version])
+			}
+			return nil
+		}
+
+		owner := sdk.Did()
+		if cctx.IsSet("owner") {
+			owner = cctx.String("owner")
+		}
+		names, err := sdk.ListSchemas(ctx, owner)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d schemas published by %s\r\n", len(names), owner)
+		for _, name := range names {
+			fmt.Println("  " + name)
+		}
+		return nil
+	},
+}
+
+var schemaGetCmd = &cli.Command{
+	Name:      "get",
+	Usage:     "print a published json schema",
+	ArgsUsage: "<name@version>",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		if cctx.NArg() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "the schema reference, name@version, is required")
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		version, resp, err := sdk.LoadSchema(ctx, cctx.Args().First())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Version: %s\r\n", version)
+		fmt.Printf("DataId: %s\r\n", resp.DataId)
+		fmt.Printf("CommitId: %s\r\n", resp.CommitId)
+		fmt.Println(resp.Content)
+		return nil
+	},
+}
+
+var schemaMigrateCmd = &cli.Command{
+	Name:      "migrate",
+	Usage:     "upgrade the data models of a schema version to a new version",
+	UsageText: "the models referring to --from in their @context are transformed by --patch-template or --command, checked against --to and committed, their @context then refers to --to.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "from",
+			Usage:    "name@version of the schema the models refer to",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "to",
+			Usage:    "name@version of the schema to upgrade the models to",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "patch-template",
+			Usage:    "path of the json patch template file, {{<json pointer>}} in its string values is replaced by the value in the model",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "command",
+			Usage:    "command reading a model from stdin and writing the upgraded model to stdout, such as 'node upgrade.js'",
+			Required: false,
+		},
+		&cli.StringSliceFlag{
+			Name:     "keyword",
+			Usage:    "dataId of a model to upgrade, repeat it for several models, all the models of the did on the platform by default",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "dry-run",
+			Usage:    "transform and check the models without committing them",
+			Value:    false,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "duration",
+			Usage:    "how many days do you want to store the upgraded models",
+			Value:    DEFAULT_DURATION,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "replica",
+			Usage:    "how many copies to store",
+			Value:    DEFAULT_REPLICA,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		var transform migration.Transform
+		switch {
+		case cctx.IsSet("patch-template") && cctx.IsSet("command"):
+			return types.Wrapf(types.ErrInvalidParameters, "either --patch-template or --command is allowed")
+		case cctx.IsSet("patch-template"):
+			template, err := os.ReadFile(cctx.String("patch-template"))
+			if err != nil {
+				return types.Wrap(types.ErrReadFileFailed, err)
+			}
+			transform, err = migration.PatchTemplate(template)
+			if err != nil {
+				return err
+			}
+		case cctx.IsSet("command"):
+			args := strings.Fields(cctx.String("command"))
+			if len(args) == 0 {
+				return types.Wrapf(types.ErrInvalidParameters, "empty --command")
+			}
+			transform = migration.Command(args[0], args[1:]...)
+		default:
+			// the @context is upgraded only
+			transform = func(_ context.Context, content []byte) ([]byte, error) {
+				return content, nil
+			}
+		}
+
+		sdk, closer, err := getSdk(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		opts := saoclient.DefaultModelOptions()
+		opts.Duration = cctx.Int("duration")
+		opts.Replica = cctx.Int("replica")
+		dryRun := cctx.Bool("dry-run")
+		results, err := sdk.MigrateSchema(ctx, cctx.String("from"), cctx.String("to"), transform, cctx.StringSlice("keyword"), dryRun, opts)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, result := range results {
+			switch {
+			case result.Err != nil:
+				failed++
+				fmt.Printf("  %s (%s) failed: %v\r\n", result.DataId, result.Alias, result.Err)
+			case dryRun:
+				fmt.Printf("  %s (%s) can be upgraded\r\n", result.DataId, result.Alias)
+			default:
+				fmt.Printf("  %s (%s) upgraded, commitId: %s\r\n", result.DataId, result.Alias, result.CommitId)
+			}
+		}
+		action := "upgraded"
+		if dryRun {
+			action = "can be upgraded"
+		}
+		fmt.Printf("%d of %d models referring to %s %s to %s.\r\n", len(results)-failed, len(results), cctx.String("from"), action, cctx.String("to"))
+		if failed > 0 {
+			return types.Wrapf(types.ErrTransformFailed, "%d models failed to upgrade", failed)
+		}
+		return nil
+	},
+}
+
+func getSdk(cctx *cli.Context) (*saoclient.Sdk, func(), error) {
+	client, closer, err := getSaoClient(cctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+	sdk, err := saoclient.NewSdk(client, didManager)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+	return sdk, closer, nil
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sao-node/chain"
+	"sao-node/client"
+	cliutil "sao-node/cmd"
+	"sao-node/types"
+	"sao-node/utils"
+	"sao-node/utils/car"
+	"time"
+
+	did "github.com/SaoNetwork/sao-did"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/ipfs/go-cid"
+	"github.com/urfave/cli/v2"
+)
+
+var snapshotCmd = &cli.Command{
+	Name:  "snapshot",
+	Usage: "back up and restore all the data models of a did",
+	Subcommands: []*cli.Command{
+		snapshotCreateCmd,
+		snapshotRestoreCmd,
+	},
+}
+
+var snapshotCreateCmd = &cli.Command{
+	Name:      "create",
+	Usage:     "export every data model owned by the did with all its commits into a CARv2 file",
+	UsageText: "the models of all the platforms are exported, the file can be restored by 'snapshot restore' or imported by import-car.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "path of the snapshot file",
+			Required: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+
+		gatewayAddress, err := client.GetNodeAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		metas, err := client.ListMetadata(ctx, didManager.Id)
+		if err != nil {
+			return err
+		}
+		if len(metas) == 0 {
+			return types.Wrapf(types.ErrNotFound, "no data model owned by %s", didManager.Id)
+		}
+
+		// a signed load request for every commit of the models
+		var requests []*types.MetadataProposal
+		for _, meta := range metas {
+			for _, commit := range meta.Commits {
+				commitInfo, err := types.ParseMetaCommit(commit)
+				if err != nil {
+					return types.Wrapf(types.ErrInvalidCommitInfo, "invalid commit information: %s", commit)
+				}
+				proposal := saotypes.QueryProposal{
+					Owner:    didManager.Id,
+					Keyword:  meta.DataId,
+					GroupId:  meta.GroupId,
+					CommitId: commitInfo.CommitId,
+				}
+				request, err := buildQueryRequest(ctx, didManager, proposal, client, gatewayAddress)
+				if err != nil {
+					return err
+				}
+				requests = append(requests, request)
+			}
+		}
+
+		resp, err := client.ModelExportCar(ctx, requests)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(cctx.String("output"), resp.Car, 0644)
+		if err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+
+		fmt.Printf("snapshot of %s created in %s: %d models with %d commits.\r\n", didManager.Id, cctx.String("output"), resp.Models, resp.Commits)
+		return nil
+	},
+}
+
+var snapshotRestoreCmd = &cli.Command{
+	Name:      "restore",
+	Usage:     "recreate the data models of a snapshot file and replay their commits",
+	UsageText: "each model is created with its first commit and updated with the others in order, the restored models get new data ids and commit ids.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "input",
+			Usage:    "path of the snapshot file",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:     "duration",
+			Usage:    "how many days do you want to store the data",
+			Value:    DEFAULT_DURATION,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "delay",
+			Usage:    "how many epochs to wait for the content to be completed storing",
+			Value:    1 * 60,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "replica",
+			Usage:    "how many copies to store",
+			Value:    DEFAULT_REPLICA,
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+
+		file, err := os.Open(cctx.String("input"))
+		if err != nil {
+			return types.Wrap(types.ErrOpenFileFailed, err)
+		}
+		defer file.Close()
+		owner, models, err := car.Read(file)
+		if err != nil {
+			return err
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		if owner != didManager.Id {
+			fmt.Printf("the models of %s are restored as %s.\r\n", owner, didManager.Id)
+		}
+
+		gatewayAddress, err := client.GetNodeAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		r := &restorer{
+			client:         client,
+			didManager:     didManager,
+			gatewayAddress: gatewayAddress,
+			duration:       uint64(time.Duration(60*60*24*cctx.Int("duration")) * time.Second / chain.Blocktime),
+			replica:        int32(cctx.Int("replica")),
+			delay:          int32(cctx.Int("delay")),
+		}
+
+		restored := 0
+		for _, m := range models {
+			if len(m.Commits) == 0 {
+				continue
+			}
+			if cctx.IsSet("platform") {
+				m.GroupId = cctx.String("platform")
+			}
+
+			steps, err := replayCommits(m.Commits)
+			if err != nil {
+				fmt.Printf("the history of %s can't be replayed: %v, restored with its latest commit only.\r\n", m.DataId, err)
+				latest := m.Commits[len(m.Commits)-1].Content
+				contentCid, err := utils.CalculateCid(latest)
+				if err != nil {
+					return err
+				}
+				steps = []replayStep{{Content: latest, Cid: contentCid}}
+			}
+
+			dataId, replayed, err := r.restore(ctx, m, steps)
+			if err != nil {
+				if replayed == 0 {
+					fmt.Printf("restore %s failed: %v\r\n", m.DataId, err)
+					continue
+				}
+				fmt.Printf("restore %s stopped after %d of %d commits: %v\r\n", m.DataId, replayed, len(steps), err)
+			} else {
+				fmt.Printf("%s restored as %s with %d commits.\r\n", m.DataId, dataId, replayed)
+			}
+			restored++
+		}
+
+		fmt.Printf("%d of %d models restored.\r\n", restored, len(models))
+		return nil
+	},
+}
+
+// replayStep is a commit of a model to replay, the first one creates the model with Content, the others update
+// it with Patch.
+type replayStep struct {
+	Content []byte
+	Patch   []byte
+	Cid     cid.Cid
+}
+
+// replayCommits turns the commits of a model, oldest first, into the steps recreating them. The commits which
+// don't change the content are skipped.
+func replayCommits(commits []car.Commit) ([]replayStep, error) {
+	var steps []replayStep
+	for _, commit := range commits {
+		if len(steps) == 0 {
+			contentCid, err := utils.CalculateCid(commit.Content)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, replayStep{Content: commit.Content, Cid: contentCid})
+			continue
+		}
+
+		last := steps[len(steps)-1].Content
+		patch, err := utils.GeneratePatch(string(last), string(commit.Content))
+		if err != nil {
+			return nil, err
+		}
+		// the gateway stores the content patched, not the exported one
+		content, err := utils.ApplyPatch(last, []byte(patch))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(content, last) {
+			continue
+		}
+		contentCid, err := utils.CalculateCid(content)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, replayStep{Content: content, Patch: []byte(patch), Cid: contentCid})
+	}
+	return steps, nil
+}
+
+type restorer struct {
+	client         *client.SaoClient
+	didManager     *did.DidManager
+	gatewayAddress string
+	duration       uint64
+	replica        int32
+	delay          int32
+}
+
+// restore creates the model and replays the steps on it, it returns the new data id and how many steps are
+// replayed.
+func (r *restorer) restore(ctx context.Context, m car.Model, steps []replayStep) (string, int, error) {
+	dataId := utils.GenerateDataId(r.didManager.Id + m.GroupId)
+	commitId := dataId
+	for i, step := range steps {
+		proposal := saotypes.Proposal{
+			DataId:    dataId,
+			Owner:     r.didManager.Id,
+			Provider:  r.gatewayAddress,
+			GroupId:   m.GroupId,
+			Duration:  r.duration,
+			Replica:   r.replica,
+			Timeout:   r.delay,
+			Alias:     m.Alias,
+			Cid:       step.Cid.String(),
+			CommitId:  commitId,
+			Size_:     uint64(len(step.Content)),
+			Operation: 1,
+		}
+		if i > 0 {
+			proposal.CommitId = commitId + "|" + utils.GenerateCommitId(r.didManager.Id+m.GroupId)
+		}
+		clientProposal, err := buildClientProposal(ctx, r.didManager, proposal, r.client)
+		if err != nil {
+			return dataId, i, err
+		}
+
+		queryProposal := saotypes.QueryProposal{Owner: r.didManager.Id, Keyword: dataId, GroupId: m.GroupId}
+		request, err := buildQueryRequest(ctx, r.didManager, queryProposal, r.client, r.gatewayAddress)
+		if err != nil {
+			return dataId, i, err
+		}
+
+		if i == 0 {
+			_, err = r.client.CreateWithRetry(ctx, request, clientProposal, 0, step.Content)
+			if err != nil {
+				return dataId, i, err
+			}
+			continue
+		}
+		resp, err := r.client.UpdateWithRetry(ctx, request, clientProposal, 0, step.Patch)
+		if err != nil {
+			return dataId, i, err
+		}
+		commitId = resp.CommitId
+	}
+	return dataId, len(steps), nil
+}
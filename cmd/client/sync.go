@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	saoclient "sao-node/client"
+	cliutil "sao-node/cmd"
+	"sao-node/types"
+	"syscall"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+)
+
+var syncCmd = &cli.Command{
+	Name:      "sync",
+	Usage:     "mirror the data models of the did in a local directory",
+	UsageText: "the commits made since the last sync are pulled, each model is a directory named by its dataId with a file per commit and the latest content in 'latest'.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "dir",
+			Usage:    "directory of the mirror, 'mirror' in the client repo by default",
+			Required: false,
+		},
+		&cli.StringSliceFlag{
+			Name:     "group-ids",
+			Usage:    "only mirror the models of the platforms",
+			Required: false,
+		},
+		&cli.StringSliceFlag{
+			Name:     "tags",
+			Usage:    "only mirror the models with one of the tags",
+			Required: false,
+		},
+		&cli.DurationFlag{
+			Name:     "interval",
+			Usage:    "keep pulling the new commits at the interval, the models are pulled once if 0",
+			Value:    0,
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "watch",
+			Usage:    "keep pulling the new commits as the models are updated on chain, the gateway notifies the updates over websocket",
+			Value:    false,
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "listen",
+			Usage:    "serve the mirror to the local apps on the address, e.g. 127.0.0.1:5160, GET /models lists the models, GET /models/<keyword>[/<commit id>] returns the content",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		dir := cctx.String("dir")
+		if dir == "" {
+			repo, err := homedir.Expand(cctx.String(FlagClientRepo))
+			if err != nil {
+				return types.Wrapf(types.ErrInvalidRepoPath, "path=%s, %v", cctx.String(FlagClientRepo), err)
+			}
+			dir = filepath.Join(repo, "mirror")
+		}
+		interval := cctx.Duration("interval")
+		watch := cctx.Bool("watch")
+		if cctx.IsSet("listen") && interval == 0 && !watch {
+			interval = time.Minute
+		}
+
+		client, closer, err := getSaoClient(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		didManager, _, err := cliutil.GetDidManager(cctx, client.Cfg.KeyName)
+		if err != nil {
+			return err
+		}
+		sdk, err := saoclient.NewSdk(client, didManager)
+		if err != nil {
+			return err
+		}
+		mirror, err := saoclient.OpenMirror(dir)
+		if err != nil {
+			return err
+		}
+		filter := saoclient.MirrorFilter{
+			GroupIds: cctx.StringSlice("group-ids"),
+			Tags:     cctx.StringSlice("tags"),
+		}
+
+		ctx, cancel := signal.NotifyContext(cctx.Context, os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		if cctx.IsSet("listen") {
+			server := &http.Server{
+				Addr:              cctx.String("listen"),
+				Handler:           mirror,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					fmt.Printf("serve the mirror error: %v\r\n", err)
+					cancel()
+				}
+			}()
+			defer server.Close()
+			fmt.Printf("mirror served on http://%s/models\r\n", cctx.String("listen"))
+		}
+
+		// a pull is triggered by the updates coming meanwhile, once for all of them
+		updated := make(chan struct{}, 1)
+		var lost <-chan struct{}
+		if watch {
+			sub, err := sdk.Subscribe(ctx, "", func(event types.ModelEvent) {
+				if event.Kind == types.ModelEventUpdate {
+					select {
+					case updated <- struct{}{}:
+					default:
+					}
+				}
+			})
+			if err != nil {
+				return err
+			}
+			defer sub.Close()
+			lost = sub.Done()
+		}
+
+		for {
+			pulled, err := mirror.Pull(ctx, sdk, filter)
+			if err != nil {
+				if interval == 0 && !watch {
+					return err
+				}
+				fmt.Printf("sync error: %v\r\n", err)
+			}
+			fmt.Printf("%d commits pulled to %s, %d models mirrored.\r\n", pulled, dir, len(mirror.Models()))
+			if interval == 0 && !watch {
+				return nil
+			}
+
+			var tick <-chan time.Time
+			if interval > 0 {
+				tick = time.After(interval)
+			}
+			select {
+			case <-tick:
+			case <-updated:
+			case <-lost:
+				return types.Wrapf(types.ErrSendRequestFailed, "the connection to the gateway is lost")
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	},
+}
@@ -1,6 +1,7 @@
 package cliutil
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,6 +18,7 @@ import (
 
 	saodid "github.com/SaoNetwork/sao-did"
 	saokey "github.com/SaoNetwork/sao-did/key"
+	"github.com/mitchellh/go-homedir"
 	"github.com/urfave/cli/v2"
 )
 
@@ -42,6 +44,23 @@ var FlagKeyringHome = &cli.StringFlag{
 	Destination: &KeyringHome,
 }
 
+var KeyringBackend string
+var FlagKeyringBackend = &cli.StringFlag{
+	Name:        "keyring-backend",
+	Usage:       "account keyring backend: os, file or test, overrides the repo config",
+	EnvVars:     []string{"SAO_KEYRING_BACKEND"},
+	Destination: &KeyringBackend,
+}
+
+// Signer is the key name signing the chain operations of the command, it overrides the default key.
+var Signer string
+var FlagSigner = &cli.StringFlag{
+	Name:        "signer",
+	Usage:       "key name or signing profile (gateway, payout or client) signing the chain operations, overrides the default key",
+	EnvVars:     []string{"SAO_SIGNER"},
+	Destination: &Signer,
+}
+
 var ChainAddress string
 var FlagChainAddress = &cli.StringFlag{
 	Name:        "chain-address",
@@ -73,36 +92,53 @@ func AskForPassphrase() (string, error) {
 }
 
 func GetDidManager(cctx *cli.Context, keyName string) (*saodid.DidManager, string, error) {
-	if cctx.IsSet(FlagKeyName) {
+	if Signer != "" {
+		keyName = Signer
+	} else if cctx.IsSet(FlagKeyName) {
 		keyName = cctx.String(FlagKeyName)
 	}
 
 	// repo := cctx.String("repo")
 
-	address, err := chain.GetAddress(cctx.Context, KeyringHome, keyName)
+	return GetDidManagerByKey(cctx.Context, keyName)
+}
+
+// GetDidManagerByKey returns the did manager of the key in the keyring, and the address of the key.
+func GetDidManagerByKey(ctx context.Context, keyName string) (*saodid.DidManager, string, error) {
+	address, err := chain.GetAddress(ctx, KeyringHome, keyName)
 	if err != nil {
 		return nil, "", err
 	}
 
 	payload := fmt.Sprintf("cosmos %s allows to generate did", address)
-	secret, err := chain.SignByAccount(cctx.Context, KeyringHome, keyName, []byte(payload))
+	secret, err := chain.SignByAccount(ctx, KeyringHome, keyName, []byte(payload))
 	if err != nil {
 		return nil, "", types.Wrap(types.ErrSignedFailed, err)
 	}
 
+	didManager, err := NewDidManagerWithSecret(secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return didManager, address, nil
+}
+
+// NewDidManagerWithSecret returns the did:key manager of the secp256k1 secret.
+func NewDidManagerWithSecret(secret []byte) (*saodid.DidManager, error) {
 	provider, err := saokey.NewSecp256k1Provider(secret)
 	if err != nil {
-		return nil, "", types.Wrap(types.ErrCreateProviderFailed, err)
+		return nil, types.Wrap(types.ErrCreateProviderFailed, err)
 	}
 	resolver := saokey.NewKeyResolver()
 
 	didManager := saodid.NewDidManager(provider, resolver)
 	_, err = didManager.Authenticate([]string{}, "")
 	if err != nil {
-		return nil, "", types.Wrap(types.ErrAuthenticateFailed, err)
+		return nil, types.Wrap(types.ErrAuthenticateFailed, err)
 	}
 
-	return &didManager, address, nil
+	return &didManager, nil
 }
 
 // TODO: move to makefile
@@ -196,3 +232,89 @@ func GetChainAddress(cctx *cli.Context, repoPath string, binaryName string) (str
 
 	return chainAddress, nil
 }
+
+// SetupKeyring selects the keyring backend of the chain package and resolves the signing profile given by
+// --signer, the flags take precedence over the repo config. A missing repo leaves the default test backend.
+func SetupKeyring(cctx *cli.Context, repoPath string, binaryName string) error {
+	path, err := homedir.Expand(repoPath)
+	if err != nil {
+		return types.Wrap(types.ErrInvalidRepoPath, err)
+	}
+	configPath := filepath.Join(path, "config.toml")
+
+	backend := KeyringBackend
+	if binaryName == APP_NAME_NODE {
+		c, err := utils.FromFile(configPath, config.DefaultSaoNode())
+		if err != nil {
+			return types.Wrap(types.ErrReadConfigFailed, err)
+		}
+		cfg, ok := c.(*config.Node)
+		if !ok {
+			return types.Wrapf(types.ErrDecodeConfigFailed, "invalid config %s", configPath)
+		}
+		if backend == "" {
+			backend = cfg.Chain.KeyringBackend
+		}
+		if key, ok := cfg.Keys.Profile(Signer); ok {
+			if key == "" {
+				return types.Wrapf(types.ErrInvalidParameters, "no key configured for signing profile %s", Signer)
+			}
+			Signer = key
+		}
+	} else if binaryName == APP_NAME_CLIENT {
+		c, err := utils.FromFile(configPath, saoclient.DefaultSaoClientConfig())
+		if err != nil {
+			return types.Wrap(types.ErrReadConfigFailed, err)
+		}
+		cfg, ok := c.(*saoclient.SaoClientConfig)
+		if !ok {
+			return types.Wrapf(types.ErrDecodeConfigFailed, "invalid config %s", configPath)
+		}
+		if backend == "" {
+			backend = cfg.KeyringBackend
+		}
+	} else {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid binary name %s", binaryName)
+	}
+
+	if backend == "" {
+		return nil
+	}
+	return chain.SetKeyringBackend(backend)
+}
+
+// OrderFilterFlags are the flags of GetOrderFilter.
+var OrderFilterFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "owner",
+		Usage: "list the orders of the did",
+	},
+	&cli.StringSliceFlag{
+		Name:  "state",
+		Usage: "list the orders in the states: staged, ready, complete, terminate or expired",
+	},
+	&cli.Int64Flag{
+		Name:  "from-height",
+		Usage: "list the orders stored or readied since the height",
+	},
+	&cli.Int64Flag{
+		Name:  "to-height",
+		Usage: "list the orders stored or readied until the height",
+	},
+}
+
+func GetOrderFilter(cctx *cli.Context) (types.OrderFilter, error) {
+	filter := types.OrderFilter{
+		Owner:      cctx.String("owner"),
+		FromHeight: cctx.Int64("from-height"),
+		ToHeight:   cctx.Int64("to-height"),
+	}
+	for _, name := range cctx.StringSlice("state") {
+		state, err := types.ParseOrderState(name)
+		if err != nil {
+			return types.OrderFilter{}, err
+		}
+		filter.States = append(filter.States, state)
+	}
+	return filter, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sao-node/api"
+	apiclient "sao-node/api/client"
+	cliutil "sao-node/cmd"
+	"sao-node/node"
+	"sao-node/node/repo"
+	"sao-node/types"
+
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/urfave/cli/v2"
+)
+
+// newGatewayApi connects to the gateway given by --gateway, or to the node running on the repo.
+func newGatewayApi(cctx *cli.Context) (api.SaoApi, jsonrpc.ClientCloser, error) {
+	if cliutil.Gateway != "" {
+		return apiclient.NewGatewayApi(cctx.Context, cliutil.Gateway, "DEFAULT_TOKEN")
+	}
+
+	r, err := prepareRepo(cctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newNodeApi(cctx.Context, r)
+}
+
+// newNodeApi connects to the node running on the repo, with the endpoint and token it wrote into the repo.
+func newNodeApi(ctx context.Context, r *repo.Repo) (api.SaoApi, jsonrpc.ClientCloser, error) {
+	ma, err := r.APIEndpoint()
+	if errors.Is(err, repo.ErrNoAPIEndpoint) {
+		return nil, nil, types.Wrapf(types.ErrConnectFailed, "%v, is the node running on %s?", err, r.Path)
+	} else if err != nil {
+		return nil, nil, err
+	}
+	scheme := "http"
+	serverName := ""
+	// a node serving the API over TLS records <address>/tls/sni/<name>/http
+	if _, err := ma.ValueForProtocol(multiaddr.P_TLS); err == nil {
+		scheme = "https"
+		serverName, _ = ma.ValueForProtocol(multiaddr.P_SNI)
+		ma = ma.Decapsulate(multiaddr.StringCast("/tls"))
+	}
+	_, addr, err := manet.DialArgs(ma)
+	if err != nil {
+		return nil, nil, types.Wrap(types.ErrConnectFailed, err)
+	}
+	if serverName != "" {
+		// the certificate is of the name, not of the listening address
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, nil, types.Wrap(types.ErrConnectFailed, err)
+		}
+		addr = net.JoinHostPort(serverName, port)
+	}
+
+	token, err := r.APIToken()
+	if err != nil {
+		// nodes started before the token was written into the repo
+		key, err := r.GetKeyBytes()
+		if err != nil {
+			return nil, nil, err
+		}
+		token, err = jwt.Sign(&node.JwtPayload{Allow: api.AllPermissions[:2]}, jwt.NewHS256(key))
+		if err != nil {
+			return nil, nil, types.Wrap(types.ErrSignedFailed, err)
+		}
+	}
+
+	gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, scheme+"://"+addr+"/rpc/v0", string(token))
+	if err != nil {
+		return nil, nil, types.Wrap(types.ErrCreateClientFailed, err)
+	}
+	return gatewayApi, closer, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sao-node/node/audit"
+	"sao-node/types"
+	"strconv"
+	"time"
+
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+var auditCmd = &cli.Command{
+	Name:  "audit",
+	Usage: "query the API audit log of the node",
+	Subcommands: []*cli.Command{
+		auditListCmd,
+		auditExportCmd,
+	},
+}
+
+var auditFilterFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "method",
+		Usage: "API method, e.g. ModelCreate",
+	},
+	&cli.StringFlag{
+		Name:  "did",
+		Usage: "did of the proposal owner",
+	},
+	&cli.StringFlag{
+		Name:  "data-id",
+		Usage: "data id or keyword of the model",
+	},
+	&cli.StringFlag{
+		Name:  "token",
+		Usage: "fingerprint of the API token",
+	},
+	&cli.StringFlag{
+		Name:  "since",
+		Usage: "RFC3339 time, unix seconds or a duration ago like 24h",
+	},
+	&cli.StringFlag{
+		Name:  "until",
+		Usage: "RFC3339 time, unix seconds or a duration ago like 1h",
+	},
+	&cli.BoolFlag{
+		Name:  "failed",
+		Usage: "only the failed calls",
+	},
+}
+
+var auditListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list the audited API calls",
+	Flags: append([]cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "show the latest n calls, 0 for all",
+			Value: 50,
+		},
+	}, auditFilterFlags...),
+	Action: func(cctx *cli.Context) error {
+		entries, err := queryAuditLog(cctx, cctx.Int("limit"))
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Time"),
+			tablewriter.Col("Method"),
+			tablewriter.Col("Token"),
+			tablewriter.Col("Did"),
+			tablewriter.Col("DataId"),
+			tablewriter.Col("Result"),
+		)
+		for _, e := range entries {
+			tw.Write(map[string]interface{}{
+				"Time":   time.Unix(e.Time, 0).Format(time.RFC3339),
+				"Method": e.Method,
+				"Token":  e.Token,
+				"Did":    e.Did,
+				"DataId": e.DataId,
+				"Result": e.Result,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
+
+var auditExportCmd = &cli.Command{
+	Name:  "export",
+	Usage: "export the audited API calls as json lines or csv",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:  "output",
+			Usage: "output file, stdout if not set",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "json or csv",
+			Value: "json",
+		},
+	}, auditFilterFlags...),
+	Action: func(cctx *cli.Context) error {
+		format := cctx.String("format")
+		if format != "json" && format != "csv" {
+			return types.Wrapf(types.ErrInvalidParameters, "invalid format %s", format)
+		}
+
+		entries, err := queryAuditLog(cctx, 0)
+		if err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		if cctx.IsSet("output") {
+			file, err := os.Create(cctx.String("output"))
+			if err != nil {
+				return types.Wrap(types.ErrCreateFileFailed, err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(out)
+			for _, e := range entries {
+				if err := encoder.Encode(e); err != nil {
+					return types.Wrap(types.ErrWriteFileFailed, err)
+				}
+			}
+		} else {
+			w := csv.NewWriter(out)
+			_ = w.Write([]string{"Time", "Method", "Token", "Did", "DataId", "Result"})
+			for _, e := range entries {
+				_ = w.Write([]string{time.Unix(e.Time, 0).Format(time.RFC3339), e.Method, e.Token, e.Did, e.DataId, e.Result})
+			}
+			w.Flush()
+			if err := w.Error(); err != nil {
+				return types.Wrap(types.ErrWriteFileFailed, err)
+			}
+		}
+
+		if cctx.IsSet("output") {
+			fmt.Printf("%d calls exported to %s.\n", len(entries), cctx.String("output"))
+		}
+		return nil
+	},
+}
+
+func queryAuditLog(cctx *cli.Context, limit int) ([]audit.Entry, error) {
+	r, err := prepareRepo(cctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := audit.Filter{
+		Method: cctx.String("method"),
+		Did:    cctx.String("did"),
+		DataId: cctx.String("data-id"),
+		Token:  cctx.String("token"),
+		Failed: cctx.Bool("failed"),
+		Limit:  limit,
+	}
+	if filter.Since, err = parseAuditTime(cctx.String("since")); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseAuditTime(cctx.String("until")); err != nil {
+		return nil, err
+	}
+
+	return audit.Query(r.AuditLogPath(), filter)
+}
+
+// parseAuditTime parses a RFC3339 time, unix seconds or a duration before now into unix seconds.
+func parseAuditTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d).Unix(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Unix(), nil
+	}
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return sec, nil
+	}
+	return 0, types.Wrapf(types.ErrInvalidParameters, "invalid time %s", value)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+var cacheCmd = &cli.Command{
+	Name:  "cache",
+	Usage: "inspect the model cache of the gateway",
+	Subcommands: []*cli.Command{
+		cacheStatsCmd,
+	},
+}
+
+var cacheStatsCmd = &cli.Command{
+	Name:  "stats",
+	Usage: "show the cache settings and the hit, miss and eviction counters of each account",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		gatewayApi, closer, err := newGatewayApi(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		stats, err := gatewayApi.CacheStats(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Backend:       %s\n", stats.Backend)
+		fmt.Printf("Enabled:       %v\n", stats.EnableCache)
+		fmt.Printf("Capacity:      %d models per account\n", stats.Capacity)
+		fmt.Printf("Content limit: %s\n", humanize.IBytes(uint64(stats.ContentLimit)))
+		fmt.Println()
+
+		tw := tablewriter.New(
+			tablewriter.Col("Account"),
+			tablewriter.Col("Size"),
+			tablewriter.Col("Capacity"),
+			tablewriter.Col("Hits"),
+			tablewriter.Col("Misses"),
+			tablewriter.Col("HitRate"),
+			tablewriter.Col("Evictions"),
+		)
+		for _, c := range stats.Caches {
+			size, capacity := "-", "-"
+			if c.Size >= 0 {
+				size = fmt.Sprint(c.Size)
+			}
+			if c.Capacity >= 0 {
+				capacity = fmt.Sprint(c.Capacity)
+			}
+			hitRate := "-"
+			if c.Hits+c.Misses > 0 {
+				hitRate = fmt.Sprintf("%.1f%%", float64(c.Hits)*100/float64(c.Hits+c.Misses))
+			}
+			tw.Write(map[string]interface{}{
+				"Account":   c.Account,
+				"Size":      size,
+				"Capacity":  capacity,
+				"Hits":      c.Hits,
+				"Misses":    c.Misses,
+				"HitRate":   hitRate,
+				"Evictions": c.Evictions,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
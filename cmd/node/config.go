@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sao-node/types"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var configCmd = &cli.Command{
+	Name:  "config",
+	Usage: "read and modify config.toml",
+	Subcommands: []*cli.Command{
+		configGetCmd,
+		configSetCmd,
+	},
+}
+
+var configGetCmd = &cli.Command{
+	Name:      "get",
+	Usage:     "print the value of a config field, e.g. Libp2p.ListenAddress",
+	ArgsUsage: "<key>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 1 {
+			return types.Wrapf(types.ErrInvalidParameters, "must provide the key")
+		}
+
+		r, err := prepareRepo(cctx)
+		if err != nil {
+			return err
+		}
+
+		value, err := r.ConfigValue(cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		switch v := value.(type) {
+		case time.Duration:
+			fmt.Println(v.String())
+		case []string:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return types.Wrap(types.ErrMarshalFailed, err)
+			}
+			fmt.Println(string(b))
+		default:
+			fmt.Println(v)
+		}
+		return nil
+	},
+}
+
+var configSetCmd = &cli.Command{
+	Name:      "set",
+	Usage:     "set the value of a config field, lists are given as comma separated values or a toml array",
+	ArgsUsage: "<key> <value>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 2 {
+			return types.Wrapf(types.ErrInvalidParameters, "must provide the key and the value")
+		}
+
+		r, err := prepareRepo(cctx)
+		if err != nil {
+			return err
+		}
+
+		err = r.SetConfigValue(cctx.Args().Get(0), cctx.Args().Get(1))
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("config updated, send SIGHUP or restart the node to apply it.")
+		return nil
+	},
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sao-node/api"
+	"sao-node/types"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	dashboardRecentCompletions = 8
+	dashboardPeers             = 8
+)
+
+var dashboardCmd = &cli.Command{
+	Name:  "dashboard",
+	Usage: "show live panels of the running node",
+	UsageText: "shard queue, recent completions, chain height, earnings, storage usage and peer connections " +
+		"are refreshed from the node api until ctrl-c is pressed.",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "refresh interval",
+			Value: 5 * time.Second,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Duration("interval") <= 0 {
+			return types.Wrapf(types.ErrInvalidParameters, "invalid interval %v", cctx.Duration("interval"))
+		}
+
+		ctx, cancel := signal.NotifyContext(cctx.Context, os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		apiClient, closer, err := newGatewayApi(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		// hide the cursor while redrawing, and restore it on exit
+		fmt.Print("\033[?25l")
+		defer fmt.Print("\033[?25h\n")
+
+		ticker := time.NewTicker(cctx.Duration("interval"))
+		defer ticker.Stop()
+
+		d := &dashboard{api: apiClient}
+		for {
+			screen := d.render(ctx)
+			// move to the top left and clear the screen before drawing the new frame
+			fmt.Print("\033[H\033[2J")
+			fmt.Print(screen)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	},
+}
+
+type dashboard struct {
+	api api.SaoApi
+
+	startHeight int64
+	startReward string
+}
+
+// render draws all panels into a buffer, so the screen is never left half drawn.
+func (d *dashboard) render(ctx context.Context) string {
+	var buf bytes.Buffer
+	title := color.New(color.FgCyan, color.Bold)
+	failed := color.New(color.FgRed)
+
+	title.Fprintf(&buf, "SAO node dashboard - %s\n\n", time.Now().Format(time.RFC3339))
+
+	stats, err := d.api.GetNodeStats(ctx)
+	if err != nil {
+		failed.Fprintf(&buf, "node stats unavailable: %v\n\n", err)
+	} else {
+		if d.startHeight == 0 {
+			d.startHeight = stats.ChainHeight
+			d.startReward = stats.Reward
+		}
+		title.Fprintln(&buf, "Node")
+		fmt.Fprintf(&buf, "  Address:       %s\n", stats.Address)
+		fmt.Fprintf(&buf, "  Chain height:  %d (+%d since start)\n", stats.ChainHeight, stats.ChainHeight-d.startHeight)
+		fmt.Fprintln(&buf)
+
+		title.Fprintln(&buf, "Earnings")
+		fmt.Fprintf(&buf, "  Balance:          %s\n", stats.Balance)
+		fmt.Fprintf(&buf, "  Claimable reward: %s (%s at start)\n", stats.Reward, d.startReward)
+		fmt.Fprintln(&buf)
+
+		title.Fprintln(&buf, "Storage")
+		for _, disk := range stats.Disks {
+			usage := color.New(color.FgGreen)
+			if disk.UsedPercent >= 90 {
+				usage = color.New(color.FgRed)
+			} else if disk.UsedPercent >= 75 {
+				usage = color.New(color.FgYellow)
+			}
+			fmt.Fprintf(&buf, "  %-40s ", disk.Path)
+			usage.Fprintf(&buf, "%5.1f%%\n", disk.UsedPercent)
+		}
+		staged := humanize.IBytes(uint64(stats.Staging.Bytes))
+		if stats.Staging.Quota > 0 {
+			staged += " of " + humanize.IBytes(uint64(stats.Staging.Quota))
+		}
+		fmt.Fprintf(&buf, "  Staging:          %s in %d files, %d orphans removed\n", staged, stats.Staging.Files, stats.Staging.Removed)
+		fmt.Fprintln(&buf)
+
+		title.Fprintln(&buf, "Protocols")
+		for _, p := range stats.Protocols {
+			fmt.Fprintf(&buf, "  %-40s %-8s %d streams\n", p.Protocol, p.Direction, p.Streams)
+		}
+		fmt.Fprintln(&buf)
+
+		title.Fprintln(&buf, "Sid document cache")
+		if sc := stats.SidDocument; sc.Enabled {
+			fmt.Fprintf(&buf, "  Size: %d of %d  Hits: %d  Misses: %d  Evictions: %d  Purges: %d\n",
+				sc.Size, sc.Capacity, sc.Hits, sc.Misses, sc.Evictions, sc.Purges)
+		} else {
+			fmt.Fprintln(&buf, "  disabled")
+		}
+		fmt.Fprintln(&buf)
+	}
+
+	shards, err := d.api.ShardList(ctx)
+	if err != nil {
+		failed.Fprintf(&buf, "shards unavailable: %v\n\n", err)
+	} else {
+		renderShards(&buf, title, shards)
+	}
+
+	peers, err := d.api.GetNetPeers(ctx)
+	if err != nil {
+		failed.Fprintf(&buf, "peers unavailable: %v\n", err)
+	} else {
+		title.Fprintf(&buf, "Peers (%d connections)\n", len(peers))
+		for i, p := range peers {
+			if i == dashboardPeers {
+				fmt.Fprintf(&buf, "  ... %d more\n", len(peers)-dashboardPeers)
+				break
+			}
+			addr := ""
+			if len(p.Addrs) > 0 {
+				addr = p.Addrs[0]
+			}
+			fmt.Fprintf(&buf, "  %s %s\n", p.ID, addr)
+		}
+	}
+
+	return buf.String()
+}
+
+func renderShards(buf *bytes.Buffer, title *color.Color, shards []types.ShardInfo) {
+	var queued, completed, terminated int
+	var stored uint64
+	var recent []types.ShardInfo
+	for _, shard := range shards {
+		switch shard.State {
+		case types.ShardStateComplete:
+			completed++
+			stored += shard.Size
+			recent = append(recent, shard)
+		case types.ShardStateTerminate:
+			terminated++
+		default:
+			queued++
+		}
+	}
+
+	title.Fprintln(buf, "Shard queue")
+	fmt.Fprintf(buf, "  Queued: %d  Completed: %d  Terminated: %d  Stored: %s\n\n",
+		queued, completed, terminated, humanize.IBytes(stored))
+
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].CompleteHeight > recent[j].CompleteHeight
+	})
+	if len(recent) > dashboardRecentCompletions {
+		recent = recent[:dashboardRecentCompletions]
+	}
+
+	title.Fprintln(buf, "Recent completions")
+	tw := tablewriter.New(
+		tablewriter.Col("Height"),
+		tablewriter.Col("OrderId"),
+		tablewriter.Col("DataId"),
+		tablewriter.Col("Size"),
+	)
+	for _, shard := range recent {
+		tw.Write(map[string]interface{}{
+			"Height":  shard.CompleteHeight,
+			"OrderId": shard.OrderId,
+			"DataId":  shard.DataId,
+			"Size":    humanize.IBytes(shard.Size),
+		})
+	}
+	_ = tw.Flush(buf)
+	fmt.Fprintln(buf)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"sao-node/types"
+
+	"github.com/urfave/cli/v2"
+)
+
+var keystoreCmd = &cli.Command{
+	Name:  "keystore",
+	Usage: "manage the node keystore",
+	Subcommands: []*cli.Command{
+		keystoreEncryptCmd,
+	},
+}
+
+var keystoreEncryptCmd = &cli.Command{
+	Name:  "encrypt",
+	Usage: "encrypt the plaintext keystore with a passphrase",
+	Action: func(cctx *cli.Context) error {
+		r, err := prepareRepo(cctx)
+		if err != nil {
+			return err
+		}
+
+		encrypted, err := r.KeystoreEncrypted()
+		if err != nil {
+			return err
+		}
+		if encrypted {
+			return types.Wrapf(types.ErrInvalidParameters, "keystore is already encrypted")
+		}
+
+		passphrase, err := newKeystorePassphrase()
+		if err != nil {
+			return err
+		}
+
+		err = r.EncryptKeystore(passphrase)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("keystore encrypted, keep the passphrase safe, it is needed to run the node.")
+		return nil
+	},
+}
@@ -6,11 +6,10 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"sao-node/api"
-	apiclient "sao-node/api/client"
 	"sao-node/build"
 	cliutil "sao-node/cmd"
 	"sao-node/cmd/account"
@@ -18,12 +17,12 @@ import (
 	"sao-node/node/config"
 	"sao-node/node/repo"
 	"sao-node/types"
+	"sao-node/utils/redact"
 	"strings"
 
 	"cosmossdk.io/math"
 	"github.com/common-nighthawk/go-figure"
 	"github.com/fatih/color"
-	"github.com/filecoin-project/go-jsonrpc"
 	"github.com/filecoin-project/lotus/lib/tablewriter"
 	"github.com/gbrlsnchs/jwt/v3"
 	"golang.org/x/xerrors"
@@ -34,8 +33,6 @@ import (
 	"os"
 	"sao-node/chain"
 
-	manet "github.com/multiformats/go-multiaddr/net"
-
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/urfave/cli/v2"
@@ -55,7 +52,13 @@ var FlagRepo = &cli.StringFlag{
 	Value:   FlagStorageDefaultRepo,
 }
 
-func before(_ *cli.Context) error {
+func before(cctx *cli.Context) error {
+	if err := redact.Install(); err != nil {
+		return err
+	}
+
+	_ = logging.SetLogLevel("alert", "INFO")
+	_ = logging.SetLogLevel("audit", "INFO")
 	_ = logging.SetLogLevel("cache", "INFO")
 	_ = logging.SetLogLevel("model", "INFO")
 	_ = logging.SetLogLevel("node", "INFO")
@@ -66,6 +69,8 @@ func before(_ *cli.Context) error {
 	_ = logging.SetLogLevel("transport", "INFO")
 	_ = logging.SetLogLevel("store", "INFO")
 	if cliutil.IsVeryVerbose {
+		_ = logging.SetLogLevel("alert", "DEBUG")
+		_ = logging.SetLogLevel("audit", "DEBUG")
 		_ = logging.SetLogLevel("cache", "DEBUG")
 		_ = logging.SetLogLevel("model", "DEBUG")
 		_ = logging.SetLogLevel("node", "DEBUG")
@@ -77,7 +82,7 @@ func before(_ *cli.Context) error {
 		_ = logging.SetLogLevel("store", "DEBUG")
 	}
 
-	return nil
+	return cliutil.SetupKeyring(cctx, cctx.String(FlagStorageRepo), cctx.App.Name)
 }
 
 func main() {
@@ -92,6 +97,8 @@ func main() {
 			cliutil.FlagChainAddress,
 			cliutil.FlagVeryVerbose,
 			cliutil.FlagKeyringHome,
+			cliutil.FlagKeyringBackend,
+			cliutil.FlagSigner,
 			cliutil.FlagGateway,
 		},
 		Commands: []*cli.Command{
@@ -102,10 +109,18 @@ func main() {
 			peersCmd,
 			runCmd,
 			authCmd,
+			auditCmd,
 			migrateCmd,
 			infoCmd,
+			dashboardCmd,
+			cacheCmd,
+			platformCmd,
+			reputationCmd,
 			claimCmd,
 			jobsCmd,
+			keystoreCmd,
+			repoCmd,
+			configCmd,
 			account.AccountCmd,
 			cliutil.GenerateDocCmd,
 		},
@@ -124,6 +139,7 @@ var jobsCmd = &cli.Command{
 		ordersCmd,
 		shardsCmd,
 		migrationsCmd,
+		scheduledJobsCmd,
 	},
 }
 
@@ -142,6 +158,11 @@ var initCmd = &cli.Command{
 			Value:    "/ip4/127.0.0.1/tcp/5153/",
 			Required: false,
 		},
+		&cli.BoolFlag{
+			Name:     "encrypt-keystore",
+			Usage:    fmt.Sprintf("encrypt the keystore with a passphrase, read from %s or prompted", repo.KeystorePassphraseEnv),
+			Required: false,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -154,7 +175,16 @@ var initCmd = &cli.Command{
 		repoPath := cctx.String(FlagStorageRepo)
 		creator := cctx.String("creator")
 
-		r, err := initRepo(repoPath, chainAddress)
+		passphrase := ""
+		if cctx.Bool("encrypt-keystore") {
+			var err error
+			passphrase, err = newKeystorePassphrase()
+			if err != nil {
+				return err
+			}
+		}
+
+		r, err := initRepo(repoPath, chainAddress, passphrase)
 		if err != nil {
 			return err
 		}
@@ -217,12 +247,13 @@ var initCmd = &cli.Command{
 	},
 }
 
-func initRepo(repoPath string, chainAddress string) (*repo.Repo, error) {
+func initRepo(repoPath string, chainAddress string, passphrase string) (*repo.Repo, error) {
 	// init base dir
 	r, err := repo.NewRepo(repoPath)
 	if err != nil {
 		return nil, err
 	}
+	r.SetPassphrase(passphrase)
 
 	ok, err := r.Exists()
 	if err != nil {
@@ -452,45 +483,10 @@ var peersCmd = &cli.Command{
 			return err
 		}
 
-		var apiClient api.SaoApiStruct
-
-		c, err := repo.Config()
-		if err != nil {
-			return types.Wrapf(types.ErrReadConfigFailed, "invalid config for repo, got: %T", c)
-		}
-
-		cfg, ok := c.(*config.Node)
-		if !ok {
-			return types.Wrapf(types.ErrDecodeConfigFailed, "invalid config for repo, got: %T", c)
-		}
-
-		key, err := repo.GetKeyBytes()
-		if err != nil {
-			return err
-		}
-
-		token, err := jwt.Sign(&node.JwtPayload{Allow: api.AllPermissions[:2]}, jwt.NewHS256(key))
-		if err != nil {
-			return types.Wrap(types.ErrSignedFailed, err)
-		}
-
-		headers := http.Header{}
-		headers.Add("Authorization", "Bearer "+string(token))
-
-		ma, err := multiaddr.NewMultiaddr(cfg.Api.ListenAddress)
-		if err != nil {
-			return types.Wrap(types.ErrInvalidServerAddress, err)
-		}
-		_, addr, err := manet.DialArgs(ma)
+		apiClient, closer, err := newNodeApi(ctx, repo)
 		if err != nil {
 			return err
 		}
-
-		apiAddress := "http://" + addr + "/rpc/v0"
-		closer, err := jsonrpc.NewMergeClient(ctx, apiAddress, "Sao", api.GetInternalStructs(&apiClient), headers)
-		if err != nil {
-			return types.Wrap(types.ErrCreateClientFailed, err)
-		}
 		defer closer()
 
 		peers, err := apiClient.GetNetPeers(ctx)
@@ -532,6 +528,13 @@ var peersCmd = &cli.Command{
 var runCmd = &cli.Command{
 	Name:  "run",
 	Usage: "start node",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:     "force",
+			Usage:    "take over the repo lock if its holder is no longer running",
+			Required: false,
+		},
+	},
 	Action: func(cctx *cli.Context) error {
 		myFigure := figure.NewFigure("Sao Network", "", true)
 		myFigure.Print()
@@ -545,14 +548,29 @@ var runCmd = &cli.Command{
 			return err
 		}
 
+		unlock, err := repo.Lock(cctx.Bool("force"))
+		if err != nil {
+			return err
+		}
+
+		err = repo.Migrate(ctx)
+		if err != nil {
+			unlock()
+			return err
+		}
+
 		snode, err := node.NewNode(ctx, repo, cliutil.KeyringHome)
 		if err != nil {
+			unlock()
 			return err
 		}
 
+		node.MonitorReload(ctx, snode)
+
 		finishCh := node.MonitorShutdown(
 			shutdownChan,
 			node.ShutdownHandler{Component: "storagenode", StopFunc: snode.Stop},
+			node.ShutdownHandler{Component: "repo lock", StopFunc: func(context.Context) error { return unlock() }},
 		)
 		<-finishCh
 		return nil
@@ -589,45 +607,10 @@ var infoCmd = &cli.Command{
 				return err
 			}
 
-			var apiClient api.SaoApiStruct
-
-			c, err := repo.Config()
-			if err != nil {
-				return types.Wrapf(types.ErrReadConfigFailed, "invalid config for repo, got: %T", c)
-			}
-
-			cfg, ok := c.(*config.Node)
-			if !ok {
-				return types.Wrapf(types.ErrDecodeConfigFailed, "invalid config for repo, got: %T", c)
-			}
-
-			key, err := repo.GetKeyBytes()
+			apiClient, closer, err := newNodeApi(ctx, repo)
 			if err != nil {
 				return err
 			}
-
-			token, err := jwt.Sign(&node.JwtPayload{Allow: api.AllPermissions[:2]}, jwt.NewHS256(key))
-			if err != nil {
-				return types.Wrap(types.ErrSignedFailed, err)
-			}
-
-			headers := http.Header{}
-			headers.Add("Authorization", "Bearer "+string(token))
-
-			ma, err := multiaddr.NewMultiaddr(cfg.Api.ListenAddress)
-			if err != nil {
-				return types.Wrap(types.ErrInvalidServerAddress, err)
-			}
-			_, addr, err := manet.DialArgs(ma)
-			if err != nil {
-				return types.Wrap(types.ErrConnectFailed, err)
-			}
-
-			apiAddress := "http://" + addr + "/rpc/v0"
-			closer, err := jsonrpc.NewMergeClient(ctx, apiAddress, "Sao", api.GetInternalStructs(&apiClient), headers)
-			if err != nil {
-				return types.Wrap(types.ErrCreateClientFailed, err)
-			}
 			defer closer()
 
 			creator, err = apiClient.GetNodeAddress(ctx)
@@ -646,7 +629,7 @@ var migrateCmd = &cli.Command{
 	Name: "migrate",
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
-		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+		gatewayApi, closer, err := newGatewayApi(cctx)
 		if err != nil {
 			return err
 		}
@@ -686,6 +669,10 @@ var claimCmd = &cli.Command{
 			Usage:    "node's account on sao chain",
 			Required: false,
 		},
+		&cli.StringFlag{
+			Name:  "payout",
+			Usage: "key name to transfer the claimed reward to, the payout key of the repo config by default",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
@@ -697,45 +684,10 @@ var claimCmd = &cli.Command{
 				return err
 			}
 
-			var apiClient api.SaoApiStruct
-
-			c, err := repo.Config()
-			if err != nil {
-				return types.Wrapf(types.ErrReadConfigFailed, "invalid config for repo, got: %T", c)
-			}
-
-			cfg, ok := c.(*config.Node)
-			if !ok {
-				return types.Wrapf(types.ErrDecodeConfigFailed, "invalid config for repo, got: %T", c)
-			}
-
-			key, err := repo.GetKeyBytes()
+			apiClient, closer, err := newNodeApi(ctx, repo)
 			if err != nil {
 				return err
 			}
-
-			token, err := jwt.Sign(&node.JwtPayload{Allow: api.AllPermissions[:2]}, jwt.NewHS256(key))
-			if err != nil {
-				return types.Wrap(types.ErrSignedFailed, err)
-			}
-
-			headers := http.Header{}
-			headers.Add("Authorization", "Bearer "+string(token))
-
-			ma, err := multiaddr.NewMultiaddr(cfg.Api.ListenAddress)
-			if err != nil {
-				return types.Wrapf(types.ErrInvalidServerAddress, "ListenAddress=%s", cfg.Api.ListenAddress)
-			}
-			_, addr, err := manet.DialArgs(ma)
-			if err != nil {
-				return types.Wrap(types.ErrConnectFailed, err)
-			}
-
-			apiAddress := "http://" + addr + "/rpc/v0"
-			closer, err := jsonrpc.NewMergeClient(ctx, apiAddress, "Sao", api.GetInternalStructs(&apiClient), headers)
-			if err != nil {
-				return types.Wrap(types.ErrCreateClientFailed, err)
-			}
 			defer closer()
 
 			creator, err = apiClient.GetNodeAddress(ctx)
@@ -749,21 +701,65 @@ var claimCmd = &cli.Command{
 			log.Warn(err)
 		}
 
-		chain, err := chain.NewChainSvc(ctx, chainAddress, "/websocket", cliutil.KeyringHome)
+		chainSvc, err := chain.NewChainSvc(ctx, chainAddress, "/websocket", cliutil.KeyringHome)
 		if err != nil {
 			return err
 		}
 
-		if tx, err := chain.ClaimReward(ctx, creator); err != nil {
+		payout, err := payoutKey(cctx)
+		if err != nil {
+			return err
+		}
+		reward, err := chainSvc.GetReward(ctx, creator)
+		if err != nil {
+			return err
+		}
+
+		if tx, err := chainSvc.ClaimReward(ctx, creator); err != nil {
 			return err
 		} else {
 			fmt.Println(tx)
 		}
 
+		if payout != "" && reward.IsPositive() {
+			payoutAddress, err := chain.GetAddress(ctx, cliutil.KeyringHome, payout)
+			if err != nil {
+				return err
+			}
+			if payoutAddress != creator {
+				tx, err := chainSvc.Send(ctx, creator, payoutAddress, reward.Amount.Int64())
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s has been transferred to payout key %s, txHash=%s\n", reward, payout, tx)
+			}
+		}
+
 		return nil
 	},
 }
 
+// payoutKey returns the payout key of the repo config, --payout takes precedence.
+func payoutKey(cctx *cli.Context) (string, error) {
+	if cctx.IsSet("payout") {
+		return cctx.String("payout"), nil
+	}
+
+	r, err := prepareRepo(cctx)
+	if err != nil {
+		return "", err
+	}
+	c, err := r.Config()
+	if err != nil {
+		return "", types.Wrap(types.ErrReadConfigFailed, err)
+	}
+	cfg, ok := c.(*config.Node)
+	if !ok {
+		return "", types.Wrapf(types.ErrDecodeConfigFailed, "invalid config for repo, got: %T", c)
+	}
+	return cfg.Keys.Payout, nil
+}
+
 var authCmd = &cli.Command{
 	Name:  "api-token-gen",
 	Usage: "Generate API tokens",
@@ -806,5 +802,51 @@ var authCmd = &cli.Command{
 }
 
 func prepareRepo(cctx *cli.Context) (*repo.Repo, error) {
-	return repo.PrepareRepo(cctx.String(FlagStorageRepo))
+	r, err := repo.PrepareRepo(cctx.String(FlagStorageRepo))
+	if err != nil {
+		return nil, err
+	}
+	r.SetPassphraseFunc(keystorePassphrase)
+	return r, nil
+}
+
+// keystorePassphrase unlocks an encrypted keystore, the passphrase is only asked when no env is set.
+func keystorePassphrase() (string, error) {
+	if passphrase, exists := os.LookupEnv(repo.KeystorePassphraseEnv); exists {
+		return passphrase, nil
+	}
+
+	fmt.Println("Keystore is encrypted.")
+	passphrase, err := cliutil.AskForPassphrase()
+	fmt.Println()
+	return passphrase, err
+}
+
+func newKeystorePassphrase() (string, error) {
+	if passphrase, exists := os.LookupEnv(repo.KeystorePassphraseEnv); exists {
+		if passphrase == "" {
+			return "", types.Wrapf(types.ErrInvalidPassphrase, "%s is empty", repo.KeystorePassphraseEnv)
+		}
+		return passphrase, nil
+	}
+
+	passphrase, err := cliutil.AskForPassphrase()
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", types.Wrapf(types.ErrInvalidPassphrase, "empty passphrase")
+	}
+
+	fmt.Print("Confirm ")
+	confirm, err := cliutil.AskForPassphrase()
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if confirm != passphrase {
+		return "", types.Wrapf(types.ErrInvalidPassphrase, "passphrases do not match")
+	}
+	return passphrase, nil
 }
@@ -3,8 +3,6 @@ package main
 import (
 	"fmt"
 	"os"
-	apiclient "sao-node/api/client"
-	cliutil "sao-node/cmd"
 
 	"github.com/filecoin-project/lotus/lib/tablewriter"
 	"github.com/urfave/cli/v2"
@@ -23,7 +21,7 @@ var migrateListCmd = &cli.Command{
 	Usage: "List migration jobs",
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
-		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+		gatewayApi, closer, err := newGatewayApi(cctx)
 		if err != nil {
 			return err
 		}
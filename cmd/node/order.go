@@ -3,9 +3,10 @@ package main
 import (
 	"fmt"
 	"os"
-	apiclient "sao-node/api/client"
 	cliutil "sao-node/cmd"
 	"sao-node/types"
+	"strings"
+	"time"
 
 	"github.com/filecoin-project/lotus/lib/tablewriter"
 	"github.com/urfave/cli/v2"
@@ -17,6 +18,7 @@ var ordersCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		orderStatusCmd,
 		orderListCmd,
+		orderAuditsCmd,
 		// orderFixCmd,
 	},
 }
@@ -24,15 +26,21 @@ var ordersCmd = &cli.Command{
 var orderListCmd = &cli.Command{
 	Name:  "list",
 	Usage: "List orders",
+	Flags: cliutil.OrderFilterFlags,
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
-		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+		filter, err := cliutil.GetOrderFilter(cctx)
+		if err != nil {
+			return err
+		}
+
+		gatewayApi, closer, err := newGatewayApi(cctx)
 		if err != nil {
 			return err
 		}
 		defer closer()
 
-		orders, err := gatewayApi.OrderList(ctx)
+		orders, err := gatewayApi.OrderList(ctx, filter)
 		if err != nil {
 			return err
 		}
@@ -53,12 +61,64 @@ var orderListCmd = &cli.Command{
 	},
 }
 
+var orderAuditsCmd = &cli.Command{
+	Name:  "audits",
+	Usage: "List the last availability audits of the orders",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "under-replicated",
+			Usage: "only the orders with fewer replicas available than their replica",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		gatewayApi, closer, err := newGatewayApi(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		audits, err := gatewayApi.OrderAudits(ctx, cctx.Bool("under-replicated"))
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Id"),
+			tablewriter.Col("OrderId"),
+			tablewriter.Col("Available"),
+			tablewriter.Col("Failed"),
+			tablewriter.Col("Audited"),
+		)
+		for _, audit := range audits {
+			var failed []string
+			for _, r := range audit.Results {
+				if !r.Passed {
+					failed = append(failed, r.Provider)
+				}
+			}
+			available := fmt.Sprintf("%d/%d", audit.Passed(), audit.Replica)
+			if audit.UnderReplicated {
+				available += " (under replicated)"
+			}
+			tw.Write(map[string]interface{}{
+				"Id":        audit.DataId,
+				"OrderId":   audit.OrderId,
+				"Available": available,
+				"Failed":    strings.Join(failed, ","),
+				"Audited":   time.Unix(audit.AuditedAt, 0).Format(time.RFC3339),
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
+
 var orderStatusCmd = &cli.Command{
 	Name:  "status",
 	Usage: "",
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
-		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+		gatewayApi, closer, err := newGatewayApi(cctx)
 		if err != nil {
 			return err
 		}
@@ -84,7 +144,7 @@ var orderStatusCmd = &cli.Command{
 // 	Usage: "",
 // 	Action: func(cctx *cli.Context) error {
 // 		ctx := cctx.Context
-// 		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+// 		gatewayApi, closer, err := newGatewayApi(cctx)
 // 		if err != nil {
 // 			return err
 // 		}
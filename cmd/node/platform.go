@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+var platformCmd = &cli.Command{
+	Name:  "platform",
+	Usage: "inspect the platforms sharing the gateway",
+	Subcommands: []*cli.Command{
+		platformUsageCmd,
+	},
+}
+
+var platformUsageCmd = &cli.Command{
+	Name:  "usage",
+	Usage: "show the models, bytes and throttled requests of each platform against its quotas",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		gatewayApi, closer, err := newGatewayApi(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		usages, err := gatewayApi.PlatformUsage(ctx)
+		if err != nil {
+			return err
+		}
+		if len(usages) == 0 {
+			fmt.Println("no platforms configured, see Gateway.Platforms")
+			return nil
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("GroupId"),
+			tablewriter.Col("Models"),
+			tablewriter.Col("Bytes"),
+			tablewriter.Col("Rate"),
+			tablewriter.Col("Throttled"),
+			tablewriter.Col("Cache"),
+			tablewriter.Col("Staging"),
+		)
+		for _, u := range usages {
+			models := fmt.Sprint(u.Models)
+			if u.MaxModels > 0 {
+				models += fmt.Sprintf(" of %d", u.MaxModels)
+			}
+			bytes := humanize.IBytes(uint64(u.Bytes))
+			if u.MaxBytes > 0 {
+				bytes += " of " + humanize.IBytes(uint64(u.MaxBytes))
+			}
+			rate := "-"
+			if u.RequestRate > 0 {
+				rate = fmt.Sprintf("%v/s", u.RequestRate)
+			}
+			cache := "shared"
+			if u.IsolateCache {
+				cache = "isolated"
+			}
+			staged := "shared"
+			if u.Staging != nil {
+				staged = humanize.IBytes(uint64(u.Staging.Bytes))
+				if u.Staging.Quota > 0 {
+					staged += " of " + humanize.IBytes(uint64(u.Staging.Quota))
+				}
+				staged += " under " + u.Staging.Path
+			}
+			tw.Write(map[string]interface{}{
+				"GroupId":   u.GroupId,
+				"Models":    models,
+				"Bytes":     bytes,
+				"Rate":      rate,
+				"Throttled": u.Throttled,
+				"Cache":     cache,
+				"Staging":   staged,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sao-node/node/repo"
+
+	"github.com/urfave/cli/v2"
+)
+
+var repoCmd = &cli.Command{
+	Name:  "repo",
+	Usage: "manage the node repo",
+	Subcommands: []*cli.Command{
+		repoVersionCmd,
+		repoMigrateCmd,
+	},
+}
+
+var repoVersionCmd = &cli.Command{
+	Name:  "version",
+	Usage: "show the repo format version",
+	Action: func(cctx *cli.Context) error {
+		r, err := prepareRepo(cctx)
+		if err != nil {
+			return err
+		}
+
+		version, err := r.Version()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("repo version: %d, supported version: %d\n", version, repo.RepoVersion)
+		return nil
+	},
+}
+
+var repoMigrateCmd = &cli.Command{
+	Name:  "migrate",
+	Usage: "migrate the repo to the format of this build, it also runs when the node starts",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:     "force",
+			Usage:    "take over the repo lock if its holder is no longer running",
+			Required: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		r, err := prepareRepo(cctx)
+		if err != nil {
+			return err
+		}
+
+		// a running node has the datastores open, they must not be migrated under it
+		unlock, err := r.Lock(cctx.Bool("force"))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		need, err := r.NeedMigrate()
+		if err != nil {
+			return err
+		}
+		if !need {
+			fmt.Println("repo is up to date.")
+			return nil
+		}
+
+		err = r.Migrate(cctx.Context)
+		if err != nil {
+			r.Close()
+			return err
+		}
+		err = r.Close()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("repo migrated to version %d.\n", repo.RepoVersion)
+		return nil
+	},
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+var reputationCmd = &cli.Command{
+	Name:  "reputation",
+	Usage: "show the completion, load, audit and migration counters of the storage providers seen by this node",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		gatewayApi, closer, err := newGatewayApi(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		providers, err := gatewayApi.ProviderReputation(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Provider"),
+			tablewriter.Col("Assigned"),
+			tablewriter.Col("Completed"),
+			tablewriter.Col("Timeouts"),
+			tablewriter.Col("AvgComplete"),
+			tablewriter.Col("Loads"),
+			tablewriter.Col("Audits"),
+			tablewriter.Col("Migrations"),
+			tablewriter.Col("FailureRate"),
+			tablewriter.Col("Updated"),
+		)
+		for _, p := range providers {
+			tw.Write(map[string]interface{}{
+				"Provider":    p.Provider,
+				"Assigned":    fmt.Sprintf("%d (%d failed)", p.Assigned, p.AssignFailures),
+				"Completed":   p.Completed,
+				"Timeouts":    p.Timeouts,
+				"AvgComplete": p.AvgCompleteLatency.Round(time.Second),
+				"Loads":       fmt.Sprintf("%d (%d failed)", p.Loads, p.LoadFailures),
+				"Audits":      fmt.Sprintf("%d (%d failed)", p.Audits, p.AuditFailures),
+				"Migrations":  fmt.Sprintf("%d (%d failed, avg %s)", p.Migrations, p.MigrationFailures, p.AvgMigrationLatency.Round(time.Second)),
+				"FailureRate": fmt.Sprintf("%.1f%%", p.FailureRate*100),
+				"Updated":     time.Unix(p.UpdatedAt, 0).Format(time.RFC3339),
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
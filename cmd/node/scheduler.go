@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/filecoin-project/lotus/lib/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+var scheduledJobsCmd = &cli.Command{
+	Name:  "scheduled",
+	Usage: "list the periodic jobs of the node with their schedules, next run times and last results",
+	Action: func(cctx *cli.Context) error {
+		ctx := cctx.Context
+		gatewayApi, closer, err := newGatewayApi(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		jobs, err := gatewayApi.JobList(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Name"),
+			tablewriter.Col("Schedule"),
+			tablewriter.Col("NextRun"),
+			tablewriter.Col("LastRun"),
+			tablewriter.Col("Duration"),
+			tablewriter.Col("Runs"),
+			tablewriter.Col("Result"),
+		)
+		for _, j := range jobs {
+			schedule, next := j.Schedule, "-"
+			if !j.Enabled {
+				schedule += " (disabled)"
+			} else if j.Running {
+				next = "running"
+			} else if j.NextRun > 0 {
+				next = time.Unix(j.NextRun, 0).Format(time.RFC3339)
+			}
+			last, result := "-", j.LastResult
+			if j.LastRun > 0 {
+				last = time.Unix(j.LastRun, 0).Format(time.RFC3339)
+			}
+			if j.LastError != "" {
+				result = "error: " + j.LastError
+			}
+			tw.Write(map[string]interface{}{
+				"Name":     j.Name,
+				"Schedule": schedule,
+				"NextRun":  next,
+				"LastRun":  last,
+				"Duration": j.LastDuration.Round(time.Millisecond),
+				"Runs":     fmt.Sprintf("%d (%d failed)", j.Runs, j.Failures),
+				"Result":   result,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
@@ -3,8 +3,6 @@ package main
 import (
 	"fmt"
 	"os"
-	apiclient "sao-node/api/client"
-	cliutil "sao-node/cmd"
 
 	"github.com/filecoin-project/lotus/lib/tablewriter"
 	"github.com/ipfs/go-cid"
@@ -44,7 +42,7 @@ var shardStatusCmd = &cli.Command{
 			return err
 		}
 
-		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+		gatewayApi, closer, err := newGatewayApi(cctx)
 		if err != nil {
 			return err
 		}
@@ -66,7 +64,7 @@ var shardListCmd = &cli.Command{
 	Usage: "List shards",
 	Action: func(cctx *cli.Context) error {
 		ctx := cctx.Context
-		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+		gatewayApi, closer, err := newGatewayApi(cctx)
 		if err != nil {
 			return err
 		}
@@ -115,7 +113,7 @@ var shardListCmd = &cli.Command{
 // 			return err
 // 		}
 
-// 		gatewayApi, closer, err := apiclient.NewGatewayApi(ctx, cliutil.Gateway, "DEFAULT_TOKEN")
+// 		gatewayApi, closer, err := newGatewayApi(cctx)
 // 		if err != nil {
 // 			return err
 // 		}
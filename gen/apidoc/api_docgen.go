@@ -14,6 +14,7 @@ import (
 	"sao-node/types"
 	"sort"
 	"strings"
+	"time"
 	"unicode"
 
 	saotypes "github.com/SaoNetwork/sao/x/sao/types"
@@ -260,6 +261,12 @@ func init() {
 		Commits: []string{"c2b37317-9612-41fe-8260-7c8aea0dbd07711196", "85de5f5e-0cfb-4e0c-abe7-bf93aec087f3712565"},
 	})
 
+	addExample(apitypes.ExportCarResp{
+		Models:  1,
+		Commits: 2,
+		Car:     []byte("car file"),
+	})
+
 	addExample(types.PeerInfo{
 		ID:    peer.ID("12D3KooWSsWdkkKzvHSV6cc8ET6eyiDoHkYeW9GwF9RuQXiWF3cS"),
 		Addrs: []string{"/ip4/127.0.0.1/tcp/26660", "/ip4/172.16.0.11/tcp/26660"},
@@ -286,6 +293,18 @@ func init() {
 	})
 
 	addExample(apitypes.UpdatePermissionResp{DataId: "4821b0f9-736c-4d48-95b7-4f80cd432781"})
+	addExample(apitypes.PermissionHistoryResp{
+		DataId: "4821b0f9-736c-4d48-95b7-4f80cd432781",
+		Changes: []apitypes.PermissionChange{{
+			Height:        1032,
+			TxHash:        "6F9C2A1C8E5B4D7A3F0E1B2C3D4E5F60718293A4B5C6D7E8F90A1B2C3D4E5F6A",
+			Time:          1675069560,
+			ReadonlyDids:  []string{"did:key:zQ3shpp99D7y2z3B2Qq6yGpWcTrxLHHnawrdHDXhVFjhE8x6h"},
+			ReadwriteDids: []string{},
+			Granted:       []string{"did:key:zQ3shpp99D7y2z3B2Qq6yGpWcTrxLHHnawrdHDXhVFjhE8x6h"},
+			Revoked:       []string{"did:key:zQ3shpp99D7y2z3B2Qq6yGpWcTrxLHHnawrdHDXhVFjhE8x66"},
+		}},
+	})
 
 	addExample(saotypes.RenewProposal{
 		Owner:    "did:key:zQ3shuvXqfLLqCnkGhhyVGLCq29tunKTFeH67ekd3Tyr2eZXX",
@@ -307,6 +326,26 @@ func init() {
 		State:            types.MigrateStateComplete,
 	}})
 
+	addExample([]types.AuditInfo{{
+		DataId:  "4821b0f9-736c-4d48-95b7-4f80cd432781",
+		OrderId: 12,
+		Replica: 2,
+		Results: []types.AuditResult{
+			{
+				Provider: "sao1qysx2gnm6xkdvs0a7nxpxmsye8qacxa6ll6gfl",
+				Cid:      "bafkreide7eax3pd3qsbolguprfta7thinb4wmbvyh2kestrdeiydg77tsq",
+				Passed:   true,
+			},
+			{
+				Provider: "sao1w0x8jd3e8cr9r3cwx9ccnuetz6jf5jvlsgk7gy",
+				Cid:      "bafkreide7eax3pd3qsbolguprfta7thinb4wmbvyh2kestrdeiydg77tsq",
+				Reason:   "transport audit request error: failed to dial",
+			},
+		},
+		UnderReplicated: true,
+		AuditedAt:       1669970712,
+	}})
+
 	addExample(apitypes.MigrateResp{
 		TxHash: "",
 		Results: map[string]string{
@@ -323,6 +362,12 @@ func init() {
 		State:   types.OrderStateStaged,
 		LastErr: "",
 	})
+	addExample(types.OrderFilter{
+		Owner:      "did:sid:67a2be7315740823ebb6a27e2cfd7825fc02102a942235dd2589af47a2dafba4",
+		States:     []types.OrderState{types.OrderStateReady},
+		FromHeight: 1,
+		ToHeight:   100,
+	})
 
 	addExample(types.ShardInfo{
 		OrderId:        1,
@@ -338,6 +383,110 @@ func init() {
 		State:          types.ShardStateTxSent,
 		LastErr:        "",
 	})
+
+	addExample(apitypes.NodeStatsResp{
+		Address:     "sao1qg3k5qq6vkdwfy8mgmndysdcg4xjhz2lmgfkpy",
+		ChainHeight: 123456,
+		Balance:     "1000000sao",
+		Reward:      "2500sao",
+		Disks: []apitypes.DiskUsage{{
+			Path:        "~/.sao-node",
+			UsedPercent: 42.5,
+		}},
+		Staging: apitypes.StagingUsage{
+			Path:  "/home/sao/.sao-node/staging",
+			Bytes: 1048576,
+			Files: 3,
+			Quota: 34359738368,
+			Owners: []apitypes.StagingOwnerUsage{{
+				Owner: "did:key:zQ3shk8GH7ZPDBSZSJMTVmPkGSM3vqLPvwNi1WYKGa7VkCNmZ",
+				Bytes: 1048576,
+			}},
+			Removed:   2,
+			ScannedAt: 1672531200,
+		},
+	})
+
+	addExample(apitypes.CacheStatsResp{
+		Backend:      "lru",
+		EnableCache:  true,
+		Capacity:     1000,
+		ContentLimit: 2097152,
+		Caches: []apitypes.CacheStats{{
+			Account:   "did:key:zQ3shkcsgb8vDZbZ2pgvPbHsUNB4HHWXYnDUFfR3hVEGSkXm8",
+			Size:      120,
+			Capacity:  1000,
+			Hits:      3400,
+			Misses:    560,
+			Evictions: 12,
+		}},
+	})
+
+	addExample([]apitypes.PlatformUsage{{
+		GroupId:      "30293f0f-3e0f-4b3c-aff1-890a2fdf063b",
+		Models:       1200,
+		MaxModels:    10000,
+		Bytes:        52428800,
+		MaxBytes:     1073741824,
+		RequestRate:  20,
+		Throttled:    35,
+		IsolateCache: true,
+		Staging: &apitypes.StagingUsage{
+			Path:      "/data/staging/app1",
+			Bytes:     1048576,
+			Files:     2,
+			Quota:     8589934592,
+			ScannedAt: 1672531200,
+		},
+	}})
+
+	addExample(time.Duration(90 * time.Second))
+	addExample([]apitypes.ProviderReputation{{
+		Provider:            "sao1qysx2gnm6xkdvs0a7nxpxmsye8qacxa6ll6gfl",
+		Assigned:            120,
+		AssignFailures:      2,
+		Completed:           115,
+		Timeouts:            3,
+		AvgCompleteLatency:  90 * time.Second,
+		Loads:               800,
+		LoadFailures:        4,
+		Audits:              12,
+		AuditFailures:       1,
+		Migrations:          5,
+		MigrationFailures:   0,
+		AvgMigrationLatency: 40 * time.Second,
+		FailureRate:         0.0107,
+		UpdatedAt:           1669970712,
+	}})
+	addExample([]apitypes.JobInfo{{
+		Name:         "gc",
+		Enabled:      true,
+		Schedule:     "0 3 * * *",
+		NextRun:      1670036400,
+		LastRun:      1669950000,
+		LastDuration: 90 * time.Second,
+		LastResult:   "2 shards released",
+		Runs:         7,
+	}})
+	addExample(types.ProgressEvent{
+		Cid:     "bafkreide7eax3xxsqmcfkmlvjtpvxo3ku2jkdo2aqhxqx2d2cpbdytcmvm",
+		Phase:   types.ProgressPhaseStaging,
+		Percent: 50,
+		Bytes:   2097152,
+		Total:   4194304,
+		Chunk:   2,
+		Chunks:  4,
+		Time:    1669970712,
+	})
+	addExample(types.ModelEventOrder)
+	addExample(types.ModelEvent{
+		Kind:    types.ModelEventOrder,
+		DataId:  "c2b37317-9612-41fe-8260-7c8aea0dbd07",
+		Owner:   "did:key:zQ3shuvXqfLLqCnkGhhyVGLCq29tunKTFeH67ekd3Tyr2eZXX",
+		OrderId: 12,
+		State:   types.OrderStateComplete,
+		Time:    1669970712,
+	})
 }
 
 func ExampleValue(method string, t, parent reflect.Type) interface{} {
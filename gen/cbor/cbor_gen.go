@@ -23,6 +23,11 @@ func main() {
 		types.MigrateKey{},
 		types.MigrateInfo{},
 		types.MigrateIndex{},
+		// complete dedup
+		types.CompleteRecord{},
+		// audit result
+		types.AuditInfo{},
+		types.AuditResult{},
 
 		types.QueryProposal{},
 		types.RelayProposal{},
@@ -37,7 +42,10 @@ func main() {
 		types.ShardLoadResp{},
 		types.ShardMigrateReq{},
 		types.ShardMigrateResp{},
+		types.ShardMigrateChunkReq{},
+		types.ShardMigrateChunkResp{},
 		types.ShardPingPong{},
+		types.RequestEnvelope{},
 	)
 	if err != nil {
 		fmt.Println(err)
@@ -0,0 +1,417 @@
+// Package modelgen generates the Go types of the data models of a JSON schema, draft 7, with the helpers
+// validating them and creating, loading and updating them through the client Sdk.
+package modelgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sao-node/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Options of the code generated.
+type Options struct {
+	Package string
+	// name of the type of the root object of the schema
+	Type string
+	// data id of the schema model, set as the @context of the models so the gateway validates them as well
+	SchemaDataId string
+}
+
+type schemaNode struct {
+	Type        interface{}            `json:"type"`
+	Description string                 `json:"description"`
+	Properties  map[string]*schemaNode `json:"properties"`
+	Required    []string               `json:"required"`
+	// a schema or a list of schemas, the lists are not typed
+	Items       json.RawMessage        `json:"items"`
+	Ref         string                 `json:"$ref"`
+	Definitions map[string]*schemaNode `json:"definitions"`
+	Defs        map[string]*schemaNode `json:"$defs"`
+}
+
+type goField struct {
+	Name        string
+	Type        string
+	Tag         string
+	Description string
+}
+
+type goType struct {
+	Name        string
+	Description string
+	Fields      []goField
+	// the type named if not a struct
+	Alias string
+}
+
+// the names of the methods generated, the fields can't have them
+var reservedNames = map[string]bool{
+	"Context":  true,
+	"Validate": true,
+	"Marshal":  true,
+	"Create":   true,
+	"Update":   true,
+}
+
+type generator struct {
+	types []*goType
+	names map[string]bool
+	// the type names of the definitions by ref
+	refs map[string]string
+}
+
+// Generate returns the Go source of the types of the schema, the root of the schema must be an object.
+func Generate(opts Options, schema []byte) ([]byte, error) {
+	if opts.Package == "" || !isIdentifier(opts.Type) {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid package %s or type %s", opts.Package, opts.Type)
+	}
+
+	var root schemaNode
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, types.Wrapf(types.ErrInvalidSchema, "%v", err)
+	}
+	if len(root.Properties) == 0 {
+		return nil, types.Wrapf(types.ErrInvalidSchema, "the root of the schema is not an object with properties")
+	}
+
+	g := &generator{
+		names: map[string]bool{opts.Type: true},
+		refs:  make(map[string]string),
+	}
+	// the definitions are named before any type refers to them
+	for _, defs := range []struct {
+		path  string
+		nodes map[string]*schemaNode
+	}{{"#/definitions/", root.Definitions}, {"#/$defs/", root.Defs}} {
+		for _, name := range sortedKeys(defs.nodes) {
+			g.refs[defs.path+name] = g.name(camel(name))
+		}
+	}
+	for _, defs := range []struct {
+		path  string
+		nodes map[string]*schemaNode
+	}{{"#/definitions/", root.Definitions}, {"#/$defs/", root.Defs}} {
+		for _, name := range sortedKeys(defs.nodes) {
+			if err := g.define(g.refs[defs.path+name], defs.nodes[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rootType, err := g.structOf(opts.Type, &root)
+	if err != nil {
+		return nil, err
+	}
+	rootType.Fields = append([]goField{{
+		Name:        "Context",
+		Type:        "string",
+		Tag:         "`json:\"@context,omitempty\"`",
+		Description: "the data id of the schema, the gateway validates the model against it",
+	}}, rootType.Fields...)
+	// the root type first
+	g.types = append([]*goType{rootType}, g.types...)
+
+	compact := new(bytes.Buffer)
+	if err := json.Compact(compact, schema); err != nil {
+		return nil, types.Wrapf(types.ErrInvalidSchema, "%v", err)
+	}
+
+	var src bytes.Buffer
+	err = codeTemplate.Execute(&src, map[string]interface{}{
+		"Package":      opts.Package,
+		"Type":         opts.Type,
+		"Var":          lowerFirst(opts.Type),
+		"SchemaDataId": strconv.Quote(opts.SchemaDataId),
+		"Schema":       strconv.Quote(compact.String()),
+		"Types":        g.types,
+	})
+	if err != nil {
+		return nil, types.Wrap(types.ErrCompileFaild, err)
+	}
+	code, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, types.Wrapf(types.ErrCompileFaild, "format the generated code: %v", err)
+	}
+	return code, nil
+}
+
+// define adds the type of the definition node named name.
+func (g *generator) define(name string, node *schemaNode) error {
+	if isObject(node) {
+		t, err := g.structOf(name, node)
+		if err != nil {
+			return err
+		}
+		g.types = append(g.types, t)
+		return nil
+	}
+
+	typ, _, err := g.typeOf(name, node)
+	if err != nil {
+		return err
+	}
+	g.types = append(g.types, &goType{Name: name, Description: node.Description, Alias: typ})
+	return nil
+}
+
+func (g *generator) structOf(name string, node *schemaNode) (*goType, error) {
+	t := &goType{Name: name, Description: node.Description}
+	required := make(map[string]bool)
+	for _, r := range node.Required {
+		required[r] = true
+	}
+
+	fieldNames := make(map[string]bool)
+	for _, prop := range sortedKeys(node.Properties) {
+		if prop == "@context" {
+			continue
+		}
+		fieldName := camel(prop)
+		if reservedNames[fieldName] {
+			fieldName += "Field"
+		}
+		for i := 2; fieldNames[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", camel(prop), i)
+		}
+		fieldNames[fieldName] = true
+
+		typ, nullable, err := g.typeOf(name+fieldName, node.Properties[prop])
+		if err != nil {
+			return nil, err
+		}
+		tag := prop
+		if !required[prop] || nullable {
+			tag += ",omitempty"
+			if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "interface{}" {
+				typ = "*" + typ
+			}
+		}
+		t.Fields = append(t.Fields, goField{
+			Name:        fieldName,
+			Type:        typ,
+			Tag:         "`json:" + strconv.Quote(tag) + "`",
+			Description: node.Properties[prop].Description,
+		})
+	}
+	return t, nil
+}
+
+// typeOf returns the Go type of the node, the structs of the objects are named by hint. nullable is set if the
+// node allows null.
+func (g *generator) typeOf(hint string, node *schemaNode) (string, bool, error) {
+	if node.Ref != "" {
+		if name, exists := g.refs[node.Ref]; exists {
+			return name, false, nil
+		}
+		return "interface{}", false, nil
+	}
+
+	var nodeTypes []string
+	nullable := false
+	switch t := node.Type.(type) {
+	case string:
+		nodeTypes = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				if s == "null" {
+					nullable = true
+				} else {
+					nodeTypes = append(nodeTypes, s)
+				}
+			}
+		}
+	}
+	if len(nodeTypes) == 0 && len(node.Properties) > 0 {
+		nodeTypes = []string{"object"}
+	}
+	if len(nodeTypes) != 1 {
+		return "interface{}", nullable, nil
+	}
+
+	switch nodeTypes[0] {
+	case "string":
+		return "string", nullable, nil
+	case "integer":
+		return "int64", nullable, nil
+	case "number":
+		return "float64", nullable, nil
+	case "boolean":
+		return "bool", nullable, nil
+	case "object":
+		if len(node.Properties) == 0 {
+			return "map[string]interface{}", nullable, nil
+		}
+		name := g.name(hint)
+		t, err := g.structOf(name, node)
+		if err != nil {
+			return "", false, err
+		}
+		g.types = append(g.types, t)
+		return name, nullable, nil
+	case "array":
+		var items schemaNode
+		if len(node.Items) == 0 || json.Unmarshal(node.Items, &items) != nil {
+			return "[]interface{}", nullable, nil
+		}
+		typ, _, err := g.typeOf(hint+"Item", &items)
+		if err != nil {
+			return "", false, err
+		}
+		return "[]" + typ, nullable, nil
+	}
+	return "interface{}", nullable, nil
+}
+
+func isObject(node *schemaNode) bool {
+	if node.Ref != "" || len(node.Properties) == 0 {
+		return false
+	}
+	t, ok := node.Type.(string)
+	return !ok || t == "object"
+}
+
+// name returns an unused type name made of base.
+func (g *generator) name(base string) string {
+	name := base
+	for i := 2; g.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.names[name] = true
+	return name
+}
+
+// camel turns a property name into an exported Go identifier.
+func camel(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func isIdentifier(s string) bool {
+	return s != "" && camel(s) == s
+}
+
+func sortedKeys(m map[string]*schemaNode) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var codeTemplate = template.Must(template.New("model").Parse(`// Code generated by saoclient gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	apitypes "sao-node/api/types"
+	"sao-node/client"
+	"sao-node/node/model/schema/validator"
+	"sync"
+)
+
+// {{.Type}}SchemaDataId is the data id of the schema model of {{.Type}}.
+const {{.Type}}SchemaDataId = {{.SchemaDataId}}
+
+// {{.Type}}Schema is the JSON schema of {{.Type}}.
+const {{.Type}}Schema = {{.Schema}}
+{{range .Types}}
+{{if .Description}}// {{.Name}}: {{.Description}}
+{{end}}{{if .Alias}}type {{.Name}} {{.Alias}}
+{{else}}type {{.Name}} struct {
+{{range .Fields}}{{if .Description}}	// {{.Description}}
+{{end}}	{{.Name}} {{.Type}} {{.Tag}}
+{{end}}}
+{{end}}{{end}}
+var {{.Var}}Validator struct {
+	once      sync.Once
+	validator *validator.Validator
+	err       error
+}
+
+// Validate checks the model against the schema.
+func (m *{{.Type}}) Validate() error {
+	{{.Var}}Validator.once.Do(func() {
+		{{.Var}}Validator.validator, {{.Var}}Validator.err = validator.NewDataModelValidator("{{.Type}}", {{.Type}}Schema, "")
+	})
+	if {{.Var}}Validator.err != nil {
+		return {{.Var}}Validator.err
+	}
+	return {{.Var}}Validator.validator.Validate(m)
+}
+
+// Marshal validates the model and returns its content.
+func (m *{{.Type}}) Marshal() ([]byte, error) {
+	m.Context = {{.Type}}SchemaDataId
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// Unmarshal{{.Type}} parses the content of a model.
+func Unmarshal{{.Type}}(content []byte) (*{{.Type}}, error) {
+	var m {{.Type}}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Create creates the model.
+func (m *{{.Type}}) Create(ctx context.Context, sdk *client.Sdk, opts client.ModelOptions) (apitypes.CreateResp, error) {
+	content, err := m.Marshal()
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	return sdk.CreateJSON(ctx, content, opts)
+}
+
+// Update commits the model as the new content of the model of keyword, its data id or alias.
+func (m *{{.Type}}) Update(ctx context.Context, sdk *client.Sdk, keyword string, opts client.ModelOptions) (apitypes.UpdateResp, error) {
+	content, err := m.Marshal()
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	return sdk.UpdateJSON(ctx, keyword, content, opts)
+}
+
+// Load{{.Type}} loads the latest commit of the model of keyword, its data id or alias.
+func Load{{.Type}}(ctx context.Context, sdk *client.Sdk, keyword string) (*{{.Type}}, error) {
+	resp, err := sdk.LoadLatest(ctx, keyword)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal{{.Type}}([]byte(resp.Content))
+}
+`))
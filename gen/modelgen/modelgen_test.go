@@ -0,0 +1,68 @@
+package modelgen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const personSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"description": "a person",
+	"properties": {
+		"@context": {"type": "string"},
+		"name": {"type": "string", "description": "full name"},
+		"age": {"type": "integer"},
+		"score": {"type": ["number", "null"]},
+		"address": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}, "zip-code": {"type": "string"}},
+			"required": ["city"]
+		},
+		"friends": {"type": "array", "items": {"$ref": "#/definitions/friend"}},
+		"labels": {"type": "array", "items": {"type": "string"}},
+		"extra": {"type": "object"},
+		"update": {"type": "boolean"}
+	},
+	"required": ["name", "age", "address"],
+	"definitions": {
+		"friend": {"type": "object", "properties": {"did": {"type": "string"}}}
+	}
+}`
+
+func TestGenerate(t *testing.T) {
+	code, err := Generate(Options{Package: "models", Type: "Person", SchemaDataId: "schema-id"}, []byte(personSchema))
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "person.go", code, parser.AllErrors)
+	require.NoError(t, err, string(code))
+
+	src := string(code)
+	for _, line := range []string{
+		"type Person struct {",
+		"Context string `json:\"@context,omitempty\"`",
+		"Name string `json:\"name\"`",
+		"Age int64 `json:\"age\"`",
+		"Score *float64 `json:\"score,omitempty\"`",
+		"Address PersonAddress `json:\"address\"`",
+		"Friends []Friend `json:\"friends,omitempty\"`",
+		"Labels []string `json:\"labels,omitempty\"`",
+		"Extra map[string]interface{} `json:\"extra,omitempty\"`",
+		"UpdateField *bool `json:\"update,omitempty\"`",
+		"type PersonAddress struct {",
+		"ZipCode *string `json:\"zip-code,omitempty\"`",
+		"type Friend struct {",
+		"const PersonSchemaDataId = \"schema-id\"",
+		"func LoadPerson(",
+	} {
+		require.Contains(t, strings.Join(strings.Fields(src), " "), strings.Join(strings.Fields(line), " "))
+	}
+
+	_, err = Generate(Options{Package: "models", Type: "Person"}, []byte(`{"type": "string"}`))
+	require.Error(t, err)
+	_, err = Generate(Options{Package: "models", Type: "person"}, []byte(personSchema))
+	require.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("alert")
+
+type Type string
+
+const (
+	TypeShardRetries    Type = "shard-retries"
+	TypeChainTx         Type = "chain-tx"
+	TypeDiskUsage       Type = "disk-usage"
+	TypeDiskPressure    Type = "disk-pressure"
+	TypeMigrationStall  Type = "migration-stall"
+	TypeUnderReplicated Type = "under-replicated"
+	TypeOrderExpiring   Type = "order-expiring"
+	TypeCorruptShard    Type = "corrupt-shard"
+)
+
+const (
+	notifyTimeout      = 30 * time.Second
+	defaultTxThreshold = 3
+)
+
+type Alert struct {
+	Type    Type
+	Node    string
+	Message string
+	Time    int64
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("[%s] %s on node %s at %s", a.Type, a.Message, a.Node,
+		time.Unix(a.Time, 0).Format(time.RFC3339))
+}
+
+// Notifier delivers the alerts, e.g. to a webhook or a mailbox.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Manager fires the alerts to all notifiers, an alert type fires at most once per throttle interval.
+// A nil Manager drops all alerts.
+type Manager struct {
+	node        string
+	notifiers   []Notifier
+	throttle    time.Duration
+	txThreshold int
+
+	lk        sync.Mutex
+	lastFired map[Type]time.Time
+	txFails   int
+}
+
+func NewManager(node string, notifiers []Notifier, throttle time.Duration, txThreshold int) *Manager {
+	if txThreshold <= 0 {
+		txThreshold = defaultTxThreshold
+	}
+	return &Manager{
+		node:        node,
+		notifiers:   notifiers,
+		throttle:    throttle,
+		txThreshold: txThreshold,
+		lastFired:   make(map[Type]time.Time),
+	}
+}
+
+// Fire sends the alert in the background, unless the same type fired within the throttle interval.
+// It returns whether the alert is sent.
+func (m *Manager) Fire(alertType Type, format string, args ...interface{}) bool {
+	if m == nil || len(m.notifiers) == 0 {
+		return false
+	}
+
+	now := time.Now()
+	m.lk.Lock()
+	if last, exists := m.lastFired[alertType]; exists && now.Sub(last) < m.throttle {
+		m.lk.Unlock()
+		log.Debugf("%s alert throttled: %s", alertType, fmt.Sprintf(format, args...))
+		return false
+	}
+	m.lastFired[alertType] = now
+	m.lk.Unlock()
+
+	alert := Alert{
+		Type:    alertType,
+		Node:    m.node,
+		Message: fmt.Sprintf(format, args...),
+		Time:    now.Unix(),
+	}
+	log.Warnf("alert %s", alert)
+
+	for _, n := range m.notifiers {
+		go func(n Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+
+			if err := n.Notify(ctx, alert); err != nil {
+				log.Errorf("send %s alert failed: %v", alertType, err)
+			}
+		}(n)
+	}
+	return true
+}
+
+// TxResult counts the consecutive failed chain txs, an alert fires once they reach the threshold.
+// It is used as the tx observer of the chain service.
+func (m *Manager) TxResult(msgType string, err error) {
+	if m == nil {
+		return
+	}
+
+	m.lk.Lock()
+	if err == nil {
+		m.txFails = 0
+		m.lk.Unlock()
+		return
+	}
+	m.txFails++
+	fails := m.txFails
+	m.lk.Unlock()
+
+	if fails >= m.txThreshold {
+		m.Fire(TypeChainTx, "%d chain txs failed in a row, the last %s: %v", fails, msgType, err)
+	}
+}
@@ -0,0 +1,42 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type chanNotifier chan Alert
+
+func (c chanNotifier) Notify(_ context.Context, alert Alert) error {
+	c <- alert
+	return nil
+}
+
+func TestThrottle(t *testing.T) {
+	ch := make(chanNotifier, 10)
+	m := NewManager("sao1node", []Notifier{ch}, time.Hour, 2)
+
+	require.True(t, m.Fire(TypeDiskUsage, "disk usage %d%%", 95))
+	require.False(t, m.Fire(TypeDiskUsage, "disk usage %d%%", 96))
+	require.True(t, m.Fire(TypeShardRetries, "shard failed"))
+
+	alert := <-ch
+	require.Equal(t, "sao1node", alert.Node)
+	<-ch
+
+	// consecutive tx failures reach the threshold, a success resets the count
+	m.TxResult("/saonetwork.sao.sao.MsgComplete", errors.New("out of gas"))
+	m.TxResult("/saonetwork.sao.sao.MsgComplete", nil)
+	m.TxResult("/saonetwork.sao.sao.MsgComplete", errors.New("out of gas"))
+	require.Len(t, ch, 0)
+	m.TxResult("/saonetwork.sao.sao.MsgComplete", errors.New("out of gas"))
+	alert = <-ch
+	require.Equal(t, TypeChainTx, alert.Type)
+
+	var none *Manager
+	require.False(t, none.Fire(TypeChainTx, "dropped"))
+}
@@ -0,0 +1,81 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+// webhookNotifier posts the alert as JSON to the url.
+type webhookNotifier struct {
+	url string
+}
+
+func NewWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{url: url}
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post %s: %s %s", w.url, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// emailNotifier mails the alert through the SMTP server, with PLAIN auth if username is set.
+type emailNotifier struct {
+	address  string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func NewEmailNotifier(address string, username string, password string, from string, to []string) Notifier {
+	return &emailNotifier{
+		address:  address,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+	}
+}
+
+func (e *emailNotifier) Notify(_ context.Context, alert Alert) error {
+	var auth smtp.Auth
+	if e.username != "" {
+		host, _, err := net.SplitHostPort(e.address)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", e.username, e.password, host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [sao-node] %s alert on %s\r\n\r\n%s\r\n",
+		e.from, strings.Join(e.to, ", "), alert.Type, alert.Node, alert)
+	return smtp.SendMail(e.address, auth, e.from, e.to, []byte(msg))
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sao-node/types"
+	"sync"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("audit")
+
+const ResultOk = "ok"
+
+// Entry is one API call in the audit log.
+type Entry struct {
+	Time   int64
+	Method string
+	// Token is the fingerprint of the API token, the token itself is never logged
+	Token  string
+	Did    string `json:",omitempty"`
+	DataId string `json:",omitempty"`
+	// Result is ResultOk or the error message
+	Result string
+}
+
+// Log appends the entries as JSON lines to the audit file. A nil Log drops all entries.
+type Log struct {
+	lk   sync.Mutex
+	file *os.File
+}
+
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	return &Log{file: file}, nil
+}
+
+func (l *Log) Record(entry Entry) {
+	if l == nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Errorf("marshal audit entry of %s: %v", entry.Method, err)
+		return
+	}
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		log.Errorf("write audit entry of %s: %v", entry.Method, err)
+	}
+}
+
+func (l *Log) Close(_ context.Context) error {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	if err := l.file.Close(); err != nil {
+		return types.Wrap(types.ErrCloseFileFailed, err)
+	}
+	return nil
+}
+
+// Filter selects the entries, zero fields match everything.
+type Filter struct {
+	Method string
+	Did    string
+	DataId string
+	Token  string
+	// unix seconds, inclusive
+	Since int64
+	Until int64
+	// only the failed calls
+	Failed bool
+	// keep the latest Limit entries
+	Limit int
+}
+
+func (f *Filter) match(e *Entry) bool {
+	return (f.Method == "" || f.Method == e.Method) &&
+		(f.Did == "" || f.Did == e.Did) &&
+		(f.DataId == "" || f.DataId == e.DataId) &&
+		(f.Token == "" || f.Token == e.Token) &&
+		(f.Since == 0 || e.Time >= f.Since) &&
+		(f.Until == 0 || e.Time <= f.Until) &&
+		(!f.Failed || e.Result != ResultOk)
+}
+
+// Query reads the entries matching the filter from the audit file, oldest first.
+func Query(path string, filter Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// a line may be cut off if the node crashed while writing it
+			log.Warnf("skip invalid audit entry: %v", err)
+			continue
+		}
+		if !filter.match(&entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) > filter.Limit {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return entries, nil
+}
+
+// Fingerprint identifies a token in the audit log without revealing it.
+func Fingerprint(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+type tokenKey struct{}
+
+// WithToken keeps the fingerprint of the API token of the request in the context.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, Fingerprint(token))
+}
+
+func tokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
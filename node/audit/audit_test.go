@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sao-node/api"
+	apitypes "sao-node/api/types"
+	"sao-node/types"
+	"testing"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeApi struct {
+	api.SaoApiStub
+}
+
+func (f *fakeApi) ModelLoad(_ context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	if req.Proposal.Keyword == "missing" {
+		return apitypes.LoadResp{}, errors.New("not found")
+	}
+	return apitypes.LoadResp{DataId: req.Proposal.Keyword}, nil
+}
+
+func TestProxy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	require.NoError(t, err)
+
+	a := Proxy(&fakeApi{}, l)
+	ctx := WithToken(context.Background(), "secret")
+	load := func(keyword string) error {
+		_, err := a.ModelLoad(ctx, &types.MetadataProposal{
+			Proposal: saotypes.QueryProposal{Owner: "did:key:alice", Keyword: keyword},
+		})
+		return err
+	}
+	require.NoError(t, load("data1"))
+	require.Error(t, load("missing"))
+	_, err = a.GetNodeAddress(ctx)
+	require.Error(t, err)
+	require.NoError(t, l.Close(ctx))
+
+	entries, err := Query(path, Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, Entry{
+		Time:   entries[0].Time,
+		Method: "ModelLoad",
+		Token:  Fingerprint("secret"),
+		Did:    "did:key:alice",
+		DataId: "data1",
+		Result: ResultOk,
+	}, entries[0])
+	require.NotContains(t, entries[0].Token, "secret")
+
+	entries, err = Query(path, Filter{Did: "did:key:alice", Failed: true})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "missing", entries[0].DataId)
+
+	entries, err = Query(path, Filter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "GetNodeAddress", entries[0].Method)
+}
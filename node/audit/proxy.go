@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"sao-node/api"
+	"time"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Proxy records every call of the api to the audit log.
+func Proxy(in api.SaoApi, l *Log) api.SaoApi {
+	var out api.SaoApiStruct
+	rint := reflect.ValueOf(&out.Internal).Elem()
+	ra := reflect.ValueOf(in)
+
+	for i := 0; i < rint.NumField(); i++ {
+		field := rint.Type().Field(i)
+		method := field.Name
+		fn := ra.MethodByName(method)
+
+		rint.Field(i).Set(reflect.MakeFunc(field.Type, func(args []reflect.Value) []reflect.Value {
+			results := fn.Call(args)
+
+			entry := Entry{
+				Time:   time.Now().Unix(),
+				Method: method,
+				Result: ResultOk,
+			}
+			if ctx, ok := args[0].Interface().(context.Context); ok {
+				entry.Token = tokenFromContext(ctx)
+			}
+			for _, arg := range args[1:] {
+				describe(arg, &entry)
+			}
+			last := results[len(results)-1]
+			if last.Type() == errorType && !last.IsNil() {
+				entry.Result = last.Interface().(error).Error()
+			}
+			l.Record(entry)
+
+			return results
+		}))
+	}
+
+	return &out
+}
+
+// describe fills the did and the data id of the entry from the proposal in the argument.
+func describe(arg reflect.Value, entry *Entry) {
+	for arg.Kind() == reflect.Pointer || arg.Kind() == reflect.Interface {
+		if arg.IsNil() {
+			return
+		}
+		arg = arg.Elem()
+	}
+	if arg.Kind() != reflect.Struct {
+		return
+	}
+
+	// the signed requests carry the proposal, the session requests carry the delegation
+	for _, name := range []string{"Proposal", "Delegation"} {
+		if f := arg.FieldByName(name); f.IsValid() {
+			describe(f, entry)
+		}
+	}
+
+	setString := func(dst *string, names ...string) {
+		for _, name := range names {
+			if f := arg.FieldByName(name); *dst == "" && f.IsValid() && f.Kind() == reflect.String {
+				*dst = f.String()
+			}
+		}
+	}
+	setString(&entry.Did, "Owner", "Issuer")
+	setString(&entry.DataId, "DataId", "Keyword")
+}
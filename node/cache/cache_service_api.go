@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	logging "github.com/ipfs/go-log/v2"
 )
@@ -13,9 +15,93 @@ type CacheSvcApi interface {
 	Evict(name string, key string)
 	GetSize(name string) int
 	ReSize(name string, capacity int) error
+	// Stats returns the counters of the caches used so far, ordered by name
+	Stats() []Stats
+}
+
+// LocalCacheSvc is a cache kept by the node itself, its limits can be changed at runtime and the expired
+// entries are dropped by Sweep.
+type LocalCacheSvc interface {
+	CacheSvcApi
+	SetCapacity(capacity int)
+	SetLimits(ttl time.Duration, maxBytes int)
+	Sweep()
 }
 
 var (
 	once sync.Once
 	log  = logging.Logger("cache")
 )
+
+// Stats are the counters of a named cache, the caches are named by account. Size and Capacity are -1
+// if the backend can't tell.
+type Stats struct {
+	Name     string
+	Size     int
+	Capacity int
+	Hits     uint64
+	Misses   uint64
+	// the entries dropped by the capacity limit or evicted explicitly
+	Evictions uint64
+}
+
+// statsRecorder counts the hits, misses and evictions of each cache.
+type statsRecorder struct {
+	lk    sync.Mutex
+	stats map[string]*Stats
+}
+
+func (r *statsRecorder) entry(name string) *Stats {
+	if r.stats == nil {
+		r.stats = make(map[string]*Stats)
+	}
+	s, exists := r.stats[name]
+	if !exists {
+		s = &Stats{Name: name, Size: -1, Capacity: -1}
+		r.stats[name] = s
+	}
+	return s
+}
+
+func (r *statsRecorder) register(name string) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.entry(name)
+}
+
+func (r *statsRecorder) hit(name string) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.entry(name).Hits++
+}
+
+func (r *statsRecorder) miss(name string) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.entry(name).Misses++
+}
+
+func (r *statsRecorder) evict(name string, n int) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.entry(name).Evictions += uint64(n)
+}
+
+// snapshot returns a copy of the counters, the sizes are filled by sizeOf if it is not nil.
+func (r *statsRecorder) snapshot(sizeOf func(name string) (size int, capacity int)) []Stats {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	stats := make([]Stats, 0, len(r.stats))
+	for _, s := range r.stats {
+		stat := *s
+		if sizeOf != nil {
+			stat.Size, stat.Capacity = sizeOf(stat.Name)
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
@@ -1,8 +1,14 @@
 package cache
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -67,3 +73,183 @@ func TestLruCache(t *testing.T) {
 	require.NoError(t, err2)
 	require.Equal(t, "ggg", data.(*Data).name)
 }
+
+func TestLruCacheResize(t *testing.T) {
+	svc := &LruCacheSvc{Caches: make(map[string]*LruCache)}
+
+	require.NoError(t, svc.CreateCache("test", 4))
+	svc.Put("test", "aaa", &Data{name: "aaa", length: 100})
+	svc.Put("test", "bbb", &Data{name: "bbb", length: 200})
+	svc.Put("test", "ccc", &Data{name: "ccc", length: 300})
+	svc.Put("test", "ddd", &Data{name: "ddd", length: 400})
+
+	require.NoError(t, svc.ReSize("test", 2))
+	require.Equal(t, 2, svc.GetSize("test"))
+	require.Equal(t, 2, svc.GetCapacity("test"))
+
+	data, err := svc.Get("test", "aaa")
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	data, err = svc.Get("test", "ddd")
+	require.NoError(t, err)
+	require.Equal(t, "ddd", data.(*Data).name)
+}
+
+func TestLruCacheStats(t *testing.T) {
+	svc := NewLruCacheSvc()
+
+	require.NoError(t, svc.CreateCache("stats", 2))
+	svc.Put("stats", "aaa", &Data{name: "aaa"})
+	svc.Put("stats", "bbb", &Data{name: "bbb"})
+	svc.Put("stats", "ccc", &Data{name: "ccc"})
+	svc.Get("stats", "aaa")
+	svc.Get("stats", "ccc")
+	svc.Evict("stats", "bbb")
+
+	var stats Stats
+	for _, s := range svc.Stats() {
+		if s.Name == "stats" {
+			stats = s
+		}
+	}
+	require.Equal(t, Stats{Name: "stats", Size: 1, Capacity: 2, Hits: 1, Misses: 1, Evictions: 2}, stats)
+}
+
+func TestLruCacheLimits(t *testing.T) {
+	// not the shared service, the limits would apply to the other tests
+	svc := &LruCacheSvc{Caches: make(map[string]*LruCache)}
+	svc.SetLimits(time.Hour, 10)
+
+	// the oldest entries are dropped by the max bytes, not the capacity
+	require.NoError(t, svc.CreateCache("limits", 100))
+	svc.Put("limits", "aaa", "aaaa")
+	svc.Put("limits", "bbb", "bbbb")
+	svc.Put("limits", "ccc", "cccc")
+	require.Equal(t, 2, svc.GetSize("limits"))
+	value, err := svc.Get("limits", "aaa")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	// an entry larger than the max bytes is not kept at all
+	svc.Put("limits", "big", "0123456789a")
+	require.Equal(t, 0, svc.GetSize("limits"))
+
+	svc.SetLimits(time.Millisecond, 0)
+	svc.Put("limits", "ddd", "dddd")
+	svc.Put("limits", "eee", "eeee")
+	time.Sleep(5 * time.Millisecond)
+	value, err = svc.Get("limits", "ddd")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	svc.Sweep()
+	require.Equal(t, 0, svc.GetSize("limits"))
+	require.Equal(t, uint64(6), svc.Stats()[0].Evictions)
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	decode := func(data []byte) (interface{}, error) {
+		var value string
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+
+	svc, err := NewTieredCacheSvc(ctx, ds, 2, 2, decode)
+	require.NoError(t, err)
+
+	svc.Put("tiered", "aaa", "a")
+	svc.Put("tiered", "bbb", "b")
+	svc.Put("tiered", "ccc", "c")
+	require.Equal(t, 2, svc.GetSize("tiered"))
+
+	// aaa is spilled to disk and moved back, bbb is spilled then
+	value, err := svc.Get("tiered", "aaa")
+	require.NoError(t, err)
+	require.Equal(t, "a", value)
+
+	// the third entry on disk drops the least recently used one
+	svc.Put("tiered", "ddd", "d")
+	svc.Put("tiered", "eee", "e")
+	value, err = svc.Get("tiered", "bbb")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	// the memory entries survive a restart
+	svc.Flush()
+	svc, err = NewTieredCacheSvc(ctx, ds, 2, 10, decode)
+	require.NoError(t, err)
+	value, err = svc.Get("tiered", "eee")
+	require.NoError(t, err)
+	require.Equal(t, "e", value)
+
+	svc.Evict("tiered", "ddd")
+	value, err = svc.Get("tiered", "ddd")
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestTieredCacheWarmUp(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	decode := func(data []byte) (interface{}, error) {
+		var value string
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+
+	svc, err := NewTieredCacheSvc(ctx, ds, 2, 10, decode)
+	require.NoError(t, err)
+	for _, key := range []string{"aaa", "bbb", "ccc", "ddd"} {
+		svc.Put("warm", key, key[:1])
+	}
+	svc.Flush()
+
+	// only the most recently used entry fits in 1 byte
+	svc, err = NewTieredCacheSvc(ctx, ds, 2, 10, decode)
+	require.NoError(t, err)
+	require.Equal(t, 1, svc.WarmUp(1))
+	require.Equal(t, 1, svc.GetSize("warm"))
+	onDisk, err := ds.Has(ctx, diskKey("warm", "ddd"))
+	require.NoError(t, err)
+	require.False(t, onDisk)
+
+	// the memory capacity bounds the warm up as well
+	require.Equal(t, 1, svc.WarmUp(1024))
+	require.Equal(t, 2, svc.GetSize("warm"))
+	onDisk, err = ds.Has(ctx, diskKey("warm", "ccc"))
+	require.NoError(t, err)
+	require.False(t, onDisk)
+}
+
+func TestCoherence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	hosts := mn.Hosts()
+
+	svc1 := &LruCacheSvc{Caches: make(map[string]*LruCache)}
+	svc2 := &LruCacheSvc{Caches: make(map[string]*LruCache)}
+	c1, err := NewCoherence(ctx, hosts[0], "coherence-test", svc1)
+	require.NoError(t, err)
+	_, err = NewCoherence(ctx, hosts[1], "coherence-test", svc2)
+	require.NoError(t, err)
+
+	require.NoError(t, svc1.CreateCache("did:key:alice", 10))
+	require.NoError(t, svc2.CreateCache("did:key:alice", 10))
+	svc1.Put("did:key:alice", "data1", "v1")
+	svc2.Put("did:key:alice", "data1", "v1")
+
+	// the publisher keeps its own entry, the mesh may take a heartbeat to form
+	require.Eventually(t, func() bool {
+		c1.Publish(ctx, "did:key:alice", "data1")
+		return svc2.GetSize("did:key:alice") == 0
+	}, 10*time.Second, 200*time.Millisecond)
+	value, err := svc1.Get("did:key:alice", "data1")
+	require.NoError(t, err)
+	require.Equal(t, "v1", value)
+}
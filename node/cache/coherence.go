@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sao-node/types"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/host"
+)
+
+// Invalidation asks the other gateways of the cluster to evict the keys of a cache.
+type Invalidation struct {
+	Name string
+	Keys []string
+}
+
+// Coherence keeps the local caches of the gateways behind the same load balancer coherent, the gateway
+// processed an update publishes an Invalidation to the cluster topic and the others evict the entries.
+type Coherence struct {
+	svc   CacheSvcApi
+	self  string
+	topic *pubsub.Topic
+	sub   *pubsub.Subscription
+}
+
+// NewCoherence joins the cluster topic over gossipsub and evicts the entries of svc on the invalidations
+// from the other gateways until ctx is done.
+func NewCoherence(ctx context.Context, h host.Host, topicName string, svc CacheSvcApi) (*Coherence, error) {
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateP2PServiceFaild, err)
+	}
+	topic, err := ps.Join(topicName)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateP2PServiceFaild, err)
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateP2PServiceFaild, err)
+	}
+
+	c := &Coherence{
+		svc:   svc,
+		self:  h.ID().String(),
+		topic: topic,
+		sub:   sub,
+	}
+	go c.receive(ctx)
+
+	log.Infof("cache coherence enabled, topic %s", topicName)
+	return c, nil
+}
+
+// Publish asks the other gateways to evict the keys, a nil Coherence publishes nothing.
+func (c *Coherence) Publish(ctx context.Context, name string, keys ...string) {
+	if c == nil {
+		return
+	}
+
+	data, err := json.Marshal(Invalidation{Name: name, Keys: keys})
+	if err != nil {
+		log.Warnf("marshal cache invalidation: %v", err)
+		return
+	}
+	if err := c.topic.Publish(ctx, data); err != nil {
+		log.Warnf("publish cache invalidation of %v: %v", keys, err)
+	}
+}
+
+func (c *Coherence) Close(_ context.Context) error {
+	if c == nil {
+		return nil
+	}
+
+	c.sub.Cancel()
+	return c.topic.Close()
+}
+
+func (c *Coherence) receive(ctx context.Context) {
+	for {
+		msg, err := c.sub.Next(ctx)
+		if err != nil {
+			// the context is done or the subscription is cancelled
+			return
+		}
+		if msg.GetFrom().String() == c.self {
+			continue
+		}
+
+		var inv Invalidation
+		if err := json.Unmarshal(msg.Data, &inv); err != nil {
+			log.Warnf("invalid cache invalidation from %s: %v", msg.GetFrom(), err)
+			continue
+		}
+		for _, key := range inv.Keys {
+			c.svc.Evict(inv.Name, key)
+		}
+		log.Debugf("evicted %v of the cache [%s] on the invalidation from %s", inv.Keys, inv.Name, msg.GetFrom())
+	}
+}
@@ -1,6 +1,9 @@
 package cache
 
 import (
+	"sao-node/types"
+	"time"
+
 	hamt "github.com/raviqqe/hamt"
 )
 
@@ -47,11 +50,19 @@ type (
 		Value interface{}
 		pre   *Node
 		next  *Node
+		// zero if the entry never expires
+		expireAt time.Time
+		size     int
 	}
 
 	LruCache struct {
 		Capacity int
 		Size     int
+		// the entries expire TTL after put, 0 keeps them until dropped
+		TTL time.Duration
+		// the estimated bytes of all entries are limited to MaxBytes, 0 for unlimited
+		MaxBytes int
+		Bytes    int
 		head     *Node
 		end      *Node
 
@@ -72,14 +83,18 @@ func (l *LruCache) addNode(node *Node) {
 }
 
 func (l *LruCache) removeNode(node *Node) hamt.Entry {
-	if node == l.end {
-		l.end = l.end.pre
-	} else if node == l.head {
-		l.head = l.head.next
-	} else {
+	if node.pre != nil {
 		node.pre.next = node.next
+	} else {
+		l.head = node.next
+	}
+	if node.next != nil {
 		node.next.pre = node.pre
+	} else {
+		l.end = node.pre
 	}
+	node.pre = nil
+	node.next = nil
 	return node.Key
 }
 
@@ -91,56 +106,139 @@ func (l *LruCache) refreshNode(node *Node) {
 	l.addNode(node)
 }
 
-func (l *LruCache) get(key string) interface{} {
+// get returns nil if the key is not cached, an expired entry is dropped and returned as well.
+func (l *LruCache) get(key string) (interface{}, *Node) {
 	value := l.Map.Find(hamt.Entry(entryString(key)))
 	if value != nil {
 		node, ok := value.(*Node)
 		if ok {
+			if node.expired(time.Now()) {
+				return nil, l.drop(node)
+			}
 			l.refreshNode(node)
-			return node.Value
+			return node.Value, nil
 		}
 	}
 
-	return nil
+	return nil, nil
+}
+
+// contains doesn't change the order of the entries.
+func (l *LruCache) contains(key string) bool {
+	return l.find(key) != nil
 }
 
-func (l *LruCache) put(keyStr string, value interface{}) {
+// find returns the node of the key without changing the order of the entries.
+func (l *LruCache) find(key string) *Node {
+	node, _ := l.Map.Find(hamt.Entry(entryString(key))).(*Node)
+	return node
+}
+
+// put returns the entries dropped for the new one by the capacity or the max bytes, the new entry itself
+// is dropped if it is larger than MaxBytes.
+func (l *LruCache) put(key string, value interface{}) []*Node {
+	var expireAt time.Time
+	if l.TTL > 0 {
+		expireAt = time.Now().Add(l.TTL)
+	}
+	return l.putExpiring(key, value, expireAt)
+}
+
+func (l *LruCache) putExpiring(keyStr string, value interface{}, expireAt time.Time) []*Node {
 	key := hamt.Entry(entryString(keyStr))
+	size := entrySize(value)
 	oldValue := l.Map.Find(key)
 	if oldValue == nil {
-		node := Node{Key: key, Value: value}
-		if l.Capacity > 0 && l.Map.Size() >= l.Capacity {
-			oldKey := l.removeNode(l.head)
-			l.Map = l.Map.Delete(oldKey).Insert(key, &node)
-		} else {
-			l.Map = l.Map.Insert(key, &node)
-		}
+		node := Node{Key: key, Value: value, expireAt: expireAt, size: size}
+		l.Map = l.Map.Insert(key, &node)
 		l.addNode(&node)
+		l.Bytes += size
 	} else {
 		node, ok := oldValue.(*Node)
 		if ok {
+			l.Bytes += size - node.size
 			node.Value = value
+			node.expireAt = expireAt
+			node.size = size
 			l.refreshNode(node)
 			l.Map = l.Map.Insert(key, node)
 		} else {
-			return
+			return nil
 		}
 	}
 	l.Size = l.Map.Size()
+	return l.shrink()
 }
 
-func (l *LruCache) evict(key string) {
+func (l *LruCache) evict(key string) bool {
 	value := l.Map.Find(hamt.Entry(entryString(key)))
 	if value != nil {
 		node, ok := value.(*Node)
 		if ok {
-			oldKey := l.removeNode(node)
-			l.Map = l.Map.Delete(oldKey)
-			l.Size = l.Map.Size()
+			l.drop(node)
+			return true
+		}
+	}
+	return false
+}
+
+// shrink drops the least recently used entries until the cache is within the capacity and the max bytes.
+func (l *LruCache) shrink() []*Node {
+	var dropped []*Node
+	for l.head != nil && ((l.Capacity > 0 && l.Map.Size() > l.Capacity) || (l.MaxBytes > 0 && l.Bytes > l.MaxBytes)) {
+		dropped = append(dropped, l.drop(l.head))
+	}
+	return dropped
+}
+
+// expire drops the entries expired at now.
+func (l *LruCache) expire(now time.Time) []*Node {
+	var dropped []*Node
+	for node := l.head; node != nil; {
+		next := node.next
+		if node.expired(now) {
+			dropped = append(dropped, l.drop(node))
 		}
+		node = next
 	}
+	return dropped
 }
 
+func (l *LruCache) drop(node *Node) *Node {
+	l.Map = l.Map.Delete(l.removeNode(node))
+	l.Bytes -= node.size
+	l.Size = l.Map.Size()
+	return node
+}
+
+func (n *Node) expired(now time.Time) bool {
+	return !n.expireAt.IsZero() && now.After(n.expireAt)
+}
+
+func (n *Node) key() string {
+	return string(n.Key.(entryString))
+}
+
+// entrySize estimates the memory held by a cached value, a model is dominated by its content.
+func entrySize(value interface{}) int {
+	switch v := value.(type) {
+	case *types.Model:
+		return len(v.Content) + modelOverhead
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	default:
+		return defaultEntrySize
+	}
+}
+
+const (
+	// the metadata of a model, like the commits and shards
+	modelOverhead    = 1024
+	defaultEntrySize = 64
+)
+
 func CreateLruCache(capacity int) *LruCache {
 	lruCache := LruCache{Capacity: capacity}
 	lruCache.Map = hamt.NewMap()
@@ -2,10 +2,16 @@ package cache
 
 import (
 	"sao-node/types"
+	"sync"
+	"time"
 )
 
 type LruCacheSvc struct {
-	Caches map[string]*LruCache
+	Caches   map[string]*LruCache
+	lk       sync.Mutex
+	ttl      time.Duration
+	maxBytes int
+	stats    statsRecorder
 }
 
 var (
@@ -22,43 +28,75 @@ func NewLruCacheSvc() *LruCacheSvc {
 }
 
 func (svc *LruCacheSvc) CreateCache(name string, capacity int) error {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
 	if svc.Caches[name] != nil {
 		return types.Wrapf(types.ErrConflictName, "the cache [%s] is existing already", name)
 	}
 
-	svc.Caches[name] = CreateLruCache(capacity)
+	cache := CreateLruCache(capacity)
+	cache.TTL = svc.ttl
+	cache.MaxBytes = svc.maxBytes
+	svc.Caches[name] = cache
+	svc.stats.register(name)
 
 	return nil
 }
 
 func (svc *LruCacheSvc) Get(name string, key string) (interface{}, error) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
 	cache := svc.Caches[name]
 	if cache == nil {
 		return nil, types.Wrapf(types.ErrNotFound, "the cache [%s] not found", name)
 	}
 
-	return cache.get(key), nil
+	value, expired := cache.get(key)
+	if expired != nil {
+		svc.stats.evict(name, 1)
+	}
+	if value == nil {
+		svc.stats.miss(name)
+	} else {
+		svc.stats.hit(name)
+	}
+	return value, nil
 }
 
 func (svc *LruCacheSvc) Put(name string, key string, value interface{}) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
 	cache := svc.Caches[name]
 	if cache == nil {
 		log.Errorf("the cache [%s] not found", name)
+		return
 	}
 
-	cache.put(key, value)
+	svc.stats.evict(name, len(cache.put(key, value)))
 }
 
 func (svc *LruCacheSvc) Evict(name string, key string) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
 	cache := svc.Caches[name]
+	// nothing is cached for the name yet
 	if cache == nil {
-		log.Errorf("the cache [%s] not found", name)
+		return
 	}
 
-	cache.evict(key)
+	if cache.evict(key) {
+		svc.stats.evict(name, 1)
+	}
 }
 
 func (svc *LruCacheSvc) GetCapacity(name string) int {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
 	cache := svc.Caches[name]
 	if cache == nil {
 		log.Errorf("the cache [%s] not found", name)
@@ -69,6 +107,9 @@ func (svc *LruCacheSvc) GetCapacity(name string) int {
 }
 
 func (svc *LruCacheSvc) GetSize(name string) int {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
 	cache := svc.Caches[name]
 	if cache == nil {
 		log.Errorf("the cache [%s] not found", name)
@@ -79,22 +120,68 @@ func (svc *LruCacheSvc) GetSize(name string) int {
 }
 
 func (svc *LruCacheSvc) ReSize(name string, capacity int) error {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	return svc.resize(name, capacity)
+}
+
+// SetCapacity resizes all caches.
+func (svc *LruCacheSvc) SetCapacity(capacity int) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	for name := range svc.Caches {
+		_ = svc.resize(name, capacity)
+	}
+}
+
+// SetLimits sets the TTL and the max bytes of all caches, the new TTL applies to the entries put later.
+func (svc *LruCacheSvc) SetLimits(ttl time.Duration, maxBytes int) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	svc.ttl = ttl
+	svc.maxBytes = maxBytes
+	for name, cache := range svc.Caches {
+		cache.TTL = ttl
+		cache.MaxBytes = maxBytes
+		svc.stats.evict(name, len(cache.shrink()))
+	}
+}
+
+// Sweep drops the expired entries of all caches.
+func (svc *LruCacheSvc) Sweep() {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	now := time.Now()
+	for name, cache := range svc.Caches {
+		svc.stats.evict(name, len(cache.expire(now)))
+	}
+}
+
+func (svc *LruCacheSvc) resize(name string, capacity int) error {
 	cache := svc.Caches[name]
 	if cache == nil {
 		return types.Wrapf(types.ErrNotFound, "the cache [%s] not found", name)
 	}
 
-	if capacity == -1 || cache.Capacity <= capacity {
-		cache.Capacity = capacity
-	} else {
-		for {
-			if cache.Map.Size() <= cache.Capacity {
-				break
-			}
-			oldKey := cache.removeNode(cache.head)
-			cache.Map.Delete(oldKey)
-		}
-	}
+	cache.Capacity = capacity
+	svc.stats.evict(name, len(cache.shrink()))
 
 	return nil
 }
+
+func (svc *LruCacheSvc) Stats() []Stats {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	return svc.stats.snapshot(func(name string) (int, int) {
+		cache := svc.Caches[name]
+		if cache == nil {
+			return -1, -1
+		}
+		return cache.Size, cache.Capacity
+	})
+}
@@ -3,19 +3,23 @@ package cache
 import (
 	"encoding/json"
 	"sao-node/types"
+	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 )
 
 type MemcachedCacheSvc struct {
 	Client *memcache.Client
+	// 0 keeps the entries until evicted
+	ttl   time.Duration
+	stats statsRecorder
 }
 
 var (
 	memcacheCacheSvc *MemcachedCacheSvc
 )
 
-func NewMemcachedCacheSvc(conn string) *MemcachedCacheSvc {
+func NewMemcachedCacheSvc(conn string, ttl time.Duration) *MemcachedCacheSvc {
 	once.Do(func() {
 		log.Infof("octopus: init memcache client: %v ******", conn)
 
@@ -24,6 +28,7 @@ func NewMemcachedCacheSvc(conn string) *MemcachedCacheSvc {
 		if cli != nil {
 			memcacheCacheSvc = &MemcachedCacheSvc{
 				Client: cli,
+				ttl:    ttl,
 			}
 		}
 	})
@@ -36,6 +41,11 @@ func (svc *MemcachedCacheSvc) CreateCache(name string, capacity int) error {
 
 func (svc *MemcachedCacheSvc) Get(name string, key string) (interface{}, error) {
 	item, err := svc.Client.Get(name + "_" + key)
+	if err == memcache.ErrCacheMiss {
+		svc.stats.miss(name)
+	} else if err == nil {
+		svc.stats.hit(name)
+	}
 	if err != nil {
 		return nil, types.Wrap(types.ErrCacheGetFailed, err)
 	}
@@ -60,9 +70,10 @@ func (svc *MemcachedCacheSvc) Put(name string, key string, value interface{}) {
 	}
 
 	err = svc.Client.Set(&memcache.Item{
-		Key:   name + "_" + key,
-		Value: bytes,
-		Flags: 0,
+		Key:        name + "_" + key,
+		Value:      bytes,
+		Flags:      0,
+		Expiration: memcachedExpiration(svc.ttl),
 	})
 	if err != nil {
 		log.Error(err.Error())
@@ -72,8 +83,12 @@ func (svc *MemcachedCacheSvc) Put(name string, key string, value interface{}) {
 func (svc *MemcachedCacheSvc) Evict(name string, key string) {
 	err := svc.Client.Delete(name + "_" + key)
 	if err != nil {
-		log.Error(err.Error())
+		if err != memcache.ErrCacheMiss {
+			log.Error(err.Error())
+		}
+		return
 	}
+	svc.stats.evict(name, 1)
 }
 
 func (svc *MemcachedCacheSvc) GetCapacity(name string) int {
@@ -93,3 +108,20 @@ func (svc *MemcachedCacheSvc) ReSize(name string, capacity int) error {
 
 	return nil
 }
+
+func (svc *MemcachedCacheSvc) Stats() []Stats {
+	return svc.stats.snapshot(nil)
+}
+
+// memcachedExpiration converts the ttl to the item expiration, memcached takes the values over 30 days
+// as a unix time.
+func memcachedExpiration(ttl time.Duration) int32 {
+	seconds := int64(ttl / time.Second)
+	if seconds <= 0 {
+		return 0
+	}
+	if seconds > 30*24*3600 {
+		return int32(time.Now().Unix() + seconds)
+	}
+	return int32(seconds)
+}
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"sao-node/types"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -12,13 +13,16 @@ import (
 type RedisCacheSvc struct {
 	Ctx    context.Context
 	Client redis.Cmdable
+	// 0 keeps the entries until evicted
+	ttl   time.Duration
+	stats statsRecorder
 }
 
 var (
 	redisCacheSvc *RedisCacheSvc
 )
 
-func NewRedisCacheSvc(conn string, password string, poolSize int) *RedisCacheSvc {
+func NewRedisCacheSvc(conn string, password string, poolSize int, ttl time.Duration) *RedisCacheSvc {
 	once.Do(func() {
 		log.Infof("octopus: init redis client: %v ******", conn)
 
@@ -44,6 +48,7 @@ func NewRedisCacheSvc(conn string, password string, poolSize int) *RedisCacheSvc
 			redisCacheSvc = &RedisCacheSvc{
 				Client: cli,
 				Ctx:    context.Background(),
+				ttl:    ttl,
 			}
 		}
 	})
@@ -64,24 +69,28 @@ func (svc *RedisCacheSvc) Get(name string, key string) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		svc.stats.hit(name)
 		return value, nil
 	}
 
+	svc.stats.miss(name)
 	return nil, types.Wrapf(types.ErrNotFound, "the key [%s] not found", name)
 }
 
 func (svc *RedisCacheSvc) Put(name string, key string, value interface{}) {
-	_, err := svc.Client.Set(svc.Ctx, key, value, 0).Result()
+	_, err := svc.Client.Set(svc.Ctx, key, value, svc.ttl).Result()
 	if err != nil {
 		log.Error(err.Error())
 	}
 }
 
 func (svc *RedisCacheSvc) Evict(name string, key string) {
-	_, err := svc.Client.Del(svc.Ctx, key, name+"_"+key).Result()
+	n, err := svc.Client.Del(svc.Ctx, key, name+"_"+key).Result()
 	if err != nil {
 		log.Error(err.Error())
+		return
 	}
+	svc.stats.evict(name, int(n))
 }
 
 func (svc *RedisCacheSvc) GetCapacity(name string) int {
@@ -101,3 +110,7 @@ func (svc *RedisCacheSvc) ReSize(name string, capacity int) error {
 
 	return nil
 }
+
+func (svc *RedisCacheSvc) Stats() []Stats {
+	return svc.stats.snapshot(nil)
+}
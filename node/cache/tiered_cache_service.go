@@ -0,0 +1,555 @@
+package cache
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sao-node/types"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// TieredCacheSvc keeps the recently used entries in memory lru caches. The entries dropped by the memory
+// capacity spill to the datastore and move back to memory when they are read again, the datastore keeps
+// up to diskCapacity entries of each cache. The memory entries are flushed to the datastore on Flush, so
+// the cache survives the restarts, and WarmUp moves the most recently used ones back to memory.
+type TieredCacheSvc struct {
+	Ctx context.Context
+
+	lk       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	maxBytes int
+	memory   map[string]*LruCache
+	// the keys on disk in lru order, the values are in the datastore
+	disk         map[string]*LruCache
+	diskCapacity int
+	ds           datastore.Batching
+	decode       func(data []byte) (interface{}, error)
+	stats        statsRecorder
+}
+
+// NewTieredCacheSvc loads the keys of the entries on disk, decode restores a value spilled as JSON.
+// The memory caches created implicitly have the capacity.
+func NewTieredCacheSvc(ctx context.Context, ds datastore.Batching, capacity int, diskCapacity int, decode func(data []byte) (interface{}, error)) (*TieredCacheSvc, error) {
+	svc := &TieredCacheSvc{
+		Ctx:          ctx,
+		capacity:     capacity,
+		memory:       make(map[string]*LruCache),
+		disk:         make(map[string]*LruCache),
+		diskCapacity: diskCapacity,
+		ds:           ds,
+		decode:       decode,
+	}
+
+	results, err := ds.Query(ctx, query.Query{KeysOnly: true})
+	if err != nil {
+		return nil, types.Wrap(types.ErrGetFailed, err)
+	}
+	defer results.Close()
+
+	var orders []string
+	count := 0
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, types.Wrap(types.ErrGetFailed, r.Error)
+		}
+		k := datastore.NewKey(r.Key)
+		if name, ok := parseOrderKey(k); ok {
+			orders = append(orders, name)
+			continue
+		}
+		name, key, ok := parseDiskKey(k)
+		if !ok {
+			log.Warnf("skip invalid disk cache key %s", r.Key)
+			continue
+		}
+		svc.diskIndex(name, 0).put(key, true)
+		count++
+	}
+
+	// restore the lru order and the expiry time saved by the last Flush, the entries spilled after it
+	// are kept as the least recently used
+	for _, name := range orders {
+		if err := svc.loadOrder(name); err != nil {
+			log.Warnf("load the order of the disk cache [%s]: %v", name, err)
+		}
+	}
+	for name, index := range svc.disk {
+		index.Capacity = diskCapacity
+		for _, node := range index.shrink() {
+			svc.deleteDisk(name, node.key())
+		}
+	}
+	log.Infof("disk cache loaded, %d entries of %d caches", count, len(svc.disk))
+
+	return svc, nil
+}
+
+func (svc *TieredCacheSvc) CreateCache(name string, capacity int) error {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	if svc.memory[name] != nil {
+		return types.Wrapf(types.ErrConflictName, "the cache [%s] is existing already", name)
+	}
+	svc.memory[name] = svc.createMemoryCache(capacity)
+	svc.stats.register(name)
+	return nil
+}
+
+func (svc *TieredCacheSvc) Get(name string, key string) (interface{}, error) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	value, expired := svc.memoryCache(name).get(key)
+	if value != nil {
+		svc.stats.hit(name)
+		return value, nil
+	}
+	if expired != nil {
+		svc.stats.evict(name, 1)
+	}
+
+	var node *Node
+	if index := svc.disk[name]; index != nil {
+		node = index.find(key)
+	}
+	if node == nil {
+		svc.stats.miss(name)
+		return nil, nil
+	}
+	if node.expired(time.Now()) {
+		svc.removeDisk(name, key)
+		svc.stats.evict(name, 1)
+		svc.stats.miss(name)
+		return nil, nil
+	}
+
+	dk := diskKey(name, key)
+	data, err := svc.ds.Get(svc.Ctx, dk)
+	if err == nil {
+		var value interface{}
+		value, err = svc.decode(data)
+		if err == nil {
+			// move the entry back to memory
+			expireAt := node.expireAt
+			svc.removeDisk(name, key)
+			svc.spillDropped(name, svc.memoryCache(name).putExpiring(key, value, expireAt))
+			svc.stats.hit(name)
+			return value, nil
+		}
+	}
+
+	log.Warnf("load %s of the cache [%s] from disk: %v", key, name, err)
+	svc.removeDisk(name, key)
+	svc.stats.miss(name)
+	return nil, nil
+}
+
+func (svc *TieredCacheSvc) Put(name string, key string, value interface{}) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	// the disk copy is stale now
+	if index := svc.disk[name]; index != nil && index.contains(key) {
+		svc.removeDisk(name, key)
+	}
+	svc.spillDropped(name, svc.memoryCache(name).put(key, value))
+}
+
+func (svc *TieredCacheSvc) Evict(name string, key string) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	evicted := false
+	if cache := svc.memory[name]; cache != nil {
+		evicted = cache.evict(key)
+	}
+	if index := svc.disk[name]; index != nil && index.contains(key) {
+		svc.removeDisk(name, key)
+		evicted = true
+	}
+	if evicted {
+		svc.stats.evict(name, 1)
+	}
+}
+
+func (svc *TieredCacheSvc) GetCapacity(name string) int {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	return svc.memoryCache(name).Capacity
+}
+
+func (svc *TieredCacheSvc) GetSize(name string) int {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	return svc.memoryCache(name).Size
+}
+
+// ReSize changes the memory capacity of the cache, the dropped entries spill to disk.
+func (svc *TieredCacheSvc) ReSize(name string, capacity int) error {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	svc.resize(name, capacity)
+	return nil
+}
+
+// SetCapacity resizes all memory caches, including the ones created later.
+func (svc *TieredCacheSvc) SetCapacity(capacity int) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	svc.capacity = capacity
+	for name := range svc.memory {
+		svc.resize(name, capacity)
+	}
+}
+
+func (svc *TieredCacheSvc) Stats() []Stats {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	return svc.stats.snapshot(func(name string) (int, int) {
+		cache := svc.memory[name]
+		if cache == nil {
+			return 0, svc.capacity
+		}
+		return cache.Size, cache.Capacity
+	})
+}
+
+// SetLimits sets the TTL and the max bytes of the memory caches, the disk entries keep their expiry time.
+// The disk entries without an expiry time expire ttl from now.
+func (svc *TieredCacheSvc) SetLimits(ttl time.Duration, maxBytes int) {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	svc.ttl = ttl
+	svc.maxBytes = maxBytes
+	for name, cache := range svc.memory {
+		cache.TTL = ttl
+		cache.MaxBytes = maxBytes
+		svc.spillDropped(name, cache.shrink())
+	}
+	if ttl > 0 {
+		expireAt := time.Now().Add(ttl)
+		for _, index := range svc.disk {
+			for node := index.head; node != nil; node = node.next {
+				if node.expireAt.IsZero() {
+					node.expireAt = expireAt
+				}
+			}
+		}
+	}
+}
+
+// Sweep drops the expired entries of both memory and disk.
+func (svc *TieredCacheSvc) Sweep() {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	now := time.Now()
+	for name, cache := range svc.memory {
+		svc.stats.evict(name, len(cache.expire(now)))
+	}
+	for name, index := range svc.disk {
+		expired := index.expire(now)
+		for _, node := range expired {
+			svc.deleteDisk(name, node.key())
+		}
+		svc.stats.evict(name, len(expired))
+	}
+}
+
+// Flush spills all memory entries to disk and saves the lru order of the disk entries, it is called
+// before the node stops.
+func (svc *TieredCacheSvc) Flush() {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	count := 0
+	for name, cache := range svc.memory {
+		// from the least recently used, so the disk keeps the same order
+		for node := cache.head; node != nil; node = node.next {
+			if node.expired(time.Now()) {
+				continue
+			}
+			svc.spill(name, node)
+			count++
+		}
+	}
+	for name := range svc.disk {
+		if err := svc.saveOrder(name); err != nil {
+			log.Warnf("save the order of the disk cache [%s]: %v", name, err)
+		}
+	}
+	log.Infof("%d cache entries flushed to disk", count)
+}
+
+// WarmUp moves the most recently used entries on disk back to memory, up to maxBytes of all caches and
+// the capacity of each memory cache. It returns the number of entries loaded.
+func (svc *TieredCacheSvc) WarmUp(maxBytes int) int {
+	svc.lk.Lock()
+	defer svc.lk.Unlock()
+
+	if maxBytes <= 0 {
+		return 0
+	}
+	names := make([]string, 0, len(svc.disk))
+	for name := range svc.disk {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	count := 0
+	for _, name := range names {
+		cache := svc.memoryCache(name)
+		limit := maxBytes
+		if cache.MaxBytes > 0 && cache.MaxBytes-cache.Bytes < limit {
+			limit = cache.MaxBytes - cache.Bytes
+		}
+
+		// the most recently used first, they are put to memory in the reverse order later
+		type warmEntry struct {
+			key      string
+			value    interface{}
+			expireAt time.Time
+		}
+		var entries []warmEntry
+		var bad []string
+		for node := svc.disk[name].end; node != nil; node = node.pre {
+			if cache.Capacity > 0 && cache.Size+len(entries) >= cache.Capacity {
+				break
+			}
+			if node.expired(now) {
+				continue
+			}
+			key := node.key()
+			data, err := svc.ds.Get(svc.Ctx, diskKey(name, key))
+			if err != nil {
+				log.Warnf("load %s of the cache [%s] from disk: %v", key, name, err)
+				bad = append(bad, key)
+				continue
+			}
+			value, err := svc.decode(data)
+			if err != nil {
+				log.Warnf("load %s of the cache [%s] from disk: %v", key, name, err)
+				bad = append(bad, key)
+				continue
+			}
+			size := entrySize(value)
+			if size > limit {
+				break
+			}
+			limit -= size
+			maxBytes -= size
+			entries = append(entries, warmEntry{key: key, value: value, expireAt: node.expireAt})
+		}
+
+		for _, key := range bad {
+			svc.removeDisk(name, key)
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			svc.removeDisk(name, e.key)
+			svc.spillDropped(name, cache.putExpiring(e.key, e.value, e.expireAt))
+		}
+		count += len(entries)
+	}
+	log.Infof("%d cache entries warmed up from disk", count)
+	return count
+}
+
+// memoryCache returns the memory cache of the name, it is created if not existing.
+func (svc *TieredCacheSvc) memoryCache(name string) *LruCache {
+	cache := svc.memory[name]
+	if cache == nil {
+		cache = svc.createMemoryCache(svc.capacity)
+		svc.memory[name] = cache
+		svc.stats.register(name)
+	}
+	return cache
+}
+
+func (svc *TieredCacheSvc) createMemoryCache(capacity int) *LruCache {
+	cache := CreateLruCache(capacity)
+	cache.TTL = svc.ttl
+	cache.MaxBytes = svc.maxBytes
+	return cache
+}
+
+func (svc *TieredCacheSvc) resize(name string, capacity int) {
+	cache := svc.memoryCache(name)
+	cache.Capacity = capacity
+	svc.spillDropped(name, cache.shrink())
+}
+
+// spillDropped spills the entries dropped from memory, the expired ones are not worth keeping.
+func (svc *TieredCacheSvc) spillDropped(name string, dropped []*Node) {
+	now := time.Now()
+	for _, node := range dropped {
+		if node.expired(now) {
+			svc.stats.evict(name, 1)
+			continue
+		}
+		svc.spill(name, node)
+	}
+}
+
+func (svc *TieredCacheSvc) spill(name string, node *Node) {
+	key := node.key()
+	data, err := json.Marshal(node.Value)
+	if err != nil {
+		log.Warnf("spill %s of the cache [%s]: %v", key, name, err)
+		svc.stats.evict(name, 1)
+		return
+	}
+
+	err = svc.ds.Put(svc.Ctx, diskKey(name, key), data)
+	if err != nil {
+		log.Warnf("spill %s of the cache [%s]: %v", key, name, err)
+		svc.stats.evict(name, 1)
+		return
+	}
+	svc.indexDisk(name, key, node.expireAt)
+}
+
+// diskIndex returns the disk index of the name, it is created with the capacity if not existing.
+func (svc *TieredCacheSvc) diskIndex(name string, capacity int) *LruCache {
+	index := svc.disk[name]
+	if index == nil {
+		index = CreateLruCache(capacity)
+		svc.disk[name] = index
+		svc.stats.register(name)
+	}
+	return index
+}
+
+// indexDisk adds the key to the disk index, the least recently used entry on disk is removed if the cache is full.
+func (svc *TieredCacheSvc) indexDisk(name string, key string, expireAt time.Time) {
+	dropped := svc.diskIndex(name, svc.diskCapacity).putExpiring(key, true, expireAt)
+	for _, node := range dropped {
+		svc.deleteDisk(name, node.key())
+	}
+	svc.stats.evict(name, len(dropped))
+}
+
+func (svc *TieredCacheSvc) removeDisk(name string, key string) {
+	if index := svc.disk[name]; index != nil {
+		index.evict(key)
+	}
+	svc.deleteDisk(name, key)
+}
+
+func (svc *TieredCacheSvc) deleteDisk(name string, key string) {
+	if err := svc.ds.Delete(svc.Ctx, diskKey(name, key)); err != nil {
+		log.Warnf("remove %s of the cache [%s] from disk: %v", key, name, err)
+	}
+}
+
+// diskOrderEntry is an entry of the saved disk order, ExpireAt is in unix nanoseconds.
+type diskOrderEntry struct {
+	Key      string
+	ExpireAt int64 `json:",omitempty"`
+}
+
+// saveOrder saves the keys on disk from the least recently used with their expiry time.
+func (svc *TieredCacheSvc) saveOrder(name string) error {
+	var order []diskOrderEntry
+	for node := svc.disk[name].head; node != nil; node = node.next {
+		e := diskOrderEntry{Key: node.key()}
+		if !node.expireAt.IsZero() {
+			e.ExpireAt = node.expireAt.UnixNano()
+		}
+		order = append(order, e)
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	err = svc.ds.Put(svc.Ctx, orderKey(name), data)
+	if err != nil {
+		return types.Wrap(types.ErrStoreFailed, err)
+	}
+	return nil
+}
+
+// loadOrder moves the indexed keys to the saved order, the keys not on disk anymore are skipped.
+func (svc *TieredCacheSvc) loadOrder(name string) error {
+	data, err := svc.ds.Get(svc.Ctx, orderKey(name))
+	if err != nil {
+		return types.Wrap(types.ErrGetFailed, err)
+	}
+	var order []diskOrderEntry
+	err = json.Unmarshal(data, &order)
+	if err != nil {
+		return types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+
+	index := svc.disk[name]
+	if index == nil {
+		return nil
+	}
+	for _, e := range order {
+		if !index.contains(e.Key) {
+			continue
+		}
+		var expireAt time.Time
+		if e.ExpireAt != 0 {
+			expireAt = time.Unix(0, e.ExpireAt)
+		}
+		index.putExpiring(e.Key, true, expireAt)
+	}
+	return nil
+}
+
+// orderKey is /<name>, it doesn't collide with the entries which have two namespaces.
+func orderKey(name string) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{base64.RawURLEncoding.EncodeToString([]byte(name))})
+}
+
+func parseOrderKey(k datastore.Key) (string, bool) {
+	parts := k.Namespaces()
+	if len(parts) != 1 {
+		return "", false
+	}
+	name, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return string(name), true
+}
+
+// diskKey is /<name>/<key>, both are encoded since the keys can contain any character.
+func diskKey(name string, key string) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{
+		base64.RawURLEncoding.EncodeToString([]byte(name)),
+		base64.RawURLEncoding.EncodeToString([]byte(key)),
+	})
+}
+
+func parseDiskKey(k datastore.Key) (string, string, bool) {
+	parts := k.Namespaces()
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	name, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", false
+	}
+	key, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", false
+	}
+	return string(name), string(key), true
+}
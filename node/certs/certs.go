@@ -0,0 +1,127 @@
+// Package certs provides the TLS certificates of the API and http file server listeners, loaded from files or
+// obtained from an ACME CA like Let's Encrypt, so a public gateway needs no reverse proxy for encryption.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sao-node/node/config"
+	"sao-node/types"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+var log = logging.Logger("certs")
+
+// Enabled reports whether the listener serves TLS.
+func Enabled(cfg *config.TLS) bool {
+	return cfg.CertFile != "" || cfg.KeyFile != "" || len(cfg.AcmeDomains) > 0
+}
+
+// NewTLSConfig returns the TLS config of a listener, nil if TLS is not configured. The ACME account and the
+// certificates obtained are cached in cacheDir.
+func NewTLSConfig(cfg *config.TLS, cacheDir string) (*tls.Config, error) {
+	if !Enabled(cfg) {
+		return nil, nil
+	}
+
+	// the websocket subscriptions of the API are upgraded from http/1.1, h2 is not offered
+	nextProtos := []string{"http/1.1"}
+	if len(cfg.AcmeDomains) > 0 {
+		if cfg.CertFile != "" || cfg.KeyFile != "" {
+			return nil, types.Wrapf(types.ErrInvalidConfig, "set either the certificate files or the ACME domains")
+		}
+		if !cfg.AcmeAcceptTOS {
+			return nil, types.Wrapf(types.ErrInvalidConfig, "accept the terms of service of the ACME CA with AcmeAcceptTOS")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cacheDir),
+			HostPolicy: autocert.HostWhitelist(cfg.AcmeDomains...),
+			Email:      cfg.AcmeEmail,
+		}
+		if cfg.AcmeDirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: cfg.AcmeDirectoryURL}
+		}
+		return &tls.Config{
+			GetCertificate: manager.GetCertificate,
+			NextProtos:     append(nextProtos, acme.ALPNProto),
+			MinVersion:     tls.VersionTLS12,
+		}, nil
+	}
+
+	pair := &keyPair{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if err := pair.load(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		GetCertificate: pair.getCertificate,
+		NextProtos:     nextProtos,
+		MinVersion:     tls.VersionTLS12,
+	}, nil
+}
+
+// ServerName returns the name the clients verify the certificate against, the first ACME domain or the first
+// DNS name of the certificate file, empty if there is none.
+func ServerName(cfg *config.TLS) string {
+	if len(cfg.AcmeDomains) > 0 {
+		return cfg.AcmeDomains[0]
+	}
+	if cfg.CertFile == "" {
+		return ""
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil || len(cert.Certificate) == 0 {
+		return ""
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || len(leaf.DNSNames) == 0 {
+		return ""
+	}
+	return leaf.DNSNames[0]
+}
+
+// keyPair serves the certificate of the files, the files are loaded again once the certificate file changes,
+// so a renewed certificate is used without restarting the node.
+type keyPair struct {
+	certFile string
+	keyFile  string
+
+	lk      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (p *keyPair) load() error {
+	info, err := os.Stat(p.certFile)
+	if err != nil {
+		return types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		return types.Wrapf(types.ErrInvalidConfig, "load the certificate %s: %v", p.certFile, err)
+	}
+	p.cert = &cert
+	p.modTime = info.ModTime()
+	return nil
+}
+
+func (p *keyPair) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	if info, err := os.Stat(p.certFile); err == nil && !info.ModTime().Equal(p.modTime) {
+		// the certificate and the key are not replaced at once, the old pair is kept until both match
+		if err := p.load(); err != nil {
+			log.Warnf("reload the certificate: %v", err)
+		} else {
+			log.Infof("reloaded the certificate %s", p.certFile)
+		}
+	}
+	return p.cert, nil
+}
@@ -0,0 +1,76 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sao-node/node/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeCert(t *testing.T, dir string, name string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tlsConfig, err := NewTLSConfig(&config.TLS{}, t.TempDir())
+	require.NoError(t, err)
+	require.Nil(t, tlsConfig)
+
+	_, err = NewTLSConfig(&config.TLS{AcmeDomains: []string{"gw.example.com"}}, t.TempDir())
+	require.Error(t, err)
+	tlsConfig, err = NewTLSConfig(&config.TLS{AcmeDomains: []string{"gw.example.com"}, AcmeAcceptTOS: true}, t.TempDir())
+	require.NoError(t, err)
+	require.Contains(t, tlsConfig.NextProtos, "acme-tls/1")
+
+	dir := t.TempDir()
+	writeCert(t, dir, "a.example.com", time.Now().Add(-time.Minute))
+	cfg := &config.TLS{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	require.Equal(t, "a.example.com", ServerName(cfg))
+	tlsConfig, err = NewTLSConfig(cfg, t.TempDir())
+	require.NoError(t, err)
+
+	leafName := func() string {
+		cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+	require.Equal(t, "a.example.com", leafName())
+
+	// a renewed certificate is served without restarting
+	writeCert(t, dir, "b.example.com", time.Now())
+	require.Equal(t, "b.example.com", leafName())
+
+	// a broken certificate keeps the last one
+	require.NoError(t, os.WriteFile(cfg.CertFile, []byte("broken"), 0644))
+	require.Equal(t, "b.example.com", leafName())
+}
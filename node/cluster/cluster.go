@@ -0,0 +1,68 @@
+// Package cluster shares the order states of the gateways serving the same node address behind a load balancer.
+// The orders are kept in a Redis hash in place of the order datastore of the repo, and the order ids are locked in
+// Redis while processed, so two gateways never send the txs of the same order.
+package cluster
+
+import (
+	"runtime"
+	"sao-node/node/config"
+	"sao-node/types"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("cluster")
+
+// Cluster is the Redis connection of the gateway to the others of its cluster.
+type Cluster struct {
+	client redis.UniversalClient
+	prefix string
+	cfg    config.Cluster
+}
+
+// New connects to the Redis of the cluster, the keys are prefixed by KeyPrefix, or by the node address if empty.
+func New(cfg config.Cluster, nodeAddress string) (*Cluster, error) {
+	if cfg.RedisConn == "" {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "no redis address of the gateway cluster")
+	}
+	if cfg.LockTTL < time.Second {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "invalid lock ttl %v", cfg.LockTTL)
+	}
+
+	poolSize := cfg.RedisPoolSize
+	if poolSize < 1 {
+		poolSize = 4 * runtime.NumCPU()
+	}
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = nodeAddress
+	}
+	log.Infof("gateway cluster %s on redis %s", prefix, cfg.RedisConn)
+
+	return &Cluster{
+		client: redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:    strings.Split(cfg.RedisConn, ","),
+			Password: cfg.RedisPassword,
+			PoolSize: poolSize,
+		}),
+		prefix: prefix,
+		cfg:    cfg,
+	}, nil
+}
+
+// Orders returns the datastore of the orders shared by the gateways.
+func (c *Cluster) Orders() *Datastore {
+	return NewDatastore(c.client, c.prefix+":orders")
+}
+
+// Locks returns the locks of the orders shared by the gateways.
+func (c *Cluster) Locks() *Locker {
+	return NewLocker(c.client, c.prefix+":lock:", c.cfg.LockTTL)
+}
+
+func (c *Cluster) Close() error {
+	return c.client.Close()
+}
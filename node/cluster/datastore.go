@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// the entries read by each HSCAN of a query
+const scanCount = 256
+
+// Datastore keeps the entries in a single Redis hash, so they sit in one hash slot of a Redis cluster and a query
+// scans one node.
+type Datastore struct {
+	client redis.UniversalClient
+	hash   string
+}
+
+var _ datastore.Batching = (*Datastore)(nil)
+
+func NewDatastore(client redis.UniversalClient, hash string) *Datastore {
+	return &Datastore{client: client, hash: hash}
+}
+
+func (d *Datastore) Get(ctx context.Context, key datastore.Key) ([]byte, error) {
+	value, err := d.client.HGet(ctx, d.hash, key.String()).Bytes()
+	if err == redis.Nil {
+		return nil, datastore.ErrNotFound
+	}
+	return value, err
+}
+
+func (d *Datastore) Has(ctx context.Context, key datastore.Key) (bool, error) {
+	return d.client.HExists(ctx, d.hash, key.String()).Result()
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key datastore.Key) (int, error) {
+	value, err := d.Get(ctx, key)
+	if err != nil {
+		return -1, err
+	}
+	return len(value), nil
+}
+
+func (d *Datastore) Put(ctx context.Context, key datastore.Key, value []byte) error {
+	return d.client.HSet(ctx, d.hash, key.String(), value).Err()
+}
+
+func (d *Datastore) Delete(ctx context.Context, key datastore.Key) error {
+	return d.client.HDel(ctx, d.hash, key.String()).Err()
+}
+
+// Query scans the whole hash, the entries are filtered and ordered in memory.
+func (d *Datastore) Query(ctx context.Context, q query.Query) (query.Results, error) {
+	var entries []query.Entry
+	// a field may be returned twice while the hash is rehashed
+	seen := make(map[string]bool)
+	var cursor uint64
+	for {
+		fields, next, err := d.client.HScan(ctx, d.hash, cursor, "", scanCount).Result()
+		if err != nil {
+			return nil, err
+		}
+		// HSCAN returns the fields and the values interleaved
+		for i := 0; i+1 < len(fields); i += 2 {
+			if seen[fields[i]] {
+				continue
+			}
+			seen[fields[i]] = true
+			entry := query.Entry{Key: fields[i], Size: len(fields[i+1])}
+			if !q.KeysOnly {
+				entry.Value = []byte(fields[i+1])
+			}
+			entries = append(entries, entry)
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	return query.NaiveQueryApply(q, query.ResultsWithEntries(q, entries)), nil
+}
+
+func (d *Datastore) Batch(_ context.Context) (datastore.Batch, error) {
+	return datastore.NewBasicBatch(d), nil
+}
+
+// Sync is a no-op, the writes are acknowledged by Redis.
+func (d *Datastore) Sync(_ context.Context, _ datastore.Key) error {
+	return nil
+}
+
+// Close is a no-op, the client is closed with the Cluster.
+func (d *Datastore) Close() error {
+	return nil
+}
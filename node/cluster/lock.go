@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sao-node/utils"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// how often a gateway tries again to take a lock held by another
+const lockRetryInterval = 100 * time.Millisecond
+
+// the lock keys are deleted and extended only by the gateway holding them, identified by the token in the key
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+type heldLock struct {
+	token  string
+	cancel context.CancelFunc
+}
+
+// Locker locks the names across the gateways of the cluster by Redis keys expiring after ttl, a lock is
+// extended while held, so the locks of a gateway which stopped are released after ttl. The goroutines of a
+// gateway wait for each other locally, not in Redis.
+type Locker struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+	local  *utils.Maplock
+
+	lk   sync.Mutex
+	held map[string]heldLock
+}
+
+func NewLocker(client redis.UniversalClient, prefix string, ttl time.Duration) *Locker {
+	return &Locker{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+		local:  utils.NewMapLock(),
+		held:   make(map[string]heldLock),
+	}
+}
+
+// Lock waits until the lock of the name is taken, Redis not responding is retried.
+func (l *Locker) Lock(name string) {
+	l.local.Lock(name)
+
+	key := l.prefix + name
+	token := newToken()
+	for {
+		ok, err := l.client.SetNX(context.Background(), key, token, l.ttl).Result()
+		if err != nil {
+			log.Warnf("take lock %s error: %v", name, err)
+		}
+		if ok {
+			break
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go l.extend(ctx, name, key, token)
+
+	l.lk.Lock()
+	l.held[name] = heldLock{token: token, cancel: cancel}
+	l.lk.Unlock()
+}
+
+func (l *Locker) Unlock(name string) error {
+	l.lk.Lock()
+	h, exists := l.held[name]
+	delete(l.held, name)
+	l.lk.Unlock()
+	if !exists {
+		return utils.ErrNoSuchLock
+	}
+
+	h.cancel()
+	err := releaseScript.Run(context.Background(), l.client, []string{l.prefix + name}, h.token).Err()
+	if err != nil {
+		// expires after ttl
+		log.Warnf("release lock %s error: %v", name, err)
+	}
+	return l.local.Unlock(name)
+}
+
+func (l *Locker) extend(ctx context.Context, name string, key string, token string) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			extended, err := extendScript.Run(ctx, l.client, []string{key}, token, l.ttl.Milliseconds()).Int()
+			if err != nil {
+				log.Warnf("extend lock %s error: %v", name, err)
+			} else if extended == 0 {
+				log.Errorf("lock %s expired while held, another gateway may process it", name)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -11,11 +11,17 @@ func DefaultSaoNode() *Node {
 			ListenAddress:    "/ip4/127.0.0.1/tcp/5151/http",
 			Timeout:          30 * time.Second,
 			EnablePermission: false,
+			EnableAuditLog:   true,
 		},
 		Cache: Cache{
-			EnableCache:   true,
-			CacheCapacity: 1000,
-			ContentLimit:  2 * 1024 * 1024,
+			EnableCache:       true,
+			CacheCapacity:     1000,
+			ContentLimit:      2 * 1024 * 1024,
+			DiskCacheCapacity: 1000,
+			CacheTTL:          24 * time.Hour,
+			CacheMaxBytes:     256 * 1024 * 1024,
+			PersistCache:      true,
+			WarmCacheBytes:    64 * 1024 * 1024,
 		},
 		SaoHttpFileServer: SaoHttpFileServer{
 			Enable:                  true,
@@ -23,36 +29,145 @@ func DefaultSaoNode() *Node {
 			HttpFileServerPath:      "~/.sao-node/http-files",
 			EnableHttpFileServerLog: false,
 			TokenPeriod:             24 * time.Hour,
+			EnableUpload:            false,
+			MaxUploadSize:           1 << 30,
+			UploadAllowOrigins:      []string{},
+			EnableShare:             true,
+		},
+		S3: S3{
+			Enable:        false,
+			ListenAddress: "127.0.0.1:5153",
+			Credentials:   []S3Credential{},
+			Duration:      365,
+			Replica:       1,
+			MaxObjectSize: 32 * 1024 * 1024,
+		},
+		WebDav: WebDav{
+			Enable:        false,
+			ListenAddress: "127.0.0.1:5154",
+			KeyName:       "",
+			GroupId:       "",
+			Username:      "",
+			PasswordEnv:   "",
+			Duration:      365,
+			Replica:       1,
+			MaxFileSize:   32 * 1024 * 1024,
+		},
+		Gateway: Gateway{
+			MaxRepublish:            2,
+			ProgressWebhooks:        []string{},
+			ProgressWebhookInterval: 5 * time.Second,
+			AuditSamples:            8,
+			RenewalWindow:           7 * 24 * 60 * 60,
+			Cluster: Cluster{
+				Enable:    false,
+				RedisConn: "127.0.0.1:6379",
+				LockTTL:   30 * time.Second,
+			},
+			Platforms: Platforms{
+				RejectUnlisted: false,
+				List:           []Platform{},
+			},
 		},
 		Storage: Storage{
-			AcceptOrder: true,
-			Ipfs:        []Ipfs{},
+			AcceptOrder:            true,
+			Ipfs:                   []Ipfs{},
+			MinFreeSpace:           1024 * 1024 * 1024,
+			FreeSpaceCheckInterval: time.Minute,
+			AnnounceProviders:      false,
+			ReprovideInterval:      12 * time.Hour,
+			ChunkSize:              256 * 1024,
+			Chunker:                "size",
+			CompleteConfirmations:  2,
+		},
+		Indexer: Indexer{
+			Enable:          false,
+			Urls:            []string{"https://cid.contact"},
+			ListenAddress:   "0.0.0.0:5155",
+			AnnounceAddress: "",
 		},
 		SaoIpfs: SaoIpfs{
 			Enable: true,
 			Repo:   "~/.sao-node/ipfs",
 		},
+		Datastore: Datastore{
+			// small values with frequent reads, leveldb puts and gets them faster, see BenchmarkDatastoreBackends
+			MetadataBackend: "leveldb",
+			// batches of larger shard records, badger keeps values out of the LSM tree and commits them several
+			// times faster
+			OrderBackend:       "badger",
+			OrderWriteInterval: 100 * time.Millisecond,
+		},
+		Keys: Keys{
+			Gateway: "",
+			Payout:  "",
+			Client:  "",
+		},
+		Log: Log{
+			Level:           "",
+			SubsystemLevels: []string{},
+		},
+		Alert: Alert{
+			Webhooks:             []string{},
+			SmtpAddress:          "",
+			SmtpUsername:         "",
+			SmtpPasswordEnv:      "SAO_ALERT_SMTP_PASSWORD",
+			EmailFrom:            "",
+			EmailTo:              []string{},
+			Throttle:             30 * time.Minute,
+			TxFailureThreshold:   3,
+			DiskUsageThreshold:   90,
+			MigrationStallBlocks: 1000,
+			CheckInterval:        5 * time.Minute,
+		},
+		Scheduler: Scheduler{
+			Renewals: Job{Enable: false, Schedule: "0 8 * * *"},
+			GC:       Job{Enable: false, Schedule: "0 3 * * *"},
+			Scrub:    Job{Enable: false, Schedule: "0 4 * * 0"},
+			Audits:   Job{Enable: false, Schedule: "@every 6h"},
+			Earnings: Job{Enable: true, Schedule: "@daily"},
+		},
 	}
 }
 
 func defCommon() Common {
 	return Common{
 		Chain: Chain{
-			Remote:     "http://localhost:26657",
-			WsEndpoint: "/websocket",
+			Remote:         "http://localhost:26657",
+			WsEndpoint:     "/websocket",
+			KeyringBackend: "test",
+			StoreBatchSize: 16,
+			BroadcastMode:  "block",
 		},
 		Libp2p: Libp2p{
 			ListenAddress: []string{
 				"/ip4/0.0.0.0/tcp/5153",
 			},
-			AnnounceAddresses: []string{},
+			AnnounceAddresses:     []string{},
+			MaxStreamsPerProtocol: 64,
+			MaxStreamsPerPeer:     16,
+			StreamQueueTimeout:    10 * time.Second,
+			MaxRequestSize:        1 << 20,
+			MaxContentSize:        1 << 30,
+			StreamReadTimeout:     30 * time.Second,
+			PeerStreamRate:        20,
+			PeerStreamBurst:       100,
+			Relay: Relay{
+				EnableService:   false,
+				MaxReservations: 128,
+				MaxCircuits:     16,
+				Relays:          []string{},
+			},
 		},
 		Transport: Transport{
 			TransportListenAddress: []string{
 				"/ip4/0.0.0.0/udp/5154",
 			},
-			StagingPath:      "~/.sao-node/staging",
-			StagingSapceSize: 32 * 1024 * 1024 * 1024,
+			StagingPath:            "~/.sao-node/staging",
+			StagingSapceSize:       32 * 1024 * 1024 * 1024,
+			StagingCleanInterval:   time.Hour,
+			StagingUploadRetention: 24 * time.Hour,
+			StagingEncryption:      true,
 		},
 		Module: Module{
 			GatewayEnable: true,
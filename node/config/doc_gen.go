@@ -28,6 +28,86 @@ var Doc = map[string][]DocField{
 
 			Comment: ``,
 		},
+		{
+			Name: "EnableAuditLog",
+			Type: "bool",
+
+			Comment: `record every API call with its token, did, dataId and result to audit.log in the repo`,
+		},
+		{
+			Name: "TLS",
+			Type: "TLS",
+
+			Comment: `serve the API over TLS`,
+		},
+	},
+	"Alert": []DocField{
+		{
+			Name: "Webhooks",
+			Type: "[]string",
+
+			Comment: `Webhook URLs the alerts are posted to in JSON`,
+		},
+		{
+			Name: "SmtpAddress",
+			Type: "string",
+
+			Comment: `SMTP server address in host:port format sending the alert emails, empty disables emails`,
+		},
+		{
+			Name: "SmtpUsername",
+			Type: "string",
+
+			Comment: `SMTP username, empty means no authentication`,
+		},
+		{
+			Name: "SmtpPasswordEnv",
+			Type: "string",
+
+			Comment: `Environment variable holding the SMTP password`,
+		},
+		{
+			Name: "EmailFrom",
+			Type: "string",
+
+			Comment: `Sender of the alert emails`,
+		},
+		{
+			Name: "EmailTo",
+			Type: "[]string",
+
+			Comment: `Recipients of the alert emails`,
+		},
+		{
+			Name: "Throttle",
+			Type: "time.Duration",
+
+			Comment: `Minimum interval between two alerts of the same type`,
+		},
+		{
+			Name: "TxFailureThreshold",
+			Type: "int",
+
+			Comment: `Number of consecutive failed chain txs firing an alert`,
+		},
+		{
+			Name: "DiskUsageThreshold",
+			Type: "float64",
+
+			Comment: `Usage percentage of the volumes holding the repo and the staging files firing an alert, 0 disables the check`,
+		},
+		{
+			Name: "MigrationStallBlocks",
+			Type: "int64",
+
+			Comment: `Number of blocks a migration may take before it is reported as stalled, 0 disables the check`,
+		},
+		{
+			Name: "CheckInterval",
+			Type: "time.Duration",
+
+			Comment: `Interval of the disk usage and migration checks`,
+		},
 	},
 	"Cache": []DocField{
 		{
@@ -72,6 +152,45 @@ var Doc = map[string][]DocField{
 
 			Comment: ``,
 		},
+		{
+			Name: "DiskCacheCapacity",
+			Type: "int",
+
+			Comment: `models of each account kept in the repo after dropped from the memory cache, so they survive restarts
+and memory pressure, 0 disables the disk cache. Only for the local cache, not Redis or Memcached`,
+		},
+		{
+			Name: "CacheTTL",
+			Type: "time.Duration",
+
+			Comment: `cached models expire this long after put, 0 keeps them until dropped by the capacity`,
+		},
+		{
+			Name: "CacheMaxBytes",
+			Type: "int",
+
+			Comment: `estimated bytes of the models in the memory cache of each account, 0 for unlimited. Only for the local
+cache, not Redis or Memcached`,
+		},
+		{
+			Name: "PersistCache",
+			Type: "bool",
+
+			Comment: `flush the memory cache to the disk cache on shutdown, needs DiskCacheCapacity`,
+		},
+		{
+			Name: "WarmCacheBytes",
+			Type: "int",
+
+			Comment: `bytes of the most recently used models moved from the disk cache to memory on startup, 0 disables it`,
+		},
+		{
+			Name: "CoherenceTopic",
+			Type: "string",
+
+			Comment: `pub/sub topic shared by the gateways of a cluster, an update through one gateway evicts the cached model
+on the others. Empty disables it`,
+		},
 	},
 	"Chain": []DocField{
 		{
@@ -87,10 +206,76 @@ var Doc = map[string][]DocField{
 			Comment: `websocket endpoint`,
 		},
 		{
-			Name: "AddressPrefix",
+			Name: "KeyringBackend",
+			Type: "string",
+
+			Comment: `keyring backend of the local keys: os, file or test, the --keyring-backend flag takes precedence`,
+		},
+		{
+			Name: "StoreBatchSize",
+			Type: "int",
+
+			Comment: `Max number of MsgStore messages of the gateway bundled in one tx, 0 or 1 disables the bundling`,
+		},
+		{
+			Name: "StoreBatchWindow",
+			Type: "time.Duration",
+
+			Comment: `How long the first MsgStore message of a bundle waits for the others, the messages are always bundled
+while a previous tx is broadcasting`,
+		},
+		{
+			Name: "BroadcastMode",
+			Type: "string",
+
+			Comment: `How the complete txs of the storage nodes are broadcast: block waits for the block including the tx, sync
+waits until the tx is accepted to the mempool and async doesn't wait. The inclusion of the sync and async txs
+is tracked, the shards are completed once it is, the other txs always wait for their blocks`,
+		},
+		{
+			Name: "TxTimeout",
+			Type: "time.Duration",
+
+			Comment: `How long a tx is waited for to be included in a block before it is taken as dropped. 0 waits as long as the
+request in the block mode, and tracks the sync and async txs for 2 minutes`,
+		},
+	},
+	"Cluster": []DocField{
+		{
+			Name: "Enable",
+			Type: "bool",
+
+			Comment: ``,
+		},
+		{
+			Name: "RedisConn",
+			Type: "string",
+
+			Comment: `Redis address, comma separated addresses of a Redis cluster`,
+		},
+		{
+			Name: "RedisPassword",
+			Type: "string",
+
+			Comment: ``,
+		},
+		{
+			Name: "RedisPoolSize",
+			Type: "int",
+
+			Comment: ``,
+		},
+		{
+			Name: "KeyPrefix",
 			Type: "string",
 
-			Comment: `sao chain account prefix`,
+			Comment: `Key prefix of the shared orders and locks, the node address if empty`,
+		},
+		{
+			Name: "LockTTL",
+			Type: "time.Duration",
+
+			Comment: `How long a lock of a gateway which stopped responding is held, the locks are extended while in use`,
 		},
 	},
 	"Common": []DocField{
@@ -119,147 +304,805 @@ var Doc = map[string][]DocField{
 			Comment: ``,
 		},
 	},
-	"Ipfs": []DocField{
+	"Datastore": []DocField{
 		{
-			Name: "Conn",
+			Name: "MetadataBackend",
 			Type: "string",
 
-			Comment: `ipfs connection string`,
+			Comment: `Backend of the metadata datastore: leveldb or badger, pebble is not supported by this build`,
 		},
-	},
-	"Libp2p": []DocField{
 		{
-			Name: "ListenAddress",
-			Type: "[]string",
+			Name: "OrderBackend",
+			Type: "string",
 
-			Comment: `Binding address for the libp2p host - 0 means random port.
-Format: multiaddress; see https://multiformats.io/multiaddr/`,
+			Comment: `Backend of the order datastore: leveldb or badger, pebble is not supported by this build`,
+		},
+		{
+			Name: "OrderWriteInterval",
+			Type: "time.Duration",
+
+			Comment: `Interval the writes to the order datastore are coalesced and committed in one batch, 0 commits every write.
+The writes of the last interval are lost if the node crashes.`,
 		},
 	},
-	"Module": []DocField{
+	"Gateway": []DocField{
 		{
-			Name: "GatewayEnable",
-			Type: "bool",
+			Name: "MaxRepublish",
+			Type: "int",
 
-			Comment: `Enable gateway module`,
+			Comment: `How many times an order expired before its storage nodes completed is stored again with the same proposal,
+toward the storage nodes the chain assigns anew. 0 disables the re-publication`,
 		},
 		{
-			Name: "StorageEnable",
-			Type: "bool",
+			Name: "ProgressWebhooks",
+			Type: "[]string",
 
-			Comment: `Enable storage module`,
+			Comment: `Webhook URLs the progress events of the uploads and the shard distributions are posted to in JSON`,
 		},
-	},
-	"Node": []DocField{
 		{
-			Name: "Cache",
-			Type: "Cache",
+			Name: "ProgressWebhookInterval",
+			Type: "time.Duration",
 
-			Comment: ``,
+			Comment: `Minimum interval between two progress events of a content posted to the webhooks, the last event of the
+upload and of the distribution are always posted`,
 		},
 		{
-			Name: "SaoHttpFileServer",
-			Type: "SaoHttpFileServer",
+			Name: "AuditSamples",
+			Type: "int",
 
-			Comment: ``,
+			Comment: `Number of completed orders picked at random by each run of Scheduler.Audits, each storage node of a sampled
+order is challenged to return its shard, which is checked against the shard cid`,
 		},
 		{
-			Name: "Api",
-			Type: "API",
+			Name: "RenewalWindow",
+			Type: "int64",
 
-			Comment: ``,
+			Comment: `Number of blocks before the end of a completed order it is reported by Scheduler.Renewals, the orders are
+renewed by their owners`,
 		},
 		{
-			Name: "Storage",
-			Type: "Storage",
+			Name: "Cluster",
+			Type: "Cluster",
 
-			Comment: ``,
+			Comment: `Share the orders with the other gateways serving the same node address`,
 		},
 		{
-			Name: "SaoIpfs",
-			Type: "SaoIpfs",
+			Name: "Platforms",
+			Type: "Platforms",
 
-			Comment: ``,
+			Comment: `Quotas and isolation of the platforms sharing the gateway`,
 		},
 	},
-	"SaoHttpFileServer": []DocField{
+	"Indexer": []DocField{
 		{
 			Name: "Enable",
 			Type: "bool",
 
-			Comment: ``,
+			Comment: `Enable the advertisements, requires the in process ipfs`,
 		},
 		{
-			Name: "HttpFileServerAddress",
-			Type: "string",
+			Name: "Urls",
+			Type: "[]string",
 
-			Comment: ``,
+			Comment: `URLs of the indexers the new advertisements are announced to`,
 		},
 		{
-			Name: "HttpFileServerPath",
+			Name: "ListenAddress",
 			Type: "string",
 
-			Comment: ``,
+			Comment: `Listening address of the http publisher the indexers fetch the advertisements from, in host:port format`,
 		},
 		{
-			Name: "EnableHttpFileServerLog",
-			Type: "bool",
+			Name: "AnnounceAddress",
+			Type: "string",
 
-			Comment: ``,
+			Comment: `Public multiaddr of the http publisher, e.g. /dns4/example.com/tcp/5155/http, required when enabled`,
 		},
+	},
+	"Ipfs": []DocField{
 		{
-			Name: "TokenPeriod",
-			Type: "time.Duration",
+			Name: "Conn",
+			Type: "string",
 
-			Comment: ``,
+			Comment: `ipfs connection string`,
 		},
 	},
-	"SaoIpfs": []DocField{
+	"Job": []DocField{
 		{
 			Name: "Enable",
 			Type: "bool",
 
-			Comment: `Enable in process ipfs instance`,
+			Comment: `Whether the job runs, the disabled jobs are listed by 'saonode job scheduled' anyway`,
 		},
 		{
-			Name: "Repo",
+			Name: "Schedule",
 			Type: "string",
 
-			Comment: `ipfs repo path`,
+			Comment: `When the job runs`,
 		},
 	},
-	"Storage": []DocField{
+	"Keys": []DocField{
 		{
-			Name: "AcceptOrder",
-			Type: "bool",
+			Name: "Gateway",
+			Type: "string",
 
-			Comment: `if this node is open to accept order shards`,
+			Comment: `Key signing MsgStore of the orders submitted to the gateway, the node address readies the orders afterwards`,
 		},
 		{
-			Name: "Ipfs",
-			Type: "[]Ipfs",
+			Name: "Payout",
+			Type: "string",
 
-			Comment: ``,
+			Comment: `Key receiving the storage rewards claimed by 'saonode claim'`,
+		},
+		{
+			Name: "Client",
+			Type: "string",
+
+			Comment: `Key signing the client operations run with '--signer client', e.g. 'account send'`,
 		},
 	},
-	"Transport": []DocField{
+	"Libp2p": []DocField{
 		{
-			Name: "TransportListenAddress",
+			Name: "ListenAddress",
 			Type: "[]string",
 
-			Comment: ``,
+			Comment: `Binding address for the libp2p host - 0 means random port.
+Format: multiaddress; see https://multiformats.io/multiaddr/`,
 		},
 		{
-			Name: "StagingPath",
-			Type: "string",
+			Name: "AnnounceAddresses",
+			Type: "[]string",
 
 			Comment: ``,
 		},
 		{
-			Name: "StagingSapceSize",
+			Name: "MaxStreamsPerProtocol",
+			Type: "int",
+
+			Comment: `Max number of inbound streams handled at the same time for each shard protocol, 0 means no limit`,
+		},
+		{
+			Name: "MaxStreamsPerPeer",
+			Type: "int",
+
+			Comment: `Max number of inbound streams handled at the same time for each remote peer, 0 means no limit`,
+		},
+		{
+			Name: "StreamQueueTimeout",
+			Type: "time.Duration",
+
+			Comment: `How long an inbound stream waits for a free slot before being answered as busy`,
+		},
+		{
+			Name: "MaxRequestSize",
 			Type: "int64",
 
-			Comment: ``,
+			Comment: `Max bytes of a request read from an inbound stream, 0 means no limit`,
+		},
+		{
+			Name: "MaxContentSize",
+			Type: "int64",
+
+			Comment: `Max bytes of a request carrying content, a migrated shard or an upload chunk, 0 means no limit`,
+		},
+		{
+			Name: "StreamReadTimeout",
+			Type: "time.Duration",
+
+			Comment: `How long reading a request from an inbound stream may take`,
+		},
+		{
+			Name: "PeerStreamRate",
+			Type: "float64",
+
+			Comment: `Inbound streams a remote peer may open per second, 0 means no limit`,
+		},
+		{
+			Name: "PeerStreamBurst",
+			Type: "int",
+
+			Comment: `Inbound streams a remote peer may open at once before PeerStreamRate applies`,
+		},
+		{
+			Name: "AcceptUnsignedRequests",
+			Type: "bool",
+
+			Comment: `Accept the unsigned shard requests of the peers still speaking the 1.0 protocols, which anyone can forge.
+Only for upgrading the nodes of a network, the requests are rejected by default`,
+		},
+		{
+			Name: "Relay",
+			Type: "Relay",
+
+			Comment: `Circuit relays of the storage nodes behind NAT`,
+		},
+	},
+	"Log": []DocField{
+		{
+			Name: "Level",
+			Type: "string",
+
+			Comment: `Log level of all subsystems, e.g. debug, info, warn, error. Empty means leaving it as it is`,
+		},
+		{
+			Name: "SubsystemLevels",
+			Type: "[]string",
+
+			Comment: `Log levels of specific subsystems in "subsystem=level" format, e.g. "gateway=debug"`,
+		},
+	},
+	"Module": []DocField{
+		{
+			Name: "GatewayEnable",
+			Type: "bool",
+
+			Comment: `Enable gateway module`,
+		},
+		{
+			Name: "StorageEnable",
+			Type: "bool",
+
+			Comment: `Enable storage module`,
+		},
+	},
+	"Node": []DocField{
+		{
+			Name: "Cache",
+			Type: "Cache",
+
+			Comment: ``,
+		},
+		{
+			Name: "SaoHttpFileServer",
+			Type: "SaoHttpFileServer",
+
+			Comment: ``,
+		},
+		{
+			Name: "S3",
+			Type: "S3",
+
+			Comment: ``,
+		},
+		{
+			Name: "WebDav",
+			Type: "WebDav",
+
+			Comment: ``,
+		},
+		{
+			Name: "Api",
+			Type: "API",
+
+			Comment: ``,
+		},
+		{
+			Name: "Gateway",
+			Type: "Gateway",
+
+			Comment: ``,
+		},
+		{
+			Name: "Storage",
+			Type: "Storage",
+
+			Comment: ``,
+		},
+		{
+			Name: "Indexer",
+			Type: "Indexer",
+
+			Comment: ``,
+		},
+		{
+			Name: "SaoIpfs",
+			Type: "SaoIpfs",
+
+			Comment: ``,
+		},
+		{
+			Name: "Datastore",
+			Type: "Datastore",
+
+			Comment: ``,
+		},
+		{
+			Name: "Keys",
+			Type: "Keys",
+
+			Comment: ``,
+		},
+		{
+			Name: "Log",
+			Type: "Log",
+
+			Comment: ``,
+		},
+		{
+			Name: "Alert",
+			Type: "Alert",
+
+			Comment: ``,
+		},
+		{
+			Name: "Scheduler",
+			Type: "Scheduler",
+
+			Comment: ``,
+		},
+	},
+	"Platform": []DocField{
+		{
+			Name: "GroupId",
+			Type: "string",
+
+			Comment: ``,
+		},
+		{
+			Name: "MaxModels",
+			Type: "int",
+
+			Comment: `Models of the platform, 0 is unlimited`,
+		},
+		{
+			Name: "MaxBytes",
+			Type: "int64",
+
+			Comment: `Bytes of the latest commits of the models of the platform, 0 is unlimited`,
+		},
+		{
+			Name: "RequestRate",
+			Type: "float64",
+
+			Comment: `Requests per second of the platform, 0 is unlimited`,
+		},
+		{
+			Name: "RequestBurst",
+			Type: "int",
+
+			Comment: `Requests allowed at once above the rate`,
+		},
+		{
+			Name: "IsolateCache",
+			Type: "bool",
+
+			Comment: `Cache the models of the platform apart from the other platforms, which may use the same aliases`,
+		},
+		{
+			Name: "CacheCapacity",
+			Type: "int",
+
+			Comment: `Models of each account cached for the platform, Cache.CacheCapacity if 0`,
+		},
+		{
+			Name: "StagingPath",
+			Type: "string",
+
+			Comment: `Stage the shards of the platform under this path instead of Transport.StagingPath`,
+		},
+		{
+			Name: "StagingSpaceSize",
+			Type: "int64",
+
+			Comment: `Total bytes under StagingPath, 0 is unlimited`,
+		},
+	},
+	"Platforms": []DocField{
+		{
+			Name: "RejectUnlisted",
+			Type: "bool",
+
+			Comment: `Reject the proposals of the groupIds not listed`,
+		},
+		{
+			Name: "List",
+			Type: "[]Platform",
+
+			Comment: `Quotas of the platforms, the groupIds not listed are not limited`,
+		},
+	},
+	"Relay": []DocField{
+		{
+			Name: "EnableService",
+			Type: "bool",
+
+			Comment: `Serve as a relay, the relayed connections are not limited in time or size since they carry shards`,
+		},
+		{
+			Name: "MaxReservations",
+			Type: "int",
+
+			Comment: `Max number of peers holding a reservation at the same time`,
+		},
+		{
+			Name: "MaxCircuits",
+			Type: "int",
+
+			Comment: `Max number of relayed connections of each peer`,
+		},
+		{
+			Name: "Relays",
+			Type: "[]string",
+
+			Comment: `Relays this node keeps a reservation on, as node addresses of the gateways registered on chain or as
+multiaddrs with the peer id. The addresses through the relays are registered with the peer info of the node`,
+		},
+	},
+	"S3": []DocField{
+		{
+			Name: "Enable",
+			Type: "bool",
+
+			Comment: `Enable the S3 listener, requires the gateway module`,
+		},
+		{
+			Name: "ListenAddress",
+			Type: "string",
+
+			Comment: `Listening address of the S3 requests in host:port format`,
+		},
+		{
+			Name: "Credentials",
+			Type: "[]S3Credential",
+
+			Comment: `Access keys accepted in the signature version 4 requests`,
+		},
+		{
+			Name: "Duration",
+			Type: "int",
+
+			Comment: `Days the objects are stored`,
+		},
+		{
+			Name: "Replica",
+			Type: "int",
+
+			Comment: `Copies of the objects to store`,
+		},
+		{
+			Name: "MaxObjectSize",
+			Type: "int64",
+
+			Comment: `Maximum size of an object in bytes`,
+		},
+	},
+	"S3Credential": []DocField{
+		{
+			Name: "AccessKey",
+			Type: "string",
+
+			Comment: `Access key id of the requests`,
+		},
+		{
+			Name: "SecretKeyEnv",
+			Type: "string",
+
+			Comment: `Environment variable holding the secret access key`,
+		},
+		{
+			Name: "KeyName",
+			Type: "string",
+
+			Comment: `Keyring key name, the did generated from it owns the objects`,
+		},
+	},
+	"SaoHttpFileServer": []DocField{
+		{
+			Name: "Enable",
+			Type: "bool",
+
+			Comment: ``,
+		},
+		{
+			Name: "HttpFileServerAddress",
+			Type: "string",
+
+			Comment: ``,
+		},
+		{
+			Name: "HttpFileServerPath",
+			Type: "string",
+
+			Comment: ``,
+		},
+		{
+			Name: "EnableHttpFileServerLog",
+			Type: "bool",
+
+			Comment: ``,
+		},
+		{
+			Name: "TokenPeriod",
+			Type: "time.Duration",
+
+			Comment: ``,
+		},
+		{
+			Name: "EnableIpfsPath",
+			Type: "bool",
+
+			Comment: `serve the content stored on this node at /ipfs/<cid> without a token, like an ipfs gateway`,
+		},
+		{
+			Name: "EnableUpload",
+			Type: "bool",
+
+			Comment: `accept the multipart file uploads at POST /upload, authorized by a proposal signed by the DID of the owner,
+and the files put to PUT /upload/<token>, authorized by the upload token pre-signed by the owner`,
+		},
+		{
+			Name: "MaxUploadSize",
+			Type: "int64",
+
+			Comment: `Maximum size of an upload request in bytes`,
+		},
+		{
+			Name: "UploadAllowOrigins",
+			Type: "[]string",
+
+			Comment: `Origins of the web pages allowed to upload, * for any, only the same origin if empty`,
+		},
+		{
+			Name: "EnableShare",
+			Type: "bool",
+
+			Comment: `serve the share links at /share/<token>, which load a model on behalf of the owner who signed the token`,
+		},
+		{
+			Name: "TLS",
+			Type: "TLS",
+
+			Comment: `serve the files over TLS, the urls of the files are https then`,
+		},
+	},
+	"SaoIpfs": []DocField{
+		{
+			Name: "Enable",
+			Type: "bool",
+
+			Comment: `Enable in process ipfs instance`,
+		},
+		{
+			Name: "Repo",
+			Type: "string",
+
+			Comment: `ipfs repo path`,
+		},
+	},
+	"Scheduler": []DocField{
+		{
+			Name: "Renewals",
+			Type: "Job",
+
+			Comment: `Reports the completed orders of the gateway ending within Gateway.RenewalWindow blocks`,
+		},
+		{
+			Name: "GC",
+			Type: "Job",
+
+			Comment: `Removes the stored shards of the orders terminated, canceled or expired on chain, or migrated away`,
+		},
+		{
+			Name: "Scrub",
+			Type: "Job",
+
+			Comment: `Verifies the stored shards against their cids, the corrupted ones are reported`,
+		},
+		{
+			Name: "Audits",
+			Type: "Job",
+
+			Comment: `Audits the availability of the completed orders of the gateway, see Gateway.AuditSamples`,
+		},
+		{
+			Name: "Earnings",
+			Type: "Job",
+
+			Comment: `Records the balance and the rewards of the node, the change since the last run is logged as the result`,
+		},
+	},
+	"Storage": []DocField{
+		{
+			Name: "AcceptOrder",
+			Type: "bool",
+
+			Comment: `if this node is open to accept order shards`,
+		},
+		{
+			Name: "Ipfs",
+			Type: "[]Ipfs",
+
+			Comment: ``,
+		},
+		{
+			Name: "MinFreeSpace",
+			Type: "int64",
+
+			Comment: `Free space in bytes below which the node stops accepting new shards and uploads, checked on the staging path,
+the http file server path and the store backends. 0 disables the protection`,
+		},
+		{
+			Name: "FreeSpaceCheckInterval",
+			Type: "time.Duration",
+
+			Comment: `Interval of the free space checks`,
+		},
+		{
+			Name: "AnnounceProviders",
+			Type: "bool",
+
+			Comment: `Announce the provider records of the stored shards on the DHT of the ipfs backends, the public ipfs DHT for
+the in process ipfs, so the content can be retrieved by the ipfs network`,
+		},
+		{
+			Name: "ReprovideInterval",
+			Type: "time.Duration",
+
+			Comment: `Interval of announcing all stored shards again, the DHT provider records expire in 24 hours`,
+		},
+		{
+			Name: "ChunkSize",
+			Type: "int64",
+
+			Comment: `Size in bytes of the blocks the contents larger than it are split into, stored as unixfs files so they can be
+read partially, share the identical blocks and be fetched by the ipfs tools. 0 stores every content as a
+single block`,
+		},
+		{
+			Name: "Chunker",
+			Type: "string",
+
+			Comment: `How the contents are split: size for the blocks of ChunkSize bytes, rabin or buzhash for the blocks cut by
+the content, so an edited file shares the unchanged blocks with its previous commits in the store`,
+		},
+		{
+			Name: "CompleteConfirmations",
+			Type: "int64",
+
+			Comment: `Blocks the complete tx of a shard must be deep before the shard is complete, a tx reorged out of the chain
+before is sent again. 0 takes the shards as complete once the tx is included`,
+		},
+	},
+	"TLS": []DocField{
+		{
+			Name: "CertFile",
+			Type: "string",
+
+			Comment: `PEM encoded certificate chain, reloaded when the file changes`,
+		},
+		{
+			Name: "KeyFile",
+			Type: "string",
+
+			Comment: `PEM encoded private key of the certificate`,
+		},
+		{
+			Name: "AcmeDomains",
+			Type: "[]string",
+
+			Comment: `Domains to obtain the certificate of from the ACME CA, the CA validates them with the TLS-ALPN-01
+challenge, so the listener must be reachable on port 443 of the domains`,
+		},
+		{
+			Name: "AcmeEmail",
+			Type: "string",
+
+			Comment: `Contact email of the ACME account`,
+		},
+		{
+			Name: "AcmeDirectoryURL",
+			Type: "string",
+
+			Comment: `Directory url of the ACME CA, Let's Encrypt if empty`,
+		},
+		{
+			Name: "AcmeAcceptTOS",
+			Type: "bool",
+
+			Comment: `Accept the terms of service of the ACME CA, required to obtain the certificates`,
+		},
+	},
+	"Transport": []DocField{
+		{
+			Name: "TransportListenAddress",
+			Type: "[]string",
+
+			Comment: ``,
+		},
+		{
+			Name: "StagingPath",
+			Type: "string",
+
+			Comment: ``,
+		},
+		{
+			Name: "StagingSapceSize",
+			Type: "int64",
+
+			Comment: `Total bytes of the shards and the uploads under the staging path, 0 is unlimited`,
+		},
+		{
+			Name: "StagingOwnerQuota",
+			Type: "int64",
+
+			Comment: `Bytes under the staging path for an owner, the did of the staged shards or the peer id of the uploads, 0 is
+unlimited`,
+		},
+		{
+			Name: "StagingCleanInterval",
+			Type: "time.Duration",
+
+			Comment: `Interval of removing the staged shards of the orders no longer in progress and the stale uploads, 0
+disables it`,
+		},
+		{
+			Name: "StagingUploadRetention",
+			Type: "time.Duration",
+
+			Comment: `Uploads not committed within this time are removed by the cleaning`,
+		},
+		{
+			Name: "StagingEncryption",
+			Type: "bool",
+
+			Comment: `Encrypt the staged shards with the staging key of the repo keystore, the shards staged in plaintext are
+read anyway`,
+		},
+	},
+	"WebDav": []DocField{
+		{
+			Name: "Enable",
+			Type: "bool",
+
+			Comment: `Enable the WebDAV listener, requires the gateway module`,
+		},
+		{
+			Name: "ListenAddress",
+			Type: "string",
+
+			Comment: `Listening address of the WebDAV requests in host:port format`,
+		},
+		{
+			Name: "KeyName",
+			Type: "string",
+
+			Comment: `Keyring key name, the did generated from it owns the files`,
+		},
+		{
+			Name: "GroupId",
+			Type: "string",
+
+			Comment: `GroupId of the files`,
+		},
+		{
+			Name: "Username",
+			Type: "string",
+
+			Comment: `Username of the basic authentication, empty disables the authentication`,
+		},
+		{
+			Name: "PasswordEnv",
+			Type: "string",
+
+			Comment: `Environment variable holding the password of the basic authentication`,
+		},
+		{
+			Name: "Duration",
+			Type: "int",
+
+			Comment: `Days the files are stored`,
+		},
+		{
+			Name: "Replica",
+			Type: "int",
+
+			Comment: `Copies of the files to store`,
+		},
+		{
+			Name: "MaxFileSize",
+			Type: "int64",
+
+			Comment: `Maximum size of a file in bytes`,
 		},
 	},
 }
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sao-node/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// GetValue returns the value of the field at key, e.g. "Libp2p.MaxStreamsPerPeer", keys are case-insensitive.
+func GetValue(cfg interface{}, key string) (interface{}, error) {
+	v, _, err := findField(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// SetValue sets the field at key in the toml data, value is parsed according to the type of the field.
+// Only the line of the field is rewritten, so the comments and other fields are kept as they are.
+// def is the default config the data is loaded onto, it is used to validate the result.
+func SetValue(data []byte, def interface{}, key string, value string) ([]byte, error) {
+	v, path, err := findField(def, key)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parseValue(v.Type(), value)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "invalid value %s for %s: %v", value, key, err)
+	}
+
+	line, err := encodeLine(path[len(path)-1], parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := replaceLine(string(data), strings.Join(path[:len(path)-1], "."), path[len(path)-1], line)
+
+	cfg := reflect.New(reflect.TypeOf(def).Elem()).Interface()
+	if _, err := toml.Decode(updated, cfg); err != nil {
+		return nil, types.Wrap(types.ErrDecodeConfigFailed, err)
+	}
+	got, _, err := findField(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(got.Interface(), parsed) {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "%s reads back as %v after update", key, got.Interface())
+	}
+
+	return []byte(updated), nil
+}
+
+// findField returns the field at key and its path with the names as they are in the struct.
+func findField(cfg interface{}, key string) (reflect.Value, []string, error) {
+	v := reflect.ValueOf(cfg)
+	var path []string
+	for _, name := range strings.Split(key, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, nil, types.Wrapf(types.ErrInvalidConfig, "unknown config key %s", key)
+		}
+
+		field, found := v.Type().FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+		if !found {
+			return reflect.Value{}, nil, types.Wrapf(types.ErrInvalidConfig, "unknown config key %s", key)
+		}
+		v = v.FieldByIndex(field.Index)
+		path = append(path, field.Name)
+	}
+
+	if v.Kind() == reflect.Struct && v.Type() != durationType {
+		return reflect.Value{}, nil, types.Wrapf(types.ErrInvalidConfig, "%s is a section, not a field", key)
+	}
+	if len(path) < 2 {
+		return reflect.Value{}, nil, types.Wrapf(types.ErrInvalidConfig, "%s is not in a section", key)
+	}
+	return v, path, nil
+}
+
+func parseValue(t reflect.Type, value string) (interface{}, error) {
+	if t == durationType {
+		return time.ParseDuration(value)
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s is not supported, edit config.toml instead", t)
+		}
+		// either a toml array, or comma separated values
+		var items []string
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var holder struct{ V []string }
+			if _, err := toml.Decode("V = "+value, &holder); err != nil {
+				return nil, err
+			}
+			items = holder.V
+		} else if value != "" {
+			for _, item := range strings.Split(value, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+		}
+		if items == nil {
+			items = []string{}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return nil, fmt.Errorf("%s is not supported, edit config.toml instead", t)
+	}
+	return v.Interface(), nil
+}
+
+func encodeLine(name string, value interface{}) (string, error) {
+	if d, ok := value.(time.Duration); ok {
+		value = d.String()
+	}
+
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(map[string]interface{}{name: value}); err != nil {
+		return "", types.Wrap(types.ErrEncodeConfigFailed, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// replaceLine replaces the line of the field in the section, commented out or not. The line is
+// appended to the section, or a new section if either is missing.
+func replaceLine(data string, section string, name string, line string) string {
+	lines := strings.Split(data, "\n")
+
+	current := ""
+	sectionEnd := -1
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "[") {
+			current = strings.Trim(trimmed, "[]")
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if trimmed != "" {
+			sectionEnd = i
+		}
+
+		field := strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+		if k, _, found := strings.Cut(field, "="); found && strings.TrimSpace(k) == name {
+			pad := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+			lines[i] = pad + line
+			return strings.Join(lines, "\n")
+		}
+	}
+
+	if sectionEnd < 0 {
+		for i, l := range lines {
+			if strings.TrimSpace(l) == "["+section+"]" {
+				sectionEnd = i
+			}
+		}
+	}
+	if sectionEnd < 0 {
+		return strings.TrimRight(data, "\n") + "\n\n[" + section + "]\n  " + line + "\n"
+	}
+
+	lines = append(lines[:sectionEnd+1], append([]string{"  " + line}, lines[sectionEnd+1:]...)...)
+	return strings.Join(lines, "\n")
+}
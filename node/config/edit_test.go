@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetValue(t *testing.T) {
+	data, err := ConfigUpdate(DefaultSaoNode(), DefaultSaoNode(), true)
+	require.NoError(t, err)
+
+	data, err = SetValue(data, DefaultSaoNode(), "libp2p.maxstreamsperpeer", "4")
+	require.NoError(t, err)
+	data, err = SetValue(data, DefaultSaoNode(), "Libp2p.StreamQueueTimeout", "1m")
+	require.NoError(t, err)
+	data, err = SetValue(data, DefaultSaoNode(), "Log.SubsystemLevels", "gateway=debug, transport=warn")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "# Max number of inbound streams handled at the same time for each remote peer")
+
+	_, err = SetValue(data, DefaultSaoNode(), "Libp2p.MaxStreamsPerPeer", "four")
+	require.Error(t, err)
+	_, err = SetValue(data, DefaultSaoNode(), "Libp2p.Unknown", "1")
+	require.Error(t, err)
+	_, err = SetValue(data, DefaultSaoNode(), "Libp2p", "1")
+	require.Error(t, err)
+
+	cfg, err := FromReader(bytes.NewReader(data), DefaultSaoNode())
+	require.NoError(t, err)
+
+	v, err := GetValue(cfg, "Libp2p.MaxStreamsPerPeer")
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+	v, err = GetValue(cfg, "Libp2p.StreamQueueTimeout")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, v)
+	v, err = GetValue(cfg, "Log.SubsystemLevels")
+	require.NoError(t, err)
+	require.Equal(t, []string{"gateway=debug", "transport=warn"}, v)
+}
@@ -14,10 +14,114 @@ type Node struct {
 
 	Cache             Cache
 	SaoHttpFileServer SaoHttpFileServer
+	S3                S3
+	WebDav            WebDav
 	Api               API
 
-	Storage Storage
-	SaoIpfs SaoIpfs
+	Gateway   Gateway
+	Storage   Storage
+	Indexer   Indexer
+	SaoIpfs   SaoIpfs
+	Datastore Datastore
+	Keys      Keys
+
+	Log       Log
+	Alert     Alert
+	Scheduler Scheduler
+}
+
+// Scheduler contains configs of the jobs run periodically by the node. The schedules are cron expressions of
+// minute, hour, day of month, month and day of week, e.g. "30 2 * * *", or the descriptors @hourly, @daily,
+// @weekly, @monthly and "@every <duration>"
+type Scheduler struct {
+	// Reports the completed orders of the gateway ending within Gateway.RenewalWindow blocks
+	Renewals Job
+	// Removes the stored shards of the orders terminated, canceled or expired on chain, or migrated away
+	GC Job
+	// Verifies the stored shards against their cids, the corrupted ones are reported
+	Scrub Job
+	// Audits the availability of the completed orders of the gateway, see Gateway.AuditSamples
+	Audits Job
+	// Records the balance and the rewards of the node, the change since the last run is logged as the result
+	Earnings Job
+}
+
+type Job struct {
+	// Whether the job runs, the disabled jobs are listed by 'saonode job scheduled' anyway
+	Enable bool
+	// When the job runs
+	Schedule string
+}
+
+// Alert contains configs for the notifications of failures, which are sent to the webhooks and by email
+type Alert struct {
+	// Webhook URLs the alerts are posted to in JSON
+	Webhooks []string
+	// SMTP server address in host:port format sending the alert emails, empty disables emails
+	SmtpAddress string
+	// SMTP username, empty means no authentication
+	SmtpUsername string
+	// Environment variable holding the SMTP password
+	SmtpPasswordEnv string
+	// Sender of the alert emails
+	EmailFrom string
+	// Recipients of the alert emails
+	EmailTo []string
+	// Minimum interval between two alerts of the same type
+	Throttle time.Duration
+	// Number of consecutive failed chain txs firing an alert
+	TxFailureThreshold int
+	// Usage percentage of the volumes holding the repo and the staging files firing an alert, 0 disables the check
+	DiskUsageThreshold float64
+	// Number of blocks a migration may take before it is reported as stalled, 0 disables the check
+	MigrationStallBlocks int64
+	// Interval of the disk usage and migration checks
+	CheckInterval time.Duration
+}
+
+// Keys contains the keyring key names signing the chain operations of each role, the role names are accepted by
+// --signer as well. Empty keys fall back to the node address. MsgComplete is always signed by the node address,
+// since the chain only accepts it from the shard provider.
+type Keys struct {
+	// Key signing MsgStore of the orders submitted to the gateway, the node address readies the orders afterwards
+	Gateway string
+	// Key receiving the storage rewards claimed by 'saonode claim'
+	Payout string
+	// Key signing the client operations run with '--signer client', e.g. 'account send'
+	Client string
+}
+
+// Profile returns the key name of the signing profile, or false if name is not a profile.
+func (k Keys) Profile(name string) (string, bool) {
+	switch name {
+	case "gateway":
+		return k.Gateway, true
+	case "payout":
+		return k.Payout, true
+	case "client":
+		return k.Client, true
+	default:
+		return "", false
+	}
+}
+
+// Log contains configs for logging, reloaded on SIGHUP
+type Log struct {
+	// Log level of all subsystems, e.g. debug, info, warn, error. Empty means leaving it as it is
+	Level string
+	// Log levels of specific subsystems in "subsystem=level" format, e.g. "gateway=debug"
+	SubsystemLevels []string
+}
+
+// Datastore contains configs for the repo datastores, run 'saonode repo migrate' after changing the backends
+type Datastore struct {
+	// Backend of the metadata datastore: leveldb or badger, pebble is not supported by this build
+	MetadataBackend string
+	// Backend of the order datastore: leveldb or badger, pebble is not supported by this build
+	OrderBackend string
+	// Interval the writes to the order datastore are coalesced and committed in one batch, 0 commits every write.
+	// The writes of the last interval are lost if the node crashes.
+	OrderWriteInterval time.Duration
 }
 
 type SaoHttpFileServer struct {
@@ -26,6 +130,82 @@ type SaoHttpFileServer struct {
 	HttpFileServerPath      string
 	EnableHttpFileServerLog bool
 	TokenPeriod             time.Duration
+	// serve the content stored on this node at /ipfs/<cid> without a token, like an ipfs gateway
+	EnableIpfsPath bool
+	// accept the multipart file uploads at POST /upload, authorized by a proposal signed by the DID of the owner,
+	// and the files put to PUT /upload/<token>, authorized by the upload token pre-signed by the owner
+	EnableUpload bool
+	// Maximum size of an upload request in bytes
+	MaxUploadSize int64
+	// Origins of the web pages allowed to upload, * for any, only the same origin if empty
+	UploadAllowOrigins []string
+	// serve the share links at /share/<token>, which load a model on behalf of the owner who signed the token
+	EnableShare bool
+	// serve the files over TLS, the urls of the files are https then
+	TLS TLS
+}
+
+// S3 contains configs for the S3 compatible frontend of the gateway, the buckets are groupIds and the objects are
+// file models aliased by their keys. Only path-style PUT, GET, HEAD and DELETE of objects are supported
+type S3 struct {
+	// Enable the S3 listener, requires the gateway module
+	Enable bool
+	// Listening address of the S3 requests in host:port format
+	ListenAddress string
+	// Access keys accepted in the signature version 4 requests
+	Credentials []S3Credential
+	// Days the objects are stored
+	Duration int
+	// Copies of the objects to store
+	Replica int
+	// Maximum size of an object in bytes
+	MaxObjectSize int64
+}
+
+type S3Credential struct {
+	// Access key id of the requests
+	AccessKey string
+	// Environment variable holding the secret access key
+	SecretKeyEnv string
+	// Keyring key name, the did generated from it owns the objects
+	KeyName string
+}
+
+// WebDav contains configs for the WebDAV frontend of the gateway, exposing the file models of a did in a groupId as
+// a flat directory. The chain can't list the models of a did, so the directory lists the files written through
+// WebDAV only, the other file models are still accessible by their names
+type WebDav struct {
+	// Enable the WebDAV listener, requires the gateway module
+	Enable bool
+	// Listening address of the WebDAV requests in host:port format
+	ListenAddress string
+	// Keyring key name, the did generated from it owns the files
+	KeyName string
+	// GroupId of the files
+	GroupId string
+	// Username of the basic authentication, empty disables the authentication
+	Username string
+	// Environment variable holding the password of the basic authentication
+	PasswordEnv string
+	// Days the files are stored
+	Duration int
+	// Copies of the files to store
+	Replica int
+	// Maximum size of a file in bytes
+	MaxFileSize int64
+}
+
+// Indexer contains configs for advertising the stored shards to the network indexers (IPNI), e.g. cid.contact. The
+// advertisements are signed by the in process ipfs node, which serves the shards over bitswap
+type Indexer struct {
+	// Enable the advertisements, requires the in process ipfs
+	Enable bool
+	// URLs of the indexers the new advertisements are announced to
+	Urls []string
+	// Listening address of the http publisher the indexers fetch the advertisements from, in host:port format
+	ListenAddress string
+	// Public multiaddr of the http publisher, e.g. /dns4/example.com/tcp/5155/http, required when enabled
+	AnnounceAddress string
 }
 
 // SaoIpfs contains configs for inprocess ipfs
@@ -42,6 +222,30 @@ type Storage struct {
 	// if this node is open to accept order shards
 	AcceptOrder bool
 	Ipfs        []Ipfs
+
+	// Free space in bytes below which the node stops accepting new shards and uploads, checked on the staging path,
+	// the http file server path and the store backends. 0 disables the protection
+	MinFreeSpace int64
+	// Interval of the free space checks
+	FreeSpaceCheckInterval time.Duration
+
+	// Announce the provider records of the stored shards on the DHT of the ipfs backends, the public ipfs DHT for
+	// the in process ipfs, so the content can be retrieved by the ipfs network
+	AnnounceProviders bool
+	// Interval of announcing all stored shards again, the DHT provider records expire in 24 hours
+	ReprovideInterval time.Duration
+
+	// Size in bytes of the blocks the contents larger than it are split into, stored as unixfs files so they can be
+	// read partially, share the identical blocks and be fetched by the ipfs tools. 0 stores every content as a
+	// single block
+	ChunkSize int64
+	// How the contents are split: size for the blocks of ChunkSize bytes, rabin or buzhash for the blocks cut by
+	// the content, so an edited file shares the unchanged blocks with its previous commits in the store
+	Chunker string
+
+	// Blocks the complete tx of a shard must be deep before the shard is complete, a tx reorged out of the chain
+	// before is sent again. 0 takes the shards as complete once the tx is included
+	CompleteConfirmations int64
 }
 
 // Ipfs contains configs for backend ipfs
@@ -51,6 +255,72 @@ type Ipfs struct {
 	Conn string
 }
 
+// Gateway contains configs for the orders handled by the gateway
+type Gateway struct {
+	// How many times an order expired before its storage nodes completed is stored again with the same proposal,
+	// toward the storage nodes the chain assigns anew. 0 disables the re-publication
+	MaxRepublish int
+	// Webhook URLs the progress events of the uploads and the shard distributions are posted to in JSON
+	ProgressWebhooks []string
+	// Minimum interval between two progress events of a content posted to the webhooks, the last event of the
+	// upload and of the distribution are always posted
+	ProgressWebhookInterval time.Duration
+	// Number of completed orders picked at random by each run of Scheduler.Audits, each storage node of a sampled
+	// order is challenged to return its shard, which is checked against the shard cid
+	AuditSamples int
+	// Number of blocks before the end of a completed order it is reported by Scheduler.Renewals, the orders are
+	// renewed by their owners
+	RenewalWindow int64
+	// Share the orders with the other gateways serving the same node address
+	Cluster Cluster
+	// Quotas and isolation of the platforms sharing the gateway
+	Platforms Platforms
+}
+
+// Platforms contains configs for sharing the gateway among applications, a platform is the groupId of the
+// proposals. The quotas only count the models created through this gateway, until they are deleted through it
+type Platforms struct {
+	// Reject the proposals of the groupIds not listed
+	RejectUnlisted bool
+	// Quotas of the platforms, the groupIds not listed are not limited
+	List []Platform
+}
+
+type Platform struct {
+	GroupId string
+	// Models of the platform, 0 is unlimited
+	MaxModels int
+	// Bytes of the latest commits of the models of the platform, 0 is unlimited
+	MaxBytes int64
+	// Requests per second of the platform, 0 is unlimited
+	RequestRate float64
+	// Requests allowed at once above the rate
+	RequestBurst int
+	// Cache the models of the platform apart from the other platforms, which may use the same aliases
+	IsolateCache bool
+	// Models of each account cached for the platform, Cache.CacheCapacity if 0
+	CacheCapacity int
+	// Stage the shards of the platform under this path instead of Transport.StagingPath
+	StagingPath string
+	// Total bytes under StagingPath, 0 is unlimited
+	StagingSpaceSize int64
+}
+
+// Cluster contains configs for the gateways serving the same node address behind a load balancer. The order
+// states are kept in Redis and each order is locked there while processed, so only one gateway publishes its
+// txs. The gateways must share the staging path, e.g. over NFS
+type Cluster struct {
+	Enable bool
+	// Redis address, comma separated addresses of a Redis cluster
+	RedisConn     string
+	RedisPassword string
+	RedisPoolSize int
+	// Key prefix of the shared orders and locks, the node address if empty
+	KeyPrefix string
+	// How long a lock of a gateway which stopped responding is held, the locks are extended while in use
+	LockTTL time.Duration
+}
+
 // Module contains configs for Submodules
 type Module struct {
 
@@ -70,6 +340,29 @@ type API struct {
 	Timeout time.Duration
 
 	EnablePermission bool
+
+	// record every API call with its token, did, dataId and result to audit.log in the repo
+	EnableAuditLog bool
+	// serve the API over TLS
+	TLS TLS
+}
+
+// TLS contains the certificate of a listener, it serves plain http if neither the certificate files nor the
+// ACME domains are set
+type TLS struct {
+	// PEM encoded certificate chain, reloaded when the file changes
+	CertFile string
+	// PEM encoded private key of the certificate
+	KeyFile string
+	// Domains to obtain the certificate of from the ACME CA, the CA validates them with the TLS-ALPN-01
+	// challenge, so the listener must be reachable on port 443 of the domains
+	AcmeDomains []string
+	// Contact email of the ACME account
+	AcmeEmail string
+	// Directory url of the ACME CA, Let's Encrypt if empty
+	AcmeDirectoryURL string
+	// Accept the terms of service of the ACME CA, required to obtain the certificates
+	AcmeAcceptTOS bool
 }
 
 // Chain contains configs for sao chain information
@@ -80,6 +373,23 @@ type Chain struct {
 
 	// websocket endpoint
 	WsEndpoint string
+
+	// keyring backend of the local keys: os, file or test, the --keyring-backend flag takes precedence
+	KeyringBackend string
+
+	// Max number of MsgStore messages of the gateway bundled in one tx, 0 or 1 disables the bundling
+	StoreBatchSize int
+	// How long the first MsgStore message of a bundle waits for the others, the messages are always bundled
+	// while a previous tx is broadcasting
+	StoreBatchWindow time.Duration
+
+	// How the complete txs of the storage nodes are broadcast: block waits for the block including the tx, sync
+	// waits until the tx is accepted to the mempool and async doesn't wait. The inclusion of the sync and async txs
+	// is tracked, the shards are completed once it is, the other txs always wait for their blocks
+	BroadcastMode string
+	// How long a tx is waited for to be included in a block before it is taken as dropped. 0 waits as long as the
+	// request in the block mode, and tracks the sync and async txs for 2 minutes
+	TxTimeout time.Duration
 }
 
 // Libp2p contains configs for libp2p
@@ -88,6 +398,42 @@ type Libp2p struct {
 	// Format: multiaddress; see https://multiformats.io/multiaddr/
 	ListenAddress     []string
 	AnnounceAddresses []string
+
+	// Max number of inbound streams handled at the same time for each shard protocol, 0 means no limit
+	MaxStreamsPerProtocol int
+	// Max number of inbound streams handled at the same time for each remote peer, 0 means no limit
+	MaxStreamsPerPeer int
+	// How long an inbound stream waits for a free slot before being answered as busy
+	StreamQueueTimeout time.Duration
+	// Max bytes of a request read from an inbound stream, 0 means no limit
+	MaxRequestSize int64
+	// Max bytes of a request carrying content, a migrated shard or an upload chunk, 0 means no limit
+	MaxContentSize int64
+	// How long reading a request from an inbound stream may take
+	StreamReadTimeout time.Duration
+	// Inbound streams a remote peer may open per second, 0 means no limit
+	PeerStreamRate float64
+	// Inbound streams a remote peer may open at once before PeerStreamRate applies
+	PeerStreamBurst int
+	// Accept the unsigned shard requests of the peers still speaking the 1.0 protocols, which anyone can forge.
+	// Only for upgrading the nodes of a network, the requests are rejected by default
+	AcceptUnsignedRequests bool
+	// Circuit relays of the storage nodes behind NAT
+	Relay Relay
+}
+
+// Relay contains configs of the libp2p circuit relays. The gateways serving as relays forward the shard
+// traffic to the storage nodes behind NAT which keep a reservation on them
+type Relay struct {
+	// Serve as a relay, the relayed connections are not limited in time or size since they carry shards
+	EnableService bool
+	// Max number of peers holding a reservation at the same time
+	MaxReservations int
+	// Max number of relayed connections of each peer
+	MaxCircuits int
+	// Relays this node keeps a reservation on, as node addresses of the gateways registered on chain or as
+	// multiaddrs with the peer id. The addresses through the relays are registered with the peer info of the node
+	Relays []string
 }
 
 type Cache struct {
@@ -98,10 +444,37 @@ type Cache struct {
 	RedisPassword string
 	RedisPoolSize int
 	MemcachedConn string
+	// models of each account kept in the repo after dropped from the memory cache, so they survive restarts
+	// and memory pressure, 0 disables the disk cache. Only for the local cache, not Redis or Memcached
+	DiskCacheCapacity int
+	// cached models expire this long after put, 0 keeps them until dropped by the capacity
+	CacheTTL time.Duration
+	// estimated bytes of the models in the memory cache of each account, 0 for unlimited. Only for the local
+	// cache, not Redis or Memcached
+	CacheMaxBytes int
+	// flush the memory cache to the disk cache on shutdown, needs DiskCacheCapacity
+	PersistCache bool
+	// bytes of the most recently used models moved from the disk cache to memory on startup, 0 disables it
+	WarmCacheBytes int
+	// pub/sub topic shared by the gateways of a cluster, an update through one gateway evicts the cached model
+	// on the others. Empty disables it
+	CoherenceTopic string
 }
 
 type Transport struct {
 	TransportListenAddress []string
 	StagingPath            string
-	StagingSapceSize       int64
+	// Total bytes of the shards and the uploads under the staging path, 0 is unlimited
+	StagingSapceSize int64
+	// Bytes under the staging path for an owner, the did of the staged shards or the peer id of the uploads, 0 is
+	// unlimited
+	StagingOwnerQuota int64
+	// Interval of removing the staged shards of the orders no longer in progress and the stale uploads, 0
+	// disables it
+	StagingCleanInterval time.Duration
+	// Uploads not committed within this time are removed by the cleaning
+	StagingUploadRetention time.Duration
+	// Encrypt the staged shards with the staging key of the repo keystore, the shards staged in plaintext are
+	// read anyway
+	StagingEncryption bool
 }
@@ -0,0 +1,410 @@
+package dav
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sao-node/types"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/net/webdav"
+)
+
+// indexPrefix keys the files written through WebDAV by groupId and name, so the directory can be listed
+const indexPrefix = "/webdav"
+
+type files interface {
+	Put(ctx context.Context, groupId string, name string, content []byte) (string, error)
+	Get(ctx context.Context, groupId string, name string) (string, []byte, error)
+	Delete(ctx context.Context, groupId string, name string) error
+}
+
+type entry struct {
+	// empty for the empty files, which can't be stored as models
+	DataId  string
+	Size    int64
+	ModTime time.Time
+}
+
+// fileSystem is a flat directory of the files in a groupId.
+type fileSystem struct {
+	files   files
+	groupId string
+	maxSize int64
+	ds      datastore.Batching
+}
+
+func newFileSystem(files files, groupId string, maxSize int64, ds datastore.Batching) *fileSystem {
+	return &fileSystem{
+		files:   files,
+		groupId: groupId,
+		maxSize: maxSize,
+		ds:      ds,
+	}
+}
+
+// fileName returns the name of the file at p, empty for the root directory. The subdirectories are not supported.
+func fileName(p string) (string, error) {
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if strings.Contains(name, "/") {
+		return "", os.ErrNotExist
+	}
+	return name, nil
+}
+
+func (fs *fileSystem) key(name string) datastore.Key {
+	return datastore.NewKey(indexPrefix).ChildString(fs.groupId).ChildString(name)
+}
+
+func (fs *fileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+func (fs *fileSystem) Stat(ctx context.Context, p string) (os.FileInfo, error) {
+	name, err := fileName(p)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return &fileInfo{dir: true, modTime: time.Now()}, nil
+	}
+	e, err := fs.entry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: name, size: e.Size, modTime: e.ModTime}, nil
+}
+
+// entry returns the index entry of the file, the files written by the other clients are indexed on the first access.
+func (fs *fileSystem) entry(ctx context.Context, name string) (*entry, error) {
+	data, err := fs.ds.Get(ctx, fs.key(name))
+	if err == nil {
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+		return &e, nil
+	} else if err != datastore.ErrNotFound {
+		return nil, types.Wrap(types.ErrGetFailed, err)
+	}
+
+	dataId, content, err := fs.files.Get(ctx, fs.groupId, name)
+	if errors.Is(err, types.ErrNotFound) {
+		return nil, os.ErrNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	e := &entry{DataId: dataId, Size: int64(len(content)), ModTime: time.Now()}
+	return e, fs.index(ctx, name, e)
+}
+
+func (fs *fileSystem) index(ctx context.Context, name string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	err = fs.ds.Put(ctx, fs.key(name), data)
+	if err != nil {
+		return types.Wrap(types.ErrStoreFailed, err)
+	}
+	return nil
+}
+
+func (fs *fileSystem) content(ctx context.Context, name string, e *entry) ([]byte, error) {
+	if e.DataId == "" {
+		return nil, nil
+	}
+	_, content, err := fs.files.Get(ctx, fs.groupId, name)
+	return content, err
+}
+
+// save stores the content as the file name, the existing file is replaced.
+func (fs *fileSystem) save(ctx context.Context, name string, content []byte) error {
+	e := &entry{Size: int64(len(content)), ModTime: time.Now()}
+	if len(content) == 0 {
+		if err := fs.files.Delete(ctx, fs.groupId, name); err != nil && !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+	} else {
+		dataId, err := fs.files.Put(ctx, fs.groupId, name, content)
+		if err != nil {
+			return err
+		}
+		e.DataId = dataId
+	}
+	return fs.index(ctx, name, e)
+}
+
+func (fs *fileSystem) OpenFile(ctx context.Context, p string, flag int, perm os.FileMode) (webdav.File, error) {
+	name, err := fileName(p)
+	if err != nil {
+		return nil, err
+	}
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0
+	if name == "" {
+		if write {
+			return nil, os.ErrPermission
+		}
+		return &dir{ctx: ctx, fs: fs}, nil
+	}
+
+	e, err := fs.entry(ctx, name)
+	if err == os.ErrNotExist {
+		if flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+		e = nil
+	} else if err != nil {
+		return nil, err
+	} else if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, os.ErrExist
+	}
+
+	f := &file{ctx: ctx, fs: fs, name: name, write: write}
+	if e != nil && flag&os.O_TRUNC == 0 {
+		f.data, err = fs.content(ctx, name, e)
+		if err != nil {
+			return nil, err
+		}
+		f.modTime = e.ModTime
+	} else {
+		f.dirty = true
+	}
+	if flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.data))
+	}
+	return f, nil
+}
+
+func (fs *fileSystem) RemoveAll(ctx context.Context, p string) error {
+	name, err := fileName(p)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return os.ErrPermission
+	}
+
+	err = fs.files.Delete(ctx, fs.groupId, name)
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return err
+	}
+	err = fs.ds.Delete(ctx, fs.key(name))
+	if err != nil {
+		return types.Wrap(types.ErrRemoveFailed, err)
+	}
+	return nil
+}
+
+// Rename stores the content as the new name, the models can't be renamed.
+func (fs *fileSystem) Rename(ctx context.Context, oldPath string, newPath string) error {
+	oldName, err := fileName(oldPath)
+	if err != nil {
+		return err
+	}
+	newName, err := fileName(newPath)
+	if err != nil {
+		return err
+	}
+	if oldName == "" || newName == "" {
+		return os.ErrPermission
+	}
+
+	e, err := fs.entry(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	content, err := fs.content(ctx, oldName, e)
+	if err != nil {
+		return err
+	}
+	if err := fs.save(ctx, newName, content); err != nil {
+		return err
+	}
+	return fs.RemoveAll(ctx, oldPath)
+}
+
+// file buffers the content, the written content is stored on Close.
+type file struct {
+	ctx     context.Context
+	fs      *fileSystem
+	name    string
+	data    []byte
+	offset  int64
+	modTime time.Time
+	write   bool
+	dirty   bool
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.offset >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if !f.write {
+		return 0, os.ErrPermission
+	}
+	end := f.offset + int64(len(p))
+	if end > f.fs.maxSize {
+		// nothing is stored on Close after a failed write
+		f.write, f.dirty = false, false
+		return 0, types.Wrapf(types.ErrInvalidParameters, "%s exceeds %d bytes", f.name, f.fs.maxSize)
+	}
+	if end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	copy(f.data[f.offset:], p)
+	f.offset = end
+	f.dirty = true
+	return len(p), nil
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	default:
+		return 0, os.ErrInvalid
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	modTime := f.modTime
+	if f.dirty {
+		modTime = time.Now()
+	}
+	return &fileInfo{name: f.name, size: int64(len(f.data)), modTime: modTime}, nil
+}
+
+func (f *file) Close() error {
+	if !f.dirty {
+		return nil
+	}
+	f.dirty = false
+	return f.fs.save(f.ctx, f.name, f.data)
+}
+
+// dir is the root directory listing the indexed files.
+type dir struct {
+	ctx     context.Context
+	fs      *fileSystem
+	entries []os.FileInfo
+	listed  bool
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.listed {
+		prefix := datastore.NewKey(indexPrefix).ChildString(d.fs.groupId)
+		results, err := d.fs.ds.Query(d.ctx, query.Query{Prefix: prefix.String()})
+		if err != nil {
+			return nil, types.Wrap(types.ErrGetFailed, err)
+		}
+		defer results.Close()
+		for r := range results.Next() {
+			if r.Error != nil {
+				return nil, types.Wrap(types.ErrGetFailed, r.Error)
+			}
+			var e entry
+			if err := json.Unmarshal(r.Value, &e); err != nil {
+				return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+			}
+			d.entries = append(d.entries, &fileInfo{name: datastore.RawKey(r.Key).BaseNamespace(), size: e.Size, modTime: e.ModTime})
+		}
+		d.listed = true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+func (d *dir) Stat() (os.FileInfo, error) {
+	return &fileInfo{dir: true, modTime: time.Now()}, nil
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi *fileInfo) Name() string {
+	if fi.dir {
+		return "/"
+	}
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.dir
+}
+
+func (fi *fileInfo) Sys() interface{} {
+	return nil
+}
@@ -0,0 +1,87 @@
+package dav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+)
+
+type memFiles map[string][]byte
+
+func (m memFiles) Put(ctx context.Context, groupId string, name string, content []byte) (string, error) {
+	m[groupId+"/"+name] = content
+	return "data-" + name, nil
+}
+
+func (m memFiles) Get(ctx context.Context, groupId string, name string) (string, []byte, error) {
+	content, ok := m[groupId+"/"+name]
+	if !ok {
+		return "", nil, types.Wrapf(types.ErrNotFound, "file %s", name)
+	}
+	return "data-" + name, content, nil
+}
+
+func (m memFiles) Delete(ctx context.Context, groupId string, name string) error {
+	if _, ok := m[groupId+"/"+name]; !ok {
+		return types.Wrapf(types.ErrNotFound, "file %s", name)
+	}
+	delete(m, groupId+"/"+name)
+	return nil
+}
+
+func TestFileSystem(t *testing.T) {
+	files := memFiles{"g/other.txt": []byte("stored by the client")}
+	handler := &webdav.Handler{
+		FileSystem: newFileSystem(files, "g", 16, dssync.MutexWrap(datastore.NewMapDatastore())),
+		LockSystem: webdav.NewMemLS(),
+	}
+	do := func(method string, path string, body string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPut, "/a.txt", "hello").Code)
+	require.Equal(t, []byte("hello"), files["g/a.txt"])
+	w := do(http.MethodGet, "/a.txt", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "hello", w.Body.String())
+
+	// the files of the other clients are accessible by name
+	w = do(http.MethodGet, "/other.txt", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "stored by the client", w.Body.String())
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/missing.txt", "").Code)
+
+	require.NotEqual(t, http.StatusCreated, do(http.MethodPut, "/large.txt", strings.Repeat("x", 17)).Code)
+	require.NotContains(t, files, "g/large.txt")
+	require.NotEqual(t, http.StatusCreated, do("MKCOL", "/sub", "").Code)
+
+	require.Equal(t, http.StatusCreated, do("MOVE", "/a.txt", "", "Destination", "/b.txt").Code)
+	require.NotContains(t, files, "g/a.txt")
+	require.Equal(t, []byte("hello"), files["g/b.txt"])
+
+	w = do("PROPFIND", "/", "", "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	require.Contains(t, w.Body.String(), "/b.txt")
+	require.Contains(t, w.Body.String(), "/other.txt")
+	require.NotContains(t, w.Body.String(), "/a.txt")
+	require.NotContains(t, w.Body.String(), "/large.txt")
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/b.txt", "").Code)
+	require.NotContains(t, files, "g/b.txt")
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/b.txt", "").Code)
+}
@@ -0,0 +1,86 @@
+// Package dav serves the file models of a did as a WebDAV directory, so SAO can be mounted by the file managers.
+package dav
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"sao-node/api"
+	"sao-node/chain"
+	"sao-node/node/config"
+	"sao-node/node/filemodel"
+	"sao-node/types"
+
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/net/webdav"
+)
+
+var log = logging.Logger("dav")
+
+type Server struct {
+	username string
+	password string
+	handler  *webdav.Handler
+	server   *http.Server
+}
+
+func StartServer(ctx context.Context, cfg *config.WebDav, gatewayApi api.SaoApi, chainSvc chain.ChainSvcApi, nodeAddress string, keyringHome string, fileServerPath string, ds datastore.Batching) (*Server, error) {
+	if cfg.GroupId == "" {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "WebDAV groupId is empty")
+	}
+	password := os.Getenv(cfg.PasswordEnv)
+	if cfg.Username != "" && password == "" {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "WebDAV user %s without password in $%s", cfg.Username, cfg.PasswordEnv)
+	}
+
+	store, err := filemodel.NewStore(ctx, gatewayApi, chainSvc, nodeAddress, keyringHome, cfg.KeyName, fileServerPath, cfg.Duration, cfg.Replica)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		username: cfg.Username,
+		password: password,
+		handler: &webdav.Handler{
+			FileSystem: newFileSystem(store, cfg.GroupId, cfg.MaxFileSize, ds),
+			LockSystem: webdav.NewMemLS(),
+			Logger: func(r *http.Request, err error) {
+				if err != nil {
+					log.Warnf("%s %s: %v", r.Method, r.URL.Path, err)
+				}
+			},
+		},
+	}
+	s.server = &http.Server{
+		Addr:    cfg.ListenAddress,
+		Handler: s,
+	}
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("WebDAV server: %v", err)
+		}
+	}()
+	log.Infof("WebDAV server listening on %s, files of %s in %s", cfg.ListenAddress, store.Owner(), cfg.GroupId)
+
+	return s, nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	log.Info("stopping WebDAV server...")
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.username != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(s.username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="sao"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	s.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,323 @@
+// Package events is the in-process event bus of the node. The gateway, the storage node and the chain
+// subscription publish what happens to the orders, the shards and the data models, and the subsystems interested
+// subscribe to the topics, so a new consumer doesn't need a channel threaded through the constructors or a call
+// added to the producers.
+package events
+
+import (
+	"context"
+	"sao-node/chain"
+	"sao-node/types"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("events")
+
+type Topic string
+
+const (
+	// the shards assigned by the gateway of this node to the storage node of this node, LocalShardAssign
+	TopicLocalShardAssign Topic = "local-shard-assign"
+	// the shards completed by the storage node of this node for the gateway of this node, LocalShardComplete
+	TopicLocalShardComplete Topic = "local-shard-complete"
+	// the state changes of the orders of the gateway, OrderEvent
+	TopicOrder Topic = "order"
+	// the outcomes of the requests to the storage providers, ShardEvent
+	TopicShard Topic = "shard"
+	// the data models changed on chain, ChainEvent
+	TopicChain Topic = "chain"
+	// the shards assigned to the storage nodes on chain, AssignmentEvent
+	TopicAssignment Topic = "assignment"
+)
+
+// Event is published to the subscribers of its topic.
+type Event interface {
+	Topic() Topic
+}
+
+type LocalShardAssign struct {
+	Req types.ShardAssignReq
+}
+
+func (LocalShardAssign) Topic() Topic { return TopicLocalShardAssign }
+
+type LocalShardComplete struct {
+	Req types.ShardCompleteReq
+}
+
+func (LocalShardComplete) Topic() Topic { return TopicLocalShardComplete }
+
+// OrderEvent is an order of the gateway moved to State.
+type OrderEvent struct {
+	DataId  string
+	OrderId uint64
+	Owner   string
+	State   types.OrderState
+	Time    time.Time
+}
+
+func (OrderEvent) Topic() Topic { return TopicOrder }
+
+type ShardEventKind string
+
+const (
+	// the shard assignment is notified to the provider, or failed to
+	ShardAssigned ShardEventKind = "assigned"
+	// the provider completed the shard
+	ShardCompleted ShardEventKind = "completed"
+	// the order expired before the provider completed the shard
+	ShardTimedOut ShardEventKind = "timed-out"
+	// the shard is loaded from the provider, or failed to
+	ShardLoaded ShardEventKind = "loaded"
+	// the provider passed the availability challenge of the shard, or failed it
+	ShardAudited ShardEventKind = "audited"
+	// the shard is migrated to the provider, or failed to
+	ShardMigrated ShardEventKind = "migrated"
+)
+
+// ShardEvent is the outcome of a request about a shard to its storage provider.
+type ShardEvent struct {
+	Kind     ShardEventKind
+	Provider string
+	OrderId  uint64
+	// the content of the order, set for ShardCompleted
+	Cid      string
+	Size     uint64
+	Replicas int
+	// false if the request failed
+	Ok bool
+	// the time the migration took, set for ShardMigrated
+	Latency time.Duration
+	Time    time.Time
+}
+
+func (ShardEvent) Topic() Topic { return TopicShard }
+
+type ChainEvent struct {
+	Update chain.ModelUpdate
+}
+
+func (ChainEvent) Topic() Topic { return TopicChain }
+
+type AssignmentEvent struct {
+	Assignment chain.ShardAssignment
+}
+
+func (AssignmentEvent) Topic() Topic { return TopicAssignment }
+
+// Subscription receives the events of a topic on C until closed.
+type Subscription struct {
+	C <-chan Event
+
+	bus    *Bus
+	topic  Topic
+	ch     chan Event
+	closed chan struct{}
+	once   sync.Once
+}
+
+// Close stops the events, C is not closed since a publisher may be sending to it.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+		s.bus.unsubscribe(s)
+	})
+}
+
+// Bus delivers the events to the subscribers of their topics. A nil Bus drops all events.
+type Bus struct {
+	lk   sync.RWMutex
+	subs map[Topic][]*Subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[Topic][]*Subscription)}
+}
+
+// Subscribe returns the subscription to the topic, the publishers wait for a subscriber once buffer events are
+// queued, so the subscribers doing slow work should hand it off.
+func (b *Bus) Subscribe(topic Topic, buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, bus: b, topic: topic, ch: ch, closed: make(chan struct{})}
+	if b == nil {
+		return s
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.subs[topic] = append(b.subs[topic], s)
+	return s
+}
+
+func (b *Bus) unsubscribe(s *Subscription) {
+	if b == nil {
+		return
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	subs := b.subs[s.topic]
+	for i, sub := range subs {
+		if sub == s {
+			b.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+}
+
+// Publish sends the event to the subscribers of its topic in the order they subscribed, waiting for the ones
+// whose buffer is full until ctx is done. It returns the count of the subscribers the event is delivered to.
+func (b *Bus) Publish(ctx context.Context, event Event) int {
+	if b == nil {
+		return 0
+	}
+
+	b.lk.RLock()
+	subs := b.subs[event.Topic()]
+	b.lk.RUnlock()
+
+	delivered := 0
+	for _, s := range subs {
+		select {
+		case s.ch <- event:
+			delivered++
+		case <-s.closed:
+		case <-ctx.Done():
+			log.Warnf("%s event dropped: %v", event.Topic(), ctx.Err())
+			return delivered
+		}
+	}
+	return delivered
+}
+
+// Consume calls f with the events of the topic in a goroutine until ctx is done.
+func (b *Bus) Consume(ctx context.Context, topic Topic, buffer int, f func(Event)) {
+	sub := b.Subscribe(topic, buffer)
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case ev := <-sub.C:
+				f(ev)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// the model events queued for a subscriber of SubscribeModels, the next ones are dropped until it catches up
+const modelEventBuffer = 64
+
+// SubscribeModels sends the events of the orders and the chain updates of the model dataId, or of the models of
+// owner if dataId is empty, until ctx is done, then the channel is closed. Unlike the subscribers of the topics, a
+// subscriber too slow to keep up drops the events rather than holding the publishers.
+func (b *Bus) SubscribeModels(ctx context.Context, dataId string, owner string) <-chan types.ModelEvent {
+	orders := b.Subscribe(TopicOrder, modelEventBuffer)
+	updates := b.Subscribe(TopicChain, modelEventBuffer)
+	out := make(chan types.ModelEvent, modelEventBuffer)
+
+	matches := func(eventDataId string, eventOwner string) bool {
+		if dataId != "" {
+			return eventDataId == dataId
+		}
+		return eventOwner == owner
+	}
+	send := func(event types.ModelEvent) {
+		select {
+		case out <- event:
+		default:
+			log.Warnf("model event of %s dropped, the subscriber is too slow", event.DataId)
+		}
+	}
+
+	go func() {
+		defer close(out)
+		defer orders.Close()
+		defer updates.Close()
+		for {
+			select {
+			case ev := <-orders.C:
+				e := ev.(OrderEvent)
+				if matches(e.DataId, e.Owner) {
+					send(types.ModelEvent{
+						Kind:    types.ModelEventOrder,
+						DataId:  e.DataId,
+						Owner:   e.Owner,
+						OrderId: e.OrderId,
+						State:   e.State,
+						Time:    e.Time.Unix(),
+					})
+				}
+			case ev := <-updates.C:
+				u := ev.(ChainEvent).Update
+				if matches(u.DataId, u.Owner) {
+					send(types.ModelEvent{
+						Kind:   types.ModelEventUpdate,
+						DataId: u.DataId,
+						Owner:  u.Owner,
+						Alias:  u.Alias,
+						Height: u.Height,
+						TxHash: u.TxHash,
+						Time:   time.Now().Unix(),
+					})
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// the sao events queued before the chain subscription waits for the bus
+const chainEventBuffer = 64
+
+// PublishChainEvents subscribes to the sao txs on chain and publishes the data models they changed as ChainEvent
+// and the shards they assigned as AssignmentEvent until ctx is done. The height of the txs published is persisted
+// in ds, the txs of the blocks produced while the node was down are published once it is up again.
+func (b *Bus) PublishChainEvents(ctx context.Context, chainSvc chain.ChainSvcApi, ds datastore.Batching) error {
+	ch := make(chan chain.SaoEvent, chainEventBuffer)
+	if err := chainSvc.SubscribeSaoEvents(ctx, datastoreCursor{ds: ds}, ch); err != nil {
+		return err
+	}
+
+	go func() {
+		for ev := range ch {
+			for _, update := range ev.Updates {
+				b.Publish(ctx, ChainEvent{Update: update})
+			}
+			for _, assignment := range ev.Assignments {
+				b.Publish(ctx, AssignmentEvent{Assignment: assignment})
+			}
+		}
+	}()
+	return nil
+}
+
+var chainCursorKey = datastore.NewKey("/chain-events/height")
+
+// datastoreCursor persists the height of the sao events in the datastore of the node.
+type datastoreCursor struct {
+	ds datastore.Batching
+}
+
+func (c datastoreCursor) Height(ctx context.Context) (int64, error) {
+	bs, err := c.ds.Get(ctx, chainCursorKey)
+	if err == datastore.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(bs), 10, 64)
+}
+
+func (c datastoreCursor) SetHeight(ctx context.Context, height int64) error {
+	return c.ds.Put(ctx, chainCursorKey, []byte(strconv.FormatInt(height, 10)))
+}
@@ -0,0 +1,138 @@
+package events
+
+import (
+	"context"
+	"sao-node/chain"
+	"sao-node/types"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewBus()
+	require.Equal(t, 0, bus.Publish(ctx, OrderEvent{OrderId: 1}))
+
+	orders := bus.Subscribe(TopicOrder, 2)
+	shards := bus.Subscribe(TopicShard, 1)
+	require.Equal(t, 1, bus.Publish(ctx, OrderEvent{OrderId: 1, State: types.OrderStateStaged}))
+	require.Equal(t, 1, bus.Publish(ctx, OrderEvent{OrderId: 1, State: types.OrderStateComplete}))
+	require.Equal(t, 1, bus.Publish(ctx, ShardEvent{Kind: ShardCompleted, OrderId: 1}))
+
+	require.Equal(t, types.OrderStateStaged, (<-orders.C).(OrderEvent).State)
+	require.Equal(t, types.OrderStateComplete, (<-orders.C).(OrderEvent).State)
+	require.Equal(t, ShardCompleted, (<-shards.C).(ShardEvent).Kind)
+
+	// the publisher waits for the full buffer until ctx is done
+	require.Equal(t, 1, bus.Publish(ctx, ShardEvent{Kind: ShardLoaded}))
+	timeout, cancelTimeout := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelTimeout()
+	require.Equal(t, 0, bus.Publish(timeout, ShardEvent{Kind: ShardAudited}))
+
+	shards.Close()
+	shards.Close()
+	require.Equal(t, 0, bus.Publish(ctx, ShardEvent{Kind: ShardMigrated}))
+
+	received := make(chan Event)
+	bus.Consume(ctx, TopicShard, 0, func(ev Event) {
+		received <- ev
+	})
+	go bus.Publish(ctx, ShardEvent{Kind: ShardAssigned})
+	require.Equal(t, ShardAssigned, (<-received).(ShardEvent).Kind)
+
+	// a nil bus drops the events
+	var none *Bus
+	sub := none.Subscribe(TopicOrder, 0)
+	require.Equal(t, 0, none.Publish(ctx, OrderEvent{}))
+	sub.Close()
+}
+
+func TestSubscribeModels(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bus := NewBus()
+
+	byDataId := bus.SubscribeModels(ctx, "data1", "")
+	byOwner := bus.SubscribeModels(ctx, "", "owner")
+	bus.Publish(ctx, OrderEvent{DataId: "data1", Owner: "other", OrderId: 1, State: types.OrderStateComplete})
+	bus.Publish(ctx, OrderEvent{DataId: "data2", Owner: "owner", OrderId: 2, State: types.OrderStateReady})
+	bus.Publish(ctx, ChainEvent{Update: chain.ModelUpdate{DataId: "data1", Owner: "other", Alias: "notes", Height: 10}})
+
+	// the orders and the chain updates are not ordered with each other
+	kinds := make(map[types.ModelEventKind]types.ModelEvent)
+	for i := 0; i < 2; i++ {
+		event := <-byDataId
+		kinds[event.Kind] = event
+	}
+	require.True(t, kinds[types.ModelEventOrder].Completed())
+	require.Equal(t, uint64(1), kinds[types.ModelEventOrder].OrderId)
+	require.Equal(t, int64(10), kinds[types.ModelEventUpdate].Height)
+	require.Equal(t, "notes", kinds[types.ModelEventUpdate].Alias)
+
+	event := <-byOwner
+	require.Equal(t, "data2", event.DataId)
+	require.False(t, event.Completed())
+
+	// the channels are closed once ctx is done
+	cancel()
+	for range byDataId {
+	}
+	for range byOwner {
+	}
+}
+
+type replayChain struct {
+	chain.ChainSvcApi
+	events []chain.SaoEvent
+}
+
+// SubscribeSaoEvents sends the events after the height of cursor as the chain service replays them.
+func (c replayChain) SubscribeSaoEvents(ctx context.Context, cursor chain.EventCursor, ch chan<- chain.SaoEvent) error {
+	synced, err := cursor.Height(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer close(ch)
+		for _, ev := range c.events {
+			if ev.Height > synced {
+				ch <- ev
+				synced = ev.Height
+			}
+		}
+		_ = cursor.SetHeight(ctx, synced)
+	}()
+	return nil
+}
+
+func TestPublishChainEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	c := replayChain{events: []chain.SaoEvent{
+		{Height: 10, Updates: []chain.ModelUpdate{{DataId: "data1", Height: 10}}},
+		{Height: 11, Assignments: []chain.ShardAssignment{{OrderId: 1, Height: 11}}, Updates: []chain.ModelUpdate{{DataId: "data2", Height: 11}}},
+	}}
+
+	bus := NewBus()
+	updates := bus.Subscribe(TopicChain, 4)
+	assignments := bus.Subscribe(TopicAssignment, 4)
+	require.NoError(t, bus.PublishChainEvents(ctx, c, ds))
+	require.Equal(t, "data1", (<-updates.C).(ChainEvent).Update.DataId)
+	require.Equal(t, "data2", (<-updates.C).(ChainEvent).Update.DataId)
+	require.Equal(t, uint64(1), (<-assignments.C).(AssignmentEvent).Assignment.OrderId)
+
+	// persisted, the events are not published again after a restart
+	require.Eventually(t, func() bool {
+		height, err := datastoreCursor{ds: ds}.Height(ctx)
+		return err == nil && height == 11
+	}, time.Second, 10*time.Millisecond)
+	c.events = append(c.events, chain.SaoEvent{Height: 12, Updates: []chain.ModelUpdate{{DataId: "data3", Height: 12}}})
+	require.NoError(t, bus.PublishChainEvents(ctx, c, ds))
+	require.Equal(t, "data3", (<-updates.C).(ChainEvent).Update.DataId)
+}
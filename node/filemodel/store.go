@@ -0,0 +1,301 @@
+// Package filemodel stores named files as file models on behalf of a keyring account, for the frontends of
+// the gateway speaking other protocols. A file is the model aliased by its name in a groupId, owned by the
+// did generated from the account the same way as the client does.
+package filemodel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sao-node/api"
+	apitypes "sao-node/api/types"
+	"sao-node/chain"
+	"sao-node/node/model/filedelta"
+	"sao-node/types"
+	"sao-node/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saokey "github.com/SaoNetwork/sao-did/key"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	// blocks to wait for the content to be stored, the default of the client
+	orderTimeout = 60
+	// blocks the signed query proposals stay valid
+	proposalValidBlocks = 200
+)
+
+type Store struct {
+	gatewayApi     api.SaoApi
+	chainSvc       chain.ChainSvcApi
+	nodeAddress    string
+	fileServerPath string
+	didManager     *saodid.DidManager
+	// days and copies of the stored files
+	duration int
+	replica  int
+}
+
+func NewStore(ctx context.Context, gatewayApi api.SaoApi, chainSvc chain.ChainSvcApi, nodeAddress string, keyringHome string, keyName string, fileServerPath string, duration int, replica int) (*Store, error) {
+	path, err := homedir.Expand(fileServerPath)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidPath, err)
+	}
+	didManager, err := newDidManager(ctx, keyringHome, keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		gatewayApi:     gatewayApi,
+		chainSvc:       chainSvc,
+		nodeAddress:    nodeAddress,
+		fileServerPath: path,
+		didManager:     didManager,
+		duration:       duration,
+		replica:        replica,
+	}, nil
+}
+
+func newDidManager(ctx context.Context, keyringHome string, keyName string) (*saodid.DidManager, error) {
+	address, err := chain.GetAddress(ctx, keyringHome, keyName)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := chain.SignByAccount(ctx, keyringHome, keyName, []byte(fmt.Sprintf("cosmos %s allows to generate did", address)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := saokey.NewSecp256k1Provider(secret)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateProviderFailed, err)
+	}
+	didManager := saodid.NewDidManager(provider, saokey.NewKeyResolver())
+	_, err = didManager.Authenticate([]string{}, "")
+	if err != nil {
+		return nil, types.Wrap(types.ErrAuthenticateFailed, err)
+	}
+	return &didManager, nil
+}
+
+// Owner returns the did owning the files.
+func (s *Store) Owner() string {
+	return s.didManager.Id
+}
+
+// Put stores the content as the file name in the group. An existing file is updated by the delta from its
+// latest version, see filedelta.
+func (s *Store) Put(ctx context.Context, groupId string, name string, content []byte) (string, error) {
+	if len(content) == 0 {
+		return "", types.Wrapf(types.ErrInvalidParameters, "empty file %s", name)
+	}
+
+	if _, err := s.Find(ctx, groupId, name); err == nil {
+		return s.update(ctx, groupId, name, content)
+	}
+
+	contentCid, err := utils.CalculateCid(content)
+	if err != nil {
+		return "", err
+	}
+	owner := s.Owner()
+	dataId := utils.GenerateDataId(owner + groupId)
+	proposal := saotypes.Proposal{
+		DataId:    dataId,
+		Owner:     owner,
+		Provider:  s.nodeAddress,
+		GroupId:   groupId,
+		Duration:  uint64(time.Duration(60*60*24*s.duration) * time.Second / chain.Blocktime),
+		Replica:   int32(s.replica),
+		Timeout:   orderTimeout,
+		Alias:     types.Type_Prefix_File + name,
+		Cid:       contentCid.String(),
+		CommitId:  dataId,
+		Size_:     uint64(len(content)),
+		Operation: 1,
+		// the content type is served by the http file server
+		ExtendInfo: utils.SetContentType("", utils.DetectContentType(name, content)),
+	}
+	signature, err := s.sign(&proposal)
+	if err != nil {
+		return "", err
+	}
+	req, err := s.queryRequest(ctx, saotypes.QueryProposal{Owner: owner, Keyword: dataId})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.gatewayApi.ModelCreate(ctx, req, &types.OrderStoreProposal{Proposal: proposal, JwsSignature: signature}, 0, content)
+	if err != nil {
+		return "", err
+	}
+	return resp.DataId, nil
+}
+
+// update commits the content as the new version of the file name in the group, only the delta from the latest
+// version is stored.
+func (s *Store) update(ctx context.Context, groupId string, name string, content []byte) (string, error) {
+	latest, base, err := s.load(ctx, groupId, name)
+	if err != nil {
+		return "", err
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(latest.Version, "v"))
+	if err != nil {
+		return "", types.Wrapf(types.ErrInvalidVersion, "invalid version %s", latest.Version)
+	}
+	delta, err := filedelta.Commit(base, latest.CommitId, version+1, content)
+	if err != nil {
+		return "", err
+	}
+	deltaCid, err := utils.CalculateCid(delta)
+	if err != nil {
+		return "", err
+	}
+
+	owner := s.Owner()
+	proposal := saotypes.Proposal{
+		DataId:     latest.DataId,
+		Owner:      owner,
+		Provider:   s.nodeAddress,
+		GroupId:    groupId,
+		Duration:   uint64(time.Duration(60*60*24*s.duration) * time.Second / chain.Blocktime),
+		Replica:    int32(s.replica),
+		Timeout:    orderTimeout,
+		Alias:      latest.Alias,
+		Cid:        deltaCid.String(),
+		CommitId:   latest.CommitId + "|" + utils.GenerateCommitId(owner+groupId),
+		Size_:      uint64(len(delta)),
+		Operation:  1,
+		ExtendInfo: utils.SetContentType("", utils.DetectContentType(name, content)),
+	}
+	signature, err := s.sign(&proposal)
+	if err != nil {
+		return "", err
+	}
+	req, err := s.queryRequest(ctx, saotypes.QueryProposal{Owner: owner, Keyword: latest.DataId})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.gatewayApi.ModelUpdate(ctx, req, &types.OrderStoreProposal{Proposal: proposal, JwsSignature: signature}, 0, delta)
+	if err != nil {
+		return "", err
+	}
+	return resp.DataId, nil
+}
+
+// Get returns the dataId and the content of the file name in the group.
+func (s *Store) Get(ctx context.Context, groupId string, name string) (string, []byte, error) {
+	resp, content, err := s.load(ctx, groupId, name)
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.DataId, content, nil
+}
+
+// load returns the latest version of the file name in the group and its content.
+func (s *Store) load(ctx context.Context, groupId string, name string) (apitypes.LoadResp, []byte, error) {
+	req, err := s.queryRequest(ctx, s.aliasProposal(groupId, name))
+	if err != nil {
+		return apitypes.LoadResp{}, nil, err
+	}
+	resp, err := s.gatewayApi.ModelLoad(ctx, req)
+	if err != nil {
+		return apitypes.LoadResp{}, nil, types.Wrapf(types.ErrNotFound, "file %s: %v", name, err)
+	}
+
+	content := []byte(resp.Content)
+	if len(content) == 0 {
+		// the file models are loaded to the http file server
+		content, err = os.ReadFile(filepath.Join(s.fileServerPath, resp.DataId))
+		if err != nil {
+			return apitypes.LoadResp{}, nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+	}
+	return resp, content, nil
+}
+
+// Find returns the dataId of the file name in the group without loading its content.
+func (s *Store) Find(ctx context.Context, groupId string, name string) (string, error) {
+	req, err := s.queryRequest(ctx, s.aliasProposal(groupId, name))
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.gatewayApi.ModelShowCommits(ctx, req)
+	if err != nil {
+		return "", types.Wrapf(types.ErrNotFound, "file %s: %v", name, err)
+	}
+	return resp.DataId, nil
+}
+
+// Delete terminates the file name in the group.
+func (s *Store) Delete(ctx context.Context, groupId string, name string) error {
+	dataId, err := s.Find(ctx, groupId, name)
+	if err != nil {
+		return err
+	}
+	return s.Terminate(ctx, dataId)
+}
+
+func (s *Store) Terminate(ctx context.Context, dataId string) error {
+	proposal := saotypes.TerminateProposal{
+		Owner:  s.Owner(),
+		DataId: dataId,
+	}
+	signature, err := s.sign(&proposal)
+	if err != nil {
+		return err
+	}
+	_, err = s.gatewayApi.ModelDelete(ctx, &types.OrderTerminateProposal{Proposal: proposal, JwsSignature: signature}, true)
+	return err
+}
+
+func (s *Store) aliasProposal(groupId string, name string) saotypes.QueryProposal {
+	return saotypes.QueryProposal{
+		Owner:       s.Owner(),
+		Keyword:     types.Type_Prefix_File + name,
+		GroupId:     groupId,
+		KeywordType: 2,
+	}
+}
+
+func (s *Store) queryRequest(ctx context.Context, proposal saotypes.QueryProposal) (*types.MetadataProposal, error) {
+	height, err := s.chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		return nil, types.Wrap(types.ErrQueryHeightFailed, err)
+	}
+	peerInfo, err := s.chainSvc.GetNodePeer(ctx, s.nodeAddress)
+	if err != nil {
+		return nil, err
+	}
+	proposal.LastValidHeight = uint64(height + proposalValidBlocks)
+	proposal.Gateway = peerInfo
+
+	signature, err := s.sign(&proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &types.MetadataProposal{Proposal: proposal, JwsSignature: signature}, nil
+}
+
+func (s *Store) sign(proposal types.ConsensusProposal) (saotypes.JwsSignature, error) {
+	data, err := proposal.Marshal()
+	if err != nil {
+		return saotypes.JwsSignature{}, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	jws, err := s.didManager.CreateJWS(data)
+	if err != nil {
+		return saotypes.JwsSignature{}, types.Wrap(types.ErrCreateJwsFailed, err)
+	}
+	return saotypes.JwsSignature{
+		Protected: jws.Signatures[0].Protected,
+		Signature: jws.Signatures[0].Signature,
+	}, nil
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"sao-node/node/alert"
+	"sao-node/node/events"
+	"sao-node/types"
+	"sao-node/utils"
+	"sort"
+	"time"
+
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	"github.com/ipfs/go-cid"
+)
+
+// SetAlerts reports the orders found under replicated and the orders ending soon to alerts.
+func (gs *GatewaySvc) SetAlerts(alerts *alert.Manager) {
+	gs.alerts = alerts
+}
+
+// AuditOrders audits up to Gateway.AuditSamples completed orders picked at random, and returns the count of the
+// orders audited.
+func (gs *GatewaySvc) AuditOrders(ctx context.Context) (int, error) {
+	orders, err := gs.OrderList(ctx, types.OrderFilter{States: []types.OrderState{types.OrderStateComplete}})
+	if err != nil {
+		return 0, err
+	}
+	rand.Shuffle(len(orders), func(i, j int) { orders[i], orders[j] = orders[j], orders[i] })
+	if samples := gs.cfg.Gateway.AuditSamples; samples > 0 && len(orders) > samples {
+		orders = orders[:samples]
+	}
+
+	audited := 0
+	for _, orderInfo := range orders {
+		order, err := gs.chainSvc.GetOrder(ctx, orderInfo.OrderId)
+		if err != nil {
+			log.Warnf("get order %d to audit error: %v", orderInfo.OrderId, err)
+			continue
+		}
+		// terminated or expired since
+		if order.Status != ordertypes.OrderCompleted {
+			continue
+		}
+
+		audit := gs.auditOrder(ctx, orderInfo, order)
+		if err := utils.SaveAudit(ctx, gs.orderDs, audit); err != nil {
+			return audited, err
+		}
+		audited++
+		if audit.UnderReplicated {
+			log.Warnf("order %d of model %s is under replicated, %d of %d replicas available",
+				audit.OrderId, audit.DataId, audit.Passed(), audit.Replica)
+			gs.alerts.Fire(alert.TypeUnderReplicated, "model %s (order %d) has %d of %d replicas available",
+				audit.DataId, audit.OrderId, audit.Passed(), audit.Replica)
+		}
+	}
+	return audited, nil
+}
+
+// auditOrder challenges the provider of each completed shard of the order, the shards waiting for a migration are
+// not challenged yet.
+func (gs *GatewaySvc) auditOrder(ctx context.Context, orderInfo types.OrderInfo, order *ordertypes.Order) types.AuditInfo {
+	audit := types.AuditInfo{
+		DataId:    orderInfo.DataId,
+		OrderId:   order.Id,
+		Replica:   uint64(order.Replica),
+		AuditedAt: time.Now().Unix(),
+	}
+
+	providers := make([]string, 0, len(order.Shards))
+	for provider := range order.Shards {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	for _, provider := range providers {
+		shard := order.Shards[provider]
+		if shard.Status != ordertypes.ShardCompleted {
+			continue
+		}
+
+		result := types.AuditResult{Provider: provider, Cid: shard.Cid, Passed: true}
+		if err := gs.challenge(ctx, orderInfo, provider, shard.Cid); err != nil {
+			result.Passed = false
+			result.Reason = err.Error()
+			log.Warnf("audit shard %s of order %d on %s failed: %v", shard.Cid, order.Id, provider, err)
+		}
+		gs.bus.Publish(ctx, events.ShardEvent{
+			Kind:     events.ShardAudited,
+			Provider: provider,
+			OrderId:  order.Id,
+			Ok:       result.Passed,
+			Time:     time.Now(),
+		})
+		audit.Results = append(audit.Results, result)
+	}
+	audit.UnderReplicated = audit.Passed() < int(audit.Replica)
+	return audit
+}
+
+// challenge requests the shard from its provider, the content returned must match the shard cid.
+func (gs *GatewaySvc) challenge(ctx context.Context, orderInfo types.OrderInfo, provider string, shardCid string) error {
+	expected, err := cid.Decode(shardCid)
+	if err != nil {
+		return types.Wrapf(types.ErrInvalidCid, "%s", shardCid)
+	}
+
+	var contentCid cid.Cid
+	if provider == gs.nodeAddress {
+		if gs.storeManager == nil {
+			return types.Wrapf(types.ErrGetFailed, "no local store for %v", expected)
+		}
+		reader, err := gs.storeManager.Get(ctx, expected)
+		if err != nil {
+			return err
+		}
+		contentCid, err = utils.CalculateCidFrom(reader)
+		if err != nil {
+			return err
+		}
+	} else {
+		peer, err := gs.chainSvc.GetNodePeer(ctx, provider)
+		if err != nil {
+			return err
+		}
+		resp := gs.gatewayProtocolMap["stream"].RequestShardAudit(ctx, types.ShardLoadReq{
+			Owner:     orderInfo.Owner,
+			OrderId:   orderInfo.OrderId,
+			Cid:       expected,
+			RequestId: time.Now().UnixMilli(),
+		}, peer)
+		if resp.Code != 0 {
+			return types.Wrapf(types.ErrFailuresResponsed, resp.Message)
+		}
+		contentCid, err = utils.CalculateCid(resp.Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the cids may differ in version and codec
+	if !bytes.Equal(contentCid.Hash(), expected.Hash()) {
+		return types.Wrapf(types.ErrInvalidCid, "content of cid %v returned, expected %v", contentCid, expected)
+	}
+	return nil
+}
+
+// OrderAudits returns the last availability audits of the orders, only the under replicated ones if
+// underReplicated.
+func (gs *GatewaySvc) OrderAudits(ctx context.Context, underReplicated bool) ([]types.AuditInfo, error) {
+	var audits []types.AuditInfo
+	err := utils.ForEachAudit(ctx, gs.orderDs, func(audit types.AuditInfo) error {
+		if !underReplicated || audit.UnderReplicated {
+			audits = append(audits, audit)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sao-node/node/events"
+	"sao-node/node/reputation"
+	"sao-node/store"
+	"sao-node/types"
+	"sao-node/utils"
+	"testing"
+	"time"
+
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+// contentBackend returns the content kept by cid, whatever the cid of the content is.
+type contentBackend map[cid.Cid][]byte
+
+func (b contentBackend) Id() string   { return "content" }
+func (b contentBackend) Type() string { return "content" }
+func (b contentBackend) Open() error  { return nil }
+func (b contentBackend) Close() error { return nil }
+func (b contentBackend) Store(ctx context.Context, reader io.Reader) (any, error) {
+	return nil, types.Wrapf(types.ErrStoreFailed, "read only")
+}
+func (b contentBackend) Remove(ctx context.Context, c cid.Cid) error {
+	delete(b, c)
+	return nil
+}
+func (b contentBackend) Get(ctx context.Context, c cid.Cid) (io.Reader, error) {
+	content, ok := b[c]
+	if !ok {
+		return nil, types.Wrapf(types.ErrNotFound, "%v", c)
+	}
+	return bytes.NewReader(content), nil
+}
+func (b contentBackend) IsExist(ctx context.Context, c cid.Cid) (bool, error) {
+	_, ok := b[c]
+	return ok, nil
+}
+
+func TestAuditOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	rep, err := reputation.NewStore(ctx, ds)
+	require.NoError(t, err)
+	bus := events.NewBus()
+	rep.Consume(ctx, bus)
+
+	content := []byte("the content of the audited shard")
+	shardCid, err := utils.CalculateCid(content)
+	require.NoError(t, err)
+	backend := contentBackend{shardCid: content}
+	gs := &GatewaySvc{
+		nodeAddress:  "sao1local",
+		orderDs:      ds,
+		storeManager: store.NewStoreManager([]store.StoreBackend{backend}),
+		reputation:   rep,
+		bus:          bus,
+	}
+
+	orderInfo := types.OrderInfo{DataId: "data", OrderId: 1, Owner: "owner"}
+	order := &ordertypes.Order{
+		Id:      1,
+		Replica: 1,
+		Shards: map[string]*ordertypes.Shard{
+			"sao1local": {Cid: shardCid.String(), Status: ordertypes.ShardCompleted},
+			// waiting for a migration, not challenged
+			"sao1waiting": {Cid: shardCid.String(), Status: ordertypes.ShardWaiting},
+		},
+	}
+	audit := gs.auditOrder(ctx, orderInfo, order)
+	require.Len(t, audit.Results, 1)
+	require.True(t, audit.Results[0].Passed)
+	require.Equal(t, 1, audit.Passed())
+	require.False(t, audit.UnderReplicated)
+	require.NoError(t, utils.SaveAudit(ctx, ds, audit))
+
+	// the content doesn't match the shard cid
+	backend[shardCid] = []byte("corrupted")
+	orderInfo.DataId, orderInfo.OrderId, order.Id = "other", 2, 2
+	audit = gs.auditOrder(ctx, orderInfo, order)
+	require.Len(t, audit.Results, 1)
+	require.False(t, audit.Results[0].Passed)
+	require.NotEmpty(t, audit.Results[0].Reason)
+	require.True(t, audit.UnderReplicated)
+	require.NoError(t, utils.SaveAudit(ctx, ds, audit))
+
+	require.Eventually(t, func() bool {
+		stats := rep.List()
+		return len(stats) == 1 && stats[0].Audits == 2 && stats[0].AuditFailures == 1
+	}, time.Second, 10*time.Millisecond)
+
+	audits, err := gs.OrderAudits(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, audits, 2)
+	audits, err = gs.OrderAudits(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, audits, 1)
+	require.Equal(t, uint64(2), audits[0].OrderId)
+}
@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
+	"sao-node/node/model/filedelta"
+	"sao-node/types"
+
+	"github.com/ipfs/go-cid"
+)
+
+// resolveDelta replaces the content of file, the content committed to the file model of meta, by the file it
+// resolves to if it is a delta, see filedelta. The commit the delta is made on is fetched and resolved in turn. It
+// returns the size and the cid of the file, the ones given if the content isn't a delta.
+func (gs *GatewaySvc) resolveDelta(ctx context.Context, req *types.MetadataProposal, meta *types.Model, file *os.File, size int64, contentCid cid.Cid) (int64, cid.Cid, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, cid.Undef, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(file, head)
+	if !filedelta.IsDelta(head[:n]) {
+		return size, contentCid, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, cid.Undef, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	delta, err := io.ReadAll(file)
+	if err != nil {
+		return 0, cid.Undef, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	header, _, err := filedelta.Parse(delta)
+	if err != nil {
+		return 0, cid.Undef, err
+	}
+
+	var base io.ReaderAt = bytes.NewReader(nil)
+	if header.Base != "" {
+		baseMeta, err := gs.baseMeta(ctx, req, meta, header.Base)
+		if err != nil {
+			return 0, cid.Undef, err
+		}
+		result, err := gs.FetchContent(ctx, req, baseMeta)
+		if err != nil {
+			return 0, cid.Undef, err
+		}
+		if len(result.Content) > 0 {
+			base = bytes.NewReader(result.Content)
+		} else {
+			baseFile, err := os.Open(result.Path)
+			if err != nil {
+				return 0, cid.Undef, types.Wrap(types.ErrReadFileFailed, err)
+			}
+			defer baseFile.Close()
+			base = baseFile
+		}
+	}
+
+	if err := file.Truncate(0); err != nil {
+		return 0, cid.Undef, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, cid.Undef, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if _, err := filedelta.Resolve(base, delta, file); err != nil {
+		return 0, cid.Undef, err
+	}
+	fileCid, err := cid.Decode(header.Cid)
+	if err != nil {
+		return 0, cid.Undef, types.Wrapf(types.ErrInvalidCid, "%s", header.Cid)
+	}
+	return int64(header.Size), fileCid, nil
+}
+
+// baseMeta returns the metadata of the commit baseCommitId, which must be earlier than the commit of meta.
+func (gs *GatewaySvc) baseMeta(ctx context.Context, req *types.MetadataProposal, meta *types.Model, baseCommitId string) (*types.Model, error) {
+	for i := 0; i < len(meta.Commits)-1; i++ {
+		commitInfo, err := types.ParseMetaCommit(meta.Commits[i])
+		if err != nil {
+			return nil, types.Wrapf(types.ErrInvalidCommitInfo, "invalid commit information: %s", meta.Commits[i])
+		}
+		if commitInfo.CommitId == baseCommitId {
+			return gs.QueryMeta(ctx, req, int64(commitInfo.Height))
+		}
+	}
+	return nil, types.Wrapf(types.ErrInvalidCommitInfo, "the file delta of commit %s is made on an unknown commit %s", meta.CommitId, baseCommitId)
+}
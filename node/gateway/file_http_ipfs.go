@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sao-node/store"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/labstack/echo/v4"
+)
+
+// the content of a cid never changes, a year is the common max-age of the ipfs gateways
+const ipfsCacheControl = "public, max-age=29030400, immutable"
+
+// content type of the raw blocks, requested with ?format=raw or the Accept header like the trustless gateways
+const ipfsRawBlock = "application/vnd.ipld.raw"
+
+// ipfsPathHandler serves /ipfs/<cid> like an ipfs gateway, the content is resolved from the local backends.
+// The content stored only on the other providers is not proxied, the shard loads need a proposal signed by
+// the owner.
+type ipfsPathHandler struct {
+	storeManager *store.StoreManager
+}
+
+func (h *ipfsPathHandler) serve(c echo.Context) error {
+	id, err := cid.Decode(c.Param("cid"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid cid %s", c.Param("cid")))
+	}
+
+	raw := c.QueryParam("format") == "raw" || c.Request().Header.Get("Accept") == ipfsRawBlock
+	etag := fmt.Sprintf("\"%s\"", id)
+	if raw {
+		etag = fmt.Sprintf("\"%s.raw\"", id)
+	}
+	setHeaders := func() {
+		header := c.Response().Header()
+		header.Set("Etag", etag)
+		header.Set("X-Ipfs-Path", "/ipfs/"+id.String())
+		header.Set("Cache-Control", ipfsCacheControl)
+	}
+	if c.Request().Header.Get("If-None-Match") == etag {
+		setHeaders()
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	ctx := c.Request().Context()
+	if h.storeManager == nil || !h.storeManager.IsExist(ctx, id) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("%s not found", id))
+	}
+	if raw {
+		// the root block is returned for the content stored as a unixfs file, the downloads fetch its links one by one
+		blockCid, data, err := h.storeManager.Block(ctx, id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+		}
+		setHeaders()
+		c.Response().Header().Set("X-Ipfs-Roots", blockCid.String())
+		return c.Blob(http.StatusOK, ipfsRawBlock, data)
+	}
+
+	reader, err := h.storeManager.Get(ctx, id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	setHeaders()
+	// the unixfs files are seekable, the ranges are served by fetching only the blocks covering them
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(c.Response(), c.Request(), "", time.Time{}, rs)
+		return nil
+	}
+	br := bufio.NewReader(reader)
+	head, _ := br.Peek(512)
+	return c.Stream(http.StatusOK, http.DetectContentType(head), br)
+}
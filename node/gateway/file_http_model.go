@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"sao-node/utils"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// the files of the models loaded to the http file server are described in this directory, it is hidden from
+// the clients like every dot file
+const fileInfoDir = ".meta"
+
+// a file can be updated with the same dataId and needs a token, the caches revalidate it on every use
+const fileCacheControl = "private, no-cache"
+
+// fileInfo describes a file of the http file server, the file is named by the dataId of its model.
+type fileInfo struct {
+	Alias       string
+	Cid         string
+	ContentType string
+}
+
+func saveFileInfo(path string, meta *types.Model) error {
+	data, err := json.Marshal(fileInfo{
+		Alias:       meta.Alias,
+		Cid:         meta.Cid,
+		ContentType: utils.ContentType(meta.ExtendInfo),
+	})
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, fileInfoDir), 0755); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if err := os.WriteFile(filepath.Join(path, fileInfoDir, meta.DataId+".json"), data, 0644); err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+// loadFileInfo returns the description of the file, empty for the files loaded before the descriptions.
+func loadFileInfo(path string, dataId string) fileInfo {
+	var info fileInfo
+	data, err := os.ReadFile(filepath.Join(path, fileInfoDir, dataId+".json"))
+	if err != nil {
+		return info
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		log.Warnf("invalid file info of %s: %v", dataId, err)
+	}
+	return info
+}
+
+// fileHandler serves /saonetwork/<dataId> with the content type of the model and its file name, the file is
+// downloaded instead of displayed with ?download=1.
+type fileHandler struct {
+	path string
+}
+
+func (h *fileHandler) serve(c echo.Context) error {
+	dataId := c.Param("*")
+	if dataId == "" || strings.HasPrefix(dataId, ".") || strings.ContainsAny(dataId, "/\\") {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	file, err := os.Open(filepath.Join(h.path, dataId))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	return serveContent(c, file, stat.ModTime(), dataId, loadFileInfo(h.path, dataId))
+}
+
+// serveContent serves the content of the model described by info, it is downloaded instead of displayed with
+// ?download=1.
+func serveContent(c echo.Context, content io.ReadSeeker, modTime time.Time, dataId string, info fileInfo) error {
+	name := filepath.Base(strings.TrimPrefix(info.Alias, types.Type_Prefix_File))
+	if info.Alias == "" || name == "." || name == string(filepath.Separator) {
+		name = dataId
+	}
+	contentType := info.ContentType
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(content, head)
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		contentType = utils.DetectContentType(name, head[:n])
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	disposition := "inline"
+	if download := c.QueryParam("download"); download != "" && download != "0" && download != "false" {
+		disposition = "attachment"
+	}
+	header := c.Response().Header()
+	header.Set("Content-Type", contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": name}); value != "" {
+		header.Set("Content-Disposition", value)
+	} else {
+		header.Set("Content-Disposition", disposition)
+	}
+	header.Set("Cache-Control", fileCacheControl)
+	if info.Cid != "" {
+		header.Set("Etag", "\""+info.Cid+"\"")
+	}
+
+	// handles the HEAD, range and conditional requests
+	http.ServeContent(c.Response(), c.Request(), name, modTime, content)
+	return nil
+}
@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	apitypes "sao-node/api/types"
+	"sao-node/types"
+	"sao-node/utils"
+	"strings"
+	"testing"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileHandler(t *testing.T) {
+	path := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(path, "data1"), []byte("%PDF-1.4 content"), 0644))
+	require.NoError(t, saveFileInfo(path, &types.Model{
+		DataId:     "data1",
+		Alias:      types.Type_Prefix_File + "docs/report été.pdf",
+		Cid:        "cid1",
+		ExtendInfo: utils.SetContentType(`{"author":"a"}`, "application/pdf"),
+	}))
+	// loaded before the file infos, the type is sniffed
+	require.NoError(t, os.WriteFile(filepath.Join(path, "data2"), []byte("<html><body></body></html>"), 0644))
+
+	e := echo.New()
+	handler := &fileHandler{path: path}
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("*")
+		c.SetParamValues(req.URL.Path[len("/saonetwork/"):])
+		if err := handler.serve(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec
+	}
+
+	rec := serve("/saonetwork/data1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+	require.Equal(t, `inline; filename*=utf-8''report%20%C3%A9t%C3%A9.pdf`, rec.Header().Get("Content-Disposition"))
+	require.Equal(t, fileCacheControl, rec.Header().Get("Cache-Control"))
+	require.Equal(t, `"cid1"`, rec.Header().Get("Etag"))
+
+	rec = serve("/saonetwork/data2?download=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Equal(t, "attachment; filename=data2", rec.Header().Get("Content-Disposition"))
+	require.Equal(t, "<html><body></body></html>", rec.Body.String())
+
+	require.Equal(t, http.StatusNotFound, serve("/saonetwork/"+fileInfoDir).Code)
+	require.Equal(t, http.StatusNotFound, serve("/saonetwork/data3").Code)
+}
+
+type testUploader struct {
+	content string
+}
+
+func (u *testUploader) ModelCreateUpload(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content io.Reader) (apitypes.CreateResp, error) {
+	if req.Proposal.Owner != orderProposal.Proposal.Owner {
+		return apitypes.CreateResp{}, types.Wrapf(types.ErrInvalidSignature, "owner")
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return apitypes.CreateResp{}, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	u.content = string(data)
+	return apitypes.CreateResp{Alias: orderProposal.Proposal.Alias, DataId: "data1", Cid: orderProposal.Proposal.Cid}, nil
+}
+
+func TestUploadHandler(t *testing.T) {
+	e := echo.New()
+	uploader := &testUploader{}
+	handler := &uploadHandler{uploader: uploader, limit: 1024}
+	upload := func(owner string, fields [][2]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for _, field := range fields {
+			if field[0] == "file" {
+				w, err := form.CreateFormFile("file", "a.txt")
+				require.NoError(t, err)
+				_, err = w.Write([]byte(field[1]))
+				require.NoError(t, err)
+			} else {
+				require.NoError(t, form.WriteField(field[0], field[1]))
+			}
+		}
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		if owner != "" {
+			auth, err := json.Marshal(types.MetadataProposal{Proposal: saotypes.QueryProposal{Owner: owner}})
+			require.NoError(t, err)
+			req.Header.Set(echo.HeaderAuthorization, "DID "+base64.StdEncoding.EncodeToString(auth))
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if err := handler.serve(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec
+	}
+	proposal := `{"Proposal":{"owner":"did:key:a","alias":"file_a.txt","cid":"cid1","size":5}}`
+
+	rec := upload("did:key:a", [][2]string{{"proposal", proposal}, {"orderId", "7"}, {"file", "hello"}})
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "hello", uploader.content)
+	var resp apitypes.CreateResp
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "data1", resp.DataId)
+
+	require.Equal(t, http.StatusUnauthorized, upload("", [][2]string{{"proposal", proposal}, {"file", "hello"}}).Code)
+	require.Equal(t, http.StatusForbidden, upload("did:key:b", [][2]string{{"proposal", proposal}, {"file", "hello"}}).Code)
+	require.Equal(t, http.StatusBadRequest, upload("did:key:a", [][2]string{{"file", "hello"}, {"proposal", proposal}}).Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, upload("did:key:a", [][2]string{{"proposal", proposal}, {"file", strings.Repeat("a", 2048)}}).Code)
+}
+
+func TestPresignedUploadHandler(t *testing.T) {
+	e := echo.New()
+	uploader := &testUploader{}
+	handler := &uploadHandler{uploader: uploader, limit: 1024}
+	upload := func(token string, content string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/upload/"+token, strings.NewReader(content))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("token")
+		c.SetParamValues(token)
+		if err := handler.servePresigned(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec
+	}
+	presign := func(size uint64, orderOwner string, dataId string) string {
+		token, err := types.EncodeUploadToken(&types.UploadToken{
+			Request: types.MetadataProposal{
+				Proposal:   saotypes.QueryProposal{Owner: "did:key:a", Keyword: "data1"},
+				Delegation: &types.SignedDelegation{},
+			},
+			Order: types.OrderStoreProposal{
+				Proposal: saotypes.Proposal{Owner: orderOwner, DataId: dataId, Alias: "file_a.txt", Cid: "cid1", Size_: size},
+			},
+		})
+		require.NoError(t, err)
+		return token
+	}
+
+	rec := upload(presign(5, "did:key:a", "data1"), "hello")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "hello", uploader.content)
+
+	// the size of the body is not the one pre-signed
+	require.Equal(t, http.StatusBadRequest, upload(presign(5, "did:key:a", "data1"), "hello!").Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, upload(presign(2048, "did:key:a", "data1"), strings.Repeat("a", 2048)).Code)
+	require.Equal(t, http.StatusForbidden, upload(presign(5, "did:key:b", "data1"), "hello").Code)
+	// the query request is of another model
+	require.Equal(t, http.StatusBadRequest, upload(presign(5, "did:key:a", "data2"), "hello").Code)
+	require.Equal(t, http.StatusBadRequest, upload("invalid", "hello").Code)
+}
+
+type testShareLoader struct {
+	models map[string]apitypes.LoadResp
+}
+
+func (l *testShareLoader) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	if req.Delegation.Delegation.Nonce == "revoked" {
+		return apitypes.LoadResp{}, types.Wrapf(types.ErrInvalidDelegation, "revoked")
+	}
+	resp, ok := l.models[req.Proposal.Keyword]
+	if !ok {
+		return apitypes.LoadResp{}, types.ErrNotFound
+	}
+	return resp, nil
+}
+
+func TestShareHandler(t *testing.T) {
+	path := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(path, "data1"), []byte("large content"), 0644))
+	require.NoError(t, saveFileInfo(path, &types.Model{DataId: "data1", Alias: types.Type_Prefix_File + "a.txt", Cid: "cid1"}))
+
+	e := echo.New()
+	handler := &shareHandler{
+		loader: &testShareLoader{models: map[string]apitypes.LoadResp{
+			"data1": {DataId: "data1", Alias: types.Type_Prefix_File + "a.txt", Cid: "cid1"},
+			"data2": {DataId: "data2", Alias: "profile", Cid: "cid2", Content: `{"name":"sao"}`},
+			// the file is of another version
+			"data3": {DataId: "data1", Alias: types.Type_Prefix_File + "a.txt", Cid: "cid0"},
+		}},
+		path: path,
+	}
+	serve := func(keyword string, nonce string) *httptest.ResponseRecorder {
+		token, err := types.EncodeShareToken(&types.MetadataProposal{
+			Proposal:   saotypes.QueryProposal{Owner: "did:key:holder", Keyword: keyword},
+			Delegation: &types.SignedDelegation{Delegation: types.Delegation{Issuer: "did:key:a", Nonce: nonce}},
+		})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/share/"+token, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("token")
+		c.SetParamValues(token)
+		if err := handler.serve(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec
+	}
+
+	rec := serve("data1", "n1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "large content", rec.Body.String())
+	require.Equal(t, "inline; filename=a.txt", rec.Header().Get("Content-Disposition"))
+
+	rec = serve("data2", "n2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `{"name":"sao"}`, rec.Body.String())
+	require.Equal(t, `"cid2"`, rec.Header().Get("Etag"))
+
+	require.Equal(t, http.StatusServiceUnavailable, serve("data3", "n3").Code)
+	require.Equal(t, http.StatusForbidden, serve("data1", "revoked").Code)
+
+	// a query request without a delegation is not a share
+	auth, err := json.Marshal(types.MetadataProposal{Proposal: saotypes.QueryProposal{Owner: "did:key:a", Keyword: "data1"}})
+	require.NoError(t, err)
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/share/x", nil), httptest.NewRecorder())
+	c.SetParamNames("token")
+	c.SetParamValues(base64.RawURLEncoding.EncodeToString(auth))
+	require.Equal(t, http.StatusBadRequest, handler.serve(c).(*echo.HTTPError).Code)
+}
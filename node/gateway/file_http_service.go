@@ -11,7 +11,9 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/mitchellh/go-homedir"
 
+	"sao-node/node/certs"
 	"sao-node/node/config"
+	"sao-node/store"
 	"sao-node/types"
 )
 
@@ -27,7 +29,10 @@ type jwtClaims struct {
 	jwt.StandardClaims
 }
 
-func StartHttpFileServer(cfg *config.SaoHttpFileServer) (*HttpFileServer, error) {
+// StartHttpFileServer starts the file server, storeManager resolves the /ipfs/<cid> paths and is nil if
+// the node doesn't store data, uploader creates the models of the files uploaded to /upload, and loader loads
+// the models of the /share/<token> links. The ACME certificates are cached in acmeCache.
+func StartHttpFileServer(cfg *config.SaoHttpFileServer, storeManager *store.StoreManager, uploader Uploader, loader ShareLoader, acmeCache string) (*HttpFileServer, error) {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
@@ -46,17 +51,59 @@ func StartHttpFileServer(cfg *config.SaoHttpFileServer) (*HttpFileServer, error)
 		return nil, types.Wrap(types.ErrInvalidPath, err)
 	}
 
-	handler := http.FileServer(http.Dir(path))
+	handler := &fileHandler{path: path}
 
 	// Configure middleware with the custom claims type
 	config := middleware.JWTConfig{
 		Claims:     &jwtClaims{},
 		SigningKey: secret,
 	}
-	e.GET("/saonetwork/*", echo.WrapHandler(http.StripPrefix("/saonetwork/", handler)), middleware.JWTWithConfig(config))
+	e.GET("/saonetwork/*", handler.serve, middleware.JWTWithConfig(config))
+	e.HEAD("/saonetwork/*", handler.serve, middleware.JWTWithConfig(config))
 
+	if cfg.EnableIpfsPath {
+		ipfs := &ipfsPathHandler{storeManager: storeManager}
+		e.GET("/ipfs/:cid", ipfs.serve)
+		e.HEAD("/ipfs/:cid", ipfs.serve)
+	}
+
+	if cfg.EnableShare {
+		share := &shareHandler{loader: loader, path: path}
+		// authorized by the token instead of a jwt
+		e.GET("/share/:token", share.serve)
+		e.HEAD("/share/:token", share.serve)
+	}
+
+	if cfg.EnableUpload {
+		upload := &uploadHandler{uploader: uploader, limit: cfg.MaxUploadSize}
+		// authorized by the signed proposal instead of a token
+		if len(cfg.UploadAllowOrigins) > 0 {
+			cors := middleware.CORSWithConfig(middleware.CORSConfig{
+				AllowOrigins: cfg.UploadAllowOrigins,
+				AllowMethods: []string{http.MethodPost, http.MethodPut},
+				AllowHeaders: []string{echo.HeaderAuthorization, echo.HeaderContentType},
+			})
+			e.Match([]string{http.MethodPost, http.MethodOptions}, "/upload", upload.serve, cors)
+			e.Match([]string{http.MethodPut, http.MethodOptions}, "/upload/:token", upload.servePresigned, cors)
+		} else {
+			e.POST("/upload", upload.serve)
+			e.PUT("/upload/:token", upload.servePresigned)
+		}
+	}
+
+	tlsConfig, err := certs.NewTLSConfig(&cfg.TLS, acmeCache)
+	if err != nil {
+		return nil, err
+	}
 	go func() {
-		err := e.Start(cfg.HttpFileServerAddress)
+		var err error
+		if tlsConfig != nil {
+			e.TLSServer.TLSConfig = tlsConfig
+			e.TLSServer.Addr = cfg.HttpFileServerAddress
+			err = e.StartServer(e.TLSServer)
+		} else {
+			err = e.Start(cfg.HttpFileServerAddress)
+		}
 		if err != nil {
 			if strings.Contains(err.Error(), "Server closed") {
 				log.Info("stopping file http service...")
@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	apitypes "sao-node/api/types"
+	"sao-node/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ShareLoader loads the model of a share token, the request is verified the same as the ones of the API.
+type ShareLoader interface {
+	ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error)
+}
+
+// shareHandler serves /share/<token>, the model of the token is loaded on behalf of its owner, who signed the token.
+// The holder of the link doesn't need a DID.
+type shareHandler struct {
+	loader ShareLoader
+	// the directory of the http file server, where the large contents are loaded to
+	path string
+}
+
+func (h *shareHandler) serve(c echo.Context) error {
+	req, err := types.DecodeShareToken(c.Param("token"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	// the links in the content are never resolved by a share link
+	req.ResolveDepth = 0
+
+	resp, err := h.loader.ModelLoad(c.Request().Context(), req)
+	if err != nil {
+		return echo.NewHTTPError(shareStatus(err), err.Error())
+	}
+
+	info := loadFileInfo(h.path, resp.DataId)
+	if len(resp.Content) > 0 {
+		info.Alias, info.Cid = resp.Alias, resp.Cid
+		return serveContent(c, bytes.NewReader([]byte(resp.Content)), time.Time{}, resp.DataId, info)
+	}
+
+	// the large content is loaded to the file of the dataId, which is the version of the token unless another
+	// version is loaded since
+	if info.Cid != resp.Cid {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "the content is being loaded, try again later")
+	}
+	file, err := os.Open(filepath.Join(h.path, resp.DataId))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	return serveContent(c, file, stat.ModTime(), resp.DataId, info)
+}
+
+func shareStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrInvalidSignature), errors.Is(err, types.ErrInvalidDelegation), errors.Is(err, types.ErrInvalidDid):
+		return http.StatusForbidden
+	case errors.Is(err, types.ErrInvalidParameters), errors.Is(err, types.ErrInvalidVersion):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
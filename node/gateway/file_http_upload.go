@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	apitypes "sao-node/api/types"
+	"sao-node/types"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// the scheme of the Authorization header of an upload, followed by the base64 encoded json of the query
+// proposal signed by the DID of the owner
+const didAuthScheme = "DID"
+
+// Uploader stages the content of a file uploaded to the http file server and creates its model.
+type Uploader interface {
+	ModelCreateUpload(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content io.Reader) (apitypes.CreateResp, error)
+}
+
+// uploadHandler serves POST /upload, a multipart form of:
+//   - proposal, the json of the order proposal signed by the owner, its cid and size are the ones of the file
+//   - orderId, optional, the order already submitted by the client
+//   - file, the content, it must be the last part so it is staged while received
+type uploadHandler struct {
+	uploader Uploader
+	// maximum size of a request
+	limit int64
+}
+
+func (h *uploadHandler) serve(c echo.Context) error {
+	req, err := parseDidAuth(c.Request().Header.Get(echo.HeaderAuthorization))
+	if err != nil {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, didAuthScheme)
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+
+	body := &limitedBody{ReadCloser: c.Request().Body}
+	if h.limit > 0 {
+		body.ReadCloser = http.MaxBytesReader(c.Response(), c.Request().Body, h.limit)
+	}
+	c.Request().Body = body
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var orderProposal *types.OrderStoreProposal
+	var orderId uint64
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return echo.NewHTTPError(http.StatusBadRequest, "missing file")
+		}
+		if body.exceeded {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "the upload exceeds the limit")
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		switch part.FormName() {
+		case "proposal":
+			orderProposal = &types.OrderStoreProposal{}
+			if err := json.NewDecoder(io.LimitReader(part, 64<<10)).Decode(orderProposal); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid proposal: "+err.Error())
+			}
+		case "orderId":
+			value, _ := io.ReadAll(io.LimitReader(part, 32))
+			orderId, err = strconv.ParseUint(strings.TrimSpace(string(value)), 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid orderId")
+			}
+		case "file":
+			if orderProposal == nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "the proposal must come before the file")
+			}
+			resp, err := h.uploader.ModelCreateUpload(c.Request().Context(), req, orderProposal, orderId, part)
+			if body.exceeded {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "the upload exceeds the limit")
+			}
+			if err != nil {
+				return echo.NewHTTPError(uploadStatus(err), err.Error())
+			}
+			return c.JSON(http.StatusCreated, resp)
+		}
+		part.Close()
+	}
+}
+
+// servePresigned serves PUT /upload/<token>, the body is the content of the file pre-signed by the token, see
+// types.EncodeUploadToken. The model is created on behalf of the owner, who signed the token, the uploader doesn't
+// need a DID.
+func (h *uploadHandler) servePresigned(c echo.Context) error {
+	token, err := types.DecodeUploadToken(c.Param("token"))
+	if err != nil {
+		return echo.NewHTTPError(uploadStatus(err), err.Error())
+	}
+	size := int64(token.Order.Proposal.Size_)
+	if c.Request().ContentLength >= 0 && c.Request().ContentLength != size {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("uploading %d bytes, the token is of %d", c.Request().ContentLength, size))
+	}
+	if h.limit > 0 && size > h.limit {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "the upload exceeds the limit")
+	}
+
+	body := &limitedBody{ReadCloser: c.Request().Body}
+	if h.limit > 0 {
+		body.ReadCloser = http.MaxBytesReader(c.Response(), c.Request().Body, h.limit)
+	}
+	resp, err := h.uploader.ModelCreateUpload(c.Request().Context(), &token.Request, &token.Order, 0, body)
+	if body.exceeded {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "the upload exceeds the limit")
+	}
+	if err != nil {
+		return echo.NewHTTPError(uploadStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// limitedBody records the request body exceeding the limit, the error is not kept by the multipart reader.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// parseDidAuth decodes the query proposal of the Authorization header.
+func parseDidAuth(header string) (*types.MetadataProposal, error) {
+	scheme, value, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, didAuthScheme) {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "missing %s authorization", didAuthScheme)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimSpace(value))
+	}
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid %s authorization: %v", didAuthScheme, err)
+	}
+	var req types.MetadataProposal
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid %s authorization: %v", didAuthScheme, err)
+	}
+	return &req, nil
+}
+
+func uploadStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrInvalidSignature), errors.Is(err, types.ErrInvalidDelegation), errors.Is(err, types.ErrNoPermission):
+		return http.StatusForbidden
+	case errors.Is(err, types.ErrNoCapacity), errors.Is(err, types.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, types.ErrBusy):
+		return http.StatusTooManyRequests
+	case errors.Is(err, types.ErrInvalidDataId), errors.Is(err, types.ErrConflictId):
+		// the model is created already, by the upload of the token if pre-signed
+		return http.StatusConflict
+	case errors.Is(err, types.ErrInvalidParameters), errors.Is(err, types.ErrInvalidContent), errors.Is(err, types.ErrInvalidCid):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -8,6 +8,8 @@ import (
 type GatewayProtocol interface {
 	RequestShardAssign(ctx context.Context, req types.ShardAssignReq, peer string) types.ShardAssignResp
 	RequestShardLoad(ctx context.Context, req types.ShardLoadReq, peer string, isForward bool) types.ShardLoadResp
+	// RequestShardAudit challenges the storage node to return a shard of an order placed by this gateway
+	RequestShardAudit(ctx context.Context, req types.ShardLoadReq, peer string) types.ShardLoadResp
 	GetPeers(ctx context.Context) string
 	Stop(ctx context.Context) error
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sao-node/node/events"
 	"sao-node/store"
 	"sao-node/types"
 	"time"
@@ -11,22 +12,23 @@ import (
 
 type LocalGatewayProtocol struct {
 	GatewayProtocolHandler
-	chans        map[string]chan interface{}
+	bus          *events.Bus
 	storeManager *store.StoreManager
 }
 
 func NewLocalGatewayProtocol(
 	ctx context.Context,
-	chans map[string]chan interface{},
+	bus *events.Bus,
 	storeManager *store.StoreManager,
 	handler GatewayProtocolHandler,
 ) LocalGatewayProtocol {
 	p := LocalGatewayProtocol{
-		chans:                  chans,
+		bus:                    bus,
 		storeManager:           storeManager,
 		GatewayProtocolHandler: handler,
 	}
-	go p.listenShardComplete(ctx)
+	// unbuffered, the storage node waits for the completion to be handled as it does over a stream
+	bus.Consume(ctx, events.TopicLocalShardComplete, 0, p.handleShardComplete)
 	return p
 }
 
@@ -35,30 +37,23 @@ func (l LocalGatewayProtocol) Stop(_ context.Context) error {
 	return nil
 }
 
-func (l LocalGatewayProtocol) listenShardComplete(ctx context.Context) {
-	if c, exists := l.chans[types.ShardCompleteProtocol]; exists {
-		for {
-			select {
-			case t, ok := <-c:
-				if !ok {
-					return
-				}
-
-				resp := l.HandleShardComplete(t.(types.ShardCompleteReq))
-				if resp.Code != 0 {
-					// TODO: consider how to continue this order
-					log.Errorf(resp.Message)
-				}
-			case <-ctx.Done():
-				return
-			}
-		}
+func (l LocalGatewayProtocol) handleShardComplete(ev events.Event) {
+	resp := l.HandleShardComplete(ev.(events.LocalShardComplete).Req)
+	if resp.Code != 0 {
+		// TODO: consider how to continue this order
+		log.Errorf(resp.Message)
 	}
 }
 
 func (l LocalGatewayProtocol) RequestShardAssign(ctx context.Context, req types.ShardAssignReq, _ string) types.ShardAssignResp {
-	l.chans[types.ShardAssignProtocol] <- req
-	return types.ShardAssignResp{Code: 0}
+	if l.bus.Publish(ctx, events.LocalShardAssign{Req: req}) == 0 {
+		return types.ShardAssignResp{
+			Code:      types.ErrorCodeInternalErr,
+			Message:   "the storage module of this node is not enabled",
+			RequestId: req.RequestId,
+		}
+	}
+	return types.ShardAssignResp{Code: 0, RequestId: req.RequestId}
 }
 
 func (l LocalGatewayProtocol) RequestShardLoad(ctx context.Context, req types.ShardLoadReq, _ string, _ bool) types.ShardLoadResp {
@@ -99,6 +94,11 @@ func (l LocalGatewayProtocol) RequestShardLoad(ctx context.Context, req types.Sh
 	}
 }
 
+// RequestShardAudit reads the shard from the local store, as a load does.
+func (l LocalGatewayProtocol) RequestShardAudit(ctx context.Context, req types.ShardLoadReq, peer string) types.ShardLoadResp {
+	return l.RequestShardLoad(ctx, req, peer, false)
+}
+
 func (l LocalGatewayProtocol) GetPeers(_ context.Context) string {
 	return ""
 }
@@ -13,35 +13,41 @@ import (
 )
 
 type StreamGatewayProtocol struct {
-	ctx  context.Context
-	host host.Host
+	ctx         context.Context
+	host        host.Host
+	nodeAddress string
+	resolver    transport.NodePeerResolver
+	limiter     *transport.StreamLimiter
 	GatewayProtocolHandler
 	LocalGatewayProtocol
 }
 
-func NewStreamGatewayProtocol(ctx context.Context, host host.Host, handler GatewayProtocolHandler, local LocalGatewayProtocol) StreamGatewayProtocol {
+func NewStreamGatewayProtocol(ctx context.Context, host host.Host, nodeAddress string, resolver transport.NodePeerResolver, limiter *transport.StreamLimiter, handler GatewayProtocolHandler, local LocalGatewayProtocol) StreamGatewayProtocol {
 	sgp := StreamGatewayProtocol{
 		ctx:                    ctx,
 		host:                   host,
+		nodeAddress:            nodeAddress,
+		resolver:               resolver,
+		limiter:                limiter,
 		GatewayProtocolHandler: handler,
 		LocalGatewayProtocol:   local,
 	}
-	host.SetStreamHandler(types.ShardStoreProtocol, sgp.handleShardStoreStream)
-	host.SetStreamHandler(types.ShardCompleteProtocol, sgp.handleShardCompleteStream)
-	host.SetStreamHandler(types.ShardLoadProtocol, sgp.handleRelayStream)
-	host.SetStreamHandler(types.ShardPingPongProtocol, transport.HandlePingRequest)
+	transport.SetStreamHandler(host, types.ShardStoreProtocol, sgp.handleShardStoreStream)
+	transport.SetStreamHandler(host, types.ShardCompleteProtocol, sgp.handleShardCompleteStream)
+	transport.SetStreamHandler(host, types.ShardLoadProtocol, sgp.handleRelayStream)
+	transport.SetStreamHandler(host, types.ShardPingPongProtocol, transport.HandlePingRequest)
 	return sgp
 }
 
 func (l StreamGatewayProtocol) Stop(ctx context.Context) error {
 	log.Info("stopping stream gateway protocol ...")
-	l.host.RemoveStreamHandler(types.ShardStoreProtocol)
-	l.host.RemoveStreamHandler(types.ShardCompleteProtocol)
+	transport.RemoveStreamHandler(l.host, types.ShardStoreProtocol)
+	transport.RemoveStreamHandler(l.host, types.ShardCompleteProtocol)
 	return nil
 }
 
 func (l StreamGatewayProtocol) handleShardStoreStream(s network.Stream) {
-	log.Infof("handling %s ...", types.ShardStoreProtocol)
+	log.Infof("handling %s ...", s.Protocol())
 	defer s.Close()
 
 	respond := func(resp types.ShardLoadResp) {
@@ -57,12 +63,19 @@ func (l StreamGatewayProtocol) handleShardStoreStream(s network.Stream) {
 		}
 	}
 
-	// Set a deadline on reading from the stream so it doesn't hang
-	_ = s.SetReadDeadline(time.Now().Add(30 * time.Second))
-	defer s.SetReadDeadline(time.Time{}) // nolint
+	r, release, err := l.limiter.Begin(s, false)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardLoadResp{
+			Code:    types.ErrorCodeBusy,
+			Message: err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	var req types.ShardLoadReq
-	err := req.Unmarshal(s, types.FormatCbor)
+	err = req.Unmarshal(r, types.FormatCbor)
 	if err != nil {
 		log.Error(types.Wrap(types.ErrUnMarshalFailed, err))
 		respond(types.ShardLoadResp{
@@ -77,7 +90,7 @@ func (l StreamGatewayProtocol) handleShardStoreStream(s network.Stream) {
 }
 
 func (l StreamGatewayProtocol) handleShardCompleteStream(s network.Stream) {
-	log.Infof("handling %s ...", types.ShardCompleteProtocol)
+	log.Infof("handling %s ...", s.Protocol())
 	defer s.Close()
 
 	respond := func(resp types.ShardCompleteResp) {
@@ -93,26 +106,36 @@ func (l StreamGatewayProtocol) handleShardCompleteStream(s network.Stream) {
 		}
 	}
 
-	// Set a deadline on reading from the stream so it doesn't hang
-	_ = s.SetReadDeadline(time.Now().Add(30 * time.Second))
-	defer s.SetReadDeadline(time.Time{}) // nolint
+	r, release, err := l.limiter.Begin(s, false)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardCompleteResp{
+			Code:        types.ErrorCodeBusy,
+			Message:     err.Error(),
+			Recoverable: true,
+		})
+		return
+	}
+	defer release()
 
 	var req types.ShardCompleteReq
-	err := req.Unmarshal(s, types.FormatCbor)
+	_, err = transport.ReadSignedRequest(l.ctx, s, r, l.resolver, &req)
 	if err != nil {
-		log.Error(types.Wrap(types.ErrUnMarshalFailed, err))
+		log.Error(err)
 		respond(types.ShardCompleteResp{
-			Code:    types.ErrorCodeInvalidRequest,
-			Message: fmt.Sprintf("failed to unmarshal request: %v", err),
+			Code:      transport.RequestErrorCode(err),
+			Message:   fmt.Sprintf("failed to read request: %v", err),
+			RequestId: req.RequestId,
 		})
 		return
 	}
+	log.Debugf("receive ShardCompleteReq: orderId=%d cids=%v requestId=%d", req.OrderId, req.Cids, req.RequestId)
 
 	respond(l.HandleShardComplete(req))
 }
 
 func (l StreamGatewayProtocol) handleRelayStream(s network.Stream) {
-	log.Infof("handling relay %s ...", types.ShardLoadProtocol)
+	log.Infof("handling relay %s ...", s.Protocol())
 	defer s.Close()
 
 	respond := func(resp types.ShardLoadResp) {
@@ -128,12 +151,19 @@ func (l StreamGatewayProtocol) handleRelayStream(s network.Stream) {
 		}
 	}
 
-	// Set a deadline on reading from the stream so it doesn't hang
-	_ = s.SetReadDeadline(time.Now().Add(30 * time.Second))
-	defer s.SetReadDeadline(time.Time{}) // nolint
+	r, release, err := l.limiter.Begin(s, false)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardLoadResp{
+			Code:    types.ErrorCodeBusy,
+			Message: err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	var req types.ShardLoadReq
-	err := req.Unmarshal(s, types.FormatCbor)
+	err = req.Unmarshal(r, types.FormatCbor)
 	if err != nil {
 		log.Error(types.Wrap(types.ErrUnMarshalFailed, err))
 		respond(types.ShardLoadResp{
@@ -144,34 +174,44 @@ func (l StreamGatewayProtocol) handleRelayStream(s network.Stream) {
 	}
 	log.Debugf("receive Relay ShardLoadReq: orderId=%d cid=%v requestId=%d", req.OrderId, req.Cid, req.RequestId)
 
-	if strings.Contains(req.RelayProposal.Proposal.TargetPeerInfo, l.host.ID().String()) {
+	target := req.RelayProposal.Proposal.TargetPeerInfo
+	if strings.Contains(target, l.host.ID().String()) {
 		// should not happen
-		respond(l.LocalGatewayProtocol.RequestShardLoad(l.ctx, req, req.RelayProposal.Proposal.TargetPeerInfo, false))
-	} else {
-		for _, peer := range l.host.Peerstore().Peers() {
-			if strings.Contains(req.RelayProposal.Proposal.TargetPeerInfo, peer.String()) {
-				respond(l.RequestShardLoad(l.ctx, req, req.RelayProposal.Proposal.TargetPeerInfo, false))
-				break
-			}
+		respond(l.LocalGatewayProtocol.RequestShardLoad(l.ctx, req, target, false))
+		return
+	}
+	// the storage nodes behind NAT are connected through their reservation on this node
+	for _, peer := range l.host.Network().Peers() {
+		if strings.Contains(target, peer.String()) {
+			respond(l.RequestShardLoad(l.ctx, req, target, false))
+			return
 		}
 	}
+	respond(types.ShardLoadResp{
+		Code:      types.ErrorCodeInternalErr,
+		Message:   fmt.Sprintf("no connection to the relay target %s", target),
+		OrderId:   req.OrderId,
+		Cid:       req.Cid,
+		RequestId: req.RequestId,
+	})
 }
 
 func (l StreamGatewayProtocol) RequestShardAssign(ctx context.Context, req types.ShardAssignReq, peer string) types.ShardAssignResp {
 	var resp types.ShardAssignResp
-	err := transport.HandleRequest(
+	err := transport.HandleSignedRequest(
 		ctx,
 		peer,
 		l.host,
+		l.nodeAddress,
 		types.ShardAssignProtocol,
 		&req,
 		&resp,
-		false,
 	)
 	if err != nil {
 		resp = types.ShardAssignResp{
-			Code:    types.ErrorCodeInternalErr,
-			Message: fmt.Sprintf("transport assign request error: %v", err),
+			Code:      types.ErrorCodeInternalErr,
+			Message:   fmt.Sprintf("transport assign request error: %v", err),
+			RequestId: req.RequestId,
 		}
 	}
 	return resp
@@ -202,6 +242,30 @@ func (l StreamGatewayProtocol) RequestShardLoad(ctx context.Context, req types.S
 	return resp
 }
 
+func (l StreamGatewayProtocol) RequestShardAudit(ctx context.Context, req types.ShardLoadReq, peer string) types.ShardLoadResp {
+	var resp types.ShardLoadResp
+	err := transport.HandleSignedRequest(
+		ctx,
+		peer,
+		l.host,
+		l.nodeAddress,
+		types.ShardAuditProtocol,
+		&req,
+		&resp,
+	)
+	if err != nil {
+		resp = types.ShardLoadResp{
+			Code:       types.ErrorCodeInternalErr,
+			Message:    fmt.Sprintf("transport audit request error: %v", err),
+			OrderId:    req.OrderId,
+			Cid:        req.Cid,
+			RequestId:  req.RequestId,
+			ResponseId: time.Now().UnixMilli(),
+		}
+	}
+	return resp
+}
+
 func (l StreamGatewayProtocol) GetPeers(_ context.Context) string {
 	return l.host.Peerstore().Peers().String()
 }
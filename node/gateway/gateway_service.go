@@ -5,14 +5,23 @@ import (
 	"context"
 	"fmt"
 	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sao-node/chain"
+	"sao-node/node/alert"
 	"sao-node/node/config"
+	"sao-node/node/events"
+	"sao-node/node/platform"
+	"sao-node/node/reputation"
+	"sao-node/node/staging"
+	"sao-node/node/transport"
 	"sao-node/store"
 	"sao-node/types"
 	"sao-node/utils"
+	"sao-node/utils/bufpool"
+	"sort"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/types/tx"
@@ -24,6 +33,7 @@ import (
 
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
 )
 
 var log = logging.Logger("gateway")
@@ -32,6 +42,10 @@ const (
 	WINDOW_SIZE       = 10
 	SCHEDULE_INTERVAL = 1
 	LOCKNAME_COMPLETE = "complete"
+
+	// how long a handled shard complete request is remembered, retries of it within the window are
+	// answered directly.
+	COMPLETE_DEDUP_WINDOW = 24 * time.Hour
 )
 
 type CommitResult struct {
@@ -47,11 +61,16 @@ type CommitResult struct {
 type FetchResult struct {
 	Cid     string
 	Content []byte
+	// file of the http file server the content is loaded to, for the file models and the content too large to
+	// return
+	Path string
 }
 
 type GatewaySvcApi interface {
 	QueryMeta(ctx context.Context, req *types.MetadataProposal, height int64) (*types.Model, error)
 	CommitModel(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte) (*CommitResult, error)
+	CommitModelAsync(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte) (*CommitResult, error)
+	CommitModelFile(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, path string) (*CommitResult, error)
 	FetchContent(ctx context.Context, req *types.MetadataProposal, meta *types.Model) (*FetchResult, error)
 	TerminateOrder(ctx context.Context, req *types.OrderTerminateProposal) error
 	RenewOrder(ctx context.Context, req *types.OrderRenewProposal) (map[string]string, error)
@@ -59,7 +78,9 @@ type GatewaySvcApi interface {
 	Stop(ctx context.Context) error
 	OrderStatus(ctx context.Context, id string) (types.OrderInfo, error)
 	OrderFix(ctx context.Context, id string) error
-	OrderList(ctx context.Context) ([]types.OrderInfo, error)
+	OrderList(ctx context.Context, filter types.OrderFilter) ([]types.OrderInfo, error)
+	OrderAudits(ctx context.Context, underReplicated bool) ([]types.AuditInfo, error)
+	OpenStaged(path string) (*staging.File, error)
 }
 
 type WorkRequest struct {
@@ -80,9 +101,17 @@ type GatewaySvc struct {
 
 	schedQueue *RequestQueue
 	locks      *utils.Maplock
+	// the orders processed, shared by the gateways of a cluster
+	orderLocks OrderLocker
 
 	completeResultChan chan string
 	completeMap        map[string]int64
+	reputation         *reputation.Store
+	bus                *events.Bus
+	staging            *staging.Quota
+	platforms          *platform.Registry
+	cipher             *staging.Cipher
+	alerts             *alert.Manager
 }
 
 func NewGatewaySvc(
@@ -92,9 +121,11 @@ func NewGatewaySvc(
 	host host.Host,
 	cfg *config.Node,
 	storeManager *store.StoreManager,
-	notifyChan map[string]chan interface{},
+	bus *events.Bus,
 	orderDs datastore.Batching,
+	orderLocks OrderLocker,
 	keyringHome string,
+	limiter *transport.StreamLimiter,
 ) *GatewaySvc {
 	cs := &GatewaySvc{
 		ctx:                ctx,
@@ -110,12 +141,17 @@ func NewGatewaySvc(
 		orderDs:            orderDs,
 		schedQueue:         &RequestQueue{},
 		locks:              utils.NewMapLock(),
+		orderLocks:         orderLocks,
+		bus:                bus,
+	}
+	if cs.orderLocks == nil {
+		cs.orderLocks = cs.locks
 	}
 	cs.gatewayProtocolMap = make(map[string]GatewayProtocol)
 
 	local := NewLocalGatewayProtocol(
 		ctx,
-		notifyChan,
+		bus,
 		storeManager,
 		cs,
 	)
@@ -123,6 +159,9 @@ func NewGatewaySvc(
 	cs.gatewayProtocolMap["stream"] = NewStreamGatewayProtocol(
 		ctx,
 		host,
+		nodeAddress,
+		chainSvc,
+		limiter,
 		cs,
 		local,
 	)
@@ -134,35 +173,282 @@ func NewGatewaySvc(
 	return cs
 }
 
+// SetReputation ranks the replicas by the reputation of their storage providers, the shards are loaded from the
+// replicas of the best reputation first.
+func (gs *GatewaySvc) SetReputation(store *reputation.Store) {
+	gs.reputation = store
+}
+
 func (gs *GatewaySvc) completeLoop(ctx context.Context) {
+	pruneTicker := time.NewTicker(time.Hour)
+	defer pruneTicker.Stop()
+	timeoutTicker := time.NewTicker(time.Minute)
+	defer timeoutTicker.Stop()
+
 	for {
 		select {
 		case dataId := <-gs.completeResultChan:
 			gs.locks.Lock("complete")
 			delete(gs.completeMap, dataId)
 			gs.locks.Unlock("complete")
-		case <-time.After(time.Minute):
+		case <-pruneTicker.C:
+			pruned, err := utils.PruneCompleteRecords(ctx, gs.orderDs, time.Now().Add(-COMPLETE_DEDUP_WINDOW).Unix())
+			if err != nil {
+				log.Warnf("prune complete records error: %v", err)
+			} else if pruned > 0 {
+				log.Debugf("pruned %d complete records", pruned)
+			}
+		case <-timeoutTicker.C:
+			gs.checkTimeouts(ctx)
 		case <-ctx.Done():
 			return
 		}
-		// TODO: handle wait completion timeout.
 	}
 }
 
+// checkTimeouts closes the orders waiting for completion past their expire height.
+func (gs *GatewaySvc) checkTimeouts(ctx context.Context) {
+	gs.locks.Lock("complete")
+	dataIds := make([]string, 0, len(gs.completeMap))
+	for dataId := range gs.completeMap {
+		dataIds = append(dataIds, dataId)
+	}
+	gs.locks.Unlock("complete")
+	if len(dataIds) == 0 {
+		return
+	}
+
+	latestHeight, err := gs.chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		log.Warnf("check order timeouts error: %v", err)
+		return
+	}
+	for _, dataId := range dataIds {
+		orderInfo, err := utils.GetOrder(ctx, gs.orderDs, dataId)
+		if err != nil {
+			log.Warnf("get order dataId=%s error: %v", dataId, err)
+			continue
+		}
+
+		lk := lockname(orderInfo.OrderId)
+		gs.orderLocks.Lock(lk)
+		// reload as the shards may complete meanwhile
+		orderInfo, err = utils.GetOrder(ctx, gs.orderDs, dataId)
+		if err == nil && orderInfo.State == types.OrderStateReady && orderInfo.ExpireHeight > 0 && latestHeight > int64(orderInfo.ExpireHeight) {
+			err = gs.expire(ctx, &orderInfo, latestHeight)
+		}
+		gs.orderLocks.Unlock(lk)
+		if err != nil {
+			log.Warnf("expire order dataId=%s error: %v", dataId, err)
+		}
+	}
+}
+
+// expire closes an order which isn't completed before its expire height, the order is canceled if it's still
+// open on chain. The final status on chain is recorded for the client, unless the order is republished.
+func (gs *GatewaySvc) expire(ctx context.Context, orderInfo *types.OrderInfo, latestHeight int64) error {
+	order, err := gs.chainSvc.GetOrder(ctx, orderInfo.OrderId)
+	if err != nil {
+		return err
+	}
+
+	switch order.Status {
+	case ordertypes.OrderCompleted:
+		// the last shard complete was missed
+		orderInfo.State = types.OrderStateComplete
+	case ordertypes.OrderPending, ordertypes.OrderInProgress, ordertypes.OrderDataReady:
+		txHash, err := gs.chainSvc.CancelOrder(ctx, gs.gatewaySigner(), orderInfo.OrderId)
+		if err != nil {
+			log.Warnf("cancel order %d error: %v", orderInfo.OrderId, err)
+		} else {
+			log.Infof("order %d canceled, tx=%s", orderInfo.OrderId, txHash)
+			order, err = gs.chainSvc.GetOrder(ctx, orderInfo.OrderId)
+			if err != nil {
+				return err
+			}
+		}
+		fallthrough
+	default:
+		orderInfo.State = types.OrderStateExpired
+		orderInfo.LastErr = fmt.Sprintf("order expired: latest=%d expireAt=%d status=%s",
+			latestHeight, orderInfo.ExpireHeight, chain.OrderStatusString(order.Status))
+	}
+	orderInfo.ChainStatus = int64(order.Status)
+	if orderInfo.State == types.OrderStateExpired {
+		for provider, shard := range order.Shards {
+			if shard.Status != ordertypes.ShardCompleted {
+				gs.bus.Publish(ctx, events.ShardEvent{
+					Kind:     events.ShardTimedOut,
+					Provider: provider,
+					OrderId:  orderInfo.OrderId,
+					Time:     time.Now(),
+				})
+			}
+		}
+	}
+	if orderInfo.State == types.OrderStateExpired && (order.Status == ordertypes.OrderCanceled || order.Status == ordertypes.OrderExpired) {
+		republished, err := gs.republish(ctx, orderInfo)
+		if republished {
+			return err
+		}
+		if err != nil {
+			log.Warnf("republish order %d error: %v", orderInfo.OrderId, err)
+		}
+	}
+	err = gs.saveOrderState(ctx, *orderInfo)
+	if err != nil {
+		return err
+	}
+	log.Infof("order %d closed as %s, status on chain: %s", orderInfo.OrderId, orderInfo.State, chain.OrderStatusString(order.Status))
+
+	err = gs.unstage(orderInfo)
+	if err != nil {
+		log.Warnf("unstage shard %s error: %v", orderInfo.Cid, err)
+	}
+	gs.locks.Lock("complete")
+	delete(gs.completeMap, orderInfo.DataId)
+	gs.locks.Unlock("complete")
+	return nil
+}
+
+// republish stores a closed order again with the client proposal, the chain assigns its shards to storage nodes
+// anew. The staged content is kept for the new order. true is returned once the new order is stored, the order
+// is then tracked by the new order id.
+func (gs *GatewaySvc) republish(ctx context.Context, orderInfo *types.OrderInfo) (bool, error) {
+	if len(orderInfo.Proposal) == 0 || orderInfo.Republishes >= uint64(gs.cfg.Gateway.MaxRepublish) {
+		return false, nil
+	}
+
+	var proposal saotypes.Proposal
+	err := proposal.Unmarshal(orderInfo.Proposal)
+	if err != nil {
+		return false, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	var signature saotypes.JwsSignature
+	err = signature.Unmarshal(orderInfo.JwsSignature)
+	if err != nil {
+		return false, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	resp, txHash, height, err := gs.chainSvc.StoreOrder(ctx, gs.gatewaySigner(), &types.OrderStoreProposal{
+		Proposal:     proposal,
+		JwsSignature: signature,
+	})
+	if err != nil {
+		return false, err
+	}
+	log.Infof("order %d republished as order %d, tx=%s", orderInfo.OrderId, resp.OrderId, txHash)
+
+	orderInfo.LastErr = fmt.Sprintf("republished as order %d, %s", resp.OrderId, orderInfo.LastErr)
+	orderInfo.OrderId = resp.OrderId
+	orderInfo.Republishes++
+	orderInfo.State = types.OrderStateStaged
+	orderInfo.ChainStatus = 0
+	orderInfo.Tries = 0
+	orderInfo.RetryAt = 0
+	err = gs.saveOrderState(ctx, *orderInfo)
+	if err != nil {
+		return true, err
+	}
+
+	if len(resp.Shards) == 0 {
+		return true, gs.ready(ctx, orderInfo)
+	}
+	err = gs.setReady(ctx, orderInfo, txHash, height, types.AssignTxTypeStore, resp.Shards)
+	if err != nil {
+		return true, err
+	}
+	gs.schedQueue.Push(&WorkRequest{Order: *orderInfo})
+	return true, nil
+}
+
+// processIncompleteOrders resumes the orders interrupted by a restart, the staged orders are readied and all
+// of them are scheduled to assign their shards again.
 func (gs *GatewaySvc) processIncompleteOrders(ctx context.Context) {
 	log.Info("process pending orders...")
 	pendings, err := gs.getPendingOrders(ctx)
 	if err != nil {
-		log.Error("process pending orders error: %v", err)
-	} else {
-		for _, p := range pendings {
-			gs.schedQueue.Push(&WorkRequest{
-				Order: p,
-			})
+		log.Errorf("process pending orders error: %v", err)
+		return
+	}
+	for _, p := range pendings {
+		p := p
+		if p.State == types.OrderStateStaged {
+			err := gs.recoverStaged(ctx, &p)
+			if err != nil {
+				log.Warnf("recover staged order dataId=%s error: %v", p.DataId, err)
+			}
+			continue
 		}
+		gs.schedQueue.Push(&WorkRequest{
+			Order: p,
+		})
 	}
 }
 
+// recoverStaged readies an order staged before the restart. The order can only be resumed once its store tx is
+// recorded and while it is pending on chain, otherwise the proposal or the assigning tx is lost and the order is
+// terminated for the client to commit again.
+func (gs *GatewaySvc) recoverStaged(ctx context.Context, orderInfo *types.OrderInfo) error {
+	gs.orderLocks.Lock(lockname(orderInfo.OrderId))
+	defer gs.orderLocks.Unlock(lockname(orderInfo.OrderId))
+
+	if !gs.reload(ctx, orderInfo) || orderInfo.State != types.OrderStateStaged {
+		return nil
+	}
+	if orderInfo.OrderId == 0 {
+		return gs.terminate(ctx, orderInfo, "interrupted before the order was stored on chain")
+	}
+
+	order, err := gs.chainSvc.GetOrder(ctx, orderInfo.OrderId)
+	if err != nil {
+		return err
+	}
+	if order.Status != ordertypes.OrderPending {
+		return gs.terminate(ctx, orderInfo, fmt.Sprintf("interrupted after the order %d was readied, status=%d", orderInfo.OrderId, order.Status))
+	}
+
+	log.Infof("resuming staged order %d", orderInfo.OrderId)
+	return gs.ready(ctx, orderInfo)
+}
+
+// reload refreshes the order locked by the caller, which may be processed by another gateway of the cluster
+// meanwhile. false is returned if the order is republished under another order id since.
+func (gs *GatewaySvc) reload(ctx context.Context, orderInfo *types.OrderInfo) bool {
+	stored, err := utils.GetOrder(ctx, gs.orderDs, orderInfo.DataId)
+	if err != nil {
+		log.Warnf("reload order dataId=%s error: %v", orderInfo.DataId, err)
+		return true
+	}
+	if stored.OrderId != orderInfo.OrderId {
+		log.Infof("order %d of dataId=%s is processed as order %d", orderInfo.OrderId, orderInfo.DataId, stored.OrderId)
+		return false
+	}
+	*orderInfo = stored
+	return true
+}
+
+func (gs *GatewaySvc) terminate(ctx context.Context, orderInfo *types.OrderInfo, reason string) error {
+	log.Warnf("order dataId=%s terminated: %s", orderInfo.DataId, reason)
+	orderInfo.State = types.OrderStateTerminate
+	orderInfo.LastErr = reason
+	return gs.saveOrderState(ctx, *orderInfo)
+}
+
+// saveOrderState saves the order moved to a new state, and publishes the change.
+func (gs *GatewaySvc) saveOrderState(ctx context.Context, orderInfo types.OrderInfo) error {
+	if err := utils.SaveOrder(ctx, gs.orderDs, orderInfo); err != nil {
+		return err
+	}
+	gs.bus.Publish(ctx, events.OrderEvent{
+		DataId:  orderInfo.DataId,
+		OrderId: orderInfo.OrderId,
+		Owner:   orderInfo.Owner,
+		State:   orderInfo.State,
+		Time:    time.Now(),
+	})
+	return nil
+}
+
 func (gs *GatewaySvc) runSched(ctx context.Context, host host.Host) {
 	throttle := make(chan struct{}, WINDOW_SIZE)
 	for {
@@ -202,12 +488,18 @@ func (gs *GatewaySvc) runSched(ctx context.Context, host host.Host) {
 // -----------------  GatewayProtocolHandler Impl -----------------
 func (gs *GatewaySvc) HandleShardComplete(req types.ShardCompleteReq) types.ShardCompleteResp {
 	logAndRespond := func(errMsg string, code uint64) types.ShardCompleteResp {
-		log.Error(errMsg)
+		log.Errorf("requestId=%d orderId=%d: %s", req.RequestId, req.OrderId, errMsg)
 		return types.ShardCompleteResp{
-			Code:    code,
-			Message: errMsg,
+			Code:      code,
+			Message:   errMsg,
+			RequestId: req.RequestId,
 		}
 	}
+	log.Infof("requestId=%d orderId=%d: handling shard complete, txHash=%s", req.RequestId, req.OrderId, req.TxHash)
+
+	if gs.isCompleteHandled(req) {
+		return types.ShardCompleteResp{Code: 0, RequestId: req.RequestId}
+	}
 
 	// query tx
 	resultTx, err := gs.chainSvc.GetTx(gs.ctx, req.TxHash, req.Height)
@@ -280,8 +572,13 @@ func (gs *GatewaySvc) HandleShardComplete(req types.ShardCompleteReq) types.Shar
 		}
 	}
 
-	gs.locks.Lock(lockname(req.OrderId))
-	defer gs.locks.Unlock(lockname(req.OrderId))
+	gs.orderLocks.Lock(lockname(req.OrderId))
+	defer gs.orderLocks.Unlock(lockname(req.OrderId))
+
+	// a retry of the same request may have been handled while validating this one
+	if gs.isCompleteHandled(req) {
+		return types.ShardCompleteResp{Code: 0, RequestId: req.RequestId}
+	}
 
 	orderInfo, err := utils.GetOrder(gs.ctx, gs.orderDs, req.DataId)
 	if err != nil {
@@ -290,6 +587,16 @@ func (gs *GatewaySvc) HandleShardComplete(req types.ShardCompleteReq) types.Shar
 			types.ErrorCodeInternalErr,
 		)
 	}
+	gs.bus.Publish(gs.ctx, events.ShardEvent{
+		Kind:     events.ShardCompleted,
+		Provider: m.Creator,
+		OrderId:  req.OrderId,
+		Cid:      order.Cid,
+		Size:     order.Size_,
+		Replicas: int(order.Replica),
+		Ok:       true,
+		Time:     time.Now(),
+	})
 	shardInfo := orderInfo.Shards[m.Creator]
 	shardInfo.State = types.ShardStateCompleted
 	shardInfo.CompleteHash = req.TxHash
@@ -301,20 +608,44 @@ func (gs *GatewaySvc) HandleShardComplete(req types.ShardCompleteReq) types.Shar
 	if orderInfo.State != types.OrderStateComplete && order.Status == ordertypes.OrderCompleted {
 		log.Debugf("complete channel done. order %d completes", orderInfo.OrderId)
 		orderInfo.State = types.OrderStateComplete
-		err = utils.SaveOrder(gs.ctx, gs.orderDs, orderInfo)
+		err = gs.saveOrderState(gs.ctx, orderInfo)
 		if err != nil {
 			log.Warn("put order %d error: %v", orderInfo.OrderId, err)
 		}
 
-		log.Debugf("unstage shard %s/%s/%v", gs.stagingPath, orderInfo.Owner, orderInfo.Cid)
-		err := UnstageShard(gs.stagingPath, orderInfo.Owner, orderInfo.Cid.String())
+		log.Debugf("unstage shard %s/%v", orderInfo.Owner, orderInfo.Cid)
+		err := gs.unstage(&orderInfo)
 		if err != nil {
 			log.Warnf("unstage shard error: %v", err)
 		}
 
 		gs.completeResultChan <- orderInfo.DataId
 	}
-	return types.ShardCompleteResp{Code: 0}
+
+	err = utils.SaveCompleteRecord(gs.ctx, gs.orderDs, types.CompleteRecord{
+		OrderId:   req.OrderId,
+		TxHash:    req.TxHash,
+		HandledAt: time.Now().Unix(),
+	})
+	if err != nil {
+		log.Warnf("save complete record order=%d txHash=%s error: %v", req.OrderId, req.TxHash, err)
+	}
+	return types.ShardCompleteResp{Code: 0, RequestId: req.RequestId}
+}
+
+func (gs *GatewaySvc) isCompleteHandled(req types.ShardCompleteReq) bool {
+	record, err := utils.GetCompleteRecord(gs.ctx, gs.orderDs, req.OrderId, req.TxHash)
+	if err != nil {
+		log.Warnf("get complete record order=%d txHash=%s error: %v", req.OrderId, req.TxHash, err)
+		return false
+	}
+	if record.TxHash == "" || time.Since(time.Unix(record.HandledAt, 0)) > COMPLETE_DEDUP_WINDOW {
+		return false
+	}
+
+	log.Infof("requestId=%d orderId=%d: shard complete with txHash=%s has been handled at %s, skip it",
+		req.RequestId, req.OrderId, req.TxHash, time.Unix(record.HandledAt, 0).Format(time.RFC3339))
+	return true
 }
 
 func (gs *GatewaySvc) HandleShardStore(req types.ShardLoadReq) types.ShardLoadResp {
@@ -325,7 +656,11 @@ func (gs *GatewaySvc) HandleShardStore(req types.ShardLoadReq) types.ShardLoadRe
 		ResponseId: time.Now().UnixMilli(),
 	}
 
-	contentBytes, err := GetStagedShard(gs.stagingPath, req.Owner, req.Cid)
+	path, err := staging.Locate(gs.stagingPaths(), req.Owner, req.Cid.String())
+	var contentBytes []byte
+	if err == nil {
+		contentBytes, err = ReadStaged(path, gs.cipher)
+	}
 	if err != nil {
 		resp.Code = types.ErrorCodeInternalErr
 		resp.Message = fmt.Sprintf("Get staged shard(%v) error: %v", req.Cid, err)
@@ -336,8 +671,35 @@ func (gs *GatewaySvc) HandleShardStore(req types.ShardLoadReq) types.ShardLoadRe
 	return resp
 }
 
+type referrerKey struct{}
+
+// WithReferrer returns the context of loading a model referred to by the content of the model loaded by req, which
+// is verified already. The query proposal of the referred model isn't signed, so its metadata is queried on behalf
+// of the did req is verified for and its shards are loaded with the signed proposal of req.
+func WithReferrer(ctx context.Context, req *types.MetadataProposal) context.Context {
+	return context.WithValue(ctx, referrerKey{}, req)
+}
+
+func referrer(ctx context.Context) *types.MetadataProposal {
+	req, _ := ctx.Value(referrerKey{}).(*types.MetadataProposal)
+	return req
+}
+
 func (gs *GatewaySvc) QueryMeta(ctx context.Context, req *types.MetadataProposal, height int64) (*types.Model, error) {
-	res, err := gs.chainSvc.QueryMetadata(ctx, req, height)
+	var res *saotypes.QueryMetadataResponse
+	var err error
+	if ref := referrer(ctx); ref != nil {
+		did := ref.Proposal.Owner
+		if ref.Delegation != nil {
+			did = ref.Delegation.Delegation.Issuer
+		}
+		res, err = gs.chainSvc.QueryMetadataAs(ctx, req.Proposal, did, height)
+	} else if req.Delegation != nil {
+		// the delegation is verified along with the proposal, query on behalf of the issuer
+		res, err = gs.chainSvc.QueryMetadataAs(ctx, req.Proposal, req.Delegation.Delegation.Issuer, height)
+	} else {
+		res, err = gs.chainSvc.QueryMetadata(ctx, req, height)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -363,63 +725,89 @@ func (gs *GatewaySvc) QueryMeta(ctx context.Context, req *types.MetadataProposal
 		Commits:  res.Metadata.Commits,
 		// Content: N/a,
 		ExtendInfo: res.Metadata.ExtendInfo,
+		Rule:       res.Metadata.Rule,
 	}, nil
 }
 
 func (gs *GatewaySvc) FetchContent(ctx context.Context, req *types.MetadataProposal, meta *types.Model) (*FetchResult, error) {
-	contentList := make([][]byte, len(meta.Shards))
+	// the replicas of each shard, tried from the provider of the best reputation
+	replicas := make(map[uint64][]string)
+	var shardIds []uint64
 	for key, shard := range meta.Shards {
-		if contentList[shard.ShardId] != nil {
-			continue
+		if _, exists := replicas[shard.ShardId]; !exists {
+			shardIds = append(shardIds, shard.ShardId)
 		}
+		replicas[shard.ShardId] = append(replicas[shard.ShardId], key)
+	}
+	sort.Slice(shardIds, func(i, j int) bool { return shardIds[i] < shardIds[j] })
 
-		shardCid, err := cid.Decode(shard.Cid)
-		if err != nil {
-			return nil, types.Wrapf(types.ErrInvalidCid, "%s", shard.Cid)
-		}
+	path, err := homedir.Expand(gs.cfg.SaoHttpFileServer.HttpFileServerPath)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidPath, "%s", gs.cfg.SaoHttpFileServer.HttpFileServerPath)
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, types.Wrap(types.ErrCreateDirFailed, err)
+	}
+	// the shards are appended to a file of the http file server, so the content is never held in memory whole.
+	// The dot file is hidden from the clients until it is renamed to the dataId.
+	file, err := os.CreateTemp(path, ".fetch-*")
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateFileFailed, err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var size int64
+	for _, shardId := range shardIds {
+		providers := replicas[shardId]
+		gs.reputation.Rank(providers)
+
+		var lastErr string
+		loaded := false
+		for _, key := range providers {
+			shard := meta.Shards[key]
+			shardCid, err := cid.Decode(shard.Cid)
+			if err != nil {
+				return nil, types.Wrapf(types.ErrInvalidCid, "%s", shard.Cid)
+			}
 
-		var gp GatewayProtocol
-		if key == gs.nodeAddress {
-			gp = gs.gatewayProtocolMap["local"]
-		} else {
-			gp = gs.gatewayProtocolMap["stream"]
-		}
-
-		resp := gp.RequestShardLoad(ctx, types.ShardLoadReq{
-			Cid:     shardCid,
-			OrderId: meta.OrderId,
-			Proposal: types.MetadataProposalCbor{
-				Proposal: types.QueryProposal{
-					Owner:           req.Proposal.Owner,
-					Keyword:         req.Proposal.Keyword,
-					GroupId:         req.Proposal.GroupId,
-					KeywordType:     uint64(req.Proposal.KeywordType),
-					LastValidHeight: req.Proposal.LastValidHeight,
-					Gateway:         req.Proposal.Gateway,
-					CommitId:        req.Proposal.CommitId,
-					Version:         req.Proposal.Version,
-				},
-				JwsSignature: types.JwsSignature{
-					Protected: req.JwsSignature.Protected,
-					Signature: req.JwsSignature.Signature,
-				},
-			},
-			RequestId:     time.Now().UnixMilli(),
-			RelayProposal: gs.buildRelayProposal(ctx, gp, shard.Peer),
-		}, shard.Peer, true)
-		if resp.Code == 0 {
-			contentList[shard.ShardId] = resp.Content
-		} else {
-			return nil, types.Wrapf(types.ErrFailuresResponsed, resp.Message)
+			var n int64
+			if key == gs.nodeAddress {
+				n, err = gs.loadLocalShard(ctx, shardCid, file)
+			} else {
+				n, err = gs.loadShard(ctx, req, meta, shardCid, shard.Peer, file)
+			}
+			gs.bus.Publish(ctx, events.ShardEvent{
+				Kind:     events.ShardLoaded,
+				Provider: key,
+				OrderId:  meta.OrderId,
+				Ok:       err == nil,
+				Time:     time.Now(),
+			})
+			if err == nil {
+				size += n
+				loaded = true
+				break
+			}
+			// drop the partial shard before trying the next replica
+			if e := file.Truncate(size); e != nil {
+				return nil, types.Wrap(types.ErrWriteFileFailed, e)
+			}
+			if _, e := file.Seek(size, io.SeekStart); e != nil {
+				return nil, types.Wrap(types.ErrWriteFileFailed, e)
+			}
+			lastErr = err.Error()
+			log.Warnf("load shard %d of order %d from %s error: %s", shardId, meta.OrderId, key, lastErr)
+		}
+		if !loaded {
+			return nil, types.Wrapf(types.ErrFailuresResponsed, lastErr)
 		}
 	}
 
-	var content []byte
-	for _, c := range contentList {
-		content = append(content, c...)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
 	}
-
-	contentCid, err := utils.CalculateCid(content)
+	contentCid, err := utils.CalculateCidFrom(file)
 	if err != nil {
 		return nil, err
 	}
@@ -431,45 +819,136 @@ func (gs *GatewaySvc) FetchContent(ctx context.Context, req *types.MetadataPropo
 	if err != nil {
 		return nil, types.Wrapf(types.ErrInvalidAlias, "%s", meta.Alias)
 	}
-
-	if len(content) > gs.cfg.Cache.ContentLimit || match {
-		// large size content should go through P2P channel
-
-		path, err := homedir.Expand(gs.cfg.SaoHttpFileServer.HttpFileServerPath)
+	if match {
+		// the updates of the file models commit deltas, resolved to the files they are made on
+		size, contentCid, err = gs.resolveDelta(ctx, req, meta, file, size, contentCid)
 		if err != nil {
-			return nil, types.Wrapf(types.ErrInvalidPath, "%s", gs.cfg.SaoHttpFileServer.HttpFileServerPath)
+			return nil, err
 		}
+	}
 
-		file, err := os.Create(filepath.Join(path, meta.DataId))
+	// the content is returned if it is small enough to be cached
+	var content []byte
+	if size <= int64(gs.cfg.Cache.ContentLimit) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		content, err = io.ReadAll(file)
 		if err != nil {
-			return nil, types.Wrap(types.ErrInvalidPath, err)
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
 		}
+	} else {
+		content = make([]byte, 0)
+	}
 
-		_, err = file.Write([]byte(content))
-		if err != nil {
+	var filePath string
+	if size > int64(gs.cfg.Cache.ContentLimit) || match {
+		// large size content should go through P2P channel
+		if err := file.Close(); err != nil {
 			return nil, types.Wrap(types.ErrWriteFileFailed, err)
 		}
+		filePath = filepath.Join(path, meta.DataId)
+		if err := os.Rename(file.Name(), filePath); err != nil {
+			return nil, types.Wrap(types.ErrWriteFileFailed, err)
+		}
+		// described by the cid of the file, not the one of the delta committed
+		info := *meta
+		info.Cid = contentCid.String()
+		if err := saveFileInfo(path, &info); err != nil {
+			log.Warnf("save file info of %s: %v", meta.DataId, err)
+		}
 
 		if gs.cfg.SaoIpfs.Enable {
-			_, err = gs.storeManager.Store(ctx, contentCid, bytes.NewReader(content))
-			if err != nil {
-				return nil, types.Wrap(types.ErrStoreFailed, err)
+			if err := gs.storeFile(ctx, contentCid, filePath); err != nil {
+				return nil, err
 			}
 		}
-
-		if len(content) > gs.cfg.Cache.ContentLimit {
-			content = make([]byte, 0)
-		}
 	}
 
 	return &FetchResult{
 		Cid:     contentCid.String(),
 		Content: content,
+		Path:    filePath,
 	}, nil
 }
 
+// loadLocalShard copies the shard stored by this node to w.
+func (gs *GatewaySvc) loadLocalShard(ctx context.Context, shardCid cid.Cid, w io.Writer) (int64, error) {
+	if gs.storeManager == nil {
+		return 0, types.Wrapf(types.ErrGetFailed, "no local store for %v", shardCid)
+	}
+	reader, err := gs.storeManager.Get(ctx, shardCid)
+	if err != nil {
+		return 0, types.Wrapf(types.ErrGetFailed, "get cid(%v) from store manager error: %v", shardCid, err)
+	}
+	n, err := bufpool.Copy(w, reader)
+	if err != nil {
+		return n, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return n, nil
+}
+
+// loadShard requests the shard from the storage node at peer and writes it to w.
+func (gs *GatewaySvc) loadShard(ctx context.Context, req *types.MetadataProposal, meta *types.Model, shardCid cid.Cid, peer string, w io.Writer) (int64, error) {
+	if ref := referrer(ctx); ref != nil {
+		req = ref
+	}
+	gp := gs.gatewayProtocolMap["stream"]
+	resp := gp.RequestShardLoad(ctx, types.ShardLoadReq{
+		Cid:     shardCid,
+		OrderId: meta.OrderId,
+		Proposal: types.MetadataProposalCbor{
+			Proposal: types.QueryProposal{
+				Owner:           req.Proposal.Owner,
+				Keyword:         req.Proposal.Keyword,
+				GroupId:         req.Proposal.GroupId,
+				KeywordType:     uint64(req.Proposal.KeywordType),
+				LastValidHeight: req.Proposal.LastValidHeight,
+				Gateway:         req.Proposal.Gateway,
+				CommitId:        req.Proposal.CommitId,
+				Version:         req.Proposal.Version,
+			},
+			JwsSignature: types.JwsSignature{
+				Protected: req.JwsSignature.Protected,
+				Signature: req.JwsSignature.Signature,
+			},
+		},
+		RequestId:     time.Now().UnixMilli(),
+		RelayProposal: gs.buildRelayProposal(ctx, gp, peer),
+	}, peer, true)
+	if resp.Code != 0 {
+		return 0, types.Wrapf(types.ErrFailuresResponsed, resp.Message)
+	}
+	n, err := w.Write(resp.Content)
+	if err != nil {
+		return int64(n), types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return int64(n), nil
+}
+
+// storeFile stores the file at path to the local backends as the content of contentCid.
+func (gs *GatewaySvc) storeFile(ctx context.Context, contentCid cid.Cid, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	_, err = gs.storeManager.Store(ctx, contentCid, file)
+	if err != nil {
+		return types.Wrap(types.ErrStoreFailed, err)
+	}
+	return nil
+}
+
+// buildRelayProposal signs the peers allowed to forward a request to the storage node at peerInfos, the relays
+// the node keeps a reservation on, or the peers connected to this gateway if it has none.
 func (gs *GatewaySvc) buildRelayProposal(ctx context.Context, gp GatewayProtocol, peerInfos string) types.RelayProposalCbor {
-	if gp.GetPeers(ctx) == "" {
+	relayPeerIds := gp.GetPeers(ctx)
+	if relays := transport.RelayPeers(peerInfos); len(relays) > 0 {
+		relayPeerIds = peer.IDSlice(relays).String()
+	}
+	if relayPeerIds == "" {
 		return types.RelayProposalCbor{
 			Proposal:  types.RelayProposal{},
 			Signature: make([]byte, 0),
@@ -479,7 +958,7 @@ func (gs *GatewaySvc) buildRelayProposal(ctx context.Context, gp GatewayProtocol
 	proposal := types.RelayProposal{
 		NodeAddress:    gs.nodeAddress,
 		LocalPeerId:    gs.localPeerId,
-		RelayPeerIds:   gp.GetPeers(ctx),
+		RelayPeerIds:   relayPeerIds,
 		TargetPeerInfo: peerInfos,
 	}
 
@@ -511,9 +990,17 @@ func (gs *GatewaySvc) buildRelayProposal(ctx context.Context, gp GatewayProtocol
 }
 
 func (gs *GatewaySvc) process(ctx context.Context, orderInfo *types.OrderInfo) error {
-	gs.locks.Lock(lockname(orderInfo.OrderId))
-	defer gs.locks.Unlock(lockname(orderInfo.OrderId))
+	gs.orderLocks.Lock(lockname(orderInfo.OrderId))
+	defer gs.orderLocks.Unlock(lockname(orderInfo.OrderId))
 
+	tries := orderInfo.Tries
+	if !gs.reload(ctx, orderInfo) {
+		return nil
+	}
+	// the tries failed before the order was saved
+	if orderInfo.Tries < tries {
+		orderInfo.Tries = tries
+	}
 	if orderInfo.State == types.OrderStateTerminate {
 		return nil
 	}
@@ -530,7 +1017,7 @@ func (gs *GatewaySvc) process(ctx context.Context, orderInfo *types.OrderInfo) e
 		orderInfo.State = types.OrderStateTerminate
 		errMsg := fmt.Sprintf("order %d too many retries %d", orderInfo.OrderId, orderInfo.Tries)
 		orderInfo.LastErr = errMsg
-		e := utils.SaveOrder(ctx, gs.orderDs, *orderInfo)
+		e := gs.saveOrderState(ctx, *orderInfo)
 		if e != nil {
 			log.Warn("put order %d error: %v", orderInfo.OrderId, e)
 		}
@@ -544,20 +1031,15 @@ func (gs *GatewaySvc) process(ctx context.Context, orderInfo *types.OrderInfo) e
 		}
 
 		if latestHeight > int64(orderInfo.ExpireHeight) {
-			orderInfo.State = types.OrderStateExpired
-			errStr := fmt.Sprintf("order expired: latest=%d expireAt=%d", latestHeight, orderInfo.ExpireHeight)
-			orderInfo.LastErr = errStr
-			e := utils.SaveOrder(ctx, gs.orderDs, *orderInfo)
-			if e != nil {
-				log.Warn("put order %d error: %v", orderInfo.OrderId, e)
-			}
-			return nil
+			return gs.expire(ctx, orderInfo, latestHeight)
 		}
 	}
 
 	if orderInfo.State < types.OrderStateComplete {
 		log.Infof("assigning shards to nodes...")
-		// assign shards to storage nodes
+		// assign shards to storage nodes. The nodes are drawn by the chain from the block hash when the order is
+		// stored or readied, neither the proposal nor MsgReady carries a choice of the gateway, so the shards can
+		// only be pushed to the nodes of the order.
 
 		log.Debugf("assigning order %d.", orderInfo.OrderId)
 		for node, shard := range orderInfo.Shards {
@@ -575,14 +1057,23 @@ func (gs *GatewaySvc) process(ctx context.Context, orderInfo *types.OrderInfo) e
 					Assignee:     node,
 					Height:       orderInfo.OrderHeight,
 					AssignTxType: orderInfo.OrderTxType,
+					RequestId:    time.Now().UnixNano(),
+					Priority:     orderInfo.Priority,
 				}
 				resp := gp.RequestShardAssign(ctx, req, shard.Peer)
+				gs.bus.Publish(ctx, events.ShardEvent{
+					Kind:     events.ShardAssigned,
+					Provider: node,
+					OrderId:  orderInfo.OrderId,
+					Ok:       resp.Code == 0,
+					Time:     time.Now(),
+				})
 				if resp.Code == 0 {
 					shard.State = types.ShardStateNotified
-					log.Infof("assigned order %d shard to node %s.", orderInfo.OrderId, node)
+					log.Infof("requestId=%d: assigned order %d shard to node %s.", req.RequestId, orderInfo.OrderId, node)
 				} else {
 					shard.State = types.ShardStateError
-					log.Errorf("assigned order %d shards to node %s failed: %v", orderInfo.OrderId, node, resp.Message)
+					log.Errorf("requestId=%d: assigned order %d shards to node %s failed: %v", req.RequestId, orderInfo.OrderId, node, resp.Message)
 				}
 			}
 		}
@@ -604,9 +1095,53 @@ func (gs *GatewaySvc) process(ctx context.Context, orderInfo *types.OrderInfo) e
 }
 
 func (gs *GatewaySvc) CommitModel(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte) (*CommitResult, error) {
+	return gs.commit(ctx, clientProposal, orderId, gs.stageContent(content), false)
+}
+
+// CommitModelAsync returns once the order is stored on chain, the order is readied in background if it needs to.
+// The progress is tracked by the order of the data id.
+func (gs *GatewaySvc) CommitModelAsync(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte) (*CommitResult, error) {
+	return gs.commit(ctx, clientProposal, orderId, gs.stageContent(content), true)
+}
+
+// CommitModelFile commits the content of the file at path, like a file uploaded to the gateway. The file is
+// staged without being read.
+func (gs *GatewaySvc) CommitModelFile(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, path string) (*CommitResult, error) {
+	return gs.commit(ctx, clientProposal, orderId, func(groupId string, owner string, cid string) (string, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", types.Wrap(types.ErrOpenFileFailed, err)
+		}
+		stagingPath, quota := gs.stagingOf(groupId)
+		// an upload staged under the owner is reserved already
+		if expanded, err := homedir.Expand(stagingPath); err != nil || filepath.Join(expanded, owner, cid) != path {
+			if err := quota.Reserve(owner, info.Size()); err != nil {
+				return "", err
+			}
+		}
+		return StageFile(stagingPath, owner, cid, path, gs.cipher)
+	}, false)
+}
+
+func (gs *GatewaySvc) stageContent(content []byte) func(groupId string, owner string, cid string) (string, error) {
+	return func(groupId string, owner string, cid string) (string, error) {
+		stagingPath, quota := gs.stagingOf(groupId)
+		if err := quota.Reserve(owner, int64(len(content))); err != nil {
+			return "", err
+		}
+		return StageShard(stagingPath, owner, cid, bytes.NewReader(content), gs.cipher)
+	}
+}
+
+// OpenStaged opens the file at path for reading, a staged shard is decrypted while an upload is read as it is.
+func (gs *GatewaySvc) OpenStaged(path string) (*staging.File, error) {
+	return gs.cipher.Open(path)
+}
+
+func (gs *GatewaySvc) commit(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, stage func(groupId string, owner string, cid string) (string, error), async bool) (*CommitResult, error) {
 	// stage order data.
 	orderProposal := clientProposal.Proposal
-	stagePath, err := StageShard(gs.stagingPath, orderProposal.Owner, orderProposal.Cid, content)
+	stagePath, err := stage(orderProposal.GroupId, orderProposal.Owner, orderProposal.Cid)
 	if err != nil {
 		return nil, err
 	}
@@ -630,11 +1165,29 @@ func (gs *GatewaySvc) CommitModel(ctx context.Context, clientProposal *types.Ord
 		OrderId:   orderId,
 		Owner:     clientProposal.Proposal.Owner,
 		Cid:       cid,
+		Priority:  clientProposal.Priority,
+
+		Proposal:     proposalBytes,
+		JwsSignature: signatureBytes,
 	}
-	err = utils.SaveOrder(ctx, gs.orderDs, orderInfo)
+
+	// a proposal resent by a client retrying waits for the previous commit of it, then resumes its order instead of
+	// storing the order again.
+	gs.orderLocks.Lock(datalockname(orderInfo.DataId))
+	defer gs.orderLocks.Unlock(datalockname(orderInfo.DataId))
+	committed, err := utils.GetOrder(ctx, gs.orderDs, orderInfo.DataId)
 	if err != nil {
 		return nil, err
 	}
+	if committed.OrderId != 0 && committed.State <= types.OrderStateComplete && bytes.Equal(committed.Proposal, proposalBytes) {
+		log.Infof("proposal of %s is resent, resume order %d", committed.DataId, committed.OrderId)
+		orderInfo = committed
+	} else {
+		err = gs.saveOrderState(ctx, orderInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var proposal saotypes.Proposal
 	err = proposal.Unmarshal(proposalBytes)
@@ -642,75 +1195,74 @@ func (gs *GatewaySvc) CommitModel(ctx context.Context, clientProposal *types.Ord
 		return nil, err
 	}
 
-	var txHash string
-	var shards map[string]*saotypes.ShardMeta
-	var txType types.AssignTxType
-	var height int64
-	if orderInfo.State < types.OrderStateReady {
-		if orderInfo.OrderId == 0 {
-			var signature saotypes.JwsSignature
-			err := signature.Unmarshal(signatureBytes)
-			if err != nil {
-				return nil, err
-			}
+	var storeHeight int64
+	if orderInfo.OrderId == 0 {
+		var signature saotypes.JwsSignature
+		err := signature.Unmarshal(signatureBytes)
+		if err != nil {
+			return nil, err
+		}
 
-			clientProposal := types.OrderStoreProposal{
-				Proposal:     proposal,
-				JwsSignature: signature,
-			}
-			var resp saotypes.MsgStoreResponse
-			resp, txHash, height, err = gs.chainSvc.StoreOrder(ctx, gs.nodeAddress, &clientProposal)
-			if err != nil {
-				return nil, err
-			}
-			shards = resp.Shards
-			txType = types.AssignTxTypeStore
-			log.Infof("StoreOrder tx succeed. orderId=%d tx=%s shards=%v", resp.OrderId, txHash, resp.Shards)
+		clientProposal := types.OrderStoreProposal{
+			Proposal:     proposal,
+			JwsSignature: signature,
+		}
+		resp, txHash, height, err := gs.chainSvc.StoreOrder(ctx, gs.gatewaySigner(), &clientProposal)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("StoreOrder tx succeed. orderId=%d tx=%s shards=%v", resp.OrderId, txHash, resp.Shards)
 
-			orderInfo.OrderId = resp.OrderId
-		} else {
-			log.Debugf("Sending OrderReady... orderId=%d", orderInfo.OrderId)
-			var resp saotypes.MsgReadyResponse
-			resp, txHash, height, err = gs.chainSvc.OrderReady(ctx, gs.nodeAddress, orderInfo.OrderId)
+		orderInfo.OrderId = resp.OrderId
+		storeHeight = height
+		// keep the order id so a retry or a restart readies the order instead of storing it again.
+		err = utils.SaveOrder(ctx, gs.orderDs, orderInfo)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Shards) > 0 {
+			err = gs.setReady(ctx, &orderInfo, txHash, height, types.AssignTxTypeStore, resp.Shards)
 			if err != nil {
 				return nil, err
 			}
-			shards = resp.Shards
-			txType = types.AssignTxTypeReady
-			log.Infof("OrderReady tx succeed. orderId=%d tx=%s", resp.OrderId, txHash)
-			log.Infof("OrderReady tx succeed. shards=%v", resp.Shards)
-
-			orderInfo.OrderId = resp.OrderId
-		}
-		orderInfo.OrderHash = txHash
-		orderInfo.OrderHeight = height
-		orderInfo.OrderTxType = txType
-		orderInfo.State = types.OrderStateReady
-		orderInfo.Shards = make(map[string]types.OrderShardInfo)
-		for node, s := range shards {
-			orderInfo.Shards[node] = types.OrderShardInfo{
-				ShardId:  s.ShardId,
-				Peer:     s.Peer,
-				Cid:      s.Cid,
-				Provider: s.Provider,
-				State:    types.ShardStateAssigned,
-			}
+			gs.schedQueue.Push(&WorkRequest{Order: orderInfo})
 		}
+	}
 
-		order, err := gs.chainSvc.GetOrder(ctx, orderInfo.OrderId)
-		if err == nil {
-			orderInfo.ExpireHeight = uint64(order.Expire)
-		} else {
-			log.Warn("chain get order err: ", err)
+	if orderInfo.State == types.OrderStateStaged {
+		// stored by the client or a separate gateway key, the order is pending until the provider readies it.
+		if async {
+			go func(orderInfo types.OrderInfo) {
+				gs.orderLocks.Lock(lockname(orderInfo.OrderId))
+				defer gs.orderLocks.Unlock(lockname(orderInfo.OrderId))
+
+				if !gs.reload(gs.ctx, &orderInfo) || orderInfo.State != types.OrderStateStaged {
+					return
+				}
+				err := gs.ready(gs.ctx, &orderInfo)
+				if err != nil {
+					log.Errorf("ready order %d error: %v", orderInfo.OrderId, err)
+					orderInfo.LastErr = err.Error()
+					if err := utils.SaveOrder(gs.ctx, gs.orderDs, orderInfo); err != nil {
+						log.Warnf("put order %d error: %v", orderInfo.OrderId, err)
+					}
+				}
+			}(orderInfo)
+
+			return &CommitResult{
+				OrderId: orderInfo.OrderId,
+				DataId:  orderInfo.DataId,
+				Cid:     orderInfo.Cid.String(),
+				Height:  storeHeight,
+			}, nil
 		}
-		err = utils.SaveOrder(ctx, gs.orderDs, orderInfo)
+
+		err = gs.ready(ctx, &orderInfo)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	gs.schedQueue.Push(&WorkRequest{Order: orderInfo})
-
 	// TODO: wsevent
 	//err = gs.chainSvc.UnsubscribeOrderComplete(ctx, orderId)
 	//if err != nil {
@@ -732,6 +1284,51 @@ func (gs *GatewaySvc) CommitModel(ctx context.Context, clientProposal *types.Ord
 	}, nil
 }
 
+// ready sends the OrderReady of a staged order and schedules the assignment of its shards.
+func (gs *GatewaySvc) ready(ctx context.Context, orderInfo *types.OrderInfo) error {
+	log.Debugf("Sending OrderReady... orderId=%d", orderInfo.OrderId)
+	resp, txHash, height, err := gs.chainSvc.OrderReady(ctx, gs.nodeAddress, orderInfo.OrderId)
+	if err != nil {
+		return err
+	}
+	log.Infof("OrderReady tx succeed. orderId=%d tx=%s shards=%v", resp.OrderId, txHash, resp.Shards)
+
+	orderInfo.OrderId = resp.OrderId
+	err = gs.setReady(ctx, orderInfo, txHash, height, types.AssignTxTypeReady, resp.Shards)
+	if err != nil {
+		return err
+	}
+	gs.schedQueue.Push(&WorkRequest{Order: *orderInfo})
+	return nil
+}
+
+// setReady moves a staged order to Ready with the shards assigned by the store or ready tx, the transition is
+// persisted so the order is resumed after a restart.
+func (gs *GatewaySvc) setReady(ctx context.Context, orderInfo *types.OrderInfo, txHash string, height int64, txType types.AssignTxType, shards map[string]*saotypes.ShardMeta) error {
+	orderInfo.OrderHash = txHash
+	orderInfo.OrderHeight = height
+	orderInfo.OrderTxType = txType
+	orderInfo.State = types.OrderStateReady
+	orderInfo.Shards = make(map[string]types.OrderShardInfo)
+	for node, s := range shards {
+		orderInfo.Shards[node] = types.OrderShardInfo{
+			ShardId:  s.ShardId,
+			Peer:     s.Peer,
+			Cid:      s.Cid,
+			Provider: s.Provider,
+			State:    types.ShardStateAssigned,
+		}
+	}
+
+	order, err := gs.chainSvc.GetOrder(ctx, orderInfo.OrderId)
+	if err == nil {
+		orderInfo.ExpireHeight = uint64(order.Expire)
+	} else {
+		log.Warn("chain get order err: ", err)
+	}
+	return gs.saveOrderState(ctx, *orderInfo)
+}
+
 func (gs *GatewaySvc) TerminateOrder(ctx context.Context, req *types.OrderTerminateProposal) error {
 	_, err := gs.chainSvc.TerminateOrder(ctx, gs.nodeAddress, *req)
 	if err != nil {
@@ -782,28 +1379,16 @@ func (gs *GatewaySvc) OrderStatus(ctx context.Context, id string) (types.OrderIn
 	return utils.GetOrder(ctx, gs.orderDs, id)
 }
 
-func (gs *GatewaySvc) getOrderKeys(ctx context.Context) ([]types.OrderKey, error) {
-	index, err := utils.GetOrderIndex(ctx, gs.orderDs)
-	if err != nil {
-		return nil, err
-	}
-
-	return index.Alls, nil
-}
-
-func (gs *GatewaySvc) OrderList(ctx context.Context) ([]types.OrderInfo, error) {
-	keys, err := gs.getOrderKeys(ctx)
-	if err != nil {
-		return nil, err
-	}
-
+func (gs *GatewaySvc) OrderList(ctx context.Context, filter types.OrderFilter) ([]types.OrderInfo, error) {
 	var orderInfos []types.OrderInfo
-	for _, orderId := range keys {
-		orderInfo, err := utils.GetOrder(ctx, gs.orderDs, orderId.DataId)
-		if err != nil {
-			return nil, err
+	err := utils.ForEachOrder(ctx, gs.orderDs, func(orderInfo types.OrderInfo) error {
+		if filter.Match(orderInfo) {
+			orderInfos = append(orderInfos, orderInfo)
 		}
-		orderInfos = append(orderInfos, orderInfo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return orderInfos, nil
 }
@@ -819,24 +1404,37 @@ func (gs *GatewaySvc) OrderFix(ctx context.Context, dataId string) error {
 }
 
 func (gs *GatewaySvc) getPendingOrders(ctx context.Context) ([]types.OrderInfo, error) {
-	orderKeys, err := gs.getOrderKeys(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	var orders []types.OrderInfo
-	for _, orderKey := range orderKeys {
-		order, err := utils.GetOrder(ctx, gs.orderDs, orderKey.DataId)
-		if err != nil {
-			return nil, err
-		}
-		if order.State != types.OrderStateComplete {
+	err := utils.ForEachOrder(ctx, gs.orderDs, func(order types.OrderInfo) error {
+		if order.State == types.OrderStateStaged || order.State == types.OrderStateReady {
 			orders = append(orders, order)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return orders, nil
 }
 
+// OrderLocker locks the orders while processed, the locks are shared by the gateways of a cluster.
+type OrderLocker interface {
+	Lock(name string)
+	Unlock(name string) error
+}
+
 func lockname(orderId uint64) string {
 	return fmt.Sprintf("lk-order-%d", orderId)
 }
+
+func datalockname(dataId string) string {
+	return "lk-data-" + dataId
+}
+
+// gatewaySigner returns the key signing the orders submitted to the gateway, the node address by default.
+func (gs *GatewaySvc) gatewaySigner() string {
+	if gs.cfg.Keys.Gateway != "" {
+		return gs.cfg.Keys.Gateway
+	}
+	return gs.nodeAddress
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"context"
+	"sao-node/types"
+	"sao-node/utils"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessReload(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	locks := utils.NewMapLock()
+	gs := &GatewaySvc{
+		orderDs:    ds,
+		locks:      locks,
+		orderLocks: locks,
+	}
+
+	contentCid, err := utils.CalculateCid([]byte("content"))
+	require.NoError(t, err)
+
+	// terminated by another gateway of the cluster since queued
+	stored := types.OrderInfo{DataId: "data", OrderId: 1, Cid: contentCid, State: types.OrderStateTerminate, Tries: 3}
+	require.NoError(t, utils.SaveOrder(ctx, ds, stored))
+	queued := stored
+	queued.State = types.OrderStateReady
+	queued.Tries = 1
+	require.NoError(t, gs.process(ctx, &queued))
+	require.Equal(t, types.OrderStateTerminate, queued.State)
+
+	// republished by another gateway under a new order id
+	stored.OrderId, stored.State = 2, types.OrderStateReady
+	require.NoError(t, utils.SaveOrder(ctx, ds, stored))
+	queued.OrderId, queued.State = 1, types.OrderStateReady
+	require.NoError(t, gs.process(ctx, &queued))
+	require.Equal(t, uint64(1), queued.OrderId)
+
+	order, err := utils.GetOrder(ctx, ds, "data")
+	require.NoError(t, err)
+	require.Equal(t, stored.OrderId, order.OrderId)
+	require.Equal(t, stored.Tries, order.Tries)
+}
+
+func TestCommitResent(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	locks := utils.NewMapLock()
+	gs := &GatewaySvc{
+		orderDs:    ds,
+		locks:      locks,
+		orderLocks: locks,
+	}
+
+	contentCid, err := utils.CalculateCid([]byte("content"))
+	require.NoError(t, err)
+	clientProposal := &types.OrderStoreProposal{}
+	clientProposal.Proposal.DataId = "data"
+	clientProposal.Proposal.Cid = contentCid.String()
+	proposalBytes, err := clientProposal.Proposal.Marshal()
+	require.NoError(t, err)
+
+	// the order stored by the first commit of the proposal, the chain is not called again
+	stored := types.OrderInfo{DataId: "data", OrderId: 7, Cid: contentCid, State: types.OrderStateReady, Proposal: proposalBytes}
+	require.NoError(t, utils.SaveOrder(ctx, ds, stored))
+	stage := func(string, string, string) (string, error) {
+		return "staged", nil
+	}
+	result, err := gs.commit(ctx, clientProposal, 0, stage, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), result.OrderId)
+
+	order, err := utils.GetOrder(ctx, ds, "data")
+	require.NoError(t, err)
+	require.Equal(t, types.OrderStateReady, order.State)
+}
+
+func TestIsCompleteHandled(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	gs := &GatewaySvc{ctx: ctx, orderDs: ds}
+
+	req := types.ShardCompleteReq{OrderId: 1, TxHash: "tx1"}
+	require.False(t, gs.isCompleteHandled(req))
+
+	// a retry of the handled request within the window
+	require.NoError(t, utils.SaveCompleteRecord(ctx, ds, types.CompleteRecord{OrderId: 1, TxHash: "tx1", HandledAt: time.Now().Unix()}))
+	require.True(t, gs.isCompleteHandled(req))
+	require.False(t, gs.isCompleteHandled(types.ShardCompleteReq{OrderId: 1, TxHash: "tx2"}))
+	require.False(t, gs.isCompleteHandled(types.ShardCompleteReq{OrderId: 2, TxHash: "tx1"}))
+
+	// handled again once the window is over
+	handledAt := time.Now().Add(-COMPLETE_DEDUP_WINDOW - time.Minute).Unix()
+	require.NoError(t, utils.SaveCompleteRecord(ctx, ds, types.CompleteRecord{OrderId: 1, TxHash: "tx1", HandledAt: handledAt}))
+	require.False(t, gs.isCompleteHandled(req))
+
+	// and its record is pruned
+	pruned, err := utils.PruneCompleteRecords(ctx, ds, time.Now().Add(-COMPLETE_DEDUP_WINDOW).Unix())
+	require.NoError(t, err)
+	require.Equal(t, 1, pruned)
+	record, err := utils.GetCompleteRecord(ctx, ds, 1, "tx1")
+	require.NoError(t, err)
+	require.Empty(t, record.TxHash)
+}
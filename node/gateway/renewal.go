@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"context"
+	"sao-node/node/alert"
+	"sao-node/types"
+	"strings"
+)
+
+// the data ids listed by the renewal alert
+const maxRenewalAlertIds = 10
+
+// RemindRenewals reports the completed orders whose models end within Gateway.RenewalWindow blocks to the
+// alerts, the gateway can't renew them since the renewals are signed by the owners. It returns the count of the
+// orders ending.
+func (gs *GatewaySvc) RemindRenewals(ctx context.Context) (int, error) {
+	orders, err := gs.OrderList(ctx, types.OrderFilter{States: []types.OrderState{types.OrderStateComplete}})
+	if err != nil {
+		return 0, err
+	}
+	height, err := gs.chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var ending []string
+	for _, orderInfo := range orders {
+		resp, err := gs.chainSvc.GetMeta(ctx, orderInfo.DataId)
+		if err != nil {
+			log.Warnf("get metadata of %s to remind the renewal error: %v", orderInfo.DataId, err)
+			continue
+		}
+		meta := resp.Metadata
+		// updated by a later order since
+		if meta.OrderId != orderInfo.OrderId {
+			continue
+		}
+
+		end := int64(meta.CreatedAt + meta.Duration)
+		if end > height && end-height <= gs.cfg.Gateway.RenewalWindow {
+			log.Infof("model %s (order %d) of %s ends in %d blocks", orderInfo.DataId, orderInfo.OrderId, orderInfo.Owner, end-height)
+			ending = append(ending, orderInfo.DataId)
+		}
+	}
+
+	if len(ending) > 0 {
+		ids := ending
+		if len(ids) > maxRenewalAlertIds {
+			ids = ids[:maxRenewalAlertIds]
+		}
+		gs.alerts.Fire(alert.TypeOrderExpiring, "%d models end within %d blocks and should be renewed by their owners: %s",
+			len(ending), gs.cfg.Gateway.RenewalWindow, strings.Join(ids, ", "))
+	}
+	return len(ending), nil
+}
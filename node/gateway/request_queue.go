@@ -4,6 +4,7 @@ import (
 	"sync"
 )
 
+// RequestQueue pops the requests of the highest order priority first, FIFO within a priority.
 type RequestQueue struct {
 	sync.Mutex
 	queue []*WorkRequest
@@ -31,8 +32,14 @@ func (q *RequestQueue) PopFront() *WorkRequest {
 		return nil
 	}
 
-	item := q.queue[0]
-	q.queue = q.queue[1:]
+	front := 0
+	for i, item := range q.queue {
+		if item.Order.Priority > q.queue[front].Order.Priority {
+			front = i
+		}
+	}
+	item := q.queue[front]
+	q.queue = append(q.queue[:front], q.queue[front+1:]...)
 	return item
 }
 
@@ -2,17 +2,18 @@ package gateway
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sao-node/node/staging"
 	"sao-node/types"
+	"sao-node/utils/bufpool"
 
 	"github.com/ipfs/go-cid"
 	"github.com/mitchellh/go-homedir"
 )
 
-func StageShard(basedir string, creator string, cid string, content []byte) (string, error) {
-	// TODO: check enough space
-	// TODO: check existence
+func stagePath(basedir string, creator string) (string, error) {
 	path, err := homedir.Expand(basedir)
 	if err != nil {
 		return "", types.Wrapf(types.ErrInvalidPath, "%s", basedir)
@@ -22,37 +23,122 @@ func StageShard(basedir string, creator string, cid string, content []byte) (str
 	if err != nil && !os.IsExist(err) {
 		return "", types.Wrap(types.ErrCreateDirFailed, err)
 	}
+	return filepath.Join(path, creator), nil
+}
+
+// StageShard writes the content read from reader to the staging file of the cid, encrypted by c. The content is
+// written to a temporary file first so a failed write never leaves a partial shard to serve.
+func StageShard(basedir string, creator string, cid string, reader io.Reader, c *staging.Cipher) (string, error) {
+	return StageVerified(basedir, creator, cid, reader, c, nil)
+}
+
+// StageVerified stages the content like StageShard, the temporary file is renamed into place only if verify accepts
+// it, so a content failing the check never replaces the shard staged already.
+func StageVerified(basedir string, creator string, cid string, reader io.Reader, c *staging.Cipher, verify func(file *staging.File) error) (string, error) {
+	// TODO: check enough space
+	path, err := stagePath(basedir, creator)
+	if err != nil {
+		return "", err
+	}
 
 	//filename := fmt.Sprintf("%d-%v", orderId, cid)
 	filename := fmt.Sprintf("%v", cid)
-	log.Debugf("staging file: %s/%s/%s", path, creator, filename)
-	filepath := filepath.Join(path, creator, filename)
-	file, err := os.Create(filepath)
+	log.Debugf("staging file: %s/%s", path, filename)
+	filepath := filepath.Join(path, filename)
+	file, err := os.CreateTemp(path, filename+".*.tmp")
 	if err != nil {
 		return "", types.Wrap(types.ErrCreateFileFailed, err)
 	}
-	defer file.Close()
+	defer os.Remove(file.Name())
 
-	_, err = file.Write(content)
+	w, err := c.Encrypt(file, creator, cid)
+	if err == nil {
+		_, err = bufpool.Copy(w, reader)
+	}
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
 	if err != nil {
 		return "", types.Wrap(types.ErrWriteFileFailed, err)
 	}
+	if verify != nil {
+		staged, err := c.OpenAs(file.Name(), creator, cid)
+		if err != nil {
+			return "", err
+		}
+		err = verify(staged)
+		staged.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(file.Name(), filepath); err != nil {
+		return "", types.Wrap(types.ErrWriteFileFailed, err)
+	}
 	return filepath, nil
 }
 
-func GetStagedShard(basedir string, creator string, cid cid.Cid) ([]byte, error) {
+// StageFile stages the file at src as the content of the cid. The file is linked instead of copied when it is
+// on the same file system and the staging files are not encrypted, the content is copied otherwise.
+func StageFile(basedir string, creator string, cid string, src string, c *staging.Cipher) (string, error) {
+	path, err := stagePath(basedir, creator)
+	if err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(path, cid)
+	if dst == src {
+		return dst, nil
+	}
+	if !c.Encrypts() {
+		_ = os.Remove(dst)
+		if err := os.Link(src, dst); err == nil {
+			log.Debugf("staging file: %s linked to %s", dst, src)
+			return dst, nil
+		}
+	}
+
+	file, err := os.Open(src)
+	if err != nil {
+		return "", types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+	return StageShard(basedir, creator, cid, file, c)
+}
+
+// OpenStagedShard opens the staging file of the cid for reading, decrypted by c, the caller closes it.
+func OpenStagedShard(basedir string, creator string, cid cid.Cid, c *staging.Cipher) (*staging.File, error) {
 	path, err := homedir.Expand(basedir)
 	if err != nil {
 		return nil, types.Wrapf(types.ErrInvalidPath, "%s", basedir)
 	}
 
-	filename := cid.String()
-	bytes, err := os.ReadFile(filepath.Join(path, creator, filename))
+	return c.Open(filepath.Join(path, creator, cid.String()))
+}
+
+func GetStagedShard(basedir string, creator string, cid cid.Cid, c *staging.Cipher) ([]byte, error) {
+	path, err := homedir.Expand(basedir)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidPath, "%s", basedir)
+	}
+	return ReadStaged(filepath.Join(path, creator, cid.String()), c)
+}
+
+// ReadStaged reads the staging file at path, decrypted by c.
+func ReadStaged(path string, c *staging.Cipher) ([]byte, error) {
+	file, err := c.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bytes, err := io.ReadAll(file)
 	if err != nil {
 		return nil, types.Wrap(types.ErrReadFileFailed, err)
-	} else {
-		return bytes, nil
 	}
+	return bytes, nil
 }
 
 func UnstageShard(basedir string, creator string, cid string) error {
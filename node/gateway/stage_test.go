@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sao-node/node/config"
+	"sao-node/node/staging"
+	"sao-node/types"
+	"sao-node/utils"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageFile(t *testing.T) {
+	basedir := t.TempDir()
+	src := filepath.Join(t.TempDir(), "upload")
+	require.NoError(t, os.WriteFile(src, []byte("content"), 0644))
+
+	c, err := cid.Decode("QmYrw5Jr6fqXbJGBAekVoL7ESTDgTG43sTjJjyykrfmL2e")
+	require.NoError(t, err)
+	path, err := StageFile(basedir, "owner", c.String(), src, nil)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(basedir, "owner", c.String()), path)
+	content, err := GetStagedShard(basedir, "owner", c, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("content"), content)
+
+	// staged again over the existing file
+	_, err = StageShard(basedir, "owner", c.String(), bytes.NewReader([]byte("other")), nil)
+	require.NoError(t, err)
+	content, err = GetStagedShard(basedir, "owner", c, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("other"), content)
+	// the linked upload is untouched
+	content, err = os.ReadFile(src)
+	require.NoError(t, err)
+	require.Equal(t, []byte("content"), content)
+
+	entries, err := os.ReadDir(filepath.Join(basedir, "owner"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// encrypted, the upload is copied instead of linked
+	cipher, err := staging.NewCipher(bytes.Repeat([]byte{1}, staging.KeyLen), true)
+	require.NoError(t, err)
+	path, err = StageFile(basedir, "owner", c.String(), src, cipher)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "content")
+	content, err = GetStagedShard(basedir, "owner", c, cipher)
+	require.NoError(t, err)
+	require.Equal(t, []byte("content"), content)
+	_, err = GetStagedShard(basedir, "owner", c, nil)
+	require.ErrorIs(t, err, types.ErrInvalidContent)
+}
+
+func TestCleanStaging(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+	quota, err := staging.NewQuota(path, 0, 0)
+	require.NoError(t, err)
+	cfg := config.DefaultSaoNode()
+	gs := &GatewaySvc{
+		cfg:     cfg,
+		orderDs: dssync.MutexWrap(datastore.NewMapDatastore()),
+		staging: quota,
+	}
+
+	inUse, err := cid.Decode("QmYrw5Jr6fqXbJGBAekVoL7ESTDgTG43sTjJjyykrfmL2e")
+	require.NoError(t, err)
+	finished, err := cid.Decode("QmfM2r8seH2GiRaC4esTjeraXEachRt8ZsSeGaWTPLyMoG")
+	require.NoError(t, err)
+	for _, order := range []types.OrderInfo{
+		{DataId: "data1", Owner: "did", Cid: inUse, State: types.OrderStateReady},
+		{DataId: "data2", Owner: "did", Cid: finished, State: types.OrderStateComplete},
+	} {
+		require.NoError(t, utils.SaveOrder(ctx, gs.orderDs, order))
+		_, err := StageShard(path, order.Owner, order.Cid.String(), bytes.NewReader([]byte("content")), nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "peer", "upload"), 0755))
+
+	// nothing is removed within the grace period
+	removed, err := gs.cleanStaging(ctx, path, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+
+	removed, err = gs.cleanStaging(ctx, path, time.Now().Add(stagingGracePeriod))
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	_, err = os.Stat(filepath.Join(path, "did", finished.String()))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(path, "did", inUse.String()))
+	require.NoError(t, err)
+
+	// the uploads are kept for the retention
+	removed, err = gs.cleanStaging(ctx, path, time.Now().Add(cfg.Transport.StagingUploadRetention))
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	_, err = os.Stat(filepath.Join(path, "peer", "upload"))
+	require.True(t, os.IsNotExist(err))
+}
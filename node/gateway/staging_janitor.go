@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sao-node/node/platform"
+	"sao-node/node/staging"
+	"sao-node/types"
+	"time"
+)
+
+// the files younger than this are never removed, a shard is staged before its order is saved and an upload
+// writes its chunks one by one
+const stagingGracePeriod = time.Hour
+
+// SetStaging accounts the staged shards to the quota, or to the quota of their platform if it stages apart, encrypts
+// them with the cipher and starts removing the orphaned staging files every Transport.StagingCleanInterval.
+func (gs *GatewaySvc) SetStaging(quota *staging.Quota, platforms *platform.Registry, cipher *staging.Cipher) {
+	gs.staging = quota
+	gs.platforms = platforms
+	gs.cipher = cipher
+	if interval := gs.cfg.Transport.StagingCleanInterval; quota != nil && interval > 0 {
+		go gs.stagingJanitor(gs.ctx, interval)
+	}
+}
+
+// stagingOf returns the staging path of the platform and its quota.
+func (gs *GatewaySvc) stagingOf(groupId string) (string, *staging.Quota) {
+	if quota := gs.platforms.Staging(groupId); quota != nil {
+		return quota.Path(), quota
+	}
+	return gs.stagingPath, gs.staging
+}
+
+// stagingPaths returns the staging path of the node and the ones of the platforms staging apart.
+func (gs *GatewaySvc) stagingPaths() []string {
+	paths := []string{gs.stagingPath}
+	for _, quota := range gs.platforms.StagingQuotas() {
+		paths = append(paths, quota.Path())
+	}
+	return paths
+}
+
+// unstage removes the staged shard of the order, under whichever staging path it is staged.
+func (gs *GatewaySvc) unstage(orderInfo *types.OrderInfo) error {
+	path, err := staging.Locate(gs.stagingPaths(), orderInfo.Owner, orderInfo.Cid.String())
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (gs *GatewaySvc) stagingJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			quotas := append([]*staging.Quota{gs.staging}, gs.platforms.StagingQuotas()...)
+			for _, quota := range quotas {
+				removed, err := gs.cleanStaging(ctx, quota.Path(), time.Now())
+				if err != nil {
+					log.Warnf("clean staging path %s: %v", quota.Path(), err)
+				}
+				if removed > 0 {
+					log.Infof("removed %d orphaned staging files under %s", removed, quota.Path())
+				}
+				quota.Removed(removed)
+				if err := quota.Scan(); err != nil {
+					log.Warnf("scan staging path %s: %v", quota.Path(), err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// cleanStaging removes the staged shards under path not used by an order in progress, they are left behind when
+// the unstaging of a finished or expired order fails, and the uploads older than the retention, an upload is
+// linked to its shard once committed. It returns the count of the removed files and upload directories.
+func (gs *GatewaySvc) cleanStaging(ctx context.Context, path string, now time.Time) (int, error) {
+	orders, err := gs.OrderList(ctx, types.OrderFilter{
+		States: []types.OrderState{types.OrderStateStaged, types.OrderStateReady},
+	})
+	if err != nil {
+		return 0, err
+	}
+	inUse := make(map[string]struct{})
+	for _, order := range orders {
+		inUse[filepath.Join(path, order.Owner, order.Cid.String())] = struct{}{}
+	}
+
+	owners, err := os.ReadDir(path)
+	if err != nil {
+		return 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	removed := 0
+	for _, owner := range owners {
+		if !owner.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(path, owner.Name()))
+		if err != nil {
+			log.Warnf("read staging directory %s: %v", owner.Name(), err)
+			continue
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			age := now.Sub(info.ModTime())
+			if age < stagingGracePeriod {
+				continue
+			}
+
+			p := filepath.Join(path, owner.Name(), entry.Name())
+			if entry.IsDir() {
+				// the chunks of an upload and the file assembled from them
+				if age < gs.cfg.Transport.StagingUploadRetention {
+					continue
+				}
+				err = os.RemoveAll(p)
+			} else if _, ok := inUse[p]; !ok {
+				err = os.Remove(p)
+			} else {
+				continue
+			}
+			if err != nil {
+				log.Warnf("remove staging file %s: %v", p, err)
+				continue
+			}
+			log.Debugf("removed orphaned staging file %s", p)
+			removed++
+		}
+	}
+	return removed, nil
+}
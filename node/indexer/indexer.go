@@ -0,0 +1,317 @@
+// Package indexer publishes the stored shards to the network indexers (IPNI). The advertisements form a chain
+// served over http at /head and /<cid>, the indexers are told about a new head by an http announce and fetch
+// the chain back to the last advertisement they know.
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sao-node/node/config"
+	"sao-node/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multihash"
+	"github.com/multiformats/go-varint"
+)
+
+var log = logging.Logger("indexer")
+
+const (
+	// the multihashes of an entry chunk, as the indexers expect at most
+	maxEntriesPerChunk = 16384
+	// multicodec of the bitswap transport, the metadata of the advertisements
+	transportBitswap = 0x0900
+
+	announceTimeout = 30 * time.Second
+)
+
+var (
+	headKey     = datastore.NewKey("/ipni/head")
+	blockPrefix = datastore.NewKey("/ipni/blocks")
+)
+
+// Publisher signs the advertisements as the provider serving the content.
+type Publisher struct {
+	ds        datastore.Batching
+	privKey   crypto.PrivKey
+	provider  peer.ID
+	addrs     func() []multiaddr.Multiaddr
+	urls      []string
+	announce  multiaddr.Multiaddr
+	server    *http.Server
+	client    *http.Client
+	publishMu sync.Mutex
+}
+
+// StartPublisher starts the http publisher, privKey and addrs are the identity and the addresses of the node
+// serving the content.
+func StartPublisher(cfg *config.Indexer, ds datastore.Batching, privKey crypto.PrivKey, addrs func() []multiaddr.Multiaddr) (*Publisher, error) {
+	provider, err := peer.IDFromPrivateKey(privKey)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	if cfg.AnnounceAddress == "" {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "the indexer announce address is required")
+	}
+	announce, err := multiaddr.NewMultiaddr(cfg.AnnounceAddress)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidConfig, "invalid indexer announce address %s: %v", cfg.AnnounceAddress, err)
+	}
+
+	p := &Publisher{
+		ds:       ds,
+		privKey:  privKey,
+		provider: provider,
+		addrs:    addrs,
+		urls:     cfg.Urls,
+		announce: announce,
+		client:   &http.Client{Timeout: announceTimeout},
+	}
+	p.server = &http.Server{
+		Addr:    cfg.ListenAddress,
+		Handler: p,
+	}
+	go func() {
+		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("indexer publisher: %v", err)
+		}
+	}()
+	log.Infof("indexer publisher of %s listening on %s", provider, cfg.ListenAddress)
+
+	return p, nil
+}
+
+func (p *Publisher) Stop(ctx context.Context) error {
+	log.Info("stopping indexer publisher...")
+	return p.server.Shutdown(ctx)
+}
+
+// Publish advertises the cids under contextID, and announces the new advertisement to the indexers.
+func (p *Publisher) Publish(ctx context.Context, contextID []byte, cids []cid.Cid) (cid.Cid, error) {
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
+	entries, err := p.putEntries(ctx, cids)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	head, err := p.head(ctx)
+	if err != nil {
+		return cid.Undef, err
+	}
+	ad := advertisement{
+		Provider:  p.provider.String(),
+		Addresses: []string{},
+		Entries:   entries,
+		ContextID: contextID,
+		Metadata:  varint.ToUvarint(transportBitswap),
+	}
+	if head.Defined() {
+		ad.PreviousID = &head
+	}
+	for _, addr := range p.addrs() {
+		ad.Addresses = append(ad.Addresses, addr.String())
+	}
+	ad.Signature, err = signAdvertisement(&ad, p.privKey)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	adCid, err := p.putBlock(ctx, ad)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err := p.ds.Put(ctx, headKey, adCid.Bytes()); err != nil {
+		return cid.Undef, types.Wrap(types.ErrStoreFailed, err)
+	}
+
+	p.announceHead(ctx, adCid)
+	return adCid, nil
+}
+
+// putEntries stores the multihashes of the cids as a chain of entry chunks, the first chunk is returned.
+func (p *Publisher) putEntries(ctx context.Context, cids []cid.Cid) (cid.Cid, error) {
+	var next *cid.Cid
+	for end := len(cids); end > 0; end -= maxEntriesPerChunk {
+		start := end - maxEntriesPerChunk
+		if start < 0 {
+			start = 0
+		}
+		chunk := entryChunk{Next: next}
+		for _, c := range cids[start:end] {
+			chunk.Entries = append(chunk.Entries, c.Hash())
+		}
+		c, err := p.putBlock(ctx, chunk)
+		if err != nil {
+			return cid.Undef, err
+		}
+		next = &c
+	}
+	if next == nil {
+		return cid.Undef, types.Wrapf(types.ErrInvalidParameters, "no cid to advertise")
+	}
+	return *next, nil
+}
+
+func (p *Publisher) putBlock(ctx context.Context, obj interface{}) (cid.Cid, error) {
+	node, err := cbor.WrapObject(obj, multihash.SHA2_256, -1)
+	if err != nil {
+		return cid.Undef, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	err = p.ds.Put(ctx, blockPrefix.ChildString(node.Cid().String()), node.RawData())
+	if err != nil {
+		return cid.Undef, types.Wrap(types.ErrStoreFailed, err)
+	}
+	return node.Cid(), nil
+}
+
+func (p *Publisher) head(ctx context.Context) (cid.Cid, error) {
+	data, err := p.ds.Get(ctx, headKey)
+	if err == datastore.ErrNotFound {
+		return cid.Undef, nil
+	} else if err != nil {
+		return cid.Undef, types.Wrap(types.ErrGetFailed, err)
+	}
+	c, err := cid.Cast(data)
+	if err != nil {
+		return cid.Undef, types.Wrap(types.ErrInvalidCid, err)
+	}
+	return c, nil
+}
+
+// announceHead tells the indexers where to fetch the new head, the indexers fetch it later if they are missed.
+func (p *Publisher) announceHead(ctx context.Context, head cid.Cid) {
+	publisher := p.announce.Encapsulate(multiaddr.StringCast("/p2p/" + p.provider.String()))
+	body, err := json.Marshal(announceMessage{
+		Addrs: []dagBytes{dagBytesOf(publisher.Bytes())},
+		Cid:   dagLink{head},
+	})
+	if err != nil {
+		log.Warnf("marshal announce message: %v", err)
+		return
+	}
+
+	for _, url := range p.urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimRight(url, "/")+"/ingest/announce", bytes.NewReader(body))
+		if err != nil {
+			log.Warnf("announce %s to %s: %v", head, url, err)
+			continue
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			log.Warnf("announce %s to %s: %v", head, url, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			log.Warnf("announce %s to %s: %s", head, url, resp.Status)
+		}
+	}
+}
+
+// ServeHTTP serves the signed head at /head and the advertisement chain blocks at /<cid>.
+func (p *Publisher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+
+	if name == "head" {
+		head, err := p.head(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !head.Defined() {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		data, err := signHead(head, p.privKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+		return
+	}
+
+	c, err := cid.Decode(name)
+	if err != nil {
+		http.Error(w, "invalid cid", http.StatusBadRequest)
+		return
+	}
+	data, err := p.ds.Get(r.Context(), blockPrefix.ChildString(c.String()))
+	if err == datastore.ErrNotFound {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(data)
+}
+
+// dagLink and dagBytes are the dag-json forms of a link and bytes.
+type dagLink struct {
+	cid.Cid
+}
+
+func (l dagLink) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"/": l.String()})
+}
+
+type dagBytes struct {
+	Slash struct {
+		Bytes string `json:"bytes"`
+	} `json:"/"`
+}
+
+func dagBytesOf(b []byte) dagBytes {
+	var d dagBytes
+	d.Slash.Bytes = base64.RawStdEncoding.EncodeToString(b)
+	return d
+}
+
+// announceMessage is the announce of a new head, the fields are in the dag-json order.
+type announceMessage struct {
+	Addrs []dagBytes
+	Cid   dagLink
+}
+
+type signedHead struct {
+	Head   dagLink  `json:"head"`
+	Pubkey dagBytes `json:"pubkey"`
+	Sig    dagBytes `json:"sig"`
+}
+
+func signHead(head cid.Cid, privKey crypto.PrivKey) ([]byte, error) {
+	sig, err := privKey.Sign(head.Bytes())
+	if err != nil {
+		return nil, types.Wrap(types.ErrSignedFailed, err)
+	}
+	pubKey, err := crypto.MarshalPublicKey(privKey.GetPublic())
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	data, err := json.Marshal(signedHead{Head: dagLink{head}, Pubkey: dagBytesOf(pubKey), Sig: dagBytesOf(sig)})
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,90 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sao-node/node/config"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/record"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+	announced := make(chan map[string]interface{}, 2)
+	indexer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ingest/announce", r.URL.Path)
+		var msg map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		announced <- msg
+	}))
+	defer indexer.Close()
+
+	privKey, _, err := crypto.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	addr := multiaddr.StringCast("/ip4/1.2.3.4/tcp/4001")
+	p, err := StartPublisher(&config.Indexer{
+		Urls:            []string{indexer.URL},
+		ListenAddress:   "127.0.0.1:0",
+		AnnounceAddress: "/dns4/example.com/tcp/5155/http",
+	}, dssync.MutexWrap(datastore.NewMapDatastore()), privKey, func() []multiaddr.Multiaddr {
+		return []multiaddr.Multiaddr{addr}
+	})
+	require.NoError(t, err)
+	defer p.Stop(ctx)
+
+	get := func(path string) []byte {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code, path)
+		data, err := io.ReadAll(w.Body)
+		require.NoError(t, err)
+		return data
+	}
+
+	shard, err := cid.Decode("bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku")
+	require.NoError(t, err)
+	first, err := p.Publish(ctx, []byte("order-1"), []cid.Cid{shard})
+	require.NoError(t, err)
+	msg := <-announced
+	require.Equal(t, map[string]interface{}{"/": first.String()}, msg["Cid"])
+
+	second, err := p.Publish(ctx, []byte("order-2"), []cid.Cid{shard})
+	require.NoError(t, err)
+	<-announced
+
+	var head map[string]interface{}
+	require.NoError(t, json.Unmarshal(get("/head"), &head))
+	require.Equal(t, map[string]interface{}{"/": second.String()}, head["head"])
+
+	var ad advertisement
+	require.NoError(t, cbor.DecodeInto(get("/"+second.String()), &ad))
+	require.Equal(t, first, *ad.PreviousID)
+	require.Equal(t, []string{addr.String()}, ad.Addresses)
+	require.Equal(t, []byte("order-2"), ad.ContextID)
+
+	// the signature is an envelope of the signed fields by the provider
+	var sig adSignature
+	envelope, err := record.ConsumeTypedEnvelope(ad.Signature, &sig)
+	require.NoError(t, err)
+	require.True(t, envelope.PublicKey.Equals(privKey.GetPublic()))
+	payload, err := signaturePayload(&ad)
+	require.NoError(t, err)
+	require.Equal(t, payload, sig.payload)
+
+	var entries entryChunk
+	require.NoError(t, cbor.DecodeInto(get("/"+ad.Entries.String()), &entries))
+	require.Equal(t, [][]byte{shard.Hash()}, entries.Entries)
+	require.Nil(t, entries.Next)
+}
@@ -0,0 +1,95 @@
+package indexer
+
+import (
+	"bytes"
+	"sao-node/types"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/record"
+	"github.com/multiformats/go-multihash"
+)
+
+// advertisement and entryChunk follow the IPNI ingest schema.
+type advertisement struct {
+	PreviousID *cid.Cid `refmt:",omitempty"`
+	Provider   string
+	Addresses  []string
+	Signature  []byte
+	Entries    cid.Cid
+	ContextID  []byte
+	Metadata   []byte
+	IsRm       bool
+}
+
+type entryChunk struct {
+	Entries [][]byte
+	Next    *cid.Cid `refmt:",omitempty"`
+}
+
+func init() {
+	cbor.RegisterCborType(advertisement{})
+	cbor.RegisterCborType(entryChunk{})
+}
+
+// adSignature is the envelope record of the advertisement signatures.
+type adSignature struct {
+	payload []byte
+}
+
+func (s *adSignature) Domain() string {
+	return "indexer"
+}
+
+func (s *adSignature) Codec() []byte {
+	return []byte("/indexer/ingest/adSignature")
+}
+
+func (s *adSignature) MarshalRecord() ([]byte, error) {
+	return s.payload, nil
+}
+
+func (s *adSignature) UnmarshalRecord(data []byte) error {
+	s.payload = data
+	return nil
+}
+
+// signaturePayload is the multihash of the signed fields of the advertisement.
+func signaturePayload(ad *advertisement) ([]byte, error) {
+	var buf bytes.Buffer
+	if ad.PreviousID != nil {
+		buf.Write(ad.PreviousID.Bytes())
+	} else {
+		buf.Write(cid.Undef.Bytes())
+	}
+	buf.Write(ad.Entries.Bytes())
+	buf.WriteString(ad.Provider)
+	for _, addr := range ad.Addresses {
+		buf.WriteString(addr)
+	}
+	buf.Write(ad.ContextID)
+	buf.Write(ad.Metadata)
+	if ad.IsRm {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	return multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+}
+
+func signAdvertisement(ad *advertisement, privKey crypto.PrivKey) ([]byte, error) {
+	payload, err := signaturePayload(ad)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCalculateCidFailed, err)
+	}
+	envelope, err := record.Seal(&adSignature{payload: payload}, privKey)
+	if err != nil {
+		return nil, types.Wrap(types.ErrSignedFailed, err)
+	}
+	data, err := envelope.Marshal()
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,143 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sao-node/chain"
+	"sao-node/node/config"
+	"sao-node/node/gateway"
+	"sao-node/node/scheduler"
+	"sao-node/types"
+	"time"
+
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+var earningsPrefix = datastore.NewKey("/earnings")
+
+// earnings is the daily rollup of the balance and the reward of the node, the amounts are in chain.DENOM.
+type earnings struct {
+	Height  int64
+	Time    int64
+	Balance string
+	Reward  string
+}
+
+func (n *Node) addJob(ctx context.Context, name string, cfg config.Job, run scheduler.Func) error {
+	return n.scheduler.Add(ctx, name, cfg.Enable, cfg.Schedule, run)
+}
+
+func (n *Node) addGatewayJobs(ctx context.Context, gatewaySvc *gateway.GatewaySvc) error {
+	cfg := n.config().Scheduler
+	err := n.addJob(ctx, "renewals", cfg.Renewals, func(ctx context.Context) (string, error) {
+		ending, err := gatewaySvc.RemindRenewals(ctx)
+		return fmt.Sprintf("%d orders ending within %d blocks", ending, n.config().Gateway.RenewalWindow), err
+	})
+	if err != nil {
+		return err
+	}
+	return n.addJob(ctx, "audits", cfg.Audits, func(ctx context.Context) (string, error) {
+		audited, err := gatewaySvc.AuditOrders(ctx)
+		return fmt.Sprintf("%d orders audited", audited), err
+	})
+}
+
+func (n *Node) addStorageJobs(ctx context.Context) error {
+	cfg := n.config().Scheduler
+	err := n.addJob(ctx, "gc", cfg.GC, func(ctx context.Context) (string, error) {
+		terminated, err := n.storeSvc.CollectGarbage(ctx)
+		return fmt.Sprintf("%d shards released", terminated), err
+	})
+	if err != nil {
+		return err
+	}
+	return n.addJob(ctx, "scrub", cfg.Scrub, func(ctx context.Context) (string, error) {
+		verified, failed, err := n.storeSvc.Scrub(ctx)
+		return fmt.Sprintf("%d shards verified, %d failed", verified, failed), err
+	})
+}
+
+// rollupEarnings records the balance and the reward of the node of the day, and returns their change since the
+// previous day recorded.
+func (n *Node) rollupEarnings(ctx context.Context) (string, error) {
+	height, err := n.chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		return "", types.Wrap(types.ErrQueryHeightFailed, err)
+	}
+	balance, err := n.chainSvc.GetBalance(ctx, n.address)
+	if err != nil {
+		return "", types.Wrap(types.ErrGetBalanceFailed, err)
+	}
+	reward := sdktypes.ZeroInt()
+	// nodes never storing any shard have no pledge yet
+	if coin, err := n.chainSvc.GetReward(ctx, n.address); err == nil {
+		reward = coin.Amount
+	}
+
+	now := time.Now()
+	today := earnings{
+		Height:  height,
+		Time:    now.Unix(),
+		Balance: balance.AmountOf(chain.DENOM).String(),
+		Reward:  reward.String(),
+	}
+	key := earningsPrefix.ChildString(now.UTC().Format("2006-01-02"))
+	prev, err := n.previousEarnings(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(today)
+	if err != nil {
+		return "", types.Wrap(types.ErrMarshalFailed, err)
+	}
+	if err := n.mds.Put(ctx, key, data); err != nil {
+		return "", types.Wrap(types.ErrWriteFileFailed, err)
+	}
+
+	result := fmt.Sprintf("balance %s%s, reward %s%s", today.Balance, chain.DENOM, today.Reward, chain.DENOM)
+	if prev == nil {
+		return result, nil
+	}
+	return fmt.Sprintf("%s, %s%s and %s%s since height %d", result,
+		balance.AmountOf(chain.DENOM).Sub(parseAmount(prev.Balance)), chain.DENOM,
+		reward.Sub(parseAmount(prev.Reward)), chain.DENOM, prev.Height), nil
+}
+
+func parseAmount(s string) sdktypes.Int {
+	amount, ok := sdktypes.NewIntFromString(s)
+	if !ok {
+		return sdktypes.ZeroInt()
+	}
+	return amount
+}
+
+// previousEarnings returns the last rollup recorded before the one of key, nil if none.
+func (n *Node) previousEarnings(ctx context.Context, key datastore.Key) (*earnings, error) {
+	results, err := n.mds.Query(ctx, query.Query{
+		Prefix: earningsPrefix.String(),
+		Orders: []query.Order{query.OrderByKeyDescending{}},
+	})
+	if err != nil {
+		return nil, types.Wrap(types.ErrGetFailed, err)
+	}
+	defer results.Close()
+
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, types.Wrap(types.ErrGetFailed, r.Error)
+		}
+		if r.Key == key.String() {
+			continue
+		}
+		var prev earnings
+		if err := json.Unmarshal(r.Value, &prev); err != nil {
+			return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+		return &prev, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,249 @@
+package eventlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"sao-node/types"
+)
+
+// A log model is an append-only list of json entries numbered from 0, such as the messages of a chat or the
+// samples of a telemetry stream. The content of each commit of a log model is a segment holding the entries the
+// commit appends, so an append doesn't rewrite the entries before it. The segments are compacted by the policy of
+// the log: every CompactEvery commits, the commit holds all the entries retained instead, and the older ones are
+// dropped by Retain. A read never looks back further than the last compacted segment.
+
+// Policy is the compaction policy of a log, it is fixed when the log is created.
+type Policy struct {
+	// how many commits a segment is compacted after, never if 0
+	CompactEvery int `json:"compactEvery,omitempty"`
+	// how many of the latest entries a compaction keeps, all if 0
+	Retain uint64 `json:"retain,omitempty"`
+}
+
+// Segment is the content of a commit of a log model.
+type Segment struct {
+	// sequence number of the first entry of the segment
+	First   uint64            `json:"first"`
+	Entries []json.RawMessage `json:"entries"`
+	// sequence number of the first entry of the log, the entries before are dropped by the compactions
+	Start uint64 `json:"start"`
+	// version of the last compacted segment, which holds the entries from Start
+	Base   int    `json:"base"`
+	Policy Policy `json:"policy"`
+}
+
+// Loader returns the segment of the version of a log model.
+type Loader func(version int) (*Segment, error)
+
+// Alias returns the alias of the log model named name.
+func Alias(name string) string {
+	return types.Type_Prefix_Log + name
+}
+
+// IsLog returns whether the model of the alias is a log model.
+func IsLog(alias string) bool {
+	return strings.HasPrefix(alias, types.Type_Prefix_Log)
+}
+
+// New returns the segment of a new log, which has no entries.
+func New(policy Policy) (*Segment, error) {
+	if policy.CompactEvery < 0 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid compaction interval %d", policy.CompactEvery)
+	}
+	return &Segment{Entries: []json.RawMessage{}, Policy: policy}, nil
+}
+
+// Parse decodes the content of a commit of a log model.
+func Parse(content []byte) (*Segment, error) {
+	var s Segment
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, types.Wrapf(types.ErrInvalidContent, "invalid log segment: %v", err)
+	}
+	if s.First < s.Start || s.Base < 0 || s.Policy.CompactEvery < 0 {
+		return nil, types.Wrapf(types.ErrInvalidContent, "invalid log segment")
+	}
+	return &s, nil
+}
+
+// Marshal returns the content of the segment.
+func (s *Segment) Marshal() ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return data, nil
+}
+
+// End returns the sequence number of the next entry appended after the segment.
+func (s *Segment) End() uint64 {
+	return s.First + uint64(len(s.Entries))
+}
+
+// compactionDue returns whether the commit of version following the segment must be compacted.
+func (s *Segment) compactionDue(version int) bool {
+	return s.Policy.CompactEvery > 0 && version-s.Base >= s.Policy.CompactEvery
+}
+
+// Append returns the segment of the commit appending the entries to the log, latest is the segment of version, the
+// latest commit. The segment is compacted if the policy says so, the entries retained are read by load then.
+func Append(latest *Segment, version int, entries []json.RawMessage, load Loader) (*Segment, error) {
+	if len(entries) == 0 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "no entries to append")
+	}
+	for _, entry := range entries {
+		if !json.Valid(entry) {
+			return nil, types.Wrapf(types.ErrInvalidContent, "the entry is not json: %s", entry)
+		}
+	}
+
+	next := version + 1
+	if !latest.compactionDue(next) {
+		return &Segment{
+			First:   latest.End(),
+			Entries: entries,
+			Start:   latest.Start,
+			Base:    latest.Base,
+			Policy:  latest.Policy,
+		}, nil
+	}
+
+	retained, _, err := Read(latest, version, latest.Start, 0, load)
+	if err != nil {
+		return nil, err
+	}
+	retained = append(retained, entries...)
+	start := latest.Start
+	if retain := latest.Policy.Retain; retain > 0 && uint64(len(retained)) > retain {
+		start += uint64(len(retained)) - retain
+		retained = retained[uint64(len(retained))-retain:]
+	}
+	return &Segment{
+		First:   start,
+		Entries: retained,
+		Start:   start,
+		Base:    next,
+		Policy:  latest.Policy,
+	}, nil
+}
+
+// Read returns the entries of the log from the sequence number from, limit entries at most or all if limit is 0,
+// and the sequence number of the first entry returned. The entries dropped by the compactions are skipped. latest
+// is the segment of version, the latest commit, the other segments of the range are read by load.
+func Read(latest *Segment, version int, from uint64, limit int, load Loader) ([]json.RawMessage, uint64, error) {
+	if from < latest.Start {
+		from = latest.Start
+	}
+	to := latest.End()
+	if limit > 0 && from+uint64(limit) < to {
+		to = from + uint64(limit)
+	}
+	entries := make([]json.RawMessage, 0)
+	if from >= to {
+		return entries, from, nil
+	}
+
+	segments := map[int]*Segment{version: latest}
+	segment := func(v int) (*Segment, error) {
+		if s, ok := segments[v]; ok {
+			return s, nil
+		}
+		s, err := load(v)
+		if err != nil {
+			return nil, err
+		}
+		segments[v] = s
+		return s, nil
+	}
+
+	// the ends of the segments since the last compaction increase with the versions, the range starts in the first
+	// segment ending after from
+	lo, hi := latest.Base, version
+	for lo < hi {
+		mid := (lo + hi) / 2
+		s, err := segment(mid)
+		if err != nil {
+			return nil, 0, err
+		}
+		if s.End() > from {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	for v := lo; v <= version; v++ {
+		s, err := segment(v)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, entry := range s.Entries {
+			seq := s.First + uint64(i)
+			if seq >= to {
+				return entries, from, nil
+			}
+			if seq >= from {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, from, nil
+}
+
+// Check verifies the content of the commit of version of a log model, the previous content is the one of the
+// commit before. A segment appends entries to the previous one, or compacts the log when the policy says so, the
+// entries committed are never changed.
+func Check(previous []byte, content []byte, version int) error {
+	next, err := Parse(content)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		if next.First != 0 || next.Start != 0 || next.Base != 0 {
+			return types.Wrapf(types.ErrInvalidContent, "a new log starts from 0")
+		}
+		return nil
+	}
+
+	prev, err := Parse(previous)
+	if err != nil {
+		return err
+	}
+	if next.Policy != prev.Policy {
+		return types.Wrapf(types.ErrInvalidContent, "the policy of a log can't be changed")
+	}
+
+	if !prev.compactionDue(version) {
+		if next.Base != prev.Base || next.Start != prev.Start || next.First != prev.End() || len(next.Entries) == 0 {
+			return types.Wrapf(types.ErrInvalidContent, "the segment doesn't append to the log ending at %d", prev.End())
+		}
+		return nil
+	}
+
+	// compacted, the entries from the start retained are all in the segment
+	if next.Base != version || next.First != next.Start || next.End() <= prev.End() {
+		return types.Wrapf(types.ErrInvalidContent, "the segment of version %d must compact the log", version)
+	}
+	start := prev.Start
+	if retain := prev.Policy.Retain; retain > 0 && next.End()-start > retain {
+		start = next.End() - retain
+	}
+	if next.Start != start {
+		return types.Wrapf(types.ErrInvalidContent, "the compacted log must start from %d", start)
+	}
+	for seq := prev.First; seq < prev.End(); seq++ {
+		if seq >= next.Start && !sameJSON(next.Entries[seq-next.Start], prev.Entries[seq-prev.First]) {
+			return types.Wrapf(types.ErrInvalidContent, "the entry %d is changed by the compaction", seq)
+		}
+	}
+	return nil
+}
+
+func sameJSON(a json.RawMessage, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
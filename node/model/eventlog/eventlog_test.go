@@ -0,0 +1,161 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testLog keeps the contents of the commits of a log model
+type testLog struct {
+	commits [][]byte
+	loads   int
+}
+
+func (l *testLog) load(version int) (*Segment, error) {
+	l.loads++
+	if version >= len(l.commits) {
+		return nil, types.ErrNotFound
+	}
+	return Parse(l.commits[version])
+}
+
+func (l *testLog) latest(t *testing.T) (*Segment, int) {
+	s, err := Parse(l.commits[len(l.commits)-1])
+	require.NoError(t, err)
+	return s, len(l.commits) - 1
+}
+
+func (l *testLog) append(t *testing.T, entries ...string) *Segment {
+	latest, version := l.latest(t)
+	raw := make([]json.RawMessage, len(entries))
+	for i, entry := range entries {
+		raw[i] = json.RawMessage(entry)
+	}
+	next, err := Append(latest, version, raw, l.load)
+	require.NoError(t, err)
+	content, err := next.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, Check(l.commits[version], content, version+1))
+	l.commits = append(l.commits, content)
+	return next
+}
+
+func (l *testLog) read(t *testing.T, from uint64, limit int) (string, uint64) {
+	latest, version := l.latest(t)
+	entries, first, err := Read(latest, version, from, limit, l.load)
+	require.NoError(t, err)
+	data, err := json.Marshal(entries)
+	require.NoError(t, err)
+	return string(data), first
+}
+
+func newTestLog(t *testing.T, policy Policy) *testLog {
+	s, err := New(policy)
+	require.NoError(t, err)
+	content, err := s.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, Check(nil, content, 0))
+	return &testLog{commits: [][]byte{content}}
+}
+
+func TestAppendRead(t *testing.T) {
+	l := newTestLog(t, Policy{})
+	entries, first := l.read(t, 0, 0)
+	require.Equal(t, "[]", entries)
+	require.Equal(t, uint64(0), first)
+
+	for i := 0; i < 10; i += 2 {
+		s := l.append(t, strconv.Itoa(i), strconv.Itoa(i+1))
+		// an append holds its entries only
+		require.Len(t, s.Entries, 2)
+		require.Equal(t, uint64(i), s.First)
+	}
+
+	entries, _ = l.read(t, 0, 0)
+	require.Equal(t, "[0,1,2,3,4,5,6,7,8,9]", entries)
+	l.loads = 0
+	entries, first = l.read(t, 5, 3)
+	require.Equal(t, "[5,6,7]", entries)
+	require.Equal(t, uint64(5), first)
+	// found by a binary search over the 5 segments
+	require.LessOrEqual(t, l.loads, 4)
+	entries, _ = l.read(t, 9, 10)
+	require.Equal(t, "[9]", entries)
+	entries, first = l.read(t, 10, 10)
+	require.Equal(t, "[]", entries)
+	require.Equal(t, uint64(10), first)
+}
+
+func TestCompaction(t *testing.T) {
+	l := newTestLog(t, Policy{CompactEvery: 3, Retain: 4})
+	l.append(t, "0", "1")
+	l.append(t, "2")
+	s := l.append(t, "3", "4")
+	// the third commit compacts the log, keeping the latest 4 entries
+	require.Equal(t, 3, s.Base)
+	require.Equal(t, uint64(1), s.Start)
+	require.Equal(t, "[1,2,3,4]", mustMarshal(t, s.Entries))
+
+	l.append(t, "5")
+	l.append(t, "6")
+	entries, first := l.read(t, 0, 0)
+	require.Equal(t, "[1,2,3,4,5,6]", entries)
+	require.Equal(t, uint64(1), first)
+
+	// the reads don't look back further than the last compaction
+	l.loads = 0
+	entries, _ = l.read(t, 5, 0)
+	require.Equal(t, "[5,6]", entries)
+	l.append(t, "7")
+	entries, first = l.read(t, 0, 2)
+	require.Equal(t, "[4,5]", entries)
+	require.Equal(t, uint64(4), first)
+}
+
+func TestCheck(t *testing.T) {
+	l := newTestLog(t, Policy{CompactEvery: 2})
+	l.append(t, `{"a":1}`)
+	latest, version := l.latest(t)
+	check := func(s *Segment) error {
+		content, err := s.Marshal()
+		require.NoError(t, err)
+		return Check(l.commits[version], content, version+1)
+	}
+
+	// the compaction is due
+	appended := &Segment{First: 1, Entries: []json.RawMessage{json.RawMessage(`2`)}, Policy: latest.Policy}
+	require.True(t, types.ErrInvalidContent.Is(check(appended)))
+	// an entry committed is changed
+	changed := &Segment{Entries: []json.RawMessage{json.RawMessage(`{"a":2}`), json.RawMessage(`2`)}, Base: 2, Policy: latest.Policy}
+	require.True(t, types.ErrInvalidContent.Is(check(changed)))
+	compacted := &Segment{Entries: []json.RawMessage{json.RawMessage(`{ "a": 1 }`), json.RawMessage(`2`)}, Base: 2, Policy: latest.Policy}
+	require.NoError(t, check(compacted))
+	// the policy is fixed
+	compacted.Policy.Retain = 1
+	require.True(t, types.ErrInvalidContent.Is(check(compacted)))
+
+	l = newTestLog(t, Policy{})
+	l.append(t, "0")
+	latest, version = l.latest(t)
+	require.True(t, types.ErrInvalidContent.Is(check(&Segment{First: 0, Entries: []json.RawMessage{json.RawMessage(`1`)}})))
+	require.True(t, types.ErrInvalidContent.Is(check(&Segment{First: 1})))
+	require.NoError(t, check(&Segment{First: 1, Entries: []json.RawMessage{json.RawMessage(`1`)}}))
+	require.True(t, types.ErrInvalidContent.Is(Check(nil, []byte(`{"first":1,"start":1}`), 0)))
+	require.True(t, types.ErrInvalidContent.Is(Check(l.commits[version], []byte(`[]`), version+1)))
+
+	_, err := Append(latest, version, nil, l.load)
+	require.True(t, types.ErrInvalidParameters.Is(err))
+	_, err = Append(latest, version, []json.RawMessage{json.RawMessage(`{`)}, l.load)
+	require.True(t, types.ErrInvalidContent.Is(err))
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,145 @@
+package filedelta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"sao-node/types"
+	"sao-node/utils"
+)
+
+// The updates of a file model commit binary deltas instead of the whole files, so updating a large binary asset only
+// transfers and stores the bytes changed. The content of such a commit is a delta: a header naming the commit it is
+// made on, and the binary patch turning the file of that commit into the new one, see utils.GenerateBinaryPatch. The
+// gateway resolves the deltas when the file is loaded, by resolving the commits they are made on in turn. A delta
+// made on no commit holds the whole file, the versions of FullEvery commit the whole file so, which bounds how many
+// commits a load resolves.
+
+// FullEvery is the interval of the versions committing the whole file, v16, v32 and so on.
+const FullEvery = 16
+
+const magic = "SAODELTA"
+
+// Header describes the file of a delta.
+type Header struct {
+	// commit id of the commit the delta is made on, empty if the delta holds the whole file
+	Base string `json:"base,omitempty"`
+	// cid and size of the file
+	Cid  string `json:"cid"`
+	Size uint64 `json:"size"`
+}
+
+// IsFile returns whether the model of the alias is a file model.
+func IsFile(alias string) bool {
+	return strings.HasPrefix(alias, types.Type_Prefix_File)
+}
+
+// IsDelta returns whether the content is a delta.
+func IsDelta(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(magic))
+}
+
+// FullDue returns whether the commit of version must hold the whole file.
+func FullDue(version int) bool {
+	return version%FullEvery == 0
+}
+
+// Make returns the delta turning base, the file of the commit baseCommitId, into target. The delta holds the whole
+// target if baseCommitId is empty.
+func Make(base []byte, baseCommitId string, target []byte) ([]byte, error) {
+	if baseCommitId == "" {
+		base = nil
+	}
+	targetCid, err := utils.CalculateCid(target)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(Header{Base: baseCommitId, Cid: targetCid.String(), Size: uint64(len(target))})
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	patch, err := utils.GenerateBinaryPatch(base, target)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := make([]byte, 0, len(magic)+binary.MaxVarintLen64+len(header)+len(patch))
+	delta = append(delta, magic...)
+	delta = binary.AppendUvarint(delta, uint64(len(header)))
+	delta = append(delta, header...)
+	return append(delta, patch...), nil
+}
+
+// Commit returns the delta committing target as the version of the file model, made on base, the file of the commit
+// baseCommitId before. The delta holds the whole target if base is empty, the version must hold the whole file, or a
+// delta isn't much smaller than the target.
+func Commit(base []byte, baseCommitId string, version int, target []byte) ([]byte, error) {
+	if len(base) == 0 || baseCommitId == "" || FullDue(version) {
+		return Make(nil, "", target)
+	}
+	delta, err := Make(base, baseCommitId, target)
+	if err != nil {
+		return nil, err
+	}
+	if len(delta) > len(target)/2 {
+		return Make(nil, "", target)
+	}
+	return delta, nil
+}
+
+// Parse returns the header and the binary patch of the delta.
+func Parse(delta []byte) (Header, []byte, error) {
+	var header Header
+	if !IsDelta(delta) {
+		return header, nil, types.Wrapf(types.ErrInvalidContent, "not a file delta")
+	}
+	length, n := binary.Uvarint(delta[len(magic):])
+	start := len(magic) + n
+	if n <= 0 || length > uint64(len(delta)-start) {
+		return header, nil, types.Wrapf(types.ErrInvalidContent, "invalid file delta header")
+	}
+	if err := json.Unmarshal(delta[start:start+int(length)], &header); err != nil {
+		return header, nil, types.Wrapf(types.ErrInvalidContent, "invalid file delta header: %v", err)
+	}
+	return header, delta[start+int(length):], nil
+}
+
+// Resolve writes the file of the delta made on base to w and returns the header of the delta. The file is checked
+// against the cid and the size of the header, base is ignored if the delta holds the whole file.
+func Resolve(base io.ReaderAt, delta []byte, w io.Writer) (Header, error) {
+	header, patch, err := Parse(delta)
+	if err != nil {
+		return header, err
+	}
+	if header.Base == "" {
+		base = bytes.NewReader(nil)
+	}
+
+	// the file is hashed as it is written
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		size, err := utils.ApplyBinaryPatch(base, patch, io.MultiWriter(w, pw))
+		if err == nil && uint64(size) != header.Size {
+			err = types.Wrapf(types.ErrInvalidContent, "the file delta resolves to %d bytes, not %d", size, header.Size)
+		}
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	fileCid, err := utils.CalculateCidFrom(pr)
+	// unblocks the patch if the hashing fails
+	pr.Close()
+	if patchErr := <-done; patchErr != nil {
+		return header, patchErr
+	}
+	if err != nil {
+		return header, err
+	}
+	if fileCid.String() != header.Cid {
+		return header, types.Wrapf(types.ErrInvalidCid, "the file delta resolves to %s, not %s", fileCid, header.Cid)
+	}
+	return header, nil
+}
@@ -0,0 +1,57 @@
+package filedelta
+
+import (
+	"bytes"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	base := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	target := append(append([]byte(nil), base[:30000]...), base[30010:]...)
+
+	delta, err := Make(base, "c1", target)
+	require.NoError(t, err)
+	require.True(t, IsDelta(delta))
+	require.Less(t, len(delta), 256)
+	header, _, err := Parse(delta)
+	require.NoError(t, err)
+	require.Equal(t, "c1", header.Base)
+	require.Equal(t, uint64(len(target)), header.Size)
+
+	var file bytes.Buffer
+	_, err = Resolve(bytes.NewReader(base), delta, &file)
+	require.NoError(t, err)
+	require.Equal(t, target, file.Bytes())
+
+	// the file is checked against the header
+	changed := append([]byte(nil), base...)
+	changed[100] = 'x'
+	_, err = Resolve(bytes.NewReader(changed), delta, &bytes.Buffer{})
+	require.True(t, types.ErrInvalidCid.Is(err))
+	_, err = Resolve(bytes.NewReader(base[:1000]), delta, &bytes.Buffer{})
+	require.True(t, types.ErrApplyPatchFailed.Is(err))
+
+	// a delta made on no commit holds the whole file
+	delta, err = Make(base, "", target)
+	require.NoError(t, err)
+	file.Reset()
+	header, err = Resolve(nil, delta, &file)
+	require.NoError(t, err)
+	require.Empty(t, header.Base)
+	require.Equal(t, target, file.Bytes())
+
+	_, _, err = Parse(target)
+	require.True(t, types.ErrInvalidContent.Is(err))
+	_, _, err = Parse(delta[:len(magic)+4])
+	require.True(t, types.ErrInvalidContent.Is(err))
+
+	require.True(t, FullDue(0))
+	require.False(t, FullDue(1))
+	require.True(t, FullDue(FullEvery))
+	require.True(t, IsFile("file_a.png"))
+	require.False(t, IsFile("log_a"))
+}
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"sao-node/node/gateway"
+	schema_helper "sao-node/node/model/schema"
+	"sao-node/types"
+	"sao-node/utils"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+const (
+	// the property of the objects linking to another model in the content, {"$ref": "sao://<dataId>"}
+	PROPERTY_REF = "$ref"
+	// the property of the links failed to resolve, set to the error
+	PROPERTY_REF_ERROR = "$error"
+
+	// the deepest links ModelLoad resolves
+	MaxResolveDepth = 5
+	// how many models are loaded at most to resolve the links of a model
+	maxResolvedLinks = 100
+)
+
+// ResolveLinks replaces the links in the content of the model loaded by req with the contents of the models they
+// refer to, up to depth levels deep, and returns the expanded content. The linked models are loaded on behalf of the
+// did req is verified for and are cached the same as the models loaded by it. A link failing to resolve is kept with
+// the error in "$error", a link to a model it is nested in is kept as is. The content not in json is returned as is.
+func (mm *ModelManager) ResolveLinks(ctx context.Context, req *types.MetadataProposal, model *types.Model, depth int) ([]byte, error) {
+	if depth > MaxResolveDepth {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "resolve depth %d exceeds %d", depth, MaxResolveDepth)
+	}
+	content, err := decodeContent(model.Content)
+	if err != nil || depth <= 0 {
+		return model.Content, nil
+	}
+
+	refCtx := ctx
+	if req.Proposal.Owner != "all" {
+		refCtx = gateway.WithReferrer(ctx, req)
+	}
+	l := &linkResolver{
+		loaded: make(map[string]interface{}),
+		load: func(dataId string) (interface{}, error) {
+			if req.Delegation != nil {
+				if err := req.Delegation.Delegation.Allows(types.CapModelLoad, dataId, time.Now()); err != nil {
+					return nil, err
+				}
+			}
+			linked, err := mm.Load(refCtx, &types.MetadataProposal{
+				Proposal: saotypes.QueryProposal{
+					Owner:           req.Proposal.Owner,
+					Keyword:         dataId,
+					GroupId:         req.Proposal.GroupId,
+					LastValidHeight: req.Proposal.LastValidHeight,
+					Gateway:         req.Proposal.Gateway,
+				},
+				Delegation: req.Delegation,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return decodeContent(linked.Content)
+		},
+	}
+	expanded, err := json.Marshal(l.resolve(content, depth, []string{model.DataId}))
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return expanded, nil
+}
+
+type linkResolver struct {
+	load func(dataId string) (interface{}, error)
+	// the decoded contents of the linked models loaded, or their errors, by data id
+	loaded map[string]interface{}
+}
+
+// resolve returns the value with its links resolved, ancestors are the data ids of the models value is nested in.
+func (l *linkResolver) resolve(value interface{}, depth int, ancestors []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if dataId, ok := linkOf(v); ok {
+			if depth <= 0 {
+				return v
+			}
+			for _, ancestor := range ancestors {
+				if ancestor == dataId {
+					return v
+				}
+			}
+			linked, err := l.fetch(dataId)
+			if err != nil {
+				return map[string]interface{}{PROPERTY_REF: v[PROPERTY_REF], PROPERTY_REF_ERROR: err.Error()}
+			}
+			return l.resolve(linked, depth-1, append(ancestors[:len(ancestors):len(ancestors)], dataId))
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			resolved[key] = l.resolve(elem, depth, ancestors)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, elem := range v {
+			resolved[i] = l.resolve(elem, depth, ancestors)
+		}
+		return resolved
+	}
+	return value
+}
+
+func (l *linkResolver) fetch(dataId string) (interface{}, error) {
+	if linked, ok := l.loaded[dataId]; ok {
+		if err, isErr := linked.(error); isErr {
+			return nil, err
+		}
+		return linked, nil
+	}
+	if len(l.loaded) >= maxResolvedLinks {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "more than %d linked models", maxResolvedLinks)
+	}
+	linked, err := l.load(dataId)
+	if err != nil {
+		l.loaded[dataId] = err
+		return nil, err
+	}
+	l.loaded[dataId] = linked
+	return linked, nil
+}
+
+// linkOf returns the data id the object links to.
+func linkOf(obj map[string]interface{}) (string, bool) {
+	ref, ok := obj[PROPERTY_REF].(string)
+	if !ok || !strings.HasPrefix(ref, schema_helper.SAO_LINK_PREFIX) {
+		return "", false
+	}
+	dataId := strings.TrimPrefix(ref, schema_helper.SAO_LINK_PREFIX)
+	return dataId, utils.IsDataId(dataId)
+}
+
+func decodeContent(content []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// the numbers are kept as they are
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveLinks(t *testing.T) {
+	const (
+		root    = "6f1e9f3e-0a6b-11ee-be56-0242ac120002"
+		author  = "7a2c1b4e-0a6b-11ee-be56-0242ac120002"
+		org     = "8b3d2c5f-0a6b-11ee-be56-0242ac120002"
+		missing = "9c4e3d60-0a6b-11ee-be56-0242ac120002"
+	)
+	contents := map[string]string{
+		author: `{"name": "Ada", "org": {"$ref": "sao://` + org + `"}, "notes": {"$ref": "sao://` + root + `"}}`,
+		org:    `{"name": "Analytical Engines", "size": 10000000000000000001}`,
+	}
+	loads := 0
+	l := &linkResolver{
+		loaded: make(map[string]interface{}),
+		load: func(dataId string) (interface{}, error) {
+			loads++
+			content, ok := contents[dataId]
+			if !ok {
+				return nil, types.Wrapf(types.ErrNotFound, "model %s", dataId)
+			}
+			return decodeContent([]byte(content))
+		},
+	}
+	content, err := decodeContent([]byte(`{
+		"title": "Note",
+		"authors": [{"$ref": "sao://` + author + `"}, {"$ref": "sao://` + author + `"}],
+		"ref": {"$ref": "sao://` + missing + `"},
+		"other": {"$ref": "https://example.com"}
+	}`))
+	require.NoError(t, err)
+
+	expanded, err := json.Marshal(l.resolve(content, 2, []string{root}))
+	require.NoError(t, err)
+	authorJSON := `{"name": "Ada", "org": {"name": "Analytical Engines", "size": 10000000000000000001}, "notes": {"$ref": "sao://` + root + `"}}`
+	require.JSONEq(t, fmt.Sprintf(`{
+		"title": "Note",
+		"authors": [%s, %s],
+		"ref": {"$ref": "sao://%s", "$error": %q},
+		"other": {"$ref": "https://example.com"}
+	}`, authorJSON, authorJSON, missing, types.Wrapf(types.ErrNotFound, "model %s", missing).Error()), string(expanded))
+	// each linked model is loaded once
+	require.Equal(t, 3, loads)
+	require.Contains(t, string(expanded), "10000000000000000001")
+
+	// one level deep, the links of the linked models are kept
+	l.loaded = make(map[string]interface{})
+	expanded, err = json.Marshal(l.resolve(content, 1, []string{root}))
+	require.NoError(t, err)
+	require.Contains(t, string(expanded), `{"$ref":"sao://`+org+`"}`)
+}
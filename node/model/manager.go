@@ -5,33 +5,60 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"sao-node/node/cache"
 	"sao-node/node/config"
+	"sao-node/node/events"
 	"sao-node/node/gateway"
+	"sao-node/node/model/eventlog"
+	"sao-node/node/model/filedelta"
+	"sao-node/node/model/rule_engine"
+	"sao-node/node/model/schema/registry"
 	"sao-node/node/model/schema/validator"
+	"sao-node/node/permission"
+	"sao-node/node/platform"
 	"sao-node/types"
 	"sao-node/utils"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/libp2p/go-libp2p/core/host"
 )
 
 const PROPERTY_CONTEXT = "@context"
 const PROPERTY_TYPE = "@type"
 const MODEL_TYPE_FILE = "File"
 
+const (
+	modelUpdateBuffer  = 64
+	cacheSweepInterval = time.Minute
+	// how many compiled CEL rules are kept
+	celRulesCacheSize = 1024
+)
+
 var log = logging.Logger("model")
 
 type ModelManager struct {
-	CacheCfg *config.Cache
+	// a copy of the cache config, ReloadCacheConfig changes it while the requests read it
+	cacheLk  sync.RWMutex
+	cacheCfg config.Cache
 	CacheSvc cache.CacheSvcApi
 	// used by gateway module
 	GatewaySvc gateway.GatewaySvcApi
+	// nil if the cache coherence is disabled
+	coherence *cache.Coherence
+	// nil if the permission history is not recorded
+	permissions *permission.History
+	celRules    *rule_engine.CelRules
+	platforms   *platform.Registry
 }
 
 var (
@@ -39,45 +66,209 @@ var (
 	once         sync.Once
 )
 
-func NewModelManager(cacheCfg *config.Cache, gatewaySvc gateway.GatewaySvcApi) *ModelManager {
+func NewModelManager(ctx context.Context, cacheCfg *config.Cache, gatewaySvc gateway.GatewaySvcApi, cacheDs datastore.Batching) *ModelManager {
 	once.Do(func() {
 		var cacheSvc cache.CacheSvcApi
 		if cacheCfg.RedisConn == "" && cacheCfg.MemcachedConn == "" {
 			cacheSvc = cache.NewLruCacheSvc()
+			if cacheCfg.DiskCacheCapacity > 0 {
+				tiered, err := cache.NewTieredCacheSvc(ctx, cacheDs, cacheCfg.CacheCapacity, cacheCfg.DiskCacheCapacity, decodeModel)
+				if err != nil {
+					log.Errorf("disk cache disabled: %v", err)
+				} else {
+					// the limits bound the warm up as well
+					tiered.SetLimits(cacheCfg.CacheTTL, cacheCfg.CacheMaxBytes)
+					tiered.WarmUp(cacheCfg.WarmCacheBytes)
+					cacheSvc = tiered
+				}
+			}
 		} else if cacheCfg.RedisConn != "" {
-			cacheSvc = cache.NewRedisCacheSvc(cacheCfg.RedisConn, cacheCfg.RedisPassword, cacheCfg.RedisPoolSize)
+			cacheSvc = cache.NewRedisCacheSvc(cacheCfg.RedisConn, cacheCfg.RedisPassword, cacheCfg.RedisPoolSize, cacheCfg.CacheTTL)
 		} else if cacheCfg.MemcachedConn != "" {
-			cacheSvc = cache.NewMemcachedCacheSvc(cacheCfg.MemcachedConn)
+			cacheSvc = cache.NewMemcachedCacheSvc(cacheCfg.MemcachedConn, cacheCfg.CacheTTL)
+		}
+
+		if local, ok := cacheSvc.(cache.LocalCacheSvc); ok {
+			local.SetLimits(cacheCfg.CacheTTL, cacheCfg.CacheMaxBytes)
+			go sweepCache(ctx, local)
+		}
+
+		celRules, err := rule_engine.NewCelRules(celRulesCacheSize)
+		if err != nil {
+			log.Errorf("CEL rules disabled: %v", err)
 		}
 
 		modelManager = &ModelManager{
-			CacheCfg:   cacheCfg,
+			cacheCfg:   *cacheCfg,
 			CacheSvc:   cacheSvc,
 			GatewaySvc: gatewaySvc,
+			celRules:   celRules,
 		}
 	})
 
 	return modelManager
 }
 
+// decodeModel restores the models spilled to the disk cache.
+func decodeModel(data []byte) (interface{}, error) {
+	var model types.Model
+	err := json.Unmarshal(data, &model)
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// sweepCache drops the expired models periodically, so the models never read again are not kept forever.
+func sweepCache(ctx context.Context, local cache.LocalCacheSvc) {
+	ticker := time.NewTicker(cacheSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			local.Sweep()
+		}
+	}
+}
+
+// ReloadCacheConfig applies the cache settings which can be changed at runtime, existing lru caches
+// are resized to the new capacity.
+func (mm *ModelManager) ReloadCacheConfig(cacheCfg config.Cache) error {
+	mm.cacheLk.Lock()
+	defer mm.cacheLk.Unlock()
+
+	// the local caches are resized, the remote ones have their own capacity and take the ttl on start
+	if local, ok := mm.CacheSvc.(cache.LocalCacheSvc); ok {
+		if cacheCfg.CacheCapacity != mm.cacheCfg.CacheCapacity {
+			local.SetCapacity(cacheCfg.CacheCapacity)
+		}
+		if cacheCfg.CacheTTL != mm.cacheCfg.CacheTTL || cacheCfg.CacheMaxBytes != mm.cacheCfg.CacheMaxBytes {
+			local.SetLimits(cacheCfg.CacheTTL, cacheCfg.CacheMaxBytes)
+		}
+	}
+
+	mm.cacheCfg.EnableCache = cacheCfg.EnableCache
+	mm.cacheCfg.CacheCapacity = cacheCfg.CacheCapacity
+	mm.cacheCfg.ContentLimit = cacheCfg.ContentLimit
+	mm.cacheCfg.CacheTTL = cacheCfg.CacheTTL
+	mm.cacheCfg.CacheMaxBytes = cacheCfg.CacheMaxBytes
+
+	return nil
+}
+
+// CacheConfig returns the cache config in effect.
+func (mm *ModelManager) CacheConfig() config.Cache {
+	mm.cacheLk.RLock()
+	defer mm.cacheLk.RUnlock()
+
+	return mm.cacheCfg
+}
+
+// SetPermissionHistory records the permission updates seen on chain once the model updates are watched.
+func (mm *ModelManager) SetPermissionHistory(history *permission.History) {
+	mm.permissions = history
+}
+
+// SetPlatforms caches the models of the platforms isolating their caches apart from the other platforms.
+func (mm *ModelManager) SetPlatforms(platforms *platform.Registry) {
+	mm.platforms = platforms
+}
+
+// PermissionHistory returns the permission changes of the data model recorded by this node.
+func (mm *ModelManager) PermissionHistory(ctx context.Context, owner string, dataId string) ([]permission.Change, error) {
+	changes, err := mm.permissions.List(ctx, dataId)
+	if err != nil {
+		return nil, types.Wrap(types.ErrGetFailed, err)
+	}
+	if len(changes) > 0 && changes[0].Owner != owner {
+		return nil, types.Wrapf(types.ErrNoPermission, "%s is not the owner of %s", owner, dataId)
+	}
+	return changes, nil
+}
+
+// EnableCoherence evicts the cached models updated through the other gateways of the cluster, and
+// tells them about the updates through this one.
+func (mm *ModelManager) EnableCoherence(ctx context.Context, h host.Host, topic string) error {
+	coherence, err := cache.NewCoherence(ctx, h, topic, mm.CacheSvc)
+	if err != nil {
+		return err
+	}
+	mm.coherence = coherence
+	return nil
+}
+
+// WatchModelUpdates evicts the cached models changed on chain, published to the bus, until ctx is done, so the
+// commits through the other gateways are loaded instead of the stale cached versions.
+func (mm *ModelManager) WatchModelUpdates(ctx context.Context, bus *events.Bus) {
+	bus.Consume(ctx, events.TopicChain, modelUpdateBuffer, func(ev events.Event) {
+		update := ev.(events.ChainEvent).Update
+		for _, name := range mm.platforms.CacheNames(update.Owner) {
+			mm.CacheSvc.Evict(name, update.DataId)
+			if update.Alias != "" {
+				mm.CacheSvc.Evict(name, update.Alias)
+			}
+		}
+		log.Debugf("evicted cached model %s of %s on chain update", update.DataId, update.Owner)
+
+		if update.Permission != nil {
+			err := mm.permissions.Record(ctx, permission.Change{
+				DataId:        update.DataId,
+				Owner:         update.Owner,
+				Height:        update.Height,
+				TxHash:        update.TxHash,
+				ReadonlyDids:  update.Permission.ReadonlyDids,
+				ReadwriteDids: update.Permission.ReadwriteDids,
+			})
+			if err != nil {
+				log.Warnf("record the permission update of %s: %v", update.DataId, err)
+			}
+		}
+	})
+}
+
 func (mm *ModelManager) Stop(ctx context.Context) error {
 	log.Info("stopping model manager...")
 
 	mm.GatewaySvc.Stop(ctx)
 
+	if err := mm.coherence.Close(ctx); err != nil {
+		log.Warnf("close cache coherence: %v", err)
+	}
+
+	if tiered, ok := mm.CacheSvc.(*cache.TieredCacheSvc); ok && mm.CacheConfig().PersistCache {
+		tiered.Flush()
+	}
+
 	return nil
 }
 
 func (mm *ModelManager) Load(ctx context.Context, req *types.MetadataProposal) (*types.Model, error) {
+	return mm.load(ctx, req, true)
+}
+
+// LoadMeta loads the model without its content, the shards are not fetched from the storage nodes. The model
+// loaded is not cached.
+func (mm *ModelManager) LoadMeta(ctx context.Context, req *types.MetadataProposal) (*types.Model, error) {
+	return mm.load(ctx, req, false)
+}
+
+func (mm *ModelManager) load(ctx context.Context, req *types.MetadataProposal, withContent bool) (*types.Model, error) {
 	log.Info("KeyWord:", req.Proposal.Keyword)
 
-	model := mm.loadModel(req.Proposal.Owner, req.Proposal.Keyword)
+	model := mm.loadModel(req.Proposal.GroupId, req.Proposal.Owner, req.Proposal.Keyword)
 	if model != nil {
 		if (req.Proposal.CommitId == "" || model.CommitId == req.Proposal.CommitId) && len(model.Content) > 0 {
 			log.Debug("model", model)
 			return model, nil
 		}
 	}
+	if !withContent {
+		// the cached model is only updated along with its content
+		model = nil
+	}
 
 	meta, err := mm.GatewaySvc.QueryMeta(ctx, req, 0)
 	if err != nil {
@@ -153,6 +344,7 @@ func (mm *ModelManager) Load(ctx context.Context, req *types.MetadataProposal) (
 			Commits:  meta.Commits,
 			// Content: N/a,
 			ExtendInfo: meta.ExtendInfo,
+			Rule:       meta.Rule,
 		}
 	} else {
 		model.OrderId = meta.OrderId
@@ -161,6 +353,12 @@ func (mm *ModelManager) Load(ctx context.Context, req *types.MetadataProposal) (
 		model.CommitId = meta.CommitId
 		model.Commits = meta.Commits
 		model.ExtendInfo = meta.ExtendInfo
+		model.Rule = meta.Rule
+	}
+
+	if !withContent {
+		model.Version = version
+		return model, nil
 	}
 
 	result, err := mm.GatewaySvc.FetchContent(ctx, req, meta)
@@ -177,24 +375,69 @@ func (mm *ModelManager) Load(ctx context.Context, req *types.MetadataProposal) (
 }
 
 func (mm *ModelManager) Create(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte) (*types.Model, error) {
+	return mm.create(ctx, req, clientProposal, orderId, content, false)
+}
+
+// CreateAsync creates the model once its order is stored on chain, without waiting for the order to be readied.
+func (mm *ModelManager) CreateAsync(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte) (*types.Model, error) {
+	return mm.create(ctx, req, clientProposal, orderId, content, true)
+}
+
+// CreateFile creates the model of the content in the file at path, the file is staged in place instead of being
+// read in memory. The model is cached without its content.
+func (mm *ModelManager) CreateFile(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal, orderId uint64, path string) (*types.Model, error) {
 	orderProposal := clientProposal.Proposal
 	if orderProposal.Alias == "" {
 		orderProposal.Alias = orderProposal.Cid
 	}
+	err := mm.checkNew(ctx, req, orderProposal)
+	if err != nil {
+		return nil, err
+	}
 
-	oldModel := mm.loadModel(orderProposal.Owner, orderProposal.DataId)
-	if oldModel != nil {
-		return nil, types.Wrapf(types.ErrInvalidDataId, "the model is exsiting already, alias: %s, dataId: %s", oldModel.Alias, oldModel.DataId)
+	file, err := mm.GatewaySvc.OpenStaged(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if orderProposal.Size_ == 0 || file.Size() == 0 {
+		return nil, types.Wrapf(types.ErrInvalidContent, "the content is empty")
 	}
 
-	oldModel = mm.loadModel(orderProposal.Owner, orderProposal.Alias)
-	if oldModel != nil {
-		return nil, types.Wrapf(types.ErrInvalidDataId, "the model is exsiting already, alias: %s, dataId: %s", oldModel.Alias, oldModel.DataId)
+	// only the json documents can refer to a schema, they are read to be validated
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if bytes.HasPrefix(bytes.TrimLeft(head[:n], " \t\r\n"), []byte("{")) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		err = mm.validateModel(ctx, orderProposal.Owner, orderProposal.Alias, content, orderProposal.Rule)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	meta, err := mm.GatewaySvc.QueryMeta(ctx, req, 0)
-	if err == nil && meta != nil {
-		return nil, types.Wrapf(types.ErrConflictId, "the model is exsiting already, alias: %s, dataId: %s", meta.Alias, meta.DataId)
+	result, err := mm.GatewaySvc.CommitModelFile(ctx, clientProposal, orderId, path)
+	if err != nil {
+		return nil, err
+	}
+	model := newModel(orderProposal, result, nil)
+	mm.cacheModel(orderProposal.Owner, model)
+	return model, nil
+}
+
+func (mm *ModelManager) create(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal, orderId uint64, content []byte, async bool) (*types.Model, error) {
+	orderProposal := clientProposal.Proposal
+	if orderProposal.Alias == "" {
+		orderProposal.Alias = orderProposal.Cid
+	}
+	err := mm.checkNew(ctx, req, orderProposal)
+	if err != nil {
+		return nil, err
 	}
 
 	if orderProposal.Size_ == 0 || len(content) == 0 {
@@ -205,18 +448,81 @@ func (mm *ModelManager) Create(ctx context.Context, req *types.MetadataProposal,
 	if err != nil {
 		return nil, err
 	}
+	if eventlog.IsLog(orderProposal.Alias) {
+		err = eventlog.Check(nil, content, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Commit
-	result, err := mm.GatewaySvc.CommitModel(ctx, clientProposal, orderId, content)
+	var result *gateway.CommitResult
+	if async {
+		result, err = mm.GatewaySvc.CommitModelAsync(ctx, clientProposal, orderId, content)
+	} else {
+		result, err = mm.GatewaySvc.CommitModel(ctx, clientProposal, orderId, content)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	model := newModel(orderProposal, result, content)
+
+	// the commit height isn't known until the order is readied
+	if !async {
+		mm.cacheModel(orderProposal.Owner, model)
+	}
+
+	return model, nil
+}
+
+// checkNew returns an error if the model of the proposal exists already.
+// CheckNew returns ErrConflictId if the data id or the alias of the order proposal is taken, or an order of the data
+// id is in process already, so the content of a model created once is not staged again.
+func (mm *ModelManager) CheckNew(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal) error {
+	orderProposal := clientProposal.Proposal
+	if orderProposal.Alias == "" {
+		orderProposal.Alias = orderProposal.Cid
+	}
+	err := mm.checkNew(ctx, req, orderProposal)
+	if err != nil {
+		return err
+	}
+
+	orderInfo, err := mm.GatewaySvc.OrderStatus(ctx, orderProposal.DataId)
+	if err != nil {
+		return types.Wrap(types.ErrGetFailed, err)
+	}
+	if orderInfo.DataId != "" {
+		return types.Wrapf(types.ErrConflictId, "the order of %s is in process already", orderProposal.DataId)
+	}
+	return nil
+}
+
+func (mm *ModelManager) checkNew(ctx context.Context, req *types.MetadataProposal, orderProposal saotypes.Proposal) error {
+	oldModel := mm.loadModel(orderProposal.GroupId, orderProposal.Owner, orderProposal.DataId)
+	if oldModel != nil {
+		return types.Wrapf(types.ErrInvalidDataId, "the model is exsiting already, alias: %s, dataId: %s", oldModel.Alias, oldModel.DataId)
+	}
+
+	oldModel = mm.loadModel(orderProposal.GroupId, orderProposal.Owner, orderProposal.Alias)
+	if oldModel != nil {
+		return types.Wrapf(types.ErrInvalidDataId, "the model is exsiting already, alias: %s, dataId: %s", oldModel.Alias, oldModel.DataId)
+	}
+
+	meta, err := mm.GatewaySvc.QueryMeta(ctx, req, 0)
+	if err == nil && meta != nil {
+		return types.Wrapf(types.ErrConflictId, "the model is exsiting already, alias: %s, dataId: %s", meta.Alias, meta.DataId)
+	}
+	return nil
+}
+
+func newModel(orderProposal saotypes.Proposal, result *gateway.CommitResult, content []byte) *types.Model {
 	commit := bytes.NewBufferString(orderProposal.CommitId)
 	commit.WriteByte(26)
 	commit.WriteString(fmt.Sprintf("%d", result.Height))
 
-	model := &types.Model{
+	return &types.Model{
 		DataId:     result.DataId,
 		Alias:      orderProposal.Alias,
 		GroupId:    orderProposal.GroupId,
@@ -231,10 +537,6 @@ func (mm *ModelManager) Create(ctx context.Context, req *types.MetadataProposal,
 		Content:    content,
 		ExtendInfo: orderProposal.ExtendInfo,
 	}
-
-	mm.cacheModel(orderProposal.Owner, model)
-
-	return model, nil
 }
 
 func (mm *ModelManager) Update(ctx context.Context, req *types.MetadataProposal, clientProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (*types.Model, error) {
@@ -249,7 +551,7 @@ func (mm *ModelManager) Update(ctx context.Context, req *types.MetadataProposal,
 
 	commitId := commitIds[0]
 	var isFetch = true
-	orgModel := mm.loadModel(clientProposal.Proposal.Owner, meta.DataId)
+	orgModel := mm.loadModel(meta.GroupId, clientProposal.Proposal.Owner, meta.DataId)
 	if orgModel != nil {
 		if commitId == meta.CommitId && len(orgModel.Content) > 0 {
 			// found latest data model in local cache
@@ -274,6 +576,8 @@ func (mm *ModelManager) Update(ctx context.Context, req *types.MetadataProposal,
 		}
 	}
 
+	// the file too large to be returned is loaded to contentPath
+	var contentPath string
 	if isFetch {
 		result, err := mm.GatewaySvc.FetchContent(ctx, req, meta)
 		if err != nil {
@@ -282,6 +586,12 @@ func (mm *ModelManager) Update(ctx context.Context, req *types.MetadataProposal,
 		log.Info("result: ", result)
 		log.Info("orgModel: ", orgModel)
 		orgModel.Content = result.Content
+		contentPath = result.Path
+	}
+
+	// the file models are updated by the binary deltas, which are committed as they are
+	if filedelta.IsFile(meta.Alias) && filedelta.IsDelta(patch) {
+		return mm.updateFile(ctx, clientProposal, orderId, meta, orgModel.Content, contentPath, patch)
 	}
 
 	log.Debug("orgModel: ", string(orgModel.Content))
@@ -295,35 +605,127 @@ func (mm *ModelManager) Update(ctx context.Context, req *types.MetadataProposal,
 		return nil, types.Wrapf(types.ErrInvalidContent, "no content updated.")
 	}
 
-	if len(newContent) != int(clientProposal.Proposal.Size_) {
-		return nil, types.Wrapf(types.ErrInvalidContent, "given size(%d) doesn't match target content size(%d)", int(clientProposal.Proposal.Size_), len(newContent))
+	err = checkProposalContent(clientProposal.Proposal, newContent)
+	if err != nil {
+		return nil, err
 	}
 
-	newContentCid, err := utils.CalculateCid(newContent)
+	err = mm.validateModel(ctx, clientProposal.Proposal.Owner, clientProposal.Proposal.Alias, newContent, clientProposal.Proposal.Rule)
 	if err != nil {
 		return nil, err
 	}
-	if newContentCid.String() != clientProposal.Proposal.Cid {
-		return nil, types.Wrapf(types.ErrInvalidCid, "cid mismatch, expected %s, but got %s", clientProposal.Proposal.Cid, newContentCid)
+	// the rule of the previous version binds the update as well, so a writer can't drop the rule to break it
+	err = mm.checkCelRules(meta.DataId, orgModel.Content, newContent, meta.Rule, clientProposal.Proposal.Rule)
+	if err != nil {
+		return nil, err
+	}
+	// the entries committed to a log model are never changed
+	if eventlog.IsLog(meta.Alias) {
+		err = eventlog.Check(orgModel.Content, newContent, len(meta.Commits))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = mm.validateModel(ctx, clientProposal.Proposal.Owner, clientProposal.Proposal.Alias, newContent, clientProposal.Proposal.Rule)
+	// Commit
+	model, err := mm.commitUpdate(ctx, clientProposal, orderId, meta, newContent)
 	if err != nil {
 		return nil, err
 	}
+	model.Content = newContent
 
-	// Commit
-	result, err := mm.GatewaySvc.CommitModel(ctx, clientProposal, orderId, newContent)
+	mm.cacheModel(clientProposal.Proposal.Owner, model)
+	mm.coherence.Publish(ctx, mm.platforms.CacheName(model.GroupId, clientProposal.Proposal.Owner), model.DataId, model.Alias)
+
+	return model, nil
+}
+
+// updateFile commits the delta made on the latest commit of the file model, see filedelta. The file of the latest
+// commit is content, or the file at path if it is too large to be returned. The file the delta resolves to is
+// checked before the delta is committed.
+func (mm *ModelManager) updateFile(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, meta *types.Model, content []byte, path string, delta []byte) (*types.Model, error) {
+	header, _, err := filedelta.Parse(delta)
+	if err != nil {
+		return nil, err
+	}
+	version := len(meta.Commits)
+	var base io.ReaderAt = bytes.NewReader(content)
+	if header.Base != "" {
+		if header.Base != meta.CommitId {
+			return nil, types.Wrapf(types.ErrInvalidCommitInfo, "the delta is made on %s, not the latest commit %s", header.Base, meta.CommitId)
+		}
+		if filedelta.FullDue(version) {
+			return nil, types.Wrapf(types.ErrInvalidContent, "the commit of version %d must hold the whole file", version)
+		}
+		if len(content) == 0 {
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, types.Wrap(types.ErrReadFileFailed, err)
+			}
+			defer file.Close()
+			base = file
+		}
+	}
+
+	err = checkProposalContent(clientProposal.Proposal, delta)
+	if err != nil {
+		return nil, err
+	}
+	// the file is cached if it is small enough
+	var file bytes.Buffer
+	var w io.Writer = io.Discard
+	if header.Size <= uint64(mm.CacheConfig().ContentLimit) {
+		w = &file
+	}
+	_, err = filedelta.Resolve(base, delta, w)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := mm.commitUpdate(ctx, clientProposal, orderId, meta, delta)
+	if err != nil {
+		return nil, err
+	}
+	model.Cid = header.Cid
+	model.Content = file.Bytes()
+
+	mm.cacheModel(clientProposal.Proposal.Owner, model)
+	mm.coherence.Publish(ctx, mm.platforms.CacheName(model.GroupId, clientProposal.Proposal.Owner), model.DataId, model.Alias)
+
+	return model, nil
+}
+
+// checkProposalContent checks the content committed against the size and the cid of the order proposal.
+func checkProposalContent(proposal saotypes.Proposal, content []byte) error {
+	if len(content) != int(proposal.Size_) {
+		return types.Wrapf(types.ErrInvalidContent, "given size(%d) doesn't match target content size(%d)", int(proposal.Size_), len(content))
+	}
+
+	contentCid, err := utils.CalculateCid(content)
+	if err != nil {
+		return err
+	}
+	if contentCid.String() != proposal.Cid {
+		return types.Wrapf(types.ErrInvalidCid, "cid mismatch, expected %s, but got %s", proposal.Cid, contentCid)
+	}
+	return nil
+}
+
+// commitUpdate commits the content as the commit following the latest commit of meta, and returns the model of the
+// commit without its content.
+func (mm *ModelManager) commitUpdate(ctx context.Context, clientProposal *types.OrderStoreProposal, orderId uint64, meta *types.Model, content []byte) (*types.Model, error) {
+	result, err := mm.GatewaySvc.CommitModel(ctx, clientProposal, orderId, content)
 	if err != nil {
 		return nil, err
 	}
 	log.Debug("CommitedModel!!!")
 
-	commit := bytes.NewBufferString(commitIds[1])
+	commitId := strings.Split(clientProposal.Proposal.CommitId, "|")[1]
+	commit := bytes.NewBufferString(commitId)
 	commit.WriteByte(26)
 	commit.WriteString(fmt.Sprintf("%d", result.Height))
 
-	model := &types.Model{
+	return &types.Model{
 		DataId:     meta.DataId,
 		Alias:      meta.Alias,
 		GroupId:    clientProposal.Proposal.GroupId,
@@ -332,16 +734,93 @@ func (mm *ModelManager) Update(ctx context.Context, req *types.MetadataProposal,
 		Tags:       clientProposal.Proposal.Tags,
 		Cid:        result.Cid,
 		Shards:     result.Shards,
-		CommitId:   commitIds[1],
+		CommitId:   commitId,
 		Commits:    append(meta.Commits, commit.String()),
 		Version:    fmt.Sprintf("v%d", len(meta.Commits)),
-		Content:    newContent,
 		ExtendInfo: clientProposal.Proposal.ExtendInfo,
+		Rule:       clientProposal.Proposal.Rule,
+	}, nil
+}
+
+// Merge three-way merges the json patch made on the commit baseCommitId of the model with the commits made since,
+// the base commit being their common ancestor. It returns the latest commit of the model and the merged content,
+// which the client commits on top of the latest commit in turn. Conflicts are the json pointers of the values
+// changed on both sides, the values of the patch are kept for them.
+func (mm *ModelManager) Merge(ctx context.Context, req *types.MetadataProposal, baseCommitId string, patch []byte) (*types.Model, []byte, []string, error) {
+	meta, err := mm.GatewaySvc.QueryMeta(ctx, req, 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	latest, err := mm.commitContent(ctx, req, meta, meta.CommitId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	model := &types.Model{
+		DataId:   meta.DataId,
+		Alias:    meta.Alias,
+		GroupId:  meta.GroupId,
+		Owner:    meta.Owner,
+		CommitId: meta.CommitId,
+		Commits:  meta.Commits,
+		Version:  fmt.Sprintf("v%d", len(meta.Commits)-1),
+		Content:  latest,
+	}
+	if baseCommitId == meta.CommitId {
+		content, err := utils.ApplyPatch(latest, patch)
+		return model, content, nil, err
 	}
 
-	mm.cacheModel(clientProposal.Proposal.Owner, model)
+	base, err := mm.commitContent(ctx, req, meta, baseCommitId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ours, err := utils.ApplyPatch(base, patch)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	merged, conflicts, err := utils.MergeJSON(base, ours, latest)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(conflicts) > 0 {
+		log.Infof("merged the patch of model %s on commit %s with conflicts at %v", meta.DataId, baseCommitId, conflicts)
+	}
+	return model, merged, conflicts, nil
+}
 
-	return model, nil
+// commitContent returns the content of the commit of the model, from the cache if it is the cached commit.
+func (mm *ModelManager) commitContent(ctx context.Context, req *types.MetadataProposal, meta *types.Model, commitId string) ([]byte, error) {
+	if cached := mm.loadModel(meta.GroupId, req.Proposal.Owner, meta.DataId); cached != nil && cached.CommitId == commitId && len(cached.Content) > 0 {
+		return cached.Content, nil
+	}
+
+	commitMeta := meta
+	if commitId != meta.CommitId {
+		height := int64(-1)
+		for _, commit := range meta.Commits {
+			commitInfo, err := types.ParseMetaCommit(commit)
+			if err != nil {
+				return nil, types.Wrapf(types.ErrInvalidCommitInfo, "invalid commit information: %s", commit)
+			}
+			if commitInfo.CommitId == commitId {
+				height = int64(commitInfo.Height)
+				break
+			}
+		}
+		if height < 0 {
+			return nil, types.Wrapf(types.ErrInvalidCommitInfo, "invalid CommitId: %s", commitId)
+		}
+		var err error
+		commitMeta, err = mm.GatewaySvc.QueryMeta(ctx, req, height)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result, err := mm.GatewaySvc.FetchContent(ctx, req, commitMeta)
+	if err != nil {
+		return nil, err
+	}
+	return result.Content, nil
 }
 
 func (mm *ModelManager) Delete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (*types.Model, error) {
@@ -352,21 +831,27 @@ func (mm *ModelManager) Delete(ctx context.Context, req *types.OrderTerminatePro
 		}
 	}
 
-	model, _ := mm.CacheSvc.Get(req.Proposal.Owner, req.Proposal.DataId)
-	if model != nil {
+	// the platform of the model isn't known, it is evicted from the caches of all platforms
+	var deleted *types.Model
+	for _, name := range mm.platforms.CacheNames(req.Proposal.Owner) {
+		model, _ := mm.CacheSvc.Get(name, req.Proposal.DataId)
+		if model == nil {
+			continue
+		}
 		m, ok := model.(*types.Model)
 		if ok {
-			mm.CacheSvc.Evict(req.Proposal.Owner, m.DataId)
-			mm.CacheSvc.Evict(req.Proposal.Owner, m.Alias+m.GroupId)
+			mm.CacheSvc.Evict(name, m.DataId)
+			mm.CacheSvc.Evict(name, m.Alias+m.GroupId)
+			mm.coherence.Publish(ctx, name, m.DataId, m.Alias+m.GroupId)
 
-			return &types.Model{
+			deleted = &types.Model{
 				DataId: m.DataId,
 				Alias:  m.Alias,
-			}, nil
+			}
 		}
 	}
 
-	return nil, nil
+	return deleted, nil
 }
 
 func (mm *ModelManager) ShowCommits(ctx context.Context, req *types.MetadataProposal) (*types.Model, error) {
@@ -402,12 +887,28 @@ func (mm *ModelManager) UpdatePermission(ctx context.Context, req *types.Permiss
 		}
 	}
 
+	// the cached model is served without querying the chain, the revoked readers could load it otherwise
+	for _, name := range mm.platforms.CacheNames(req.Proposal.Owner) {
+		mm.CacheSvc.Evict(name, req.Proposal.DataId)
+		mm.coherence.Publish(ctx, name, req.Proposal.DataId)
+	}
+
 	return &types.Model{
 		DataId: req.Proposal.DataId,
 	}, nil
 }
 
 func (mm *ModelManager) validateModel(ctx context.Context, account string, alias string, contentBytes []byte, rule string) error {
+	if rule_engine.IsCelRule(rule) {
+		// checked against the previous versions by Update, the rule must compile for the new models
+		if mm.celRules != nil {
+			if _, err := mm.celRules.Compile(rule); err != nil {
+				return err
+			}
+		}
+		rule = ""
+	}
+
 	schemaStr := jsoniter.Get(contentBytes, PROPERTY_CONTEXT).ToString()
 	if schemaStr == "" {
 		return nil
@@ -431,32 +932,12 @@ func (mm *ModelManager) validateModel(ctx context.Context, account string, alias
 		for _, schema := range schemas {
 			sch, ok := schema.(string)
 			if ok && sch != "" {
-				if utils.IsDataId(sch) {
-					model, err := mm.CacheSvc.Get(account, sch)
-					if err != nil {
-						return err
-					}
-
-					if model == nil {
-						req := &types.MetadataProposal{
-							Proposal: saotypes.QueryProposal{
-								Owner:       "all",
-								Keyword:     sch,
-								KeywordType: 0,
-							},
-						}
-
-						model, err = mm.Load(ctx, req)
-						if err != nil {
-							return err
-						}
-					}
-					m, ok := model.(*types.Model)
-					if ok {
-						sch = string(m.Content)
-					} else {
-						return types.Wrapf(types.ErrInvalidSchema, "invalid schema: %v", m)
-					}
+				content, found, err := mm.loadSchema(ctx, account, sch)
+				if err != nil {
+					return err
+				}
+				if found {
+					sch = content
 				}
 
 				validator, err := validator.NewDataModelValidator(alias, sch, rule)
@@ -473,36 +954,12 @@ func (mm *ModelManager) validateModel(ctx context.Context, account string, alias
 		}
 	} else {
 		iter := jsoniter.ParseString(jsoniter.ConfigDefault, schemaStr)
-		dataId := iter.ReadString()
-		var schema string
-		if utils.IsDataId(dataId) {
-			model, err := mm.CacheSvc.Get(account, dataId)
-			if err != nil {
-				return err
-			}
-
-			if model == nil {
-				req := &types.MetadataProposal{
-					Proposal: saotypes.QueryProposal{
-						Owner:       "all",
-						Keyword:     dataId,
-						KeywordType: 0,
-					},
-				}
-
-				model, err = mm.Load(ctx, req)
-				if err != nil {
-					return err
-				}
-			}
-
-			m, ok := model.(*types.Model)
-			if ok {
-				schema = string(m.Content)
-			} else {
-				return types.Wrapf(types.ErrInvalidSchema, "invalid schema: %v", m)
-			}
-		} else {
+		keyword := iter.ReadString()
+		schema, found, err := mm.loadSchema(ctx, account, keyword)
+		if err != nil {
+			return err
+		}
+		if !found {
 			schema = iter.ReadObject()
 		}
 
@@ -519,15 +976,92 @@ func (mm *ModelManager) validateModel(ctx context.Context, account string, alias
 	return nil
 }
 
-func (mm *ModelManager) loadModel(account string, key string) *types.Model {
-	if !mm.CacheCfg.EnableCache {
+// checkCelRules checks the update of the model from oldContent to newContent against the CEL rules among rules.
+func (mm *ModelManager) checkCelRules(dataId string, oldContent []byte, newContent []byte, rules ...string) error {
+	for i, rule := range rules {
+		if !rule_engine.IsCelRule(rule) || (i > 0 && rule == rules[i-1]) {
+			continue
+		}
+		if mm.celRules == nil {
+			return types.Wrapf(types.ErrInvalidRule, "CEL rules are not supported by the gateway")
+		}
+		if err := mm.celRules.Check(dataId, rule, newContent, oldContent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSchema loads the content of the schema model referred to by keyword in @context, which is either the data id
+// of the model or the name@version of a schema published to the registry. found is false if keyword is neither,
+// such as an inline schema.
+func (mm *ModelManager) loadSchema(ctx context.Context, account string, keyword string) (string, bool, error) {
+	if name, query, ok := registry.ParseRef(keyword); ok {
+		indexContent, err := mm.loadSchemaModel(ctx, account, registry.IndexDataId(name))
+		if err != nil {
+			return "", false, types.Wrapf(types.ErrInvalidSchema, "schema %s is not published: %v", name, err)
+		}
+		var index registry.Index
+		if err := json.Unmarshal(indexContent, &index); err != nil || index.Name != name {
+			return "", false, types.Wrapf(types.ErrInvalidSchema, "invalid index of schema %s", name)
+		}
+		_, dataId, err := index.Resolve(query)
+		if err != nil {
+			return "", false, types.Wrap(types.ErrInvalidSchema, err)
+		}
+		keyword = dataId
+	} else if !utils.IsDataId(keyword) {
+		return "", false, nil
+	}
+
+	content, err := mm.loadSchemaModel(ctx, account, keyword)
+	if err != nil {
+		return "", false, err
+	}
+	return string(content), true, nil
+}
+
+func (mm *ModelManager) loadSchemaModel(ctx context.Context, account string, dataId string) ([]byte, error) {
+	model, err := mm.CacheSvc.Get(account, dataId)
+	if err != nil {
+		return nil, err
+	}
+
+	if model == nil {
+		req := &types.MetadataProposal{
+			Proposal: saotypes.QueryProposal{
+				Owner:       "all",
+				Keyword:     dataId,
+				KeywordType: 0,
+			},
+		}
+
+		model, err = mm.Load(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, ok := model.(*types.Model)
+	if !ok {
+		return nil, types.Wrapf(types.ErrInvalidSchema, "invalid schema: %v", m)
+	}
+	return m.Content, nil
+}
+
+// loadModel returns the model cached by the key for the account, in the cache of the platform groupId if the
+// platform isolates its caches.
+func (mm *ModelManager) loadModel(groupId string, account string, key string) *types.Model {
+	cacheCfg := mm.CacheConfig()
+	if !cacheCfg.EnableCache {
 		return nil
 	}
 
-	value, err := mm.CacheSvc.Get(account, key)
+	name := mm.platforms.CacheName(groupId, account)
+	value, err := mm.CacheSvc.Get(name, key)
 	if err != nil {
-		if strings.Contains(err.Error(), fmt.Sprintf("the cache [%s] not found", account)) {
-			err = mm.CacheSvc.CreateCache(account, mm.CacheCfg.CacheCapacity)
+		if strings.Contains(err.Error(), fmt.Sprintf("the cache [%s] not found", name)) {
+			err = mm.CacheSvc.CreateCache(name, mm.platforms.CacheCapacity(groupId, cacheCfg.CacheCapacity))
 			if err != nil {
 				log.Error(err.Error())
 				return nil
@@ -541,7 +1075,7 @@ func (mm *ModelManager) loadModel(account string, key string) *types.Model {
 	if value != nil {
 		dataId, ok := value.(string)
 		if ok {
-			value, err = mm.CacheSvc.Get(account, dataId)
+			value, err = mm.CacheSvc.Get(name, dataId)
 			if err != nil {
 				log.Warn(err.Error())
 			}
@@ -567,16 +1101,18 @@ func (mm *ModelManager) loadModel(account string, key string) *types.Model {
 }
 
 func (mm *ModelManager) cacheModel(account string, model *types.Model) {
-	if !mm.CacheCfg.EnableCache {
+	cacheCfg := mm.CacheConfig()
+	if !cacheCfg.EnableCache {
 		return
 	}
+	name := mm.platforms.CacheName(model.GroupId, account)
 
-	if len(model.Content) > mm.CacheCfg.ContentLimit {
+	if len(model.Content) > cacheCfg.ContentLimit {
 		// large size content should go through P2P channel
 		model.Content = make([]byte, 0)
 	}
-	mm.CacheSvc.Put(account, model.DataId, model)
-	mm.CacheSvc.Put(account, model.Alias, model)
+	mm.CacheSvc.Put(name, model.DataId, model)
+	mm.CacheSvc.Put(name, model.Alias, model)
 
 	buf, _ := json.Marshal(model)
 	log.Debug("model: ", string(buf), " CACHED!!!")
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"sao-node/node/cache"
+	"sao-node/node/config"
+	"sao-node/types"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadCacheConfig(t *testing.T) {
+	mm := &ModelManager{
+		cacheCfg: config.Cache{EnableCache: true, CacheCapacity: 10, ContentLimit: 4},
+		CacheSvc: cache.NewLruCacheSvc(),
+	}
+
+	// the requests read the config while it is reloaded, go test -race tells the unguarded reads
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			mm.cacheModel("account1", &types.Model{DataId: "data1", Alias: "alias1", Content: []byte("{}")})
+			mm.loadModel("", "account1", "data1")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, mm.ReloadCacheConfig(config.Cache{EnableCache: true, CacheCapacity: 10 + i, ContentLimit: 4}))
+	}
+	wg.Wait()
+
+	require.NoError(t, mm.ReloadCacheConfig(config.Cache{EnableCache: false, CacheCapacity: 20, ContentLimit: 8}))
+	cacheCfg := mm.CacheConfig()
+	require.False(t, cacheCfg.EnableCache)
+	require.Equal(t, 20, cacheCfg.CacheCapacity)
+	require.Equal(t, 8, cacheCfg.ContentLimit)
+	require.Nil(t, mm.loadModel("", "account1", "data1"))
+}
@@ -0,0 +1,150 @@
+package provenance
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"sao-node/chain"
+	"sao-node/types"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	"github.com/SaoNetwork/sao-did/parser"
+	"github.com/SaoNetwork/sao-did/sid"
+	saodidtypes "github.com/SaoNetwork/sao-did/types"
+	"github.com/SaoNetwork/sao-did/util"
+	"github.com/dvsekhvalnov/jose2go/base64url"
+)
+
+// Chain is what the commits are verified against.
+type Chain interface {
+	GetStoreTx(ctx context.Context, orderId uint64) (*chain.StoreTx, error)
+	GetSidDocument(ctx context.Context, versionId string) (*sid.SidDocument, error)
+	GetSidDocumentVersions(ctx context.Context, docId string, height int64) ([]string, error)
+	GetBlockTime(ctx context.Context, height int64) (time.Time, error)
+}
+
+// Commit is a commit of a model as recorded in the metadata of the model at the commit height.
+type Commit struct {
+	CommitId string
+	Version  string
+	Height   uint64
+	OrderId  uint64
+	Cid      string
+}
+
+// Verify returns the provenance of the commit of the model. The proposal of the MsgStore tx ordering the commit
+// must be of the model, the commit and the content, and its JWS must be made by a key of the did of its owner. For
+// a sid, the key is looked up in the version of the sid document the JWS refers to, which must be the latest
+// version at the height of the tx, so the keys removed from the document before can't be used. The commit is
+// returned unverified with the error if any check fails.
+func Verify(ctx context.Context, c Chain, dataId string, commit Commit) types.CommitProvenance {
+	p := types.CommitProvenance{
+		CommitId: commit.CommitId,
+		Version:  commit.Version,
+		Cid:      commit.Cid,
+		OrderId:  commit.OrderId,
+		Height:   commit.Height,
+	}
+	if t, err := c.GetBlockTime(ctx, int64(commit.Height)); err == nil {
+		p.Time = t
+	}
+
+	if err := verify(ctx, c, dataId, commit, &p); err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	p.Verified = true
+	return p
+}
+
+func verify(ctx context.Context, c Chain, dataId string, commit Commit, p *types.CommitProvenance) error {
+	storeTx, err := c.GetStoreTx(ctx, commit.OrderId)
+	if err != nil {
+		return err
+	}
+	p.StoreTxHash = storeTx.Hash
+	p.StoreHeight = storeTx.Height
+
+	proposal := storeTx.Msg.Proposal
+	commitIds := strings.Split(proposal.CommitId, "|")
+	switch {
+	case proposal.DataId != dataId:
+		return types.Wrapf(types.ErrUnverifiedCommit, "order %d is of model %s", commit.OrderId, proposal.DataId)
+	case commitIds[len(commitIds)-1] != commit.CommitId:
+		return types.Wrapf(types.ErrUnverifiedCommit, "order %d is of commit %s", commit.OrderId, proposal.CommitId)
+	case proposal.Cid != commit.Cid:
+		return types.Wrapf(types.ErrUnverifiedCommit, "order %d is of content %s, the commit is %s", commit.OrderId, proposal.Cid, commit.Cid)
+	}
+
+	signature := saodidtypes.JwsSignature(storeTx.Msg.JwsSignature)
+	p.Kid, err = signature.GetKid()
+	if err != nil {
+		return types.Wrap(types.ErrInvalidSignature, err)
+	}
+	p.Signer, err = util.KidToDid(p.Kid)
+	if err != nil {
+		return types.Wrap(types.ErrInvalidDid, err)
+	}
+	if p.Signer != proposal.Owner {
+		return types.Wrapf(types.ErrInvalidSignature, "signed by %s, the owner is %s", p.Signer, proposal.Owner)
+	}
+
+	p.SidVersion, err = checkSidVersion(ctx, c, p.Kid, storeTx.Height)
+	if err != nil {
+		return err
+	}
+
+	didManager, err := saodid.NewDidManagerWithDid(proposal.Owner, func(versionId string) (*sid.SidDocument, error) {
+		return c.GetSidDocument(ctx, versionId)
+	})
+	if err != nil {
+		return types.Wrap(types.ErrInvalidDid, err)
+	}
+	proposalBytes, err := proposal.Marshal()
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	_, err = didManager.VerifyJWS(saodidtypes.GeneralJWS{
+		Payload:    base64url.Encode(proposalBytes),
+		Signatures: []saodidtypes.JwsSignature{signature},
+	})
+	if err != nil {
+		return types.Wrap(types.ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// checkSidVersion returns the sid document version of the key id, which must be the latest version at the height.
+// The key ids of the other dids have no version.
+func checkSidVersion(ctx context.Context, c Chain, kid string, height int64) (string, error) {
+	did, err := parser.Parse(kid)
+	if err != nil {
+		return "", types.Wrap(types.ErrInvalidDid, err)
+	}
+	if did.Method != sid.SidMethod {
+		return "", nil
+	}
+
+	query, err := url.ParseQuery(did.Query)
+	if err != nil {
+		return "", types.Wrap(types.ErrInvalidDid, err)
+	}
+	version := query.Get("versionId")
+	if version == "" {
+		version = query.Get("version-id")
+	}
+	if version == "" {
+		return "", types.Wrapf(types.ErrInvalidDid, "no sid document version in %s", kid)
+	}
+
+	versions, err := c.GetSidDocumentVersions(ctx, did.ID, height)
+	if err != nil {
+		return version, err
+	}
+	if len(versions) == 0 || versions[len(versions)-1] != version {
+		return version, types.Wrapf(types.ErrUnverifiedCommit, "signed by sid document version %s, which is not the valid version at height %d", version, height)
+	}
+	return version, nil
+}
@@ -0,0 +1,110 @@
+package provenance
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"sao-node/chain"
+	"sao-node/client"
+	"sao-node/types"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saokey "github.com/SaoNetwork/sao-did/key"
+	"github.com/SaoNetwork/sao-did/sid"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/stretchr/testify/require"
+)
+
+type provenanceChain struct {
+	stores   map[uint64]*chain.StoreTx
+	versions []string
+}
+
+func (c provenanceChain) GetStoreTx(_ context.Context, orderId uint64) (*chain.StoreTx, error) {
+	storeTx, ok := c.stores[orderId]
+	if !ok {
+		return nil, types.Wrapf(types.ErrTxQueryFailed, "no MsgStore tx of order %d", orderId)
+	}
+	return storeTx, nil
+}
+
+func (provenanceChain) GetSidDocument(context.Context, string) (*sid.SidDocument, error) {
+	return nil, nil
+}
+
+func (c provenanceChain) GetSidDocumentVersions(context.Context, string, int64) ([]string, error) {
+	return c.versions, nil
+}
+
+func (provenanceChain) GetBlockTime(_ context.Context, height int64) (time.Time, error) {
+	return time.Unix(height, 0), nil
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	const dataId = "6f1e9f3e-0a6b-11ee-be56-0242ac120002"
+
+	secret, err := hex.DecodeString("a3709843cbd4e72d7215512e28385123b44eab5e27f36001d74ee1cff671502d")
+	require.NoError(t, err)
+	provider, err := saokey.NewSecp256k1Provider(secret)
+	require.NoError(t, err)
+	didManager := saodid.NewDidManager(provider, saokey.NewKeyResolver())
+	_, err = didManager.Authenticate([]string{}, "")
+	require.NoError(t, err)
+
+	order, err := client.BuildOrderProposal(&didManager, saotypes.Proposal{
+		Owner:    didManager.Id,
+		DataId:   dataId,
+		CommitId: "c0|c1",
+		Cid:      "bafkreieerchgnsjxcmelllftgqgrm7ftusfkbdylhmhx6kjgnfqm2hdvce",
+	})
+	require.NoError(t, err)
+	forged := *order
+	forged.Proposal.Cid = "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+	c := provenanceChain{stores: map[uint64]*chain.StoreTx{
+		1: {Msg: saotypes.MsgStore{Proposal: order.Proposal, JwsSignature: order.JwsSignature}, Hash: "A1", Height: 9},
+		2: {Msg: saotypes.MsgStore{Proposal: forged.Proposal, JwsSignature: forged.JwsSignature}, Hash: "A2", Height: 19},
+	}}
+
+	p := Verify(ctx, c, dataId, Commit{CommitId: "c1", Version: "v1", Height: 10, OrderId: 1, Cid: order.Proposal.Cid})
+	require.True(t, p.Verified, p.Error)
+	require.Equal(t, didManager.Id, p.Signer)
+	require.Equal(t, "A1", p.StoreTxHash)
+	require.Equal(t, int64(9), p.StoreHeight)
+	require.Equal(t, time.Unix(10, 0), p.Time)
+	require.Empty(t, p.SidVersion)
+
+	// the order is of another commit
+	p = Verify(ctx, c, dataId, Commit{CommitId: "c2", Height: 20, OrderId: 1, Cid: order.Proposal.Cid})
+	require.False(t, p.Verified)
+	require.Contains(t, p.Error, "c0|c1")
+
+	// the proposal doesn't match its signature
+	p = Verify(ctx, c, dataId, Commit{CommitId: "c1", Height: 20, OrderId: 2, Cid: forged.Proposal.Cid})
+	require.False(t, p.Verified)
+	require.Equal(t, didManager.Id, p.Signer)
+	require.NotEmpty(t, p.Error)
+
+	p = Verify(ctx, c, dataId, Commit{CommitId: "c1", Height: 20, OrderId: 3})
+	require.False(t, p.Verified)
+	require.NotEmpty(t, p.Error)
+}
+
+func TestCheckSidVersion(t *testing.T) {
+	ctx := context.Background()
+	kid := "did:sid:8a6b2c?versionId=v2#signing"
+
+	version, err := checkSidVersion(ctx, provenanceChain{versions: []string{"v1", "v2"}}, kid, 10)
+	require.NoError(t, err)
+	require.Equal(t, "v2", version)
+
+	// the document is updated to v3 before the height
+	version, err = checkSidVersion(ctx, provenanceChain{versions: []string{"v1", "v2", "v3"}}, kid, 10)
+	require.True(t, types.ErrUnverifiedCommit.Is(err))
+	require.Equal(t, "v2", version)
+
+	_, err = checkSidVersion(ctx, provenanceChain{versions: []string{"v1"}}, "did:sid:8a6b2c#signing", 10)
+	require.True(t, types.ErrInvalidDid.Is(err))
+}
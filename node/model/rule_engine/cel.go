@@ -0,0 +1,102 @@
+package rule_engine
+
+import (
+	"encoding/json"
+	"strings"
+
+	"sao-node/types"
+
+	"github.com/google/cel-go/cel"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// the prefix of the model rules in CEL, the other rules are grule rules in json
+const CelRulePrefix = "cel:"
+
+// IsCelRule returns whether the rule of a model is a CEL expression.
+func IsCelRule(rule string) bool {
+	return strings.HasPrefix(rule, CelRulePrefix)
+}
+
+type celProgram struct {
+	rule string
+	prg  cel.Program
+}
+
+// CelRules checks the updates of the models against their CEL rules. The rule is a boolean expression of new, the
+// content of the update, and old, the content of the previous version, such as "new.balance >= old.balance". The
+// programs compiled are kept by the data ids of the models, a program is compiled again once its rule changes.
+type CelRules struct {
+	env      *cel.Env
+	programs *lru.Cache
+}
+
+func NewCelRules(size int) (*CelRules, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("new", cel.DynType),
+		cel.Variable("old", cel.DynType),
+		// the json numbers are doubles, comparable with the int literals of the rules
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCompileFaild, err)
+	}
+	programs, err := lru.New(size)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	return &CelRules{env: env, programs: programs}, nil
+}
+
+// Compile compiles the CEL rule, the error is ErrInvalidRule if the rule isn't a boolean expression.
+func (r *CelRules) Compile(rule string) (cel.Program, error) {
+	ast, issues := r.env.Compile(strings.TrimPrefix(rule, CelRulePrefix))
+	if issues != nil && issues.Err() != nil {
+		return nil, types.Wrapf(types.ErrInvalidRule, "%v", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, types.Wrapf(types.ErrInvalidRule, "the rule is %v, bool expected", ast.OutputType())
+	}
+	prg, err := r.env.Program(ast)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidRule, "%v", err)
+	}
+	return prg, nil
+}
+
+// Check evaluates the rule of the model against the update from oldContent to newContent, the error is
+// ErrRuleCheckFaild if the rule is not true for it.
+func (r *CelRules) Check(dataId string, rule string, newContent []byte, oldContent []byte) error {
+	prg, err := r.program(dataId, rule)
+	if err != nil {
+		return err
+	}
+
+	var newValue, oldValue interface{}
+	if err := json.Unmarshal(newContent, &newValue); err != nil {
+		return types.Wrapf(types.ErrRuleCheckFaild, "the content is not json: %v", err)
+	}
+	if err := json.Unmarshal(oldContent, &oldValue); err != nil {
+		return types.Wrapf(types.ErrRuleCheckFaild, "the previous content is not json: %v", err)
+	}
+	out, _, err := prg.Eval(map[string]interface{}{"new": newValue, "old": oldValue})
+	if err != nil {
+		return types.Wrapf(types.ErrRuleCheckFaild, "%s: %v", rule, err)
+	}
+	if passed, ok := out.Value().(bool); !ok || !passed {
+		return types.Wrapf(types.ErrRuleCheckFaild, "%s is %v", rule, out)
+	}
+	return nil
+}
+
+func (r *CelRules) program(dataId string, rule string) (cel.Program, error) {
+	if cached, ok := r.programs.Get(dataId); ok && cached.(celProgram).rule == rule {
+		return cached.(celProgram).prg, nil
+	}
+	prg, err := r.Compile(rule)
+	if err != nil {
+		return nil, err
+	}
+	r.programs.Add(dataId, celProgram{rule: rule, prg: prg})
+	return prg, nil
+}
@@ -0,0 +1,40 @@
+package rule_engine
+
+import (
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCelRules(t *testing.T) {
+	const dataId = "6f1e9f3e-0a6b-11ee-be56-0242ac120002"
+	rules, err := NewCelRules(16)
+	require.NoError(t, err)
+
+	require.True(t, IsCelRule("cel:new.balance >= old.balance"))
+	require.False(t, IsCelRule(`{"rules": []}`))
+
+	balance := "cel:new.balance >= old.balance"
+	require.NoError(t, rules.Check(dataId, balance, []byte(`{"balance": 12.5}`), []byte(`{"balance": 10}`)))
+	err = rules.Check(dataId, balance, []byte(`{"balance": 9}`), []byte(`{"balance": 10}`))
+	require.True(t, types.ErrRuleCheckFaild.Is(err))
+	// a field missing fails the rule
+	err = rules.Check(dataId, balance, []byte(`{}`), []byte(`{"balance": 10}`))
+	require.True(t, types.ErrRuleCheckFaild.Is(err))
+
+	// the json numbers compare with int literals
+	require.NoError(t, rules.Check(dataId, "cel:new.count < 3 && size(new.items) == new.count", []byte(`{"count": 2, "items": ["a", "b"]}`), []byte(`{}`)))
+
+	// the program is compiled again for the rule changed
+	err = rules.Check(dataId, "cel:new.balance == old.balance", []byte(`{"balance": 11}`), []byte(`{"balance": 10}`))
+	require.True(t, types.ErrRuleCheckFaild.Is(err))
+
+	_, err = rules.Compile("cel:new.balance >=")
+	require.True(t, types.ErrInvalidRule.Is(err))
+	_, err = rules.Compile(`cel:"balance"`)
+	require.True(t, types.ErrInvalidRule.Is(err))
+	err = rules.Check(dataId, "cel:new.balance +", []byte(`{}`), []byte(`{}`))
+	require.True(t, types.ErrInvalidRule.Is(err))
+}
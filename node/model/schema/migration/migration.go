@@ -0,0 +1,208 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sao-node/types"
+	"sao-node/utils"
+)
+
+const propertyContext = "@context"
+
+// Transform upgrades the content of a model to the new version of its schema.
+type Transform func(ctx context.Context, content []byte) ([]byte, error)
+
+var placeholder = regexp.MustCompile(`\{\{\s*(/[^}]*|)\s*\}\}`)
+
+// PatchTemplate returns the transform applying the json patch template to the models. The placeholders
+// {{<json pointer>}} in the string values of the template are replaced by the values at the pointers in the model,
+// a string of a single placeholder by the value itself and the others by the values formatted in the string. The
+// operations whose placeholders refer to the fields a model doesn't have are skipped for the model.
+func PatchTemplate(template []byte) (Transform, error) {
+	var ops []map[string]interface{}
+	if err := json.Unmarshal(template, &ops); err != nil {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid json patch template: %v", err)
+	}
+
+	return func(_ context.Context, content []byte) ([]byte, error) {
+		var doc interface{}
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+
+		patch := make([]interface{}, 0, len(ops))
+		for _, op := range ops {
+			expanded, ok := expand(op, doc)
+			if ok {
+				patch = append(patch, expanded)
+			}
+		}
+		patchBytes, err := json.Marshal(patch)
+		if err != nil {
+			return nil, types.Wrap(types.ErrMarshalFailed, err)
+		}
+		upgraded, err := utils.ApplyPatch(content, patchBytes)
+		if err != nil {
+			return nil, types.Wrap(types.ErrTransformFailed, err)
+		}
+		return upgraded, nil
+	}, nil
+}
+
+// Command returns the transform running the command for each model, the content of the model is written to its
+// stdin and the upgraded content is read from its stdout. A js function can be run by node or a wasm module by a
+// wasm runtime this way, such as "node upgrade.js" or "wasmtime upgrade.wasm".
+func Command(name string, args ...string) Transform {
+	return func(ctx context.Context, content []byte) ([]byte, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdin = bytes.NewReader(content)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, types.Wrapf(types.ErrTransformFailed, "%s: %v %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.Bytes(), nil
+	}
+}
+
+// References returns whether the @context of the model content has an entry matched by match.
+func References(content []byte, match func(ref string) bool) bool {
+	var doc map[string]interface{}
+	if json.Unmarshal(content, &doc) != nil {
+		return false
+	}
+	for _, ref := range contextRefs(doc[propertyContext]) {
+		if match(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade transforms the content of a model referring to the old schema in its @context, and replaces the entries
+// matched by match with to in the @context of the result, unless the transform changed them already.
+func Upgrade(ctx context.Context, content []byte, transform Transform, match func(ref string) bool, to string) ([]byte, error) {
+	upgraded, err := transform(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(upgraded, &doc); err != nil {
+		return nil, types.Wrapf(types.ErrTransformFailed, "the upgraded model is not a json object: %v", err)
+	}
+	switch c := doc[propertyContext].(type) {
+	case string:
+		if match(c) {
+			doc[propertyContext] = to
+		}
+	case []interface{}:
+		for i, entry := range c {
+			if ref, ok := entry.(string); ok && match(ref) {
+				c[i] = to
+			}
+		}
+	}
+	upgraded, err = json.Marshal(doc)
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return upgraded, nil
+}
+
+func contextRefs(context interface{}) []string {
+	switch c := context.(type) {
+	case string:
+		return []string{c}
+	case []interface{}:
+		var refs []string
+		for _, entry := range c {
+			if ref, ok := entry.(string); ok {
+				refs = append(refs, ref)
+			}
+		}
+		return refs
+	}
+	return nil
+}
+
+// expand replaces the placeholders in the values of the template operation, ok is false if a value refers to a
+// field the model doesn't have.
+func expand(value interface{}, doc interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if m := placeholder.FindStringSubmatch(v); m != nil && m[0] == v {
+			return lookup(doc, strings.TrimSpace(m[1]))
+		}
+		ok := true
+		expanded := placeholder.ReplaceAllStringFunc(v, func(s string) string {
+			found, exists := lookup(doc, strings.TrimSpace(placeholder.FindStringSubmatch(s)[1]))
+			if !exists {
+				ok = false
+				return ""
+			}
+			if str, isStr := found.(string); isStr {
+				return str
+			}
+			b, _ := json.Marshal(found)
+			return string(b)
+		})
+		return expanded, ok
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			e, ok := expand(elem, doc)
+			if !ok {
+				return nil, false
+			}
+			expanded[key] = e
+		}
+		return expanded, true
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for i, elem := range v {
+			e, ok := expand(elem, doc)
+			if !ok {
+				return nil, false
+			}
+			expanded[i] = e
+		}
+		return expanded, true
+	}
+	return value, true
+}
+
+// lookup returns the value at the json pointer in the document.
+func lookup(doc interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch c := current.(type) {
+		case map[string]interface{}:
+			next, ok := c[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			current = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
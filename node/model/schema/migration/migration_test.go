@@ -0,0 +1,61 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchTemplate(t *testing.T) {
+	ctx := context.Background()
+	transform, err := PatchTemplate([]byte(`[
+		{"op": "add", "path": "/fullName", "value": "{{/firstName}} {{ /lastName }}"},
+		{"op": "add", "path": "/contact", "value": {"email": "{{/email}}"}},
+		{"op": "add", "path": "/tags", "value": "{{/labels}}"},
+		{"op": "remove", "path": "/labels"},
+		{"op": "add", "path": "/version", "value": 2}
+	]`))
+	require.NoError(t, err)
+
+	upgraded, err := transform(ctx, []byte(`{"firstName": "Ada", "lastName": "Lovelace", "labels": ["a", "b"]}`))
+	require.NoError(t, err)
+	// the contact is skipped without an email
+	require.JSONEq(t, `{"firstName": "Ada", "lastName": "Lovelace", "fullName": "Ada Lovelace", "tags": ["a", "b"], "version": 2}`, string(upgraded))
+
+	_, err = PatchTemplate([]byte(`{"op": "add"}`))
+	require.True(t, types.ErrInvalidParameters.Is(err))
+}
+
+func TestUpgrade(t *testing.T) {
+	ctx := context.Background()
+	match := func(ref string) bool {
+		return ref == "person@1.0.0" || ref == "person@1"
+	}
+	content := []byte(`{"@context": ["person@1", "address@2.0.0"], "name": "Ada"}`)
+	require.True(t, References(content, match))
+	require.False(t, References([]byte(`{"@context": "address@2.0.0"}`), match))
+	require.False(t, References([]byte(`[1, 2]`), match))
+
+	transform, err := PatchTemplate([]byte(`[{"op": "move", "from": "/name", "path": "/fullName"}]`))
+	require.NoError(t, err)
+	upgraded, err := Upgrade(ctx, content, transform, match, "person@2.0.0")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"@context": ["person@2.0.0", "address@2.0.0"], "fullName": "Ada"}`, string(upgraded))
+
+	upgraded, err = Upgrade(ctx, []byte(`{"@context": "person@1.0.0"}`), transform, match, "person@2.0.0")
+	require.True(t, types.ErrTransformFailed.Is(err))
+	require.Nil(t, upgraded)
+}
+
+func TestCommand(t *testing.T) {
+	ctx := context.Background()
+	upgraded, err := Command("sed", "s/name/fullName/")(ctx, []byte(`{"name": "Ada"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"fullName": "Ada"}`, string(upgraded))
+
+	_, err = Command("false")(ctx, []byte(`{}`))
+	require.True(t, types.ErrTransformFailed.Is(err))
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"sao-node/types"
+
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/mod/semver"
+)
+
+// The schemas are published to the registry as models, each version of a schema is a model of its own and the
+// index of a schema is a model mapping its versions to their data ids. The data id of the index is derived from the
+// name of the schema, so the first did publishing a name owns it and is the only one able to publish its versions.
+const (
+	// prefix of the aliases of the registry models
+	AliasPrefix = "schema:"
+	// the version resolved to the latest release of a schema
+	Latest = "latest"
+
+	indexNamespace = "sao://schemas/"
+	maxNameLength  = 128
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Index is the content of the index model of a schema.
+type Index struct {
+	Name string `json:"name"`
+	// the data ids of the schema models by version
+	Versions map[string]string `json:"versions"`
+}
+
+// NewIndex returns the index of a schema without any version.
+func NewIndex(name string) Index {
+	return Index{Name: name, Versions: make(map[string]string)}
+}
+
+// IndexDataId returns the data id of the index model of the schema.
+func IndexDataId(name string) string {
+	return uuid.NewV5(uuid.NamespaceURL, indexNamespace+name).String()
+}
+
+// IndexAlias returns the alias of the index model of the schema.
+func IndexAlias(name string) string {
+	return AliasPrefix + name
+}
+
+// VersionAlias returns the alias of the model of the schema version.
+func VersionAlias(name string, version string) string {
+	return AliasPrefix + name + "@" + version
+}
+
+// ValidateName checks the name of a schema, which is letters, digits, '.', '_' and '-'.
+func ValidateName(name string) error {
+	if len(name) > maxNameLength || !namePattern.MatchString(name) {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid schema name %s", name)
+	}
+	return nil
+}
+
+// CanonicalVersion returns the semantic version without the leading 'v' and the build metadata, such as 1.2.0 of
+// v1.2.0+build.
+func CanonicalVersion(version string) (string, error) {
+	v := "v" + strings.TrimPrefix(version, "v")
+	if !semver.IsValid(v) || semver.Canonical(v) != strings.SplitN(v, "+", 2)[0] {
+		return "", types.Wrapf(types.ErrInvalidVersion, "invalid semantic version %s, MAJOR.MINOR.PATCH expected", version)
+	}
+	return strings.TrimPrefix(semver.Canonical(v), "v"), nil
+}
+
+// ParseRef parses a schema reference, name@version. The version is either a full semantic version, a major or a
+// major.minor version resolved to the latest release of it, or "latest". ok is false if ref is not a reference.
+func ParseRef(ref string) (name string, version string, ok bool) {
+	i := strings.LastIndex(ref, "@")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", false
+	}
+	name, version = ref[:i], ref[i+1:]
+	if ValidateName(name) != nil {
+		return "", "", false
+	}
+	if version != Latest && !semver.IsValid("v"+strings.TrimPrefix(version, "v")) {
+		return "", "", false
+	}
+	return name, version, true
+}
+
+// Resolve returns the version matching the query and the data id of its model. A full version matches itself only,
+// the others match the latest release, which is not a pre-release.
+func (idx Index) Resolve(query string) (string, string, error) {
+	if query != Latest {
+		if version, err := CanonicalVersion(query); err == nil {
+			if dataId, ok := idx.Versions[version]; ok {
+				return version, dataId, nil
+			}
+			return "", "", types.Wrapf(types.ErrNotFound, "schema %s@%s", idx.Name, version)
+		}
+	}
+
+	prefix := ""
+	if query != Latest {
+		prefix = "v" + strings.TrimPrefix(query, "v")
+	}
+	versions := idx.Sorted()
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := "v" + versions[i]
+		if semver.Prerelease(v) != "" {
+			continue
+		}
+		if prefix == "" || semver.Major(v) == prefix || semver.MajorMinor(v) == prefix {
+			return versions[i], idx.Versions[versions[i]], nil
+		}
+	}
+	return "", "", types.Wrapf(types.ErrNotFound, "schema %s@%s", idx.Name, query)
+}
+
+// Sorted returns the versions of the schema from the lowest to the highest.
+func (idx Index) Sorted() []string {
+	versions := make([]string, 0, len(idx.Versions))
+	for version := range idx.Versions {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare("v"+versions[i], "v"+versions[j]) < 0
+	})
+	return versions
+}
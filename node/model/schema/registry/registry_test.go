@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"testing"
+
+	"sao-node/types"
+	"sao-node/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRef(t *testing.T) {
+	for ref, expected := range map[string][2]string{
+		"person@1.2.0":   {"person", "1.2.0"},
+		"person@v1":      {"person", "v1"},
+		"my.person@1.2":  {"my.person", "1.2"},
+		"person@latest":  {"person", Latest},
+		"person@2.0.0-a": {"person", "2.0.0-a"},
+	} {
+		name, version, ok := ParseRef(ref)
+		require.True(t, ok, ref)
+		require.Equal(t, expected, [2]string{name, version})
+	}
+
+	for _, ref := range []string{"person", "@1.0.0", "person@", "person@one", "per son@1.0.0", `{"type":"object"}`} {
+		_, _, ok := ParseRef(ref)
+		require.False(t, ok, ref)
+	}
+}
+
+func TestCanonicalVersion(t *testing.T) {
+	version, err := CanonicalVersion("v1.2.3+build")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.3", version)
+
+	for _, v := range []string{"1.2", "1", "latest", "01.2.3"} {
+		_, err := CanonicalVersion(v)
+		require.True(t, types.ErrInvalidVersion.Is(err), v)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	idx := NewIndex("person")
+	idx.Versions["1.0.0"] = "d100"
+	idx.Versions["1.2.0"] = "d120"
+	idx.Versions["1.10.1"] = "d1101"
+	idx.Versions["2.0.0"] = "d200"
+	idx.Versions["2.1.0-rc.1"] = "d210rc1"
+
+	require.Equal(t, []string{"1.0.0", "1.2.0", "1.10.1", "2.0.0", "2.1.0-rc.1"}, idx.Sorted())
+
+	for query, expected := range map[string]string{
+		"1.2.0":      "d120",
+		"v1.2.0":     "d120",
+		"1":          "d1101",
+		"1.2":        "d120",
+		"2":          "d200",
+		Latest:       "d200",
+		"2.1.0-rc.1": "d210rc1",
+	} {
+		_, dataId, err := idx.Resolve(query)
+		require.NoError(t, err, query)
+		require.Equal(t, expected, dataId, query)
+	}
+
+	for _, query := range []string{"1.3.0", "3", "1.3"} {
+		_, _, err := idx.Resolve(query)
+		require.True(t, types.ErrNotFound.Is(err), query)
+	}
+}
+
+func TestIndexDataId(t *testing.T) {
+	require.True(t, utils.IsDataId(IndexDataId("person")))
+	require.Equal(t, IndexDataId("person"), IndexDataId("person"))
+	require.NotEqual(t, IndexDataId("person"), IndexDataId("people"))
+}
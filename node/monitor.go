@@ -0,0 +1,205 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sao-node/node/alert"
+	"sao-node/node/config"
+	"sao-node/store"
+	"sao-node/types"
+	"syscall"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// newAlertManager returns the alert manager sending to the configured webhooks and mailbox.
+func newAlertManager(nodeAddr string, cfg *config.Alert) *alert.Manager {
+	var notifiers []alert.Notifier
+	for _, url := range cfg.Webhooks {
+		notifiers = append(notifiers, alert.NewWebhookNotifier(url))
+	}
+	if cfg.SmtpAddress != "" && len(cfg.EmailTo) > 0 {
+		notifiers = append(notifiers, alert.NewEmailNotifier(cfg.SmtpAddress, cfg.SmtpUsername,
+			os.Getenv(cfg.SmtpPasswordEnv), cfg.EmailFrom, cfg.EmailTo))
+	}
+	return alert.NewManager(nodeAddr, notifiers, cfg.Throttle, cfg.TxFailureThreshold)
+}
+
+// monitorAlerts checks the disk usage and the pending migrations every check interval until ctx is done.
+func (n *Node) monitorAlerts(ctx context.Context) {
+	cfg := n.config().Alert
+	if cfg.CheckInterval <= 0 || (cfg.DiskUsageThreshold <= 0 && cfg.MigrationStallBlocks <= 0) {
+		return
+	}
+
+	paths := n.diskPaths()
+
+	go func() {
+		ticker := time.NewTicker(cfg.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if cfg.DiskUsageThreshold > 0 {
+					n.checkDiskUsage(paths, cfg.DiskUsageThreshold)
+				}
+				if cfg.MigrationStallBlocks > 0 && n.storeSvc != nil {
+					n.checkMigrations(ctx, cfg.MigrationStallBlocks)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// diskPaths returns the paths the node writes data to.
+func (n *Node) diskPaths() []string {
+	paths := append([]string{n.repo.Path}, n.stagingPaths()...)
+	if cfg := n.config().SaoIpfs; cfg.Enable {
+		paths = append(paths, cfg.Repo)
+	}
+	return paths
+}
+
+func (n *Node) checkDiskUsage(paths []string, threshold float64) {
+	for _, p := range paths {
+		usage, err := diskUsage(p)
+		if err != nil {
+			log.Warnf("check disk usage of %s: %v", p, err)
+			continue
+		}
+		if usage >= threshold {
+			n.alerts.Fire(alert.TypeDiskUsage, "disk usage of %s is %.1f%%, over the threshold %.1f%%", p, usage, threshold)
+		}
+	}
+}
+
+func (n *Node) checkMigrations(ctx context.Context, stallBlocks int64) {
+	migrations, err := n.storeSvc.MigrateList(ctx)
+	if err != nil {
+		log.Warnf("check migrations: %v", err)
+		return
+	}
+	if len(migrations) == 0 {
+		return
+	}
+
+	height, err := n.chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		log.Warnf("check migrations: %v", err)
+		return
+	}
+	for _, m := range migrations {
+		if m.State == types.MigrateStateTxSent && height-m.MigrateTxHeight > stallBlocks {
+			n.alerts.Fire(alert.TypeMigrationStall, "migration of %s from %s to %s is not completed %d blocks after tx %s",
+				m.DataId, m.FromProvider, m.ToProvider, height-m.MigrateTxHeight, m.MigrateTxHash)
+		}
+	}
+}
+
+// monitorCapacity checks the free space of the staging path, the http file server path and the store backends
+// every check interval, new shards and uploads are rejected while any of them is below the threshold.
+func (n *Node) monitorCapacity(ctx context.Context, storeManager *store.StoreManager) {
+	nodeCfg := n.config()
+	cfg := nodeCfg.Storage
+	if cfg.MinFreeSpace <= 0 || cfg.FreeSpaceCheckInterval <= 0 {
+		return
+	}
+
+	paths := n.stagingPaths()
+	if nodeCfg.SaoHttpFileServer.Enable {
+		paths = append(paths, nodeCfg.SaoHttpFileServer.HttpFileServerPath)
+	}
+	if nodeCfg.SaoIpfs.Enable {
+		paths = append(paths, nodeCfg.SaoIpfs.Repo)
+	}
+
+	check := func() {
+		reason := n.lowSpaceReason(ctx, paths, storeManager, uint64(cfg.MinFreeSpace))
+		if !n.capacity.SetFull(reason) {
+			return
+		}
+		if reason != "" {
+			n.alerts.Fire(alert.TypeDiskPressure, "stop accepting new shards and uploads, %s", reason)
+		} else {
+			log.Info("free space recovered, accepting new shards and uploads again")
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.FreeSpaceCheckInterval)
+		defer ticker.Stop()
+
+		check()
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// lowSpaceReason returns why the node is short of space, or empty if all paths and backends have enough.
+func (n *Node) lowSpaceReason(ctx context.Context, paths []string, storeManager *store.StoreManager, minFree uint64) string {
+	for _, p := range paths {
+		free, err := freeSpace(p)
+		if err != nil {
+			log.Warnf("check free space of %s: %v", p, err)
+			continue
+		}
+		if free < minFree {
+			return fmt.Sprintf("free space of %s is %d bytes, below %d", p, free, minFree)
+		}
+	}
+
+	if storeManager != nil {
+		for id, free := range storeManager.FreeSpace(ctx) {
+			if free < minFree {
+				return fmt.Sprintf("free space of store backend %s is %d bytes, below %d", id, free, minFree)
+			}
+		}
+	}
+	return ""
+}
+
+// freeSpace returns the bytes available to unprivileged users on the volume holding the path.
+func freeSpace(path string) (uint64, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var st syscall.Statfs_t
+	err = syscall.Statfs(p, &st)
+	if err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
+// diskUsage returns the used percentage of the volume holding the path.
+func diskUsage(path string) (float64, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var st syscall.Statfs_t
+	err = syscall.Statfs(p, &st)
+	if err != nil {
+		return 0, err
+	}
+	// the blocks reserved for root are excluded like df does
+	used := st.Blocks - st.Bfree
+	if used+st.Bavail == 0 {
+		return 0, nil
+	}
+	return float64(used) * 100 / float64(used+st.Bavail), nil
+}
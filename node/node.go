@@ -1,17 +1,28 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 	"sao-node/api"
 	"sao-node/chain"
+	"sao-node/node/alert"
+	"sao-node/node/audit"
+	"sao-node/node/cache"
+	"sao-node/node/certs"
+	"sao-node/node/dav"
+	"sao-node/node/events"
 	"sao-node/node/gateway"
+	"sao-node/node/indexer"
+	"sao-node/node/s3"
+	"sao-node/node/scheduler"
 	"sao-node/node/transport"
 	"sao-node/store"
+	"sao-node/utils"
+	"sao-node/utils/car"
 	"sort"
 	"time"
 
@@ -19,6 +30,7 @@ import (
 	"github.com/SaoNetwork/sao-did/sid"
 	saodidtypes "github.com/SaoNetwork/sao-did/types"
 	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
 	"github.com/dvsekhvalnov/jose2go/base64url"
 	"github.com/filecoin-project/go-jsonrpc/auth"
 	"github.com/gbrlsnchs/jwt/v3"
@@ -26,15 +38,24 @@ import (
 
 	"fmt"
 	apitypes "sao-node/api/types"
+	"sao-node/node/cluster"
 	"sao-node/node/config"
 	"sao-node/node/model"
+	"sao-node/node/model/provenance"
+	"sao-node/node/permission"
+	"sao-node/node/platform"
+	"sao-node/node/progress"
 	"sao-node/node/repo"
+	"sao-node/node/reputation"
+	"sao-node/node/staging"
 	"sao-node/node/storage"
 	"sao-node/types"
 	"strings"
+	"sync"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/kubo/core"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -52,26 +73,78 @@ const NODE_STATUS_SERVE_STORAGE uint32 = 1 << 2
 const NODE_STATUS_ACCEPT_ORDER uint32 = 1 << 3
 
 type Node struct {
-	ctx        context.Context
-	cfg        *config.Node
-	host       host.Host
-	repo       *repo.Repo
-	address    string
-	stopFuncs  []StopFunc
-	gatewaySvc gateway.GatewaySvcApi
+	ctx context.Context
+	// guards cfg, which ReloadConfig replaces by an updated copy
+	cfgLk sync.RWMutex
+	cfg   *config.Node
+	// serializes the reloads of the config
+	reloadLk sync.Mutex
+	// the log levels before the config applies, the levels removed from the config revert to them
+	baseLogLevels map[string]string
+	host          host.Host
+	repo          *repo.Repo
+	address       string
+	stopFuncs     []StopFunc
+	gatewaySvc    gateway.GatewaySvcApi
 	// used by store module
 	storeSvc  *storage.StoreSvc
 	chainSvc  *chain.ChainSvc
 	manager   *model.ModelManager
 	tds       datastore.Read
+	mds       datastore.Batching
 	hfs       *gateway.HttpFileServer
 	rpcServer *http.Server
+	limiter   *transport.StreamLimiter
+	alerts    *alert.Manager
+	capacity  *transport.CapacityGuard
+	// local reputation of the storage providers
+	reputation *reputation.Store
+	progress   *progress.Tracker
+	staging    *staging.Quota
+	cipher     *staging.Cipher
+	platforms  *platform.Registry
+	scheduler  *scheduler.Scheduler
+	bus        *events.Bus
 }
 
 type JwtPayload struct {
 	Allow []auth.Permission
 }
 
+// repoDatastores are the datastores of the repo the node keeps its state in.
+type repoDatastores struct {
+	metadata   datastore.Batching
+	transport  datastore.Batching
+	order      datastore.Batching
+	reputation datastore.Batching
+	platform   datastore.Batching
+	store      datastore.Batching
+	cache      datastore.Batching
+	permission datastore.Batching
+}
+
+// openRepoDatastores opens all datastores of the node, every namespace must be one of the repo.
+func openRepoDatastores(ctx context.Context, r *repo.Repo) (*repoDatastores, error) {
+	dss := &repoDatastores{}
+	for ns, ds := range map[string]*datastore.Batching{
+		"/metadata":   &dss.metadata,
+		"/transport":  &dss.transport,
+		"/order":      &dss.order,
+		"/reputation": &dss.reputation,
+		"/platform":   &dss.platform,
+		"/store":      &dss.store,
+		"/cache":      &dss.cache,
+		"/permission": &dss.permission,
+	} {
+		var err error
+		*ds, err = r.Datastore(ctx, ns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dss, nil
+}
+
 func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, error) {
 	c, err := repo.Config()
 	if err != nil {
@@ -83,11 +156,22 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 		return nil, types.Wrapf(types.ErrDecodeConfigFailed, "invalid config for repo, got: %T", c)
 	}
 
+	err = validateLogConfig(cfg.Log)
+	if err != nil {
+		return nil, err
+	}
+	baseLogLevels := logLevels()
+	err = applyLogConfig(baseLogLevels, cfg.Log)
+	if err != nil {
+		return nil, err
+	}
+
 	// get node address
-	mds, err := repo.Datastore(ctx, "/metadata")
+	dss, err := openRepoDatastores(ctx, repo)
 	if err != nil {
 		return nil, err
 	}
+	mds := dss.metadata
 	abytes, err := mds.Get(ctx, datastore.NewKey("node-address"))
 	if err != nil {
 		return nil, types.Wrap(types.ErrGetFailed, err)
@@ -101,7 +185,8 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 	}
 
 	listenAddrsOption := libp2p.ListenAddrStrings(cfg.Libp2p.ListenAddress...)
-	host, err := libp2p.New(listenAddrsOption, libp2p.Identity(peerKey))
+	p2pOptions := append([]libp2p.Option{listenAddrsOption, libp2p.Identity(peerKey)}, transport.RelayOptions(cfg.Libp2p.Relay)...)
+	host, err := libp2p.New(p2pOptions...)
 	if err != nil {
 		return nil, types.Wrap(types.ErrCreateP2PServiceFaild, err)
 	}
@@ -125,35 +210,92 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 	if err != nil {
 		return nil, err
 	}
+	if len(cfg.Libp2p.Relay.Relays) > 0 {
+		relays, err := transport.ResolveRelays(ctx, chainSvc, cfg.Libp2p.Relay.Relays)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range transport.RelayedAddrs(relays, host.ID()) {
+			if len(peerInfos) > 0 {
+				peerInfos = peerInfos + ","
+			}
+			peerInfos = peerInfos + a
+		}
+		transport.KeepReservations(ctx, host, relays)
+	}
+	alerts := newAlertManager(nodeAddr, &cfg.Alert)
+	chainSvc.SetTxObserver(alerts.TxResult)
+	// sid documents are queried for every shard load verification
+	if err := chainSvc.EnableSidDocumentCache(ctx); err != nil {
+		log.Warnf("sid document cache disabled, subscribe did events failed: %v", err)
+	}
+	if cfg.Chain.StoreBatchSize > 1 {
+		chainSvc.EnableStoreBatching(cfg.Chain.StoreBatchSize, cfg.Chain.StoreBatchWindow)
+	}
+	if err := chainSvc.SetBroadcastPolicy(cfg.Chain.BroadcastMode, cfg.Chain.TxTimeout); err != nil {
+		return nil, types.Wrap(types.ErrInvalidConfig, err)
+	}
 
 	var stopFuncs []StopFunc
-	tds, err := repo.Datastore(ctx, "/transport")
-	if err != nil {
-		return nil, err
-	}
+	tds := dss.transport
 
 	key := datastore.NewKey(fmt.Sprintf(types.PEER_INFO_PREFIX))
 	tds.Put(ctx, key, []byte(peerInfos))
 
-	ods, err := repo.Datastore(ctx, "/order")
+	ods := dss.order
+	providers, err := reputation.NewStore(ctx, dss.reputation)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := staging.NewQuota(cfg.Transport.StagingPath, cfg.Transport.StagingOwnerQuota, cfg.Transport.StagingSapceSize)
+	if err != nil {
+		return nil, err
+	}
+	platforms, err := platform.NewRegistry(ctx, cfg.Gateway.Platforms, cfg.Transport.StagingOwnerQuota, dss.platform)
+	if err != nil {
+		return nil, err
+	}
+	stagingKey, err := repo.StagingKey()
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := staging.NewCipher(stagingKey, cfg.Transport.StagingEncryption)
 	if err != nil {
 		return nil, err
 	}
 
 	sn := Node{
-		ctx:       ctx,
-		cfg:       cfg,
-		repo:      repo,
-		address:   nodeAddr,
-		stopFuncs: stopFuncs,
-		host:      host,
-		tds:       tds,
-		chainSvc:  chainSvc,
+		ctx:           ctx,
+		cfg:           cfg,
+		baseLogLevels: baseLogLevels,
+		repo:          repo,
+		address:       nodeAddr,
+		stopFuncs:     stopFuncs,
+		host:          host,
+		tds:           tds,
+		mds:           mds,
+		chainSvc:      chainSvc,
+		alerts:        alerts,
+		capacity:      transport.NewCapacityGuard(),
+		reputation:    providers,
+		progress:      progress.NewTracker(cfg.Gateway.ProgressWebhooks, cfg.Gateway.ProgressWebhookInterval),
+		staging:       quota,
+		cipher:        cipher,
+		platforms:     platforms,
+		scheduler:     scheduler.New(mds),
+		bus:           events.NewBus(),
 	}
+	providers.Consume(ctx, sn.bus)
+	sn.progress.Consume(ctx, sn.bus)
+	limiter := transport.NewStreamLimiter(cfg.Libp2p.MaxStreamsPerProtocol, cfg.Libp2p.MaxStreamsPerPeer, cfg.Libp2p.StreamQueueTimeout)
+	limiter.SetRequestLimits(cfg.Libp2p.MaxRequestSize, cfg.Libp2p.MaxContentSize, cfg.Libp2p.StreamReadTimeout)
+	limiter.SetPeerRate(cfg.Libp2p.PeerStreamRate, cfg.Libp2p.PeerStreamBurst)
+	transport.AcceptUnsignedRequests(cfg.Libp2p.AcceptUnsignedRequests)
+	sn.limiter = limiter
 
 	for _, address := range cfg.Transport.TransportListenAddress {
 		if strings.Contains(address, "udp") {
-			_, err := transport.StartLibp2pRpcServer(ctx, &sn, address, peerKey, tds, cfg)
+			_, err := transport.StartLibp2pRpcServer(ctx, &sn, address, peerKey, tds, cfg, sn.capacity, sn.progress, sn.staging, limiter)
 			if err != nil {
 				return nil, types.Wrap(types.ErrStartLibP2PRPCServerFailed, err)
 			}
@@ -177,11 +319,6 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 
 	var status = NODE_STATUS_ONLINE
 	var storageManager *store.StoreManager = nil
-	notifyChan := make(map[string]chan interface{})
-	if cfg.Module.StorageEnable && cfg.Module.GatewayEnable {
-		notifyChan[types.ShardAssignProtocol] = make(chan interface{})
-		notifyChan[types.ShardCompleteProtocol] = make(chan interface{})
-	}
 	if cfg.Module.StorageEnable {
 		status = status | NODE_STATUS_SERVE_STORAGE
 		if cfg.Storage.AcceptOrder {
@@ -202,6 +339,7 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 			}
 		}
 
+		var ipfsNode *core.IpfsNode
 		if cfg.SaoIpfs.Enable {
 			ipfsDaemon, err := store.NewIpfsDaemon(cfg.SaoIpfs.Repo)
 			if err != nil {
@@ -220,25 +358,81 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 				return nil, err
 			}
 			backends = append(backends, ipfsBackend)
+			ipfsNode = node
 			log.Info("ipfs daemon initialized")
 		}
 
 		storageManager = store.NewStoreManager(backends)
+		if cfg.Storage.ChunkSize > 0 {
+			err = storageManager.EnableUnixfs(dss.store, cfg.Storage.ChunkSize, cfg.Storage.Chunker)
+			if err != nil {
+				return nil, err
+			}
+		}
 		log.Info("store manager daemon initialized")
 
-		sn.storeSvc, err = storage.NewStoreService(ctx, nodeAddr, chainSvc, host, cfg.Transport.StagingPath, storageManager, notifyChan, ods)
+		sn.storeSvc, err = storage.NewStoreService(ctx, nodeAddr, chainSvc, host, sn.stagingPaths(), repo.MigrationPath(), cipher, storageManager, sn.bus, ods, limiter, alerts, sn.capacity)
 		if err != nil {
 			return nil, err
 		}
+		if cfg.Storage.AnnounceProviders {
+			if cfg.Storage.ReprovideInterval <= 0 {
+				return nil, types.Wrapf(types.ErrInvalidConfig, "invalid reprovide interval %v", cfg.Storage.ReprovideInterval)
+			}
+			sn.storeSvc.EnableProviderAnnouncements(ctx, cfg.Storage.ReprovideInterval)
+		}
+		if cfg.Storage.CompleteConfirmations > 0 {
+			sn.storeSvc.EnableCompleteConfirmations(ctx, cfg.Storage.CompleteConfirmations)
+		}
+		if cfg.Indexer.Enable {
+			// the in process ipfs serves the shards over bitswap, so it is the provider of the advertisements
+			if ipfsNode == nil {
+				return nil, types.Wrapf(types.ErrInvalidConfig, "indexer advertisements require the in process ipfs")
+			}
+			publisher, err := indexer.StartPublisher(&cfg.Indexer, mds, ipfsNode.PrivateKey, ipfsNode.PeerHost.Addrs)
+			if err != nil {
+				return nil, err
+			}
+			sn.storeSvc.SetAdvertiser(publisher)
+			sn.stopFuncs = append(sn.stopFuncs, publisher.Stop)
+		}
+		if err := sn.addStorageJobs(ctx); err != nil {
+			return nil, err
+		}
 		log.Info("storage node initialized")
 		go sn.storeSvc.Start(ctx)
 		sn.stopFuncs = append(sn.stopFuncs, sn.storeSvc.Stop)
 	}
 
+	var gatewayCluster *cluster.Cluster
 	if cfg.Module.GatewayEnable {
 		status = status | NODE_STATUS_SERVE_GATEWAY
-		var gatewaySvc = gateway.NewGatewaySvc(ctx, nodeAddr, chainSvc, host, cfg, storageManager, notifyChan, ods, keyringHome)
-		sn.manager = model.NewModelManager(&cfg.Cache, gatewaySvc)
+		var gatewayOds datastore.Batching = ods
+		var orderLocks gateway.OrderLocker
+		if cfg.Gateway.Cluster.Enable {
+			gatewayCluster, err = cluster.New(cfg.Gateway.Cluster, nodeAddr)
+			if err != nil {
+				return nil, err
+			}
+			gatewayOds = gatewayCluster.Orders()
+			orderLocks = gatewayCluster.Locks()
+		}
+		var gatewaySvc = gateway.NewGatewaySvc(ctx, nodeAddr, chainSvc, host, cfg, storageManager, sn.bus, gatewayOds, orderLocks, keyringHome, limiter)
+		gatewaySvc.SetReputation(providers)
+		gatewaySvc.SetStaging(sn.staging, sn.platforms, sn.cipher)
+		gatewaySvc.SetAlerts(alerts)
+		if err := sn.addGatewayJobs(ctx, gatewaySvc); err != nil {
+			return nil, err
+		}
+		sn.manager = model.NewModelManager(ctx, &cfg.Cache, gatewaySvc, dss.cache)
+		sn.manager.SetPermissionHistory(permission.NewHistory(dss.permission))
+		sn.manager.SetPlatforms(sn.platforms)
+		sn.manager.WatchModelUpdates(ctx, sn.bus)
+		if cfg.Cache.CoherenceTopic != "" {
+			if err := sn.manager.EnableCoherence(ctx, host, cfg.Cache.CoherenceTopic); err != nil {
+				return nil, err
+			}
+		}
 		sn.gatewaySvc = gatewaySvc
 		sn.stopFuncs = append(sn.stopFuncs, sn.manager.Stop)
 
@@ -246,7 +440,7 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 		if cfg.SaoHttpFileServer.Enable {
 			log.Info("initialize http file server")
 
-			hfs, err := gateway.StartHttpFileServer(&cfg.SaoHttpFileServer)
+			hfs, err := gateway.StartHttpFileServer(&cfg.SaoHttpFileServer, storageManager, &sn, &sn, repo.AcmeCachePath())
 			if err != nil {
 				return nil, err
 			}
@@ -254,16 +448,58 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 			sn.stopFuncs = append(sn.stopFuncs, hfs.Stop)
 		}
 
+		if cfg.S3.Enable {
+			log.Info("initialize S3 server")
+
+			s3Server, err := s3.StartServer(ctx, &cfg.S3, &sn, chainSvc, nodeAddr, keyringHome, cfg.SaoHttpFileServer.HttpFileServerPath)
+			if err != nil {
+				return nil, err
+			}
+			sn.stopFuncs = append(sn.stopFuncs, s3Server.Stop)
+		}
+
+		if cfg.WebDav.Enable {
+			log.Info("initialize WebDAV server")
+
+			davServer, err := dav.StartServer(ctx, &cfg.WebDav, &sn, chainSvc, nodeAddr, keyringHome, cfg.SaoHttpFileServer.HttpFileServerPath, mds)
+			if err != nil {
+				return nil, err
+			}
+			sn.stopFuncs = append(sn.stopFuncs, davServer.Stop)
+		}
+
 		log.Info("gateway node initialized")
 	}
 
+	if cfg.Module.GatewayEnable || cfg.Module.StorageEnable {
+		// the cursor is kept with the orders, so the assignments missed while the node was down are replayed
+		if err := sn.bus.PublishChainEvents(ctx, chainSvc, ods); err != nil {
+			log.Warnf("cached models are not evicted and missed shard assignments are not recovered, subscribe sao events failed: %v", err)
+		}
+	}
+
+	if err := sn.addJob(ctx, "earnings", cfg.Scheduler.Earnings, sn.rollupEarnings); err != nil {
+		return nil, err
+	}
+	sn.scheduler.Start(ctx)
+
 	// api server
-	rpcServer, err := newRpcServer(&sn, &cfg.Api)
+	var auditLog *audit.Log
+	if cfg.Api.EnableAuditLog {
+		auditLog, err = audit.Open(repo.AuditLogPath())
+		if err != nil {
+			return nil, err
+		}
+	}
+	rpcServer, err := newRpcServer(&sn, &cfg.Api, auditLog, repo.AcmeCachePath())
 	if err != nil {
 		return nil, err
 	}
 	sn.rpcServer = rpcServer
 	sn.stopFuncs = append(sn.stopFuncs, rpcServer.Shutdown)
+	if auditLog != nil {
+		sn.stopFuncs = append(sn.stopFuncs, auditLog.Close)
+	}
 
 	tokenRead, err := sn.AuthNew(ctx, api.AllPermissions[:2])
 	if err != nil {
@@ -277,9 +513,39 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 	}
 	log.Info("Write token: ", string(tokenWrite))
 
+	// let commands on the same repo find the api
+	tokenAdmin, err := sn.AuthNew(ctx, api.AllPermissions[:4])
+	if err != nil {
+		return nil, err
+	}
+	err = repo.SetAPIToken(tokenAdmin)
+	if err != nil {
+		return nil, err
+	}
+	apiEndpoint, err := multiaddr.NewMultiaddr(strings.TrimSpace(cfg.Api.ListenAddress))
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidServerAddress, "invalid endpoint: %s, %s", cfg.Api.ListenAddress, err)
+	}
+	if certs.Enabled(&cfg.Api.TLS) {
+		apiEndpoint, err = tlsEndpoint(apiEndpoint, certs.ServerName(&cfg.Api.TLS))
+		if err != nil {
+			return nil, types.Wrapf(types.ErrInvalidServerAddress, "invalid endpoint: %s, %s", cfg.Api.ListenAddress, err)
+		}
+	}
+	err = repo.SetAPIEndpoint(apiEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	sn.stopFuncs = append(sn.stopFuncs, func(_ context.Context) error {
+		return repo.RemoveAPIEndpoint()
+	})
+
 	// Connect to P2P network
 	sn.ConnectToGatewayCluster(ctx)
 
+	sn.monitorAlerts(ctx)
+	sn.monitorCapacity(ctx, storageManager)
+
 	// chainSvc.stop should be after chain listener unsubscribe
 	sn.stopFuncs = append(sn.stopFuncs, chainSvc.Stop)
 
@@ -292,20 +558,23 @@ func NewNode(ctx context.Context, repo *repo.Repo, keyringHome string) (*Node, e
 
 	chainSvc.StartStatusReporter(ctx, sn.address, status)
 
+	// after all services writing to the datastores are stopped
+	if gatewayCluster != nil {
+		sn.stopFuncs = append(sn.stopFuncs, func(_ context.Context) error {
+			return gatewayCluster.Close()
+		})
+	}
 	sn.stopFuncs = append(sn.stopFuncs, func(_ context.Context) error {
-		for _, c := range notifyChan {
-			close(c)
-		}
-		return nil
+		return repo.Close()
 	})
 
 	return &sn, nil
 }
 
-func newRpcServer(ga api.SaoApi, cfg *config.API) (*http.Server, error) {
+func newRpcServer(ga api.SaoApi, cfg *config.API, auditLog *audit.Log, acmeCache string) (*http.Server, error) {
 	log.Info("initialize rpc server")
 
-	handler, err := GatewayRpcHandler(ga, cfg.EnablePermission)
+	handler, err := GatewayRpcHandler(ga, cfg.EnablePermission, auditLog)
 	if err != nil {
 		return nil, types.Wrapf(types.ErrStartPRPCServerFailed, "failed to instantiate rpc handler: %v", err)
 	}
@@ -315,13 +584,32 @@ func newRpcServer(ga api.SaoApi, cfg *config.API) (*http.Server, error) {
 	if err != nil {
 		return nil, types.Wrapf(types.ErrInvalidServerAddress, "invalid endpoint: %s, %s", strma, err)
 	}
-	rpcServer, err := ServeRPC(handler, endpoint)
+	tlsConfig, err := certs.NewTLSConfig(&cfg.TLS, acmeCache)
+	if err != nil {
+		return nil, err
+	}
+	rpcServer, err := ServeRPC(handler, endpoint, tlsConfig)
 	if err != nil {
 		return nil, types.Wrapf(types.ErrStartPRPCServerFailed, "failed to start json-rpc endpoint: %s", err)
 	}
 	return rpcServer, nil
 }
 
+// tlsEndpoint returns the endpoint of the API served over TLS, /tls/sni/<name> tells the commands on the repo
+// the name to verify the certificate against.
+func tlsEndpoint(endpoint multiaddr.Multiaddr, serverName string) (multiaddr.Multiaddr, error) {
+	endpoint = endpoint.Decapsulate(multiaddr.StringCast("/http"))
+	suffix := "/tls"
+	if serverName != "" {
+		suffix += "/sni/" + serverName
+	}
+	tlsAddr, err := multiaddr.NewMultiaddr(suffix + "/http")
+	if err != nil {
+		return nil, err
+	}
+	return endpoint.Encapsulate(tlsAddr), nil
+}
+
 func (n *Node) ConnectToGatewayCluster(ctx context.Context) {
 	nodes, err := n.chainSvc.ListNodes(ctx)
 	if err != nil {
@@ -421,20 +709,64 @@ func (n *Node) AuthNew(ctx context.Context, perms []auth.Permission) ([]byte, er
 }
 
 func (n *Node) ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) {
+	err := n.capacity.Check()
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
 	// verify signature
-	err := n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	err = n.validQueryProposal(ctx, req, types.CapModelCreate)
 	if err != nil {
 		return apitypes.CreateResp{}, err
 	}
 
-	err = n.validSignature(ctx, &orderProposal.Proposal, orderProposal.Proposal.Owner, orderProposal.JwsSignature)
+	err = n.validOrderProposal(ctx, req, orderProposal)
 	if err != nil {
 		return apitypes.CreateResp{}, err
 	}
 
 	// model process
+	revert, err := n.reservePlatform(ctx, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
 	model, err := n.manager.Create(ctx, req, orderProposal, orderId, content)
 	if err != nil {
+		revert()
+		return apitypes.CreateResp{}, err
+	}
+
+	return apitypes.CreateResp{
+		Alias:  model.Alias,
+		DataId: model.DataId,
+		Cid:    model.Cid,
+	}, nil
+}
+
+func (n *Node) ModelCreateAsync(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) {
+	err := n.capacity.Check()
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
+	// verify signature
+	err = n.validQueryProposal(ctx, req, types.CapModelCreate)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
+	err = n.validOrderProposal(ctx, req, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
+	revert, err := n.reservePlatform(ctx, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	model, err := n.manager.CreateAsync(ctx, req, orderProposal, orderId, content)
+	if err != nil {
+		revert()
 		return apitypes.CreateResp{}, err
 	}
 
@@ -445,6 +777,58 @@ func (n *Node) ModelCreate(ctx context.Context, req *types.MetadataProposal, ord
 	}, nil
 }
 
+func (n *Node) CommitStatus(ctx context.Context, dataId string) (apitypes.CommitStatusResp, error) {
+	orderInfo, err := n.gatewaySvc.OrderStatus(ctx, dataId)
+	if err != nil {
+		return apitypes.CommitStatusResp{}, err
+	}
+
+	resp := apitypes.CommitStatusResp{
+		DataId:  orderInfo.DataId,
+		OrderId: orderInfo.OrderId,
+		State:   orderInfo.State.String(),
+		Shards:  len(orderInfo.Shards),
+		Done:    orderInfo.State >= types.OrderStateComplete,
+		LastErr: orderInfo.LastErr,
+	}
+	for _, shard := range orderInfo.Shards {
+		if shard.State == types.ShardStateCompleted {
+			resp.CompletedShards++
+		}
+	}
+	return resp, nil
+}
+
+func (n *Node) ModelEstimateFee(ctx context.Context, owner string, size uint64, duration uint64, replica int32) (apitypes.EstimateFeeResp, error) {
+	if duration == 0 || replica <= 0 {
+		return apitypes.EstimateFeeResp{}, types.Wrapf(types.ErrInvalidParameters, "invalid duration %d or replica %d", duration, replica)
+	}
+
+	amount, err := n.chainSvc.EstimateOrderFee(ctx, size, duration, replica)
+	if err != nil {
+		return apitypes.EstimateFeeResp{}, err
+	}
+	resp := apitypes.EstimateFeeResp{
+		Amount: amount.String(),
+		Gas:    n.chainSvc.EstimateGas(&saotypes.MsgStore{}),
+	}
+
+	if owner != "" {
+		address, err := n.chainSvc.QueryPaymentAddress(ctx, owner)
+		if err != nil {
+			return apitypes.EstimateFeeResp{}, types.Wrap(types.ErrGetAddressFailed, err)
+		}
+		balances, err := n.chainSvc.GetBalance(ctx, address)
+		if err != nil {
+			return apitypes.EstimateFeeResp{}, types.Wrap(types.ErrGetBalanceFailed, err)
+		}
+		balance := sdktypes.NewCoin(amount.Denom, balances.AmountOf(amount.Denom))
+		resp.Balance = balance.String()
+		resp.Sufficient = !balance.IsLT(amount)
+	}
+	return resp, nil
+}
+
 func (n *Node) ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (apitypes.CreateResp, error) {
 	// Asynchronous order and the content has been uploaded already
 	cidStr := orderProposal.Proposal.Cid
@@ -462,29 +846,25 @@ func (n *Node) ModelCreateFile(ctx context.Context, req *types.MetadataProposal,
 		}
 
 		var path = filepath.Join(basePath, cidStr)
-		file, err := os.Open(path)
-		if err != nil {
-			return apitypes.CreateResp{}, types.Wrap(types.ErrOpenFileFailed, err)
-		}
 
-		content, err := io.ReadAll(file)
+		// verify signature
+		err = n.validQueryProposal(ctx, req, types.CapModelCreate)
 		if err != nil {
-			return apitypes.CreateResp{}, types.Wrap(types.ErrReadFileFailed, err)
+			return apitypes.CreateResp{}, err
 		}
 
-		// verify signature
-		err = n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+		err = n.validOrderProposal(ctx, req, orderProposal)
 		if err != nil {
 			return apitypes.CreateResp{}, err
 		}
 
-		err = n.validSignature(ctx, &orderProposal.Proposal, orderProposal.Proposal.Owner, orderProposal.JwsSignature)
+		revert, err := n.reservePlatform(ctx, orderProposal)
 		if err != nil {
 			return apitypes.CreateResp{}, err
 		}
-
-		model, err := n.manager.Create(ctx, req, orderProposal, orderId, content)
+		model, err := n.manager.CreateFile(ctx, req, orderProposal, orderId, path)
 		if err != nil {
+			revert()
 			return apitypes.CreateResp{}, err
 		}
 		return apitypes.CreateResp{
@@ -498,8 +878,95 @@ func (n *Node) ModelCreateFile(ctx context.Context, req *types.MetadataProposal,
 	}
 }
 
+// ModelCreateUpload creates the file model of the content uploaded to the http file server. The proposals are
+// verified before the content is received, the content is staged under the owner and must match the cid and the
+// size of the order proposal.
+func (n *Node) ModelCreateUpload(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content io.Reader) (apitypes.CreateResp, error) {
+	err := n.capacity.Check()
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
+	err = n.validQueryProposal(ctx, req, types.CapModelCreate)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	err = n.validOrderProposal(ctx, req, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+
+	proposal := orderProposal.Proposal
+	contentCid, err := cid.Decode(proposal.Cid)
+	if err != nil {
+		return apitypes.CreateResp{}, types.Wrap(types.ErrInvalidCid, err)
+	}
+	if proposal.Size_ == 0 {
+		return apitypes.CreateResp{}, types.Wrapf(types.ErrInvalidContent, "the content is empty")
+	}
+	// an upload token is valid until its delegation expires, the replays are rejected before staging anything
+	err = n.manager.CheckNew(ctx, req, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	revert, err := n.reservePlatform(ctx, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	quota := n.staging
+	if platformQuota := n.platforms.Staging(proposal.GroupId); platformQuota != nil {
+		quota = platformQuota
+	}
+	if err := quota.Reserve(proposal.Owner, int64(proposal.Size_)); err != nil {
+		revert()
+		return apitypes.CreateResp{}, err
+	}
+	created := false
+	defer func() {
+		// the staged bytes are counted again by the next scan, if the file is left behind
+		if !created {
+			quota.Release(proposal.Owner, int64(proposal.Size_))
+		}
+	}()
+	// the upload is renamed into place once verified, so a bad upload never replaces the shard staged already
+	path, err := gateway.StageVerified(quota.Path(), proposal.Owner, proposal.Cid, io.LimitReader(content, int64(proposal.Size_)+1), n.cipher,
+		func(file *staging.File) error {
+			return verifyStaged(file, contentCid, proposal.Size_)
+		})
+	if err != nil {
+		revert()
+		return apitypes.CreateResp{}, err
+	}
+
+	model, err := n.manager.CreateFile(ctx, req, orderProposal, orderId, path)
+	if err != nil {
+		revert()
+		return apitypes.CreateResp{}, err
+	}
+	created = true
+	return apitypes.CreateResp{
+		Alias:  model.Alias,
+		DataId: model.DataId,
+		Cid:    model.Cid,
+	}, nil
+}
+
+func verifyStaged(file *staging.File, contentCid cid.Cid, size uint64) error {
+	if uint64(file.Size()) != size {
+		return types.Wrapf(types.ErrInvalidContent, "uploaded %d bytes, the proposal is of %d", file.Size(), size)
+	}
+	c, err := utils.CalculateCidFrom(file)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(c.Hash(), contentCid.Hash()) {
+		return types.Wrapf(types.ErrInvalidCid, "uploaded %s, the proposal is of %s", c, contentCid)
+	}
+	return nil
+}
+
 func (n *Node) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
-	err := n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	err := n.validQueryProposal(ctx, req, types.CapModelLoad)
 	if err != nil {
 		return apitypes.LoadResp{}, err
 	}
@@ -509,13 +976,41 @@ func (n *Node) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apit
 		return apitypes.LoadResp{}, err
 	}
 
+	content := model.Content
+	if req.ResolveDepth > 0 {
+		content, err = n.manager.ResolveLinks(ctx, req, model, req.ResolveDepth)
+		if err != nil {
+			return apitypes.LoadResp{}, err
+		}
+	}
+
+	return apitypes.LoadResp{
+		DataId:   model.DataId,
+		Alias:    model.Alias,
+		CommitId: model.CommitId,
+		Version:  model.Version,
+		Cid:      model.Cid,
+		Content:  string(content),
+	}, nil
+}
+
+func (n *Node) ModelLoadMeta(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	err := n.validQueryProposal(ctx, req, types.CapModelLoad)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+
+	model, err := n.manager.LoadMeta(ctx, req)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+
 	return apitypes.LoadResp{
 		DataId:   model.DataId,
 		Alias:    model.Alias,
 		CommitId: model.CommitId,
 		Version:  model.Version,
 		Cid:      model.Cid,
-		Content:  string(model.Content),
 	}, nil
 }
 
@@ -529,6 +1024,7 @@ func (n *Node) ModelDelete(ctx context.Context, req *types.OrderTerminateProposa
 	if err != nil {
 		return apitypes.DeleteResp{}, err
 	}
+	n.platforms.Release(ctx, req.Proposal.DataId)
 	return apitypes.DeleteResp{
 		DataId: model.DataId,
 		Alias:  model.Alias,
@@ -536,19 +1032,29 @@ func (n *Node) ModelDelete(ctx context.Context, req *types.OrderTerminateProposa
 }
 
 func (n *Node) ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error) {
+	err := n.capacity.Check()
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+
 	// verify signature
-	err := n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	err = n.validQueryProposal(ctx, req, types.CapModelUpdate)
 	if err != nil {
 		return apitypes.UpdateResp{}, err
 	}
 
-	err = n.validSignature(ctx, &orderProposal.Proposal, orderProposal.Proposal.Owner, orderProposal.JwsSignature)
+	err = n.validOrderProposal(ctx, req, orderProposal)
 	if err != nil {
 		return apitypes.UpdateResp{}, err
 	}
 
+	revert, err := n.reservePlatform(ctx, orderProposal)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
 	model, err := n.manager.Update(ctx, req, orderProposal, orderId, patch)
 	if err != nil {
+		revert()
 		return apitypes.UpdateResp{}, err
 	}
 	return apitypes.UpdateResp{
@@ -559,8 +1065,93 @@ func (n *Node) ModelUpdate(ctx context.Context, req *types.MetadataProposal, ord
 	}, nil
 }
 
+func (n *Node) ModelMerge(ctx context.Context, req *types.MetadataProposal, baseCommitId string, patch []byte) (apitypes.MergeResp, error) {
+	err := n.validQueryProposal(ctx, req, types.CapModelUpdate)
+	if err != nil {
+		return apitypes.MergeResp{}, err
+	}
+
+	model, merged, conflicts, err := n.manager.Merge(ctx, req, baseCommitId, patch)
+	if err != nil {
+		return apitypes.MergeResp{}, err
+	}
+	mergePatch, err := utils.GeneratePatch(string(model.Content), string(merged))
+	if err != nil {
+		return apitypes.MergeResp{}, err
+	}
+	return apitypes.MergeResp{
+		DataId:    model.DataId,
+		Alias:     model.Alias,
+		CommitId:  model.CommitId,
+		Content:   string(merged),
+		Patch:     mergePatch,
+		Conflicts: conflicts,
+	}, nil
+}
+
+func (n *Node) ModelProvenance(ctx context.Context, req *types.MetadataProposal) (types.SignedProvenanceReport, error) {
+	err := n.validQueryProposal(ctx, req, types.CapModelCommits)
+	if err != nil {
+		return types.SignedProvenanceReport{}, err
+	}
+
+	meta, err := n.gatewaySvc.QueryMeta(ctx, req, 0)
+	if err != nil {
+		return types.SignedProvenanceReport{}, err
+	}
+	height, err := n.chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		return types.SignedProvenanceReport{}, err
+	}
+	report := &types.ProvenanceReport{
+		DataId:     meta.DataId,
+		Alias:      meta.Alias,
+		Owner:      meta.Owner,
+		Gateway:    n.address,
+		Height:     height,
+		ReportedAt: time.Now(),
+	}
+	for i, c := range meta.Commits {
+		commitInfo, err := types.ParseMetaCommit(c)
+		if err != nil {
+			return types.SignedProvenanceReport{}, err
+		}
+		commit := provenance.Commit{
+			CommitId: commitInfo.CommitId,
+			Version:  fmt.Sprintf("v%d", i),
+			Height:   commitInfo.Height,
+		}
+		// the order and the content of the commit are of the metadata at the commit height
+		commitMeta, err := n.gatewaySvc.QueryMeta(ctx, req, int64(commitInfo.Height))
+		if err == nil && commitMeta.CommitId != commitInfo.CommitId {
+			err = types.Wrapf(types.ErrUnverifiedCommit, "the commit at height %d is %s", commitInfo.Height, commitMeta.CommitId)
+		}
+		if err != nil {
+			report.Commits = append(report.Commits, types.CommitProvenance{
+				CommitId: commit.CommitId,
+				Version:  commit.Version,
+				Height:   commit.Height,
+				Error:    err.Error(),
+			})
+			continue
+		}
+		commit.OrderId, commit.Cid = commitMeta.OrderId, commitMeta.Cid
+		report.Commits = append(report.Commits, provenance.Verify(ctx, n.chainSvc, meta.DataId, commit))
+	}
+
+	key := n.host.Peerstore().PrivKey(n.host.ID())
+	if key == nil {
+		return types.SignedProvenanceReport{}, types.Wrapf(types.ErrSignedFailed, "private key of %s not found", n.host.ID())
+	}
+	signed, err := types.SignProvenanceReport(report, key)
+	if err != nil {
+		return types.SignedProvenanceReport{}, err
+	}
+	return *signed, nil
+}
+
 func (n *Node) ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (apitypes.ShowCommitsResp, error) {
-	err := n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	err := n.validQueryProposal(ctx, req, types.CapModelCommits)
 	if err != nil {
 		return apitypes.ShowCommitsResp{}, err
 	}
@@ -576,6 +1167,53 @@ func (n *Node) ModelShowCommits(ctx context.Context, req *types.MetadataProposal
 	}, nil
 }
 
+func (n *Node) ModelExportCar(ctx context.Context, reqs []*types.MetadataProposal) (apitypes.ExportCarResp, error) {
+	if len(reqs) == 0 {
+		return apitypes.ExportCarResp{}, types.Wrapf(types.ErrInvalidParameters, "no model to export")
+	}
+	owner := reqs[0].Proposal.Owner
+
+	// the commits of a model are grouped in the order of the requests
+	var models []car.Model
+	index := make(map[string]int)
+	for _, req := range reqs {
+		if req.Proposal.Owner != owner {
+			return apitypes.ExportCarResp{}, types.Wrapf(types.ErrInvalidParameters, "the models of %s and %s can't be exported together", owner, req.Proposal.Owner)
+		}
+		err := n.validQueryProposal(ctx, req, types.CapModelLoad)
+		if err != nil {
+			return apitypes.ExportCarResp{}, err
+		}
+
+		model, err := n.manager.Load(ctx, req)
+		if err != nil {
+			return apitypes.ExportCarResp{}, err
+		}
+		i, ok := index[model.DataId]
+		if !ok {
+			i = len(models)
+			index[model.DataId] = i
+			models = append(models, car.Model{DataId: model.DataId, Alias: model.Alias, GroupId: model.GroupId})
+		}
+		models[i].Commits = append(models[i].Commits, car.Commit{
+			CommitId: model.CommitId,
+			Version:  model.Version,
+			Content:  model.Content,
+		})
+	}
+
+	var buf bytes.Buffer
+	err := car.Write(&buf, owner, models)
+	if err != nil {
+		return apitypes.ExportCarResp{}, err
+	}
+	return apitypes.ExportCarResp{
+		Models:  len(models),
+		Commits: len(reqs),
+		Car:     buf.Bytes(),
+	}, nil
+}
+
 func (n *Node) ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (apitypes.RenewResp, error) {
 	err := n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
 	if err != nil {
@@ -606,6 +1244,34 @@ func (n *Node) ModelUpdatePermission(ctx context.Context, req *types.PermissionP
 	}, nil
 }
 
+func (n *Node) ModelPermissionHistory(ctx context.Context, req *types.MetadataProposal) (apitypes.PermissionHistoryResp, error) {
+	err := n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	if err != nil {
+		return apitypes.PermissionHistoryResp{}, err
+	}
+	if !utils.IsDataId(req.Proposal.Keyword) {
+		return apitypes.PermissionHistoryResp{}, types.Wrapf(types.ErrInvalidParameters, "invalid dataId %s", req.Proposal.Keyword)
+	}
+
+	changes, err := n.manager.PermissionHistory(ctx, req.Proposal.Owner, req.Proposal.Keyword)
+	if err != nil {
+		return apitypes.PermissionHistoryResp{}, err
+	}
+	resp := apitypes.PermissionHistoryResp{DataId: req.Proposal.Keyword}
+	for _, change := range changes {
+		resp.Changes = append(resp.Changes, apitypes.PermissionChange{
+			Height:        change.Height,
+			TxHash:        change.TxHash,
+			Time:          change.Time,
+			ReadonlyDids:  change.ReadonlyDids,
+			ReadwriteDids: change.ReadwriteDids,
+			Granted:       change.Granted,
+			Revoked:       change.Revoked,
+		})
+	}
+	return resp, nil
+}
+
 func (n *Node) GetPeerInfo(ctx context.Context) (apitypes.GetPeerInfoResp, error) {
 	key := datastore.NewKey(types.PEER_INFO_PREFIX)
 	if peerInfo, err := n.tds.Get(ctx, key); err == nil {
@@ -630,19 +1296,50 @@ func (n *Node) GenerateToken(ctx context.Context, owner string) (apitypes.Genera
 }
 
 func (n *Node) GetHttpUrl(ctx context.Context, dataId string) (apitypes.GetUrlResp, error) {
-	if n.cfg.SaoHttpFileServer.HttpFileServerAddress != "" {
+	cfg := n.config().SaoHttpFileServer
+	if cfg.HttpFileServerAddress != "" {
 		return apitypes.GetUrlResp{
-			Url: "http://" + n.cfg.SaoHttpFileServer.HttpFileServerAddress + "/saonetwork/" + dataId,
+			Url: fileServerScheme(cfg) + "://" + cfg.HttpFileServerAddress + "/saonetwork/" + dataId,
 		}, nil
 	} else {
 		return apitypes.GetUrlResp{}, types.Wrapf(types.ErrGetHttpUrlFaild, "failed to get http url")
 	}
 }
 
+// GetShareUrl returns the url of the http file server loading the model of the share token.
+func (n *Node) GetShareUrl(ctx context.Context, token string) (apitypes.GetUrlResp, error) {
+	cfg := n.config().SaoHttpFileServer
+	if cfg.HttpFileServerAddress == "" || !cfg.EnableShare {
+		return apitypes.GetUrlResp{}, types.Wrapf(types.ErrGetHttpUrlFaild, "share links are not served")
+	}
+	return apitypes.GetUrlResp{
+		Url: fileServerScheme(cfg) + "://" + cfg.HttpFileServerAddress + "/share/" + token,
+	}, nil
+}
+
+// GetUploadUrl returns the url of the http file server the file of the pre-signed upload token is put to.
+func (n *Node) GetUploadUrl(ctx context.Context, token string) (apitypes.GetUrlResp, error) {
+	cfg := n.config().SaoHttpFileServer
+	if cfg.HttpFileServerAddress == "" || !cfg.EnableUpload {
+		return apitypes.GetUrlResp{}, types.Wrapf(types.ErrGetHttpUrlFaild, "uploads are not accepted")
+	}
+	return apitypes.GetUrlResp{
+		Url: fileServerScheme(cfg) + "://" + cfg.HttpFileServerAddress + "/upload/" + token,
+	}, nil
+}
+
+func fileServerScheme(cfg config.SaoHttpFileServer) string {
+	if certs.Enabled(&cfg.TLS) {
+		return "https"
+	}
+	return "http"
+}
+
 func (n *Node) GetIpfsUrl(ctx context.Context, cid string) (apitypes.GetUrlResp, error) {
-	if n.cfg.SaoIpfs.Enable {
+	cfg := n.config()
+	if cfg.SaoIpfs.Enable {
 		return apitypes.GetUrlResp{
-			Url: "ipfs+https://" + n.cfg.SaoHttpFileServer.HttpFileServerAddress + "/ipfs/" + cid,
+			Url: "ipfs+https://" + cfg.SaoHttpFileServer.HttpFileServerAddress + "/ipfs/" + cid,
 		}, nil
 	} else {
 		return apitypes.GetUrlResp{}, types.Wrapf(types.ErrGetIpfsUrlFaild, "failed to get ipfs url")
@@ -653,6 +1350,162 @@ func (n *Node) GetNodeAddress(ctx context.Context) (string, error) {
 	return n.address, nil
 }
 
+func (n *Node) GetNodeStats(ctx context.Context) (apitypes.NodeStatsResp, error) {
+	height, err := n.chainSvc.GetLastHeight(ctx)
+	if err != nil {
+		return apitypes.NodeStatsResp{}, types.Wrap(types.ErrQueryHeightFailed, err)
+	}
+	balance, err := n.chainSvc.GetBalance(ctx, n.address)
+	if err != nil {
+		return apitypes.NodeStatsResp{}, types.Wrap(types.ErrGetBalanceFailed, err)
+	}
+	// nodes never storing any shard have no pledge yet
+	reward, err := n.chainSvc.GetReward(ctx, n.address)
+	if err != nil {
+		log.Debugf("get reward of %s: %v", n.address, err)
+		reward = sdktypes.NewCoin(chain.DENOM, sdktypes.ZeroInt())
+	}
+
+	stats := apitypes.NodeStatsResp{
+		Address:     n.address,
+		ChainHeight: height,
+		Balance:     balance.AmountOf(chain.DENOM).String() + chain.DENOM,
+		Reward:      reward.String(),
+	}
+	for _, p := range n.diskPaths() {
+		usage, err := diskUsage(p)
+		if err != nil {
+			log.Warnf("check disk usage of %s: %v", p, err)
+			continue
+		}
+		stats.Disks = append(stats.Disks, apitypes.DiskUsage{Path: p, UsedPercent: usage})
+	}
+	stats.Staging = n.staging.Usage()
+	for _, s := range transport.ProtocolStats() {
+		stats.Protocols = append(stats.Protocols, apitypes.ProtocolStreams{
+			Protocol:  s.Protocol,
+			Version:   s.Version,
+			Direction: s.Direction,
+			Streams:   s.Streams,
+		})
+	}
+	sidStats := n.chainSvc.SidDocumentCacheStats()
+	stats.SidDocument = apitypes.SidDocumentCacheStats{
+		Enabled:   sidStats.Enabled,
+		Size:      sidStats.Size,
+		Capacity:  sidStats.Capacity,
+		Hits:      sidStats.Hits,
+		Misses:    sidStats.Misses,
+		Evictions: sidStats.Evictions,
+		Purges:    sidStats.Purges,
+	}
+	return stats, nil
+}
+
+func (n *Node) PlatformUsage(ctx context.Context) ([]apitypes.PlatformUsage, error) {
+	if n.manager == nil {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "the platforms are only served by the gateway module")
+	}
+	return n.platforms.Usage(), nil
+}
+
+func (n *Node) CacheStats(ctx context.Context) (apitypes.CacheStatsResp, error) {
+	if n.manager == nil {
+		return apitypes.CacheStatsResp{}, types.Wrapf(types.ErrInvalidParameters, "the model cache is only used by the gateway module")
+	}
+
+	cacheCfg := n.manager.CacheConfig()
+	resp := apitypes.CacheStatsResp{
+		EnableCache:  cacheCfg.EnableCache,
+		Capacity:     cacheCfg.CacheCapacity,
+		ContentLimit: cacheCfg.ContentLimit,
+	}
+	switch n.manager.CacheSvc.(type) {
+	case *cache.LruCacheSvc:
+		resp.Backend = "lru"
+	case *cache.TieredCacheSvc:
+		resp.Backend = "lru+disk"
+	case *cache.RedisCacheSvc:
+		resp.Backend = "redis"
+	case *cache.MemcachedCacheSvc:
+		resp.Backend = "memcached"
+	}
+	for _, s := range n.manager.CacheSvc.Stats() {
+		resp.Caches = append(resp.Caches, apitypes.CacheStats{
+			Account:   s.Name,
+			Size:      s.Size,
+			Capacity:  s.Capacity,
+			Hits:      s.Hits,
+			Misses:    s.Misses,
+			Evictions: s.Evictions,
+		})
+	}
+	return resp, nil
+}
+
+func (n *Node) ProgressGet(ctx context.Context, cid string) (types.ProgressEvent, error) {
+	event, ok := n.progress.Get(cid)
+	if !ok {
+		return types.ProgressEvent{}, types.Wrapf(types.ErrNotFound, "no progress of %s", cid)
+	}
+	return event, nil
+}
+
+func (n *Node) ModelSubscribe(ctx context.Context, dataId string, owner string) (<-chan types.ModelEvent, error) {
+	if dataId == "" && owner == "" {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "dataId or owner is required")
+	}
+	return n.bus.SubscribeModels(ctx, dataId, owner), nil
+}
+
+func (n *Node) ProgressSubscribe(ctx context.Context, cid string) (<-chan types.ProgressEvent, error) {
+	return n.progress.Subscribe(ctx, cid), nil
+}
+
+func (n *Node) ProviderReputation(ctx context.Context) ([]apitypes.ProviderReputation, error) {
+	var list []apitypes.ProviderReputation
+	for _, s := range n.reputation.List() {
+		list = append(list, apitypes.ProviderReputation{
+			Provider:            s.Provider,
+			Assigned:            s.Assigned,
+			AssignFailures:      s.AssignFailures,
+			Completed:           s.Completed,
+			Timeouts:            s.Timeouts,
+			AvgCompleteLatency:  s.AvgCompleteLatency(),
+			Loads:               s.Loads,
+			LoadFailures:        s.LoadFailures,
+			Audits:              s.Audits,
+			AuditFailures:       s.AuditFailures,
+			Migrations:          s.Migrations,
+			MigrationFailures:   s.MigrationFailures,
+			AvgMigrationLatency: s.AvgMigrationLatency(),
+			FailureRate:         s.FailureRate(),
+			UpdatedAt:           s.UpdatedAt,
+		})
+	}
+	return list, nil
+}
+
+func (n *Node) JobList(ctx context.Context) ([]apitypes.JobInfo, error) {
+	var list []apitypes.JobInfo
+	for _, j := range n.scheduler.List() {
+		list = append(list, apitypes.JobInfo{
+			Name:         j.Name,
+			Enabled:      j.Enabled,
+			Schedule:     j.Schedule,
+			Running:      j.Running,
+			NextRun:      j.NextRun,
+			LastRun:      j.LastRun,
+			LastDuration: j.LastDuration,
+			LastResult:   j.LastResult,
+			LastError:    j.LastError,
+			Runs:         j.Runs,
+			Failures:     j.Failures,
+		})
+	}
+	return list, nil
+}
+
 func (n *Node) GetNetPeers(context.Context) ([]types.PeerInfo, error) {
 	host := n.host
 	conns := host.Network().Conns()
@@ -709,12 +1562,98 @@ func (n *Node) validSignature(ctx context.Context, proposal types.ConsensusPropo
 	return nil
 }
 
+// validQueryProposal verifies the query proposal is signed by its owner. If the proposal comes with a delegation,
+// or the owner is a registered session key, the owner is the audience of the delegation, which must be signed
+// by the issuer and grant the capability on the keyword of the proposal.
+func (n *Node) validQueryProposal(ctx context.Context, req *types.MetadataProposal, capability string) error {
+	if err := n.verifyQueryProposal(ctx, req, capability); err != nil {
+		return err
+	}
+	// the rate of the platform is taken by the verified requests only, so the requests forged with its groupId
+	// can't lock its users out
+	return n.platforms.Allow(req.Proposal.GroupId)
+}
+
+func (n *Node) verifyQueryProposal(ctx context.Context, req *types.MetadataProposal, capability string) error {
+	if req.Delegation == nil {
+		if req.Session == "" {
+			return n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+		}
+		// the owner is a session key registered by SessionRegister
+		session, err := n.loadSession(ctx, req.Proposal.Owner)
+		if err != nil {
+			return err
+		}
+		if session == nil || session.Delegation.Issuer != req.Session {
+			return types.Wrapf(types.ErrInvalidDelegation, "%s is not a session key of %s", req.Proposal.Owner, req.Session)
+		}
+		req.Delegation = session
+	}
+
+	delegation := req.Delegation.Delegation
+	if delegation.Audience != req.Proposal.Owner {
+		return types.Wrapf(types.ErrInvalidDelegation, "delegated to %s, but the proposal is from %s", delegation.Audience, req.Proposal.Owner)
+	}
+	if delegation.Issuer == "all" {
+		return types.Wrapf(types.ErrInvalidDelegation, "invalid issuer %s", delegation.Issuer)
+	}
+	if delegation.Nonce == "" {
+		return types.Wrapf(types.ErrInvalidDelegation, "the delegation of %s has no nonce to revoke it by", delegation.Issuer)
+	}
+	err := delegation.Allows(capability, req.Proposal.Keyword, time.Now())
+	if err != nil {
+		return err
+	}
+	err = n.checkRevoked(ctx, &delegation)
+	if err != nil {
+		return err
+	}
+
+	err = n.validSignature(ctx, &delegation, delegation.Issuer, req.Delegation.JwsSignature)
+	if err != nil {
+		return types.Wrapf(types.ErrInvalidDelegation, "verify the delegation of %s: %v", delegation.Issuer, err)
+	}
+
+	return n.validSignature(ctx, &req.Proposal, req.Proposal.Owner, req.JwsSignature)
+}
+
+// validOrderProposal verifies the order proposal, which is submitted to the chain and always signed by the owner,
+// so it must be the issuer of the delegation if any. A delegate writes with the order proposals the owner signed
+// in advance only.
+func (n *Node) validOrderProposal(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal) error {
+	if req.Delegation != nil && orderProposal.Proposal.Owner != req.Delegation.Delegation.Issuer {
+		return types.Wrapf(types.ErrInvalidDelegation, "order of %s, but delegated by %s", orderProposal.Proposal.Owner, req.Delegation.Delegation.Issuer)
+	}
+
+	return n.validSignature(ctx, &orderProposal.Proposal, orderProposal.Proposal.Owner, orderProposal.JwsSignature)
+}
+
+// reservePlatform takes the commit of the order proposal from the quotas of its platform, the returned function
+// reverts it if the commit fails.
+func (n *Node) reservePlatform(ctx context.Context, orderProposal *types.OrderStoreProposal) (func(), error) {
+	proposal := orderProposal.Proposal
+	return n.platforms.Reserve(ctx, proposal.GroupId, proposal.DataId, proposal.Size_)
+}
+
+// stagingPaths returns the staging path of the node and the ones of the platforms staging apart.
+func (n *Node) stagingPaths() []string {
+	paths := []string{n.config().Transport.StagingPath}
+	for _, quota := range n.platforms.StagingQuotas() {
+		paths = append(paths, quota.Path())
+	}
+	return paths
+}
+
 func (n *Node) OrderStatus(ctx context.Context, id string) (types.OrderInfo, error) {
 	return n.gatewaySvc.OrderStatus(ctx, id)
 }
 
-func (n *Node) OrderList(ctx context.Context) ([]types.OrderInfo, error) {
-	return n.gatewaySvc.OrderList(ctx)
+func (n *Node) OrderList(ctx context.Context, filter types.OrderFilter) ([]types.OrderInfo, error) {
+	return n.gatewaySvc.OrderList(ctx, filter)
+}
+
+func (n *Node) OrderAudits(ctx context.Context, underReplicated bool) ([]types.AuditInfo, error) {
+	return n.gatewaySvc.OrderAudits(ctx, underReplicated)
 }
 
 func (n *Node) OrderFix(ctx context.Context, id string) error {
@@ -1,11 +1,26 @@
 package node
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sao-node/node/gateway"
+	"sao-node/node/model"
+	"sao-node/node/repo"
+	"sao-node/node/staging"
+	"sao-node/node/transport"
+	"sao-node/types"
+	"sao-node/utils"
+	"testing"
+
 	saodid "github.com/SaoNetwork/sao-did"
 	saokey "github.com/SaoNetwork/sao-did/key"
 	saodidtypes "github.com/SaoNetwork/sao-did/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
 	"github.com/dvsekhvalnov/jose2go/arrays"
-	"testing"
+	"github.com/stretchr/testify/require"
 )
 
 func TestKeyDidSign(t *testing.T) {
@@ -38,3 +53,97 @@ func TestKeyDidSign(t *testing.T) {
 	}
 
 }
+
+// TestOpenRepoDatastores opens the datastores of the node on a fresh repo, each of them must be created by the repo.
+func TestOpenRepoDatastores(t *testing.T) {
+	r, err := repo.NewRepo(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, r.Init("sao1chain"))
+	defer r.Close()
+
+	dss, err := openRepoDatastores(context.Background(), r)
+	require.NoError(t, err)
+	require.NotNil(t, dss.platform)
+	require.NotNil(t, dss.permission)
+}
+
+// fakeGatewaySvc answers the queries of the model manager from the orders in process, no model is on chain.
+type fakeGatewaySvc struct {
+	gateway.GatewaySvcApi
+	orders map[string]types.OrderInfo
+}
+
+func (g *fakeGatewaySvc) QueryMeta(_ context.Context, req *types.MetadataProposal, _ int64) (*types.Model, error) {
+	return nil, types.Wrapf(types.ErrQueryMetadataFailed, "%s not found", req.Proposal.Keyword)
+}
+
+func (g *fakeGatewaySvc) OrderStatus(_ context.Context, id string) (types.OrderInfo, error) {
+	return g.orders[id], nil
+}
+
+func TestModelCreateUpload(t *testing.T) {
+	ctx := context.Background()
+	n := newTestNode(t)
+	quota, err := staging.NewQuota(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	n.staging = quota
+	n.cipher, err = staging.NewCipher(make([]byte, staging.KeyLen), true)
+	require.NoError(t, err)
+	n.capacity = transport.NewCapacityGuard()
+	gatewaySvc := &fakeGatewaySvc{orders: make(map[string]types.OrderInfo)}
+	n.manager = &model.ModelManager{GatewaySvc: gatewaySvc}
+
+	owner := newTestDid(t)
+	req := &types.MetadataProposal{Proposal: saotypes.QueryProposal{Owner: owner.Id, Keyword: "data1"}}
+	req.JwsSignature = signTest(t, owner, &req.Proposal)
+	content := []byte("content")
+	contentCid, err := utils.CalculateCid(content)
+	require.NoError(t, err)
+	order := &types.OrderStoreProposal{Proposal: saotypes.Proposal{
+		Owner:  owner.Id,
+		DataId: "data1",
+		Cid:    contentCid.String(),
+		Size_:  uint64(len(content)),
+	}}
+	order.JwsSignature = signTest(t, owner, &order.Proposal)
+
+	// the first upload staged the content, the verified content is renamed into place
+	path, err := gateway.StageVerified(quota.Path(), owner.Id, order.Proposal.Cid, bytes.NewReader(content), n.cipher,
+		func(file *staging.File) error {
+			return verifyStaged(file, contentCid, order.Proposal.Size_)
+		})
+	require.NoError(t, err)
+	staged := func() []byte {
+		file, err := n.cipher.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		return data
+	}
+	require.Equal(t, content, staged())
+
+	// the upload is short of the size of the proposal
+	_, err = n.ModelCreateUpload(ctx, req, order, 0, bytes.NewReader(content[:4]))
+	require.True(t, types.ErrInvalidContent.Is(err))
+	require.Equal(t, int64(0), quota.Usage().Bytes)
+
+	// other bytes of the same size, the staged shard is kept
+	_, err = n.ModelCreateUpload(ctx, req, order, 0, bytes.NewReader([]byte("CONTENT")))
+	require.True(t, types.ErrInvalidCid.Is(err))
+	require.Equal(t, content, staged())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// a replay of the upload once its order is in process
+	gatewaySvc.orders["data1"] = types.OrderInfo{DataId: "data1", Owner: owner.Id, StagePath: path}
+	_, err = n.ModelCreateUpload(ctx, req, order, 0, bytes.NewReader([]byte("CONTENT")))
+	require.True(t, types.ErrConflictId.Is(err))
+	require.Equal(t, content, staged())
+
+	// low on disk space
+	n.capacity.SetFull("disk full")
+	_, err = n.ModelCreateUpload(ctx, req, order, 0, bytes.NewReader(content))
+	require.True(t, types.ErrNoCapacity.Is(err))
+}
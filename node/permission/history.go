@@ -0,0 +1,163 @@
+// Package permission keeps the local history of the permission updates of the data models seen on chain, so the
+// owners can audit who had access to a model and when. The chain only keeps the current readers and writers.
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("permission")
+
+var keyPrefix = datastore.NewKey("/permission")
+
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+// Change is a permission update of a data model, ReadonlyDids and ReadwriteDids are the ones after the update.
+type Change struct {
+	DataId string
+	Owner  string
+	Height int64
+	TxHash string
+	// when the update was seen by this node
+	Time int64
+
+	ReadonlyDids  []string
+	ReadwriteDids []string
+	// the dids whose access was raised, from none to read or write or from read to write, and the ones whose
+	// access was lowered, relative to the previous change recorded. Both are empty for the first change.
+	Granted []string
+	Revoked []string
+}
+
+// History records the changes per data model. A nil History drops all changes.
+type History struct {
+	ds datastore.Batching
+	lk sync.Mutex
+}
+
+func NewHistory(ds datastore.Batching) *History {
+	return &History{ds: ds}
+}
+
+// Record appends the change to the history of its data model.
+func (h *History) Record(ctx context.Context, change Change) error {
+	if h == nil {
+		return nil
+	}
+
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	changes, err := h.list(ctx, change.DataId)
+	if err != nil {
+		return err
+	}
+	if change.Time == 0 {
+		change.Time = time.Now().Unix()
+	}
+	// the updates are seen in the order of the chain, except the ones replayed after a restart
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Height < change.Height {
+			change.Granted, change.Revoked = diff(changes[i], change)
+			break
+		}
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return h.ds.Put(ctx, changeKey(change), data)
+}
+
+// List returns the changes of the data model in the order of the chain.
+func (h *History) List(ctx context.Context, dataId string) ([]Change, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	return h.list(ctx, dataId)
+}
+
+func (h *History) list(ctx context.Context, dataId string) ([]Change, error) {
+	results, err := h.ds.Query(ctx, query.Query{Prefix: keyPrefix.ChildString(dataId).String() + "/"})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var changes []Change
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		var change Change
+		if err := json.Unmarshal(r.Value, &change); err != nil {
+			log.Warnf("invalid permission change %s: %v", r.Key, err)
+			continue
+		}
+		changes = append(changes, change)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Height < changes[j].Height
+	})
+	return changes, nil
+}
+
+// Access returns the access of the did after the change, empty if it has none.
+func (c Change) Access(did string) string {
+	if did == c.Owner {
+		return AccessWrite
+	}
+	for _, d := range c.ReadwriteDids {
+		if d == did {
+			return AccessWrite
+		}
+	}
+	for _, d := range c.ReadonlyDids {
+		if d == did {
+			return AccessRead
+		}
+	}
+	return ""
+}
+
+func diff(prev Change, next Change) (granted []string, revoked []string) {
+	rank := map[string]int{"": 0, AccessRead: 1, AccessWrite: 2}
+	seen := make(map[string]bool)
+	for _, dids := range [][]string{prev.ReadwriteDids, prev.ReadonlyDids, next.ReadwriteDids, next.ReadonlyDids} {
+		for _, did := range dids {
+			if seen[did] {
+				continue
+			}
+			seen[did] = true
+
+			before, after := rank[prev.Access(did)], rank[next.Access(did)]
+			if after > before {
+				granted = append(granted, did)
+			} else if after < before {
+				revoked = append(revoked, did)
+			}
+		}
+	}
+	return granted, revoked
+}
+
+func changeKey(change Change) datastore.Key {
+	return keyPrefix.ChildString(change.DataId).ChildString(fmt.Sprintf("%020d-%s", change.Height, change.TxHash))
+}
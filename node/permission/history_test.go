@@ -0,0 +1,52 @@
+package permission
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	h := NewHistory(dssync.MutexWrap(datastore.NewMapDatastore()))
+
+	record := func(height int64, readonly []string, readwrite []string) {
+		require.NoError(t, h.Record(ctx, Change{
+			DataId:        "data1",
+			Owner:         "did:key:owner",
+			Height:        height,
+			TxHash:        "TX",
+			ReadonlyDids:  readonly,
+			ReadwriteDids: readwrite,
+		}))
+	}
+	record(10, []string{"did:key:alice"}, nil)
+	record(30, nil, []string{"did:key:bob"})
+	// recorded out of order, listed in the order of the chain
+	record(20, []string{"did:key:bob"}, []string{"did:key:alice"})
+	require.NoError(t, h.Record(ctx, Change{DataId: "data10", Owner: "did:key:other", Height: 5}))
+
+	changes, err := h.List(ctx, "data1")
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	require.Equal(t, []int64{10, 20, 30}, []int64{changes[0].Height, changes[1].Height, changes[2].Height})
+	require.NotZero(t, changes[0].Time)
+
+	// the diff is relative to the change before it on chain
+	require.Empty(t, changes[1].Revoked)
+	require.Equal(t, []string{"did:key:alice", "did:key:bob"}, changes[1].Granted)
+	// recorded before the change at 20
+	require.Equal(t, []string{"did:key:bob"}, changes[2].Granted)
+	require.Equal(t, []string{"did:key:alice"}, changes[2].Revoked)
+	require.Equal(t, AccessWrite, changes[2].Access("did:key:owner"))
+	require.Equal(t, "", changes[2].Access("did:key:alice"))
+
+	var nilHistory *History
+	require.NoError(t, nilHistory.Record(ctx, Change{DataId: "data1"}))
+	changes, err = nilHistory.List(ctx, "data1")
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
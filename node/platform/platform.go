@@ -0,0 +1,337 @@
+// Package platform lets applications share a gateway. A platform is the groupId of the proposals, the models each
+// platform commits through the gateway are counted against its quotas, its requests are rate limited, and it can be
+// given caches and a staging path of its own, so a platform can neither starve the others nor be served the models
+// cached for them.
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	apitypes "sao-node/api/types"
+	"sao-node/node/config"
+	"sao-node/node/staging"
+	"sao-node/types"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/time/rate"
+)
+
+var log = logging.Logger("platform")
+
+var keyPrefix = datastore.NewKey("/platform")
+
+// entry is a model committed through the gateway, persisted across restarts.
+type entry struct {
+	GroupId string
+	// size of the latest commit
+	Size uint64
+}
+
+type platform struct {
+	cfg     config.Platform
+	limiter *rate.Limiter
+	staging *staging.Quota
+
+	models    int
+	bytes     int64
+	throttled uint64
+}
+
+// Registry enforces the quotas of the configured platforms. A nil Registry never rejects and isolates nothing.
+type Registry struct {
+	ds             datastore.Batching
+	rejectUnlisted bool
+
+	lk        sync.Mutex
+	platforms map[string]*platform
+	// the models of the listed platforms by dataId
+	models map[string]entry
+}
+
+// NewRegistry creates the registry of the platforms, the staging paths of the isolated platforms are scanned with
+// ownerQuota as the quota of each owner, see staging.Quota. The models counted before are loaded from ds.
+func NewRegistry(ctx context.Context, cfg config.Platforms, ownerQuota int64, ds datastore.Batching) (*Registry, error) {
+	r := &Registry{
+		ds:             ds,
+		rejectUnlisted: cfg.RejectUnlisted,
+		platforms:      make(map[string]*platform),
+		models:         make(map[string]entry),
+	}
+	for _, c := range cfg.List {
+		if c.GroupId == "" {
+			return nil, types.Wrapf(types.ErrInvalidParameters, "a platform without groupId")
+		}
+		if _, exists := r.platforms[c.GroupId]; exists {
+			return nil, types.Wrapf(types.ErrInvalidParameters, "platform %s is listed twice", c.GroupId)
+		}
+		p := &platform{cfg: c}
+		if c.RequestRate > 0 {
+			burst := c.RequestBurst
+			if burst < 1 {
+				burst = 1
+			}
+			p.limiter = rate.NewLimiter(rate.Limit(c.RequestRate), burst)
+		}
+		if c.StagingPath != "" {
+			quota, err := staging.NewQuota(c.StagingPath, ownerQuota, c.StagingSpaceSize)
+			if err != nil {
+				return nil, err
+			}
+			p.staging = quota
+		}
+		r.platforms[c.GroupId] = p
+	}
+
+	results, err := ds.Query(ctx, query.Query{Prefix: keyPrefix.String()})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for res := range results.Next() {
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		var e entry
+		if err := json.Unmarshal(res.Value, &e); err != nil {
+			log.Warnf("invalid platform model %s: %v", res.Key, err)
+			continue
+		}
+		p := r.platforms[e.GroupId]
+		if p == nil {
+			// the platform is no longer listed
+			continue
+		}
+		r.models[datastore.RawKey(res.Key).BaseNamespace()] = e
+		p.models++
+		p.bytes += int64(e.Size)
+	}
+	return r, nil
+}
+
+// Allow takes a request of the platform from its rate, ErrBusy is returned if the rate is exceeded, and
+// ErrNoPermission if the platform is not listed while the unlisted ones are rejected.
+func (r *Registry) Allow(groupId string) error {
+	if r == nil {
+		return nil
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	p := r.platforms[groupId]
+	if p == nil {
+		if r.rejectUnlisted {
+			return types.Wrapf(types.ErrNoPermission, "platform %s is not served by this gateway", groupId)
+		}
+		return nil
+	}
+	if p.limiter != nil && !p.limiter.Allow() {
+		p.throttled++
+		return types.Wrapf(types.ErrBusy, "platform %s exceeds %v requests per second", groupId, p.cfg.RequestRate)
+	}
+	return nil
+}
+
+// Reserve counts the commit of size bytes to the model dataId of the platform, replacing its former commit, the
+// model is counted as a new one if it wasn't committed through the gateway before. ErrQuotaExceeded is returned if
+// the platform would exceed a quota. The returned function reverts the reservation, for the commit failed.
+func (r *Registry) Reserve(ctx context.Context, groupId string, dataId string, size uint64) (func(), error) {
+	if r == nil {
+		return func() {}, nil
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	former, exists := r.models[dataId]
+	if exists {
+		groupId = former.GroupId
+	}
+	p := r.platforms[groupId]
+	if p == nil {
+		if r.rejectUnlisted {
+			return nil, types.Wrapf(types.ErrNoPermission, "platform %s is not served by this gateway", groupId)
+		}
+		return func() {}, nil
+	}
+
+	models, bytes := p.models, p.bytes+int64(size)
+	if exists {
+		bytes -= int64(former.Size)
+	} else {
+		models++
+	}
+	if p.cfg.MaxModels > 0 && models > p.cfg.MaxModels {
+		return nil, types.Wrapf(types.ErrQuotaExceeded, "platform %s has %d models already", groupId, p.models)
+	}
+	if p.cfg.MaxBytes > 0 && bytes > p.cfg.MaxBytes {
+		return nil, types.Wrapf(types.ErrQuotaExceeded, "platform %s needs %d bytes but only %d left",
+			groupId, size, p.cfg.MaxBytes-p.bytes)
+	}
+	p.models, p.bytes = models, bytes
+	committed := entry{GroupId: groupId, Size: size}
+	r.models[dataId] = committed
+	r.save(ctx, dataId, committed)
+
+	return func() {
+		r.lk.Lock()
+		defer r.lk.Unlock()
+
+		if r.models[dataId] != committed {
+			// committed again since
+			return
+		}
+		p.bytes -= int64(size)
+		if exists {
+			p.bytes += int64(former.Size)
+			r.models[dataId] = former
+			r.save(ctx, dataId, former)
+		} else {
+			p.models--
+			delete(r.models, dataId)
+			r.remove(ctx, dataId)
+		}
+	}, nil
+}
+
+// Release uncounts the model dataId, which is deleted.
+func (r *Registry) Release(ctx context.Context, dataId string) {
+	if r == nil {
+		return
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	e, exists := r.models[dataId]
+	if !exists {
+		return
+	}
+	if p := r.platforms[e.GroupId]; p != nil {
+		p.models--
+		p.bytes -= int64(e.Size)
+	}
+	delete(r.models, dataId)
+	r.remove(ctx, dataId)
+}
+
+func (r *Registry) save(ctx context.Context, dataId string, e entry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Warnf("marshal platform model %s: %v", dataId, err)
+		return
+	}
+	if err := r.ds.Put(ctx, keyPrefix.ChildString(dataId), data); err != nil {
+		log.Warnf("put platform model %s: %v", dataId, err)
+	}
+}
+
+func (r *Registry) remove(ctx context.Context, dataId string) {
+	if err := r.ds.Delete(ctx, keyPrefix.ChildString(dataId)); err != nil {
+		log.Warnf("delete platform model %s: %v", dataId, err)
+	}
+}
+
+// CacheName returns the name of the cache of the account for the platform, the platforms isolating their caches
+// have caches of their own.
+func (r *Registry) CacheName(groupId string, account string) string {
+	if r == nil {
+		return account
+	}
+	if p := r.platforms[groupId]; p != nil && p.cfg.IsolateCache {
+		return groupId + "/" + account
+	}
+	return account
+}
+
+// CacheNames returns the names of all caches of the account, for evicting a model whose platform isn't known.
+func (r *Registry) CacheNames(account string) []string {
+	names := []string{account}
+	if r == nil {
+		return names
+	}
+	for groupId, p := range r.platforms {
+		if p.cfg.IsolateCache {
+			names = append(names, groupId+"/"+account)
+		}
+	}
+	return names
+}
+
+// CacheCapacity returns the capacity of the caches named by CacheName for the platform, def if not configured.
+func (r *Registry) CacheCapacity(groupId string, def int) int {
+	if r == nil {
+		return def
+	}
+	if p := r.platforms[groupId]; p != nil && p.cfg.IsolateCache && p.cfg.CacheCapacity > 0 {
+		return p.cfg.CacheCapacity
+	}
+	return def
+}
+
+// Staging returns the quota of the staging path of the platform, nil if it stages under the staging path of the
+// node.
+func (r *Registry) Staging(groupId string) *staging.Quota {
+	if r == nil {
+		return nil
+	}
+	if p := r.platforms[groupId]; p != nil {
+		return p.staging
+	}
+	return nil
+}
+
+// StagingQuotas returns the quotas of the staging paths of all platforms.
+func (r *Registry) StagingQuotas() []*staging.Quota {
+	if r == nil {
+		return nil
+	}
+	var quotas []*staging.Quota
+	for _, p := range r.platforms {
+		if p.staging != nil {
+			quotas = append(quotas, p.staging)
+		}
+	}
+	sort.Slice(quotas, func(i, j int) bool {
+		return quotas[i].Path() < quotas[j].Path()
+	})
+	return quotas
+}
+
+// Usage returns the usage of the listed platforms ordered by groupId.
+func (r *Registry) Usage() []apitypes.PlatformUsage {
+	if r == nil {
+		return nil
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	usages := make([]apitypes.PlatformUsage, 0, len(r.platforms))
+	for groupId, p := range r.platforms {
+		usage := apitypes.PlatformUsage{
+			GroupId:      groupId,
+			Models:       p.models,
+			MaxModels:    p.cfg.MaxModels,
+			Bytes:        p.bytes,
+			MaxBytes:     p.cfg.MaxBytes,
+			RequestRate:  p.cfg.RequestRate,
+			Throttled:    p.throttled,
+			IsolateCache: p.cfg.IsolateCache,
+		}
+		if p.staging != nil {
+			staged := p.staging.Usage()
+			usage.Staging = &staged
+		}
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].GroupId < usages[j].GroupId
+	})
+	return usages
+}
@@ -0,0 +1,88 @@
+package platform
+
+import (
+	"context"
+	"testing"
+
+	"sao-node/node/config"
+	"sao-node/types"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	cfg := config.Platforms{
+		List: []config.Platform{
+			{GroupId: "app1", MaxModels: 2, MaxBytes: 100, RequestRate: 1, RequestBurst: 2, IsolateCache: true, CacheCapacity: 10},
+			{GroupId: "app2", StagingPath: t.TempDir()},
+		},
+	}
+	r, err := NewRegistry(ctx, cfg, 0, ds)
+	require.NoError(t, err)
+
+	_, err = r.Reserve(ctx, "app1", "data1", 60)
+	require.NoError(t, err)
+	// over the bytes
+	_, err = r.Reserve(ctx, "app1", "data2", 50)
+	require.True(t, types.ErrQuotaExceeded.Is(err))
+	revert, err := r.Reserve(ctx, "app1", "data2", 40)
+	require.NoError(t, err)
+	// over the models
+	_, err = r.Reserve(ctx, "app1", "data3", 0)
+	require.True(t, types.ErrQuotaExceeded.Is(err))
+	// an update replaces the size of the model
+	_, err = r.Reserve(ctx, "app1", "data1", 10)
+	require.NoError(t, err)
+
+	// the failed commit is reverted
+	revert()
+	_, err = r.Reserve(ctx, "app1", "data3", 90)
+	require.NoError(t, err)
+	r.Release(ctx, "data1")
+	usage := r.Usage()
+	require.Equal(t, "app1", usage[0].GroupId)
+	require.Equal(t, 1, usage[0].Models)
+	require.Equal(t, int64(90), usage[0].Bytes)
+	require.Nil(t, usage[0].Staging)
+	require.NotNil(t, usage[1].Staging)
+
+	// the models are loaded again
+	r, err = NewRegistry(ctx, cfg, 0, ds)
+	require.NoError(t, err)
+	require.Equal(t, 1, r.Usage()[0].Models)
+	require.Equal(t, int64(90), r.Usage()[0].Bytes)
+
+	// the unlisted platforms are not limited
+	_, err = r.Reserve(ctx, "other", "data4", 1000)
+	require.NoError(t, err)
+	require.NoError(t, r.Allow("other"))
+	require.NoError(t, r.Allow("app1"))
+	require.NoError(t, r.Allow("app1"))
+	require.True(t, types.ErrBusy.Is(r.Allow("app1")))
+	require.Equal(t, uint64(1), r.Usage()[0].Throttled)
+
+	require.Equal(t, "app1/did1", r.CacheName("app1", "did1"))
+	require.Equal(t, "did1", r.CacheName("app2", "did1"))
+	require.Equal(t, []string{"did1", "app1/did1"}, r.CacheNames("did1"))
+	require.Equal(t, 10, r.CacheCapacity("app1", 100))
+	require.Equal(t, 100, r.CacheCapacity("app2", 100))
+	require.Nil(t, r.Staging("app1"))
+	require.Len(t, r.StagingQuotas(), 1)
+
+	cfg.RejectUnlisted = true
+	r, err = NewRegistry(ctx, cfg, 0, ds)
+	require.NoError(t, err)
+	require.True(t, types.ErrNoPermission.Is(r.Allow("other")))
+	_, err = r.Reserve(ctx, "other", "data5", 1)
+	require.True(t, types.ErrNoPermission.Is(err))
+
+	var nilRegistry *Registry
+	require.NoError(t, nilRegistry.Allow("app1"))
+	_, err = nilRegistry.Reserve(ctx, "app1", "data1", 1000)
+	require.NoError(t, err)
+	require.Equal(t, "did1", nilRegistry.CacheName("app1", "did1"))
+}
@@ -0,0 +1,257 @@
+// Package progress tracks the contents uploaded to the staging path of the gateway and distributed to the
+// storage nodes. The events of a content are sent to its subscribers and posted to the webhooks.
+package progress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sao-node/node/events"
+	"sao-node/types"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("progress")
+
+const (
+	// the events of the contents not updated for this long are dropped
+	retention     = time.Hour
+	postTimeout   = 30 * time.Second
+	subscriberBuf = 16
+	shardEventBuf = 64
+)
+
+type content struct {
+	event  types.ProgressEvent
+	stored map[string]struct{}
+	posted time.Time
+	subs   map[chan types.ProgressEvent]struct{}
+}
+
+// Tracker keeps the last event of each content. A nil Tracker drops all events.
+type Tracker struct {
+	webhooks []string
+	interval time.Duration
+	client   *http.Client
+
+	lk       sync.Mutex
+	contents map[string]*content
+}
+
+// NewTracker creates a tracker posting the events to the webhooks, at most once per interval for a content
+// except the last events of the phases.
+func NewTracker(webhooks []string, interval time.Duration) *Tracker {
+	return &Tracker{
+		webhooks: webhooks,
+		interval: interval,
+		client:   &http.Client{Timeout: postTimeout},
+		contents: make(map[string]*content),
+	}
+}
+
+// Staged records the bytes of the content received by the gateway, chunk is the count of the chunks received.
+func (t *Tracker) Staged(cid string, received uint64, total uint64, chunk int, chunks int) {
+	if t == nil {
+		return
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	t.update(cid, func(c *content) {
+		c.event.Phase = types.ProgressPhaseStaging
+		c.event.Bytes = received
+		c.event.Total = total
+		c.event.Chunk = chunk
+		c.event.Chunks = chunks
+	})
+}
+
+// Distributed records the shard of the content stored by the provider, out of the replicas of the order.
+func (t *Tracker) Distributed(cid string, provider string, size uint64, replicas int) {
+	if t == nil {
+		return
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	t.update(cid, func(c *content) {
+		if c.event.Phase != types.ProgressPhaseDistribution {
+			c.stored = make(map[string]struct{})
+		}
+		c.stored[provider] = struct{}{}
+		c.event.Phase = types.ProgressPhaseDistribution
+		c.event.Chunk = len(c.stored)
+		c.event.Chunks = replicas
+		c.event.Bytes = uint64(len(c.stored)) * size
+		c.event.Total = uint64(replicas) * size
+	})
+}
+
+// Consume records the shards completed by the storage nodes, published to the bus, until ctx is done.
+func (t *Tracker) Consume(ctx context.Context, bus *events.Bus) {
+	if t == nil {
+		return
+	}
+
+	bus.Consume(ctx, events.TopicShard, shardEventBuf, func(ev events.Event) {
+		if e := ev.(events.ShardEvent); e.Kind == events.ShardCompleted {
+			t.Distributed(e.Cid, e.Provider, e.Size, e.Replicas)
+		}
+	})
+}
+
+func (t *Tracker) update(cid string, f func(c *content)) {
+	now := time.Now()
+	for key, c := range t.contents {
+		if len(c.subs) == 0 && now.Sub(time.Unix(c.event.Time, 0)) > retention {
+			delete(t.contents, key)
+		}
+	}
+
+	c, exists := t.contents[cid]
+	if !exists {
+		c = &content{
+			event: types.ProgressEvent{Cid: cid},
+			subs:  make(map[chan types.ProgressEvent]struct{}),
+		}
+		t.contents[cid] = c
+	}
+	f(c)
+	c.event.Time = now.Unix()
+	c.event.Percent = 0
+	if c.event.Total > 0 {
+		c.event.Percent = float64(c.event.Bytes) * 100 / float64(c.event.Total)
+	} else if c.event.Chunks > 0 {
+		c.event.Percent = float64(c.event.Chunk) * 100 / float64(c.event.Chunks)
+	}
+
+	for ch := range c.subs {
+		send(ch, c.event)
+	}
+	if c.event.Done() {
+		for ch := range c.subs {
+			close(ch)
+		}
+		c.subs = make(map[chan types.ProgressEvent]struct{})
+	}
+
+	// the last event of each phase is always posted
+	last := c.event.Chunks > 0 && c.event.Chunk >= c.event.Chunks
+	if len(t.webhooks) > 0 && (last || now.Sub(c.posted) >= t.interval) {
+		c.posted = now
+		go t.post(c.event)
+	}
+}
+
+// send replaces the oldest pending event if the subscriber is slow, the latest event matters.
+func send(ch chan types.ProgressEvent, event types.ProgressEvent) {
+	select {
+	case ch <- event:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- event:
+	default:
+	}
+}
+
+func (t *Tracker) post(event types.ProgressEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("marshal progress of %s: %v", event.Cid, err)
+		return
+	}
+	for _, url := range t.webhooks {
+		ctx, cancel := context.WithTimeout(context.Background(), postTimeout)
+		err := postJSON(ctx, t.client, url, body)
+		cancel()
+		if err != nil {
+			log.Warnf("post progress of %s to %s: %v", event.Cid, url, err)
+		}
+	}
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Get returns the last event of the content, false if it is unknown.
+func (t *Tracker) Get(cid string) (types.ProgressEvent, bool) {
+	if t == nil {
+		return types.ProgressEvent{}, false
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	c, exists := t.contents[cid]
+	if !exists {
+		return types.ProgressEvent{}, false
+	}
+	return c.event, true
+}
+
+// Subscribe returns the events of the content starting from the last one, the channel is closed when the content
+// is stored by all storage nodes or ctx is done.
+func (t *Tracker) Subscribe(ctx context.Context, cid string) <-chan types.ProgressEvent {
+	ch := make(chan types.ProgressEvent, subscriberBuf)
+	if t == nil {
+		close(ch)
+		return ch
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	c, exists := t.contents[cid]
+	if !exists {
+		c = &content{
+			event: types.ProgressEvent{Cid: cid, Time: time.Now().Unix()},
+			subs:  make(map[chan types.ProgressEvent]struct{}),
+		}
+		t.contents[cid] = c
+	} else if c.event.Phase != "" {
+		ch <- c.event
+		if c.event.Done() {
+			close(ch)
+			return ch
+		}
+	}
+	c.subs[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		t.lk.Lock()
+		defer t.lk.Unlock()
+		if _, subscribed := c.subs[ch]; subscribed {
+			delete(c.subs, ch)
+			close(ch)
+		}
+	}()
+	return ch
+}
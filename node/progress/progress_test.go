@@ -0,0 +1,67 @@
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sao-node/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	posted := make(chan types.ProgressEvent, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event types.ProgressEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		posted <- event
+	}))
+	defer server.Close()
+
+	tracker := NewTracker([]string{server.URL}, time.Hour)
+	ctx := context.Background()
+
+	events := tracker.Subscribe(ctx, "cid")
+	tracker.Staged("cid", 50, 100, 1, 2)
+	event := <-events
+	require.Equal(t, types.ProgressPhaseStaging, event.Phase)
+	require.Equal(t, 50.0, event.Percent)
+	// the first event is posted, the next ones wait for the interval unless they end a phase
+	require.Equal(t, 50.0, (<-posted).Percent)
+
+	tracker.Staged("cid", 100, 100, 2, 2)
+	require.Equal(t, 100.0, (<-events).Percent)
+	require.Equal(t, 100.0, (<-posted).Percent)
+
+	tracker.Distributed("cid", "sp1", 100, 2)
+	tracker.Distributed("cid", "sp1", 100, 2)
+	event = <-events
+	require.Equal(t, types.ProgressPhaseDistribution, event.Phase)
+	require.Equal(t, 1, event.Chunk)
+	require.Equal(t, 50.0, (<-events).Percent)
+
+	tracker.Distributed("cid", "sp2", 100, 2)
+	event = <-events
+	require.True(t, event.Done())
+	require.Equal(t, uint64(200), event.Bytes)
+	_, open := <-events
+	require.False(t, open)
+	require.True(t, (<-posted).Done())
+
+	last, ok := tracker.Get("cid")
+	require.True(t, ok)
+	require.True(t, last.Done())
+	// a subscription after the end gets the last event only
+	events = tracker.Subscribe(ctx, "cid")
+	require.True(t, (<-events).Done())
+	_, open = <-events
+	require.False(t, open)
+
+	var nilTracker *Tracker
+	nilTracker.Staged("cid", 1, 1, 1, 1)
+	_, open = <-nilTracker.Subscribe(ctx, "cid")
+	require.False(t, open)
+}
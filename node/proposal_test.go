@@ -0,0 +1,159 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sao-node/node/config"
+	"sao-node/node/platform"
+	"sao-node/types"
+
+	saodid "github.com/SaoNetwork/sao-did"
+	saokey "github.com/SaoNetwork/sao-did/key"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/dvsekhvalnov/jose2go/arrays"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDid(t *testing.T) *saodid.DidManager {
+	seed, err := arrays.Random(32)
+	require.NoError(t, err)
+	provider, err := saokey.NewSecp256k1Provider(seed)
+	require.NoError(t, err)
+	didManager := saodid.NewDidManager(provider, saokey.NewKeyResolver())
+	_, err = didManager.Authenticate([]string{}, "")
+	require.NoError(t, err)
+	return &didManager
+}
+
+func signTest(t *testing.T, didManager *saodid.DidManager, proposal types.ConsensusProposal) saotypes.JwsSignature {
+	data, err := proposal.Marshal()
+	require.NoError(t, err)
+	jws, err := didManager.CreateJWS(data)
+	require.NoError(t, err)
+	return saotypes.JwsSignature{
+		Protected: jws.Signatures[0].Protected,
+		Signature: jws.Signatures[0].Signature,
+	}
+}
+
+func newTestNode(t *testing.T) *Node {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	platforms, err := platform.NewRegistry(ctx, config.Platforms{
+		List: []config.Platform{{GroupId: "app1", RequestRate: 1, RequestBurst: 1}},
+	}, 0, ds)
+	require.NoError(t, err)
+	return &Node{ctx: ctx, mds: ds, platforms: platforms}
+}
+
+func TestValidQueryProposalRate(t *testing.T) {
+	ctx := context.Background()
+	n := newTestNode(t)
+	owner := newTestDid(t)
+
+	// the forged requests don't take the rate of the platform
+	forged := &types.MetadataProposal{Proposal: saotypes.QueryProposal{Owner: owner.Id, Keyword: "data1", GroupId: "app1"}}
+	for i := 0; i < 3; i++ {
+		require.True(t, types.ErrInvalidSignature.Is(n.validQueryProposal(ctx, forged, types.CapModelLoad)))
+	}
+
+	req := &types.MetadataProposal{Proposal: saotypes.QueryProposal{Owner: owner.Id, Keyword: "data1", GroupId: "app1"}}
+	req.JwsSignature = signTest(t, owner, &req.Proposal)
+	require.NoError(t, n.validQueryProposal(ctx, req, types.CapModelLoad))
+	require.True(t, types.ErrBusy.Is(n.validQueryProposal(ctx, req, types.CapModelLoad)))
+}
+
+func delegatedRequest(t *testing.T, issuer *saodid.DidManager, audience *saodid.DidManager, delegation types.Delegation, keyword string) *types.MetadataProposal {
+	delegation.Issuer = issuer.Id
+	if delegation.Audience == "" {
+		delegation.Audience = audience.Id
+	}
+	req := &types.MetadataProposal{
+		Proposal: saotypes.QueryProposal{Owner: audience.Id, Keyword: keyword},
+		Delegation: &types.SignedDelegation{
+			Delegation:   delegation,
+			JwsSignature: signTest(t, issuer, &delegation),
+		},
+	}
+	req.JwsSignature = signTest(t, audience, &req.Proposal)
+	return req
+}
+
+func TestValidQueryProposalDelegation(t *testing.T) {
+	ctx := context.Background()
+	n := newTestNode(t)
+	owner, delegate := newTestDid(t), newTestDid(t)
+	now := time.Now()
+	delegation := types.Delegation{
+		Capabilities: []string{types.CapModelLoad, types.CapModelUpdate},
+		Resources:    []string{"data1"},
+		NotBefore:    now.Unix(),
+		Expiration:   now.Add(time.Hour).Unix(),
+		Nonce:        "nonce1",
+	}
+
+	require.NoError(t, n.validQueryProposal(ctx, delegatedRequest(t, owner, delegate, delegation, "data1"), types.CapModelLoad))
+
+	// the capability or the model is not delegated
+	err := n.validQueryProposal(ctx, delegatedRequest(t, owner, delegate, delegation, "data1"), types.CapModelCreate)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+	err = n.validQueryProposal(ctx, delegatedRequest(t, owner, delegate, delegation, "data2"), types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+
+	// expired
+	expired := delegation
+	expired.Expiration = now.Add(-time.Minute).Unix()
+	err = n.validQueryProposal(ctx, delegatedRequest(t, owner, delegate, expired, "data1"), types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+
+	// delegated to another did
+	other := delegation
+	other.Audience = newTestDid(t).Id
+	err = n.validQueryProposal(ctx, delegatedRequest(t, owner, delegate, other, "data1"), types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+
+	// signed by the delegate, not by the issuer it claims
+	forged := delegatedRequest(t, owner, delegate, delegation, "data1")
+	forged.Delegation.JwsSignature = signTest(t, delegate, &forged.Delegation.Delegation)
+	err = n.validQueryProposal(ctx, forged, types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+
+	// a delegation can't be revoked without a nonce
+	unrevocable := delegation
+	unrevocable.Nonce = ""
+	err = n.validQueryProposal(ctx, delegatedRequest(t, owner, delegate, unrevocable, "data1"), types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+
+	revoke := types.ShareRevoke{Issuer: owner.Id, Nonce: "nonce1", Expiration: delegation.Expiration}
+	require.NoError(t, n.ModelShareRevoke(ctx, &types.ShareRevokeProposal{Proposal: revoke, JwsSignature: signTest(t, owner, &revoke)}))
+	err = n.validQueryProposal(ctx, delegatedRequest(t, owner, delegate, delegation, "data1"), types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+}
+
+func TestValidOrderProposalDelegation(t *testing.T) {
+	ctx := context.Background()
+	n := newTestNode(t)
+	owner, delegate := newTestDid(t), newTestDid(t)
+	req := delegatedRequest(t, owner, delegate, types.Delegation{
+		Capabilities: []string{types.CapModelCreate},
+		Resources:    []string{"data1"},
+		Expiration:   time.Now().Add(time.Hour).Unix(),
+		Nonce:        "nonce1",
+	}, "data1")
+
+	// the order proposal is signed by the owner in advance
+	order := &types.OrderStoreProposal{Proposal: saotypes.Proposal{Owner: owner.Id, DataId: "data1"}}
+	order.JwsSignature = signTest(t, owner, &order.Proposal)
+	require.NoError(t, n.validOrderProposal(ctx, req, order))
+
+	// the delegate can't sign the orders of the owner
+	order.JwsSignature = signTest(t, delegate, &order.Proposal)
+	require.True(t, types.ErrInvalidSignature.Is(n.validOrderProposal(ctx, req, order)))
+	order = &types.OrderStoreProposal{Proposal: saotypes.Proposal{Owner: delegate.Id, DataId: "data1"}}
+	order.JwsSignature = signTest(t, delegate, &order.Proposal)
+	require.True(t, types.ErrInvalidDelegation.Is(n.validOrderProposal(ctx, req, order)))
+}
@@ -0,0 +1,226 @@
+package node
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sao-node/node/config"
+	"sao-node/types"
+	"strings"
+	"syscall"
+
+	logging "github.com/ipfs/go-log/v2"
+	"go.uber.org/zap/zapcore"
+)
+
+// MonitorReload reloads the node config every time SIGHUP is received, until ctx is done.
+func MonitorReload(ctx context.Context, n *Node) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigCh)
+
+		for {
+			select {
+			case <-sigCh:
+				log.Info("received SIGHUP, reloading config...")
+				if err := n.ReloadConfig(ctx); err != nil {
+					log.Errorf("reload config failed: %v", err)
+				} else {
+					log.Info("config reloaded")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// ReloadConfig reads the config file again and applies the sections which can be changed at runtime:
+// log levels, cache sizes and stream limits. Other changes only take effect after restarting the node.
+// Nothing is applied if the new config is invalid, and the running config is restored if applying fails.
+func (n *Node) ReloadConfig(ctx context.Context) error {
+	c, err := n.repo.Config()
+	if err != nil {
+		return err
+	}
+	cfg, ok := c.(*config.Node)
+	if !ok {
+		return types.Wrapf(types.ErrDecodeConfigFailed, "invalid config for repo, got: %T", c)
+	}
+
+	err = validateReloadable(cfg)
+	if err != nil {
+		return err
+	}
+
+	// the reloads are applied one by one, the readers of the config see either the running or the new one
+	n.reloadLk.Lock()
+	defer n.reloadLk.Unlock()
+
+	running := n.config()
+	next, err := n.applyReloadable(running, cfg)
+	if err != nil {
+		if _, rerr := n.applyReloadable(cfg, running); rerr != nil {
+			log.Errorf("rollback config failed: %v", rerr)
+		}
+		return err
+	}
+
+	n.cfgLk.Lock()
+	n.cfg = next
+	n.cfgLk.Unlock()
+	return nil
+}
+
+// config returns the running config, it must not be modified.
+func (n *Node) config() *config.Node {
+	n.cfgLk.RLock()
+	defer n.cfgLk.RUnlock()
+
+	return n.cfg
+}
+
+func validateReloadable(cfg *config.Node) error {
+	if err := validateLogConfig(cfg.Log); err != nil {
+		return err
+	}
+
+	if cfg.Cache.CacheCapacity < -1 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Cache.CacheCapacity %d", cfg.Cache.CacheCapacity)
+	}
+	if cfg.Cache.ContentLimit < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Cache.ContentLimit %d", cfg.Cache.ContentLimit)
+	}
+	if cfg.Cache.CacheTTL < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Cache.CacheTTL %v", cfg.Cache.CacheTTL)
+	}
+	if cfg.Cache.CacheMaxBytes < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Cache.CacheMaxBytes %d", cfg.Cache.CacheMaxBytes)
+	}
+
+	if cfg.Libp2p.MaxStreamsPerProtocol < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Libp2p.MaxStreamsPerProtocol %d", cfg.Libp2p.MaxStreamsPerProtocol)
+	}
+	if cfg.Libp2p.MaxStreamsPerPeer < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Libp2p.MaxStreamsPerPeer %d", cfg.Libp2p.MaxStreamsPerPeer)
+	}
+	if cfg.Libp2p.MaxStreamsPerProtocol > 0 && cfg.Libp2p.StreamQueueTimeout <= 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Libp2p.StreamQueueTimeout %v", cfg.Libp2p.StreamQueueTimeout)
+	}
+	if cfg.Libp2p.MaxRequestSize < 0 || cfg.Libp2p.MaxContentSize < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Libp2p.MaxRequestSize %d or Libp2p.MaxContentSize %d", cfg.Libp2p.MaxRequestSize, cfg.Libp2p.MaxContentSize)
+	}
+	if cfg.Libp2p.PeerStreamRate < 0 || (cfg.Libp2p.PeerStreamRate > 0 && cfg.Libp2p.PeerStreamBurst <= 0) {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid Libp2p.PeerStreamRate %v or Libp2p.PeerStreamBurst %d", cfg.Libp2p.PeerStreamRate, cfg.Libp2p.PeerStreamBurst)
+	}
+
+	return nil
+}
+
+func validateLogConfig(cfg config.Log) error {
+	if cfg.Level != "" {
+		if _, err := logging.LevelFromString(cfg.Level); err != nil {
+			return types.Wrapf(types.ErrInvalidConfig, "invalid Log.Level %s", cfg.Level)
+		}
+	}
+
+	subsystems := make(map[string]struct{})
+	for _, s := range logging.GetSubsystems() {
+		subsystems[s] = struct{}{}
+	}
+	for _, sl := range cfg.SubsystemLevels {
+		kv := strings.SplitN(sl, "=", 2)
+		if len(kv) != 2 {
+			return types.Wrapf(types.ErrInvalidConfig, "invalid Log.SubsystemLevels %s, should be subsystem=level", sl)
+		}
+		if _, exists := subsystems[kv[0]]; !exists {
+			return types.Wrapf(types.ErrInvalidConfig, "unknown log subsystem %s", kv[0])
+		}
+		if _, err := logging.LevelFromString(kv[1]); err != nil {
+			return types.Wrapf(types.ErrInvalidConfig, "invalid log level %s of %s", kv[1], kv[0])
+		}
+	}
+
+	return nil
+}
+
+// logLevels returns the level of every log subsystem.
+func logLevels() map[string]string {
+	levels := make(map[string]string)
+	for _, s := range logging.GetSubsystems() {
+		core := logging.Logger(s).Desugar().Core()
+		for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+			if core.Enabled(l) {
+				levels[s] = l.String()
+				break
+			}
+		}
+	}
+	return levels
+}
+
+// applyLogConfig sets the log levels of the config over the base levels, so a level removed from the config
+// reverts to its base level.
+func applyLogConfig(base map[string]string, cfg config.Log) error {
+	for s, level := range base {
+		if err := logging.SetLogLevel(s, level); err != nil {
+			return types.Wrapf(types.ErrInvalidConfig, "set log level of %s: %v", s, err)
+		}
+	}
+	if cfg.Level != "" {
+		if err := logging.SetLogLevel("*", cfg.Level); err != nil {
+			return types.Wrap(types.ErrInvalidConfig, err)
+		}
+	}
+	for _, sl := range cfg.SubsystemLevels {
+		kv := strings.SplitN(sl, "=", 2)
+		if err := logging.SetLogLevel(kv[0], kv[1]); err != nil {
+			return types.Wrapf(types.ErrInvalidConfig, "set log level of %s: %v", kv[0], err)
+		}
+	}
+	return nil
+}
+
+// applyReloadable applies the reloadable sections of cfg changed from the running config, and returns the running
+// config updated with them.
+func (n *Node) applyReloadable(running *config.Node, cfg *config.Node) (*config.Node, error) {
+	next := *running
+
+	err := applyLogConfig(n.baseLogLevels, cfg.Log)
+	if err != nil {
+		return nil, err
+	}
+	next.Log = cfg.Log
+
+	n.limiter.SetLimits(cfg.Libp2p.MaxStreamsPerProtocol, cfg.Libp2p.MaxStreamsPerPeer, cfg.Libp2p.StreamQueueTimeout)
+	next.Libp2p.MaxStreamsPerProtocol = cfg.Libp2p.MaxStreamsPerProtocol
+	next.Libp2p.MaxStreamsPerPeer = cfg.Libp2p.MaxStreamsPerPeer
+	next.Libp2p.StreamQueueTimeout = cfg.Libp2p.StreamQueueTimeout
+	n.limiter.SetRequestLimits(cfg.Libp2p.MaxRequestSize, cfg.Libp2p.MaxContentSize, cfg.Libp2p.StreamReadTimeout)
+	next.Libp2p.MaxRequestSize = cfg.Libp2p.MaxRequestSize
+	next.Libp2p.MaxContentSize = cfg.Libp2p.MaxContentSize
+	next.Libp2p.StreamReadTimeout = cfg.Libp2p.StreamReadTimeout
+	// setting the rate refills the token buckets of all peers
+	if cfg.Libp2p.PeerStreamRate != running.Libp2p.PeerStreamRate || cfg.Libp2p.PeerStreamBurst != running.Libp2p.PeerStreamBurst {
+		n.limiter.SetPeerRate(cfg.Libp2p.PeerStreamRate, cfg.Libp2p.PeerStreamBurst)
+	}
+	next.Libp2p.PeerStreamRate = cfg.Libp2p.PeerStreamRate
+	next.Libp2p.PeerStreamBurst = cfg.Libp2p.PeerStreamBurst
+
+	if n.manager != nil {
+		// the model manager keeps its own copy, which the requests read
+		err = n.manager.ReloadCacheConfig(cfg.Cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+	next.Cache.EnableCache = cfg.Cache.EnableCache
+	next.Cache.CacheCapacity = cfg.Cache.CacheCapacity
+	next.Cache.ContentLimit = cfg.Cache.ContentLimit
+	next.Cache.CacheTTL = cfg.Cache.CacheTTL
+	next.Cache.CacheMaxBytes = cfg.Cache.CacheMaxBytes
+
+	return &next, nil
+}
@@ -0,0 +1,47 @@
+package node
+
+import (
+	"sao-node/node/config"
+	"sao-node/node/transport"
+	"testing"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyReloadable(t *testing.T) {
+	require.NoError(t, logging.SetLogLevel("node", "info"))
+	n := &Node{
+		limiter:       transport.NewStreamLimiter(0, 0, 0),
+		baseLogLevels: logLevels(),
+	}
+	running := config.DefaultSaoNode()
+	n.cfg = running
+
+	cfg := config.DefaultSaoNode()
+	cfg.Log.SubsystemLevels = []string{"node=debug"}
+	cfg.Libp2p.PeerStreamRate = running.Libp2p.PeerStreamRate + 10
+	next, err := n.applyReloadable(n.config(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, "debug", logLevels()["node"])
+	require.Equal(t, cfg.Log, next.Log)
+	require.Equal(t, cfg.Libp2p.PeerStreamRate, next.Libp2p.PeerStreamRate)
+	// the running config is replaced, not modified under its readers
+	require.Empty(t, running.Log.SubsystemLevels)
+	require.Equal(t, config.DefaultSaoNode().Libp2p.PeerStreamRate, running.Libp2p.PeerStreamRate)
+
+	// a level removed from the config reverts to the level before the config
+	cfg = config.DefaultSaoNode()
+	next, err = n.applyReloadable(next, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "info", logLevels()["node"])
+	require.Empty(t, next.Log.SubsystemLevels)
+
+	cfg.Log.Level = "error"
+	_, err = n.applyReloadable(next, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "error", logLevels()["node"])
+	_, err = n.applyReloadable(next, config.DefaultSaoNode())
+	require.NoError(t, err)
+	require.Equal(t, "info", logLevels()["node"])
+}
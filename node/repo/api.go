@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"strings"
+
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	fsAPI      = "api"
+	fsAPIToken = "token"
+	fsAuditLog = "audit.log"
+	fsAcme     = "acme"
+	// the shards being migrated to this node
+	fsMigrations = "migrations"
+)
+
+// SetAPIEndpoint records the RPC endpoint of the running node, so commands on the same repo can find it.
+func (r *Repo) SetAPIEndpoint(ma multiaddr.Multiaddr) error {
+	err := os.WriteFile(filepath.Join(r.Path, fsAPI), []byte(ma.String()), 0644)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+// APIEndpoint returns the RPC endpoint of the running node, ErrNoAPIEndpoint is returned if it is not running.
+func (r *Repo) APIEndpoint() (multiaddr.Multiaddr, error) {
+	data, err := os.ReadFile(filepath.Join(r.Path, fsAPI))
+	if os.IsNotExist(err) {
+		return nil, ErrNoAPIEndpoint
+	} else if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	ma, err := multiaddr.NewMultiaddr(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidServerAddress, "api file: %v", err)
+	}
+	return ma, nil
+}
+
+// RemoveAPIEndpoint is called when the node stops.
+func (r *Repo) RemoveAPIEndpoint() error {
+	err := os.Remove(filepath.Join(r.Path, fsAPI))
+	if err != nil && !os.IsNotExist(err) {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+// SetAPIToken stores the admin token of the node API, readable by the repo owner only.
+func (r *Repo) SetAPIToken(token []byte) error {
+	err := os.WriteFile(filepath.Join(r.Path, fsAPIToken), token, 0600)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+func (r *Repo) APIToken() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(r.Path, fsAPIToken))
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return []byte(strings.TrimSpace(string(data))), nil
+}
+
+// AcmeCachePath returns the directory of the ACME account and the certificates obtained.
+func (r *Repo) AcmeCachePath() string {
+	return filepath.Join(r.Path, fsAcme)
+}
+
+// AuditLogPath returns the path of the API audit log.
+func (r *Repo) AuditLogPath() string {
+	return filepath.Join(r.Path, fsAuditLog)
+}
+
+// MigrationPath returns the directory of the shards being migrated to this node.
+func (r *Repo) MigrationPath() string {
+	return filepath.Join(r.Path, fsMigrations)
+}
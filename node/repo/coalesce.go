@@ -0,0 +1,206 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// the writes pending before they are committed without waiting for the interval
+const coalesceMaxPending = 1024
+
+type pendingWrite struct {
+	value   []byte
+	deleted bool
+}
+
+// CoalescingDatastore buffers the writes to a datastore and commits them in one batch every interval, so a burst
+// of state updates costs one sync of the backend instead of one per record. The writes pending are seen by the
+// reads, the queries commit them first. The writes not committed yet are lost if the process crashes, Close
+// commits them on a clean shutdown.
+type CoalescingDatastore struct {
+	datastore.Batching
+
+	lk      sync.Mutex
+	pending map[datastore.Key]pendingWrite
+	// the writes being committed by flush, still seen by the reads until committed
+	flushing map[datastore.Key]pendingWrite
+	// serializes the flushes, held without lk while the batch is committed
+	flushLk sync.Mutex
+
+	closeOnce sync.Once
+	closing   chan struct{}
+	done      chan struct{}
+}
+
+// NewCoalescingDatastore wraps ds, the writes are committed every interval.
+func NewCoalescingDatastore(ds datastore.Batching, interval time.Duration) *CoalescingDatastore {
+	c := &CoalescingDatastore{
+		Batching: ds,
+		pending:  make(map[datastore.Key]pendingWrite),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go c.loop(interval)
+	return c
+}
+
+func (c *CoalescingDatastore) loop(interval time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(context.Background()); err != nil {
+				log.Errorf("commit the coalesced writes: %v", err)
+			}
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+func (c *CoalescingDatastore) write(ctx context.Context, key datastore.Key, w pendingWrite) error {
+	c.lk.Lock()
+	c.pending[key] = w
+	full := len(c.pending) >= coalesceMaxPending
+	c.lk.Unlock()
+
+	if full {
+		return c.Flush(ctx)
+	}
+	return nil
+}
+
+func (c *CoalescingDatastore) Put(ctx context.Context, key datastore.Key, value []byte) error {
+	// the callers may reuse the buffer
+	return c.write(ctx, key, pendingWrite{value: append([]byte(nil), value...)})
+}
+
+func (c *CoalescingDatastore) Delete(ctx context.Context, key datastore.Key) error {
+	return c.write(ctx, key, pendingWrite{deleted: true})
+}
+
+// lookup returns the write pending for the key, if any.
+func (c *CoalescingDatastore) lookup(key datastore.Key) (pendingWrite, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if w, ok := c.pending[key]; ok {
+		return w, true
+	}
+	w, ok := c.flushing[key]
+	return w, ok
+}
+
+func (c *CoalescingDatastore) Get(ctx context.Context, key datastore.Key) ([]byte, error) {
+	if w, ok := c.lookup(key); ok {
+		if w.deleted {
+			return nil, datastore.ErrNotFound
+		}
+		return append([]byte(nil), w.value...), nil
+	}
+	return c.Batching.Get(ctx, key)
+}
+
+func (c *CoalescingDatastore) Has(ctx context.Context, key datastore.Key) (bool, error) {
+	if w, ok := c.lookup(key); ok {
+		return !w.deleted, nil
+	}
+	return c.Batching.Has(ctx, key)
+}
+
+func (c *CoalescingDatastore) GetSize(ctx context.Context, key datastore.Key) (int, error) {
+	if w, ok := c.lookup(key); ok {
+		if w.deleted {
+			return -1, datastore.ErrNotFound
+		}
+		return len(w.value), nil
+	}
+	return c.Batching.GetSize(ctx, key)
+}
+
+func (c *CoalescingDatastore) Query(ctx context.Context, q query.Query) (query.Results, error) {
+	if err := c.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return c.Batching.Query(ctx, q)
+}
+
+func (c *CoalescingDatastore) Sync(ctx context.Context, prefix datastore.Key) error {
+	if err := c.Flush(ctx); err != nil {
+		return err
+	}
+	return c.Batching.Sync(ctx, prefix)
+}
+
+// Batch returns a batch adding its writes to the pending ones on commit, so they are not reordered with them.
+func (c *CoalescingDatastore) Batch(_ context.Context) (datastore.Batch, error) {
+	return datastore.NewBasicBatch(c), nil
+}
+
+// Flush commits the pending writes in one batch. The writes failed are pending again, unless overwritten since.
+func (c *CoalescingDatastore) Flush(ctx context.Context) error {
+	c.flushLk.Lock()
+	defer c.flushLk.Unlock()
+
+	c.lk.Lock()
+	writes := c.pending
+	if len(writes) == 0 {
+		c.lk.Unlock()
+		return nil
+	}
+	c.pending = make(map[datastore.Key]pendingWrite)
+	c.flushing = writes
+	c.lk.Unlock()
+
+	err := c.commit(ctx, writes)
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.flushing = nil
+	if err != nil {
+		for key, w := range writes {
+			if _, ok := c.pending[key]; !ok {
+				c.pending[key] = w
+			}
+		}
+	}
+	return err
+}
+
+func (c *CoalescingDatastore) commit(ctx context.Context, writes map[datastore.Key]pendingWrite) error {
+	batch, err := c.Batching.Batch(ctx)
+	if err != nil {
+		return err
+	}
+	for key, w := range writes {
+		if w.deleted {
+			err = batch.Delete(ctx, key)
+		} else {
+			err = batch.Put(ctx, key, w.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Commit(ctx)
+}
+
+// Close commits the pending writes and closes the wrapped datastore.
+func (c *CoalescingDatastore) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closing)
+	})
+	<-c.done
+
+	if err := c.Flush(context.Background()); err != nil {
+		return err
+	}
+	return c.Batching.Close()
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescingDatastore(t *testing.T) {
+	ctx := context.Background()
+	base := dssync.MutexWrap(datastore.NewMapDatastore())
+	require.NoError(t, base.Put(ctx, datastore.NewKey("/old"), []byte("old")))
+	ds := NewCoalescingDatastore(base, time.Hour)
+
+	buf := []byte("v1")
+	require.NoError(t, ds.Put(ctx, datastore.NewKey("/a"), buf))
+	buf[1] = '2'
+	require.NoError(t, ds.Delete(ctx, datastore.NewKey("/old")))
+
+	// pending, but seen by the reads
+	exists, err := base.Has(ctx, datastore.NewKey("/a"))
+	require.NoError(t, err)
+	require.False(t, exists)
+	value, err := ds.Get(ctx, datastore.NewKey("/a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), value)
+	_, err = ds.Get(ctx, datastore.NewKey("/old"))
+	require.Equal(t, datastore.ErrNotFound, err)
+
+	batch, err := ds.Batch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, batch.Put(ctx, datastore.NewKey("/b"), []byte("b")))
+	require.NoError(t, batch.Commit(ctx))
+
+	// the query commits the pending writes first
+	results, err := ds.Query(ctx, query.Query{KeysOnly: true})
+	require.NoError(t, err)
+	entries, err := results.Rest()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	value, err = base.Get(ctx, datastore.NewKey("/b"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), value)
+
+	require.NoError(t, ds.Put(ctx, datastore.NewKey("/c"), []byte("c")))
+	require.NoError(t, ds.Close())
+	exists, err = base.Has(ctx, datastore.NewKey("/c"))
+	require.NoError(t, err)
+	require.True(t, exists)
+}
@@ -1,12 +1,15 @@
 package repo
 
 import (
+	"context"
 	"os"
-	"path/filepath"
+	"sao-node/node/config"
 	"sao-node/types"
+	"strings"
 
 	dgbadger "github.com/dgraph-io/badger/v2"
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
 	badger "github.com/ipfs/go-ds-badger2"
 	levelds "github.com/ipfs/go-ds-leveldb"
 	measure "github.com/ipfs/go-ds-measure"
@@ -14,18 +17,47 @@ import (
 )
 
 const (
-	dsNsMetadata  = "metadata"
-	dsNsOrder     = "order"
-	dsNsTransport = "transport"
+	dsNsMetadata   = "metadata"
+	dsNsOrder      = "order"
+	dsNsTransport  = "transport"
+	dsNsCache      = "cache"
+	dsNsReputation = "reputation"
+	dsNsStore      = "store"
+	dsNsPermission = "permission"
+	dsNsPlatform   = "platform"
 )
 
+const (
+	DsBackendLevelDB = "leveldb"
+	DsBackendBadger  = "badger"
+	DsBackendPebble  = "pebble"
+)
+
+// the backend of a datastore is recorded in <ns>.backend next to its directory
+const fsBackendSuffix = ".backend"
+
+const convertBatchSize = 1024
+
 type dsCtor func(path string, readonly bool) (datastore.Batching, error)
 
-var fsDatastores = map[string]dsCtor{
-	dsNsMetadata: levelDs,
+// pebble is not built in yet, it is rejected by checkBackend
+var dsBackends = map[string]dsCtor{
+	DsBackendLevelDB: levelDs,
+	DsBackendBadger:  badgerDs,
+}
+
+// fsDatastores are the default backends, which are also the backends of the datastores
+// created before the backend was recorded.
+var fsDatastores = map[string]string{
+	dsNsMetadata: DsBackendLevelDB,
 	// Those need to be fast for large writes... but also need a really good GC
-	dsNsOrder:     badgerDs,
-	dsNsTransport: levelDs,
+	dsNsOrder:      DsBackendBadger,
+	dsNsTransport:  DsBackendLevelDB,
+	dsNsCache:      DsBackendLevelDB,
+	dsNsReputation: DsBackendLevelDB,
+	dsNsStore:      DsBackendLevelDB,
+	dsNsPermission: DsBackendLevelDB,
+	dsNsPlatform:   DsBackendLevelDB,
 }
 
 func levelDs(path string, readonly bool) (datastore.Batching, error) {
@@ -46,24 +78,252 @@ func badgerDs(path string, readonly bool) (datastore.Batching, error) {
 	return badger.NewDatastore(path, &opts)
 }
 
+// datastoreBackends returns the configured backend of each datastore.
+func (fsr *Repo) datastoreBackends() (map[string]string, error) {
+	cfg, err := fsr.nodeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	backends := make(map[string]string)
+	for ns, backend := range fsDatastores {
+		backends[ns] = backend
+	}
+	if cfg.Datastore.MetadataBackend != "" {
+		backends[dsNsMetadata] = strings.ToLower(cfg.Datastore.MetadataBackend)
+	}
+	if cfg.Datastore.OrderBackend != "" {
+		backends[dsNsOrder] = strings.ToLower(cfg.Datastore.OrderBackend)
+	}
+
+	for ns, backend := range backends {
+		if err := checkBackend(ns, backend); err != nil {
+			return nil, err
+		}
+	}
+	return backends, nil
+}
+
+// checkBackend checks the backend of the datastore ns is built in.
+func checkBackend(ns string, backend string) error {
+	if backend == DsBackendPebble {
+		return types.Wrapf(types.ErrInvalidConfig, "pebble backend of %s datastore is not included in this build, use %s or %s", ns, DsBackendLevelDB, DsBackendBadger)
+	}
+	if _, exists := dsBackends[backend]; !exists {
+		return types.Wrapf(types.ErrInvalidConfig, "unknown backend %s of %s datastore", backend, ns)
+	}
+	return nil
+}
+
+func (fsr *Repo) nodeConfig() (*config.Node, error) {
+	c, err := fsr.Config()
+	if err != nil {
+		return nil, err
+	}
+	cfg, ok := c.(*config.Node)
+	if !ok {
+		return nil, types.Wrapf(types.ErrDecodeConfigFailed, "invalid config for repo, got: %T", c)
+	}
+	return cfg, nil
+}
+
+// currentBackend returns the backend of the datastore on disk, false is returned if the datastore is not created yet.
+func (fsr *Repo) currentBackend(ns string) (string, bool, error) {
+	data, err := os.ReadFile(fsr.join(fsDatastore, ns+fsBackendSuffix))
+	if err == nil {
+		return strings.TrimSpace(string(data)), true, nil
+	} else if !os.IsNotExist(err) {
+		return "", false, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	_, err = os.Stat(fsr.join(fsDatastore, ns))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	return fsDatastores[ns], true, nil
+}
+
+func (fsr *Repo) setCurrentBackend(ns string, backend string) error {
+	err := os.WriteFile(fsr.join(fsDatastore, ns+fsBackendSuffix), []byte(backend), 0644)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+// pendingConversions returns the datastores whose backend on disk differs from the configured one,
+// mapped to the [current, configured] backends.
+func (fsr *Repo) pendingConversions() (map[string][2]string, error) {
+	backends, err := fsr.datastoreBackends()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string][2]string)
+	for ns, backend := range backends {
+		current, exists, err := fsr.currentBackend(ns)
+		if err != nil {
+			return nil, err
+		}
+		if exists && current != backend {
+			pending[ns] = [2]string{current, backend}
+		}
+	}
+	return pending, nil
+}
+
 func (fsr *Repo) openDatastores(readonly bool) (map[string]datastore.Batching, error) {
 	if err := os.MkdirAll(fsr.join(fsDatastore), 0755); err != nil {
 		return nil, types.Wrapf(types.ErrCreateDirFailed, "mkdir %s: %v", fsr.join(fsDatastore), err)
 	}
 
+	backends, err := fsr.datastoreBackends()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := fsr.nodeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	created := make(map[string]bool)
+	for p, backend := range backends {
+		current, exists, err := fsr.currentBackend(p)
+		if err != nil {
+			return nil, err
+		}
+		if exists && current != backend {
+			return nil, types.Wrapf(types.ErrInvalidConfig, "%s datastore is %s but configured as %s, run 'saonode repo migrate' to convert it", p, current, backend)
+		}
+		created[p] = !exists
+	}
+
 	out := map[string]datastore.Batching{}
+	closeAll := func() {
+		for _, ds := range out {
+			ds.Close()
+		}
+	}
 
-	for p, ctor := range fsDatastores {
+	for p, backend := range backends {
 		// TODO: optimization: don't init datastores we don't need
-		ds, err := ctor(fsr.join(filepath.Join(fsDatastore, p)), readonly)
+		ds, err := dsBackends[backend](fsr.join(fsDatastore, p), readonly)
 		if err != nil {
+			closeAll()
 			return nil, types.Wrap(types.ErrOpenDataStoreFailed, err)
 		}
+		if created[p] && !readonly {
+			if err := fsr.setCurrentBackend(p, backend); err != nil {
+				ds.Close()
+				closeAll()
+				return nil, err
+			}
+		}
 
 		ds = measure.New("fsrepo."+p, ds)
+		// the shard and order states are updated in bursts while the shards are ingested
+		if p == dsNsOrder && cfg.Datastore.OrderWriteInterval > 0 && !readonly {
+			ds = NewCoalescingDatastore(ds, cfg.Datastore.OrderWriteInterval)
+		}
 
 		out[datastore.NewKey(p).String()] = ds
 	}
 
 	return out, nil
 }
+
+// convertDatastore copies all entries of the datastore into a new one of the given backend.
+// The old datastore is kept as <ns>.<backend>.bak and can be removed once the node works fine.
+func (fsr *Repo) convertDatastore(ctx context.Context, ns string, from string, to string) error {
+	path := fsr.join(fsDatastore, ns)
+	tmpPath := path + ".converting"
+	bakPath := path + "." + from + ".bak"
+
+	if err := checkBackend(ns, from); err != nil {
+		return err
+	}
+	if _, err := os.Stat(bakPath); err == nil {
+		return types.Wrapf(types.ErrMigrateRepoFailed, "backup %s exists, remove it first", bakPath)
+	}
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return types.Wrap(types.ErrMigrateRepoFailed, err)
+	}
+
+	src, err := dsBackends[from](path, false)
+	if err != nil {
+		return types.Wrap(types.ErrOpenDataStoreFailed, err)
+	}
+	defer src.Close()
+
+	dst, err := dsBackends[to](tmpPath, false)
+	if err != nil {
+		return types.Wrap(types.ErrOpenDataStoreFailed, err)
+	}
+	defer dst.Close()
+
+	count, err := copyDatastore(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+
+	if err := src.Close(); err != nil {
+		return types.Wrap(types.ErrMigrateRepoFailed, err)
+	}
+	if err := dst.Close(); err != nil {
+		return types.Wrap(types.ErrMigrateRepoFailed, err)
+	}
+
+	if err := os.Rename(path, bakPath); err != nil {
+		return types.Wrap(types.ErrMigrateRepoFailed, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return types.Wrap(types.ErrMigrateRepoFailed, err)
+	}
+	if err := fsr.setCurrentBackend(ns, to); err != nil {
+		return err
+	}
+
+	log.Infof("converted %d entries of %s datastore from %s to %s, the old one is kept at %s", count, ns, from, to, bakPath)
+	return nil
+}
+
+func copyDatastore(ctx context.Context, src datastore.Batching, dst datastore.Batching) (int, error) {
+	results, err := src.Query(ctx, query.Query{})
+	if err != nil {
+		return 0, types.Wrap(types.ErrGetFailed, err)
+	}
+	defer results.Close()
+
+	batch, err := dst.Batch(ctx)
+	if err != nil {
+		return 0, types.Wrap(types.ErrMigrateRepoFailed, err)
+	}
+
+	count := 0
+	for r := range results.Next() {
+		if r.Error != nil {
+			return count, types.Wrap(types.ErrGetFailed, r.Error)
+		}
+		if err := batch.Put(ctx, datastore.NewKey(r.Key), r.Value); err != nil {
+			return count, types.Wrap(types.ErrMigrateRepoFailed, err)
+		}
+
+		count++
+		if count%convertBatchSize == 0 {
+			if err := batch.Commit(ctx); err != nil {
+				return count, types.Wrap(types.ErrMigrateRepoFailed, err)
+			}
+			batch, err = dst.Batch(ctx)
+			if err != nil {
+				return count, types.Wrap(types.ErrMigrateRepoFailed, err)
+			}
+		}
+	}
+
+	if err := batch.Commit(ctx); err != nil {
+		return count, types.Wrap(types.ErrMigrateRepoFailed, err)
+	}
+	return count, nil
+}
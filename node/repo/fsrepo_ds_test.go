@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sao-node/types"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBackend(t *testing.T) {
+	require.NoError(t, checkBackend(dsNsMetadata, DsBackendLevelDB))
+	require.NoError(t, checkBackend(dsNsOrder, DsBackendBadger))
+	require.True(t, types.ErrInvalidConfig.Is(checkBackend(dsNsOrder, DsBackendPebble)))
+	require.True(t, types.ErrInvalidConfig.Is(checkBackend(dsNsOrder, "rocksdb")))
+}
+
+// BenchmarkDatastoreBackends compares the backends on the workloads of the datastores they are the defaults of:
+// small metadata values read back often, and the batches of the larger shard and order records.
+func BenchmarkDatastoreBackends(b *testing.B) {
+	ctx := context.Background()
+	workloads := []struct {
+		name      string
+		valueSize int
+		batch     int
+	}{
+		{name: "metadata", valueSize: 256, batch: 1},
+		{name: "order", valueSize: 4 << 10, batch: 64},
+	}
+
+	for _, backend := range []string{DsBackendLevelDB, DsBackendBadger} {
+		for _, w := range workloads {
+			value := make([]byte, w.valueSize)
+			_, err := rand.Read(value)
+			require.NoError(b, err)
+
+			b.Run(fmt.Sprintf("%s/%s/put", backend, w.name), func(b *testing.B) {
+				ds, err := dsBackends[backend](b.TempDir(), false)
+				require.NoError(b, err)
+				defer ds.Close()
+
+				b.SetBytes(int64(w.valueSize))
+				b.ResetTimer()
+				for i := 0; i < b.N; i += w.batch {
+					batch, err := ds.Batch(ctx)
+					require.NoError(b, err)
+					for j := i; j < i+w.batch && j < b.N; j++ {
+						require.NoError(b, batch.Put(ctx, datastore.NewKey(fmt.Sprint(j)), value))
+					}
+					require.NoError(b, batch.Commit(ctx))
+				}
+			})
+
+			b.Run(fmt.Sprintf("%s/%s/get", backend, w.name), func(b *testing.B) {
+				ds, err := dsBackends[backend](b.TempDir(), false)
+				require.NoError(b, err)
+				defer ds.Close()
+
+				const keys = 1024
+				for i := 0; i < keys; i++ {
+					require.NoError(b, ds.Put(ctx, datastore.NewKey(fmt.Sprint(i)), value))
+				}
+				b.SetBytes(int64(w.valueSize))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err := ds.Get(ctx, datastore.NewKey(fmt.Sprint(i%keys)))
+					require.NoError(b, err)
+				}
+			})
+		}
+	}
+}
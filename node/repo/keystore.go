@@ -0,0 +1,254 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"sao-node/node/staging"
+	"sao-node/types"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeystorePassphraseEnv is read to unlock an encrypted keystore without prompting.
+const KeystorePassphraseEnv = "SAO_KEYSTORE_PASSPHRASE"
+
+const (
+	// encrypted key file layout: magic | salt | nonce | AES-256-GCM sealed key
+	keystoreMagic = "SAOKS1"
+	saltLen       = 32
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	aesKeyLen     = 32
+)
+
+// SetPassphrase makes the repo encrypt the keys it writes with the passphrase, and use it to unlock encrypted keys.
+func (r *Repo) SetPassphrase(passphrase string) {
+	r.keyLk.Lock()
+	defer r.keyLk.Unlock()
+
+	r.passphrase = passphrase
+}
+
+// SetPassphraseFunc sets the function called to get the passphrase when an encrypted key is read
+// and no passphrase is given yet.
+func (r *Repo) SetPassphraseFunc(f func() (string, error)) {
+	r.keyLk.Lock()
+	defer r.keyLk.Unlock()
+
+	r.passphraseFunc = f
+}
+
+// KeystoreEncrypted tells if the libp2p key of the repo is encrypted.
+func (r *Repo) KeystoreEncrypted() (bool, error) {
+	data, err := os.ReadFile(filepath.Join(r.Path, fsKeystore, fsLibp2pKey))
+	if err != nil {
+		return false, types.Wrap(types.ErrReadConfigFailed, err)
+	}
+	return isEncryptedKey(data), nil
+}
+
+// EncryptKeystore encrypts the plaintext keys in keystore with the passphrase.
+func (r *Repo) EncryptKeystore(passphrase string) error {
+	if passphrase == "" {
+		return types.Wrapf(types.ErrInvalidPassphrase, "empty passphrase")
+	}
+
+	names := []string{fsLibp2pKey}
+	if _, err := os.Stat(filepath.Join(r.Path, fsKeystore, fsStagingKey)); err == nil {
+		names = append(names, fsStagingKey)
+	}
+	keys := make(map[string][]byte)
+	for _, name := range names {
+		key, err := r.readKey(name)
+		if err != nil {
+			return err
+		}
+		keys[name] = key
+	}
+
+	r.SetPassphrase(passphrase)
+	for name, key := range keys {
+		if err := r.writeKey(name, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StagingKey returns the key the staged shards are encrypted with, it is generated on the first call.
+func (r *Repo) StagingKey() ([]byte, error) {
+	_, err := os.Stat(filepath.Join(r.Path, fsKeystore, fsStagingKey))
+	if os.IsNotExist(err) {
+		key := make([]byte, staging.KeyLen)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, types.Wrap(types.ErrEncryptFailed, err)
+		}
+		return key, r.writeKey(fsStagingKey, key)
+	}
+	return r.readKey(fsStagingKey)
+}
+
+func (r *Repo) readKey(name string) ([]byte, error) {
+	r.keyLk.Lock()
+	defer r.keyLk.Unlock()
+
+	if key, exists := r.keys[name]; exists {
+		return key, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.Path, fsKeystore, name))
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadConfigFailed, err)
+	}
+
+	key := data
+	if isEncryptedKey(data) {
+		if r.passphrase == "" && r.passphraseFunc != nil {
+			r.passphrase, err = r.passphraseFunc()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if r.passphrase == "" {
+			return nil, types.Wrapf(types.ErrInvalidPassphrase, "keystore is encrypted, set %s or enter the passphrase", KeystorePassphraseEnv)
+		}
+
+		key, err = decryptKey(data, r.passphrase)
+		if err != nil {
+			r.passphrase = ""
+			return nil, err
+		}
+	}
+
+	if r.keys == nil {
+		r.keys = make(map[string][]byte)
+	}
+	r.keys[name] = key
+	return key, nil
+}
+
+func (r *Repo) writeKey(name string, key []byte) error {
+	r.keyLk.Lock()
+	defer r.keyLk.Unlock()
+
+	data := key
+	if r.passphrase != "" {
+		var err error
+		data, err = encryptKey(key, r.passphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomic(filepath.Join(r.Path, fsKeystore, name), data); err != nil {
+		return types.Wrap(types.ErrWriteConfigFailed, err)
+	}
+
+	if r.keys == nil {
+		r.keys = make(map[string][]byte)
+	}
+	r.keys[name] = key
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data readable only by the owner. The data is written to a temp
+// file in the same directory and synced before it is renamed into place, so a crash leaves either the old or the
+// new key, never a truncated one.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func isEncryptedKey(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(keystoreMagic))
+}
+
+func encryptKey(key []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, types.Wrap(types.ErrEncryptFailed, err)
+	}
+
+	gcm, err := newKeystoreCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, types.Wrap(types.ErrEncryptFailed, err)
+	}
+
+	buf := bytes.NewBufferString(keystoreMagic)
+	buf.Write(salt)
+	buf.Write(nonce)
+	buf.Write(gcm.Seal(nil, nonce, key, []byte(keystoreMagic)))
+	return buf.Bytes(), nil
+}
+
+func decryptKey(data []byte, passphrase string) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte(keystoreMagic))
+	if len(data) < saltLen {
+		return nil, types.Wrapf(types.ErrInvalidPassphrase, "malformed encrypted key")
+	}
+	salt := data[:saltLen]
+
+	gcm, err := newKeystoreCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[saltLen:]
+	if len(data) < gcm.NonceSize() {
+		return nil, types.Wrapf(types.ErrInvalidPassphrase, "malformed encrypted key")
+	}
+
+	key, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(keystoreMagic))
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidPassphrase, "failed to decrypt keystore, wrong passphrase?")
+	}
+	return key, nil
+}
+
+func newKeystoreCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	dk, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, aesKeyLen)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidPassphrase, err)
+	}
+
+	block, err := aes.NewCipher(dk)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidPassphrase, err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptKey(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	data, err := encryptKey(key, "passphrase")
+	require.NoError(t, err)
+	require.True(t, isEncryptedKey(data))
+	require.False(t, bytes.Contains(data, key))
+
+	decrypted, err := decryptKey(data, "passphrase")
+	require.NoError(t, err)
+	require.Equal(t, key, decrypted)
+
+	_, err = decryptKey(data, "wrong")
+	require.True(t, types.ErrInvalidPassphrase.Is(err))
+	data[len(data)-1] ^= 1
+	_, err = decryptKey(data, "passphrase")
+	require.True(t, types.ErrInvalidPassphrase.Is(err))
+	_, err = decryptKey([]byte(keystoreMagic+"short"), "passphrase")
+	require.True(t, types.ErrInvalidPassphrase.Is(err))
+}
+
+func TestEncryptKeystore(t *testing.T) {
+	path := t.TempDir()
+	r, err := NewRepo(path)
+	require.NoError(t, err)
+	require.NoError(t, r.Init("sao1chain"))
+
+	// the keys of a new repo are in plaintext until the keystore is encrypted
+	encrypted, err := r.KeystoreEncrypted()
+	require.NoError(t, err)
+	require.False(t, encrypted)
+	peerKey, err := r.GetKeyBytes()
+	require.NoError(t, err)
+	stagingKey, err := r.StagingKey()
+	require.NoError(t, err)
+
+	require.True(t, types.ErrInvalidPassphrase.Is(r.EncryptKeystore("")))
+	require.NoError(t, r.EncryptKeystore("passphrase"))
+	encrypted, err = r.KeystoreEncrypted()
+	require.NoError(t, err)
+	require.True(t, encrypted)
+	for _, name := range []string{fsLibp2pKey, fsStagingKey} {
+		data, err := os.ReadFile(filepath.Join(path, fsKeystore, name))
+		require.NoError(t, err)
+		require.True(t, isEncryptedKey(data), name)
+		info, err := os.Stat(filepath.Join(path, fsKeystore, name))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm(), name)
+	}
+	// the keys are replaced by renaming, no temp file is left behind
+	tmps, err := filepath.Glob(filepath.Join(path, fsKeystore, "*.tmp"))
+	require.NoError(t, err)
+	require.Empty(t, tmps)
+
+	open := func() *Repo {
+		r, err := NewRepo(path)
+		require.NoError(t, err)
+		return r
+	}
+
+	// the migrated keys are read back with the passphrase
+	r = open()
+	r.SetPassphrase("passphrase")
+	key, err := r.GetKeyBytes()
+	require.NoError(t, err)
+	require.Equal(t, peerKey, key)
+	key, err = r.StagingKey()
+	require.NoError(t, err)
+	require.Equal(t, stagingKey, key)
+
+	r = open()
+	_, err = r.GetKeyBytes()
+	require.True(t, types.ErrInvalidPassphrase.Is(err))
+
+	// a wrong passphrase is dropped, so the next read asks for it again
+	r = open()
+	prompts := []string{"wrong", "passphrase"}
+	r.SetPassphraseFunc(func() (string, error) {
+		passphrase := prompts[0]
+		prompts = prompts[1:]
+		return passphrase, nil
+	})
+	_, err = r.GetKeyBytes()
+	require.True(t, types.ErrInvalidPassphrase.Is(err))
+	key, err = r.GetKeyBytes()
+	require.NoError(t, err)
+	require.Equal(t, peerKey, key)
+}
@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const fsLock = "repo.lock"
+
+// Lock takes an exclusive flock on the repo, so no other node process can open the same datastores.
+// The lock file records the pid of the holder. With force, a lock held by a pid not running on this
+// host is treated as stale (e.g. left on a shared filesystem) and taken over.
+// The returned function releases the lock.
+func (r *Repo) Lock(force bool) (func() error, error) {
+	lockPath := filepath.Join(r.Path, fsLock)
+
+	f, err := lockFile(lockPath)
+	if err == nil {
+		return unlockFunc(f), nil
+	}
+	if !force {
+		return nil, err
+	}
+
+	pid, startedAt := readLockHolder(lockPath)
+	if pid > 0 && processAlive(pid) {
+		return nil, types.Wrapf(types.ErrRepoLocked, "pid %d is still running since %s", pid, startedAt)
+	}
+
+	log.Warnf("taking over stale repo lock of pid %d since %s", pid, startedAt)
+	if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+		return nil, types.Wrap(types.ErrRepoLocked, err)
+	}
+	f, err = lockFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	return unlockFunc(f), nil
+}
+
+func lockFile(lockPath string) (*os.File, error) {
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		f.Close()
+		pid, startedAt := readLockHolder(lockPath)
+		return nil, types.Wrapf(types.ErrRepoLocked, "held by pid %d since %s, stop it or run with --force if it is stale", pid, startedAt)
+	}
+
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteString(fmt.Sprintf("%d %s", os.Getpid(), time.Now().Format(time.RFC3339)))
+	}
+	if err != nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+		return nil, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return f, nil
+}
+
+// unlockFunc releases the lock but keeps the lock file: removing it would let a process that already opened it
+// lock the removed file while another one locks a new file of the same path.
+func unlockFunc(f *os.File) func() error {
+	return func() error {
+		f.Truncate(0)
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+		if err != nil {
+			return types.Wrap(types.ErrRepoLocked, err)
+		}
+		return nil
+	}
+}
+
+func readLockHolder(lockPath string) (int, string) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0, "unknown"
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0, "unknown"
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "unknown"
+	}
+	return pid, fields[1]
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	r, err := NewRepo(t.TempDir())
+	require.NoError(t, err)
+
+	unlock, err := r.Lock(false)
+	require.NoError(t, err)
+	_, err = r.Lock(false)
+	require.True(t, types.ErrRepoLocked.Is(err))
+	// the holder is running
+	_, err = r.Lock(true)
+	require.True(t, types.ErrRepoLocked.Is(err))
+
+	// the lock file stays, so every process locks the same file
+	require.NoError(t, unlock())
+	_, err = os.Stat(filepath.Join(r.Path, fsLock))
+	require.NoError(t, err)
+
+	unlock, err = r.Lock(false)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+}
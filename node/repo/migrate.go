@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sao-node/node/config"
+	"sao-node/types"
+	"sao-node/utils"
+	"strconv"
+	"strings"
+)
+
+const fsVersion = "version"
+
+// RepoVersion is the version of the repo format this build works with.
+// Repos created before the format was versioned have no version file and are at version 0.
+const RepoVersion = 2
+
+type migration struct {
+	// version is the repo version after the migration
+	version     int
+	description string
+	migrate     func(ctx context.Context, r *Repo) error
+}
+
+// migrations must be ordered by version, a new step is appended whenever the repo format changes.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "write the config fields added since the unversioned repo format",
+		migrate:     migrateConfigFields,
+	},
+	{
+		version:     2,
+		description: "move the orders, shards and migrate jobs from the index records to the prefixed keys",
+		migrate:     migrateStateKeys,
+	},
+}
+
+// Version returns the version of the repo format on disk.
+func (r *Repo) Version() (int, error) {
+	data, err := os.ReadFile(filepath.Join(r.Path, fsVersion))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, types.Wrapf(types.ErrMigrateRepoFailed, "invalid repo version %s", data)
+	}
+	return version, nil
+}
+
+func (r *Repo) setVersion(version int) error {
+	err := os.WriteFile(filepath.Join(r.Path, fsVersion), []byte(strconv.Itoa(version)), 0644)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+// NeedMigrate tells if the repo is older than RepoVersion, or any datastore needs to be converted
+// to the configured backend.
+func (r *Repo) NeedMigrate() (bool, error) {
+	version, err := r.Version()
+	if err != nil {
+		return false, err
+	}
+	if version > RepoVersion {
+		return false, types.Wrapf(types.ErrMigrateRepoFailed, "repo version %d is newer than %d supported by this build", version, RepoVersion)
+	}
+
+	pending, err := r.pendingConversions()
+	if err != nil {
+		return false, err
+	}
+	return version < RepoVersion || len(pending) > 0, nil
+}
+
+// Migrate converts the datastores to the configured backends, then runs the pending migration steps in order.
+// The version is recorded after every step, so an interrupted migration continues from the failed step next time.
+func (r *Repo) Migrate(ctx context.Context) error {
+	version, err := r.Version()
+	if err != nil {
+		return err
+	}
+	if version > RepoVersion {
+		return types.Wrapf(types.ErrMigrateRepoFailed, "repo version %d is newer than %d supported by this build", version, RepoVersion)
+	}
+
+	pending, err := r.pendingConversions()
+	if err != nil {
+		return err
+	}
+	for ns, backends := range pending {
+		log.Infof("converting %s datastore from %s to %s", ns, backends[0], backends[1])
+		if err := r.convertDatastore(ctx, ns, backends[0], backends[1]); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		log.Infof("migrating repo from version %d to %d: %s", version, m.version, m.description)
+		if err := m.migrate(ctx, r); err != nil {
+			return types.Wrapf(types.ErrMigrateRepoFailed, "version %d: %v", m.version, err)
+		}
+		if err := r.setVersion(m.version); err != nil {
+			return err
+		}
+		version = m.version
+	}
+
+	return nil
+}
+
+func migrateConfigFields(_ context.Context, r *Repo) error {
+	c, err := r.Config()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(r.configPath)
+	if err != nil {
+		return types.Wrap(types.ErrReadConfigFailed, err)
+	}
+	err = os.WriteFile(r.configPath+".bak", data, 0644)
+	if err != nil {
+		return types.Wrap(types.ErrWriteConfigFailed, err)
+	}
+
+	comm, err := config.ConfigUpdate(c, r.defaultConfig(), true)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(r.configPath, comm, 0644)
+	if err != nil {
+		return types.Wrap(types.ErrWriteConfigFailed, err)
+	}
+	return nil
+}
+
+func migrateStateKeys(ctx context.Context, r *Repo) error {
+	ds, err := r.Datastore(ctx, "/order")
+	if err != nil {
+		return err
+	}
+
+	moved, err := utils.MigrateStateKeys(ctx, ds)
+	if err != nil {
+		return err
+	}
+	log.Infof("moved %d order records", moved)
+	return nil
+}
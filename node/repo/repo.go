@@ -25,7 +25,9 @@ const (
 	fsConfig    = "config.toml"
 	fsKeystore  = "keystore"
 	fsLibp2pKey = "libp2p.key"
-	fsDatastore = "datastore"
+	// key of the staging files encryption
+	fsStagingKey = "staging.key"
+	fsDatastore  = "datastore"
 )
 
 var (
@@ -41,6 +43,11 @@ type Repo struct {
 	ds     map[string]datastore.Batching
 	dsErr  error
 	dsOnce sync.Once
+
+	keyLk          sync.Mutex
+	keys           map[string][]byte
+	passphrase     string
+	passphraseFunc func() (string, error)
 }
 
 func PrepareRepo(repoPath string) (*Repo, error) {
@@ -113,6 +120,11 @@ func (r *Repo) Init(chainAddress string) error {
 		return types.Wrap(types.ErrInitRepoFailed, err)
 	}
 
+	err = r.setVersion(RepoVersion)
+	if err != nil {
+		return types.Wrap(types.ErrInitRepoFailed, err)
+	}
+
 	return nil
 }
 
@@ -136,36 +148,53 @@ func (r *Repo) GeneratePeerId() (crypto.PrivKey, error) {
 }
 
 func (r *Repo) GetKeyBytes() ([]byte, error) {
-	libp2pPath := filepath.Join(r.Path, fsKeystore, fsLibp2pKey)
-	key, err := os.ReadFile(libp2pPath)
-	if err != nil {
-		return nil, types.Wrap(types.ErrReadConfigFailed, err)
-	}
-	return key, nil
+	return r.readKey(fsLibp2pKey)
 }
 
 func (r *Repo) PeerId() (crypto.PrivKey, error) {
-	libp2pPath := filepath.Join(r.Path, fsKeystore, fsLibp2pKey)
-	key, err := os.ReadFile(libp2pPath)
+	key, err := r.readKey(fsLibp2pKey)
 	if err != nil {
-		return nil, types.Wrap(types.ErrReadConfigFailed, err)
+		return nil, err
 	}
 	return crypto.UnmarshalPrivateKey(key)
 }
 
 func (r *Repo) setPeerId(data []byte) error {
-	libp2pPath := filepath.Join(r.Path, fsKeystore, fsLibp2pKey)
-	err := os.WriteFile(libp2pPath, data, 0600)
-	if err != nil {
-		return err
-	}
-	return nil
+	return r.writeKey(fsLibp2pKey, data)
 }
 
 func (r *Repo) Config() (interface{}, error) {
 	return utils.FromFile(r.configPath, r.defaultConfig())
 }
 
+// ConfigValue returns the value at key of the config, e.g. "Libp2p.ListenAddress".
+func (r *Repo) ConfigValue(key string) (interface{}, error) {
+	c, err := r.Config()
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadConfigFailed, err)
+	}
+	return config.GetValue(c, key)
+}
+
+// SetConfigValue updates the value at key in config.toml, other lines of the file are left untouched.
+func (r *Repo) SetConfigValue(key string, value string) error {
+	data, err := os.ReadFile(r.configPath)
+	if err != nil {
+		return types.Wrap(types.ErrReadConfigFailed, err)
+	}
+
+	data, err = config.SetValue(data, r.defaultConfig(), key, value)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(r.configPath, data, 0644)
+	if err != nil {
+		return types.Wrap(types.ErrWriteConfigFailed, err)
+	}
+	return nil
+}
+
 func (r *Repo) Datastore(ctx context.Context, ns string) (datastore.Batching, error) {
 	r.dsOnce.Do(func() {
 		r.ds, r.dsErr = r.openDatastores(r.readonly)
@@ -181,6 +210,19 @@ func (r *Repo) Datastore(ctx context.Context, ns string) (datastore.Batching, er
 	return nil, types.Wrapf(types.ErrOpenDataStoreFailed, "no such datastore: %s", ns)
 }
 
+// Close closes the datastores opened, the writes coalesced are committed first.
+func (r *Repo) Close() error {
+	var err error
+	for ns, ds := range r.ds {
+		if e := ds.Close(); e != nil {
+			log.Errorf("close %s datastore: %v", ns, e)
+			err = e
+		}
+	}
+	r.ds = nil
+	return err
+}
+
 func (r *Repo) initConfig(chainAddress string) error {
 	_, err := os.Stat(r.configPath)
 	if err == nil {
@@ -0,0 +1,269 @@
+// Package reputation keeps the local view of the storage providers: how fast they complete the shards notified
+// to them, how often they fail the assignments, the shard loads, the audits and the migrations. The shards are assigned by
+// the chain, so the reputation only decides which replica a shard is fetched from first.
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"sao-node/node/events"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("reputation")
+
+var keyPrefix = datastore.NewKey("/reputation")
+
+// Stats are the counters of a provider, persisted across restarts.
+type Stats struct {
+	Provider string
+
+	// shard assignments notified, and the notifications failed
+	Assigned       uint64
+	AssignFailures uint64
+	// assigned shards completed, and the ones not completed before the order expired
+	Completed uint64
+	Timeouts  uint64
+	// sum of the time from the notification to the completion, only counted while the gateway is running
+	CompleteLatency time.Duration
+	CompleteSamples uint64
+
+	Loads        uint64
+	LoadFailures uint64
+	// availability challenges of the gateway, failed if the content is not returned or doesn't match the cid
+	Audits        uint64
+	AuditFailures uint64
+
+	Migrations        uint64
+	MigrationFailures uint64
+	MigrationLatency  time.Duration
+
+	UpdatedAt int64
+}
+
+func (s Stats) FailureRate() float64 {
+	total := s.Assigned + s.Loads + s.Audits + s.Migrations
+	if total == 0 {
+		return 0
+	}
+	return float64(s.AssignFailures+s.Timeouts+s.LoadFailures+s.AuditFailures+s.MigrationFailures) / float64(total)
+}
+
+func (s Stats) AvgCompleteLatency() time.Duration {
+	if s.CompleteSamples == 0 {
+		return 0
+	}
+	return s.CompleteLatency / time.Duration(s.CompleteSamples)
+}
+
+func (s Stats) AvgMigrationLatency() time.Duration {
+	if s.Migrations == 0 {
+		return 0
+	}
+	return s.MigrationLatency / time.Duration(s.Migrations)
+}
+
+type notification struct {
+	provider string
+	orderId  uint64
+}
+
+// Store records the provider events. A nil Store drops all events.
+type Store struct {
+	ds datastore.Batching
+
+	lk       sync.Mutex
+	stats    map[string]*Stats
+	notified map[notification]time.Time
+}
+
+func NewStore(ctx context.Context, ds datastore.Batching) (*Store, error) {
+	s := &Store{
+		ds:       ds,
+		stats:    make(map[string]*Stats),
+		notified: make(map[notification]time.Time),
+	}
+
+	results, err := ds.Query(ctx, query.Query{Prefix: keyPrefix.String()})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		var stats Stats
+		if err := json.Unmarshal(r.Value, &stats); err != nil {
+			log.Warnf("invalid reputation %s: %v", r.Key, err)
+			continue
+		}
+		s.stats[stats.Provider] = &stats
+	}
+	return s, nil
+}
+
+// Assigned records the notification of a shard assigned to the provider.
+func (s *Store) Assigned(ctx context.Context, provider string, orderId uint64, ok bool) {
+	s.update(ctx, provider, func(stats *Stats) {
+		stats.Assigned++
+		if !ok {
+			stats.AssignFailures++
+			return
+		}
+		s.notified[notification{provider, orderId}] = time.Now()
+	})
+}
+
+func (s *Store) Completed(ctx context.Context, provider string, orderId uint64) {
+	s.update(ctx, provider, func(stats *Stats) {
+		stats.Completed++
+		key := notification{provider, orderId}
+		if at, exists := s.notified[key]; exists {
+			stats.CompleteLatency += time.Since(at)
+			stats.CompleteSamples++
+			delete(s.notified, key)
+		}
+	})
+}
+
+// TimedOut records a shard of the provider not completed before its order expired.
+func (s *Store) TimedOut(ctx context.Context, provider string, orderId uint64) {
+	s.update(ctx, provider, func(stats *Stats) {
+		stats.Timeouts++
+		delete(s.notified, notification{provider, orderId})
+	})
+}
+
+func (s *Store) Loaded(ctx context.Context, provider string, ok bool) {
+	s.update(ctx, provider, func(stats *Stats) {
+		stats.Loads++
+		if !ok {
+			stats.LoadFailures++
+		}
+	})
+}
+
+// Audited records an availability challenge of a shard stored by the provider.
+func (s *Store) Audited(ctx context.Context, provider string, ok bool) {
+	s.update(ctx, provider, func(stats *Stats) {
+		stats.Audits++
+		if !ok {
+			stats.AuditFailures++
+		}
+	})
+}
+
+func (s *Store) Migrated(ctx context.Context, provider string, latency time.Duration, ok bool) {
+	s.update(ctx, provider, func(stats *Stats) {
+		stats.Migrations++
+		stats.MigrationLatency += latency
+		if !ok {
+			stats.MigrationFailures++
+		}
+	})
+}
+
+// shardEventBuffer is the events queued before the publishers wait for the store.
+const shardEventBuffer = 256
+
+// Consume records the shard events published to the bus until ctx is done.
+func (s *Store) Consume(ctx context.Context, bus *events.Bus) {
+	if s == nil {
+		return
+	}
+
+	bus.Consume(ctx, events.TopicShard, shardEventBuffer, func(ev events.Event) {
+		e := ev.(events.ShardEvent)
+		switch e.Kind {
+		case events.ShardAssigned:
+			s.Assigned(ctx, e.Provider, e.OrderId, e.Ok)
+		case events.ShardCompleted:
+			s.Completed(ctx, e.Provider, e.OrderId)
+		case events.ShardTimedOut:
+			s.TimedOut(ctx, e.Provider, e.OrderId)
+		case events.ShardLoaded:
+			s.Loaded(ctx, e.Provider, e.Ok)
+		case events.ShardAudited:
+			s.Audited(ctx, e.Provider, e.Ok)
+		case events.ShardMigrated:
+			s.Migrated(ctx, e.Provider, e.Latency, e.Ok)
+		}
+	})
+}
+
+func (s *Store) update(ctx context.Context, provider string, f func(stats *Stats)) {
+	if s == nil {
+		return
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	stats, exists := s.stats[provider]
+	if !exists {
+		stats = &Stats{Provider: provider}
+		s.stats[provider] = stats
+	}
+	f(stats)
+	stats.UpdatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		log.Warnf("marshal reputation of %s: %v", provider, err)
+		return
+	}
+	if err := s.ds.Put(ctx, keyPrefix.ChildString(provider), data); err != nil {
+		log.Warnf("put reputation of %s: %v", provider, err)
+	}
+}
+
+// List returns the stats of all providers ordered by address.
+func (s *Store) List() []Stats {
+	if s == nil {
+		return nil
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	list := make([]Stats, 0, len(s.stats))
+	for _, stats := range s.stats {
+		list = append(list, *stats)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Provider < list[j].Provider
+	})
+	return list
+}
+
+// Rank orders the providers by failure rate then by completion latency, the unknown providers are ranked as
+// the ones never failed.
+func (s *Store) Rank(providers []string) {
+	if s == nil {
+		return
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	stats := func(provider string) Stats {
+		if stats, exists := s.stats[provider]; exists {
+			return *stats
+		}
+		return Stats{Provider: provider}
+	}
+	sort.SliceStable(providers, func(i, j int) bool {
+		a, b := stats(providers[i]), stats(providers[j])
+		if a.FailureRate() != b.FailureRate() {
+			return a.FailureRate() < b.FailureRate()
+		}
+		return a.AvgCompleteLatency() < b.AvgCompleteLatency()
+	})
+}
@@ -0,0 +1,45 @@
+package reputation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	s, err := NewStore(ctx, ds)
+	require.NoError(t, err)
+
+	s.Assigned(ctx, "good", 1, true)
+	s.Completed(ctx, "good", 1)
+	s.Assigned(ctx, "slow", 1, true)
+	s.TimedOut(ctx, "slow", 1)
+	s.Loaded(ctx, "flaky", false)
+	s.Loaded(ctx, "flaky", true)
+	s.Audited(ctx, "flaky", true)
+	s.Audited(ctx, "flaky", false)
+
+	providers := []string{"slow", "flaky", "unknown", "good"}
+	s.Rank(providers)
+	require.Equal(t, []string{"unknown", "good", "flaky", "slow"}, providers)
+
+	// the counters survive a restart
+	s, err = NewStore(ctx, ds)
+	require.NoError(t, err)
+	list := s.List()
+	require.Len(t, list, 3)
+	require.Equal(t, "flaky", list[0].Provider)
+	require.Equal(t, 0.5, list[0].FailureRate())
+	require.Equal(t, uint64(1), list[0].AuditFailures)
+	require.Equal(t, uint64(1), list[1].CompleteSamples)
+	require.Equal(t, uint64(1), list[2].Timeouts)
+
+	var nilStore *Store
+	nilStore.Loaded(ctx, "good", true)
+	require.Empty(t, nilStore.List())
+}
@@ -2,8 +2,11 @@ package node
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"sao-node/api"
+	"sao-node/node/audit"
+	"strings"
 
 	"github.com/filecoin-project/go-jsonrpc"
 	"github.com/filecoin-project/go-jsonrpc/auth"
@@ -16,20 +19,26 @@ import (
 
 var rpclog = logging.Logger("rpc")
 
-func ServeRPC(h http.Handler, addr multiaddr.Multiaddr) (*http.Server, error) {
+// ServeRPC serves the handler at addr, over TLS if tlsConfig is not nil.
+func ServeRPC(h http.Handler, addr multiaddr.Multiaddr, tlsConfig *tls.Config) (*http.Server, error) {
 	// Start listening to the addr; if invalid or occupied, we will fail early.
 	lst, err := manet.Listen(addr)
 	if err != nil {
 		return nil, err
 	}
+	listener := manet.NetListener(lst)
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
 
 	// Instantiate the server and start listening.
 	srv := &http.Server{
-		Handler: h,
+		Handler:   h,
+		TLSConfig: tlsConfig,
 	}
 
 	go func() {
-		err = srv.Serve(manet.NetListener(lst))
+		err = srv.Serve(listener)
 		if err != http.ErrServerClosed {
 			rpclog.Warnf("rpc server failed: %s", err)
 		}
@@ -38,21 +47,31 @@ func ServeRPC(h http.Handler, addr multiaddr.Multiaddr) (*http.Server, error) {
 	return srv, err
 }
 
-func GatewayRpcHandler(ga api.SaoApi, enablePermission bool) (http.Handler, error) {
+func GatewayRpcHandler(ga api.SaoApi, enablePermission bool, auditLog *audit.Log) (http.Handler, error) {
 	m := mux.NewRouter()
 
 	if enablePermission {
 		ga = api.PermissionedSaoNodeAPI(ga)
 	}
+	// the calls rejected by the permission check are audited too
+	served := ga
+	if auditLog != nil {
+		served = audit.Proxy(ga, auditLog)
+	}
 
 	rpcServer := jsonrpc.NewServer()
-	rpcServer.Register("Sao", ga)
+	rpcServer.Register("Sao", served)
 
 	m.Handle("/rpc/v0", rpcServer)
 
 	var handler = &auth.Handler{
 		Next: m.ServeHTTP,
 	}
+	if auditLog != nil {
+		handler.Next = func(w http.ResponseWriter, r *http.Request) {
+			m.ServeHTTP(w, r.WithContext(audit.WithToken(r.Context(), requestToken(r))))
+		}
+	}
 
 	if enablePermission {
 		handler.Verify = ga.AuthVerify
@@ -63,6 +82,15 @@ func GatewayRpcHandler(ga api.SaoApi, enablePermission bool) (http.Handler, erro
 	return cors.AllowAll().Handler(handler), nil
 }
 
+// requestToken returns the API token of the request, looked up the same way as auth.Handler does.
+func requestToken(r *http.Request) string {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return r.FormValue("token")
+	}
+	return strings.TrimPrefix(token, "Bearer ")
+}
+
 func authVerify(ctx context.Context, token string) ([]auth.Permission, error) {
 
 	return api.AllPermissions, nil
@@ -0,0 +1,236 @@
+// Package s3 serves a subset of the S3 protocol on the gateway, so the applications using the S3 SDKs
+// can store data in SAO. The buckets are groupIds and the objects are file models aliased by their keys,
+// owned by the did of the keyring account configured for the access key.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sao-node/api"
+	"sao-node/chain"
+	"sao-node/node/config"
+	"sao-node/node/filemodel"
+	"sao-node/types"
+	"sao-node/utils"
+	"strings"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("s3")
+
+type account struct {
+	secretKey string
+	store     *filemodel.Store
+}
+
+type Server struct {
+	cfg      *config.S3
+	accounts map[string]account
+	server   *http.Server
+}
+
+// StartServer starts the S3 listener, the objects of each access key are stored by the keyring account of it.
+func StartServer(ctx context.Context, cfg *config.S3, gatewayApi api.SaoApi, chainSvc chain.ChainSvcApi, nodeAddress string, keyringHome string, fileServerPath string) (*Server, error) {
+	s := &Server{
+		cfg:      cfg,
+		accounts: make(map[string]account),
+	}
+	for _, c := range cfg.Credentials {
+		secretKey := os.Getenv(c.SecretKeyEnv)
+		if c.AccessKey == "" || secretKey == "" {
+			return nil, types.Wrapf(types.ErrInvalidConfig, "S3 access key %s without secret key in $%s", c.AccessKey, c.SecretKeyEnv)
+		}
+		store, err := filemodel.NewStore(ctx, gatewayApi, chainSvc, nodeAddress, keyringHome, c.KeyName, fileServerPath, cfg.Duration, cfg.Replica)
+		if err != nil {
+			return nil, err
+		}
+		s.accounts[c.AccessKey] = account{secretKey: secretKey, store: store}
+		log.Infof("S3 access key %s maps to %s", c.AccessKey, store.Owner())
+	}
+
+	s.server = &http.Server{
+		Addr:    cfg.ListenAddress,
+		Handler: s,
+	}
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("S3 server: %v", err)
+		}
+	}()
+	log.Infof("S3 server listening on %s", cfg.ListenAddress)
+
+	return s, nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	log.Info("stopping S3 server...")
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	acc, payload, s3err := s.authenticate(r)
+	if s3err != nil {
+		writeError(w, s3err)
+		return
+	}
+
+	// path-style only, /<bucket>/<key>
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		writeError(w, errNotImplemented)
+		return
+	}
+	bucket, key := parts[0], parts[1]
+
+	switch r.Method {
+	case http.MethodPut:
+		s3err = putObject(r.Context(), w, acc.store, bucket, key, payload)
+	case http.MethodGet, http.MethodHead:
+		s3err = getObject(r.Context(), w, acc.store, bucket, key)
+	case http.MethodDelete:
+		s3err = deleteObject(r.Context(), w, acc.store, bucket, key)
+	default:
+		s3err = errNotImplemented
+	}
+	if s3err != nil {
+		writeError(w, s3err)
+	}
+}
+
+// authenticate verifies the signature of the request and reads the payload.
+func (s *Server) authenticate(r *http.Request) (*account, []byte, *s3Error) {
+	sig, ok := parseSigV4(r.Header.Get("Authorization"))
+	if !ok {
+		return nil, nil, errAccessDenied
+	}
+	acc, ok := s.accounts[sig.accessKey]
+	if !ok {
+		return nil, nil, errInvalidAccessKeyId
+	}
+
+	amzDate, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil || time.Since(amzDate) > maxClockSkew || time.Until(amzDate) > maxClockSkew {
+		return nil, nil, errRequestTimeTooSkewed
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = unsignedPayload
+	}
+	if !sig.verify(r, acc.secretKey, payloadHash) {
+		return nil, nil, errSignatureDoesNotMatch
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, s.cfg.MaxObjectSize+1))
+	if err != nil {
+		return nil, nil, errIncompleteBody
+	}
+	if int64(len(payload)) > s.cfg.MaxObjectSize {
+		return nil, nil, errEntityTooLarge
+	}
+	if payloadHash != unsignedPayload {
+		// the streaming payloads are signed per chunk, they are not supported
+		sum := sha256.Sum256(payload)
+		if hex.EncodeToString(sum[:]) != payloadHash {
+			return nil, nil, errContentSha256Mismatch
+		}
+	}
+
+	return &acc, payload, nil
+}
+
+func putObject(ctx context.Context, w http.ResponseWriter, store *filemodel.Store, bucket string, key string, content []byte) *s3Error {
+	if len(content) == 0 {
+		// the models can't be empty
+		return errInvalidRequest
+	}
+	dataId, err := store.Put(ctx, bucket, key, content)
+	if err != nil {
+		return internalError(err)
+	}
+	log.Debugf("object %s/%s stored as %s", bucket, key, dataId)
+
+	w.Header().Set("ETag", etag(content))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func getObject(ctx context.Context, w http.ResponseWriter, store *filemodel.Store, bucket string, key string) *s3Error {
+	_, content, err := store.Get(ctx, bucket, key)
+	if errors.Is(err, types.ErrNotFound) {
+		return errNoSuchKey
+	} else if err != nil {
+		return internalError(err)
+	}
+
+	w.Header().Set("ETag", etag(content))
+	contentType := utils.DetectContentType(key, content)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+	return nil
+}
+
+func deleteObject(ctx context.Context, w http.ResponseWriter, store *filemodel.Store, bucket string, key string) *s3Error {
+	err := store.Delete(ctx, bucket, key)
+	// deleting a missing object succeeds in S3
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return internalError(err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func etag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return fmt.Sprintf("\"%s\"", hex.EncodeToString(sum[:16]))
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string
+	Message string
+	status  int
+}
+
+var (
+	errAccessDenied          = &s3Error{Code: "AccessDenied", Message: "Access Denied", status: http.StatusForbidden}
+	errInvalidAccessKeyId    = &s3Error{Code: "InvalidAccessKeyId", Message: "The access key does not exist", status: http.StatusForbidden}
+	errSignatureDoesNotMatch = &s3Error{Code: "SignatureDoesNotMatch", Message: "The request signature does not match", status: http.StatusForbidden}
+	errRequestTimeTooSkewed  = &s3Error{Code: "RequestTimeTooSkewed", Message: "The request time is too skewed", status: http.StatusForbidden}
+	errContentSha256Mismatch = &s3Error{Code: "XAmzContentSHA256Mismatch", Message: "The payload doesn't match x-amz-content-sha256", status: http.StatusBadRequest}
+	errIncompleteBody        = &s3Error{Code: "IncompleteBody", Message: "The request body is incomplete", status: http.StatusBadRequest}
+	errEntityTooLarge        = &s3Error{Code: "EntityTooLarge", Message: "The object is too large", status: http.StatusBadRequest}
+	errInvalidRequest        = &s3Error{Code: "InvalidRequest", Message: "Empty objects are not supported", status: http.StatusBadRequest}
+	errNoSuchKey             = &s3Error{Code: "NoSuchKey", Message: "The specified key does not exist", status: http.StatusNotFound}
+	errNotImplemented        = &s3Error{Code: "NotImplemented", Message: "Only PUT, GET, HEAD and DELETE of path-style objects are supported", status: http.StatusNotImplemented}
+)
+
+func internalError(err error) *s3Error {
+	log.Error(err)
+	return &s3Error{Code: "InternalError", Message: err.Error(), status: http.StatusInternalServerError}
+}
+
+func writeError(w http.ResponseWriter, e *s3Error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	_ = xml.NewEncoder(&buf).Encode(e)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,152 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// the requests signed earlier or later are rejected, like S3 does
+	maxClockSkew = 15 * time.Minute
+)
+
+// sigV4 is the parsed Authorization header of a request signed by AWS signature version 4.
+type sigV4 struct {
+	accessKey     string
+	date          string
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+}
+
+// parseSigV4 parses "AWS4-HMAC-SHA256 Credential=<key>/<date>/<region>/<service>/aws4_request,
+// SignedHeaders=<headers>, Signature=<signature>".
+func parseSigV4(auth string) (*sigV4, bool) {
+	if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+		return nil, false
+	}
+
+	var sig sigV4
+	for _, part := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return nil, false
+		}
+		switch kv[0] {
+		case "Credential":
+			scope := strings.Split(kv[1], "/")
+			if len(scope) != 5 || scope[4] != "aws4_request" {
+				return nil, false
+			}
+			sig.accessKey, sig.date, sig.region, sig.service = scope[0], scope[1], scope[2], scope[3]
+		case "SignedHeaders":
+			sig.signedHeaders = strings.Split(kv[1], ";")
+		case "Signature":
+			sig.signature = kv[1]
+		}
+	}
+	if sig.accessKey == "" || len(sig.signedHeaders) == 0 || sig.signature == "" {
+		return nil, false
+	}
+	return &sig, true
+}
+
+func (sig *sigV4) scope() string {
+	return strings.Join([]string{sig.date, sig.region, sig.service, "aws4_request"}, "/")
+}
+
+// verify checks the signature of the request with the secret key, payloadHash is the
+// x-amz-content-sha256 header.
+func (sig *sigV4) verify(r *http.Request, secretKey string, payloadHash string) bool {
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, sig.date) {
+		return false
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders(r, sig.signedHeaders),
+		strings.Join(sig.signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, sig.scope(), hex.EncodeToString(hash[:])}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+secretKey), sig.date)
+	for _, s := range []string{sig.region, sig.service, "aws4_request"} {
+		key = hmacSha256(key, s)
+	}
+	expected := hex.EncodeToString(hmacSha256(key, stringToSign))
+	return hmac.Equal([]byte(expected), []byte(sig.signature))
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func canonicalHeaders(r *http.Request, signed []string) string {
+	var b strings.Builder
+	for _, name := range signed {
+		var value string
+		if name == "host" {
+			value = r.Host
+		} else {
+			value = strings.Join(r.Header.Values(name), ",")
+		}
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(strings.Fields(value), " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// uriEncode encodes everything except the unreserved characters, as the signature version 4 requires.
+func uriEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&15])
+	}
+	return b.String()
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"sao-node/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next returns the first time after t the job runs, the zero time if never.
+	Next(t time.Time) time.Time
+}
+
+var descriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// Parse parses a cron expression of minute, hour, day of month, month and day of week, e.g. "30 2 * * 1-5".
+// The fields accept *, lists, ranges and steps, the day of week is 0 to 7 and both 0 and 7 are Sunday. The
+// descriptors @hourly, @daily, @weekly, @monthly and @yearly are accepted too, and "@every <duration>" runs the
+// job at a fixed interval.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || interval < time.Second {
+			return nil, types.Wrapf(types.ErrInvalidParameters, "invalid interval of %q", spec)
+		}
+		return every(interval), nil
+	}
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "%q should have 5 fields, got %d", spec, len(fields))
+	}
+	var c cron
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.anyDom = strings.HasPrefix(fields[2], "*")
+	c.anyDow = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseField returns the bits of the values matched by the field.
+func parseField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rng = part[:i]
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, types.Wrapf(types.ErrInvalidParameters, "invalid step in %q", part)
+			}
+			step = s
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, types.Wrapf(types.ErrInvalidParameters, "invalid value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, types.Wrapf(types.ErrInvalidParameters, "invalid value in %q", part)
+				}
+			} else if step > 1 {
+				// "5/15" is the same as "5-59/15"
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, types.Wrapf(types.ErrInvalidParameters, "%q is out of %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// a day matches either of the day of month and the day of week if both are given, as in cron
+	anyDom, anyDow bool
+}
+
+func (c cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// no match in 5 years, e.g. February 30
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.anyDom || c.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
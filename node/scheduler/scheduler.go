@@ -0,0 +1,211 @@
+// Package scheduler runs the periodic jobs of the node, e.g. the garbage collection of the stored shards, on cron
+// like schedules. The state of the jobs survives the restarts, so the last results are listed after a restart and
+// a job missed while the node was down is not run again, the next run is computed from the start.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"sao-node/types"
+
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("scheduler")
+
+var keyPrefix = datastore.NewKey("/jobs")
+
+// Func runs a job, the result is a short summary of what was done.
+type Func func(ctx context.Context) (string, error)
+
+// State is the persisted state of a job.
+type State struct {
+	Name string
+
+	Runs     uint64
+	Failures uint64
+	// the start and the duration of the last run
+	LastRun      int64
+	LastDuration time.Duration
+	LastResult   string
+	LastError    string
+}
+
+// Info describes a job and its state.
+type Info struct {
+	State
+
+	Enabled  bool
+	Schedule string
+	Running  bool
+	// zero if the job is disabled or never runs again
+	NextRun int64
+}
+
+type job struct {
+	name     string
+	enabled  bool
+	spec     string
+	schedule Schedule
+	run      Func
+
+	running bool
+	next    time.Time
+	state   State
+}
+
+type Scheduler struct {
+	ds datastore.Batching
+
+	lk      sync.Mutex
+	jobs    map[string]*job
+	started bool
+}
+
+func New(ds datastore.Batching) *Scheduler {
+	return &Scheduler{
+		ds:   ds,
+		jobs: make(map[string]*job),
+	}
+}
+
+// Add registers the job run on the schedule spec, see Parse. The disabled jobs are listed but never run.
+func (s *Scheduler) Add(ctx context.Context, name string, enabled bool, spec string, run Func) error {
+	j := &job{
+		name:    name,
+		enabled: enabled,
+		spec:    spec,
+		run:     run,
+		state:   State{Name: name},
+	}
+	if enabled {
+		schedule, err := Parse(spec)
+		if err != nil {
+			return types.Wrapf(types.ErrInvalidConfig, "schedule of job %s: %v", name, err)
+		}
+		j.schedule = schedule
+	}
+
+	data, err := s.ds.Get(ctx, keyPrefix.ChildString(name))
+	if err == nil {
+		if err := json.Unmarshal(data, &j.state); err != nil {
+			log.Warnf("invalid state of job %s: %v", name, err)
+		}
+	} else if err != datastore.ErrNotFound {
+		return types.Wrap(types.ErrGetFailed, err)
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return types.Wrapf(types.ErrInvalidParameters, "job %s exists", name)
+	}
+	s.jobs[name] = j
+	if s.started && enabled {
+		go s.loop(ctx, j)
+	}
+	return nil
+}
+
+// Start runs the enabled jobs on their schedules until ctx is done, the jobs added later are run once added.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.started = true
+	for _, j := range s.jobs {
+		if j.enabled {
+			go s.loop(ctx, j)
+		}
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	for {
+		next := j.schedule.Next(time.Now())
+		s.lk.Lock()
+		j.next = next
+		s.lk.Unlock()
+		if next.IsZero() {
+			log.Warnf("job %s never runs again", j.name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			s.runJob(ctx, j)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, j *job) {
+	s.lk.Lock()
+	j.running = true
+	s.lk.Unlock()
+
+	start := time.Now()
+	result, err := j.run(ctx)
+	duration := time.Since(start)
+	if err != nil {
+		log.Warnf("job %s failed after %s: %v", j.name, duration, err)
+	} else {
+		log.Infof("job %s done in %s: %s", j.name, duration, result)
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	j.running = false
+	j.state.Runs++
+	j.state.LastRun = start.Unix()
+	j.state.LastDuration = duration
+	j.state.LastResult = result
+	j.state.LastError = ""
+	if err != nil {
+		j.state.Failures++
+		j.state.LastError = err.Error()
+	}
+
+	data, err := json.Marshal(j.state)
+	if err != nil {
+		log.Warnf("marshal state of job %s: %v", j.name, err)
+		return
+	}
+	if err := s.ds.Put(ctx, keyPrefix.ChildString(j.name), data); err != nil {
+		log.Warnf("save state of job %s: %v", j.name, err)
+	}
+}
+
+// List returns the jobs ordered by name.
+func (s *Scheduler) List() []Info {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	list := make([]Info, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		info := Info{
+			State:    j.state,
+			Enabled:  j.enabled,
+			Schedule: j.spec,
+			Running:  j.running,
+		}
+		if !j.next.IsZero() {
+			info.NextRun = j.next.Unix()
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
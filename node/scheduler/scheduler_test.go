@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2022, 12, 7, 10, 30, 15, 0, time.UTC)
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2022, 12, 7, 10, 31, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2022, 12, 8, 2, 30, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2022, 12, 7, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2022, 12, 7, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2022, 12, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2022, 12, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// either the day of month or the day of week
+		{"0 0 20 * 5", time.Date(2022, 12, 9, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2022, 12, 8, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", now.Add(90 * time.Minute)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, c := range cases {
+		schedule, err := Parse(c.spec)
+		require.NoError(t, err, c.spec)
+		require.Equal(t, c.next, schedule.Next(now), c.spec)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every 1ms", "@never"} {
+		_, err := Parse(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	s := New(ds)
+	fail := false
+	require.NoError(t, s.Add(ctx, "gc", true, "@hourly", func(ctx context.Context) (string, error) {
+		if fail {
+			return "", errors.New("failed")
+		}
+		return "done", nil
+	}))
+	require.NoError(t, s.Add(ctx, "scrub", false, "invalid", nil))
+	require.Error(t, s.Add(ctx, "audits", true, "invalid", nil))
+	require.Error(t, s.Add(ctx, "gc", true, "@daily", nil))
+
+	s.runJob(ctx, s.jobs["gc"])
+	fail = true
+	s.runJob(ctx, s.jobs["gc"])
+
+	s.Start(ctx)
+	require.Eventually(t, func() bool {
+		return s.List()[0].NextRun > 0
+	}, time.Second, 10*time.Millisecond)
+
+	list := s.List()
+	require.Len(t, list, 2)
+	require.Equal(t, "gc", list[0].Name)
+	require.Equal(t, uint64(2), list[0].Runs)
+	require.Equal(t, uint64(1), list[0].Failures)
+	require.Equal(t, "failed", list[0].LastError)
+	require.Equal(t, "scrub", list[1].Name)
+	require.False(t, list[1].Enabled)
+	require.Zero(t, list[1].NextRun)
+
+	// the state survives a restart
+	s = New(ds)
+	require.NoError(t, s.Add(ctx, "gc", true, "@hourly", nil))
+	list = s.List()
+	require.Equal(t, uint64(2), list[0].Runs)
+	require.Equal(t, "failed", list[0].LastError)
+}
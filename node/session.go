@@ -0,0 +1,143 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sao-node/types"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// session keys are delegations registered at the gateway, so the proposals signed by the session key are
+// accepted without attaching the delegation, when the request names the issuer in Session.
+const sessionPrefix = "/session"
+
+func sessionKey(audience string) datastore.Key {
+	return datastore.NewKey(sessionPrefix).ChildString(audience)
+}
+
+// SessionRegister registers the audience of the delegation as a session key of the issuer until the delegation expires.
+func (n *Node) SessionRegister(ctx context.Context, session *types.SignedDelegation) error {
+	delegation := session.Delegation
+	if delegation.Issuer == delegation.Audience || delegation.Issuer == "all" {
+		return types.Wrapf(types.ErrInvalidDelegation, "invalid issuer %s", delegation.Issuer)
+	}
+	if delegation.Expiration <= time.Now().Unix() {
+		return types.Wrapf(types.ErrInvalidDelegation, "delegation expired")
+	}
+	if delegation.Nonce == "" {
+		return types.Wrapf(types.ErrInvalidDelegation, "the delegation has no nonce to revoke it by")
+	}
+
+	err := n.validSignature(ctx, &delegation, delegation.Issuer, session.JwsSignature)
+	if err != nil {
+		return types.Wrapf(types.ErrInvalidDelegation, "verify the delegation of %s: %v", delegation.Issuer, err)
+	}
+
+	// one session key can only act for one issuer
+	existing, err := n.loadSession(ctx, delegation.Audience)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Delegation.Issuer != delegation.Issuer {
+		return types.Wrapf(types.ErrInvalidDelegation, "%s is a session key of %s already", delegation.Audience, existing.Delegation.Issuer)
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	err = n.mds.Put(ctx, sessionKey(delegation.Audience), data)
+	if err != nil {
+		return types.Wrap(types.ErrStoreFailed, err)
+	}
+
+	log.Infof("registered session key %s of %s, expires at %s", delegation.Audience, delegation.Issuer,
+		time.Unix(delegation.Expiration, 0).Format(time.RFC3339))
+	return nil
+}
+
+// SessionRevoke removes the session key, the request is signed by the issuer of the session and names the nonce of
+// the session's delegation, so it can't be replayed against the session registered again.
+func (n *Node) SessionRevoke(ctx context.Context, req *types.SessionRevokeProposal) error {
+	if req.Proposal.Expiration <= time.Now().Unix() {
+		return types.Wrapf(types.ErrInvalidParameters, "the revoke request expired")
+	}
+
+	err := n.validSignature(ctx, &req.Proposal, req.Proposal.Issuer, req.JwsSignature)
+	if err != nil {
+		return err
+	}
+
+	session, err := n.loadSession(ctx, req.Proposal.Audience)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.Delegation.Issuer != req.Proposal.Issuer {
+		return types.Wrapf(types.ErrNotFound, "%s is not a session key of %s", req.Proposal.Audience, req.Proposal.Issuer)
+	}
+	if session.Delegation.Nonce != req.Proposal.Nonce {
+		return types.Wrapf(types.ErrNotFound, "the session %s of %s is not found", req.Proposal.Nonce, req.Proposal.Issuer)
+	}
+
+	err = n.mds.Delete(ctx, sessionKey(req.Proposal.Audience))
+	if err != nil {
+		return types.Wrap(types.ErrRemoveFailed, err)
+	}
+
+	log.Infof("revoked session key %s of %s", req.Proposal.Audience, req.Proposal.Issuer)
+	return nil
+}
+
+// SessionList returns the unexpired session keys of the issuer.
+func (n *Node) SessionList(ctx context.Context, issuer string) ([]types.SignedDelegation, error) {
+	results, err := n.mds.Query(ctx, query.Query{Prefix: sessionPrefix})
+	if err != nil {
+		return nil, types.Wrap(types.ErrGetFailed, err)
+	}
+	defer results.Close()
+
+	sessions := make([]types.SignedDelegation, 0)
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, types.Wrap(types.ErrGetFailed, r.Error)
+		}
+
+		var session types.SignedDelegation
+		err := json.Unmarshal(r.Value, &session)
+		if err != nil {
+			return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+		if session.Delegation.Issuer == issuer && session.Delegation.Expiration > time.Now().Unix() {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// loadSession returns the unexpired session of the audience, or nil if there is none.
+func (n *Node) loadSession(ctx context.Context, audience string) (*types.SignedDelegation, error) {
+	data, err := n.mds.Get(ctx, sessionKey(audience))
+	if err == datastore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, types.Wrap(types.ErrGetFailed, err)
+	}
+
+	var session types.SignedDelegation
+	err = json.Unmarshal(data, &session)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+
+	if session.Delegation.Expiration <= time.Now().Unix() {
+		if err := n.mds.Delete(ctx, sessionKey(audience)); err != nil {
+			log.Warnf("delete expired session %s: %v", audience, err)
+		}
+		return nil, nil
+	}
+	return &session, nil
+}
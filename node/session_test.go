@@ -0,0 +1,67 @@
+package node
+
+import (
+	"context"
+	"sao-node/types"
+	"testing"
+	"time"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	n := newTestNode(t)
+	issuer, session := newTestDid(t), newTestDid(t)
+
+	delegation := types.Delegation{
+		Issuer:       issuer.Id,
+		Audience:     session.Id,
+		Capabilities: []string{types.CapModelLoad},
+		Resources:    []string{types.CapAll},
+		Expiration:   time.Now().Add(time.Hour).Unix(),
+		Nonce:        "nonce1",
+	}
+	require.NoError(t, n.SessionRegister(ctx, &types.SignedDelegation{Delegation: delegation, JwsSignature: signTest(t, issuer, &delegation)}))
+
+	request := func(sessionOf string) *types.MetadataProposal {
+		req := &types.MetadataProposal{Proposal: saotypes.QueryProposal{Owner: session.Id, Keyword: "data1"}, Session: sessionOf}
+		req.JwsSignature = signTest(t, session, &req.Proposal)
+		return req
+	}
+
+	// the session key acts for the issuer only when the request asks for it
+	req := request(issuer.Id)
+	require.NoError(t, n.validQueryProposal(ctx, req, types.CapModelLoad))
+	require.Equal(t, issuer.Id, req.Delegation.Delegation.Issuer)
+	req = request("")
+	require.NoError(t, n.validQueryProposal(ctx, req, types.CapModelLoad))
+	require.Nil(t, req.Delegation)
+	err := n.validQueryProposal(ctx, request(newTestDid(t).Id), types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+
+	revoke := func(nonce string, expiration time.Time) error {
+		proposal := types.SessionRevoke{Issuer: issuer.Id, Audience: session.Id, Nonce: nonce, Expiration: expiration.Unix()}
+		return n.SessionRevoke(ctx, &types.SessionRevokeProposal{Proposal: proposal, JwsSignature: signTest(t, issuer, &proposal)})
+	}
+	require.True(t, types.ErrInvalidParameters.Is(revoke("nonce1", time.Now().Add(-time.Second))))
+	require.True(t, types.ErrNotFound.Is(revoke("nonce2", time.Now().Add(time.Minute))))
+
+	// a captured revoke request can't revoke the session registered again
+	captured := types.SessionRevoke{Issuer: issuer.Id, Audience: session.Id, Nonce: "nonce1", Expiration: time.Now().Add(time.Minute).Unix()}
+	capturedReq := &types.SessionRevokeProposal{Proposal: captured, JwsSignature: signTest(t, issuer, &captured)}
+	require.NoError(t, n.SessionRevoke(ctx, capturedReq))
+	err = n.validQueryProposal(ctx, request(issuer.Id), types.CapModelLoad)
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+
+	delegation.Nonce = "nonce2"
+	require.NoError(t, n.SessionRegister(ctx, &types.SignedDelegation{Delegation: delegation, JwsSignature: signTest(t, issuer, &delegation)}))
+	require.True(t, types.ErrNotFound.Is(n.SessionRevoke(ctx, capturedReq)))
+	require.NoError(t, n.validQueryProposal(ctx, request(issuer.Id), types.CapModelLoad))
+
+	// sessions are revoked by nonce, so they must have one
+	delegation.Nonce = ""
+	err = n.SessionRegister(ctx, &types.SignedDelegation{Delegation: delegation, JwsSignature: signTest(t, issuer, &delegation)})
+	require.True(t, types.ErrInvalidDelegation.Is(err))
+}
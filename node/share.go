@@ -0,0 +1,89 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sao-node/types"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// the shares and the uploads revoked at this gateway, see types.EncodeShareToken and types.EncodeUploadToken. They
+// are kept until they expire, the delegations of the tokens are rejected after that anyway.
+const shareRevokedPrefix = "/share/revoked"
+
+func shareRevokedKey(issuer string, nonce string) datastore.Key {
+	return datastore.NewKey(shareRevokedPrefix).ChildString(issuer).ChildString(nonce)
+}
+
+// ModelShareRevoke adds the delegation of a share, an upload token or a DID to the revocation list of the gateway by
+// its nonce, the request is signed by the issuer of the delegation.
+func (n *Node) ModelShareRevoke(ctx context.Context, req *types.ShareRevokeProposal) error {
+	revoke := req.Proposal
+	if revoke.Issuer == "all" || revoke.Nonce == "" {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid share %s of %s", revoke.Nonce, revoke.Issuer)
+	}
+	err := n.validSignature(ctx, &revoke, revoke.Issuer, req.JwsSignature)
+	if err != nil {
+		return err
+	}
+
+	n.pruneRevokedShares(ctx)
+	if revoke.Expiration <= time.Now().Unix() {
+		return nil
+	}
+
+	data, err := json.Marshal(revoke)
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	err = n.mds.Put(ctx, shareRevokedKey(revoke.Issuer, revoke.Nonce), data)
+	if err != nil {
+		return types.Wrap(types.ErrStoreFailed, err)
+	}
+
+	log.Infof("revoked share %s of %s", revoke.Nonce, revoke.Issuer)
+	return nil
+}
+
+// checkRevoked rejects the delegation revoked by its nonce, as a share, an upload token or a delegation.
+func (n *Node) checkRevoked(ctx context.Context, delegation *types.Delegation) error {
+	revoked, err := n.mds.Has(ctx, shareRevokedKey(delegation.Issuer, delegation.Nonce))
+	if err != nil {
+		return types.Wrap(types.ErrGetFailed, err)
+	}
+	if revoked {
+		return types.Wrapf(types.ErrInvalidDelegation, "the delegation %s of %s is revoked", delegation.Nonce, delegation.Issuer)
+	}
+	return nil
+}
+
+// pruneRevokedShares removes the revoked shares expired.
+func (n *Node) pruneRevokedShares(ctx context.Context) {
+	results, err := n.mds.Query(ctx, query.Query{Prefix: shareRevokedPrefix})
+	if err != nil {
+		log.Warnf("query revoked shares: %v", err)
+		return
+	}
+	defer results.Close()
+
+	now := time.Now().Unix()
+	var expired []datastore.Key
+	for r := range results.Next() {
+		if r.Error != nil {
+			log.Warnf("query revoked shares: %v", r.Error)
+			break
+		}
+		var revoke types.ShareRevoke
+		if err := json.Unmarshal(r.Value, &revoke); err != nil || revoke.Expiration <= now {
+			expired = append(expired, datastore.NewKey(r.Key))
+		}
+	}
+	for _, key := range expired {
+		if err := n.mds.Delete(ctx, key); err != nil {
+			log.Warnf("delete revoked share %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package staging
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+)
+
+const (
+	// encrypted staging file layout: magic | iv | AES-256-CTR encrypted content
+	cipherMagic = "SAOSTG1"
+	headerLen   = len(cipherMagic) + aes.BlockSize
+	// KeyLen is the length of the staging key
+	KeyLen = 32
+)
+
+// Cipher encrypts the shards staged by the gateway, so the content of the clients is not kept in plaintext on
+// the disk of the host. Each file is encrypted with its own key, derived from the staging key of the repo and the
+// owner and the cid of the shard, and a random iv. The content is not authenticated, the shards are checked
+// against their cids by the storage nodes. A nil Cipher stages the files in plaintext.
+type Cipher struct {
+	key []byte
+	// the files staged in plaintext are read anyway, encrypt is false while the encryption is disabled
+	encrypt bool
+}
+
+func NewCipher(key []byte, encrypt bool) (*Cipher, error) {
+	if len(key) != KeyLen {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "staging key of %d bytes, should be %d", len(key), KeyLen)
+	}
+	return &Cipher{key: key, encrypt: encrypt}, nil
+}
+
+// Encrypts reports whether the files staged are encrypted.
+func (c *Cipher) Encrypts() bool {
+	return c != nil && c.encrypt
+}
+
+func (c *Cipher) block(owner string, cid string) (cipher.Block, error) {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(owner + "/" + cid))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	return block, nil
+}
+
+// Encrypt writes the header of the staging file of the shard to w, and returns the writer encrypting the
+// content to w. w is returned as is if the encryption is disabled.
+func (c *Cipher) Encrypt(w io.Writer, owner string, cid string) (io.Writer, error) {
+	if !c.Encrypts() {
+		return w, nil
+	}
+
+	block, err := c.block(owner, cid)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	if _, err := w.Write(append([]byte(cipherMagic), iv...)); err != nil {
+		return nil, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return cipher.StreamWriter{S: cipher.NewCTR(block, iv), W: w}, nil
+}
+
+// Open opens the staging file at path, <staging path>/<owner>/<cid>, for reading. The files staged in plaintext
+// are read as they are.
+func (c *Cipher) Open(path string) (*File, error) {
+	return c.OpenAs(path, filepath.Base(filepath.Dir(path)), filepath.Base(path))
+}
+
+// OpenAs opens the file at path staged as the shard of the cid of the owner, e.g. a temporary file not renamed into
+// place yet.
+func (c *Cipher) OpenAs(path string, owner string, cid string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	f := &File{file: file, size: stat.Size()}
+	header := make([]byte, headerLen)
+	if n, _ := file.ReadAt(header, 0); n < headerLen || !bytes.HasPrefix(header, []byte(cipherMagic)) {
+		return f, nil
+	}
+	if c == nil {
+		file.Close()
+		return nil, types.Wrapf(types.ErrInvalidContent, "%s is encrypted, but no staging key is given", path)
+	}
+
+	f.block, err = c.block(owner, cid)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	f.iv = header[len(cipherMagic):]
+	f.header = int64(headerLen)
+	f.size -= f.header
+	return f, nil
+}
+
+// File is a staging file opened for reading, the content is decrypted while read.
+type File struct {
+	file *os.File
+	// nil if the file is in plaintext
+	block  cipher.Block
+	iv     []byte
+	header int64
+
+	offset int64
+	size   int64
+}
+
+// Size returns the size of the content.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	if f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if remain := f.size - f.offset; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+
+	n, err := f.file.ReadAt(p, f.header+f.offset)
+	if f.block != nil {
+		f.xor(p[:n], f.offset)
+	}
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// xor decrypts p read at offset, the counter of the block at offset is the iv plus the index of the block.
+func (f *File) xor(p []byte, offset int64) {
+	// on the stack, the file is read in many small reads
+	var iv [aes.BlockSize]byte
+	copy(iv[:], f.iv)
+	counter := uint64(offset / aes.BlockSize)
+	for i := len(iv) - 1; i >= 0 && counter > 0; i-- {
+		sum := uint64(iv[i]) + counter&0xff
+		iv[i] = byte(sum)
+		counter = counter>>8 + sum>>8
+	}
+
+	stream := cipher.NewCTR(f.block, iv[:])
+	if skip := offset % aes.BlockSize; skip > 0 {
+		var pad [aes.BlockSize]byte
+		stream.XORKeyStream(pad[:skip], pad[:skip])
+	}
+	stream.XORKeyStream(p, p)
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, types.Wrapf(types.ErrInvalidParameters, "invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "negative offset %d", offset)
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *File) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,220 @@
+// Package staging accounts the files under the staging path of the node against the quotas. The gateway stages
+// the shards of an owner under <path>/<did> and the uploads of a client under <path>/<peer id>, each of these
+// directories is limited by the owner quota and all of them by the total quota.
+package staging
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	apitypes "sao-node/api/types"
+	"sao-node/types"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// Quota tracks the bytes staged under each owner directory. The usage grows with the reservations and is
+// computed again from the files by Scan, so the removed files are released by the next scan. A nil Quota
+// never rejects.
+type Quota struct {
+	path string
+	// 0 is unlimited
+	ownerQuota int64
+	totalQuota int64
+
+	lk        sync.Mutex
+	owners    map[string]int64
+	total     int64
+	files     int
+	removed   uint64
+	scannedAt time.Time
+}
+
+// NewQuota creates the quota of the staging path and scans the files staged already.
+func NewQuota(path string, ownerQuota int64, totalQuota int64) (*Quota, error) {
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidPath, "%s", path)
+	}
+	if err := os.MkdirAll(expanded, 0755); err != nil {
+		return nil, types.Wrap(types.ErrCreateDirFailed, err)
+	}
+
+	q := &Quota{
+		path:       expanded,
+		ownerQuota: ownerQuota,
+		totalQuota: totalQuota,
+		owners:     make(map[string]int64),
+	}
+	if err := q.Scan(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Path returns the expanded staging path.
+func (q *Quota) Path() string {
+	return q.path
+}
+
+// Scan computes the usage from the files under the staging path.
+func (q *Quota) Scan() error {
+	if q == nil {
+		return nil
+	}
+
+	owners := make(map[string]int64)
+	var total int64
+	files := 0
+	err := filepath.WalkDir(q.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(q.path, path)
+		if err != nil {
+			return err
+		}
+		owners[owner(rel)] += info.Size()
+		total += info.Size()
+		files++
+		return nil
+	})
+	if err != nil {
+		return types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	q.lk.Lock()
+	defer q.lk.Unlock()
+	q.owners = owners
+	q.total = total
+	q.files = files
+	q.scannedAt = time.Now()
+	return nil
+}
+
+// owner returns the owner directory of the path relative to the staging path.
+func owner(rel string) string {
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	if len(parts) < 2 {
+		// a file right under the staging path
+		return ""
+	}
+	return parts[0]
+}
+
+// Locate returns the staging file of the cid staged for the owner under one of the staging paths, the staging path
+// of the node first and then the ones of the platforms staging apart. The file under the first path is returned if
+// the cid is staged under none of them.
+func Locate(paths []string, owner string, cid string) (string, error) {
+	var first string
+	for i, path := range paths {
+		expanded, err := homedir.Expand(path)
+		if err != nil {
+			return "", types.Wrapf(types.ErrInvalidPath, "%s", path)
+		}
+		file := filepath.Join(expanded, owner, cid)
+		if i == 0 {
+			first = file
+		}
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return first, nil
+}
+
+// Reserve accounts size more bytes to the owner, ErrNoCapacity is returned if a quota would be exceeded.
+func (q *Quota) Reserve(owner string, size int64) error {
+	if q == nil {
+		return nil
+	}
+
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	if q.totalQuota > 0 && q.total+size > q.totalQuota {
+		return types.Wrapf(types.ErrNoCapacity, "not enough staging space under %s, need %d but only %d left",
+			q.path, size, q.totalQuota-q.total)
+	}
+	if q.ownerQuota > 0 && q.owners[owner]+size > q.ownerQuota {
+		return types.Wrapf(types.ErrNoCapacity, "staging quota of %s exceeded, need %d but only %d left",
+			owner, size, q.ownerQuota-q.owners[owner])
+	}
+	q.owners[owner] += size
+	q.total += size
+	return nil
+}
+
+// Release gives back the bytes reserved for the owner by Reserve, for the files never staged.
+func (q *Quota) Release(owner string, size int64) {
+	if q == nil {
+		return
+	}
+
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	q.owners[owner] -= size
+	if q.owners[owner] <= 0 {
+		delete(q.owners, owner)
+	}
+	q.total -= size
+	if q.total < 0 {
+		q.total = 0
+	}
+}
+
+// Removed counts the files removed by the janitor.
+func (q *Quota) Removed(files int) {
+	if q == nil {
+		return
+	}
+
+	q.lk.Lock()
+	defer q.lk.Unlock()
+	q.removed += uint64(files)
+}
+
+// Usage returns the staged bytes, the largest owners first.
+func (q *Quota) Usage() apitypes.StagingUsage {
+	if q == nil {
+		return apitypes.StagingUsage{}
+	}
+
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	usage := apitypes.StagingUsage{
+		Path:       q.path,
+		Bytes:      q.total,
+		Files:      q.files,
+		Quota:      q.totalQuota,
+		OwnerQuota: q.ownerQuota,
+		Removed:    q.removed,
+		ScannedAt:  q.scannedAt.Unix(),
+	}
+	for owner, bytes := range q.owners {
+		if bytes > 0 {
+			usage.Owners = append(usage.Owners, apitypes.StagingOwnerUsage{Owner: owner, Bytes: bytes})
+		}
+	}
+	sort.Slice(usage.Owners, func(i, j int) bool {
+		return usage.Owners[i].Bytes > usage.Owners[j].Bytes
+	})
+	return usage
+}
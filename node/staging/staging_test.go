@@ -0,0 +1,150 @@
+package staging
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuota(t *testing.T) {
+	path := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "did1"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "did1", "cid1"), make([]byte, 6), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "peer1", "cid2"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "peer1", "cid2", "chunk"), make([]byte, 2), 0644))
+
+	q, err := NewQuota(path, 8, 12)
+	require.NoError(t, err)
+	usage := q.Usage()
+	require.Equal(t, int64(8), usage.Bytes)
+	require.Equal(t, 2, usage.Files)
+	require.Equal(t, "did1", usage.Owners[0].Owner)
+	require.Equal(t, int64(2), usage.Owners[1].Bytes)
+
+	require.NoError(t, q.Reserve("did1", 2))
+	// over the quota of the owner
+	require.True(t, errors.Is(q.Reserve("did1", 1), types.ErrNoCapacity))
+	require.NoError(t, q.Reserve("did2", 2))
+	// over the total quota
+	require.True(t, errors.Is(q.Reserve("peer1", 1), types.ErrNoCapacity))
+	// the bytes never staged are given back
+	q.Release("did2", 2)
+	require.NoError(t, q.Reserve("peer1", 1))
+	q.Release("peer1", 1)
+
+	// the reservations are replaced by the files on scan
+	require.NoError(t, os.Remove(filepath.Join(path, "did1", "cid1")))
+	require.NoError(t, q.Scan())
+	require.Equal(t, int64(2), q.Usage().Bytes)
+	require.NoError(t, q.Reserve("did1", 8))
+
+	var nilQuota *Quota
+	require.NoError(t, nilQuota.Reserve("did1", 100))
+	nilQuota.Release("did1", 100)
+}
+
+func TestLocate(t *testing.T) {
+	node, platform := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(platform, "did1"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(platform, "did1", "cid1"), []byte("shard"), 0644))
+
+	file, err := Locate([]string{node, platform}, "did1", "cid1")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(platform, "did1", "cid1"), file)
+	// the path of the node if not staged
+	file, err = Locate([]string{node, platform}, "did1", "cid2")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(node, "did1", "cid2"), file)
+}
+
+func TestCipher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "did1")
+	require.NoError(t, os.MkdirAll(path, 0755))
+	content := make([]byte, 100000)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	c, err := NewCipher(bytes.Repeat([]byte{1}, KeyLen), true)
+	require.NoError(t, err)
+	stage := func(c *Cipher, cid string) string {
+		buf := new(bytes.Buffer)
+		w, err := c.Encrypt(buf, "did1", cid)
+		require.NoError(t, err)
+		_, err = w.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(path, cid), buf.Bytes(), 0644))
+		return filepath.Join(path, cid)
+	}
+
+	encrypted := stage(c, "cid1")
+	data, err := os.ReadFile(encrypted)
+	require.NoError(t, err)
+	require.Len(t, data, len(content)+headerLen)
+	require.False(t, bytes.Contains(data, content[:64]))
+
+	f, err := c.Open(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), f.Size())
+	data, err = io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+
+	// read from offsets within the blocks
+	for _, offset := range []int64{1, 15, 16, 4095, 65537} {
+		_, err = f.Seek(offset, io.SeekStart)
+		require.NoError(t, err)
+		data = make([]byte, 100)
+		_, err = io.ReadFull(f, data)
+		require.NoError(t, err)
+		require.Equal(t, content[offset:offset+100], data)
+	}
+	require.NoError(t, f.Close())
+
+	// the key is bound to the path of the file
+	require.NoError(t, os.Rename(encrypted, filepath.Join(path, "cid2")))
+	f, err = c.Open(filepath.Join(path, "cid2"))
+	require.NoError(t, err)
+	data, err = io.ReadAll(f)
+	require.NoError(t, err)
+	require.NotEqual(t, content, data)
+	require.NoError(t, f.Close())
+
+	// staged in plaintext, with the encryption disabled or before it was enabled
+	disabled, err := NewCipher(bytes.Repeat([]byte{1}, KeyLen), false)
+	require.NoError(t, err)
+	plain := stage(disabled, "cid3")
+	for _, c := range []*Cipher{c, nil} {
+		f, err = c.Open(plain)
+		require.NoError(t, err)
+		data, err = io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+		require.NoError(t, f.Close())
+	}
+}
+
+func TestCipherCounter(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte{2}, KeyLen), true)
+	require.NoError(t, err)
+	f := &File{iv: bytes.Repeat([]byte{0xff}, aes.BlockSize)}
+	f.block, err = c.block("did1", "cid1")
+	require.NoError(t, err)
+
+	// the counter of the iv overflows, the keystream at an offset matches the one of the writer
+	expected := make([]byte, 64)
+	cipher.NewCTR(f.block, f.iv).XORKeyStream(expected, expected)
+	for _, offset := range []int64{0, 7, 16, 33} {
+		p := make([]byte, 64-offset)
+		f.xor(p, offset)
+		require.Equal(t, expected[offset:], p)
+	}
+}
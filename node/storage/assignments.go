@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"sao-node/chain"
+	"sao-node/node/events"
+	"sao-node/types"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	// the assignments queued before the storage service takes them
+	assignmentBuffer = 16
+	// how long the gateway is given to notify an assignment before it is taken from the chain
+	assignmentRecoveryDelay = time.Minute
+)
+
+// watchAssignments takes the shards assigned to this node on chain the gateway didn't notify, such as while the
+// node was down, so no assignment is lost.
+func (ss *StoreSvc) watchAssignments() {
+	ss.bus.Consume(ss.ctx, events.TopicAssignment, assignmentBuffer, func(ev events.Event) {
+		assignment := ev.(events.AssignmentEvent).Assignment
+		if _, ok := assignment.Shards[ss.nodeAddress]; !ok {
+			return
+		}
+		time.AfterFunc(assignmentRecoveryDelay, func() {
+			if ss.ctx.Err() == nil {
+				ss.recoverAssignment(assignment)
+			}
+		})
+	})
+}
+
+func (ss *StoreSvc) recoverAssignment(assignment chain.ShardAssignment) {
+	shardCid, err := cid.Decode(assignment.Shards[ss.nodeAddress])
+	if err != nil {
+		log.Warnf("shard assignment of order %d: invalid cid %s", assignment.OrderId, assignment.Shards[ss.nodeAddress])
+		return
+	}
+	shard, err := ss.shards.Get(ss.ctx, assignment.OrderId, shardCid)
+	if err != nil {
+		log.Warnf("get shard order=%d cid=%v error: %v", assignment.OrderId, shardCid, err)
+		return
+	}
+	if shard != (types.ShardInfo{}) {
+		// notified by the gateway
+		return
+	}
+
+	log.Infof("recover the shard of order %d assigned by tx %s", assignment.OrderId, assignment.TxHash)
+	resp := ss.HandleShardAssign(types.ShardAssignReq{
+		OrderId:      assignment.OrderId,
+		DataId:       assignment.DataId,
+		Assignee:     ss.nodeAddress,
+		TxHash:       assignment.TxHash,
+		Height:       assignment.Height,
+		AssignTxType: assignment.TxType,
+		RequestId:    time.Now().UnixNano(),
+	}, "")
+	if resp.Code != 0 {
+		log.Errorf("recover the shard of order %d: %s", assignment.OrderId, resp.Message)
+	}
+}
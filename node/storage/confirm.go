@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"sao-node/chain"
+	"sao-node/types"
+	"sao-node/utils"
+	"strings"
+	"time"
+
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+const confirmInterval = 10 * time.Second
+
+// txConfirmer looks up the chain records the complete txs are confirmed against.
+type txConfirmer interface {
+	// GetLastHeight returns the latest block height of the chain
+	GetLastHeight(ctx context.Context) (int64, error)
+	// TxHeight returns the height the tx is included at, found is false if the tx is not on chain or failed
+	TxHeight(ctx context.Context, hash string, height int64) (int64, bool, error)
+	// ShardCompleted returns whether the shard of the order stored by provider is completed on chain
+	ShardCompleted(ctx context.Context, orderId uint64, provider string) (bool, error)
+}
+
+type chainTxConfirmer struct {
+	ss *StoreSvc
+}
+
+func (c chainTxConfirmer) GetLastHeight(ctx context.Context) (int64, error) {
+	return c.ss.chainSvc.GetLastHeight(ctx)
+}
+
+func (c chainTxConfirmer) TxHeight(ctx context.Context, hash string, height int64) (int64, bool, error) {
+	resultTx, err := c.ss.chainSvc.GetTx(ctx, hash, height)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return resultTx.Height, resultTx.TxResult.Code == 0, nil
+}
+
+func (c chainTxConfirmer) ShardCompleted(ctx context.Context, orderId uint64, provider string) (bool, error) {
+	order, err := c.ss.chainSvc.GetOrder(ctx, orderId)
+	if err != nil {
+		return false, err
+	}
+	shard, exists := order.Shards[provider]
+	return exists && shard.Status == ordertypes.ShardCompleted, nil
+}
+
+// EnableCompleteConfirmations keeps the shards and the migrations provisional until their complete txs are depth
+// blocks deep. The txs reorged out of the chain are sent again, unless the shard is completed on chain anyway.
+func (ss *StoreSvc) EnableCompleteConfirmations(ctx context.Context, depth int64) {
+	ss.confirmations = depth
+	go func() {
+		ticker := time.NewTicker(confirmInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ss.confirm(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// completeState is the state of a shard once its complete tx is sent.
+func (ss *StoreSvc) completeState() types.ShardState {
+	if ss.confirmations > 0 {
+		return types.ShardStateTxSent
+	}
+	return types.ShardStateComplete
+}
+
+func (ss *StoreSvc) confirm(ctx context.Context) {
+	height, err := ss.confirmer.GetLastHeight(ctx)
+	if err != nil {
+		log.Warnf("get last height to confirm the complete txs error: %v", err)
+		return
+	}
+	ss.confirmShards(ctx, height)
+	ss.confirmMigrations(ctx, height)
+}
+
+func (ss *StoreSvc) confirmShards(ctx context.Context, height int64) {
+	var sent []types.ShardInfo
+	err := utils.ForEachShard(ctx, ss.orderDs, func(shard types.ShardInfo) error {
+		// the txs not included yet are tracked by the chain service, see handleTxResult
+		if shard.State == types.ShardStateTxSent && shard.CompleteHeight > 0 && shard.CompleteHeight+ss.confirmations <= height {
+			sent = append(sent, shard)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warnf("list shards to confirm error: %v", err)
+		return
+	}
+
+	for _, shard := range sent {
+		txHeight, found, err := ss.confirmer.TxHeight(ctx, shard.CompleteHash, shard.CompleteHeight)
+		if err != nil {
+			log.Warnf("confirm shard order=%d cid=%v error: %v", shard.OrderId, shard.Cid, err)
+			continue
+		}
+		if found && txHeight != shard.CompleteHeight {
+			// included again at another height, to be confirmed there
+			shard.CompleteHeight = txHeight
+			if txHeight+ss.confirmations > height {
+				ss.saveShard(ctx, shard)
+				continue
+			}
+		}
+		if !found {
+			completed, err := ss.confirmer.ShardCompleted(ctx, shard.OrderId, ss.nodeAddress)
+			if err != nil {
+				log.Warnf("confirm shard order=%d cid=%v error: %v", shard.OrderId, shard.Cid, err)
+				continue
+			}
+			if !completed {
+				log.Warnf("complete tx %s of shard order=%d cid=%v is reorged out, resubmit", shard.CompleteHash, shard.OrderId, shard.Cid)
+				shard.State = types.ShardStateStored
+				shard.CompleteHash = ""
+				shard.CompleteHeight = 0
+				ss.saveShard(ctx, shard)
+				ss.tasks.Push(shard)
+				continue
+			}
+		}
+		shard.State = types.ShardStateComplete
+		ss.saveShard(ctx, shard)
+	}
+}
+
+// handleTxResult takes the result of a complete tx broadcast without waiting for its block. The shard is
+// processed again to notify the gateway once the tx is included, or to send the tx again if it was dropped.
+func (ss *StoreSvc) handleTxResult(result chain.TxResult) {
+	if result.MsgType != sdktypes.MsgTypeURL(&saotypes.MsgComplete{}) {
+		return
+	}
+	var sent []types.ShardInfo
+	err := utils.ForEachShard(ss.ctx, ss.orderDs, func(shard types.ShardInfo) error {
+		if shard.State == types.ShardStateTxSent && shard.CompleteHash == result.Hash {
+			sent = append(sent, shard)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warnf("list shards of complete tx %s error: %v", result.Hash, err)
+		return
+	}
+
+	for _, shard := range sent {
+		if result.Err == nil {
+			shard.CompleteHeight = result.Height
+			ss.saveShard(ss.ctx, shard)
+			ss.tasks.Push(shard)
+			continue
+		}
+
+		shard.State = types.ShardStateStored
+		shard.CompleteHash = ""
+		shard.CompleteHeight = 0
+		ss.updateShardError(shard, result.Err)
+		// a failed tx is sent again on the next start, as when it fails in the block mode
+		if types.ErrTxNotIncluded.Is(result.Err) {
+			log.Warnf("complete tx %s of shard order=%d cid=%v is dropped, resubmit", result.Hash, shard.OrderId, shard.Cid)
+			ss.tasks.Push(shard)
+		}
+	}
+}
+
+func (ss *StoreSvc) confirmMigrations(ctx context.Context, height int64) {
+	var unconfirmed []types.MigrateInfo
+	err := utils.ForEachMigrate(ctx, ss.orderDs, func(migrate types.MigrateInfo) error {
+		if migrate.State == types.MigrateStateUnconfirmed && migrate.CompleteTxHeight+ss.confirmations <= height {
+			unconfirmed = append(unconfirmed, migrate)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warnf("list migrations to confirm error: %v", err)
+		return
+	}
+
+	for _, migrate := range unconfirmed {
+		txHeight, found, err := ss.confirmer.TxHeight(ctx, migrate.CompleteTxHash, migrate.CompleteTxHeight)
+		if err != nil {
+			log.Warnf("confirm migration dataId=%s order=%d error: %v", migrate.DataId, migrate.OrderId, err)
+			continue
+		}
+		if found && txHeight != migrate.CompleteTxHeight {
+			migrate.CompleteTxHeight = txHeight
+			if txHeight+ss.confirmations > height {
+				ss.saveMigrate(ctx, migrate)
+				continue
+			}
+		}
+		if !found {
+			completed, err := ss.confirmer.ShardCompleted(ctx, migrate.OrderId, migrate.ToProvider)
+			if err != nil {
+				log.Warnf("confirm migration dataId=%s order=%d error: %v", migrate.DataId, migrate.OrderId, err)
+				continue
+			}
+			if !completed {
+				log.Warnf("complete tx %s of migration dataId=%s order=%d is reorged out, resubmit", migrate.CompleteTxHash, migrate.DataId, migrate.OrderId)
+				migrate.State = types.MigrateStateTxSent
+				migrate.CompleteTxHash = ""
+				migrate.CompleteTxHeight = 0
+				ss.saveMigrate(ctx, migrate)
+				select {
+				case ss.migrateChan <- MigrateRequest{
+					FromProvider:  migrate.FromProvider,
+					OrderId:       migrate.OrderId,
+					DataId:        migrate.DataId,
+					Cid:           migrate.Cid,
+					ToProvider:    migrate.ToProvider,
+					MigrateTxHash: migrate.MigrateTxHash,
+					MigrateHeight: migrate.MigrateTxHeight,
+				}:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+		}
+		migrate.State = types.MigrateStateComplete
+		ss.saveMigrate(ctx, migrate)
+	}
+}
+
+func (ss *StoreSvc) saveShard(ctx context.Context, shard types.ShardInfo) {
+	if err := ss.shards.Save(ctx, shard); err != nil {
+		log.Warnf("put shard order=%d cid=%v error: %v", shard.OrderId, shard.Cid, err)
+	}
+}
+
+func (ss *StoreSvc) saveMigrate(ctx context.Context, migrate types.MigrateInfo) {
+	if err := utils.SaveMigrate(ctx, ss.orderDs, migrate); err != nil {
+		log.Warnf("save migrate dataId=%s error: %v", migrate.DataId, err)
+	}
+}
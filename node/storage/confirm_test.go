@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"sao-node/chain"
+	"sao-node/types"
+	"sao-node/utils"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+type testTxConfirmer struct {
+	// height of the txs on chain by hash
+	txs       map[string]int64
+	completed map[uint64]bool
+}
+
+func (c testTxConfirmer) GetLastHeight(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (c testTxConfirmer) TxHeight(_ context.Context, hash string, _ int64) (int64, bool, error) {
+	height, found := c.txs[hash]
+	return height, found, nil
+}
+
+func (c testTxConfirmer) ShardCompleted(_ context.Context, orderId uint64, _ string) (bool, error) {
+	return c.completed[orderId], nil
+}
+
+func TestConfirmShards(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	ss := &StoreSvc{
+		orderDs:       ds,
+		shards:        newShardCache(ds),
+		tasks:         newTaskQueue(),
+		migrateChan:   make(chan MigrateRequest, 1),
+		confirmations: 2,
+		confirmer: testTxConfirmer{
+			txs:       map[string]int64{"tx1": 10, "tx2": 11},
+			completed: map[uint64]bool{3: true},
+		},
+	}
+	require.Equal(t, types.ShardStateTxSent, ss.completeState())
+
+	c1 := cid.MustParse("bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	shards := []types.ShardInfo{
+		// confirmed
+		{OrderId: 1, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx1", CompleteHeight: 10},
+		// included again at a later height, not deep enough there
+		{OrderId: 2, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx2", CompleteHeight: 9},
+		// reorged out but completed on chain by another tx
+		{OrderId: 3, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx3", CompleteHeight: 10},
+		// reorged out
+		{OrderId: 4, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx4", CompleteHeight: 10},
+		// not deep enough
+		{OrderId: 5, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx5", CompleteHeight: 11},
+	}
+	require.NoError(t, ss.shards.SaveAll(ctx, shards))
+
+	ss.confirmShards(ctx, 12)
+	states := map[uint64]types.ShardInfo{}
+	for _, shard := range shards {
+		stored, err := utils.GetShard(ctx, ds, shard.OrderId, c1)
+		require.NoError(t, err)
+		states[shard.OrderId] = stored
+	}
+	require.Equal(t, types.ShardStateComplete, states[1].State)
+	require.Equal(t, types.ShardStateTxSent, states[2].State)
+	require.Equal(t, int64(11), states[2].CompleteHeight)
+	require.Equal(t, types.ShardStateComplete, states[3].State)
+	require.Equal(t, types.ShardStateStored, states[4].State)
+	require.Empty(t, states[4].CompleteHash)
+	require.Equal(t, types.ShardStateTxSent, states[5].State)
+
+	// the reorged out shard is resubmitted
+	task, ok := ss.tasks.Pop(ctx)
+	require.True(t, ok)
+	require.Equal(t, uint64(4), task.OrderId)
+	require.Equal(t, types.ShardStateStored, task.State)
+
+	ss.confirmShards(ctx, 13)
+	stored, err := utils.GetShard(ctx, ds, 2, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateComplete, stored.State)
+}
+
+func TestConfirmMigrations(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	ss := &StoreSvc{
+		orderDs:       ds,
+		shards:        newShardCache(ds),
+		tasks:         newTaskQueue(),
+		migrateChan:   make(chan MigrateRequest, 1),
+		confirmations: 2,
+		confirmer:     testTxConfirmer{txs: map[string]int64{"tx1": 10}},
+	}
+	migrations := []types.MigrateInfo{
+		{DataId: "d1", OrderId: 1, FromProvider: "p1", ToProvider: "p2", CompleteTxHash: "tx1", CompleteTxHeight: 10, State: types.MigrateStateUnconfirmed},
+		{DataId: "d2", OrderId: 2, Cid: "cid", FromProvider: "p1", ToProvider: "p2", MigrateTxHash: "m2", MigrateTxHeight: 5, CompleteTxHash: "tx2", CompleteTxHeight: 10, State: types.MigrateStateUnconfirmed},
+	}
+	for _, migrate := range migrations {
+		require.NoError(t, utils.SaveMigrate(ctx, ds, migrate))
+	}
+
+	ss.confirmMigrations(ctx, 12)
+	confirmed, err := utils.GetMigrate(ctx, ds, "d1", "p1")
+	require.NoError(t, err)
+	require.Equal(t, types.MigrateStateComplete, confirmed.State)
+
+	reorged, err := utils.GetMigrate(ctx, ds, "d2", "p1")
+	require.NoError(t, err)
+	require.Equal(t, types.MigrateStateTxSent, reorged.State)
+	require.Empty(t, reorged.CompleteTxHash)
+	req := <-ss.migrateChan
+	require.Equal(t, MigrateRequest{FromProvider: "p1", OrderId: 2, DataId: "d2", Cid: "cid", ToProvider: "p2", MigrateTxHash: "m2", MigrateHeight: 5}, req)
+}
+
+func TestHandleTxResult(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	ss := &StoreSvc{
+		ctx:     ctx,
+		orderDs: ds,
+		shards:  newShardCache(ds),
+		tasks:   newTaskQueue(),
+	}
+	c1 := cid.MustParse("bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	require.NoError(t, ss.shards.SaveAll(ctx, []types.ShardInfo{
+		{OrderId: 1, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx1"},
+		{OrderId: 2, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx2"},
+		{OrderId: 3, Cid: c1, State: types.ShardStateTxSent, CompleteHash: "tx3"},
+	}))
+	msgType := sdktypes.MsgTypeURL(&saotypes.MsgComplete{})
+
+	// included, the shard is processed again to notify the gateway
+	ss.handleTxResult(chain.TxResult{MsgType: msgType, Hash: "tx1", Height: 10})
+	shard, err := utils.GetShard(ctx, ds, 1, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateTxSent, shard.State)
+	require.Equal(t, int64(10), shard.CompleteHeight)
+	task, ok := ss.tasks.Pop(ctx)
+	require.True(t, ok)
+	require.Equal(t, uint64(1), task.OrderId)
+
+	// dropped, resubmitted
+	ss.handleTxResult(chain.TxResult{MsgType: msgType, Hash: "tx2", Err: types.Wrapf(types.ErrTxNotIncluded, "tx2")})
+	shard, err = utils.GetShard(ctx, ds, 2, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateStored, shard.State)
+	require.Empty(t, shard.CompleteHash)
+	task, ok = ss.tasks.Pop(ctx)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), task.OrderId)
+
+	// failed on chain, recorded
+	ss.handleTxResult(chain.TxResult{MsgType: msgType, Hash: "tx3", Err: types.Wrapf(types.ErrTxProcessFailed, "code=5")})
+	shard, err = utils.GetShard(ctx, ds, 3, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateStored, shard.State)
+	require.NotEmpty(t, shard.LastErr)
+	require.Empty(t, ss.tasks.tasks)
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sao-node/node/alert"
+	"sao-node/types"
+	"sao-node/utils"
+
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	"github.com/ipfs/go-cid"
+)
+
+// released reports whether the provider no longer stores its shard of the order.
+func released(order *ordertypes.Order, provider string) bool {
+	switch order.Status {
+	case ordertypes.OrderTerminated, ordertypes.OrderCanceled, ordertypes.OrderExpired:
+		return true
+	}
+	// migrated to another node
+	shard, ok := order.Shards[provider]
+	return !ok || shard.Status == ordertypes.ShardTerminated
+}
+
+// CollectGarbage removes the content of the completed shards released on chain and marks them terminated, the
+// content shared with a shard still stored is kept. It returns the count of the shards terminated.
+func (ss *StoreSvc) CollectGarbage(ctx context.Context) (int, error) {
+	shards, err := ss.ShardList(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	inUse := make(map[cid.Cid]bool)
+	var releasedShards []types.ShardInfo
+	for _, shard := range shards {
+		if shard.State == types.ShardStateTerminate {
+			continue
+		}
+		if shard.State != types.ShardStateComplete {
+			inUse[shard.Cid] = true
+			continue
+		}
+		order, err := ss.chainSvc.GetOrder(ctx, shard.OrderId)
+		if err != nil {
+			log.Warnf("get order %d to collect garbage error: %v", shard.OrderId, err)
+			inUse[shard.Cid] = true
+			continue
+		}
+		if !released(order, ss.nodeAddress) {
+			inUse[shard.Cid] = true
+			continue
+		}
+		releasedShards = append(releasedShards, shard)
+	}
+
+	terminated := 0
+	removed := make(map[cid.Cid]bool)
+	for _, shard := range releasedShards {
+		if !inUse[shard.Cid] && !removed[shard.Cid] {
+			if err := ss.storeManager.Remove(ctx, shard.Cid); err != nil {
+				log.Warnf("remove shard order=%d cid=%v error: %v", shard.OrderId, shard.Cid, err)
+				continue
+			}
+			removed[shard.Cid] = true
+		}
+		shard.State = types.ShardStateTerminate
+		if err := ss.shards.Save(ctx, shard); err != nil {
+			return terminated, err
+		}
+		log.Infof("shard order=%d cid=%v released, terminated", shard.OrderId, shard.Cid)
+		terminated++
+	}
+	return terminated, nil
+}
+
+// Scrub reads the stored shards back and verifies them against their cids, the missing and corrupted shards are
+// reported to the alerts. It returns the count of the shards verified and of the ones failed.
+func (ss *StoreSvc) Scrub(ctx context.Context) (int, int, error) {
+	shards, err := ss.ShardList(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	verified := make(map[cid.Cid]bool)
+	var failed []string
+	for _, shard := range shards {
+		if shard.State < types.ShardStateStored || shard.State == types.ShardStateTerminate || verified[shard.Cid] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return len(verified), len(failed), err
+		}
+		verified[shard.Cid] = true
+
+		if err := ss.verifyStored(ctx, shard.Cid); err != nil {
+			log.Errorf("scrub shard order=%d cid=%v error: %v", shard.OrderId, shard.Cid, err)
+			failed = append(failed, shard.Cid.String())
+		}
+	}
+
+	if len(failed) > 0 {
+		ss.alerts.Fire(alert.TypeCorruptShard, "%d of %d stored shards are missing or corrupted: %v",
+			len(failed), len(verified), failed)
+	}
+	return len(verified), len(failed), nil
+}
+
+func (ss *StoreSvc) verifyStored(ctx context.Context, shardCid cid.Cid) error {
+	reader, err := ss.storeManager.Get(ctx, shardCid)
+	if err != nil {
+		return err
+	}
+	contentCid, err := utils.CalculateCidFrom(reader)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(contentCid.Hash(), shardCid.Hash()) {
+		return types.Wrapf(types.ErrInvalidCid, "content of cid %v stored", contentCid)
+	}
+	return nil
+}
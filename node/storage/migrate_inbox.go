@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sao-node/types"
+	"sync"
+)
+
+// migrateInbox keeps the shards being migrated to this node in part files named by their migrations. The bytes
+// received survive a failed request and a restart of the node, so the migration resumes from them.
+type migrateInbox struct {
+	path string
+
+	lk sync.Mutex
+	// the sizes of the shards being received, known once the first chunk of the migration is checked against
+	// the order
+	sizes map[migrateKey]uint64
+}
+
+// migrateKey is the migration of the shard of cid in the order from the node, a chunk is only appended to the
+// migration its sender is checked for.
+type migrateKey struct {
+	orderId uint64
+	from    string
+	cid     string
+}
+
+func (k migrateKey) String() string {
+	return fmt.Sprintf("%d-%s-%s", k.orderId, k.from, k.cid)
+}
+
+func newMigrateInbox(path string) *migrateInbox {
+	return &migrateInbox{
+		path:  path,
+		sizes: make(map[migrateKey]uint64),
+	}
+}
+
+func (m *migrateInbox) partPath(key migrateKey) string {
+	return filepath.Join(m.path, key.String()+".part")
+}
+
+// expected returns the size of the shard, if its migration is checked already.
+func (m *migrateInbox) expected(key migrateKey) (uint64, bool) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	size, ok := m.sizes[key]
+	return size, ok
+}
+
+func (m *migrateInbox) expect(key migrateKey, size uint64) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	m.sizes[key] = size
+}
+
+// received returns the bytes of the shard received.
+func (m *migrateInbox) received(key migrateKey) (uint64, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	return m.stat(key)
+}
+
+func (m *migrateInbox) stat(key migrateKey) (uint64, error) {
+	info, err := os.Stat(m.partPath(key))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return uint64(info.Size()), nil
+}
+
+// write appends content to the shard of size bytes if it starts at the bytes received, the chunks sent again
+// are ignored. It returns the bytes received.
+func (m *migrateInbox) write(key migrateKey, offset uint64, content []byte, size uint64) (uint64, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	received, err := m.stat(key)
+	if err != nil {
+		return 0, err
+	}
+	if offset != received || len(content) == 0 {
+		return received, nil
+	}
+	if offset+uint64(len(content)) > size {
+		return received, types.Wrapf(types.ErrInvalidParameters, "chunk at %d of %d bytes exceeds the shard size %d", offset, len(content), size)
+	}
+
+	if err := os.MkdirAll(m.path, 0755); err != nil {
+		return received, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	file, err := os.OpenFile(m.partPath(key), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return received, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteAt(content, int64(offset)); err != nil {
+		return received, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return offset + uint64(len(content)), nil
+}
+
+// open opens the shard received for reading.
+func (m *migrateInbox) open(key migrateKey) (*os.File, error) {
+	file, err := os.Open(m.partPath(key))
+	if err != nil {
+		return nil, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	return file, nil
+}
+
+// remove drops the shard received, once stored or found corrupted.
+func (m *migrateInbox) remove(key migrateKey) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	delete(m.sizes, key)
+	if err := os.Remove(m.partPath(key)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("remove migrated shard %s: %v", key.cid, err)
+	}
+}
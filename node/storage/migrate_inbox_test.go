@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"io"
+	"testing"
+
+	"sao-node/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateInbox(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("the content of the migrated shard")
+	c, err := utils.CalculateCid(content)
+	require.NoError(t, err)
+	size := uint64(len(content))
+	key := migrateKey{orderId: 1, from: "sao1from", cid: c.String()}
+
+	m := newMigrateInbox(dir)
+	received, err := m.received(key)
+	require.NoError(t, err)
+	require.Zero(t, received)
+
+	received, err = m.write(key, 0, content[:10], size)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), received)
+	// sent again, or out of order
+	received, err = m.write(key, 0, content[:10], size)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), received)
+	received, err = m.write(key, 20, content[20:], size)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), received)
+	// beyond the size of the shard
+	_, err = m.write(key, 10, content[10:], size-1)
+	require.Error(t, err)
+
+	// the shard of the same cid from another node or of another order is another migration
+	_, ok := m.expected(key)
+	require.False(t, ok)
+	m.expect(key, size)
+	for _, other := range []migrateKey{
+		{orderId: 1, from: "sao1other", cid: c.String()},
+		{orderId: 2, from: "sao1from", cid: c.String()},
+	} {
+		_, ok = m.expected(other)
+		require.False(t, ok)
+		received, err = m.received(other)
+		require.NoError(t, err)
+		require.Zero(t, received)
+		received, err = m.write(other, 0, []byte("forged"), size)
+		require.NoError(t, err)
+		require.Equal(t, uint64(6), received)
+		m.remove(other)
+	}
+	received, err = m.received(key)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), received)
+
+	// resumed after a restart
+	m = newMigrateInbox(dir)
+	received, err = m.received(key)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), received)
+	received, err = m.write(key, 10, content[10:], size)
+	require.NoError(t, err)
+	require.Equal(t, size, received)
+
+	ss := &StoreSvc{}
+	file, err := m.open(key)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = ss.verifyMigrated(file, c, size+1)
+	require.Error(t, err)
+	n, err := ss.verifyMigrated(file, c, size)
+	require.NoError(t, err)
+	require.Equal(t, size, n)
+	stored, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.Equal(t, content, stored)
+
+	other, err := utils.CalculateCid([]byte("other"))
+	require.NoError(t, err)
+	_, err = ss.verifyMigrated(file, other, 0)
+	require.Error(t, err)
+
+	m.remove(key)
+	received, err = m.received(key)
+	require.NoError(t, err)
+	require.Zero(t, received)
+}
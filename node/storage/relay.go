@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sao-node/types"
+	"strings"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// relayVerifier looks up the chain records a relay proposal is checked against.
+type relayVerifier interface {
+	// GetPubKey returns the public key of the node account
+	GetPubKey(ctx context.Context, address string) (cryptotypes.PubKey, error)
+	// GetNodePeer returns the peer info registered by the node
+	GetNodePeer(ctx context.Context, address string) (string, error)
+}
+
+type chainRelayVerifier struct {
+	ss *StoreSvc
+}
+
+func (v chainRelayVerifier) GetPubKey(ctx context.Context, address string) (cryptotypes.PubKey, error) {
+	account, err := v.ss.chainSvc.GetAccount(ctx, address)
+	if err != nil {
+		return nil, types.Wrap(types.ErrAccountNotFound, err)
+	}
+	if account.GetPubKey() == nil {
+		return nil, types.Wrapf(types.ErrAccountNotFound, "no public key of %s on chain", address)
+	}
+	return account.GetPubKey(), nil
+}
+
+func (v chainRelayVerifier) GetNodePeer(ctx context.Context, address string) (string, error) {
+	return v.ss.chainSvc.GetNodePeer(ctx, address)
+}
+
+// verifyRelay checks a shard load request relayed to this node, the request comes from remotePeerId instead of
+// the gateway of the query proposal. The relay proposal must be signed by the account of the gateway, whose
+// registered peer is the gateway of the query, and must be relayed by one of the peers it names to localPeerId.
+func verifyRelay(ctx context.Context, verifier relayVerifier, relay types.RelayProposalCbor, gateway string, remotePeerId string, localPeerId string) error {
+	proposal := relay.Proposal
+	if len(relay.Signature) == 0 {
+		return types.Wrapf(types.ErrInvalidSignature, "unexpected gateway %s, should be %s", remotePeerId, gateway)
+	}
+	if proposal.NodeAddress == "" || proposal.LocalPeerId == "" {
+		return types.Wrapf(types.ErrInvalidParameters, "incomplete relay proposal")
+	}
+	if !isRelayPeer(proposal.RelayPeerIds, remotePeerId) {
+		return types.Wrapf(types.ErrInvalidSignature, "%s is not a relay of the proposal", remotePeerId)
+	}
+	gatewayPeer, err := peer.Decode(proposal.LocalPeerId)
+	if err != nil {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid peer %s of the relay proposal", proposal.LocalPeerId)
+	}
+	if !namesPeer(gateway, gatewayPeer.String()) {
+		return types.Wrapf(types.ErrInvalidSignature, "relay proposal of %s, but the query is for %s", proposal.LocalPeerId, gateway)
+	}
+	if !namesPeer(proposal.TargetPeerInfo, localPeerId) {
+		return types.Wrapf(types.ErrInvalidSignature, "relay proposal to %s, not to this node", proposal.TargetPeerInfo)
+	}
+
+	peerInfo, err := verifier.GetNodePeer(ctx, proposal.NodeAddress)
+	if err != nil {
+		return err
+	}
+	if !namesPeer(peerInfo, gatewayPeer.String()) {
+		return types.Wrapf(types.ErrUnregisteredPeer, "node=%s, peer=%s", proposal.NodeAddress, proposal.LocalPeerId)
+	}
+
+	pubKey, err := verifier.GetPubKey(ctx, proposal.NodeAddress)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := proposal.MarshalCBOR(buf); err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	if !pubKey.VerifySignature(buf.Bytes(), relay.Signature) {
+		return types.Wrapf(types.ErrInvalidSignature, "relay proposal is not signed by %s", proposal.NodeAddress)
+	}
+	return nil
+}
+
+// isRelayPeer tells if peerId is one of the comma separated peer ids of a relay proposal.
+func isRelayPeer(relayPeerIds string, peerId string) bool {
+	for _, id := range strings.Split(relayPeerIds, ",") {
+		if strings.TrimSpace(id) == peerId {
+			return true
+		}
+	}
+	return false
+}
+
+// namesPeer tells if one of the comma separated multiaddrs of peerInfos is an address of peerId, the target of a
+// circuit address is the peer it names.
+func namesPeer(peerInfos string, peerId string) bool {
+	for _, peerInfo := range strings.Split(peerInfos, ",") {
+		addr, err := ma.NewMultiaddr(strings.TrimSpace(peerInfo))
+		if err != nil {
+			continue
+		}
+		info, err := peer.AddrInfoFromP2pAddr(addr)
+		if err == nil && info.ID.String() == peerId {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySender checks the node which signed the request envelope is the one the request is from. An empty
+// sender is a request over a 1.0 protocol, which is unsigned, or from this node itself.
+func verifySender(sender string, expected string) error {
+	if sender != "" && sender != expected {
+		return types.Wrapf(types.ErrInvalidSignature, "request signed by %s, but it is from %s", sender, expected)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"sao-node/types"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type testRelayVerifier struct {
+	keys  map[string]cryptotypes.PubKey
+	peers map[string]string
+}
+
+func (v testRelayVerifier) GetPubKey(_ context.Context, address string) (cryptotypes.PubKey, error) {
+	key, ok := v.keys[address]
+	if !ok {
+		return nil, types.Wrapf(types.ErrAccountNotFound, "%s", address)
+	}
+	return key, nil
+}
+
+func (v testRelayVerifier) GetNodePeer(_ context.Context, address string) (string, error) {
+	return v.peers[address], nil
+}
+
+func testPeerId(t *testing.T) string {
+	_, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPublicKey(pub)
+	require.NoError(t, err)
+	return id.String()
+}
+
+func TestVerifyRelay(t *testing.T) {
+	ctx := context.Background()
+	gatewayKey := secp256k1.GenPrivKey()
+	otherKey := secp256k1.GenPrivKey()
+	gatewayPeer, otherPeer, storagePeer, otherStoragePeer := testPeerId(t), testPeerId(t), testPeerId(t), testPeerId(t)
+	relayPeer1, relayPeer2, relayPeer3, evilPeer := testPeerId(t), testPeerId(t), testPeerId(t), testPeerId(t)
+	gateway := "/ip4/1.2.3.4/tcp/5153/p2p/" + gatewayPeer
+	other := "/ip4/1.2.3.5/tcp/5153/p2p/" + otherPeer
+	verifier := testRelayVerifier{
+		keys:  map[string]cryptotypes.PubKey{"gateway": gatewayKey.PubKey(), "other": otherKey.PubKey()},
+		peers: map[string]string{"gateway": gateway, "other": other},
+	}
+
+	sign := func(proposal types.RelayProposal, key *secp256k1.PrivKey) types.RelayProposalCbor {
+		buf := new(bytes.Buffer)
+		require.NoError(t, proposal.MarshalCBOR(buf))
+		signature, err := key.Sign(buf.Bytes())
+		require.NoError(t, err)
+		return types.RelayProposalCbor{Proposal: proposal, Signature: signature}
+	}
+	proposal := types.RelayProposal{
+		NodeAddress:    "gateway",
+		LocalPeerId:    gatewayPeer,
+		RelayPeerIds:   relayPeer1 + ", " + relayPeer2,
+		TargetPeerInfo: "/ip4/1.2.3.6/tcp/5153/p2p/" + storagePeer,
+	}
+
+	relay := sign(proposal, gatewayKey)
+	require.NoError(t, verifyRelay(ctx, verifier, relay, gateway, relayPeer2, storagePeer))
+
+	// the result of the signature verification is not ignored
+	tampered := relay
+	tampered.Proposal.RelayPeerIds = proposal.RelayPeerIds + ", " + evilPeer
+	require.ErrorIs(t, verifyRelay(ctx, verifier, tampered, gateway, evilPeer, storagePeer), types.ErrInvalidSignature)
+	require.ErrorIs(t, verifyRelay(ctx, verifier, sign(proposal, otherKey), gateway, relayPeer2, storagePeer), types.ErrInvalidSignature)
+
+	unsigned := relay
+	unsigned.Signature = nil
+	require.ErrorIs(t, verifyRelay(ctx, verifier, unsigned, gateway, relayPeer2, storagePeer), types.ErrInvalidSignature)
+
+	// not a relay named by the proposal
+	require.ErrorIs(t, verifyRelay(ctx, verifier, relay, gateway, relayPeer3, storagePeer), types.ErrInvalidSignature)
+	// relayed to another storage node
+	require.ErrorIs(t, verifyRelay(ctx, verifier, relay, gateway, relayPeer2, otherStoragePeer), types.ErrInvalidSignature)
+	// a proposal of another gateway than the one of the query
+	require.ErrorIs(t, verifyRelay(ctx, verifier, relay, other, relayPeer2, storagePeer), types.ErrInvalidSignature)
+
+	// signed by a node claiming the peer of the gateway
+	impersonated := proposal
+	impersonated.NodeAddress = "other"
+	require.ErrorIs(t, verifyRelay(ctx, verifier, sign(impersonated, otherKey), gateway, relayPeer2, storagePeer), types.ErrUnregisteredPeer)
+
+	// the peers are compared exactly, not as substrings of the addresses
+	substring := proposal
+	substring.NodeAddress = "other"
+	substring.LocalPeerId = "/ip4/"
+	require.Error(t, verifyRelay(ctx, verifier, sign(substring, otherKey), gateway, relayPeer2, storagePeer))
+	substring.LocalPeerId = otherPeer[:len(otherPeer)-1]
+	require.Error(t, verifyRelay(ctx, verifier, sign(substring, otherKey), gateway, relayPeer2, storagePeer))
+	substring = proposal
+	substring.RelayPeerIds = relayPeer1 + relayPeer2
+	require.ErrorIs(t, verifyRelay(ctx, verifier, sign(substring, gatewayKey), gateway, relayPeer2, storagePeer), types.ErrInvalidSignature)
+	substring = proposal
+	substring.TargetPeerInfo = "/ip4/1.2.3.6/tcp/5153/p2p/" + otherStoragePeer + "/" + storagePeer
+	require.ErrorIs(t, verifyRelay(ctx, verifier, sign(substring, gatewayKey), gateway, relayPeer2, storagePeer), types.ErrInvalidSignature)
+	substring.TargetPeerInfo = "/ip4/1.2.3.6/tcp/5153/p2p/" + relayPeer1 + "/p2p-circuit/p2p/" + storagePeer
+	require.NoError(t, verifyRelay(ctx, verifier, sign(substring, gatewayKey), gateway, relayPeer2, storagePeer))
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sao-node/types"
+	"sao-node/utils"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+// the shards not used for an interval are dropped from the cache
+const shardCacheSweepInterval = 5 * time.Minute
+
+type cachedShard struct {
+	info types.ShardInfo
+	// used since the last sweep
+	used bool
+}
+
+// shardCache keeps the states of the shards in memory, so the shard workers don't read them back from the
+// datastore at every step. The writes go through to the datastore, the cache only saves the reads.
+type shardCache struct {
+	ds datastore.Batching
+
+	lk     sync.Mutex
+	shards map[types.ShardKey]*cachedShard
+}
+
+func newShardCache(ds datastore.Batching) *shardCache {
+	return &shardCache{
+		ds:     ds,
+		shards: make(map[types.ShardKey]*cachedShard),
+	}
+}
+
+// Get returns the state of the shard, an empty shard is returned if not found.
+func (c *shardCache) Get(ctx context.Context, orderId uint64, cid cid.Cid) (types.ShardInfo, error) {
+	key := types.ShardKey{OrderId: orderId, Cid: cid}
+
+	c.lk.Lock()
+	if s, ok := c.shards[key]; ok {
+		s.used = true
+		c.lk.Unlock()
+		return s.info, nil
+	}
+	c.lk.Unlock()
+
+	shard, err := utils.GetShard(ctx, c.ds, orderId, cid)
+	if err != nil || shard == (types.ShardInfo{}) {
+		return shard, err
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	// saved while it was read
+	if s, ok := c.shards[key]; ok {
+		s.used = true
+		return s.info, nil
+	}
+	c.shards[key] = &cachedShard{info: shard, used: true}
+	return shard, nil
+}
+
+// Save writes the state of the shard to the datastore, then caches it.
+func (c *shardCache) Save(ctx context.Context, shard types.ShardInfo) error {
+	// held while written, so the cache and the datastore agree on the last state saved
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if err := utils.SaveShard(ctx, c.ds, shard); err != nil {
+		return err
+	}
+	c.shards[types.ShardKey{OrderId: shard.OrderId, Cid: shard.Cid}] = &cachedShard{info: shard, used: true}
+	return nil
+}
+
+// SaveAll writes the states of the shards to the datastore in one batch, then caches them.
+func (c *shardCache) SaveAll(ctx context.Context, shards []types.ShardInfo) error {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if err := utils.SaveShards(ctx, c.ds, shards); err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		c.shards[types.ShardKey{OrderId: shard.OrderId, Cid: shard.Cid}] = &cachedShard{info: shard, used: true}
+	}
+	return nil
+}
+
+// sweep drops the shards not used since the last sweep, it returns the number of shards dropped.
+func (c *shardCache) sweep() int {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	dropped := 0
+	for key, s := range c.shards {
+		if !s.used {
+			delete(c.shards, key)
+			dropped++
+			continue
+		}
+		s.used = false
+	}
+	return dropped
+}
+
+func (c *shardCache) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if dropped := c.sweep(); dropped > 0 {
+				log.Debugf("dropped %d idle shards from the cache", dropped)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
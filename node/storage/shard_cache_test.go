@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"sao-node/types"
+	"sao-node/utils"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardCache(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	c := newShardCache(ds)
+
+	c1 := cid.MustParse("bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	require.NoError(t, utils.SaveShard(ctx, ds, types.ShardInfo{OrderId: 1, Cid: c1, State: types.ShardStateValidated}))
+
+	shard, err := c.Get(ctx, 1, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateValidated, shard.State)
+	// not found, not cached
+	shard, err = c.Get(ctx, 2, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardInfo{}, shard)
+	require.Len(t, c.shards, 1)
+
+	// written through
+	require.NoError(t, c.Save(ctx, types.ShardInfo{OrderId: 1, Cid: c1, State: types.ShardStateStored}))
+	stored, err := utils.GetShard(ctx, ds, 1, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateStored, stored.State)
+	require.NoError(t, c.SaveAll(ctx, []types.ShardInfo{{OrderId: 2, Cid: c1}}))
+
+	// used since the last sweep
+	require.Zero(t, c.sweep())
+	_, err = c.Get(ctx, 1, c1)
+	require.NoError(t, err)
+	require.Equal(t, 1, c.sweep())
+	require.Len(t, c.shards, 1)
+
+	// read back from the datastore once dropped
+	require.Equal(t, 1, c.sweep())
+	shard, err = c.Get(ctx, 1, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateStored, shard.State)
+}
@@ -9,11 +9,14 @@ type StorageProtocol interface {
 	RequestShardComplete(ctx context.Context, req types.ShardCompleteReq, peer string) types.ShardCompleteResp
 	RequestShardStore(ctx context.Context, req types.ShardLoadReq, peer string) types.ShardLoadResp
 	RequestShardMigrate(ctx context.Context, req types.ShardMigrateReq, peer string) types.ShardMigrateResp
+	RequestShardMigrateChunk(ctx context.Context, req types.ShardMigrateChunkReq, peer string) types.ShardMigrateChunkResp
 	Stop(ctx context.Context) error
 }
 
 type StorageProtocolHandler interface {
-	HandleShardAssign(req types.ShardAssignReq) types.ShardAssignResp
+	HandleShardAssign(req types.ShardAssignReq, sender string) types.ShardAssignResp
 	HandleShardLoad(req types.ShardLoadReq, remotePeerId string) types.ShardLoadResp
-	HandleShardMigrate(req types.ShardMigrateReq) types.ShardMigrateResp
+	HandleShardMigrate(req types.ShardMigrateReq, sender string) types.ShardMigrateResp
+	HandleShardMigrateChunk(req types.ShardMigrateChunkReq, sender string) types.ShardMigrateChunkResp
+	HandleShardAudit(req types.ShardLoadReq, sender string) types.ShardLoadResp
 }
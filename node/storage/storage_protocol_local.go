@@ -3,32 +3,36 @@ package storage
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"sao-node/node/events"
+	"sao-node/node/staging"
 	"sao-node/types"
 	"time"
-
-	"github.com/mitchellh/go-homedir"
 )
 
 type LocalStorageProtocol struct {
 	StorageProtocolHandler
-	chans       map[string]chan interface{}
-	stagingPath string
+	bus *events.Bus
+	// the staging path of the node and the ones of the platforms staging apart
+	stagingPaths []string
+	cipher       *staging.Cipher
 }
 
 func NewLocalStorageProtocol(
 	ctx context.Context,
-	chans map[string]chan interface{},
-	stagingPath string,
+	bus *events.Bus,
+	stagingPaths []string,
+	cipher *staging.Cipher,
 	handler StorageProtocolHandler,
 ) LocalStorageProtocol {
 	p := LocalStorageProtocol{
-		chans:                  chans,
-		stagingPath:            stagingPath,
+		bus:                    bus,
+		stagingPaths:           stagingPaths,
+		cipher:                 cipher,
 		StorageProtocolHandler: handler,
 	}
-	go p.listenShardAssign(ctx)
+	// unbuffered, the gateway waits for the assignment to be handled as it does over a stream
+	bus.Consume(ctx, events.TopicLocalShardAssign, 0, p.handleShardAssign)
 	return p
 }
 
@@ -37,27 +41,23 @@ func (l LocalStorageProtocol) Stop(_ context.Context) error {
 	return nil
 }
 
-func (l LocalStorageProtocol) listenShardAssign(ctx context.Context) {
-	for {
-		select {
-		case t, ok := <-l.chans[types.ShardAssignProtocol]:
-			if !ok {
-				return
-			}
-			// process
-			resp := l.HandleShardAssign(t.(types.ShardAssignReq))
-			if resp.Code != 0 {
-				log.Errorf(resp.Message)
-			}
-		case <-ctx.Done():
-			return
-		}
+func (l LocalStorageProtocol) handleShardAssign(ev events.Event) {
+	// process, assigned by the gateway of this node
+	resp := l.HandleShardAssign(ev.(events.LocalShardAssign).Req, "")
+	if resp.Code != 0 {
+		log.Errorf("requestId=%d: %s", resp.RequestId, resp.Message)
 	}
 }
 
 func (l LocalStorageProtocol) RequestShardComplete(ctx context.Context, req types.ShardCompleteReq, _ string) types.ShardCompleteResp {
-	l.chans[types.ShardCompleteProtocol] <- req
-	return types.ShardCompleteResp{Code: 0}
+	if l.bus.Publish(ctx, events.LocalShardComplete{Req: req}) == 0 {
+		return types.ShardCompleteResp{
+			Code:      types.ErrorCodeInternalErr,
+			Message:   "the gateway module of this node is not enabled",
+			RequestId: req.RequestId,
+		}
+	}
+	return types.ShardCompleteResp{Code: 0, RequestId: req.RequestId}
 }
 
 func (l LocalStorageProtocol) RequestShardStore(ctx context.Context, req types.ShardLoadReq, _ string) types.ShardLoadResp {
@@ -67,16 +67,15 @@ func (l LocalStorageProtocol) RequestShardStore(ctx context.Context, req types.S
 		RequestId: req.RequestId,
 	}
 
-	path, err := homedir.Expand(l.stagingPath)
+	filename, err := staging.Locate(l.stagingPaths, req.Owner, req.Cid.String())
 	if err != nil {
 		resp.ResponseId = time.Now().UnixMilli()
 		resp.Code = types.ErrorCodeInternalErr
-		resp.Message = fmt.Sprintf("invalid path: %s", l.stagingPath)
+		resp.Message = err.Error()
 		return resp
 	}
 
-	filename := filepath.Join(path, req.Owner, req.Cid.String())
-	bytes, err := os.ReadFile(filename)
+	bytes, err := l.readShard(filename)
 	if err != nil {
 		resp.ResponseId = time.Now().UnixMilli()
 		resp.Code = types.ErrorCodeInternalErr
@@ -90,9 +89,43 @@ func (l LocalStorageProtocol) RequestShardStore(ctx context.Context, req types.S
 	}
 }
 
+func (l LocalStorageProtocol) readShard(filename string) ([]byte, error) {
+	file, err := l.cipher.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the size is known, the content is read at once instead of growing a buffer
+	content := make([]byte, file.Size())
+	if _, err := io.ReadFull(file, content); err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return content, nil
+}
+
+// OpenShard opens the staging file of the shard, the gateway and the storage node share the staging path so the
+// shard is read in place instead of through a response.
+func (l LocalStorageProtocol) OpenShard(req types.ShardLoadReq) (*staging.File, error) {
+	filename, err := staging.Locate(l.stagingPaths, req.Owner, req.Cid.String())
+	if err != nil {
+		return nil, err
+	}
+	return l.cipher.Open(filename)
+}
+
 func (l LocalStorageProtocol) RequestShardMigrate(ctx context.Context, req types.ShardMigrateReq, _ string) types.ShardMigrateResp {
 	return types.ShardMigrateResp{
-		Code:    types.ErrorCodeInternalErr,
-		Message: "unsupported",
+		Code:      types.ErrorCodeInternalErr,
+		Message:   "unsupported",
+		RequestId: req.RequestId,
+	}
+}
+
+func (l LocalStorageProtocol) RequestShardMigrateChunk(ctx context.Context, req types.ShardMigrateChunkReq, _ string) types.ShardMigrateChunkResp {
+	return types.ShardMigrateChunkResp{
+		Code:      types.ErrorCodeInternalErr,
+		Message:   "unsupported",
+		RequestId: req.RequestId,
 	}
 }
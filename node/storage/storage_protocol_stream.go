@@ -12,31 +12,47 @@ import (
 )
 
 type StreamStorageProtocol struct {
-	host host.Host
+	ctx         context.Context
+	host        host.Host
+	nodeAddress string
+	resolver    transport.NodePeerResolver
+	limiter     *transport.StreamLimiter
 	StorageProtocolHandler
 }
 
 func NewStreamStorageProtocol(
+	ctx context.Context,
 	host host.Host,
+	nodeAddress string,
+	resolver transport.NodePeerResolver,
+	limiter *transport.StreamLimiter,
 	handler StorageProtocolHandler,
 ) StreamStorageProtocol {
 	ssp := StreamStorageProtocol{
+		ctx:                    ctx,
 		host:                   host,
+		nodeAddress:            nodeAddress,
+		resolver:               resolver,
+		limiter:                limiter,
 		StorageProtocolHandler: handler,
 	}
-	host.SetStreamHandler(types.ShardAssignProtocol, ssp.handleShardAssign)
-	host.SetStreamHandler(types.ShardLoadProtocol, ssp.handleShardLoad)
-	host.SetStreamHandler(types.ShardMigrateProtocol, ssp.handleShardMigrate)
-	host.SetStreamHandler(types.ShardPingPongProtocol, transport.HandlePingRequest)
+	transport.SetStreamHandler(host, types.ShardAssignProtocol, ssp.handleShardAssign)
+	transport.SetStreamHandler(host, types.ShardLoadProtocol, ssp.handleShardLoad)
+	transport.SetStreamHandler(host, types.ShardMigrateProtocol, ssp.handleShardMigrate)
+	transport.SetStreamHandler(host, types.ShardMigrateChunkProtocol, ssp.handleShardMigrateChunk)
+	transport.SetStreamHandler(host, types.ShardAuditProtocol, ssp.handleShardAudit)
+	transport.SetStreamHandler(host, types.ShardPingPongProtocol, transport.HandlePingRequest)
 
 	return ssp
 }
 
 func (l StreamStorageProtocol) Stop(ctx context.Context) error {
 	log.Info("stopping stream storage protocol")
-	l.host.RemoveStreamHandler(types.ShardAssignProtocol)
-	l.host.RemoveStreamHandler(types.ShardLoadProtocol)
-	l.host.RemoveStreamHandler(types.ShardMigrateProtocol)
+	transport.RemoveStreamHandler(l.host, types.ShardAssignProtocol)
+	transport.RemoveStreamHandler(l.host, types.ShardLoadProtocol)
+	transport.RemoveStreamHandler(l.host, types.ShardMigrateProtocol)
+	transport.RemoveStreamHandler(l.host, types.ShardMigrateChunkProtocol)
+	transport.RemoveStreamHandler(l.host, types.ShardAuditProtocol)
 	return nil
 }
 
@@ -56,20 +72,72 @@ func (l StreamStorageProtocol) handleShardMigrate(s network.Stream) {
 		}
 	}
 
-	// Set a deadline on reading from the stream so it doesn't hang
-	_ = s.SetReadDeadline(time.Now().Add(30 * time.Second))
-	defer s.SetReadDeadline(time.Time{}) // nolint
+	r, release, err := l.limiter.Begin(s, true)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardMigrateResp{
+			Code:    types.ErrorCodeBusy,
+			Message: err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	var req types.ShardMigrateReq
-	err := req.Unmarshal(s, types.FormatCbor)
+	sender, err := transport.ReadSignedRequest(l.ctx, s, r, l.resolver, &req)
 	if err != nil {
+		log.Error(err.Error())
 		respond(types.ShardMigrateResp{
-			Code:    types.ErrorCodeInternalErr,
-			Message: fmt.Sprintf("failed to unmarshal request: %v", err),
+			Code:      transport.RequestErrorCode(err),
+			Message:   fmt.Sprintf("failed to read request: %v", err),
+			RequestId: req.RequestId,
+		})
+		return
+	}
+	log.Debugf("receive ShardMigrateReq: orderId=%d dataId=%s requestId=%d", req.OrderId, req.DataId, req.RequestId)
+	respond(l.HandleShardMigrate(req, sender))
+}
+
+func (l StreamStorageProtocol) handleShardMigrateChunk(s network.Stream) {
+	defer s.Close()
+
+	respond := func(resp types.ShardMigrateChunkResp) {
+		err := resp.Marshal(s, types.FormatCbor)
+		if err != nil {
+			log.Error(err.Error())
+			return
+		}
+
+		if err = s.CloseWrite(); err != nil {
+			log.Error(err.Error())
+			return
+		}
+	}
+
+	r, release, err := l.limiter.Begin(s, true)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardMigrateChunkResp{
+			Code:    types.ErrorCodeBusy,
+			Message: err.Error(),
 		})
 		return
 	}
-	respond(l.HandleShardMigrate(req))
+	defer release()
+
+	var req types.ShardMigrateChunkReq
+	sender, err := transport.ReadSignedRequest(l.ctx, s, r, l.resolver, &req)
+	if err != nil {
+		log.Error(err.Error())
+		respond(types.ShardMigrateChunkResp{
+			Code:      transport.RequestErrorCode(err),
+			Message:   fmt.Sprintf("failed to read request: %v", err),
+			RequestId: req.RequestId,
+		})
+		return
+	}
+	log.Debugf("receive ShardMigrateChunkReq: orderId=%d offset=%d requestId=%d", req.OrderId, req.Offset, req.RequestId)
+	respond(l.HandleShardMigrateChunk(req, sender))
 }
 
 func (l StreamStorageProtocol) handleShardLoad(s network.Stream) {
@@ -88,12 +156,19 @@ func (l StreamStorageProtocol) handleShardLoad(s network.Stream) {
 		}
 	}
 
-	// Set a deadline on reading from the stream so it doesn't hang
-	_ = s.SetReadDeadline(time.Now().Add(30 * time.Second))
-	defer s.SetReadDeadline(time.Time{}) // nolint
+	r, release, err := l.limiter.Begin(s, false)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardLoadResp{
+			Code:    types.ErrorCodeBusy,
+			Message: err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	var req types.ShardLoadReq
-	err := req.Unmarshal(s, types.FormatCbor)
+	err = req.Unmarshal(r, types.FormatCbor)
 	if err != nil {
 		respond(types.ShardLoadResp{
 			Code:       types.ErrorCodeInvalidRequest,
@@ -109,6 +184,50 @@ func (l StreamStorageProtocol) handleShardLoad(s network.Stream) {
 	respond(l.HandleShardLoad(req, s.Conn().RemotePeer().String()))
 }
 
+func (l StreamStorageProtocol) handleShardAudit(s network.Stream) {
+	defer s.Close()
+
+	respond := func(resp types.ShardLoadResp) {
+		err := resp.Marshal(s, types.FormatCbor)
+		if err != nil {
+			log.Error(err.Error())
+			return
+		}
+
+		if err = s.CloseWrite(); err != nil {
+			log.Error(err.Error())
+			return
+		}
+	}
+
+	r, release, err := l.limiter.Begin(s, false)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardLoadResp{
+			Code:       types.ErrorCodeBusy,
+			Message:    err.Error(),
+			ResponseId: time.Now().UnixMilli(),
+		})
+		return
+	}
+	defer release()
+
+	var req types.ShardLoadReq
+	sender, err := transport.ReadSignedRequest(l.ctx, s, r, l.resolver, &req)
+	if err != nil {
+		log.Error(err.Error())
+		respond(types.ShardLoadResp{
+			Code:       transport.RequestErrorCode(err),
+			Message:    fmt.Sprintf("failed to read request: %v", err),
+			RequestId:  req.RequestId,
+			ResponseId: time.Now().UnixMilli(),
+		})
+		return
+	}
+	log.Debugf("receive ShardAuditReq: orderId=%d cid=%v requestId=%d", req.OrderId, req.Cid, req.RequestId)
+	respond(l.HandleShardAudit(req, sender))
+}
+
 func (l StreamStorageProtocol) handleShardAssign(s network.Stream) {
 	defer s.Close()
 
@@ -125,19 +244,30 @@ func (l StreamStorageProtocol) handleShardAssign(s network.Stream) {
 		}
 	}
 
-	// Set a deadline on reading from the stream so it doesn't hang
-	_ = s.SetReadDeadline(time.Now().Add(30 * time.Second))
-	defer s.SetReadDeadline(time.Time{}) // nolint
+	r, release, err := l.limiter.Begin(s, false)
+	if err != nil {
+		log.Warn(err.Error())
+		respond(types.ShardAssignResp{
+			Code:    types.ErrorCodeBusy,
+			Message: err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	var req types.ShardAssignReq
-	err := req.Unmarshal(s, types.FormatCbor)
+	sender, err := transport.ReadSignedRequest(l.ctx, s, r, l.resolver, &req)
 	if err != nil {
+		log.Error(err.Error())
 		respond(types.ShardAssignResp{
-			Code:    types.ErrorCodeInvalidRequest,
-			Message: fmt.Sprintf("failed to unmarshal request: %v", err),
+			Code:      transport.RequestErrorCode(err),
+			Message:   fmt.Sprintf("failed to read request: %v", err),
+			RequestId: req.RequestId,
 		})
+		return
 	}
-	respond(l.HandleShardAssign(req))
+	log.Debugf("receive ShardAssignReq: orderId=%d dataId=%s requestId=%d", req.OrderId, req.DataId, req.RequestId)
+	respond(l.HandleShardAssign(req, sender))
 }
 
 func (l StreamStorageProtocol) RequestShardMigrate(
@@ -146,11 +276,29 @@ func (l StreamStorageProtocol) RequestShardMigrate(
 	peer string,
 ) types.ShardMigrateResp {
 	resp := types.ShardMigrateResp{}
-	err := transport.HandleRequest(ctx, peer, l.host, types.ShardMigrateProtocol, &req, &resp, false)
+	err := transport.HandleSignedRequest(ctx, peer, l.host, l.nodeAddress, types.ShardMigrateProtocol, &req, &resp)
 	if err != nil {
 		resp = types.ShardMigrateResp{
-			Code:    types.ErrorCodeInternalErr,
-			Message: fmt.Sprintf("transport migrate request error: %v", err),
+			Code:      types.ErrorCodeInternalErr,
+			Message:   fmt.Sprintf("transport migrate request error: %v", err),
+			RequestId: req.RequestId,
+		}
+	}
+	return resp
+}
+
+func (l StreamStorageProtocol) RequestShardMigrateChunk(
+	ctx context.Context,
+	req types.ShardMigrateChunkReq,
+	peer string,
+) types.ShardMigrateChunkResp {
+	resp := types.ShardMigrateChunkResp{}
+	err := transport.HandleSignedRequest(ctx, peer, l.host, l.nodeAddress, types.ShardMigrateChunkProtocol, &req, &resp)
+	if err != nil {
+		resp = types.ShardMigrateChunkResp{
+			Code:      types.ErrorCodeInternalErr,
+			Message:   fmt.Sprintf("transport migrate chunk request error: %v", err),
+			RequestId: req.RequestId,
 		}
 	}
 	return resp
@@ -158,20 +306,21 @@ func (l StreamStorageProtocol) RequestShardMigrate(
 
 func (l StreamStorageProtocol) RequestShardComplete(ctx context.Context, req types.ShardCompleteReq, peer string) types.ShardCompleteResp {
 	resp := types.ShardCompleteResp{}
-	err := transport.HandleRequest(
+	err := transport.HandleSignedRequest(
 		ctx,
 		peer,
 		l.host,
+		l.nodeAddress,
 		types.ShardCompleteProtocol,
 		&req,
 		&resp,
-		false,
 	)
 	if err != nil {
 		resp = types.ShardCompleteResp{
 			Code:        types.ErrorCodeInternalErr,
 			Message:     fmt.Sprintf("transport complete request error: %v", err),
 			Recoverable: true,
+			RequestId:   req.RequestId,
 		}
 	}
 	return resp
@@ -5,10 +5,16 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"sao-node/chain"
+	"sao-node/node/alert"
+	"sao-node/node/events"
+	"sao-node/node/staging"
+	"sao-node/node/transport"
 	"sao-node/store"
 	"sao-node/types"
 	"sao-node/utils"
+	"sao-node/utils/bufpool"
 	"strings"
 	"time"
 
@@ -37,6 +43,8 @@ var log = logging.Logger("storage")
 
 const (
 	MAX_RETRIES = 3
+	// the bytes of a shard sent by a migrate chunk request
+	migrateChunkSize = 4 << 20
 )
 
 type MigrateRequest struct {
@@ -52,14 +60,31 @@ type MigrateRequest struct {
 type StoreSvc struct {
 	nodeAddress        string
 	chainSvc           *chain.ChainSvc
-	taskChan           chan types.ShardInfo
+	tasks              *taskQueue
 	migrateChan        chan MigrateRequest
 	host               host.Host
 	stagingPath        string
+	migrations         *migrateInbox
 	storeManager       *store.StoreManager
 	ctx                context.Context
 	orderDs            datastore.Batching
+	shards             *shardCache
 	storageProtocolMap map[string]StorageProtocol
+	alerts             *alert.Manager
+	capacity           *transport.CapacityGuard
+	bus                *events.Bus
+	// announce the stored shards on the DHT
+	provide    bool
+	advertiser Advertiser
+	relay      relayVerifier
+	// blocks the complete txs must be deep before the shards and the migrations are complete
+	confirmations int64
+	confirmer     txConfirmer
+}
+
+// Advertiser publishes the stored shards to the network indexers.
+type Advertiser interface {
+	Publish(ctx context.Context, contextID []byte, cids []cid.Cid) (cid.Cid, error)
 }
 
 func NewStoreService(
@@ -67,43 +92,121 @@ func NewStoreService(
 	nodeAddress string,
 	chainSvc *chain.ChainSvc,
 	host host.Host,
-	stagingPath string,
+	stagingPaths []string,
+	migrationPath string,
+	cipher *staging.Cipher,
 	storeManager *store.StoreManager,
-	notifyChan map[string]chan interface{},
+	bus *events.Bus,
 	orderDs datastore.Batching,
+	limiter *transport.StreamLimiter,
+	alerts *alert.Manager,
+	capacity *transport.CapacityGuard,
 ) (*StoreSvc, error) {
 	ss := &StoreSvc{
 		nodeAddress:  nodeAddress,
 		chainSvc:     chainSvc,
-		taskChan:     make(chan types.ShardInfo),
+		tasks:        newTaskQueue(),
 		migrateChan:  make(chan MigrateRequest),
 		host:         host,
-		stagingPath:  stagingPath,
+		stagingPath:  stagingPaths[0],
+		migrations:   newMigrateInbox(migrationPath),
 		storeManager: storeManager,
 		ctx:          ctx,
 		orderDs:      orderDs,
+		shards:       newShardCache(orderDs),
+		alerts:       alerts,
+		capacity:     capacity,
+		bus:          bus,
 	}
+	ss.relay = chainRelayVerifier{ss: ss}
+	ss.confirmer = chainTxConfirmer{ss: ss}
+	chainSvc.SetTxResultHandler(ss.handleTxResult)
 
 	ss.storageProtocolMap = make(map[string]StorageProtocol)
 	ss.storageProtocolMap["local"] = NewLocalStorageProtocol(
 		ctx,
-		notifyChan,
-		stagingPath,
+		bus,
+		stagingPaths,
+		cipher,
 		ss,
 	)
-	ss.storageProtocolMap["stream"] = NewStreamStorageProtocol(host, ss)
+	ss.storageProtocolMap["stream"] = NewStreamStorageProtocol(ctx, host, nodeAddress, chainSvc, limiter, ss)
 
 	// wsevent way to receive shard assign
 	//if err := ss.chainSvc.SubscribeShardTask(ctx, ss.nodeAddress, ss.taskChan); err != nil {
 	//	return nil, err
 	//}
 
+	ss.watchAssignments()
 	go ss.processIncompleteShards(ctx)
 	go ss.processMigrateLoop(ctx)
+	go ss.shards.run(ctx, shardCacheSweepInterval)
 
 	return ss, nil
 }
 
+// EnableProviderAnnouncements announces the stored shards on the DHT through the backends, so they can be retrieved
+// by the ipfs network. The records expire, all shards are announced again every interval until ctx is done.
+func (ss *StoreSvc) EnableProviderAnnouncements(ctx context.Context, interval time.Duration) {
+	ss.provide = true
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ss.reprovide(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (ss *StoreSvc) reprovide(ctx context.Context) {
+	shards, err := ss.ShardList(ctx)
+	if err != nil {
+		log.Warnf("list shards to announce error: %v", err)
+		return
+	}
+	announced := 0
+	for _, shard := range shards {
+		if shard.State < types.ShardStateStored || shard.State == types.ShardStateTerminate {
+			continue
+		}
+		if err := ss.storeManager.Provide(ctx, shard.Cid); err != nil {
+			log.Warnf("announce shard order=%d cid=%v error: %v", shard.OrderId, shard.Cid, err)
+			continue
+		}
+		announced++
+	}
+	log.Infof("%d shards announced", announced)
+}
+
+// SetAdvertiser advertises the newly stored shards to the network indexers through advertiser.
+func (ss *StoreSvc) SetAdvertiser(advertiser Advertiser) {
+	ss.advertiser = advertiser
+}
+
+// announce announces a newly stored shard in the background.
+func (ss *StoreSvc) announce(orderId uint64, shardCid cid.Cid) {
+	if ss.provide {
+		go func() {
+			if err := ss.storeManager.Provide(ss.ctx, shardCid); err != nil {
+				log.Warnf("announce shard cid=%v error: %v", shardCid, err)
+			}
+		}()
+	}
+	if ss.advertiser != nil {
+		go func() {
+			contextID := []byte(fmt.Sprintf("%d/%s", orderId, shardCid))
+			if _, err := ss.advertiser.Publish(ss.ctx, contextID, []cid.Cid{shardCid}); err != nil {
+				log.Warnf("advertise shard order=%d cid=%v error: %v", orderId, shardCid, err)
+			}
+		}()
+	}
+}
+
 func (ss *StoreSvc) processMigrateLoop(ctx context.Context) {
 	for {
 		select {
@@ -123,30 +226,38 @@ func (ss *StoreSvc) processMigrate(ctx context.Context, req MigrateRequest) erro
 	if err != nil {
 		return err
 	}
-	reader, err := ss.storeManager.Get(ss.ctx, cid)
-	if err != nil {
-		return err
-	}
-	shardContent, err := io.ReadAll(reader)
-	if err != nil {
-		return err
-	}
-
 	peer, err := ss.chainSvc.GetNodePeer(ctx, req.ToProvider)
 	if err != nil {
 		return err
 	}
 	p := ss.storageProtocolMap["stream"]
+	start := time.Now()
+	migrated := false
+	defer func() {
+		ss.bus.Publish(ctx, events.ShardEvent{
+			Kind:     events.ShardMigrated,
+			Provider: req.ToProvider,
+			OrderId:  req.OrderId,
+			Ok:       migrated,
+			Latency:  time.Since(start),
+			Time:     time.Now(),
+		})
+	}()
+
+	if err := ss.sendMigrateChunks(ctx, p, req, cid, peer); err != nil {
+		return err
+	}
+	// the content is sent by the chunks
 	resp := p.RequestShardMigrate(ctx, types.ShardMigrateReq{
 		MigrateFrom: req.FromProvider,
 		OrderId:     req.OrderId,
 		DataId:      req.DataId,
 		TxHash:      req.MigrateTxHash,
 		Cid:         req.Cid,
-		Content:     shardContent,
+		RequestId:   time.Now().UnixNano(),
 	}, peer)
 	if resp.Code != 0 {
-		return xerrors.Errorf(resp.Message)
+		return xerrors.Errorf("requestId=%d: %s", resp.RequestId, resp.Message)
 	}
 
 	// validate transaction
@@ -177,12 +288,16 @@ func (ss *StoreSvc) processMigrate(ctx context.Context, req MigrateRequest) erro
 		return xerrors.Errorf("shard status should be ShardCompleted, but is %d", shard.Status)
 	}
 	log.Info("migrate response validate pass.")
+	migrated = true
 
 	migrateInfo, err := utils.GetMigrate(ss.ctx, ss.orderDs, req.DataId, req.FromProvider)
 	if err != nil {
 		log.Error("get migrate error: ", err)
 	} else {
 		migrateInfo.State = types.MigrateStateComplete
+		if ss.confirmations > 0 {
+			migrateInfo.State = types.MigrateStateUnconfirmed
+		}
 		migrateInfo.CompleteTxHash = resp.CompleteHash
 		migrateInfo.CompleteTxHeight = resp.CompleteHeight
 		err = utils.SaveMigrate(ss.ctx, ss.orderDs, migrateInfo)
@@ -194,6 +309,102 @@ func (ss *StoreSvc) processMigrate(ctx context.Context, req MigrateRequest) erro
 	return nil
 }
 
+// sendMigrateChunks sends the content of the shard to the new provider by chunks, from the bytes it has received
+// already, so the shard is never held in memory. A failed transfer is resumed, up to MAX_RETRIES attempts.
+func (ss *StoreSvc) sendMigrateChunks(ctx context.Context, p StorageProtocol, req MigrateRequest, shardCid cid.Cid, peer string) error {
+	chunkReq := func(offset uint64, content []byte) types.ShardMigrateChunkResp {
+		return p.RequestShardMigrateChunk(ctx, types.ShardMigrateChunkReq{
+			MigrateFrom: req.FromProvider,
+			OrderId:     req.OrderId,
+			Cid:         req.Cid,
+			Offset:      offset,
+			Content:     content,
+			RequestId:   time.Now().UnixNano(),
+		}, peer)
+	}
+
+	buf := bufpool.Get(migrateChunkSize)
+	defer bufpool.Put(buf)
+
+	// send sends the chunks from the bytes received, it returns the response of the chunk failed if any
+	send := func() (types.ShardMigrateChunkResp, error) {
+		resp := chunkReq(0, nil)
+		if resp.Code != 0 {
+			return resp, nil
+		}
+		if resp.Received > 0 {
+			log.Infof("order %d: resume the migration to %s from %d bytes", req.OrderId, req.ToProvider, resp.Received)
+		}
+
+		reader, err := ss.openShardAt(ctx, shardCid, resp.Received)
+		if err != nil {
+			return resp, err
+		}
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+		for offset := resp.Received; ; {
+			n, err := io.ReadFull(reader, buf)
+			last := err == io.EOF || err == io.ErrUnexpectedEOF
+			if err != nil && !last {
+				return resp, types.Wrap(types.ErrReadFileFailed, err)
+			}
+			if n > 0 {
+				resp = chunkReq(offset, buf[:n])
+				if resp.Code != 0 {
+					return resp, nil
+				}
+				if resp.Received != offset+uint64(n) {
+					resp.Code = types.ErrorCodeInternalErr
+					resp.Message = fmt.Sprintf("chunk at %d not received, %d bytes received", offset, resp.Received)
+					return resp, nil
+				}
+				offset = resp.Received
+			}
+			if last {
+				return types.ShardMigrateChunkResp{}, nil
+			}
+		}
+	}
+
+	for retries := 0; ; retries++ {
+		resp, err := send()
+		if err != nil {
+			return err
+		}
+		if resp.Code == 0 {
+			return nil
+		}
+		if retries+1 >= MAX_RETRIES {
+			return xerrors.Errorf("order %d: migrate to %s failed: requestId=%d: %s", req.OrderId, req.ToProvider, resp.RequestId, resp.Message)
+		}
+		log.Warnf("order %d: migrate to %s failed, resume: %s", req.OrderId, req.ToProvider, resp.Message)
+		select {
+		case <-time.After(time.Duration(retries+1) * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// openShardAt returns the content of the shard from offset.
+func (ss *StoreSvc) openShardAt(ctx context.Context, shardCid cid.Cid, offset uint64) (io.Reader, error) {
+	reader, err := ss.storeManager.Get(ctx, shardCid)
+	if err != nil {
+		return nil, err
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		if _, err := seeker.Seek(int64(offset), io.SeekStart); err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		return reader, nil
+	}
+	if _, err := io.CopyN(io.Discard, reader, int64(offset)); err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return reader, nil
+}
+
 func (ss *StoreSvc) processIncompleteShards(ctx context.Context) {
 	log.Info("processing pending shards...")
 	pendings, err := ss.getPendingShardList(ctx)
@@ -201,18 +412,25 @@ func (ss *StoreSvc) processIncompleteShards(ctx context.Context) {
 		log.Errorf("process pending shards error: %v", err)
 	}
 	for _, p := range pendings {
-		ss.taskChan <- p
+		ss.tasks.Push(p)
 	}
 }
 
-func (ss *StoreSvc) HandleShardMigrate(req types.ShardMigrateReq) types.ShardMigrateResp {
+// HandleShardMigrate stores the shard migrated from another node, sender is the node which signed the request.
+func (ss *StoreSvc) HandleShardMigrate(req types.ShardMigrateReq, sender string) types.ShardMigrateResp {
 	logAndRespond := func(code uint64, errMsg string) types.ShardMigrateResp {
-		log.Error(errMsg)
+		log.Errorf("requestId=%d orderId=%d: %s", req.RequestId, req.OrderId, errMsg)
 		return types.ShardMigrateResp{
-			Code:    code,
-			Message: errMsg,
+			Code:      code,
+			Message:   errMsg,
+			RequestId: req.RequestId,
 		}
 	}
+	log.Infof("requestId=%d orderId=%d: handling shard migrate from %s", req.RequestId, req.OrderId, req.MigrateFrom)
+
+	if err := verifySender(sender, req.MigrateFrom); err != nil {
+		return logAndRespond(types.ErrorCodeInvalidSignature, err.Error())
+	}
 
 	resultTx, err := ss.chainSvc.GetTx(ss.ctx, req.TxHash, req.TxHeight)
 	if err != nil {
@@ -259,37 +477,9 @@ func (ss *StoreSvc) HandleShardMigrate(req types.ShardMigrateReq) types.ShardMig
 			fmt.Sprintf("dataId migrate fails: %s", m),
 		)
 	}
-	order, err := ss.chainSvc.GetOrder(ss.ctx, req.OrderId)
+	order, shard, err := ss.migratedShard(req.OrderId, req.MigrateFrom, req.Cid)
 	if err != nil {
-		return logAndRespond(
-			types.ErrorCodeInternalErr,
-			fmt.Sprintf("get order %d error: %d", order.Id, err),
-		)
-	}
-	shard, exists := order.Shards[ss.nodeAddress]
-	if !exists {
-		return logAndRespond(
-			types.ErrorCodeInternalErr,
-			fmt.Sprintf("no shard to current provider %s", ss.nodeAddress),
-		)
-	}
-	if shard.From != req.MigrateFrom {
-		return logAndRespond(
-			types.ErrorCodeInternalErr,
-			fmt.Sprintf("unmatched migrate from: expected %s, actual %s", req.MigrateFrom, shard.From),
-		)
-	}
-	if shard.Cid != req.Cid {
-		return logAndRespond(
-			types.ErrorCodeInternalErr,
-			fmt.Sprintf("unmatched cid: expected %s, actual %s", req.Cid, shard.Cid),
-		)
-	}
-	if shard.Status != ordertypes.ShardWaiting {
-		return logAndRespond(
-			types.ErrorCodeInternalErr,
-			fmt.Sprintf("shard status is not invalid, expected ShardWaiting, actual %d", shard.Status),
-		)
+		return logAndRespond(types.ErrorCodeInternalErr, err.Error())
 	}
 
 	cid, err := cid.Decode(shard.Cid)
@@ -299,19 +489,45 @@ func (ss *StoreSvc) HandleShardMigrate(req types.ShardMigrateReq) types.ShardMig
 			fmt.Sprintf("invalid cid %s error: %v", shard.Cid, err),
 		)
 	}
-	// TODO: size check
-	_, err = ss.storeManager.Store(ss.ctx, cid, bytes.NewReader(req.Content))
+	var content io.Reader = bytes.NewReader(req.Content)
+	size := uint64(len(req.Content))
+	key := migrateKey{orderId: order.Id, from: req.MigrateFrom, cid: shard.Cid}
+	if size == 0 {
+		// the content is received by chunks
+		file, err := ss.migrations.open(key)
+		if err != nil {
+			return logAndRespond(types.ErrorCodeInternalErr, fmt.Sprintf("open migrated shard %s error: %v", cid, err))
+		}
+		defer file.Close()
+		size, err = ss.verifyMigrated(file, cid, order.Size_)
+		if err != nil {
+			ss.migrations.remove(key)
+			return logAndRespond(types.ErrorCodeInvalidShardCid, err.Error())
+		}
+		content = file
+	}
+	_, err = ss.storeManager.Store(ss.ctx, cid, content)
 	if err != nil {
 		return logAndRespond(types.ErrorCodeInternalErr, fmt.Sprintf("store cid %s error: %v", cid, err))
 	}
+	ss.migrations.remove(key)
+	ss.announce(order.Id, cid)
 	// send tx
-	txHash, height, err := ss.chainSvc.CompleteOrder(ss.ctx, ss.nodeAddress, order.Id, cid, uint64(len(req.Content)))
+	txHash, height, err := ss.chainSvc.CompleteOrder(ss.ctx, ss.nodeAddress, order.Id, cid, size)
 	if err != nil {
 		return logAndRespond(
 			types.ErrorCodeInvalidTx,
 			fmt.Sprintf("complete order tx failed: %v", err),
 		)
 	}
+	if height == 0 {
+		// the old provider validates the tx at its height
+		resultTx, err := ss.chainSvc.WaitTx(ss.ctx, txHash)
+		if err != nil {
+			return logAndRespond(types.ErrorCodeInvalidTx, fmt.Sprintf("complete order tx %s: %v", txHash, err))
+		}
+		height = resultTx.Height
+	}
 
 	return types.ShardMigrateResp{
 		Code:           0,
@@ -320,6 +536,91 @@ func (ss *StoreSvc) HandleShardMigrate(req types.ShardMigrateReq) types.ShardMig
 	}
 }
 
+// HandleShardMigrateChunk stores a chunk of the shard migrated from another node, sender is the node which signed
+// the request. The order is checked on chain by the first chunk of the migration from the sender.
+func (ss *StoreSvc) HandleShardMigrateChunk(req types.ShardMigrateChunkReq, sender string) types.ShardMigrateChunkResp {
+	logAndRespond := func(code uint64, errMsg string) types.ShardMigrateChunkResp {
+		log.Errorf("requestId=%d orderId=%d: %s", req.RequestId, req.OrderId, errMsg)
+		return types.ShardMigrateChunkResp{
+			Code:      code,
+			Message:   errMsg,
+			RequestId: req.RequestId,
+		}
+	}
+
+	if err := verifySender(sender, req.MigrateFrom); err != nil {
+		return logAndRespond(types.ErrorCodeInvalidSignature, err.Error())
+	}
+
+	// the chunks of a migration checked already are only appended for the same order and sender, any other
+	// sender is checked against the order again
+	key := migrateKey{orderId: req.OrderId, from: req.MigrateFrom, cid: req.Cid}
+	size, ok := ss.migrations.expected(key)
+	if !ok {
+		order, _, err := ss.migratedShard(req.OrderId, req.MigrateFrom, req.Cid)
+		if err != nil {
+			return logAndRespond(types.ErrorCodeInternalErr, err.Error())
+		}
+		size = order.Size_
+		ss.migrations.expect(key, size)
+	}
+
+	received, err := ss.migrations.write(key, req.Offset, req.Content, size)
+	if err != nil {
+		return logAndRespond(types.ErrorCodeInternalErr, err.Error())
+	}
+	return types.ShardMigrateChunkResp{
+		Received:  received,
+		RequestId: req.RequestId,
+	}
+}
+
+// migratedShard returns the order and the shard of this node, which should be waiting for the migration of the shard
+// of cid from the given node.
+func (ss *StoreSvc) migratedShard(orderId uint64, from string, shardCid string) (*ordertypes.Order, *ordertypes.Shard, error) {
+	order, err := ss.chainSvc.GetOrder(ss.ctx, orderId)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("get order %d error: %v", orderId, err)
+	}
+	shard, exists := order.Shards[ss.nodeAddress]
+	if !exists {
+		return nil, nil, xerrors.Errorf("no shard to current provider %s", ss.nodeAddress)
+	}
+	if shard.From != from {
+		return nil, nil, xerrors.Errorf("unmatched migrate from: expected %s, actual %s", from, shard.From)
+	}
+	if shard.Cid != shardCid {
+		return nil, nil, xerrors.Errorf("unmatched cid: expected %s, actual %s", shardCid, shard.Cid)
+	}
+	if shard.Status != ordertypes.ShardWaiting {
+		return nil, nil, xerrors.Errorf("shard status is not invalid, expected ShardWaiting, actual %d", shard.Status)
+	}
+	return order, shard, nil
+}
+
+// verifyMigrated checks the shard received by chunks is of the size of the order and of the cid, it returns the
+// size and rewinds the file to be stored.
+func (ss *StoreSvc) verifyMigrated(file *os.File, shardCid cid.Cid, size uint64) (uint64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	if size > 0 && uint64(info.Size()) != size {
+		return 0, types.Wrapf(types.ErrInvalidContent, "migrated shard %s of %d bytes, expected %d", shardCid, info.Size(), size)
+	}
+	contentCid, err := utils.CalculateCidFrom(file)
+	if err != nil {
+		return 0, err
+	}
+	if !contentCid.Equals(shardCid) {
+		return 0, types.Wrapf(types.ErrInvalidCid, "migrated shard cid %s, expected %s", contentCid, shardCid)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return uint64(info.Size()), nil
+}
+
 func (ss *StoreSvc) HandleShardLoad(req types.ShardLoadReq, remotePeerId string) types.ShardLoadResp {
 	logAndRespond := func(code uint64, errMsg string) types.ShardLoadResp {
 		log.Error(errMsg)
@@ -372,28 +673,12 @@ func (ss *StoreSvc) HandleShardLoad(req types.ShardLoadReq, remotePeerId string)
 	}
 
 	log.Debugf("check peer: %s<->%s", req.Proposal.Proposal.Gateway, remotePeerId)
-	if !strings.Contains(req.Proposal.Proposal.Gateway, remotePeerId) {
-		if len(req.RelayProposal.Signature) > 0 && strings.Contains(req.RelayProposal.Proposal.RelayPeerIds, remotePeerId) {
-			account, err := ss.chainSvc.GetAccount(ss.ctx, req.RelayProposal.Proposal.NodeAddress)
-			if err != nil {
-				return logAndRespond(
-					types.ErrorCodeInternalErr,
-					fmt.Sprintf("failed to get gateway account info: %v", err),
-				)
-			}
-			buf := new(bytes.Buffer)
-			err = req.RelayProposal.Proposal.MarshalCBOR(buf)
-			if err != nil {
-				return logAndRespond(
-					types.ErrorCodeInternalErr,
-					fmt.Sprintf("failed marshal relay proposal: %v", err),
-				)
-			}
-			account.GetPubKey().VerifySignature(buf.Bytes(), req.RelayProposal.Signature)
-		} else {
+	if !namesPeer(req.Proposal.Proposal.Gateway, remotePeerId) {
+		err = verifyRelay(ss.ctx, ss.relay, req.RelayProposal, req.Proposal.Proposal.Gateway, remotePeerId, ss.host.ID().String())
+		if err != nil {
 			return logAndRespond(
-				types.ErrorCodeInternalErr,
-				fmt.Sprintf("invalid query, unexpect gateway:%s, should be %s", remotePeerId, req.Proposal.Proposal.Gateway),
+				types.ErrorCodeInvalidSignature,
+				fmt.Sprintf("invalid relayed query: %v", err),
 			)
 		}
 	}
@@ -438,14 +723,69 @@ func (ss *StoreSvc) HandleShardLoad(req types.ShardLoadReq, remotePeerId string)
 	}
 }
 
-func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq) types.ShardAssignResp {
+// HandleShardAudit returns the content of a shard challenged by the gateway of its order, sender is the node which
+// signed the request. Unlike a load, no query of the owner is needed, the gateway placed the order.
+func (ss *StoreSvc) HandleShardAudit(req types.ShardLoadReq, sender string) types.ShardLoadResp {
+	logAndRespond := func(code uint64, errMsg string) types.ShardLoadResp {
+		log.Errorf("requestId=%d orderId=%d: %s", req.RequestId, req.OrderId, errMsg)
+		return types.ShardLoadResp{
+			Code:       code,
+			Message:    errMsg,
+			OrderId:    req.OrderId,
+			Cid:        req.Cid,
+			RequestId:  req.RequestId,
+			ResponseId: time.Now().UnixMilli(),
+		}
+	}
+
+	order, err := ss.chainSvc.GetOrder(ss.ctx, req.OrderId)
+	if err != nil {
+		return logAndRespond(types.ErrorCodeInternalErr, fmt.Sprintf("get order %d error: %v", req.OrderId, err))
+	}
+	// unsigned over 1.0, but there is no 1.0 version of the audits
+	if sender == "" || sender != order.Provider {
+		return logAndRespond(
+			types.ErrorCodeInvalidOrderProvider,
+			fmt.Sprintf("audit of order %d requested by %q, but its gateway is %s", req.OrderId, sender, order.Provider),
+		)
+	}
+	shard, exists := order.Shards[ss.nodeAddress]
+	if !exists || shard.Cid != req.Cid.String() {
+		return logAndRespond(
+			types.ErrorCodeInvalidShardCid,
+			fmt.Sprintf("no shard %v of order %d on %s", req.Cid, req.OrderId, ss.nodeAddress),
+		)
+	}
+
+	reader, err := ss.storeManager.Get(ss.ctx, req.Cid)
+	if err != nil {
+		return logAndRespond(types.ErrorCodeInternalErr, fmt.Sprintf("get %v from store error: %v", req.Cid, err))
+	}
+	shardContent, err := io.ReadAll(reader)
+	if err != nil {
+		return logAndRespond(types.ErrorCodeInternalErr, fmt.Sprintf("get %v from store error: %v", req.Cid, err))
+	}
+	return types.ShardLoadResp{
+		OrderId:    req.OrderId,
+		Cid:        req.Cid,
+		Content:    shardContent,
+		RequestId:  req.RequestId,
+		ResponseId: time.Now().UnixMilli(),
+	}
+}
+
+// HandleShardAssign stores the shards of the order assigned to this node, sender is the node which signed the
+// request, it must be the gateway of the order.
+func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq, sender string) types.ShardAssignResp {
 	logAndRespond := func(code uint64, errMsg string) types.ShardAssignResp {
-		log.Error(errMsg)
+		log.Errorf("requestId=%d orderId=%d: %s", req.RequestId, req.OrderId, errMsg)
 		return types.ShardAssignResp{
-			Code:    code,
-			Message: errMsg,
+			Code:      code,
+			Message:   errMsg,
+			RequestId: req.RequestId,
 		}
 	}
+	log.Infof("requestId=%d orderId=%d: handling shard assign, txHash=%s", req.RequestId, req.OrderId, req.TxHash)
 
 	// validate request
 	if req.Assignee != ss.nodeAddress {
@@ -455,6 +795,10 @@ func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq) types.ShardAssig
 		)
 	}
 
+	if err := ss.capacity.Check(); err != nil {
+		return logAndRespond(types.ErrorCodeNoCapacity, err.Error())
+	}
+
 	resultTx, err := ss.chainSvc.GetTx(ss.ctx, req.TxHash, req.Height)
 	if err != nil {
 		return logAndRespond(
@@ -495,6 +839,9 @@ func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq) types.ShardAssig
 				fmt.Sprintf("internal error: %v", err),
 			)
 		}
+		if err := verifySender(sender, order.Provider); err != nil {
+			return logAndRespond(types.ErrorCodeInvalidSignature, err.Error())
+		}
 
 		var shardCids []string
 		for key, shard := range order.Shards {
@@ -508,6 +855,7 @@ func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq) types.ShardAssig
 				fmt.Sprintf("order %d doesn't have shard provider %s", req.OrderId, ss.nodeAddress),
 			)
 		}
+		var shards, created []types.ShardInfo
 		for _, shardCid := range shardCids {
 			cid, err := cid.Decode(shardCid)
 			if err != nil {
@@ -517,7 +865,7 @@ func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq) types.ShardAssig
 				)
 			}
 
-			shardInfo, _ := utils.GetShard(ss.ctx, ss.orderDs, req.OrderId, cid)
+			shardInfo, _ := ss.shards.Get(ss.ctx, req.OrderId, cid)
 			if (types.ShardInfo{} == shardInfo) {
 				shardInfo = types.ShardInfo{
 					Owner:          order.Owner,
@@ -529,16 +877,23 @@ func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq) types.ShardAssig
 					ShardOperation: fmt.Sprintf("%d", order.Operation),
 					State:          types.ShardStateValidated,
 					ExpireHeight:   uint64(order.Expire),
+					Priority:       req.Priority,
 				}
-				err = utils.SaveShard(ss.ctx, ss.orderDs, shardInfo)
-				if err != nil {
-					// do not throw error, the best case is storage node handle shard again.
-					log.Warn("put shard order=%d cid=%v error: %v", shardInfo.OrderId, shardInfo.Cid, err)
-				}
+				created = append(created, shardInfo)
+			}
+			shards = append(shards, shardInfo)
+		}
+		if len(created) > 0 {
+			err = ss.shards.SaveAll(ss.ctx, created)
+			if err != nil {
+				// do not throw error, the best case is storage node handle shard again.
+				log.Warnf("put shards of order=%d error: %v", req.OrderId, err)
 			}
-			ss.taskChan <- shardInfo
 		}
-		return types.ShardAssignResp{Code: 0}
+		for _, shardInfo := range shards {
+			ss.tasks.Push(shardInfo)
+		}
+		return types.ShardAssignResp{Code: 0, RequestId: req.RequestId}
 	} else {
 		return logAndRespond(
 			types.ErrorCodeInvalidTx,
@@ -549,19 +904,15 @@ func (ss *StoreSvc) HandleShardAssign(req types.ShardAssignReq) types.ShardAssig
 
 func (ss *StoreSvc) Start(ctx context.Context) error {
 	for {
-		select {
-		case t, ok := <-ss.taskChan:
-			if !ok {
-				return nil
-			}
-			err := ss.process(ctx, t)
-			if err != nil {
-				// TODO: retry mechanism
-				log.Error(err)
-			}
-		case <-ctx.Done():
+		t, ok := ss.tasks.Pop(ctx)
+		if !ok {
 			return nil
 		}
+		err := ss.process(ctx, t)
+		if err != nil {
+			// TODO: retry mechanism
+			log.Error(err)
+		}
 	}
 }
 
@@ -577,6 +928,7 @@ func (ss *StoreSvc) process(ctx context.Context, task types.ShardInfo) error {
 		task.State = types.ShardStateTerminate
 		errMsg := fmt.Sprintf("order %d shard %v too many retries %d", task.OrderId, task.DataId, task.Tries)
 		ss.updateShardError(task, xerrors.Errorf(errMsg))
+		ss.alerts.Fire(alert.TypeShardRetries, "%s, last error: %s", errMsg, task.LastErr)
 		return types.Wrapf(types.ErrRetriesExceed, errMsg)
 	}
 
@@ -603,30 +955,17 @@ func (ss *StoreSvc) process(ctx context.Context, task types.ShardInfo) error {
 	if task.State < types.ShardStateStored {
 		// check if it's a renew order(Operation is 3)
 		if task.OrderOperation != "3" || task.ShardOperation != "3" {
-			resp := sp.RequestShardStore(ctx, types.ShardLoadReq{
+			size, err := ss.storeShard(ctx, sp, peerInfo, types.ShardLoadReq{
 				Owner:   task.Owner,
 				OrderId: task.OrderId,
 				Cid:     task.Cid,
-			}, peerInfo)
-			if resp.Code != 0 {
-				ss.updateShardError(task, types.Wrapf(types.ErrFailuresResponsed, resp.Message))
-				return types.Wrapf(types.ErrFailuresResponsed, resp.Message)
-			} else {
-				cid, _ := utils.CalculateCid(resp.Content)
-				log.Debugf("ipfs cid %v, task cid %v, order id %v", cid, task.Cid, task.OrderId)
-				if cid.String() != task.Cid.String() {
-					ss.updateShardError(task, err)
-					return types.Wrapf(types.ErrInvalidCid, "ipfs cid %v != task cid %v", cid, task.Cid)
-				}
-			}
-
-			// store to backends
-			_, err = ss.storeManager.Store(ctx, task.Cid, bytes.NewReader(resp.Content))
+			})
 			if err != nil {
 				ss.updateShardError(task, err)
-				return types.Wrap(types.ErrStoreFailed, err)
+				return err
 			}
-			task.Size = uint64(len(resp.Content))
+			task.Size = size
+			ss.announce(task.OrderId, task.Cid)
 		} else {
 			// make sure the data is still there
 			isExist := ss.storeManager.IsExist(ctx, task.Cid)
@@ -636,7 +975,7 @@ func (ss *StoreSvc) process(ctx context.Context, task types.ShardInfo) error {
 			}
 		}
 		task.State = types.ShardStateStored
-		err = utils.SaveShard(ctx, ss.orderDs, task)
+		err = ss.shards.Save(ctx, task)
 		if err != nil {
 			log.Warnf("put shard order=%d cid=%v error: %v", task.OrderId, task.Cid, err)
 		}
@@ -650,29 +989,37 @@ func (ss *StoreSvc) process(ctx context.Context, task types.ShardInfo) error {
 		}
 		log.Infof("Complete order succeed: txHash: %s, OrderId: %d, cid: %s", txHash, task.OrderId, task.Cid)
 
-		task.State = types.ShardStateComplete
+		task.State = ss.completeState()
 		task.CompleteHash = txHash
 		task.CompleteHeight = height
-		err = utils.SaveShard(ss.ctx, ss.orderDs, task)
+		err = ss.shards.Save(ss.ctx, task)
 		if err != nil {
 			log.Warnf("put shard order=%d cid=%v error: %v", task.OrderId, task.Cid, err)
 		}
 	}
 
+	if task.CompleteHeight == 0 {
+		// broadcast without waiting for its block, the shard is processed again once the tx is included
+		ss.chainSvc.TrackTx(task.CompleteHash, sdktypes.MsgTypeURL(&saotypes.MsgComplete{}))
+		return nil
+	}
+
 	resp := sp.RequestShardComplete(ctx, types.ShardCompleteReq{
-		OrderId: task.OrderId,
-		DataId:  task.DataId,
-		Cids:    []cid.Cid{task.Cid},
-		Height:  task.CompleteHeight,
-		TxHash:  task.CompleteHash,
+		OrderId:   task.OrderId,
+		DataId:    task.DataId,
+		Cids:      []cid.Cid{task.Cid},
+		Height:    task.CompleteHeight,
+		TxHash:    task.CompleteHash,
+		RequestId: time.Now().UnixNano(),
 	}, peerInfo)
 	if resp.Code != 0 {
-		ss.updateShardError(task, types.Wrapf(types.ErrFailuresResponsed, resp.Message))
+		log.Errorf("requestId=%d orderId=%d: complete failed: %s", resp.RequestId, task.OrderId, resp.Message)
+		ss.updateShardError(task, types.Wrapf(types.ErrFailuresResponsed, "requestId=%d: %s", resp.RequestId, resp.Message))
 		// return types.Wrapf(types.ErrFailuresResponsed, resp.Message)
 	}
-	if task.State < types.ShardStateComplete {
-		task.State = types.ShardStateComplete
-		err = utils.SaveShard(ss.ctx, ss.orderDs, task)
+	if task.State < ss.completeState() {
+		task.State = ss.completeState()
+		err = ss.shards.Save(ss.ctx, task)
 		if err != nil {
 			log.Warnf("put shard order=%d cid=%v error: %v", task.OrderId, task.Cid, err)
 		}
@@ -686,7 +1033,7 @@ func (ss *StoreSvc) Stop(ctx context.Context) error {
 	//	return err
 	//}
 	log.Info("stopping storage service...")
-	close(ss.taskChan)
+	ss.tasks.Close()
 
 	var err error
 	for k, p := range ss.storageProtocolMap {
@@ -707,6 +1054,50 @@ func (ss *StoreSvc) getSidDocFunc() func(versionId string) (*sid.SidDocument, er
 	}
 }
 
+// storeShard fetches the shard from the gateway, checks it and stores it to the backends. The shard staged by this
+// node is stored from the staging file, so it is never held in memory.
+func (ss *StoreSvc) storeShard(ctx context.Context, sp StorageProtocol, peerInfo string, req types.ShardLoadReq) (uint64, error) {
+	var content io.ReadSeeker
+	if local, ok := sp.(LocalStorageProtocol); ok {
+		// the gateway of this node may have staged the shard encrypted, it is decrypted while read
+		file, err := local.OpenShard(req)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		content = file
+	} else {
+		resp := sp.RequestShardStore(ctx, req, peerInfo)
+		if resp.Code != 0 {
+			return 0, types.Wrapf(types.ErrFailuresResponsed, resp.Message)
+		}
+		content = bytes.NewReader(resp.Content)
+	}
+
+	cid, err := utils.CalculateCidFrom(content)
+	if err != nil {
+		return 0, err
+	}
+	log.Debugf("ipfs cid %v, task cid %v, order id %v", cid, req.Cid, req.OrderId)
+	if cid.String() != req.Cid.String() {
+		return 0, types.Wrapf(types.ErrInvalidCid, "ipfs cid %v != task cid %v", cid, req.Cid)
+	}
+	size, err := content.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	// store to backends
+	_, err = ss.storeManager.Store(ctx, req.Cid, content)
+	if err != nil {
+		return 0, types.Wrap(types.ErrStoreFailed, err)
+	}
+	return uint64(size), nil
+}
+
 func (ss *StoreSvc) getStorageProtocolAndPeer(
 	ctx context.Context,
 	targetAddress string,
@@ -725,7 +1116,7 @@ func (ss *StoreSvc) getStorageProtocolAndPeer(
 
 func (ss *StoreSvc) updateShardError(shard types.ShardInfo, err error) {
 	shard.LastErr = err.Error()
-	err = utils.SaveShard(ss.ctx, ss.orderDs, shard)
+	err = ss.shards.Save(ss.ctx, shard)
 	if err != nil {
 		log.Warnf("put shard order=%d cid=%v error: %v", shard.OrderId, shard.Cid, err)
 	}
@@ -733,60 +1124,42 @@ func (ss *StoreSvc) updateShardError(shard types.ShardInfo, err error) {
 }
 
 func (ss *StoreSvc) ShardStatus(ctx context.Context, orderId uint64, cid cid.Cid) (types.ShardInfo, error) {
-	return utils.GetShard(ctx, ss.orderDs, orderId, cid)
+	return ss.shards.Get(ctx, orderId, cid)
 }
 
 func (ss *StoreSvc) getPendingShardList(ctx context.Context) ([]types.ShardInfo, error) {
-	shardKeys, err := ss.getShardKeyList(ctx)
-	if err != nil {
-		return nil, err
-	}
-	// TODO: optimize add a pending list in OrderShards
 	var pending []types.ShardInfo
-	for _, shardKey := range shardKeys {
-		shard, err := utils.GetShard(ctx, ss.orderDs, shardKey.OrderId, shardKey.Cid)
-		if err != nil {
-			return nil, err
-		}
+	err := utils.ForEachShard(ctx, ss.orderDs, func(shard types.ShardInfo) error {
 		if shard.State != types.ShardStateComplete && shard.State != types.ShardStateTerminate {
 			pending = append(pending, shard)
 		}
-	}
-	return pending, nil
-}
-
-func (ss *StoreSvc) getShardKeyList(ctx context.Context) ([]types.ShardKey, error) {
-	index, err := utils.GetShardIndex(ctx, ss.orderDs)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return index.All, nil
+	return pending, nil
 }
 
 func (ss *StoreSvc) ShardList(ctx context.Context) ([]types.ShardInfo, error) {
-	shardKeys, err := ss.getShardKeyList(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	var shardInfos []types.ShardInfo
-	for _, shardKey := range shardKeys {
-		shard, err := utils.GetShard(ctx, ss.orderDs, shardKey.OrderId, shardKey.Cid)
-		if err != nil {
-			return nil, err
-		}
+	err := utils.ForEachShard(ctx, ss.orderDs, func(shard types.ShardInfo) error {
 		shardInfos = append(shardInfos, shard)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return shardInfos, nil
 }
 
 func (ss *StoreSvc) ShardFix(ctx context.Context, orderId uint64, cid cid.Cid) error {
-	shardInfo, err := utils.GetShard(ctx, ss.orderDs, orderId, cid)
+	shardInfo, err := ss.shards.Get(ctx, orderId, cid)
 	if err != nil {
 		return nil
 	}
 
-	ss.taskChan <- shardInfo
+	ss.tasks.Push(shardInfo)
 	return nil
 }
 
@@ -851,26 +1224,13 @@ func (ss *StoreSvc) Migrate(ctx context.Context, dataIds []string) (string, map[
 }
 
 func (ss *StoreSvc) MigrateList(ctx context.Context) ([]types.MigrateInfo, error) {
-	migrateKeys, err := ss.getMigrateKeyList(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	var migrateInfos []types.MigrateInfo
-	for _, migrateKey := range migrateKeys {
-		migrate, err := utils.GetMigrate(ctx, ss.orderDs, migrateKey.DataId, migrateKey.FromProvider)
-		if err != nil {
-			return nil, err
-		}
+	err := utils.ForEachMigrate(ctx, ss.orderDs, func(migrate types.MigrateInfo) error {
 		migrateInfos = append(migrateInfos, migrate)
-	}
-	return migrateInfos, nil
-}
-
-func (ss *StoreSvc) getMigrateKeyList(ctx context.Context) ([]types.MigrateKey, error) {
-	index, err := utils.GetMigrateIndex(ctx, ss.orderDs)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return index.All, nil
+	return migrateInfos, nil
 }
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"sao-node/types"
+	"sync"
+)
+
+// taskQueue holds the shard tasks to process, the tasks of the highest priority are popped first, FIFO within a
+// priority, so the small interactive orders are not stuck behind the archival ones.
+type taskQueue struct {
+	lock   sync.Mutex
+	tasks  []types.ShardInfo
+	notify chan struct{}
+	closed bool
+}
+
+func newTaskQueue() *taskQueue {
+	return &taskQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *taskQueue) Push(task types.ShardInfo) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		log.Warnf("task queue closed, drop order=%d cid=%v", task.OrderId, task.Cid)
+		return
+	}
+	q.tasks = append(q.tasks, task)
+	q.signal()
+}
+
+// Pop waits for a task, false is returned once the queue is closed or ctx is done.
+func (q *taskQueue) Pop(ctx context.Context) (types.ShardInfo, bool) {
+	for {
+		q.lock.Lock()
+		if q.closed {
+			q.lock.Unlock()
+			return types.ShardInfo{}, false
+		}
+		if len(q.tasks) > 0 {
+			front := 0
+			for i, task := range q.tasks {
+				if task.Priority > q.tasks[front].Priority {
+					front = i
+				}
+			}
+			task := q.tasks[front]
+			q.tasks = append(q.tasks[:front], q.tasks[front+1:]...)
+			q.lock.Unlock()
+			return task, true
+		}
+		q.lock.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return types.ShardInfo{}, false
+		}
+	}
+}
+
+func (q *taskQueue) Close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.closed = true
+	q.signal()
+}
+
+func (q *taskQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskQueue(t *testing.T) {
+	ctx := context.Background()
+	q := newTaskQueue()
+	q.Push(types.ShardInfo{OrderId: 1, Priority: types.PriorityArchival})
+	q.Push(types.ShardInfo{OrderId: 2})
+	q.Push(types.ShardInfo{OrderId: 3, Priority: types.PriorityInteractive})
+	q.Push(types.ShardInfo{OrderId: 4})
+
+	for _, orderId := range []uint64{3, 2, 4, 1} {
+		task, ok := q.Pop(ctx)
+		require.True(t, ok)
+		require.Equal(t, orderId, task.OrderId)
+	}
+
+	done := make(chan types.ShardInfo)
+	go func() {
+		task, _ := q.Pop(ctx)
+		done <- task
+	}()
+	q.Push(types.ShardInfo{OrderId: 5})
+	require.Equal(t, uint64(5), (<-done).OrderId)
+
+	q.Close()
+	_, ok := q.Pop(ctx)
+	require.False(t, ok)
+}
@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"sao-node/types"
+	"sync"
+)
+
+// CapacityGuard rejects new shards and uploads while the node is short of disk space. The node monitors the
+// free space and marks the guard full or resumes it. A nil guard never rejects.
+type CapacityGuard struct {
+	lk     sync.RWMutex
+	reason string
+}
+
+func NewCapacityGuard() *CapacityGuard {
+	return &CapacityGuard{}
+}
+
+// Check returns ErrNoCapacity while the guard is full.
+func (g *CapacityGuard) Check() error {
+	if g == nil {
+		return nil
+	}
+
+	g.lk.RLock()
+	defer g.lk.RUnlock()
+
+	if g.reason != "" {
+		return types.Wrapf(types.ErrNoCapacity, "%s", g.reason)
+	}
+	return nil
+}
+
+// SetFull marks the guard full with the reason, or resumes it if reason is empty.
+// It returns whether the state changed.
+func (g *CapacityGuard) SetFull(reason string) bool {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+
+	changed := (g.reason == "") != (reason == "")
+	g.reason = reason
+	return changed
+}
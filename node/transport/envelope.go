@@ -0,0 +1,149 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sao-node/types"
+	"strings"
+	"sync/atomic"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// NodePeerResolver looks up the peer info a node has registered on chain.
+type NodePeerResolver interface {
+	GetNodePeer(ctx context.Context, creator string) (string, error)
+}
+
+// the unsigned requests of the peers still speaking the 1.0 protocols are rejected unless accepted explicitly
+var acceptUnsigned atomic.Bool
+
+// AcceptUnsignedRequests sets whether ReadSignedRequest accepts the unsigned requests over the 1.0 protocols, for
+// upgrading the peers of a network without a flag day. Anyone can forge those requests.
+func AcceptUnsignedRequests(accept bool) {
+	acceptUnsigned.Store(accept)
+}
+
+// signedRequest is sealed into a RequestEnvelope once the protocol version of the stream is known,
+// peers still speaking 1.0 get the bare request.
+type signedRequest struct {
+	host        host.Host
+	nodeAddress string
+	req         CommonMarshaler
+}
+
+func (r *signedRequest) seal(s network.Stream) (CommonMarshaler, error) {
+	pid := string(s.Protocol())
+	if types.ProtocolVersion(pid) == "1.0" {
+		return r.req, nil
+	}
+
+	var buf bytes.Buffer
+	if err := r.req.Marshal(&buf, types.FormatCbor); err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+
+	key := r.host.Peerstore().PrivKey(r.host.ID())
+	if key == nil {
+		return nil, types.Wrapf(types.ErrSignedFailed, "private key of %s not found", r.host.ID())
+	}
+
+	sig, err := key.Sign(envelopeBytes(pid, r.nodeAddress, buf.Bytes()))
+	if err != nil {
+		return nil, types.Wrap(types.ErrSignedFailed, err)
+	}
+
+	return &types.RequestEnvelope{
+		NodeAddress: r.nodeAddress,
+		Payload:     buf.Bytes(),
+		Signature:   sig,
+	}, nil
+}
+
+// HandleSignedRequest works like HandleRequest, but signs the request with the node key of the host.
+func HandleSignedRequest(ctx context.Context, peerInfos string, host host.Host, nodeAddress string, protocol protocol.ID, req CommonMarshaler, resp interface{}) error {
+	return HandleRequest(ctx, peerInfos, host, protocol, &signedRequest{
+		host:        host,
+		nodeAddress: nodeAddress,
+		req:         req,
+	}, resp, false)
+}
+
+// ReadSignedRequest reads a request sent by HandleSignedRequest, and returns the node address of the sender.
+// The signature must be made by the remote peer, and the remote peer must be registered on chain by the node.
+// Requests over 1.0 protocols are not signed, they are rejected unless AcceptUnsignedRequests, and an empty node
+// address is returned for them. The request is read from r, the bounded reader of s, see RequestErrorCode for
+// answering the errors.
+func ReadSignedRequest(ctx context.Context, s network.Stream, r io.Reader, resolver NodePeerResolver, req CommonUnmarshaler) (string, error) {
+	pid := string(s.Protocol())
+	if types.ProtocolVersion(pid) == "1.0" {
+		if !acceptUnsigned.Load() {
+			return "", types.Wrapf(types.ErrInvalidSignature, "unsigned request from %s over %s", s.Conn().RemotePeer(), pid)
+		}
+		log.Warnf("unsigned request from %s over %s", s.Conn().RemotePeer(), pid)
+		if err := req.Unmarshal(r, types.FormatCbor); err != nil {
+			return "", readError(r, err)
+		}
+		return "", nil
+	}
+
+	var envelope types.RequestEnvelope
+	if err := envelope.Unmarshal(r, types.FormatCbor); err != nil {
+		return "", readError(r, err)
+	}
+
+	remotePeer := s.Conn().RemotePeer()
+	pubKey := s.Conn().RemotePublicKey()
+	if pubKey == nil {
+		return "", types.Wrapf(types.ErrInvalidSignature, "public key of %s not found", remotePeer)
+	}
+	ok, err := pubKey.Verify(envelopeBytes(pid, envelope.NodeAddress, envelope.Payload), envelope.Signature)
+	if err != nil || !ok {
+		return "", types.Wrapf(types.ErrInvalidSignature, "request from %s is not signed by itself", remotePeer)
+	}
+
+	peerInfo, err := resolver.GetNodePeer(ctx, envelope.NodeAddress)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(peerInfo, remotePeer.String()) {
+		return "", types.Wrapf(types.ErrUnregisteredPeer, "node=%s, peer=%s", envelope.NodeAddress, remotePeer)
+	}
+
+	if err := req.Unmarshal(bytes.NewReader(envelope.Payload), types.FormatCbor); err != nil {
+		return "", types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	return envelope.NodeAddress, nil
+}
+
+// RequestErrorCode returns the code of the response to a request ReadSignedRequest failed to read.
+func RequestErrorCode(err error) uint64 {
+	switch {
+	case types.ErrInvalidSignature.Is(err), types.ErrUnregisteredPeer.Is(err):
+		return types.ErrorCodeInvalidSignature
+	case types.ErrRequestTooLarge.Is(err):
+		return types.ErrorCodeRequestTooLarge
+	case types.ErrUnMarshalFailed.Is(err):
+		return types.ErrorCodeInvalidRequest
+	default:
+		return types.ErrorCodeInternalErr
+	}
+}
+
+// readError tells the request exceeding the limit of the reader from a malformed one.
+func readError(r io.Reader, err error) error {
+	if rr, ok := r.(*requestReader); ok && rr.n < 0 {
+		return types.Wrapf(types.ErrRequestTooLarge, "request exceeds %d bytes", rr.limit)
+	}
+	return types.Wrap(types.ErrUnMarshalFailed, err)
+}
+
+func envelopeBytes(protocol string, nodeAddress string, payload []byte) []byte {
+	buf := bytes.NewBufferString(protocol)
+	buf.WriteString(nodeAddress)
+	buf.Write(payload)
+	return buf.Bytes()
+}
@@ -0,0 +1,135 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"sao-node/types"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+type readResult struct {
+	sender string
+	req    types.ShardCompleteReq
+	err    error
+}
+
+// newEnvelopeHosts returns a client connected to a server, which reads the requests of pid with a limit of
+// requests bytes, 0 is unlimited.
+func newEnvelopeHosts(t *testing.T, resolver NodePeerResolver, pid string, limit int64) (host.Host, host.Host, chan readResult) {
+	client, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	server, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	results := make(chan readResult, 1)
+	server.SetStreamHandler(protocol.ID(pid), func(s network.Stream) {
+		defer s.Close()
+		var r io.Reader = s
+		if limit > 0 {
+			r = &requestReader{r: s, n: limit, limit: limit}
+		}
+		var result readResult
+		result.sender, result.err = ReadSignedRequest(context.Background(), s, r, resolver, &result.req)
+		results <- result
+		// answered so the client doesn't wait for the response
+		_ = (&types.ShardCompleteResp{Code: RequestErrorCode(result.err)}).Marshal(s, types.FormatCbor)
+		_ = s.CloseWrite()
+	})
+	require.NoError(t, client.Connect(context.Background(), peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}))
+	return client, server, results
+}
+
+func peerInfoOf(h host.Host) string {
+	return h.Addrs()[0].String() + "/p2p/" + h.ID().String()
+}
+
+func readOne(t *testing.T, results chan readResult) readResult {
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(10 * time.Second):
+		t.Fatal("no request read")
+		return readResult{}
+	}
+}
+
+func TestReadSignedRequest(t *testing.T) {
+	ctx := context.Background()
+	resolver := peerResolver{}
+	client, server, results := newEnvelopeHosts(t, resolver, types.ShardCompleteProtocol, 0)
+	req := &types.ShardCompleteReq{OrderId: 1, DataId: "data1", RequestId: 7}
+
+	// signed by the peer registered for the node
+	resolver["sao1node"] = "/ip4/127.0.0.1/tcp/1/p2p/" + client.ID().String()
+	_ = HandleSignedRequest(ctx, peerInfoOf(server), client, "sao1node", types.ShardCompleteProtocol, req, &types.ShardCompleteResp{})
+	result := readOne(t, results)
+	require.NoError(t, result.err)
+	require.Equal(t, "sao1node", result.sender)
+	require.Equal(t, "data1", result.req.DataId)
+
+	// the node is registered with another peer
+	resolver["sao1node"] = "/ip4/127.0.0.1/tcp/1/p2p/" + server.ID().String()
+	_ = HandleSignedRequest(ctx, peerInfoOf(server), client, "sao1node", types.ShardCompleteProtocol, req, &types.ShardCompleteResp{})
+	result = readOne(t, results)
+	require.True(t, types.ErrUnregisteredPeer.Is(result.err))
+	require.Equal(t, uint64(types.ErrorCodeInvalidSignature), RequestErrorCode(result.err))
+
+	// the payload is tampered with after it is signed
+	resolver["sao1node"] = "/ip4/127.0.0.1/tcp/1/p2p/" + client.ID().String()
+	s, err := client.NewStream(ctx, server.ID(), protocol.ID(types.ShardCompleteProtocol))
+	require.NoError(t, err)
+	sealed, err := (&signedRequest{host: client, nodeAddress: "sao1node", req: req}).seal(s)
+	require.NoError(t, err)
+	envelope := sealed.(*types.RequestEnvelope)
+	envelope.Payload[len(envelope.Payload)-1] ^= 1
+	require.NoError(t, envelope.Marshal(s, types.FormatCbor))
+	require.NoError(t, s.CloseWrite())
+	result = readOne(t, results)
+	require.True(t, types.ErrInvalidSignature.Is(result.err))
+	require.Equal(t, uint64(types.ErrorCodeInvalidSignature), RequestErrorCode(result.err))
+	s.Close()
+}
+
+func TestReadSignedRequestLimit(t *testing.T) {
+	resolver := peerResolver{}
+	client, server, results := newEnvelopeHosts(t, resolver, types.ShardCompleteProtocol, 64)
+	resolver["sao1node"] = "/ip4/127.0.0.1/tcp/1/p2p/" + client.ID().String()
+
+	req := &types.ShardCompleteReq{OrderId: 1, DataId: string(make([]byte, 1024))}
+	_ = HandleSignedRequest(context.Background(), peerInfoOf(server), client, "sao1node", types.ShardCompleteProtocol, req, &types.ShardCompleteResp{})
+	result := readOne(t, results)
+	require.True(t, types.ErrRequestTooLarge.Is(result.err))
+	require.Equal(t, uint64(types.ErrorCodeRequestTooLarge), RequestErrorCode(result.err))
+}
+
+func TestReadUnsignedRequest(t *testing.T) {
+	defer AcceptUnsignedRequests(false)
+	resolver := peerResolver{}
+	// the server only speaks 1.0, the request is sent unsigned
+	client, server, results := newEnvelopeHosts(t, resolver, types.ShardCompleteProtocolV1_0, 0)
+	req := &types.ShardCompleteReq{OrderId: 1, DataId: "data1"}
+
+	_ = HandleSignedRequest(context.Background(), peerInfoOf(server), client, "sao1node", types.ShardCompleteProtocol, req, &types.ShardCompleteResp{})
+	result := readOne(t, results)
+	require.True(t, types.ErrInvalidSignature.Is(result.err))
+
+	AcceptUnsignedRequests(true)
+	_ = HandleSignedRequest(context.Background(), peerInfoOf(server), client, "sao1node", types.ShardCompleteProtocol, req, &types.ShardCompleteResp{})
+	result = readOne(t, results)
+	require.NoError(t, result.err)
+	require.Equal(t, "", result.sender)
+	require.Equal(t, "data1", result.req.DataId)
+}
@@ -9,7 +9,10 @@ import (
 	"os/signal"
 	"path/filepath"
 	"sao-node/api"
+	apitypes "sao-node/api/types"
 	"sao-node/node/config"
+	"sao-node/node/progress"
+	"sao-node/node/staging"
 	"sao-node/types"
 	"sao-node/utils"
 	"strconv"
@@ -21,21 +24,25 @@ import (
 	"github.com/mitchellh/go-homedir"
 	ma "github.com/multiformats/go-multiaddr"
 
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/network"
 )
 
 type Libp2pRpcServer struct {
-	Ctx              context.Context
-	DbLk             sync.Mutex
-	Db               datastore.Batching
-	GatewayApi       api.SaoApi
-	StagingPath      string
-	StagingSapceSize int64
+	Ctx         context.Context
+	DbLk        sync.Mutex
+	Db          datastore.Batching
+	GatewayApi  api.SaoApi
+	StagingPath string
+	Staging     *staging.Quota
+	Capacity    *CapacityGuard
+	Progress    *progress.Tracker
+	Limiter     *StreamLimiter
 }
 
-func StartLibp2pRpcServer(ctx context.Context, ga api.SaoApi, address string, serverKey crypto.PrivKey, db datastore.Batching, cfg *config.Node) (*Libp2pRpcServer, error) {
+func StartLibp2pRpcServer(ctx context.Context, ga api.SaoApi, address string, serverKey crypto.PrivKey, db datastore.Batching, cfg *config.Node, capacity *CapacityGuard, tracker *progress.Tracker, quota *staging.Quota, limiter *StreamLimiter) (*Libp2pRpcServer, error) {
 	tr, err := libp2pwebtransport.New(serverKey, nil, network.NullResourceManager)
 	if err != nil {
 		return nil, err
@@ -52,11 +59,14 @@ func StartLibp2pRpcServer(ctx context.Context, ga api.SaoApi, address string, se
 	}
 
 	rs := &Libp2pRpcServer{
-		Ctx:              ctx,
-		Db:               db,
-		GatewayApi:       ga,
-		StagingPath:      cfg.Transport.StagingPath,
-		StagingSapceSize: cfg.Transport.StagingSapceSize,
+		Ctx:         ctx,
+		Db:          db,
+		GatewayApi:  ga,
+		StagingPath: cfg.Transport.StagingPath,
+		Staging:     quota,
+		Capacity:    capacity,
+		Progress:    tracker,
+		Limiter:     limiter,
 	}
 
 	h.Network().SetStreamHandler(rs.HandleStream)
@@ -74,16 +84,23 @@ func StartLibp2pRpcServer(ctx context.Context, ga api.SaoApi, address string, se
 func (rs *Libp2pRpcServer) HandleStream(s network.Stream) {
 	defer s.Close()
 
-	// Set a deadline on reading from the stream so it doesn’t hang
-	_ = s.SetReadDeadline(time.Now().Add(30 * time.Second))
-	defer s.SetReadDeadline(time.Time{}) // nolint
-
 	var req types.RpcReq
 	var resp = types.RpcResp{}
 
+	// an upload request carries a chunk of the file
+	r, release, err := rs.Limiter.Begin(s, true)
+	if err != nil {
+		log.Warn(err.Error())
+		respondRpc(s, types.RpcResp{Error: err.Error()})
+		return
+	}
+	defer release()
+
 	buf := &bytes.Buffer{}
-	buf.ReadFrom(s)
-	err := json.Unmarshal(buf.Bytes(), &req)
+	_, err = buf.ReadFrom(r)
+	if err == nil {
+		err = json.Unmarshal(buf.Bytes(), &req)
+	}
 	if err == nil {
 		log.Info("Got rpc request: ", req.Method)
 
@@ -96,7 +113,9 @@ func (rs *Libp2pRpcServer) HandleStream(s network.Stream) {
 		case "Sao.ModelCreate":
 			result, err = rs.create(req.Params)
 		case "Sao.ModelLoad":
-			result, err = rs.load(req.Params)
+			result, err = rs.load(req.Params, rs.GatewayApi.ModelLoad)
+		case "Sao.ModelLoadMeta":
+			result, err = rs.load(req.Params, rs.GatewayApi.ModelLoadMeta)
 		case "Sao.ModelUpdate":
 			result, err = rs.update(req.Params)
 		default:
@@ -112,6 +131,10 @@ func (rs *Libp2pRpcServer) HandleStream(s network.Stream) {
 		resp.Error = err.Error()
 	}
 
+	respondRpc(s, resp)
+}
+
+func respondRpc(s network.Stream, resp types.RpcResp) {
 	bytes, err := json.Marshal(resp)
 	if err != nil {
 		log.Error(err.Error())
@@ -131,7 +154,8 @@ func (rs *Libp2pRpcServer) HandleStream(s network.Stream) {
 	log.Info("Sent rpc response: ", resp)
 }
 
-func (rs *Libp2pRpcServer) handleChunkInfo(req *types.FileChunkReq, path string) {
+// handleChunkInfo records the received chunk, it returns nil if the chunk is not recorded.
+func (rs *Libp2pRpcServer) handleChunkInfo(req *types.FileChunkReq, path string) *types.ReceivedFileInfo {
 	rs.DbLk.Lock()
 	defer rs.DbLk.Unlock()
 
@@ -152,7 +176,7 @@ func (rs *Libp2pRpcServer) handleChunkInfo(req *types.FileChunkReq, path string)
 		err := json.Unmarshal(info, &fileInfo)
 		if err != nil {
 			log.Error(err.Error())
-			return
+			return nil
 		}
 
 		if fileInfo.ChunkCids[req.ChunkId] == "" {
@@ -160,25 +184,26 @@ func (rs *Libp2pRpcServer) handleChunkInfo(req *types.FileChunkReq, path string)
 			fileInfo.ReceivedLength += len(req.Content)
 		} else {
 			log.Error("invalid chunk ", req.Cid, ", received already")
-			return
+			return nil
 		}
 	} else {
 		// should not happen
 		log.Error("invalid req, ", err.Error())
-		return
+		return nil
 	}
 
 	info, err := json.Marshal(fileInfo)
 	if err != nil {
 		log.Error(err.Error())
-		return
+		return nil
 	}
 
 	err = rs.Db.Put(rs.Ctx, key, info)
 	if err != nil {
 		log.Error(err.Error())
-		return
+		return nil
 	}
+	return fileInfo
 }
 
 func (rs *Libp2pRpcServer) upload(params []string) (string, error) {
@@ -199,22 +224,28 @@ func (rs *Libp2pRpcServer) upload(params []string) (string, error) {
 	}
 
 	if len(req.Content) > 0 {
-		stagingPath, err := homedir.Expand(rs.StagingPath)
-		if err != nil {
-			return "", nil
+		// the uploads in progress are allowed to finish
+		if req.ChunkId == 0 {
+			if err := rs.Capacity.Check(); err != nil {
+				return "", err
+			}
 		}
 
-		info, err := os.Stat(stagingPath)
-		if err != nil {
-			return "", nil
-		} else {
-			if info.Size()+int64(len(req.Content)) > rs.StagingSapceSize {
-				return "", types.Wrapf(types.ErrInvalidParameters, "not enough staging space under %s, need %v but only %v left", rs.StagingPath, len(req.Content), rs.StagingSapceSize-info.Size())
-			}
+		// the uploads are staged under the peer id of the client
+		if err := rs.Staging.Reserve(filepath.Base(params[1]), int64(len(req.Content))); err != nil {
+			return "", err
 		}
 
 		path := filepath.Join(params[1], req.Cid)
-		rs.handleChunkInfo(&req, path)
+		if info := rs.handleChunkInfo(&req, path); info != nil {
+			received := 0
+			for _, chunkCid := range info.ChunkCids {
+				if chunkCid != "" {
+					received++
+				}
+			}
+			rs.Progress.Staged(req.Cid, uint64(info.ReceivedLength), uint64(info.TotalLength), received, info.TotalChunks)
+		}
 
 		path, err = homedir.Expand(path)
 		if err != nil {
@@ -261,23 +292,7 @@ func (rs *Libp2pRpcServer) upload(params []string) (string, error) {
 			}
 			log.Info("path: ", basePath)
 
-			var fileContent []byte
-			for _, chunkCid := range fileInfo.ChunkCids {
-				var path = filepath.Join(basePath, chunkCid)
-				file, err := os.Open(path)
-				if err != nil {
-					return "", err
-				}
-
-				content, err := io.ReadAll(file)
-				if err != nil {
-					return "", err
-				}
-
-				fileContent = append(fileContent, content...)
-			}
-
-			contentCid, err := utils.CalculateCid(fileContent)
+			contentCid, length, err := assembleChunks(basePath, fileInfo.ChunkCids, req.Cid)
 			if err != nil {
 				return "", err
 			}
@@ -285,17 +300,7 @@ func (rs *Libp2pRpcServer) upload(params []string) (string, error) {
 			log.Info("Requested file, CID: ", req.Cid)
 			log.Info("Requested file, length: ", req.TotalLength)
 			log.Info("Received file, CID: ", contentCid)
-			log.Info("Received file, length: ", len(fileContent))
-
-			file, err := os.Create(filepath.Join(basePath, req.Cid))
-			if err != nil {
-				return "", err
-			}
-
-			_, err = file.Write(fileContent)
-			if err != nil {
-				return "", err
-			}
+			log.Info("Received file, length: ", length)
 		} else {
 			return "", err
 		}
@@ -304,6 +309,47 @@ func (rs *Libp2pRpcServer) upload(params []string) (string, error) {
 	return req.Cid, nil
 }
 
+// assembleChunks concatenates the chunk files under basePath into the file named name, streaming each chunk so
+// the file is never held in memory. The file is replaced at once, so a file staged from a previous upload of the
+// same name by a link keeps its content.
+func assembleChunks(basePath string, chunkCids []string, name string) (cid.Cid, int64, error) {
+	file, err := os.CreateTemp(basePath, name+".*.tmp")
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var length int64
+	for _, chunkCid := range chunkCids {
+		chunk, err := os.Open(filepath.Join(basePath, chunkCid))
+		if err != nil {
+			return cid.Undef, 0, err
+		}
+		n, err := io.Copy(file, chunk)
+		chunk.Close()
+		if err != nil {
+			return cid.Undef, 0, err
+		}
+		length += n
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return cid.Undef, 0, err
+	}
+	contentCid, err := utils.CalculateCidFrom(file)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	if err := file.Close(); err != nil {
+		return cid.Undef, 0, err
+	}
+	if err := os.Rename(file.Name(), filepath.Join(basePath, name)); err != nil {
+		return cid.Undef, 0, err
+	}
+	return contentCid, length, nil
+}
+
 func (rs *Libp2pRpcServer) create(params []string) (string, error) {
 	if len(params) != 3 {
 		return "", types.Wrapf(types.ErrInvalidParameters, "invalid params length")
@@ -341,7 +387,7 @@ func (rs *Libp2pRpcServer) create(params []string) (string, error) {
 	return string(b), nil
 }
 
-func (rs *Libp2pRpcServer) load(params []string) (string, error) {
+func (rs *Libp2pRpcServer) load(params []string, load func(context.Context, *types.MetadataProposal) (apitypes.LoadResp, error)) (string, error) {
 	if len(params) != 1 {
 		return "", types.Wrapf(types.ErrInvalidParameters, "invalid params length")
 	}
@@ -352,7 +398,7 @@ func (rs *Libp2pRpcServer) load(params []string) (string, error) {
 		log.Error(err.Error())
 		return "", nil
 	}
-	resp, err := rs.GatewayApi.ModelLoad(rs.Ctx, &req)
+	resp, err := load(rs.Ctx, &req)
 	if err != nil {
 		log.Error(err.Error())
 		return "", nil
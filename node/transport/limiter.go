@@ -0,0 +1,249 @@
+package transport
+
+import (
+	"io"
+	"sao-node/types"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultReadTimeout = 30 * time.Second
+	// the buckets of the idle peers are dropped once they would be full again
+	pruneInterval = time.Minute
+)
+
+// StreamLimiter bounds the number of inbound streams handled concurrently, both per protocol and per remote peer.
+// Streams beyond the protocol limit wait in queue for up to queueTimeout, streams beyond the peer limit
+// are rejected at once. The streams a peer opens are also throttled by a token bucket, and the requests read
+// from the streams are bounded in size and time.
+type StreamLimiter struct {
+	protocolLimit int
+	peerLimit     int
+	queueTimeout  time.Duration
+
+	// bytes of a request, and of a request carrying a shard or an upload chunk, 0 is unlimited
+	maxRequestSize int64
+	maxContentSize int64
+	readTimeout    time.Duration
+	// streams per second of a peer, 0 is unlimited
+	peerRate  rate.Limit
+	peerBurst int
+
+	lk        sync.Mutex
+	protocols map[string]chan struct{}
+	peers     map[string]int
+	buckets   map[string]*peerBucket
+	prunedAt  time.Time
+}
+
+type peerBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewStreamLimiter(protocolLimit int, peerLimit int, queueTimeout time.Duration) *StreamLimiter {
+	return &StreamLimiter{
+		protocolLimit: protocolLimit,
+		peerLimit:     peerLimit,
+		queueTimeout:  queueTimeout,
+		readTimeout:   defaultReadTimeout,
+		protocols:     make(map[string]chan struct{}),
+		peers:         make(map[string]int),
+		buckets:       make(map[string]*peerBucket),
+	}
+}
+
+// Acquire reserves a slot for the stream, the returned function must be called once the stream is handled.
+// ErrBusy is returned if no slot is available.
+func (sl *StreamLimiter) Acquire(s network.Stream) (func(), error) {
+	if sl == nil {
+		return func() {}, nil
+	}
+
+	// the versions of a protocol share the slots, so a peer can't double the limit by speaking both
+	pid := types.ProtocolName(string(s.Protocol()))
+	peerId := s.Conn().RemotePeer().String()
+
+	sl.lk.Lock()
+	if !sl.allow(peerId, time.Now()) {
+		sl.lk.Unlock()
+		return nil, types.Wrapf(types.ErrBusy, "peer %s exceeds %v streams per second", peerId, float64(sl.peerRate))
+	}
+	if sl.peerLimit > 0 && sl.peers[peerId] >= sl.peerLimit {
+		sl.lk.Unlock()
+		return nil, types.Wrapf(types.ErrBusy, "peer %s has %d streams in process", peerId, sl.peerLimit)
+	}
+	sl.peers[peerId]++
+
+	protocolLimit := sl.protocolLimit
+	queueTimeout := sl.queueTimeout
+	var slots chan struct{}
+	if protocolLimit > 0 {
+		slots = sl.protocols[pid]
+		if slots == nil {
+			slots = make(chan struct{}, protocolLimit)
+			sl.protocols[pid] = slots
+		}
+	}
+	sl.lk.Unlock()
+
+	releasePeer := func() {
+		sl.lk.Lock()
+		defer sl.lk.Unlock()
+
+		sl.peers[peerId]--
+		if sl.peers[peerId] <= 0 {
+			delete(sl.peers, peerId)
+		}
+	}
+
+	if slots == nil {
+		return releasePeer, nil
+	}
+
+	timer := time.NewTimer(queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return func() {
+			<-slots
+			releasePeer()
+		}, nil
+	case <-timer.C:
+		releasePeer()
+		return nil, types.Wrapf(types.ErrBusy, "%s has %d streams in process", pid, protocolLimit)
+	}
+}
+
+// Begin reserves a slot for the stream and returns the reader of its request, which is bounded in time and size so
+// a peer can't hold the handler or exhaust the memory. The returned function clears the read deadline and releases
+// the slot, it must be called once the stream is handled. ErrBusy is returned if no slot is available.
+func (sl *StreamLimiter) Begin(s network.Stream, withContent bool) (io.Reader, func(), error) {
+	release, err := sl.Acquire(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := sl.RequestReader(s, withContent)
+	return r, func() {
+		_ = s.SetReadDeadline(time.Time{})
+		release()
+	}, nil
+}
+
+// SetLimits changes the limits, streams in process keep the slots they hold.
+func (sl *StreamLimiter) SetLimits(protocolLimit int, peerLimit int, queueTimeout time.Duration) {
+	sl.lk.Lock()
+	defer sl.lk.Unlock()
+
+	if sl.protocolLimit != protocolLimit {
+		sl.protocols = make(map[string]chan struct{})
+	}
+	sl.protocolLimit = protocolLimit
+	sl.peerLimit = peerLimit
+	sl.queueTimeout = queueTimeout
+}
+
+// SetRequestLimits changes the max bytes of a request and of a request carrying content, and how long reading a
+// request may take.
+func (sl *StreamLimiter) SetRequestLimits(maxRequestSize int64, maxContentSize int64, readTimeout time.Duration) {
+	sl.lk.Lock()
+	defer sl.lk.Unlock()
+
+	sl.maxRequestSize = maxRequestSize
+	sl.maxContentSize = maxContentSize
+	sl.readTimeout = readTimeout
+	if sl.readTimeout <= 0 {
+		sl.readTimeout = defaultReadTimeout
+	}
+}
+
+// SetPeerRate changes the token bucket of each peer, a peer opens up to burst streams at once and perSecond
+// more every second. 0 disables the throttling.
+func (sl *StreamLimiter) SetPeerRate(perSecond float64, burst int) {
+	sl.lk.Lock()
+	defer sl.lk.Unlock()
+
+	sl.peerRate = rate.Limit(perSecond)
+	sl.peerBurst = burst
+	if sl.peerBurst <= 0 {
+		sl.peerBurst = 1
+	}
+	sl.buckets = make(map[string]*peerBucket)
+}
+
+// allow takes a token of the peer, the caller holds the lock.
+func (sl *StreamLimiter) allow(peerId string, now time.Time) bool {
+	if sl.peerRate <= 0 {
+		return true
+	}
+
+	if now.Sub(sl.prunedAt) > pruneInterval {
+		refill := time.Duration(float64(sl.peerBurst) / float64(sl.peerRate) * float64(time.Second))
+		for id, bucket := range sl.buckets {
+			if idle := now.Sub(bucket.lastSeen); idle > pruneInterval && idle > refill {
+				delete(sl.buckets, id)
+			}
+		}
+		sl.prunedAt = now
+	}
+
+	bucket, ok := sl.buckets[peerId]
+	if !ok {
+		bucket = &peerBucket{limiter: rate.NewLimiter(sl.peerRate, sl.peerBurst)}
+		sl.buckets[peerId] = bucket
+	}
+	bucket.lastSeen = now
+	return bucket.limiter.AllowN(now, 1)
+}
+
+// RequestReader sets the read deadline of the stream and returns the reader of the request on it, which fails
+// once the request exceeds the max request size, or the max content size if withContent.
+func (sl *StreamLimiter) RequestReader(s network.Stream, withContent bool) io.Reader {
+	if sl == nil {
+		_ = s.SetReadDeadline(time.Now().Add(defaultReadTimeout))
+		return s
+	}
+
+	sl.lk.Lock()
+	limit := sl.maxRequestSize
+	if withContent {
+		limit = sl.maxContentSize
+	}
+	readTimeout := sl.readTimeout
+	sl.lk.Unlock()
+
+	_ = s.SetReadDeadline(time.Now().Add(readTimeout))
+	if limit <= 0 {
+		return s
+	}
+	return &requestReader{r: s, n: limit, limit: limit}
+}
+
+// requestReader reads up to limit bytes, reading more fails instead of truncating the request.
+type requestReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (r *requestReader) Read(p []byte) (int, error) {
+	if r.n < 0 {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "request exceeds %d bytes", r.limit)
+	}
+	// one more byte tells a request of exactly the limit from a larger one
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.r.Read(p)
+	r.n -= int64(n)
+	if r.n < 0 {
+		return n + int(r.n), types.Wrapf(types.ErrInvalidParameters, "request exceeds %d bytes", r.limit)
+	}
+	return n, err
+}
@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sao-node/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamLimiterAllow(t *testing.T) {
+	sl := NewStreamLimiter(0, 0, time.Second)
+	now := time.Now()
+	require.True(t, sl.allow("peer1", now))
+
+	sl.SetPeerRate(1, 2)
+	require.True(t, sl.allow("peer1", now))
+	require.True(t, sl.allow("peer1", now))
+	require.False(t, sl.allow("peer1", now))
+	// the buckets are per peer
+	require.True(t, sl.allow("peer2", now))
+	// refilled at the rate
+	require.True(t, sl.allow("peer1", now.Add(time.Second)))
+	require.False(t, sl.allow("peer1", now.Add(time.Second)))
+
+	// idle peers are pruned
+	require.True(t, sl.allow("peer3", now.Add(2*pruneInterval)))
+	require.Len(t, sl.buckets, 1)
+}
+
+func TestRequestReader(t *testing.T) {
+	read := func(data string, limit int64) ([]byte, error) {
+		return io.ReadAll(&requestReader{r: strings.NewReader(data), n: limit, limit: limit})
+	}
+
+	data, err := read("0123456789", 10)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(data))
+
+	data, err = read("0123456789a", 10)
+	require.ErrorIs(t, err, types.ErrInvalidParameters)
+	require.Equal(t, "0123456789", string(data))
+
+	_, err = io.ReadAll(&requestReader{r: bytes.NewReader(make([]byte, 1<<20)), n: 1 << 10, limit: 1 << 10})
+	require.ErrorIs(t, err, types.ErrInvalidParameters)
+}
+
+func TestStreamLimiterProtocolVersions(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestHost(t), newTestHost(t)
+	SetStreamHandler(server, types.ShardPingPongProtocol, func(s network.Stream) { s.Close() })
+	require.NoError(t, client.Connect(ctx, peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}))
+
+	newStream := func(pid string) network.Stream {
+		s, err := client.NewStream(ctx, server.ID(), protocol.ID(pid))
+		require.NoError(t, err)
+		t.Cleanup(func() { s.Close() })
+		return s
+	}
+	current := newStream(types.ShardPingPongProtocol)
+	legacy := newStream(types.ShardPingPongProtocolV1_0)
+	require.Equal(t, types.ProtocolName(types.ShardPingPongProtocol), types.ProtocolName(types.ShardPingPongProtocolV1_0))
+
+	// the versions of a protocol share its slots
+	sl := NewStreamLimiter(1, 0, 10*time.Millisecond)
+	release, err := sl.Acquire(current)
+	require.NoError(t, err)
+	_, err = sl.Acquire(legacy)
+	require.ErrorIs(t, err, types.ErrBusy)
+
+	release()
+	release, err = sl.Acquire(legacy)
+	require.NoError(t, err)
+	release()
+}
@@ -17,14 +17,14 @@ func DoPingRequest(ctx context.Context, host host.Host) {
 			continue
 		}
 
-		stream, err := host.NewStream(ctx, peerId, types.ShardPingPongProtocol)
+		stream, err := NewStream(ctx, host, peerId, types.ShardPingPongProtocol)
 		if err != nil {
 			log.Error(types.Wrap(types.ErrCreateStreamFailed, err))
 			continue
 		}
 
 		defer stream.Close()
-		log.Debugf("open stream to %s protocol %s.", peerId, types.ShardPingPongProtocol)
+		log.Debugf("open stream to %s protocol %s.", peerId, stream.Protocol())
 
 		// Set a deadline on reading from the stream so it doesn't hang
 		_ = stream.SetReadDeadline(time.Now().Add(300 * time.Second))
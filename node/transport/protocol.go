@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"context"
+	"sao-node/types"
+	"sort"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+const (
+	StreamInbound  = "inbound"
+	StreamOutbound = "outbound"
+)
+
+type ProtocolStat struct {
+	Protocol  string
+	Version   string
+	Direction string
+	Streams   uint64
+}
+
+var protocolStats = struct {
+	sync.Mutex
+	counts map[string]*ProtocolStat
+}{
+	counts: make(map[string]*ProtocolStat),
+}
+
+// SetStreamHandler registers the handler for every supported version of the protocol,
+// libp2p multistream-select then picks the newest version both sides speak.
+func SetStreamHandler(h host.Host, p string, handler network.StreamHandler) {
+	for _, pid := range types.ProtocolVersions(p) {
+		h.SetStreamHandler(protocol.ID(pid), func(s network.Stream) {
+			recordStream(s, StreamInbound)
+			handler(s)
+		})
+	}
+}
+
+func RemoveStreamHandler(h host.Host, p string) {
+	for _, pid := range types.ProtocolVersions(p) {
+		h.RemoveStreamHandler(protocol.ID(pid))
+	}
+}
+
+// NewStream opens a stream to the peer with the newest protocol version the remote peer supports.
+func NewStream(ctx context.Context, h host.Host, peerId peer.ID, p string) (network.Stream, error) {
+	var pids []protocol.ID
+	for _, pid := range types.ProtocolVersions(p) {
+		pids = append(pids, protocol.ID(pid))
+	}
+
+	s, err := h.NewStream(ctx, peerId, pids...)
+	if err != nil {
+		return nil, err
+	}
+	recordStream(s, StreamOutbound)
+
+	return s, nil
+}
+
+// ProtocolStats returns the number of streams per negotiated protocol version, sorted by the protocol id.
+func ProtocolStats() []ProtocolStat {
+	protocolStats.Lock()
+	defer protocolStats.Unlock()
+
+	stats := make([]ProtocolStat, 0, len(protocolStats.counts))
+	for _, stat := range protocolStats.counts {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Protocol != stats[j].Protocol {
+			return stats[i].Protocol < stats[j].Protocol
+		}
+		return stats[i].Direction < stats[j].Direction
+	})
+	return stats
+}
+
+func recordStream(s network.Stream, direction string) {
+	pid := string(s.Protocol())
+	log.Debugf("%s stream %s negotiated version %s with peer %s", direction, pid, types.ProtocolVersion(pid), s.Conn().RemotePeer())
+
+	protocolStats.Lock()
+	defer protocolStats.Unlock()
+
+	key := direction + pid
+	stat, exists := protocolStats.counts[key]
+	if !exists {
+		stat = &ProtocolStat{
+			Protocol:  pid,
+			Version:   types.ProtocolVersion(pid),
+			Direction: direction,
+		}
+		protocolStats.counts[key] = stat
+	}
+	stat.Streams++
+}
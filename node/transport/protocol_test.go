@@ -0,0 +1,67 @@
+package transport
+
+import (
+	"context"
+	"sao-node/types"
+	"testing"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHost(t *testing.T) host.Host {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func streamCount(pid string, direction string) uint64 {
+	for _, stat := range ProtocolStats() {
+		if stat.Protocol == pid && stat.Direction == direction {
+			return stat.Streams
+		}
+	}
+	return 0
+}
+
+func TestNewStreamVersion(t *testing.T) {
+	ctx := context.Background()
+	client := newTestHost(t)
+	closeStream := func(s network.Stream) { s.Close() }
+
+	// both sides speak every version, so the newest is negotiated
+	current := newTestHost(t)
+	SetStreamHandler(current, types.ShardPingPongProtocol, closeStream)
+	require.NoError(t, client.Connect(ctx, peer.AddrInfo{ID: current.ID(), Addrs: current.Addrs()}))
+
+	outbound := streamCount(types.ShardPingPongProtocol, StreamOutbound)
+	s, err := NewStream(ctx, client, current.ID(), types.ShardPingPongProtocol)
+	require.NoError(t, err)
+	s.Close()
+	require.Equal(t, protocol.ID(types.ShardPingPongProtocol), s.Protocol())
+	require.Equal(t, outbound+1, streamCount(types.ShardPingPongProtocol, StreamOutbound))
+
+	// a peer which only speaks 1.0 falls back to it
+	legacy := newTestHost(t)
+	legacy.SetStreamHandler(protocol.ID(types.ShardPingPongProtocolV1_0), closeStream)
+	require.NoError(t, client.Connect(ctx, peer.AddrInfo{ID: legacy.ID(), Addrs: legacy.Addrs()}))
+
+	outbound = streamCount(types.ShardPingPongProtocolV1_0, StreamOutbound)
+	s, err = NewStream(ctx, client, legacy.ID(), types.ShardPingPongProtocol)
+	require.NoError(t, err)
+	s.Close()
+	require.Equal(t, protocol.ID(types.ShardPingPongProtocolV1_0), s.Protocol())
+	require.Equal(t, "1.0", types.ProtocolVersion(string(s.Protocol())))
+	require.Equal(t, outbound+1, streamCount(types.ShardPingPongProtocolV1_0, StreamOutbound))
+
+	// a peer which speaks none of the versions is refused
+	other := newTestHost(t)
+	require.NoError(t, client.Connect(ctx, peer.AddrInfo{ID: other.ID(), Addrs: other.Addrs()}))
+	_, err = NewStream(ctx, client, other.ID(), types.ShardPingPongProtocol)
+	require.Error(t, err)
+}
@@ -0,0 +1,177 @@
+package transport
+
+import (
+	"context"
+	"sao-node/node/config"
+	"sao-node/types"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/client"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// a reservation is refreshed this long before it expires
+	reservationRefreshMargin = 5 * time.Minute
+	reservationRetryMin      = 10 * time.Second
+	reservationRetryMax      = 5 * time.Minute
+	// the tag of the connections to the relays, so the connection manager keeps them
+	relayConnTag = "sao-relay"
+)
+
+// RelayOptions returns the libp2p options of the relay service of the node. The relayed connections carry whole
+// shards, so they are not limited in time or size, only the reservations and the circuits are.
+func RelayOptions(cfg config.Relay) []libp2p.Option {
+	if !cfg.EnableService {
+		return nil
+	}
+
+	resources := relay.DefaultResources()
+	resources.Limit = nil
+	if cfg.MaxReservations > 0 {
+		resources.MaxReservations = cfg.MaxReservations
+	}
+	if cfg.MaxCircuits > 0 {
+		resources.MaxCircuits = cfg.MaxCircuits
+	}
+	return []libp2p.Option{libp2p.EnableRelayService(relay.WithResources(resources))}
+}
+
+// ParsePeerInfo parses the comma separated multiaddrs of a peer, as registered on chain, into the addresses of
+// the peer. The udp and the loopback addresses are skipped.
+func ParsePeerInfo(peerInfos string) (*peer.AddrInfo, error) {
+	var addrs []ma.Multiaddr
+	for _, peerInfo := range strings.Split(peerInfos, ",") {
+		if peerInfo == "" || strings.Contains(peerInfo, "udp") || strings.Contains(peerInfo, "127.0.0.1") {
+			continue
+		}
+
+		a, err := ma.NewMultiaddr(peerInfo)
+		if err != nil {
+			return nil, types.Wrapf(types.ErrInvalidServerAddress, "peerInfo=%s", peerInfo)
+		}
+		addrs = append(addrs, a)
+	}
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+
+	infos, err := peer.AddrInfosFromP2pAddrs(addrs...)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidServerAddress, "peerInfo=%s: %v", peerInfos, err)
+	}
+	if len(infos) > 1 {
+		return nil, types.Wrapf(types.ErrInvalidServerAddress, "peerInfo=%s names %d peers", peerInfos, len(infos))
+	}
+	return &infos[0], nil
+}
+
+// ResolveRelays returns the addresses of the relays, given as the node addresses of the gateways registered
+// on chain or as multiaddrs with the peer id.
+func ResolveRelays(ctx context.Context, resolver NodePeerResolver, relays []string) ([]peer.AddrInfo, error) {
+	var infos []peer.AddrInfo
+	for _, r := range relays {
+		peerInfo := r
+		if !strings.HasPrefix(r, "/") {
+			var err error
+			peerInfo, err = resolver.GetNodePeer(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		info, err := ParsePeerInfo(peerInfo)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			return nil, types.Wrapf(types.ErrInvalidServerAddress, "no address of relay %s", r)
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+// RelayedAddrs returns the addresses of the node through the relays, they are registered on chain with its
+// peer info.
+func RelayedAddrs(relays []peer.AddrInfo, self peer.ID) []string {
+	var addrs []string
+	for _, r := range relays {
+		for _, a := range r.Addrs {
+			addrs = append(addrs, a.String()+"/p2p/"+r.ID.String()+"/p2p-circuit/p2p/"+self.String())
+		}
+	}
+	return addrs
+}
+
+// RelayPeers returns the relays named by the relayed addresses of the peer info.
+func RelayPeers(peerInfos string) []peer.ID {
+	var relays []peer.ID
+	seen := make(map[peer.ID]bool)
+	for _, peerInfo := range strings.Split(peerInfos, ",") {
+		a, err := ma.NewMultiaddr(peerInfo)
+		if err != nil {
+			continue
+		}
+
+		var last peer.ID
+		ma.ForEach(a, func(c ma.Component) bool {
+			switch c.Protocol().Code {
+			case ma.P_P2P:
+				last, err = peer.IDFromBytes(c.RawValue())
+				return err == nil
+			case ma.P_CIRCUIT:
+				if last != "" && !seen[last] {
+					seen[last] = true
+					relays = append(relays, last)
+				}
+				return false
+			}
+			return true
+		})
+	}
+	return relays
+}
+
+// KeepReservations keeps a reservation on each relay until ctx is done, so the node is reachable through them
+// behind NAT. The reservations are refreshed before they expire and taken again once lost.
+func KeepReservations(ctx context.Context, h host.Host, relays []peer.AddrInfo) {
+	for _, r := range relays {
+		h.ConnManager().Protect(r.ID, relayConnTag)
+		go keepReservation(ctx, h, r)
+	}
+}
+
+func keepReservation(ctx context.Context, h host.Host, r peer.AddrInfo) {
+	retry := reservationRetryMin
+	for {
+		wait := retry
+		rsvp, err := client.Reserve(ctx, h, r)
+		if err != nil {
+			log.Warnf("reserve relay %s error: %v, retry in %v", r.ID, err, retry)
+			retry *= 2
+			if retry > reservationRetryMax {
+				retry = reservationRetryMax
+			}
+		} else {
+			retry = reservationRetryMin
+			wait = time.Until(rsvp.Expiration) - reservationRefreshMargin
+			if wait < reservationRetryMin {
+				wait = reservationRetryMin
+			}
+			log.Infof("reserved relay %s until %v", r.ID, rsvp.Expiration)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			h.ConnManager().Unprotect(r.ID, relayConnTag)
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+type peerResolver map[string]string
+
+func (r peerResolver) GetNodePeer(_ context.Context, address string) (string, error) {
+	return r[address], nil
+}
+
+func newPeerId(t *testing.T) peer.ID {
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(key)
+	require.NoError(t, err)
+	return id
+}
+
+func TestRelayAddrs(t *testing.T) {
+	self, relay := newPeerId(t), newPeerId(t)
+
+	relays, err := ResolveRelays(context.Background(), peerResolver{
+		"sao1relay": "/ip4/127.0.0.1/tcp/5153/p2p/" + relay.String() + ",/ip4/1.2.3.4/tcp/5153/p2p/" + relay.String(),
+	}, []string{"sao1relay"})
+	require.NoError(t, err)
+	require.Len(t, relays, 1)
+	require.Equal(t, relay, relays[0].ID)
+	require.Equal(t, []ma.Multiaddr{ma.StringCast("/ip4/1.2.3.4/tcp/5153")}, relays[0].Addrs)
+
+	relayed := RelayedAddrs(relays, self)
+	require.Equal(t, []string{"/ip4/1.2.3.4/tcp/5153/p2p/" + relay.String() + "/p2p-circuit/p2p/" + self.String()}, relayed)
+
+	// the direct and the relayed addresses of the node
+	peerInfos := strings.Join(append([]string{"/ip4/10.0.0.1/tcp/5153/p2p/" + self.String()}, relayed...), ",")
+	require.Equal(t, []peer.ID{relay}, RelayPeers(peerInfos))
+	info, err := ParsePeerInfo(peerInfos)
+	require.NoError(t, err)
+	require.Equal(t, self, info.ID)
+	require.Len(t, info.Addrs, 2)
+
+	require.Empty(t, RelayPeers("/ip4/10.0.0.1/tcp/5153/p2p/"+self.String()))
+	info, err = ParsePeerInfo("/ip4/127.0.0.1/tcp/5153/p2p/" + self.String())
+	require.NoError(t, err)
+	require.Nil(t, info)
+	_, err = ParsePeerInfo("/ip4/10.0.0.1/tcp/5153/p2p/" + self.String() + ",/ip4/10.0.0.2/tcp/5153/p2p/" + relay.String())
+	require.Error(t, err)
+}
@@ -8,9 +8,7 @@ import (
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/host"
-	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
-	ma "github.com/multiformats/go-multiaddr"
 
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p/core/network"
@@ -27,28 +25,17 @@ type CommonMarshaler interface {
 }
 
 func HandleRequest(ctx context.Context, peerInfos string, host host.Host, protocol protocol.ID, req interface{}, resp interface{}, isForward bool) error {
-	var pi *peer.AddrInfo
-	for _, peerInfo := range strings.Split(peerInfos, ",") {
-		if strings.Contains(peerInfo, "udp") || strings.Contains(peerInfo, "127.0.0.1") {
-			continue
-		}
-
-		a, err := ma.NewMultiaddr(peerInfo)
-		if err != nil {
-			return types.Wrapf(types.ErrInvalidServerAddress, "peerInfo=%s", peerInfo)
-		}
-		pi, err = peer.AddrInfoFromP2pAddr(a)
-		if err != nil {
-			return types.Wrapf(types.ErrInvalidServerAddress, "a=%v", a)
-		}
+	// the relayed addresses are dialed too if the direct ones fail
+	pi, err := ParsePeerInfo(peerInfos)
+	if err != nil {
+		return err
 	}
 	var stream network.Stream = nil
-	var err error = nil
 	if pi == nil {
 		for _, peerId := range host.Peerstore().Peers() {
 			log.Debug("peerId", peerId)
 			if strings.Contains(peerInfos, peerId.String()) {
-				stream, err = host.NewStream(ctx, peerId, protocol)
+				stream, err = NewStream(ctx, host, peerId, string(protocol))
 				if err != nil {
 					return types.Wrap(types.ErrCreateStreamFailed, err)
 				}
@@ -65,19 +52,25 @@ func HandleRequest(ctx context.Context, peerInfos string, host host.Host, protoc
 		if err != nil {
 			return types.Wrap(types.ErrConnectFailed, err)
 		}
-		stream, err = host.NewStream(ctx, pi.ID, protocol)
+		stream, err = NewStream(ctx, host, pi.ID, string(protocol))
 	}
 
 	if err != nil {
 		if isForward {
-			for _, peerId := range host.Peerstore().Peers() {
-				relayStream, err := host.NewStream(ctx, peerId, protocol)
+			// the relays of the peer forward the request, or any peer connected if it has none
+			relays := RelayPeers(peerInfos)
+			if len(relays) == 0 {
+				relays = host.Peerstore().Peers()
+			}
+			for _, peerId := range relays {
+				relayStream, err := NewStream(ctx, host, peerId, string(protocol))
 				if err != nil {
 					log.Warn(types.Wrap(types.ErrCreateStreamFailed, err))
+					continue
 				}
 
 				defer relayStream.Close()
-				log.Debugf("open stream to %s protocol %s.", peerId, protocol)
+				log.Debugf("open stream to %s protocol %s.", peerId, relayStream.Protocol())
 
 				// Set a deadline on reading from the stream so it doesn't hang
 				_ = relayStream.SetReadDeadline(time.Now().Add(300 * time.Second))
@@ -94,7 +87,7 @@ func HandleRequest(ctx context.Context, peerInfos string, host host.Host, protoc
 		return types.Wrap(types.ErrCreateStreamFailed, err)
 	}
 	defer stream.Close()
-	log.Debugf("open stream to %s protocol %s.", peerInfos, protocol)
+	log.Debugf("open stream to %s protocol %s.", peerInfos, stream.Protocol())
 
 	// Set a deadline on reading from the stream so it doesn't hang
 	_ = stream.SetReadDeadline(time.Now().Add(300 * time.Second))
@@ -117,6 +110,14 @@ func HandleRequest(ctx context.Context, peerInfos string, host host.Host, protoc
 }
 
 func DoRequest(ctx context.Context, s network.Stream, req interface{}, resp interface{}, format string) error {
+	if sr, ok := req.(*signedRequest); ok {
+		sealed, err := sr.seal(s)
+		if err != nil {
+			return err
+		}
+		req = sealed
+	}
+
 	errc := make(chan error)
 	go func() {
 		if m, ok := req.(CommonMarshaler); ok {
@@ -95,6 +95,35 @@ func (b *IpfsBackend) Store(ctx context.Context, reader io.Reader) (any, error)
 	return blkSt.Path().Cid().String(), nil
 }
 
+// StoreUnixfs adds the content as a unixfs file of raw leaves split by the chunker, pinned recursively.
+func (b *IpfsBackend) StoreUnixfs(ctx context.Context, reader io.Reader, chunker string) (cid.Cid, error) {
+	p, err := b.api.Unixfs().Add(ctx, files.NewReaderFile(reader),
+		options.Unixfs.Pin(true),
+		options.Unixfs.CidVersion(1),
+		options.Unixfs.RawLeaves(true),
+		options.Unixfs.Chunker(chunker),
+	)
+	if err != nil {
+		return cid.Undef, types.Wrap(types.ErrStoreFailed, err)
+	}
+	log.Debugf("%s store unixfs root: %v", b.Id(), p.Cid())
+	return p.Cid(), nil
+}
+
+// GetUnixfs returns the unixfs file of the root, it is an io.ReadSeeker fetching the blocks as they are read.
+func (b *IpfsBackend) GetUnixfs(ctx context.Context, root cid.Cid) (io.Reader, error) {
+	node, err := b.api.Unixfs().Get(ctx, icorepath.IpfsPath(root))
+	if err != nil {
+		return nil, types.Wrap(types.ErrGetFailed, err)
+	}
+	f, ok := node.(files.File)
+	if !ok {
+		node.Close()
+		return nil, types.Wrapf(types.ErrGetFailed, "%v is not a unixfs file", root)
+	}
+	return f, nil
+}
+
 func (b *IpfsBackend) IsExist(ctx context.Context, cid cid.Cid) (bool, error) {
 	path := icorepath.New(cid.String())
 	s, err := b.api.Block().Stat(ctx, path)
@@ -125,3 +154,34 @@ func (b *IpfsBackend) Remove(ctx context.Context, cid cid.Cid) error {
 	path := icorepath.New(cid.String())
 	return b.api.Pin().Rm(ctx, path)
 }
+
+// Provide announces the cid on the DHT of the ipfs node, the public one unless the node is configured otherwise.
+func (b *IpfsBackend) Provide(ctx context.Context, cid cid.Cid) error {
+	err := b.api.Dht().Provide(ctx, icorepath.IpfsPath(cid))
+	if err != nil {
+		return types.Wrap(types.ErrProvideFailed, err)
+	}
+	return nil
+}
+
+// FreeSpace returns the space left under the StorageMax of a remote ipfs repo. The in process ipfs repo is
+// on the local disk, ok is false for it.
+func (b *IpfsBackend) FreeSpace(ctx context.Context) (uint64, bool, error) {
+	api, ok := b.api.(*httpapi.HttpApi)
+	if !ok {
+		return 0, false, nil
+	}
+
+	var stat struct {
+		RepoSize   uint64
+		StorageMax uint64
+	}
+	err := api.Request("repo/stat").Option("size-only", true).Exec(ctx, &stat)
+	if err != nil {
+		return 0, true, types.Wrap(types.ErrStatFailed, err)
+	}
+	if stat.RepoSize >= stat.StorageMax {
+		return 0, true, nil
+	}
+	return stat.StorageMax - stat.RepoSize, true, nil
+}
@@ -1,16 +1,23 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
 	"sao-node/types"
+	"sao-node/utils/bufpool"
 
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
 )
 
 var log = logging.Logger("store")
 
+var unixfsRootPrefix = datastore.NewKey("/unixfs")
+
 type StoreBackend interface {
 	Id() string
 	Type() string
@@ -22,8 +29,54 @@ type StoreBackend interface {
 	IsExist(ctx context.Context, cid cid.Cid) (bool, error)
 }
 
+// SpaceReporter is implemented by the backends which know how much space is left.
+type SpaceReporter interface {
+	// FreeSpace returns the bytes left, ok is false if the backend can't tell.
+	FreeSpace(ctx context.Context) (free uint64, ok bool, err error)
+}
+
+// Provider is implemented by the backends which can announce the content on the DHT.
+type Provider interface {
+	Provide(ctx context.Context, cid cid.Cid) error
+}
+
+// UnixfsStorer is implemented by the backends which can store the content as a unixfs file, split in blocks by
+// the chunker, an ipfs chunker spec. The root is removed, checked and provided like a block.
+type UnixfsStorer interface {
+	StoreUnixfs(ctx context.Context, reader io.Reader, chunker string) (cid.Cid, error)
+	GetUnixfs(ctx context.Context, root cid.Cid) (io.Reader, error)
+}
+
 type StoreManager struct {
 	backends []StoreBackend
+
+	// unixfs roots of the contents larger than chunkSize by the content cid, nil if the contents are single blocks
+	roots     datastore.Batching
+	chunkSize int64
+	chunker   string
+}
+
+const (
+	ChunkerSize    = "size"
+	ChunkerRabin   = "rabin"
+	ChunkerBuzhash = "buzhash"
+)
+
+// chunkerSpec returns the ipfs chunker spec of the chunker. The fixed size blocks are chunkSize bytes, the content
+// defined ones are cut where the rolling hash of the content matches, so an edit only changes the blocks around
+// it and the other blocks are shared with the previous commits. The rabin blocks average chunkSize bytes, the
+// buzhash blocks are 128KiB to 512KiB.
+func chunkerSpec(chunker string, chunkSize int64) (string, error) {
+	switch chunker {
+	case ChunkerSize, "":
+		return fmt.Sprintf("size-%d", chunkSize), nil
+	case ChunkerRabin:
+		return fmt.Sprintf("rabin-%d-%d-%d", chunkSize/4, chunkSize, chunkSize*4), nil
+	case ChunkerBuzhash:
+		return "buzhash", nil
+	default:
+		return "", types.Wrapf(types.ErrInvalidConfig, "unknown chunker %s", chunker)
+	}
 }
 
 func NewStoreManager(initial []StoreBackend) *StoreManager {
@@ -32,6 +85,23 @@ func NewStoreManager(initial []StoreBackend) *StoreManager {
 	}
 }
 
+// EnableUnixfs stores the contents larger than chunkSize as unixfs files split by the chunker, so they can be
+// read partially, share the identical blocks and be fetched by the ipfs tools from the root. The contents are
+// still addressed by their cid, the roots are kept in ds.
+func (ss *StoreManager) EnableUnixfs(ds datastore.Batching, chunkSize int64, chunker string) error {
+	if chunkSize <= 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid chunk size %d", chunkSize)
+	}
+	spec, err := chunkerSpec(chunker, chunkSize)
+	if err != nil {
+		return err
+	}
+	ss.roots = ds
+	ss.chunkSize = chunkSize
+	ss.chunker = spec
+	return nil
+}
+
 func (ss *StoreManager) AddBackend(backend StoreBackend) {
 	ss.backends = append(ss.backends, backend)
 }
@@ -67,9 +137,27 @@ func (ss *StoreManager) Close() error {
 }
 
 func (ss *StoreManager) Store(ctx context.Context, cid cid.Cid, reader io.Reader) (any, error) {
-	var err error
+	content, release, err := replayable(reader)
+	if err != nil {
+		return nil, types.Wrap(types.ErrStoreFailed, err)
+	}
+	defer release()
+
+	if ss.roots != nil {
+		size, err := content.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, types.Wrap(types.ErrStoreFailed, err)
+		}
+		if size > ss.chunkSize {
+			return nil, ss.storeUnixfs(ctx, cid, content)
+		}
+	}
+
 	for _, back := range ss.backends {
-		_, err = back.Store(ctx, reader)
+		if _, err = content.Seek(0, io.SeekStart); err != nil {
+			return nil, types.Wrap(types.ErrStoreFailed, err)
+		}
+		_, err = back.Store(ctx, content)
 		if err != nil {
 			log.Errorf("%s store error: %v", back.Id(), err)
 		} else {
@@ -79,20 +167,159 @@ func (ss *StoreManager) Store(ctx context.Context, cid cid.Cid, reader io.Reader
 	return nil, err
 }
 
+// contents up to this size are buffered in memory to be read by every backend, the larger ones are spooled to
+// a temporary file
+const spoolThreshold = 1 << 20
+
+// replayable returns the content of reader as a ReadSeeker from its current offset, so every backend reads the
+// whole content. The files and the in-memory readers are used as is, release removes the spooled file if any, or
+// returns the buffer to the pool.
+func replayable(reader io.Reader) (io.ReadSeeker, func(), error) {
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			return &offsetReader{ReadSeeker: seeker, start: start}, func() {}, nil
+		}
+	}
+
+	head := bufpool.Get(spoolThreshold)
+	n, err := io.ReadFull(reader, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return bytes.NewReader(head[:n]), func() { bufpool.Put(head) }, nil
+	} else if err != nil {
+		bufpool.Put(head)
+		return nil, nil, err
+	}
+	defer bufpool.Put(head)
+
+	file, err := os.CreateTemp("", "sao-store-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	if _, err := bufpool.Copy(file, io.MultiReader(bytes.NewReader(head), reader)); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return file, release, nil
+}
+
+// offsetReader is a ReadSeeker starting at the offset start of the underlying one.
+type offsetReader struct {
+	io.ReadSeeker
+	start int64
+}
+
+func (r *offsetReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		offset += r.start
+	}
+	pos, err := r.ReadSeeker.Seek(offset, whence)
+	return pos - r.start, err
+}
+
+// storeUnixfs stores the content as a unixfs file in the backends supporting it, and as a block in the others.
+func (ss *StoreManager) storeUnixfs(ctx context.Context, id cid.Cid, content io.ReadSeeker) error {
+	var root cid.Cid
+	var err error
+	for _, back := range ss.backends {
+		if _, e := content.Seek(0, io.SeekStart); e != nil {
+			return types.Wrap(types.ErrStoreFailed, e)
+		}
+		storer, ok := back.(UnixfsStorer)
+		if !ok {
+			_, err = back.Store(ctx, content)
+			if err != nil {
+				log.Errorf("%s store error: %v", back.Id(), err)
+			}
+			continue
+		}
+		r, e := storer.StoreUnixfs(ctx, content, ss.chunker)
+		if e != nil {
+			log.Errorf("%s store unixfs error: %v", back.Id(), e)
+			err = e
+			continue
+		}
+		root, err = r, nil
+	}
+	if err != nil {
+		return err
+	}
+	if root.Defined() {
+		log.Debugf("%v stored as unixfs %v", id, root)
+		err = ss.roots.Put(ctx, unixfsRootPrefix.ChildString(id.String()), root.Bytes())
+		if err != nil {
+			return types.Wrap(types.ErrStoreFailed, err)
+		}
+	}
+	return nil
+}
+
+// unixfsRoot returns the root of the content stored as a unixfs file, undefined for the blocks.
+func (ss *StoreManager) unixfsRoot(ctx context.Context, id cid.Cid) cid.Cid {
+	if ss.roots == nil {
+		return cid.Undef
+	}
+	data, err := ss.roots.Get(ctx, unixfsRootPrefix.ChildString(id.String()))
+	if err != nil {
+		if err != datastore.ErrNotFound {
+			log.Warnf("get unixfs root of %v error: %v", id, err)
+		}
+		return cid.Undef
+	}
+	root, err := cid.Cast(data)
+	if err != nil {
+		log.Warnf("invalid unixfs root of %v: %v", id, err)
+		return cid.Undef
+	}
+	return root
+}
+
 func (ss *StoreManager) Remove(ctx context.Context, cid cid.Cid) error {
+	id := cid
+	if root := ss.unixfsRoot(ctx, cid); root.Defined() {
+		id = root
+	}
+
 	var err error
 	for _, back := range ss.backends {
-		err = back.Remove(ctx, cid)
+		err = back.Remove(ctx, id)
 		if err != nil {
-			log.Errorf("%s remove cid=%v error: %v", back.Id(), cid, err)
+			log.Errorf("%s remove cid=%v error: %v", back.Id(), id, err)
 		} else {
 			err = nil
 		}
 	}
+	if err == nil && id != cid {
+		err = ss.roots.Delete(ctx, unixfsRootPrefix.ChildString(cid.String()))
+		if err != nil {
+			return types.Wrap(types.ErrRemoveFailed, err)
+		}
+	}
 	return err
 }
 
+// Get returns the content of the cid, the content stored as a unixfs file is returned as an io.ReadSeeker to be
+// read partially.
 func (ss *StoreManager) Get(ctx context.Context, cid cid.Cid) (io.Reader, error) {
+	if root := ss.unixfsRoot(ctx, cid); root.Defined() {
+		for _, back := range ss.backends {
+			storer, ok := back.(UnixfsStorer)
+			if !ok {
+				continue
+			}
+			reader, err := storer.GetUnixfs(ctx, root)
+			if err != nil {
+				log.Errorf("%s get unixfs cid=%v root=%v error: %v", back.Id(), cid, root, err)
+				continue
+			}
+			return reader, nil
+		}
+	}
+
 	for _, back := range ss.backends {
 		reader, err := back.Get(ctx, cid)
 		if err != nil {
@@ -104,7 +331,32 @@ func (ss *StoreManager) Get(ctx context.Context, cid cid.Cid) (io.Reader, error)
 	return nil, types.Wrapf(types.ErrGetFailed, "failed to get cid %s", cid)
 }
 
+// Block returns the raw block of the cid and the cid of the block, the root block for the content stored as a
+// unixfs file.
+func (ss *StoreManager) Block(ctx context.Context, cid cid.Cid) (cid.Cid, []byte, error) {
+	if root := ss.unixfsRoot(ctx, cid); root.Defined() {
+		cid = root
+	}
+	for _, back := range ss.backends {
+		reader, err := back.Get(ctx, cid)
+		if err != nil {
+			log.Errorf("%s get block cid=%v error: %v", back.Id(), cid, err)
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			log.Errorf("%s read block cid=%v error: %v", back.Id(), cid, err)
+			continue
+		}
+		return cid, data, nil
+	}
+	return cid, nil, types.Wrapf(types.ErrGetFailed, "failed to get block %s", cid)
+}
+
 func (ss *StoreManager) IsExist(ctx context.Context, cid cid.Cid) bool {
+	if root := ss.unixfsRoot(ctx, cid); root.Defined() {
+		cid = root
+	}
 	for _, back := range ss.backends {
 		isExist, err := back.IsExist(ctx, cid)
 		if err != nil {
@@ -121,3 +373,44 @@ func (ss *StoreManager) IsExist(ctx context.Context, cid cid.Cid) bool {
 
 	return false
 }
+
+// FreeSpace returns the space left of the backends reporting it, by backend id.
+func (ss *StoreManager) FreeSpace(ctx context.Context) map[string]uint64 {
+	spaces := make(map[string]uint64)
+	for _, back := range ss.backends {
+		reporter, ok := back.(SpaceReporter)
+		if !ok {
+			continue
+		}
+		free, ok, err := reporter.FreeSpace(ctx)
+		if err != nil {
+			log.Warnf("%s free space error: %v", back.Id(), err)
+			continue
+		}
+		if ok {
+			spaces[back.Id()] = free
+		}
+	}
+	return spaces
+}
+
+// Provide announces the cid on the DHT through the backends supporting it, it fails if no backend announced it.
+// The root is announced for the content stored as a unixfs file.
+func (ss *StoreManager) Provide(ctx context.Context, cid cid.Cid) error {
+	if root := ss.unixfsRoot(ctx, cid); root.Defined() {
+		cid = root
+	}
+	err := types.Wrapf(types.ErrProvideFailed, "no backend announces %s", cid)
+	for _, back := range ss.backends {
+		provider, ok := back.(Provider)
+		if !ok {
+			continue
+		}
+		if e := provider.Provide(ctx, cid); e != nil {
+			log.Warnf("%s provide cid=%v error: %v", back.Id(), cid, e)
+			continue
+		}
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sao-node/utils"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+// memBackend keeps the blocks and the unixfs files by cid, a unixfs root is the cid of the content suffixed.
+type memBackend struct {
+	contents map[cid.Cid][]byte
+}
+
+func (b *memBackend) Id() string   { return "mem" }
+func (b *memBackend) Type() string { return "mem" }
+func (b *memBackend) Open() error  { return nil }
+func (b *memBackend) Close() error { return nil }
+func (b *memBackend) IsExist(ctx context.Context, cid cid.Cid) (bool, error) {
+	_, ok := b.contents[cid]
+	return ok, nil
+}
+
+func (b *memBackend) Store(ctx context.Context, reader io.Reader) (any, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	c, err := utils.CalculateCid(data)
+	if err != nil {
+		return nil, err
+	}
+	b.contents[c] = data
+	return c, nil
+}
+
+func (b *memBackend) Remove(ctx context.Context, cid cid.Cid) error {
+	delete(b.contents, cid)
+	return nil
+}
+
+func (b *memBackend) Get(ctx context.Context, cid cid.Cid) (io.Reader, error) {
+	return bytes.NewReader(b.contents[cid]), nil
+}
+
+func (b *memBackend) StoreUnixfs(ctx context.Context, reader io.Reader, chunker string) (cid.Cid, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return cid.Undef, err
+	}
+	root, err := utils.CalculateCid(append(data, []byte("unixfs")...))
+	if err != nil {
+		return cid.Undef, err
+	}
+	b.contents[root] = data
+	return root, nil
+}
+
+func (b *memBackend) GetUnixfs(ctx context.Context, root cid.Cid) (io.Reader, error) {
+	return bytes.NewReader(b.contents[root]), nil
+}
+
+func TestStoreManagerUnixfs(t *testing.T) {
+	ctx := context.Background()
+	backend := &memBackend{contents: make(map[cid.Cid][]byte)}
+	sm := NewStoreManager([]StoreBackend{backend})
+	require.Error(t, sm.EnableUnixfs(dssync.MutexWrap(datastore.NewMapDatastore()), 4, "fastcdc"))
+	require.NoError(t, sm.EnableUnixfs(dssync.MutexWrap(datastore.NewMapDatastore()), 4, ChunkerRabin))
+	require.Equal(t, "rabin-1-4-16", sm.chunker)
+
+	small := []byte("abc")
+	smallCid, err := utils.CalculateCid(small)
+	require.NoError(t, err)
+	_, err = sm.Store(ctx, smallCid, bytes.NewReader(small))
+	require.NoError(t, err)
+	require.Contains(t, backend.contents, smallCid)
+	require.False(t, sm.unixfsRoot(ctx, smallCid).Defined())
+
+	large := []byte("abcdefgh")
+	largeCid, err := utils.CalculateCid(large)
+	require.NoError(t, err)
+	_, err = sm.Store(ctx, largeCid, bytes.NewReader(large))
+	require.NoError(t, err)
+	require.NotContains(t, backend.contents, largeCid)
+	root := sm.unixfsRoot(ctx, largeCid)
+	require.True(t, root.Defined())
+
+	require.True(t, sm.IsExist(ctx, largeCid))
+	reader, err := sm.Get(ctx, largeCid)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, large, data)
+
+	require.NoError(t, sm.Remove(ctx, largeCid))
+	require.NotContains(t, backend.contents, root)
+	require.False(t, sm.unixfsRoot(ctx, largeCid).Defined())
+	require.False(t, sm.IsExist(ctx, largeCid))
+}
+
+func TestStoreManagerReplay(t *testing.T) {
+	ctx := context.Background()
+	first := &memBackend{contents: make(map[cid.Cid][]byte)}
+	second := &memBackend{contents: make(map[cid.Cid][]byte)}
+	sm := NewStoreManager([]StoreBackend{first, second})
+
+	// every backend reads the whole content, the large ones are spooled to a file
+	for _, content := range [][]byte{[]byte("abc"), bytes.Repeat([]byte("a"), spoolThreshold+1)} {
+		contentCid, err := utils.CalculateCid(content)
+		require.NoError(t, err)
+		_, err = sm.Store(ctx, contentCid, io.MultiReader(bytes.NewReader(content)))
+		require.NoError(t, err)
+		require.Equal(t, content, first.contents[contentCid])
+		require.Equal(t, content, second.contents[contentCid])
+
+		streamed, err := utils.CalculateCidFrom(bytes.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, contentCid, streamed)
+	}
+}
@@ -498,7 +498,7 @@ func (t *OrderInfo) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{174}); err != nil {
+	if _, err := cw.Write([]byte{179}); err != nil {
 		return err
 	}
 
@@ -734,6 +734,114 @@ func (t *OrderInfo) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
+	// t.ChainStatus (int64) (int64)
+	if len("ChainStatus") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"ChainStatus\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("ChainStatus"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("ChainStatus")); err != nil {
+		return err
+	}
+
+	if t.ChainStatus >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.ChainStatus)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.ChainStatus-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Priority (types.Priority) (int64)
+	if len("Priority") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Priority\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Priority"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Priority")); err != nil {
+		return err
+	}
+
+	if t.Priority >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Priority)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Priority-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Proposal ([]uint8) (slice)
+	if len("Proposal") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Proposal\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Proposal"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Proposal")); err != nil {
+		return err
+	}
+
+	if len(t.Proposal) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Proposal was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Proposal))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Proposal[:]); err != nil {
+		return err
+	}
+
+	// t.JwsSignature ([]uint8) (slice)
+	if len("JwsSignature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"JwsSignature\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("JwsSignature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("JwsSignature")); err != nil {
+		return err
+	}
+
+	if len(t.JwsSignature) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.JwsSignature was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.JwsSignature))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.JwsSignature[:]); err != nil {
+		return err
+	}
+
+	// t.Republishes (uint64) (uint64)
+	if len("Republishes") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Republishes\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Republishes"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Republishes")); err != nil {
+		return err
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Republishes)); err != nil {
+		return err
+	}
+
 	// t.State (types.OrderState) (uint64)
 	if len("State") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"State\" was too long")
@@ -1016,6 +1124,117 @@ func (t *OrderInfo) UnmarshalCBOR(r io.Reader) (err error) {
 				}
 				t.ExpireHeight = uint64(extra)
 
+			}
+			// t.ChainStatus (int64) (int64)
+		case "ChainStatus":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.ChainStatus = int64(extraI)
+			}
+			// t.Priority (types.Priority) (int64)
+		case "Priority":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.Priority = Priority(extraI)
+			}
+			// t.Proposal ([]uint8) (slice)
+		case "Proposal":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Proposal: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Proposal = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.Proposal[:]); err != nil {
+				return err
+			}
+			// t.JwsSignature ([]uint8) (slice)
+		case "JwsSignature":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.JwsSignature: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.JwsSignature = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.JwsSignature[:]); err != nil {
+				return err
+			}
+			// t.Republishes (uint64) (uint64)
+		case "Republishes":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Republishes = uint64(extra)
+
 			}
 			// t.State (types.OrderState) (uint64)
 		case "State":
@@ -1223,7 +1442,7 @@ func (t *ShardInfo) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{174}); err != nil {
+	if _, err := cw.Write([]byte{175}); err != nil {
 		return err
 	}
 
@@ -1435,6 +1654,28 @@ func (t *ShardInfo) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
+	// t.Priority (types.Priority) (int64)
+	if len("Priority") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Priority\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Priority"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Priority")); err != nil {
+		return err
+	}
+
+	if t.Priority >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Priority)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Priority-1)); err != nil {
+			return err
+		}
+	}
+
 	// t.Tries (uint64) (uint64)
 	if len("Tries") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"Tries\" was too long")
@@ -1681,6 +1922,32 @@ func (t *ShardInfo) UnmarshalCBOR(r io.Reader) (err error) {
 				t.Size = uint64(extra)
 
 			}
+			// t.Priority (types.Priority) (int64)
+		case "Priority":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.Priority = Priority(extraI)
+			}
 			// t.Tries (uint64) (uint64)
 		case "Tries":
 
@@ -2529,7 +2796,7 @@ func (t *MigrateIndex) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *QueryProposal) MarshalCBOR(w io.Writer) error {
+func (t *CompleteRecord) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -2537,184 +2804,306 @@ func (t *QueryProposal) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{168}); err != nil {
+	if _, err := cw.Write([]byte{163}); err != nil {
 		return err
 	}
 
-	// t.Owner (string) (string)
-	if len("Owner") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Owner\" was too long")
+	// t.OrderId (uint64) (uint64)
+	if len("OrderId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"OrderId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Owner"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Owner")); err != nil {
+	if _, err := io.WriteString(w, string("OrderId")); err != nil {
 		return err
 	}
 
-	if len(t.Owner) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Owner was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Owner))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string(t.Owner)); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
 		return err
 	}
 
-	// t.Keyword (string) (string)
-	if len("Keyword") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Keyword\" was too long")
+	// t.TxHash (string) (string)
+	if len("TxHash") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TxHash\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Keyword"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHash"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Keyword")); err != nil {
+	if _, err := io.WriteString(w, string("TxHash")); err != nil {
 		return err
 	}
 
-	if len(t.Keyword) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Keyword was too long")
+	if len(t.TxHash) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.TxHash was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Keyword))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TxHash))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Keyword)); err != nil {
+	if _, err := io.WriteString(w, string(t.TxHash)); err != nil {
 		return err
 	}
 
-	// t.GroupId (string) (string)
-	if len("GroupId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"GroupId\" was too long")
+	// t.HandledAt (int64) (int64)
+	if len("HandledAt") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"HandledAt\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("GroupId"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("HandledAt"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("GroupId")); err != nil {
+	if _, err := io.WriteString(w, string("HandledAt")); err != nil {
 		return err
 	}
 
-	if len(t.GroupId) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.GroupId was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.GroupId))); err != nil {
-		return err
+	if t.HandledAt >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.HandledAt)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.HandledAt-1)); err != nil {
+			return err
+		}
 	}
-	if _, err := io.WriteString(w, string(t.GroupId)); err != nil {
+	return nil
+}
+
+func (t *CompleteRecord) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = CompleteRecord{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
 		return err
 	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
 
-	// t.KeywordType (uint64) (uint64)
-	if len("KeywordType") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"KeywordType\" was too long")
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("KeywordType"))); err != nil {
-		return err
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("CompleteRecord: map struct too large (%d)", extra)
 	}
-	if _, err := io.WriteString(w, string("KeywordType")); err != nil {
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.OrderId (uint64) (uint64)
+		case "OrderId":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.OrderId = uint64(extra)
+
+			}
+			// t.TxHash (string) (string)
+		case "TxHash":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.TxHash = string(sval)
+			}
+			// t.HandledAt (int64) (int64)
+		case "HandledAt":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.HandledAt = int64(extraI)
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
+func (t *AuditInfo) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
 		return err
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.KeywordType)); err != nil {
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{166}); err != nil {
 		return err
 	}
 
-	// t.LastValidHeight (uint64) (uint64)
-	if len("LastValidHeight") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"LastValidHeight\" was too long")
+	// t.DataId (string) (string)
+	if len("DataId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"DataId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("LastValidHeight"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("DataId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("LastValidHeight")); err != nil {
+	if _, err := io.WriteString(w, string("DataId")); err != nil {
 		return err
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.LastValidHeight)); err != nil {
+	if len(t.DataId) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.DataId was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.DataId))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.DataId)); err != nil {
 		return err
 	}
 
-	// t.Gateway (string) (string)
-	if len("Gateway") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Gateway\" was too long")
+	// t.OrderId (uint64) (uint64)
+	if len("OrderId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"OrderId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Gateway"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Gateway")); err != nil {
+	if _, err := io.WriteString(w, string("OrderId")); err != nil {
 		return err
 	}
 
-	if len(t.Gateway) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Gateway was too long")
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
+		return err
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Gateway))); err != nil {
+	// t.Replica (uint64) (uint64)
+	if len("Replica") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Replica\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Replica"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Gateway)); err != nil {
+	if _, err := io.WriteString(w, string("Replica")); err != nil {
 		return err
 	}
 
-	// t.CommitId (string) (string)
-	if len("CommitId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"CommitId\" was too long")
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Replica)); err != nil {
+		return err
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("CommitId"))); err != nil {
+	// t.Results ([]types.AuditResult) (slice)
+	if len("Results") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Results\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Results"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("CommitId")); err != nil {
+	if _, err := io.WriteString(w, string("Results")); err != nil {
 		return err
 	}
 
-	if len(t.CommitId) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.CommitId was too long")
+	if len(t.Results) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Results was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.CommitId))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Results))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.CommitId)); err != nil {
-		return err
+	for _, v := range t.Results {
+		if err := v.MarshalCBOR(cw); err != nil {
+			return err
+		}
 	}
 
-	// t.Version (string) (string)
-	if len("Version") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Version\" was too long")
+	// t.UnderReplicated (bool) (bool)
+	if len("UnderReplicated") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"UnderReplicated\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Version"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("UnderReplicated"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Version")); err != nil {
+	if _, err := io.WriteString(w, string("UnderReplicated")); err != nil {
 		return err
 	}
 
-	if len(t.Version) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Version was too long")
+	if err := cbg.WriteBool(w, t.UnderReplicated); err != nil {
+		return err
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Version))); err != nil {
+	// t.AuditedAt (int64) (int64)
+	if len("AuditedAt") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"AuditedAt\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("AuditedAt"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Version)); err != nil {
+	if _, err := io.WriteString(w, string("AuditedAt")); err != nil {
 		return err
 	}
+
+	if t.AuditedAt >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.AuditedAt)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.AuditedAt-1)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = QueryProposal{}
+func (t *AuditInfo) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = AuditInfo{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -2733,7 +3122,7 @@ func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("QueryProposal: map struct too large (%d)", extra)
+		return fmt.Errorf("AuditInfo: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -2751,30 +3140,8 @@ func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
-		// t.Owner (string) (string)
-		case "Owner":
-
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
-
-				t.Owner = string(sval)
-			}
-			// t.Keyword (string) (string)
-		case "Keyword":
-
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
-
-				t.Keyword = string(sval)
-			}
-			// t.GroupId (string) (string)
-		case "GroupId":
+		// t.DataId (string) (string)
+		case "DataId":
 
 			{
 				sval, err := cbg.ReadString(cr)
@@ -2782,10 +3149,10 @@ func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
 					return err
 				}
 
-				t.GroupId = string(sval)
+				t.DataId = string(sval)
 			}
-			// t.KeywordType (uint64) (uint64)
-		case "KeywordType":
+			// t.OrderId (uint64) (uint64)
+		case "OrderId":
 
 			{
 
@@ -2796,11 +3163,11 @@ func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
 				if maj != cbg.MajUnsignedInt {
 					return fmt.Errorf("wrong type for uint64 field")
 				}
-				t.KeywordType = uint64(extra)
+				t.OrderId = uint64(extra)
 
 			}
-			// t.LastValidHeight (uint64) (uint64)
-		case "LastValidHeight":
+			// t.Replica (uint64) (uint64)
+		case "Replica":
 
 			{
 
@@ -2811,41 +3178,82 @@ func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
 				if maj != cbg.MajUnsignedInt {
 					return fmt.Errorf("wrong type for uint64 field")
 				}
-				t.LastValidHeight = uint64(extra)
+				t.Replica = uint64(extra)
 
 			}
-			// t.Gateway (string) (string)
-		case "Gateway":
+			// t.Results ([]types.AuditResult) (slice)
+		case "Results":
 
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
 
-				t.Gateway = string(sval)
+			if extra > cbg.MaxLength {
+				return fmt.Errorf("t.Results: array too large (%d)", extra)
 			}
-			// t.CommitId (string) (string)
-		case "CommitId":
 
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
+			if maj != cbg.MajArray {
+				return fmt.Errorf("expected cbor array")
+			}
 
-				t.CommitId = string(sval)
+			if extra > 0 {
+				t.Results = make([]AuditResult, extra)
 			}
-			// t.Version (string) (string)
-		case "Version":
 
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
+			for i := 0; i < int(extra); i++ {
+
+				var v AuditResult
+				if err := v.UnmarshalCBOR(cr); err != nil {
 					return err
 				}
 
-				t.Version = string(sval)
+				t.Results[i] = v
+			}
+
+			// t.UnderReplicated (bool) (bool)
+		case "UnderReplicated":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.UnderReplicated = false
+			case 21:
+				t.UnderReplicated = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.AuditedAt (int64) (int64)
+		case "AuditedAt":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.AuditedAt = int64(extraI)
 			}
 
 		default:
@@ -2856,7 +3264,7 @@ func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *RelayProposal) MarshalCBOR(w io.Writer) error {
+func (t *AuditResult) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -2868,102 +3276,95 @@ func (t *RelayProposal) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	// t.NodeAddress (string) (string)
-	if len("NodeAddress") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"NodeAddress\" was too long")
+	// t.Provider (string) (string)
+	if len("Provider") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Provider\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("NodeAddress"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Provider"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("NodeAddress")); err != nil {
+	if _, err := io.WriteString(w, string("Provider")); err != nil {
 		return err
 	}
 
-	if len(t.NodeAddress) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.NodeAddress was too long")
+	if len(t.Provider) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Provider was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.NodeAddress))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Provider))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.NodeAddress)); err != nil {
+	if _, err := io.WriteString(w, string(t.Provider)); err != nil {
 		return err
 	}
 
-	// t.LocalPeerId (string) (string)
-	if len("LocalPeerId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"LocalPeerId\" was too long")
+	// t.Cid (string) (string)
+	if len("Cid") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Cid\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("LocalPeerId"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cid"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("LocalPeerId")); err != nil {
+	if _, err := io.WriteString(w, string("Cid")); err != nil {
 		return err
 	}
 
-	if len(t.LocalPeerId) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.LocalPeerId was too long")
+	if len(t.Cid) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Cid was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.LocalPeerId))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Cid))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.LocalPeerId)); err != nil {
+	if _, err := io.WriteString(w, string(t.Cid)); err != nil {
 		return err
 	}
 
-	// t.RelayPeerIds (string) (string)
-	if len("RelayPeerIds") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"RelayPeerIds\" was too long")
+	// t.Passed (bool) (bool)
+	if len("Passed") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Passed\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RelayPeerIds"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Passed"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("RelayPeerIds")); err != nil {
+	if _, err := io.WriteString(w, string("Passed")); err != nil {
 		return err
 	}
 
-	if len(t.RelayPeerIds) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.RelayPeerIds was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.RelayPeerIds))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string(t.RelayPeerIds)); err != nil {
+	if err := cbg.WriteBool(w, t.Passed); err != nil {
 		return err
 	}
 
-	// t.TargetPeerInfo (string) (string)
-	if len("TargetPeerInfo") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"TargetPeerInfo\" was too long")
+	// t.Reason (string) (string)
+	if len("Reason") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Reason\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TargetPeerInfo"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Reason"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("TargetPeerInfo")); err != nil {
+	if _, err := io.WriteString(w, string("Reason")); err != nil {
 		return err
 	}
 
-	if len(t.TargetPeerInfo) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.TargetPeerInfo was too long")
+	if len(t.Reason) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Reason was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TargetPeerInfo))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Reason))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.TargetPeerInfo)); err != nil {
+	if _, err := io.WriteString(w, string(t.Reason)); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = RelayProposal{}
+func (t *AuditResult) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = AuditResult{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -2982,7 +3383,7 @@ func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("RelayProposal: map struct too large (%d)", extra)
+		return fmt.Errorf("AuditResult: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -3000,8 +3401,8 @@ func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
-		// t.NodeAddress (string) (string)
-		case "NodeAddress":
+		// t.Provider (string) (string)
+		case "Provider":
 
 			{
 				sval, err := cbg.ReadString(cr)
@@ -3009,10 +3410,10 @@ func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
 					return err
 				}
 
-				t.NodeAddress = string(sval)
+				t.Provider = string(sval)
 			}
-			// t.LocalPeerId (string) (string)
-		case "LocalPeerId":
+			// t.Cid (string) (string)
+		case "Cid":
 
 			{
 				sval, err := cbg.ReadString(cr)
@@ -3020,21 +3421,28 @@ func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
 					return err
 				}
 
-				t.LocalPeerId = string(sval)
+				t.Cid = string(sval)
 			}
-			// t.RelayPeerIds (string) (string)
-		case "RelayPeerIds":
-
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
+			// t.Passed (bool) (bool)
+		case "Passed":
 
-				t.RelayPeerIds = string(sval)
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
 			}
-			// t.TargetPeerInfo (string) (string)
-		case "TargetPeerInfo":
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
+			}
+			switch extra {
+			case 20:
+				t.Passed = false
+			case 21:
+				t.Passed = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+			}
+			// t.Reason (string) (string)
+		case "Reason":
 
 			{
 				sval, err := cbg.ReadString(cr)
@@ -3042,7 +3450,7 @@ func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
 					return err
 				}
 
-				t.TargetPeerInfo = string(sval)
+				t.Reason = string(sval)
 			}
 
 		default:
@@ -3053,7 +3461,7 @@ func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *JwsSignature) MarshalCBOR(w io.Writer) error {
+func (t *QueryProposal) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -3061,117 +3469,1433 @@ func (t *JwsSignature) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{162}); err != nil {
+	if _, err := cw.Write([]byte{168}); err != nil {
 		return err
 	}
 
-	// t.Protected (string) (string)
-	if len("Protected") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Protected\" was too long")
+	// t.Owner (string) (string)
+	if len("Owner") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Owner\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Protected"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Owner"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Protected")); err != nil {
+	if _, err := io.WriteString(w, string("Owner")); err != nil {
 		return err
 	}
 
-	if len(t.Protected) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Protected was too long")
+	if len(t.Owner) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Owner was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Protected))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Owner))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Protected)); err != nil {
+	if _, err := io.WriteString(w, string(t.Owner)); err != nil {
 		return err
 	}
 
-	// t.Signature (string) (string)
-	if len("Signature") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Signature\" was too long")
+	// t.Keyword (string) (string)
+	if len("Keyword") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Keyword\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Signature"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Keyword"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Signature")); err != nil {
+	if _, err := io.WriteString(w, string("Keyword")); err != nil {
 		return err
 	}
 
-	if len(t.Signature) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Signature was too long")
+	if len(t.Keyword) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Keyword was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Signature))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Keyword))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Signature)); err != nil {
+	if _, err := io.WriteString(w, string(t.Keyword)); err != nil {
 		return err
 	}
-	return nil
-}
-
-func (t *JwsSignature) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = JwsSignature{}
 
-	cr := cbg.NewCborReader(r)
+	// t.GroupId (string) (string)
+	if len("GroupId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"GroupId\" was too long")
+	}
 
-	maj, extra, err := cr.ReadHeader()
-	if err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("GroupId"))); err != nil {
 		return err
 	}
-	defer func() {
-		if err == io.EOF {
-			err = io.ErrUnexpectedEOF
-		}
-	}()
-
-	if maj != cbg.MajMap {
-		return fmt.Errorf("cbor input should be of type map")
+	if _, err := io.WriteString(w, string("GroupId")); err != nil {
+		return err
 	}
 
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("JwsSignature: map struct too large (%d)", extra)
+	if len(t.GroupId) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.GroupId was too long")
 	}
 
-	var name string
-	n := extra
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.GroupId))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.GroupId)); err != nil {
+		return err
+	}
 
-	for i := uint64(0); i < n; i++ {
+	// t.KeywordType (uint64) (uint64)
+	if len("KeywordType") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"KeywordType\" was too long")
+	}
 
-		{
-			sval, err := cbg.ReadString(cr)
-			if err != nil {
-				return err
-			}
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("KeywordType"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("KeywordType")); err != nil {
+		return err
+	}
 
-			name = string(sval)
-		}
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.KeywordType)); err != nil {
+		return err
+	}
 
-		switch name {
-		// t.Protected (string) (string)
-		case "Protected":
+	// t.LastValidHeight (uint64) (uint64)
+	if len("LastValidHeight") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"LastValidHeight\" was too long")
+	}
 
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("LastValidHeight"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("LastValidHeight")); err != nil {
+		return err
+	}
 
-				t.Protected = string(sval)
-			}
-			// t.Signature (string) (string)
-		case "Signature":
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.LastValidHeight)); err != nil {
+		return err
+	}
 
+	// t.Gateway (string) (string)
+	if len("Gateway") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Gateway\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Gateway"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Gateway")); err != nil {
+		return err
+	}
+
+	if len(t.Gateway) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Gateway was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Gateway))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Gateway)); err != nil {
+		return err
+	}
+
+	// t.CommitId (string) (string)
+	if len("CommitId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"CommitId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("CommitId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("CommitId")); err != nil {
+		return err
+	}
+
+	if len(t.CommitId) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.CommitId was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.CommitId))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.CommitId)); err != nil {
+		return err
+	}
+
+	// t.Version (string) (string)
+	if len("Version") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Version\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Version"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Version")); err != nil {
+		return err
+	}
+
+	if len(t.Version) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Version was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Version))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Version)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *QueryProposal) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = QueryProposal{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("QueryProposal: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.Owner (string) (string)
+		case "Owner":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Owner = string(sval)
+			}
+			// t.Keyword (string) (string)
+		case "Keyword":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Keyword = string(sval)
+			}
+			// t.GroupId (string) (string)
+		case "GroupId":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.GroupId = string(sval)
+			}
+			// t.KeywordType (uint64) (uint64)
+		case "KeywordType":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.KeywordType = uint64(extra)
+
+			}
+			// t.LastValidHeight (uint64) (uint64)
+		case "LastValidHeight":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.LastValidHeight = uint64(extra)
+
+			}
+			// t.Gateway (string) (string)
+		case "Gateway":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Gateway = string(sval)
+			}
+			// t.CommitId (string) (string)
+		case "CommitId":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.CommitId = string(sval)
+			}
+			// t.Version (string) (string)
+		case "Version":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Version = string(sval)
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
+func (t *RelayProposal) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{164}); err != nil {
+		return err
+	}
+
+	// t.NodeAddress (string) (string)
+	if len("NodeAddress") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"NodeAddress\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("NodeAddress"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("NodeAddress")); err != nil {
+		return err
+	}
+
+	if len(t.NodeAddress) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.NodeAddress was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.NodeAddress))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.NodeAddress)); err != nil {
+		return err
+	}
+
+	// t.LocalPeerId (string) (string)
+	if len("LocalPeerId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"LocalPeerId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("LocalPeerId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("LocalPeerId")); err != nil {
+		return err
+	}
+
+	if len(t.LocalPeerId) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.LocalPeerId was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.LocalPeerId))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.LocalPeerId)); err != nil {
+		return err
+	}
+
+	// t.RelayPeerIds (string) (string)
+	if len("RelayPeerIds") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RelayPeerIds\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RelayPeerIds"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("RelayPeerIds")); err != nil {
+		return err
+	}
+
+	if len(t.RelayPeerIds) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.RelayPeerIds was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.RelayPeerIds))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.RelayPeerIds)); err != nil {
+		return err
+	}
+
+	// t.TargetPeerInfo (string) (string)
+	if len("TargetPeerInfo") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TargetPeerInfo\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TargetPeerInfo"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("TargetPeerInfo")); err != nil {
+		return err
+	}
+
+	if len(t.TargetPeerInfo) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.TargetPeerInfo was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TargetPeerInfo))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.TargetPeerInfo)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *RelayProposal) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = RelayProposal{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("RelayProposal: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.NodeAddress (string) (string)
+		case "NodeAddress":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.NodeAddress = string(sval)
+			}
+			// t.LocalPeerId (string) (string)
+		case "LocalPeerId":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.LocalPeerId = string(sval)
+			}
+			// t.RelayPeerIds (string) (string)
+		case "RelayPeerIds":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.RelayPeerIds = string(sval)
+			}
+			// t.TargetPeerInfo (string) (string)
+		case "TargetPeerInfo":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.TargetPeerInfo = string(sval)
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
+func (t *JwsSignature) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{162}); err != nil {
+		return err
+	}
+
+	// t.Protected (string) (string)
+	if len("Protected") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Protected\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Protected"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Protected")); err != nil {
+		return err
+	}
+
+	if len(t.Protected) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Protected was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Protected))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Protected)); err != nil {
+		return err
+	}
+
+	// t.Signature (string) (string)
+	if len("Signature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Signature\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Signature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Signature")); err != nil {
+		return err
+	}
+
+	if len(t.Signature) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Signature was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Signature))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Signature)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *JwsSignature) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = JwsSignature{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("JwsSignature: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.Protected (string) (string)
+		case "Protected":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Protected = string(sval)
+			}
+			// t.Signature (string) (string)
+		case "Signature":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Signature = string(sval)
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
+func (t *MetadataProposalCbor) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{162}); err != nil {
+		return err
+	}
+
+	// t.Proposal (types.QueryProposal) (struct)
+	if len("Proposal") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Proposal\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Proposal"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Proposal")); err != nil {
+		return err
+	}
+
+	if err := t.Proposal.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.JwsSignature (types.JwsSignature) (struct)
+	if len("JwsSignature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"JwsSignature\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("JwsSignature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("JwsSignature")); err != nil {
+		return err
+	}
+
+	if err := t.JwsSignature.MarshalCBOR(cw); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *MetadataProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = MetadataProposalCbor{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("MetadataProposalCbor: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.Proposal (types.QueryProposal) (struct)
+		case "Proposal":
+
+			{
+
+				if err := t.Proposal.UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Proposal: %w", err)
+				}
+
+			}
+			// t.JwsSignature (types.JwsSignature) (struct)
+		case "JwsSignature":
+
+			{
+
+				if err := t.JwsSignature.UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.JwsSignature: %w", err)
+				}
+
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
+func (t *RelayProposalCbor) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{162}); err != nil {
+		return err
+	}
+
+	// t.Proposal (types.RelayProposal) (struct)
+	if len("Proposal") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Proposal\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Proposal"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Proposal")); err != nil {
+		return err
+	}
+
+	if err := t.Proposal.MarshalCBOR(cw); err != nil {
+		return err
+	}
+
+	// t.Signature ([]uint8) (slice)
+	if len("Signature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Signature\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Signature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Signature")); err != nil {
+		return err
+	}
+
+	if len(t.Signature) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Signature was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Signature))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Signature[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *RelayProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = RelayProposalCbor{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("RelayProposalCbor: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.Proposal (types.RelayProposal) (struct)
+		case "Proposal":
+
+			{
+
+				if err := t.Proposal.UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Proposal: %w", err)
+				}
+
+			}
+			// t.Signature ([]uint8) (slice)
+		case "Signature":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Signature: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Signature = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.Signature[:]); err != nil {
+				return err
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
+func (t *ShardAssignReq) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{168}); err != nil {
+		return err
+	}
+
+	// t.OrderId (uint64) (uint64)
+	if len("OrderId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"OrderId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("OrderId")); err != nil {
+		return err
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
+		return err
+	}
+
+	// t.DataId (string) (string)
+	if len("DataId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"DataId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("DataId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("DataId")); err != nil {
+		return err
+	}
+
+	if len(t.DataId) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.DataId was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.DataId))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.DataId)); err != nil {
+		return err
+	}
+
+	// t.Assignee (string) (string)
+	if len("Assignee") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Assignee\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Assignee"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Assignee")); err != nil {
+		return err
+	}
+
+	if len(t.Assignee) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Assignee was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Assignee))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Assignee)); err != nil {
+		return err
+	}
+
+	// t.TxHash (string) (string)
+	if len("TxHash") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TxHash\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHash"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("TxHash")); err != nil {
+		return err
+	}
+
+	if len(t.TxHash) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.TxHash was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TxHash))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.TxHash)); err != nil {
+		return err
+	}
+
+	// t.Height (int64) (int64)
+	if len("Height") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Height\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Height"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Height")); err != nil {
+		return err
+	}
+
+	if t.Height >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Height)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Height-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.AssignTxType (types.AssignTxType) (string)
+	if len("AssignTxType") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"AssignTxType\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("AssignTxType"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("AssignTxType")); err != nil {
+		return err
+	}
+
+	if len(t.AssignTxType) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.AssignTxType was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.AssignTxType))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.AssignTxType)); err != nil {
+		return err
+	}
+
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
+		return err
+	}
+
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.Priority (types.Priority) (int64)
+	if len("Priority") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Priority\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Priority"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Priority")); err != nil {
+		return err
+	}
+
+	if t.Priority >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Priority)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Priority-1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ShardAssignReq) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardAssignReq{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("ShardAssignReq: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.OrderId (uint64) (uint64)
+		case "OrderId":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.OrderId = uint64(extra)
+
+			}
+			// t.DataId (string) (string)
+		case "DataId":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.DataId = string(sval)
+			}
+			// t.Assignee (string) (string)
+		case "Assignee":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Assignee = string(sval)
+			}
+			// t.TxHash (string) (string)
+		case "TxHash":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.TxHash = string(sval)
+			}
+			// t.Height (int64) (int64)
+		case "Height":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.Height = int64(extraI)
+			}
+			// t.AssignTxType (types.AssignTxType) (string)
+		case "AssignTxType":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.AssignTxType = AssignTxType(sval)
+			}
+			// t.RequestId (int64) (int64)
+		case "RequestId":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.RequestId = int64(extraI)
+			}
+			// t.Priority (types.Priority) (int64)
+		case "Priority":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.Priority = Priority(extraI)
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
+func (t *ShardAssignResp) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{163}); err != nil {
+		return err
+	}
+
+	// t.Code (uint64) (uint64)
+	if len("Code") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Code\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Code"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Code")); err != nil {
+		return err
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Code)); err != nil {
+		return err
+	}
+
+	// t.Message (string) (string)
+	if len("Message") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Message\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Message"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Message")); err != nil {
+		return err
+	}
+
+	if len(t.Message) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Message was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Message))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Message)); err != nil {
+		return err
+	}
+
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
+		return err
+	}
+
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ShardAssignResp) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardAssignResp{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("ShardAssignResp: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.Code (uint64) (uint64)
+		case "Code":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Code = uint64(extra)
+
+			}
+			// t.Message (string) (string)
+		case "Message":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Message = string(sval)
+			}
+			// t.RequestId (int64) (int64)
+		case "RequestId":
 			{
-				sval, err := cbg.ReadString(cr)
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
 				if err != nil {
 					return err
 				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
 
-				t.Signature = string(sval)
+				t.RequestId = int64(extraI)
 			}
 
 		default:
@@ -3182,7 +4906,7 @@ func (t *JwsSignature) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *MetadataProposalCbor) MarshalCBOR(w io.Writer) error {
+func (t *ShardCompleteReq) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -3190,46 +4914,145 @@ func (t *MetadataProposalCbor) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{162}); err != nil {
+	if _, err := cw.Write([]byte{166}); err != nil {
 		return err
 	}
 
-	// t.Proposal (types.QueryProposal) (struct)
-	if len("Proposal") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Proposal\" was too long")
+	// t.OrderId (uint64) (uint64)
+	if len("OrderId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"OrderId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Proposal"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Proposal")); err != nil {
+	if _, err := io.WriteString(w, string("OrderId")); err != nil {
 		return err
 	}
 
-	if err := t.Proposal.MarshalCBOR(cw); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
 		return err
 	}
 
-	// t.JwsSignature (types.JwsSignature) (struct)
-	if len("JwsSignature") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"JwsSignature\" was too long")
+	// t.DataId (string) (string)
+	if len("DataId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"DataId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("JwsSignature"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("DataId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("JwsSignature")); err != nil {
+	if _, err := io.WriteString(w, string("DataId")); err != nil {
 		return err
 	}
 
-	if err := t.JwsSignature.MarshalCBOR(cw); err != nil {
+	if len(t.DataId) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.DataId was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.DataId))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.DataId)); err != nil {
+		return err
+	}
+
+	// t.Cids ([]cid.Cid) (slice)
+	if len("Cids") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Cids\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cids"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Cids")); err != nil {
+		return err
+	}
+
+	if len(t.Cids) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Cids was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Cids))); err != nil {
+		return err
+	}
+	for _, v := range t.Cids {
+		if err := cbg.WriteCid(w, v); err != nil {
+			return xerrors.Errorf("failed writing cid field t.Cids: %w", err)
+		}
+	}
+
+	// t.TxHash (string) (string)
+	if len("TxHash") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TxHash\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHash"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("TxHash")); err != nil {
+		return err
+	}
+
+	if len(t.TxHash) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.TxHash was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TxHash))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.TxHash)); err != nil {
+		return err
+	}
+
+	// t.Height (int64) (int64)
+	if len("Height") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Height\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Height"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Height")); err != nil {
+		return err
+	}
+
+	if t.Height >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Height)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Height-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
 		return err
 	}
+
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (t *MetadataProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = MetadataProposalCbor{}
+func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardCompleteReq{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -3248,7 +5071,7 @@ func (t *MetadataProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("MetadataProposalCbor: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardCompleteReq: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -3266,25 +5089,123 @@ func (t *MetadataProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
-		// t.Proposal (types.QueryProposal) (struct)
-		case "Proposal":
+		// t.OrderId (uint64) (uint64)
+		case "OrderId":
 
 			{
 
-				if err := t.Proposal.UnmarshalCBOR(cr); err != nil {
-					return xerrors.Errorf("unmarshaling t.Proposal: %w", err)
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
 				}
+				t.OrderId = uint64(extra)
 
 			}
-			// t.JwsSignature (types.JwsSignature) (struct)
-		case "JwsSignature":
+			// t.DataId (string) (string)
+		case "DataId":
 
 			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
 
-				if err := t.JwsSignature.UnmarshalCBOR(cr); err != nil {
-					return xerrors.Errorf("unmarshaling t.JwsSignature: %w", err)
+				t.DataId = string(sval)
+			}
+			// t.Cids ([]cid.Cid) (slice)
+		case "Cids":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.MaxLength {
+				return fmt.Errorf("t.Cids: array too large (%d)", extra)
+			}
+
+			if maj != cbg.MajArray {
+				return fmt.Errorf("expected cbor array")
+			}
+
+			if extra > 0 {
+				t.Cids = make([]cid.Cid, extra)
+			}
+
+			for i := 0; i < int(extra); i++ {
+
+				c, err := cbg.ReadCid(cr)
+				if err != nil {
+					return xerrors.Errorf("reading cid field t.Cids failed: %w", err)
+				}
+				t.Cids[i] = c
+			}
+
+			// t.TxHash (string) (string)
+		case "TxHash":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.TxHash = string(sval)
+			}
+			// t.Height (int64) (int64)
+		case "Height":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.Height = int64(extraI)
+			}
+			// t.RequestId (int64) (int64)
+		case "RequestId":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
+				t.RequestId = int64(extraI)
 			}
 
 		default:
@@ -3295,7 +5216,7 @@ func (t *MetadataProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *RelayProposalCbor) MarshalCBOR(w io.Writer) error {
+func (t *ShardCompleteResp) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -3303,54 +5224,91 @@ func (t *RelayProposalCbor) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{162}); err != nil {
+	if _, err := cw.Write([]byte{164}); err != nil {
 		return err
 	}
 
-	// t.Proposal (types.RelayProposal) (struct)
-	if len("Proposal") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Proposal\" was too long")
+	// t.Code (uint64) (uint64)
+	if len("Code") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Code\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Proposal"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Code"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Proposal")); err != nil {
+	if _, err := io.WriteString(w, string("Code")); err != nil {
 		return err
 	}
 
-	if err := t.Proposal.MarshalCBOR(cw); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Code)); err != nil {
 		return err
 	}
 
-	// t.Signature ([]uint8) (slice)
-	if len("Signature") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Signature\" was too long")
+	// t.Message (string) (string)
+	if len("Message") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Message\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Signature"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Message"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Signature")); err != nil {
+	if _, err := io.WriteString(w, string("Message")); err != nil {
 		return err
 	}
 
-	if len(t.Signature) > cbg.ByteArrayMaxLen {
-		return xerrors.Errorf("Byte array in field t.Signature was too long")
+	if len(t.Message) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Message was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Signature))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Message))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Message)); err != nil {
 		return err
 	}
 
-	if _, err := cw.Write(t.Signature[:]); err != nil {
+	// t.Recoverable (bool) (bool)
+	if len("Recoverable") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Recoverable\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Recoverable"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Recoverable")); err != nil {
+		return err
+	}
+
+	if err := cbg.WriteBool(w, t.Recoverable); err != nil {
+		return err
+	}
+
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
 		return err
 	}
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
+		return err
+	}
+
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (t *RelayProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = RelayProposalCbor{}
+func (t *ShardCompleteResp) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardCompleteResp{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -3369,7 +5327,7 @@ func (t *RelayProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("RelayProposalCbor: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardCompleteResp: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -3387,37 +5345,75 @@ func (t *RelayProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
-		// t.Proposal (types.RelayProposal) (struct)
-		case "Proposal":
+		// t.Code (uint64) (uint64)
+		case "Code":
 
 			{
 
-				if err := t.Proposal.UnmarshalCBOR(cr); err != nil {
-					return xerrors.Errorf("unmarshaling t.Proposal: %w", err)
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
 				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Code = uint64(extra)
 
 			}
-			// t.Signature ([]uint8) (slice)
-		case "Signature":
+			// t.Message (string) (string)
+		case "Message":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Message = string(sval)
+			}
+			// t.Recoverable (bool) (bool)
+		case "Recoverable":
 
 			maj, extra, err = cr.ReadHeader()
 			if err != nil {
 				return err
 			}
-
-			if extra > cbg.ByteArrayMaxLen {
-				return fmt.Errorf("t.Signature: byte array too large (%d)", extra)
-			}
-			if maj != cbg.MajByteString {
-				return fmt.Errorf("expected byte array")
+			if maj != cbg.MajOther {
+				return fmt.Errorf("booleans must be major type 7")
 			}
-
-			if extra > 0 {
-				t.Signature = make([]uint8, extra)
+			switch extra {
+			case 20:
+				t.Recoverable = false
+			case 21:
+				t.Recoverable = true
+			default:
+				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
 			}
+			// t.RequestId (int64) (int64)
+		case "RequestId":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
 
-			if _, err := io.ReadFull(cr, t.Signature[:]); err != nil {
-				return err
+				t.RequestId = int64(extraI)
 			}
 
 		default:
@@ -3428,7 +5424,7 @@ func (t *RelayProposalCbor) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *ShardAssignReq) MarshalCBOR(w io.Writer) error {
+func (t *ShardLoadReq) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -3440,140 +5436,119 @@ func (t *ShardAssignReq) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	// t.OrderId (uint64) (uint64)
-	if len("OrderId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"OrderId\" was too long")
+	// t.Owner (string) (string)
+	if len("Owner") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Owner\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("OrderId")); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Owner"))); err != nil {
 		return err
 	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
+	if _, err := io.WriteString(w, string("Owner")); err != nil {
 		return err
 	}
 
-	// t.DataId (string) (string)
-	if len("DataId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"DataId\" was too long")
+	if len(t.Owner) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Owner was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("DataId"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Owner))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("DataId")); err != nil {
+	if _, err := io.WriteString(w, string(t.Owner)); err != nil {
 		return err
 	}
 
-	if len(t.DataId) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.DataId was too long")
+	// t.OrderId (uint64) (uint64)
+	if len("OrderId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"OrderId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.DataId))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.DataId)); err != nil {
+	if _, err := io.WriteString(w, string("OrderId")); err != nil {
 		return err
 	}
 
-	// t.Assignee (string) (string)
-	if len("Assignee") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Assignee\" was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Assignee"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("Assignee")); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
 		return err
 	}
 
-	if len(t.Assignee) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Assignee was too long")
+	// t.Cid (cid.Cid) (struct)
+	if len("Cid") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Cid\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Assignee))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cid"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Assignee)); err != nil {
+	if _, err := io.WriteString(w, string("Cid")); err != nil {
 		return err
 	}
 
-	// t.TxHash (string) (string)
-	if len("TxHash") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"TxHash\" was too long")
+	if err := cbg.WriteCid(cw, t.Cid); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Cid: %w", err)
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHash"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("TxHash")); err != nil {
-		return err
+	// t.Proposal (types.MetadataProposalCbor) (struct)
+	if len("Proposal") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Proposal\" was too long")
 	}
 
-	if len(t.TxHash) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.TxHash was too long")
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Proposal"))); err != nil {
+		return err
 	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TxHash))); err != nil {
+	if _, err := io.WriteString(w, string("Proposal")); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.TxHash)); err != nil {
+
+	if err := t.Proposal.MarshalCBOR(cw); err != nil {
 		return err
 	}
 
-	// t.Height (int64) (int64)
-	if len("Height") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Height\" was too long")
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Height"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Height")); err != nil {
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
 		return err
 	}
-
-	if t.Height >= 0 {
-		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Height)); err != nil {
+
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
 			return err
 		}
 	} else {
-		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Height-1)); err != nil {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
 			return err
 		}
 	}
 
-	// t.AssignTxType (types.AssignTxType) (string)
-	if len("AssignTxType") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"AssignTxType\" was too long")
+	// t.RelayProposal (types.RelayProposalCbor) (struct)
+	if len("RelayProposal") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RelayProposal\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("AssignTxType"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RelayProposal"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("AssignTxType")); err != nil {
+	if _, err := io.WriteString(w, string("RelayProposal")); err != nil {
 		return err
 	}
 
-	if len(t.AssignTxType) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.AssignTxType was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.AssignTxType))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string(t.AssignTxType)); err != nil {
+	if err := t.RelayProposal.MarshalCBOR(cw); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *ShardAssignReq) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardAssignReq{}
+func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardLoadReq{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -3592,7 +5567,7 @@ func (t *ShardAssignReq) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardAssignReq: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardLoadReq: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -3610,7 +5585,18 @@ func (t *ShardAssignReq) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
-		// t.OrderId (uint64) (uint64)
+		// t.Owner (string) (string)
+		case "Owner":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Owner = string(sval)
+			}
+			// t.OrderId (uint64) (uint64)
 		case "OrderId":
 
 			{
@@ -3625,41 +5611,31 @@ func (t *ShardAssignReq) UnmarshalCBOR(r io.Reader) (err error) {
 				t.OrderId = uint64(extra)
 
 			}
-			// t.DataId (string) (string)
-		case "DataId":
+			// t.Cid (cid.Cid) (struct)
+		case "Cid":
 
 			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
-
-				t.DataId = string(sval)
-			}
-			// t.Assignee (string) (string)
-		case "Assignee":
 
-			{
-				sval, err := cbg.ReadString(cr)
+				c, err := cbg.ReadCid(cr)
 				if err != nil {
-					return err
+					return xerrors.Errorf("failed to read cid field t.Cid: %w", err)
 				}
 
-				t.Assignee = string(sval)
+				t.Cid = c
+
 			}
-			// t.TxHash (string) (string)
-		case "TxHash":
+			// t.Proposal (types.MetadataProposalCbor) (struct)
+		case "Proposal":
 
 			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
+
+				if err := t.Proposal.UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.Proposal: %w", err)
 				}
 
-				t.TxHash = string(sval)
 			}
-			// t.Height (int64) (int64)
-		case "Height":
+			// t.RequestId (int64) (int64)
+		case "RequestId":
 			{
 				maj, extra, err := cr.ReadHeader()
 				var extraI int64
@@ -3682,18 +5658,17 @@ func (t *ShardAssignReq) UnmarshalCBOR(r io.Reader) (err error) {
 					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
-				t.Height = int64(extraI)
+				t.RequestId = int64(extraI)
 			}
-			// t.AssignTxType (types.AssignTxType) (string)
-		case "AssignTxType":
+			// t.RelayProposal (types.RelayProposalCbor) (struct)
+		case "RelayProposal":
 
 			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
+
+				if err := t.RelayProposal.UnmarshalCBOR(cr); err != nil {
+					return xerrors.Errorf("unmarshaling t.RelayProposal: %w", err)
 				}
 
-				t.AssignTxType = AssignTxType(sval)
 			}
 
 		default:
@@ -3704,7 +5679,7 @@ func (t *ShardAssignReq) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *ShardAssignResp) MarshalCBOR(w io.Writer) error {
+func (t *ShardLoadResp) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -3712,7 +5687,7 @@ func (t *ShardAssignResp) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{162}); err != nil {
+	if _, err := cw.Write([]byte{167}); err != nil {
 		return err
 	}
 
@@ -3754,93 +5729,6 @@ func (t *ShardAssignResp) MarshalCBOR(w io.Writer) error {
 	if _, err := io.WriteString(w, string(t.Message)); err != nil {
 		return err
 	}
-	return nil
-}
-
-func (t *ShardAssignResp) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardAssignResp{}
-
-	cr := cbg.NewCborReader(r)
-
-	maj, extra, err := cr.ReadHeader()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err == io.EOF {
-			err = io.ErrUnexpectedEOF
-		}
-	}()
-
-	if maj != cbg.MajMap {
-		return fmt.Errorf("cbor input should be of type map")
-	}
-
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardAssignResp: map struct too large (%d)", extra)
-	}
-
-	var name string
-	n := extra
-
-	for i := uint64(0); i < n; i++ {
-
-		{
-			sval, err := cbg.ReadString(cr)
-			if err != nil {
-				return err
-			}
-
-			name = string(sval)
-		}
-
-		switch name {
-		// t.Code (uint64) (uint64)
-		case "Code":
-
-			{
-
-				maj, extra, err = cr.ReadHeader()
-				if err != nil {
-					return err
-				}
-				if maj != cbg.MajUnsignedInt {
-					return fmt.Errorf("wrong type for uint64 field")
-				}
-				t.Code = uint64(extra)
-
-			}
-			// t.Message (string) (string)
-		case "Message":
-
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
-
-				t.Message = string(sval)
-			}
-
-		default:
-			// Field doesn't exist on this type, so ignore it
-			cbg.ScanForLinks(r, func(cid.Cid) {})
-		}
-	}
-
-	return nil
-}
-func (t *ShardCompleteReq) MarshalCBOR(w io.Writer) error {
-	if t == nil {
-		_, err := w.Write(cbg.CborNull)
-		return err
-	}
-
-	cw := cbg.NewCborWriter(w)
-
-	if _, err := cw.Write([]byte{165}); err != nil {
-		return err
-	}
 
 	// t.OrderId (uint64) (uint64)
 	if len("OrderId") > cbg.MaxLength {
@@ -3858,103 +5746,94 @@ func (t *ShardCompleteReq) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	// t.DataId (string) (string)
-	if len("DataId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"DataId\" was too long")
+	// t.Cid (cid.Cid) (struct)
+	if len("Cid") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Cid\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("DataId"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cid"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("DataId")); err != nil {
+	if _, err := io.WriteString(w, string("Cid")); err != nil {
 		return err
 	}
 
-	if len(t.DataId) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.DataId was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.DataId))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string(t.DataId)); err != nil {
-		return err
+	if err := cbg.WriteCid(cw, t.Cid); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Cid: %w", err)
 	}
 
-	// t.Cids ([]cid.Cid) (slice)
-	if len("Cids") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Cids\" was too long")
+	// t.Content ([]uint8) (slice)
+	if len("Content") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Content\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cids"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Content"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Cids")); err != nil {
+	if _, err := io.WriteString(w, string("Content")); err != nil {
 		return err
 	}
 
-	if len(t.Cids) > cbg.MaxLength {
-		return xerrors.Errorf("Slice value in field t.Cids was too long")
+	if len(t.Content) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Content was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajArray, uint64(len(t.Cids))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Content))); err != nil {
 		return err
 	}
-	for _, v := range t.Cids {
-		if err := cbg.WriteCid(w, v); err != nil {
-			return xerrors.Errorf("failed writing cid field t.Cids: %w", err)
-		}
-	}
-
-	// t.TxHash (string) (string)
-	if len("TxHash") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"TxHash\" was too long")
-	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHash"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("TxHash")); err != nil {
+	if _, err := cw.Write(t.Content[:]); err != nil {
 		return err
 	}
 
-	if len(t.TxHash) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.TxHash was too long")
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TxHash))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.TxHash)); err != nil {
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
 		return err
 	}
 
-	// t.Height (int64) (int64)
-	if len("Height") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Height\" was too long")
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.ResponseId (int64) (int64)
+	if len("ResponseId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"ResponseId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Height"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("ResponseId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Height")); err != nil {
+	if _, err := io.WriteString(w, string("ResponseId")); err != nil {
 		return err
 	}
 
-	if t.Height >= 0 {
-		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Height)); err != nil {
+	if t.ResponseId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.ResponseId)); err != nil {
 			return err
 		}
 	} else {
-		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.Height-1)); err != nil {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.ResponseId-1)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardCompleteReq{}
+func (t *ShardLoadResp) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardLoadResp{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -3973,7 +5852,7 @@ func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardCompleteReq: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardLoadResp: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -3991,8 +5870,8 @@ func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
-		// t.OrderId (uint64) (uint64)
-		case "OrderId":
+		// t.Code (uint64) (uint64)
+		case "Code":
 
 			{
 
@@ -4003,11 +5882,11 @@ func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
 				if maj != cbg.MajUnsignedInt {
 					return fmt.Errorf("wrong type for uint64 field")
 				}
-				t.OrderId = uint64(extra)
+				t.Code = uint64(extra)
 
 			}
-			// t.DataId (string) (string)
-		case "DataId":
+			// t.Message (string) (string)
+		case "Message":
 
 			{
 				sval, err := cbg.ReadString(cr)
@@ -4015,50 +5894,86 @@ func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
 					return err
 				}
 
-				t.DataId = string(sval)
+				t.Message = string(sval)
 			}
-			// t.Cids ([]cid.Cid) (slice)
-		case "Cids":
+			// t.OrderId (uint64) (uint64)
+		case "OrderId":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.OrderId = uint64(extra)
+
+			}
+			// t.Cid (cid.Cid) (struct)
+		case "Cid":
+
+			{
+
+				c, err := cbg.ReadCid(cr)
+				if err != nil {
+					return xerrors.Errorf("failed to read cid field t.Cid: %w", err)
+				}
+
+				t.Cid = c
+
+			}
+			// t.Content ([]uint8) (slice)
+		case "Content":
 
 			maj, extra, err = cr.ReadHeader()
 			if err != nil {
 				return err
 			}
 
-			if extra > cbg.MaxLength {
-				return fmt.Errorf("t.Cids: array too large (%d)", extra)
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Content: byte array too large (%d)", extra)
 			}
-
-			if maj != cbg.MajArray {
-				return fmt.Errorf("expected cbor array")
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
 			}
 
 			if extra > 0 {
-				t.Cids = make([]cid.Cid, extra)
+				t.Content = make([]uint8, extra)
 			}
 
-			for i := 0; i < int(extra); i++ {
-
-				c, err := cbg.ReadCid(cr)
-				if err != nil {
-					return xerrors.Errorf("reading cid field t.Cids failed: %w", err)
-				}
-				t.Cids[i] = c
+			if _, err := io.ReadFull(cr, t.Content[:]); err != nil {
+				return err
 			}
-
-			// t.TxHash (string) (string)
-		case "TxHash":
-
+			// t.RequestId (int64) (int64)
+		case "RequestId":
 			{
-				sval, err := cbg.ReadString(cr)
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
 				if err != nil {
 					return err
 				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
 
-				t.TxHash = string(sval)
+				t.RequestId = int64(extraI)
 			}
-			// t.Height (int64) (int64)
-		case "Height":
+			// t.ResponseId (int64) (int64)
+		case "ResponseId":
 			{
 				maj, extra, err := cr.ReadHeader()
 				var extraI int64
@@ -4081,7 +5996,7 @@ func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
 					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
-				t.Height = int64(extraI)
+				t.ResponseId = int64(extraI)
 			}
 
 		default:
@@ -4092,7 +6007,7 @@ func (t *ShardCompleteReq) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *ShardCompleteResp) MarshalCBOR(w io.Writer) error {
+func (t *ShardMigrateReq) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -4100,210 +6015,118 @@ func (t *ShardCompleteResp) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{163}); err != nil {
-		return err
-	}
-
-	// t.Code (uint64) (uint64)
-	if len("Code") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Code\" was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Code"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("Code")); err != nil {
-		return err
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Code)); err != nil {
+	if _, err := cw.Write([]byte{168}); err != nil {
 		return err
 	}
 
-	// t.Message (string) (string)
-	if len("Message") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Message\" was too long")
+	// t.MigrateFrom (string) (string)
+	if len("MigrateFrom") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"MigrateFrom\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Message"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("MigrateFrom"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Message")); err != nil {
+	if _, err := io.WriteString(w, string("MigrateFrom")); err != nil {
 		return err
 	}
 
-	if len(t.Message) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Message was too long")
+	if len(t.MigrateFrom) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.MigrateFrom was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Message))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.MigrateFrom))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Message)); err != nil {
+	if _, err := io.WriteString(w, string(t.MigrateFrom)); err != nil {
 		return err
 	}
 
-	// t.Recoverable (bool) (bool)
-	if len("Recoverable") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Recoverable\" was too long")
+	// t.OrderId (uint64) (uint64)
+	if len("OrderId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"OrderId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Recoverable"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("Recoverable")); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
 		return err
 	}
-
-	if err := cbg.WriteBool(w, t.Recoverable); err != nil {
+	if _, err := io.WriteString(w, string("OrderId")); err != nil {
 		return err
 	}
-	return nil
-}
-
-func (t *ShardCompleteResp) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardCompleteResp{}
-
-	cr := cbg.NewCborReader(r)
 
-	maj, extra, err := cr.ReadHeader()
-	if err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
 		return err
 	}
-	defer func() {
-		if err == io.EOF {
-			err = io.ErrUnexpectedEOF
-		}
-	}()
-
-	if maj != cbg.MajMap {
-		return fmt.Errorf("cbor input should be of type map")
-	}
-
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardCompleteResp: map struct too large (%d)", extra)
-	}
-
-	var name string
-	n := extra
-
-	for i := uint64(0); i < n; i++ {
-
-		{
-			sval, err := cbg.ReadString(cr)
-			if err != nil {
-				return err
-			}
-
-			name = string(sval)
-		}
-
-		switch name {
-		// t.Code (uint64) (uint64)
-		case "Code":
-
-			{
-
-				maj, extra, err = cr.ReadHeader()
-				if err != nil {
-					return err
-				}
-				if maj != cbg.MajUnsignedInt {
-					return fmt.Errorf("wrong type for uint64 field")
-				}
-				t.Code = uint64(extra)
-
-			}
-			// t.Message (string) (string)
-		case "Message":
-
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
-
-				t.Message = string(sval)
-			}
-			// t.Recoverable (bool) (bool)
-		case "Recoverable":
-
-			maj, extra, err = cr.ReadHeader()
-			if err != nil {
-				return err
-			}
-			if maj != cbg.MajOther {
-				return fmt.Errorf("booleans must be major type 7")
-			}
-			switch extra {
-			case 20:
-				t.Recoverable = false
-			case 21:
-				t.Recoverable = true
-			default:
-				return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
-			}
-
-		default:
-			// Field doesn't exist on this type, so ignore it
-			cbg.ScanForLinks(r, func(cid.Cid) {})
-		}
+
+	// t.DataId (string) (string)
+	if len("DataId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"DataId\" was too long")
 	}
 
-	return nil
-}
-func (t *ShardLoadReq) MarshalCBOR(w io.Writer) error {
-	if t == nil {
-		_, err := w.Write(cbg.CborNull)
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("DataId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("DataId")); err != nil {
 		return err
 	}
 
-	cw := cbg.NewCborWriter(w)
+	if len(t.DataId) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.DataId was too long")
+	}
 
-	if _, err := cw.Write([]byte{166}); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.DataId))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.DataId)); err != nil {
 		return err
 	}
 
-	// t.Owner (string) (string)
-	if len("Owner") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Owner\" was too long")
+	// t.TxHash (string) (string)
+	if len("TxHash") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TxHash\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Owner"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHash"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Owner")); err != nil {
+	if _, err := io.WriteString(w, string("TxHash")); err != nil {
 		return err
 	}
 
-	if len(t.Owner) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Owner was too long")
+	if len(t.TxHash) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.TxHash was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Owner))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TxHash))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.Owner)); err != nil {
+	if _, err := io.WriteString(w, string(t.TxHash)); err != nil {
 		return err
 	}
 
-	// t.OrderId (uint64) (uint64)
-	if len("OrderId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"OrderId\" was too long")
+	// t.TxHeight (int64) (int64)
+	if len("TxHeight") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"TxHeight\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHeight"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("OrderId")); err != nil {
+	if _, err := io.WriteString(w, string("TxHeight")); err != nil {
 		return err
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
-		return err
+	if t.TxHeight >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.TxHeight)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.TxHeight-1)); err != nil {
+			return err
+		}
 	}
 
-	// t.Cid (cid.Cid) (struct)
+	// t.Cid (string) (string)
 	if len("Cid") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"Cid\" was too long")
 	}
@@ -4315,23 +6138,38 @@ func (t *ShardLoadReq) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	if err := cbg.WriteCid(cw, t.Cid); err != nil {
-		return xerrors.Errorf("failed to write cid field t.Cid: %w", err)
+	if len(t.Cid) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Cid was too long")
 	}
 
-	// t.Proposal (types.MetadataProposalCbor) (struct)
-	if len("Proposal") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Proposal\" was too long")
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Cid))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.Cid)); err != nil {
+		return err
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Proposal"))); err != nil {
+	// t.Content ([]uint8) (slice)
+	if len("Content") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Content\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Content"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Proposal")); err != nil {
+	if _, err := io.WriteString(w, string("Content")); err != nil {
 		return err
 	}
 
-	if err := t.Proposal.MarshalCBOR(cw); err != nil {
+	if len(t.Content) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Content was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Content))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Content[:]); err != nil {
 		return err
 	}
 
@@ -4356,27 +6194,11 @@ func (t *ShardLoadReq) MarshalCBOR(w io.Writer) error {
 			return err
 		}
 	}
-
-	// t.RelayProposal (types.RelayProposalCbor) (struct)
-	if len("RelayProposal") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"RelayProposal\" was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RelayProposal"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("RelayProposal")); err != nil {
-		return err
-	}
-
-	if err := t.RelayProposal.MarshalCBOR(cw); err != nil {
-		return err
-	}
 	return nil
 }
 
-func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardLoadReq{}
+func (t *ShardMigrateReq) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardMigrateReq{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -4395,7 +6217,7 @@ func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardLoadReq: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardMigrateReq: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -4413,8 +6235,8 @@ func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
-		// t.Owner (string) (string)
-		case "Owner":
+		// t.MigrateFrom (string) (string)
+		case "MigrateFrom":
 
 			{
 				sval, err := cbg.ReadString(cr)
@@ -4422,7 +6244,7 @@ func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
 					return err
 				}
 
-				t.Owner = string(sval)
+				t.MigrateFrom = string(sval)
 			}
 			// t.OrderId (uint64) (uint64)
 		case "OrderId":
@@ -4439,31 +6261,30 @@ func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
 				t.OrderId = uint64(extra)
 
 			}
-			// t.Cid (cid.Cid) (struct)
-		case "Cid":
+			// t.DataId (string) (string)
+		case "DataId":
 
 			{
-
-				c, err := cbg.ReadCid(cr)
+				sval, err := cbg.ReadString(cr)
 				if err != nil {
-					return xerrors.Errorf("failed to read cid field t.Cid: %w", err)
+					return err
 				}
 
-				t.Cid = c
-
+				t.DataId = string(sval)
 			}
-			// t.Proposal (types.MetadataProposalCbor) (struct)
-		case "Proposal":
+			// t.TxHash (string) (string)
+		case "TxHash":
 
 			{
-
-				if err := t.Proposal.UnmarshalCBOR(cr); err != nil {
-					return xerrors.Errorf("unmarshaling t.Proposal: %w", err)
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
 				}
 
+				t.TxHash = string(sval)
 			}
-			// t.RequestId (int64) (int64)
-		case "RequestId":
+			// t.TxHeight (int64) (int64)
+		case "TxHeight":
 			{
 				maj, extra, err := cr.ReadHeader()
 				var extraI int64
@@ -4486,17 +6307,66 @@ func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
 					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
-				t.RequestId = int64(extraI)
+				t.TxHeight = int64(extraI)
 			}
-			// t.RelayProposal (types.RelayProposalCbor) (struct)
-		case "RelayProposal":
+			// t.Cid (string) (string)
+		case "Cid":
 
 			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
 
-				if err := t.RelayProposal.UnmarshalCBOR(cr); err != nil {
-					return xerrors.Errorf("unmarshaling t.RelayProposal: %w", err)
+				t.Cid = string(sval)
+			}
+			// t.Content ([]uint8) (slice)
+		case "Content":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Content: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Content = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.Content[:]); err != nil {
+				return err
+			}
+			// t.RequestId (int64) (int64)
+		case "RequestId":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
+				t.RequestId = int64(extraI)
 			}
 
 		default:
@@ -4507,7 +6377,7 @@ func (t *ShardLoadReq) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *ShardLoadResp) MarshalCBOR(w io.Writer) error {
+func (t *ShardMigrateResp) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -4515,7 +6385,7 @@ func (t *ShardLoadResp) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{167}); err != nil {
+	if _, err := cw.Write([]byte{165}); err != nil {
 		return err
 	}
 
@@ -4558,110 +6428,77 @@ func (t *ShardLoadResp) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	// t.OrderId (uint64) (uint64)
-	if len("OrderId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"OrderId\" was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("OrderId"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("OrderId")); err != nil {
-		return err
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.OrderId)); err != nil {
-		return err
-	}
-
-	// t.Cid (cid.Cid) (struct)
-	if len("Cid") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Cid\" was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cid"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("Cid")); err != nil {
-		return err
-	}
-
-	if err := cbg.WriteCid(cw, t.Cid); err != nil {
-		return xerrors.Errorf("failed to write cid field t.Cid: %w", err)
-	}
-
-	// t.Content ([]uint8) (slice)
-	if len("Content") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Content\" was too long")
+	// t.CompleteHash (string) (string)
+	if len("CompleteHash") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"CompleteHash\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Content"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("CompleteHash"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Content")); err != nil {
+	if _, err := io.WriteString(w, string("CompleteHash")); err != nil {
 		return err
 	}
-
-	if len(t.Content) > cbg.ByteArrayMaxLen {
-		return xerrors.Errorf("Byte array in field t.Content was too long")
+
+	if len(t.CompleteHash) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.CompleteHash was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Content))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.CompleteHash))); err != nil {
 		return err
 	}
-
-	if _, err := cw.Write(t.Content[:]); err != nil {
+	if _, err := io.WriteString(w, string(t.CompleteHash)); err != nil {
 		return err
 	}
 
-	// t.RequestId (int64) (int64)
-	if len("RequestId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"RequestId\" was too long")
+	// t.CompleteHeight (int64) (int64)
+	if len("CompleteHeight") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"CompleteHeight\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("CompleteHeight"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("RequestId")); err != nil {
+	if _, err := io.WriteString(w, string("CompleteHeight")); err != nil {
 		return err
 	}
 
-	if t.RequestId >= 0 {
-		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
+	if t.CompleteHeight >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.CompleteHeight)); err != nil {
 			return err
 		}
 	} else {
-		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.CompleteHeight-1)); err != nil {
 			return err
 		}
 	}
 
-	// t.ResponseId (int64) (int64)
-	if len("ResponseId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"ResponseId\" was too long")
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("ResponseId"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("ResponseId")); err != nil {
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
 		return err
 	}
 
-	if t.ResponseId >= 0 {
-		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.ResponseId)); err != nil {
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
 			return err
 		}
 	} else {
-		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.ResponseId-1)); err != nil {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (t *ShardLoadResp) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardLoadResp{}
+func (t *ShardMigrateResp) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardMigrateResp{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -4680,7 +6517,7 @@ func (t *ShardLoadResp) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardLoadResp: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardMigrateResp: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -4724,58 +6561,19 @@ func (t *ShardLoadResp) UnmarshalCBOR(r io.Reader) (err error) {
 
 				t.Message = string(sval)
 			}
-			// t.OrderId (uint64) (uint64)
-		case "OrderId":
+			// t.CompleteHash (string) (string)
+		case "CompleteHash":
 
 			{
-
-				maj, extra, err = cr.ReadHeader()
+				sval, err := cbg.ReadString(cr)
 				if err != nil {
 					return err
 				}
-				if maj != cbg.MajUnsignedInt {
-					return fmt.Errorf("wrong type for uint64 field")
-				}
-				t.OrderId = uint64(extra)
-
-			}
-			// t.Cid (cid.Cid) (struct)
-		case "Cid":
-
-			{
-
-				c, err := cbg.ReadCid(cr)
-				if err != nil {
-					return xerrors.Errorf("failed to read cid field t.Cid: %w", err)
-				}
-
-				t.Cid = c
-
-			}
-			// t.Content ([]uint8) (slice)
-		case "Content":
-
-			maj, extra, err = cr.ReadHeader()
-			if err != nil {
-				return err
-			}
-
-			if extra > cbg.ByteArrayMaxLen {
-				return fmt.Errorf("t.Content: byte array too large (%d)", extra)
-			}
-			if maj != cbg.MajByteString {
-				return fmt.Errorf("expected byte array")
-			}
-
-			if extra > 0 {
-				t.Content = make([]uint8, extra)
-			}
 
-			if _, err := io.ReadFull(cr, t.Content[:]); err != nil {
-				return err
+				t.CompleteHash = string(sval)
 			}
-			// t.RequestId (int64) (int64)
-		case "RequestId":
+			// t.CompleteHeight (int64) (int64)
+		case "CompleteHeight":
 			{
 				maj, extra, err := cr.ReadHeader()
 				var extraI int64
@@ -4798,10 +6596,10 @@ func (t *ShardLoadResp) UnmarshalCBOR(r io.Reader) (err error) {
 					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
-				t.RequestId = int64(extraI)
+				t.CompleteHeight = int64(extraI)
 			}
-			// t.ResponseId (int64) (int64)
-		case "ResponseId":
+			// t.RequestId (int64) (int64)
+		case "RequestId":
 			{
 				maj, extra, err := cr.ReadHeader()
 				var extraI int64
@@ -4824,7 +6622,7 @@ func (t *ShardLoadResp) UnmarshalCBOR(r io.Reader) (err error) {
 					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
-				t.ResponseId = int64(extraI)
+				t.RequestId = int64(extraI)
 			}
 
 		default:
@@ -4835,7 +6633,7 @@ func (t *ShardLoadResp) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *ShardMigrateReq) MarshalCBOR(w io.Writer) error {
+func (t *ShardMigrateChunkReq) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -4843,7 +6641,7 @@ func (t *ShardMigrateReq) MarshalCBOR(w io.Writer) error {
 
 	cw := cbg.NewCborWriter(w)
 
-	if _, err := cw.Write([]byte{167}); err != nil {
+	if _, err := cw.Write([]byte{166}); err != nil {
 		return err
 	}
 
@@ -4886,94 +6684,42 @@ func (t *ShardMigrateReq) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	// t.DataId (string) (string)
-	if len("DataId") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"DataId\" was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("DataId"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("DataId")); err != nil {
-		return err
-	}
-
-	if len(t.DataId) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.DataId was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.DataId))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string(t.DataId)); err != nil {
-		return err
-	}
-
-	// t.TxHash (string) (string)
-	if len("TxHash") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"TxHash\" was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHash"))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string("TxHash")); err != nil {
-		return err
-	}
-
-	if len(t.TxHash) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.TxHash was too long")
+	// t.Cid (string) (string)
+	if len("Cid") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Cid\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.TxHash))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cid"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string(t.TxHash)); err != nil {
+	if _, err := io.WriteString(w, string("Cid")); err != nil {
 		return err
 	}
 
-	// t.TxHeight (int64) (int64)
-	if len("TxHeight") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"TxHeight\" was too long")
+	if len(t.Cid) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.Cid was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("TxHeight"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Cid))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("TxHeight")); err != nil {
+	if _, err := io.WriteString(w, string(t.Cid)); err != nil {
 		return err
 	}
 
-	if t.TxHeight >= 0 {
-		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.TxHeight)); err != nil {
-			return err
-		}
-	} else {
-		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.TxHeight-1)); err != nil {
-			return err
-		}
-	}
-
-	// t.Cid (string) (string)
-	if len("Cid") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"Cid\" was too long")
+	// t.Offset (uint64) (uint64)
+	if len("Offset") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Offset\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Cid"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Offset"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("Cid")); err != nil {
+	if _, err := io.WriteString(w, string("Offset")); err != nil {
 		return err
 	}
 
-	if len(t.Cid) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.Cid was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.Cid))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string(t.Cid)); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Offset)); err != nil {
 		return err
 	}
 
@@ -5000,11 +6746,33 @@ func (t *ShardMigrateReq) MarshalCBOR(w io.Writer) error {
 	if _, err := cw.Write(t.Content[:]); err != nil {
 		return err
 	}
+
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
+		return err
+	}
+
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
+			return err
+		}
+	} else {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (t *ShardMigrateReq) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardMigrateReq{}
+func (t *ShardMigrateChunkReq) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardMigrateChunkReq{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -5023,7 +6791,7 @@ func (t *ShardMigrateReq) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardMigrateReq: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardMigrateChunkReq: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -5067,54 +6835,6 @@ func (t *ShardMigrateReq) UnmarshalCBOR(r io.Reader) (err error) {
 				t.OrderId = uint64(extra)
 
 			}
-			// t.DataId (string) (string)
-		case "DataId":
-
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
-
-				t.DataId = string(sval)
-			}
-			// t.TxHash (string) (string)
-		case "TxHash":
-
-			{
-				sval, err := cbg.ReadString(cr)
-				if err != nil {
-					return err
-				}
-
-				t.TxHash = string(sval)
-			}
-			// t.TxHeight (int64) (int64)
-		case "TxHeight":
-			{
-				maj, extra, err := cr.ReadHeader()
-				var extraI int64
-				if err != nil {
-					return err
-				}
-				switch maj {
-				case cbg.MajUnsignedInt:
-					extraI = int64(extra)
-					if extraI < 0 {
-						return fmt.Errorf("int64 positive overflow")
-					}
-				case cbg.MajNegativeInt:
-					extraI = int64(extra)
-					if extraI < 0 {
-						return fmt.Errorf("int64 negative oveflow")
-					}
-					extraI = -1 - extraI
-				default:
-					return fmt.Errorf("wrong type for int64 field: %d", maj)
-				}
-
-				t.TxHeight = int64(extraI)
-			}
 			// t.Cid (string) (string)
 		case "Cid":
 
@@ -5126,6 +6846,21 @@ func (t *ShardMigrateReq) UnmarshalCBOR(r io.Reader) (err error) {
 
 				t.Cid = string(sval)
 			}
+			// t.Offset (uint64) (uint64)
+		case "Offset":
+
+			{
+
+				maj, extra, err = cr.ReadHeader()
+				if err != nil {
+					return err
+				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Offset = uint64(extra)
+
+			}
 			// t.Content ([]uint8) (slice)
 		case "Content":
 
@@ -5148,6 +6883,32 @@ func (t *ShardMigrateReq) UnmarshalCBOR(r io.Reader) (err error) {
 			if _, err := io.ReadFull(cr, t.Content[:]); err != nil {
 				return err
 			}
+			// t.RequestId (int64) (int64)
+		case "RequestId":
+			{
+				maj, extra, err := cr.ReadHeader()
+				var extraI int64
+				if err != nil {
+					return err
+				}
+				switch maj {
+				case cbg.MajUnsignedInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 positive overflow")
+					}
+				case cbg.MajNegativeInt:
+					extraI = int64(extra)
+					if extraI < 0 {
+						return fmt.Errorf("int64 negative oveflow")
+					}
+					extraI = -1 - extraI
+				default:
+					return fmt.Errorf("wrong type for int64 field: %d", maj)
+				}
+
+				t.RequestId = int64(extraI)
+			}
 
 		default:
 			// Field doesn't exist on this type, so ignore it
@@ -5157,7 +6918,7 @@ func (t *ShardMigrateReq) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-func (t *ShardMigrateResp) MarshalCBOR(w io.Writer) error {
+func (t *ShardMigrateChunkResp) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
@@ -5208,55 +6969,48 @@ func (t *ShardMigrateResp) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
-	// t.CompleteHash (string) (string)
-	if len("CompleteHash") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"CompleteHash\" was too long")
+	// t.Received (uint64) (uint64)
+	if len("Received") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Received\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("CompleteHash"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Received"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("CompleteHash")); err != nil {
+	if _, err := io.WriteString(w, string("Received")); err != nil {
 		return err
 	}
 
-	if len(t.CompleteHash) > cbg.MaxLength {
-		return xerrors.Errorf("Value in field t.CompleteHash was too long")
-	}
-
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.CompleteHash))); err != nil {
-		return err
-	}
-	if _, err := io.WriteString(w, string(t.CompleteHash)); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.Received)); err != nil {
 		return err
 	}
 
-	// t.CompleteHeight (int64) (int64)
-	if len("CompleteHeight") > cbg.MaxLength {
-		return xerrors.Errorf("Value in field \"CompleteHeight\" was too long")
+	// t.RequestId (int64) (int64)
+	if len("RequestId") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RequestId\" was too long")
 	}
 
-	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("CompleteHeight"))); err != nil {
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("RequestId"))); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(w, string("CompleteHeight")); err != nil {
+	if _, err := io.WriteString(w, string("RequestId")); err != nil {
 		return err
 	}
 
-	if t.CompleteHeight >= 0 {
-		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.CompleteHeight)); err != nil {
+	if t.RequestId >= 0 {
+		if err := cw.WriteMajorTypeHeader(cbg.MajUnsignedInt, uint64(t.RequestId)); err != nil {
 			return err
 		}
 	} else {
-		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.CompleteHeight-1)); err != nil {
+		if err := cw.WriteMajorTypeHeader(cbg.MajNegativeInt, uint64(-t.RequestId-1)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (t *ShardMigrateResp) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = ShardMigrateResp{}
+func (t *ShardMigrateChunkResp) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = ShardMigrateChunkResp{}
 
 	cr := cbg.NewCborReader(r)
 
@@ -5275,7 +7029,7 @@ func (t *ShardMigrateResp) UnmarshalCBOR(r io.Reader) (err error) {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("ShardMigrateResp: map struct too large (%d)", extra)
+		return fmt.Errorf("ShardMigrateChunkResp: map struct too large (%d)", extra)
 	}
 
 	var name string
@@ -5319,19 +7073,23 @@ func (t *ShardMigrateResp) UnmarshalCBOR(r io.Reader) (err error) {
 
 				t.Message = string(sval)
 			}
-			// t.CompleteHash (string) (string)
-		case "CompleteHash":
+			// t.Received (uint64) (uint64)
+		case "Received":
 
 			{
-				sval, err := cbg.ReadString(cr)
+
+				maj, extra, err = cr.ReadHeader()
 				if err != nil {
 					return err
 				}
+				if maj != cbg.MajUnsignedInt {
+					return fmt.Errorf("wrong type for uint64 field")
+				}
+				t.Received = uint64(extra)
 
-				t.CompleteHash = string(sval)
 			}
-			// t.CompleteHeight (int64) (int64)
-		case "CompleteHeight":
+			// t.RequestId (int64) (int64)
+		case "RequestId":
 			{
 				maj, extra, err := cr.ReadHeader()
 				var extraI int64
@@ -5354,7 +7112,7 @@ func (t *ShardMigrateResp) UnmarshalCBOR(r io.Reader) (err error) {
 					return fmt.Errorf("wrong type for int64 field: %d", maj)
 				}
 
-				t.CompleteHeight = int64(extraI)
+				t.RequestId = int64(extraI)
 			}
 
 		default:
@@ -5460,3 +7218,190 @@ func (t *ShardPingPong) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
+func (t *RequestEnvelope) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+
+	cw := cbg.NewCborWriter(w)
+
+	if _, err := cw.Write([]byte{163}); err != nil {
+		return err
+	}
+
+	// t.NodeAddress (string) (string)
+	if len("NodeAddress") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"NodeAddress\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("NodeAddress"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("NodeAddress")); err != nil {
+		return err
+	}
+
+	if len(t.NodeAddress) > cbg.MaxLength {
+		return xerrors.Errorf("Value in field t.NodeAddress was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(t.NodeAddress))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(t.NodeAddress)); err != nil {
+		return err
+	}
+
+	// t.Payload ([]uint8) (slice)
+	if len("Payload") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Payload\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Payload"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Payload")); err != nil {
+		return err
+	}
+
+	if len(t.Payload) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Payload was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Payload))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Payload[:]); err != nil {
+		return err
+	}
+
+	// t.Signature ([]uint8) (slice)
+	if len("Signature") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"Signature\" was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajTextString, uint64(len("Signature"))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string("Signature")); err != nil {
+		return err
+	}
+
+	if len(t.Signature) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Signature was too long")
+	}
+
+	if err := cw.WriteMajorTypeHeader(cbg.MajByteString, uint64(len(t.Signature))); err != nil {
+		return err
+	}
+
+	if _, err := cw.Write(t.Signature[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *RequestEnvelope) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = RequestEnvelope{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	if maj != cbg.MajMap {
+		return fmt.Errorf("cbor input should be of type map")
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("RequestEnvelope: map struct too large (%d)", extra)
+	}
+
+	var name string
+	n := extra
+
+	for i := uint64(0); i < n; i++ {
+
+		{
+			sval, err := cbg.ReadString(cr)
+			if err != nil {
+				return err
+			}
+
+			name = string(sval)
+		}
+
+		switch name {
+		// t.NodeAddress (string) (string)
+		case "NodeAddress":
+
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.NodeAddress = string(sval)
+			}
+			// t.Payload ([]uint8) (slice)
+		case "Payload":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Payload: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Payload = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.Payload[:]); err != nil {
+				return err
+			}
+			// t.Signature ([]uint8) (slice)
+		case "Signature":
+
+			maj, extra, err = cr.ReadHeader()
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.Signature: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+
+			if extra > 0 {
+				t.Signature = make([]uint8, extra)
+			}
+
+			if _, err := io.ReadFull(cr, t.Signature[:]); err != nil {
+				return err
+			}
+
+		default:
+			// Field doesn't exist on this type, so ignore it
+			cbg.ScanForLinks(r, func(cid.Cid) {})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+// capabilities a delegation grants on the models of the issuer.
+const (
+	CapModelLoad    = "model/load"
+	CapModelCommits = "model/commits"
+	CapModelCreate  = "model/create"
+	CapModelUpdate  = "model/update"
+	CapAll          = "*"
+)
+
+// Delegation grants the audience DID the capabilities on the resources of the issuer DID, a resource is
+// a dataId or an alias, or "*" for all models. Expiration and NotBefore are unix seconds. The gateway accepts the
+// query proposals signed by the audience on behalf of the issuer, until the delegation expires or its nonce is
+// revoked, see ShareRevoke.
+//
+// The order proposals of the writes are verified by the chain against the key of the owner, so they can't be
+// delegated. CapModelCreate and CapModelUpdate let the audience send the writes whose order proposals the issuer
+// signed in advance, like an upload token.
+type Delegation struct {
+	Issuer       string
+	Audience     string
+	Capabilities []string
+	Resources    []string
+	NotBefore    int64
+	Expiration   int64
+	Nonce        string
+}
+
+// SignedDelegation is a delegation signed with the key of the issuer DID.
+type SignedDelegation struct {
+	Delegation   Delegation
+	JwsSignature saotypes.JwsSignature
+}
+
+// Marshal returns the payload the issuer signs.
+func (d *Delegation) Marshal() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// Allows checks the delegation covers the capability on the resource at the time.
+func (d *Delegation) Allows(capability string, resource string, now time.Time) error {
+	if d.Expiration == 0 {
+		return Wrapf(ErrInvalidDelegation, "delegation without expiration")
+	}
+	if now.Unix() >= d.Expiration {
+		return Wrapf(ErrInvalidDelegation, "delegation expired at %s", time.Unix(d.Expiration, 0).Format(time.RFC3339))
+	}
+	if now.Unix() < d.NotBefore {
+		return Wrapf(ErrInvalidDelegation, "delegation not valid before %s", time.Unix(d.NotBefore, 0).Format(time.RFC3339))
+	}
+
+	if !contains(d.Capabilities, capability) {
+		return Wrapf(ErrInvalidDelegation, "%s is not delegated", capability)
+	}
+	if !contains(d.Resources, resource) {
+		return Wrapf(ErrInvalidDelegation, "%s is not delegated", resource)
+	}
+	return nil
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item || i == CapAll {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionRevoke revokes the session key registered for the audience, it is signed by the issuer. Nonce is the nonce
+// of the session's delegation, so the request can't revoke a session registered again later, and the gateway
+// rejects it after Expiration.
+type SessionRevoke struct {
+	Issuer     string
+	Audience   string
+	Nonce      string
+	Expiration int64
+}
+
+type SessionRevokeProposal struct {
+	Proposal     SessionRevoke
+	JwsSignature saotypes.JwsSignature
+}
+
+func (r *SessionRevoke) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -19,9 +19,12 @@ var (
 
 	ErrInvalidBinaryName = errors.Register(ModuleCommon, 10008, "invalid binary name")
 
-	ErrMarshalFailed   = errors.Register(ModuleCommon, 10009, "failed to marshal payload")
-	ErrUnMarshalFailed = errors.Register(ModuleCommon, 10010, "failed to unmarshal payload")
-	ErrUnSupport       = errors.Register(ModuleCommon, 10011, "not implemented yet")
+	ErrMarshalFailed     = errors.Register(ModuleCommon, 10009, "failed to marshal payload")
+	ErrUnMarshalFailed   = errors.Register(ModuleCommon, 10010, "failed to unmarshal payload")
+	ErrUnSupport         = errors.Register(ModuleCommon, 10011, "not implemented yet")
+	ErrEncryptFailed     = errors.Register(ModuleCommon, 10012, "failed to encrypt")
+	ErrMigrateRepoFailed = errors.Register(ModuleCommon, 10013, "failed to migrate the repo")
+	ErrRepoLocked        = errors.Register(ModuleCommon, 10014, "repo is locked by another process")
 )
 
 var (
@@ -62,6 +65,11 @@ var (
 
 	ErrQueryHeightFailed   = errors.Register(ModuleChain, 11026, "failed to query the latest height")
 	ErrInconsistentAddress = errors.Register(ModuleChain, 11027, "inconsistent address")
+	ErrDeleteAccountFailed = errors.Register(ModuleChain, 11028, "failed to delete the account")
+	ErrQueryParamsFailed   = errors.Register(ModuleChain, 11029, "failed to query the chain parameters")
+	ErrInsufficientFunds   = errors.Register(ModuleChain, 11030, "insufficient funds to pay the order or the fee")
+	ErrTxSimulateFailed    = errors.Register(ModuleChain, 11031, "the tx is rejected by the simulation")
+	ErrTxNotIncluded       = errors.Register(ModuleChain, 11032, "the tx is not included in a block in time")
 )
 
 var (
@@ -99,6 +107,8 @@ var (
 	ErrUnSupportProtocol          = errors.Register(ModuleStore, 13012, "unsupported ipfs connection protocol")
 	ErrRemoveFailed               = errors.Register(ModuleStore, 13013, "remove data failed")
 	ErrDataMissing                = errors.Register(ModuleStore, 13014, "cannot found the data")
+	ErrNoCapacity                 = errors.Register(ModuleStore, 13015, "no storage capacity left")
+	ErrProvideFailed              = errors.Register(ModuleStore, 13016, "failed to announce the provider record")
 )
 
 var (
@@ -142,6 +152,13 @@ var (
 	ErrProcessOrderFailed = errors.Register(ModuleModel, 14028, "failed to process the order")
 	ErrExpiredOrder       = errors.Register(ModuleModel, 14029, "expired order")
 	ErrRetriesExceed      = errors.Register(ModuleModel, 14030, "shard retries too many times")
+
+	ErrInvalidDelegation = errors.Register(ModuleModel, 14031, "invalid delegation")
+	ErrNoPermission      = errors.Register(ModuleModel, 14032, "no permission")
+	ErrCommitConflict    = errors.Register(ModuleModel, 14033, "the model has been committed since")
+	ErrTransformFailed   = errors.Register(ModuleModel, 14034, "failed to transform the model")
+	ErrUnverifiedCommit  = errors.Register(ModuleModel, 14035, "the commit can't be verified")
+	ErrQuotaExceeded     = errors.Register(ModuleModel, 14036, "the quota of the platform is exceeded")
 )
 
 var (
@@ -157,6 +174,9 @@ var (
 	ErrSendRequestFailed          = errors.Register(ModuleNetwork, 15007, "failed to send the request")
 	ErrReadResponseFailed         = errors.Register(ModuleNetwork, 15008, "failed to read the response")
 	ErrFailuresResponsed          = errors.Register(ModuleNetwork, 15009, "received failed response")
+	ErrUnregisteredPeer           = errors.Register(ModuleNetwork, 15010, "peer is not registered for the node")
+	ErrBusy                       = errors.Register(ModuleNetwork, 15011, "busy, retry later")
+	ErrRequestTooLarge            = errors.Register(ModuleNetwork, 15012, "request exceeds the limit")
 )
 
 func Wrap(err0 error, err1 error) error {
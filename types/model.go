@@ -16,9 +16,11 @@ type Model struct {
 	Version    string
 	Content    []byte
 	ExtendInfo string
+	Rule       string
 }
 
 const Type_Prefix_File = "file_"
 const Type_Prefix_Model = "model_"
 const Type_Prefix_Rule = "rule_"
 const Type_Prefix_Schema = "schema_"
+const Type_Prefix_Log = "log_"
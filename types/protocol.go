@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"strings"
 
 	"github.com/ipfs/go-cid"
 )
@@ -11,12 +12,27 @@ import (
 type AssignTxType string
 
 const (
-	ShardLoadProtocol     = "/sao/shard/load/1.0"
-	ShardStoreProtocol    = "/sao/shard/store/1.0"
-	ShardAssignProtocol   = "/sao/shard/assign/1.0"
-	ShardCompleteProtocol = "/sao/shard/complete/1.0"
-	ShardMigrateProtocol  = "/sao/shard/migrate/1.0"
-	ShardPingPongProtocol = "/sao/shard/pingpong/1.0"
+	ShardLoadProtocol     = "/sao/shard/load/1.1"
+	ShardStoreProtocol    = "/sao/shard/store/1.1"
+	ShardAssignProtocol   = "/sao/shard/assign/1.1"
+	ShardCompleteProtocol = "/sao/shard/complete/1.1"
+	ShardMigrateProtocol  = "/sao/shard/migrate/1.1"
+	ShardPingPongProtocol = "/sao/shard/pingpong/1.1"
+	// the chunks of a migrated shard, there is no 1.0 version
+	ShardMigrateChunkProtocol = "/sao/shard/migrate-chunk/1.1"
+	// the availability challenges of the gateways, a shard load signed by the gateway of the order instead of
+	// a query of the owner, there is no 1.0 version
+	ShardAuditProtocol = "/sao/shard/audit/1.1"
+
+	// previous protocol versions, still served and dialed as a fallback so
+	// that nodes of different releases can talk to each other during a
+	// rolling upgrade.
+	ShardLoadProtocolV1_0     = "/sao/shard/load/1.0"
+	ShardStoreProtocolV1_0    = "/sao/shard/store/1.0"
+	ShardAssignProtocolV1_0   = "/sao/shard/assign/1.0"
+	ShardCompleteProtocolV1_0 = "/sao/shard/complete/1.0"
+	ShardMigrateProtocolV1_0  = "/sao/shard/migrate/1.0"
+	ShardPingPongProtocolV1_0 = "/sao/shard/pingpong/1.0"
 
 	ErrorCodeInvalidRequest       = 1
 	ErrorCodeInvalidTx            = 2
@@ -25,6 +41,10 @@ const (
 	ErrorCodeInvalidShardCid      = 5
 	ErrorCodeInvalidOrderProvider = 6
 	ErrorCodeInvalidShardAssignee = 7
+	ErrorCodeInvalidSignature     = 8
+	ErrorCodeBusy                 = 9
+	ErrorCodeNoCapacity           = 10
+	ErrorCodeRequestTooLarge      = 11
 
 	AssignTxTypeStore AssignTxType = "MsgStore"
 	AssignTxTypeReady AssignTxType = "MsgReady"
@@ -33,6 +53,44 @@ const (
 	FormatCbor string = "cbor"
 )
 
+// protocol versions supported by this node, the newest one goes first.
+var protocolVersions = map[string][]string{
+	ShardLoadProtocol:     {ShardLoadProtocol, ShardLoadProtocolV1_0},
+	ShardStoreProtocol:    {ShardStoreProtocol, ShardStoreProtocolV1_0},
+	ShardAssignProtocol:   {ShardAssignProtocol, ShardAssignProtocolV1_0},
+	ShardCompleteProtocol: {ShardCompleteProtocol, ShardCompleteProtocolV1_0},
+	ShardMigrateProtocol:  {ShardMigrateProtocol, ShardMigrateProtocolV1_0},
+	ShardPingPongProtocol: {ShardPingPongProtocol, ShardPingPongProtocolV1_0},
+}
+
+// ProtocolVersions returns all the protocol ids of the given protocol which this
+// node is able to speak, ordered by preference.
+func ProtocolVersions(protocol string) []string {
+	if versions, exists := protocolVersions[protocol]; exists {
+		return versions
+	}
+	return []string{protocol}
+}
+
+// ProtocolVersion returns the version part of a protocol id, e.g. "1.1" for
+// "/sao/shard/assign/1.1".
+func ProtocolVersion(protocol string) string {
+	return protocol[strings.LastIndex(protocol, "/")+1:]
+}
+
+// ProtocolName returns the protocol id without the version part, e.g. "/sao/shard/assign" for
+// "/sao/shard/assign/1.0", the ids of the unversioned protocols are returned as is.
+func ProtocolName(protocol string) string {
+	for _, versions := range protocolVersions {
+		for _, version := range versions {
+			if version == protocol {
+				return strings.TrimSuffix(protocol, "/"+ProtocolVersion(protocol))
+			}
+		}
+	}
+	return protocol
+}
+
 type ShardStaging struct {
 	Basedir string
 }
@@ -64,27 +122,34 @@ type ShardAssignReq struct {
 	TxHash       string
 	Height       int64
 	AssignTxType AssignTxType
+	RequestId    int64
+	Priority     Priority
 }
 
 type ShardAssignResp struct {
-	Code    uint64
-	Message string
+	Code      uint64
+	Message   string
+	RequestId int64
 }
 
 type ShardCompleteReq struct {
-	OrderId uint64
-	DataId  string
-	Cids    []cid.Cid
-	TxHash  string
-	Height  int64
+	OrderId   uint64
+	DataId    string
+	Cids      []cid.Cid
+	TxHash    string
+	Height    int64
+	RequestId int64
 }
 
 type ShardCompleteResp struct {
 	Code        uint64
 	Message     string
 	Recoverable bool // if can handle this shard after retry
+	RequestId   int64
 }
 
+// ShardMigrateReq completes the migration of a shard. The content is sent before by ShardMigrateChunkReq and
+// Content is empty, the nodes of previous releases send the whole shard in Content.
 type ShardMigrateReq struct {
 	MigrateFrom string
 	OrderId     uint64
@@ -93,6 +158,7 @@ type ShardMigrateReq struct {
 	TxHeight    int64
 	Cid         string
 	Content     []byte
+	RequestId   int64
 }
 
 type ShardMigrateResp struct {
@@ -100,6 +166,35 @@ type ShardMigrateResp struct {
 	Message        string
 	CompleteHash   string
 	CompleteHeight int64
+	RequestId      int64
+}
+
+// ShardMigrateChunkReq carries the bytes of a migrated shard at Offset. The receiver keeps the bytes received
+// across the requests, so an interrupted migration resumes from Received instead of the start. A request
+// without content only asks for Received.
+type ShardMigrateChunkReq struct {
+	MigrateFrom string
+	OrderId     uint64
+	Cid         string
+	Offset      uint64
+	Content     []byte
+	RequestId   int64
+}
+
+type ShardMigrateChunkResp struct {
+	Code      uint64
+	Message   string
+	Received  uint64
+	RequestId int64
+}
+
+// RequestEnvelope carries a cbor encoded request over the 1.1 assign, complete
+// and migrate protocols. The payload is signed with the libp2p key of the sender,
+// whose peer id must be registered on chain for NodeAddress.
+type RequestEnvelope struct {
+	NodeAddress string
+	Payload     []byte
+	Signature   []byte
 }
 
 type ShardPingPong struct {
@@ -158,6 +253,58 @@ func (f *ShardMigrateResp) Marshal(w io.Writer, format string) error {
 	return err
 }
 
+func (f *ShardMigrateChunkReq) Unmarshal(r io.Reader, format string) error {
+	var err error
+	if format == FormatJson {
+		buf := &bytes.Buffer{}
+		buf.ReadFrom(r)
+		err = json.Unmarshal(buf.Bytes(), f)
+	} else {
+		err = f.UnmarshalCBOR(r)
+	}
+	return err
+}
+
+func (f *ShardMigrateChunkReq) Marshal(w io.Writer, format string) error {
+	var err error
+	if format == FormatJson {
+		bytes, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes)
+	} else {
+		err = f.MarshalCBOR(w)
+	}
+	return err
+}
+
+func (f *ShardMigrateChunkResp) Unmarshal(r io.Reader, format string) error {
+	var err error
+	if format == FormatJson {
+		buf := &bytes.Buffer{}
+		buf.ReadFrom(r)
+		err = json.Unmarshal(buf.Bytes(), f)
+	} else {
+		err = f.UnmarshalCBOR(r)
+	}
+	return err
+}
+
+func (f *ShardMigrateChunkResp) Marshal(w io.Writer, format string) error {
+	var err error
+	if format == FormatJson {
+		bytes, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes)
+	} else {
+		err = f.MarshalCBOR(w)
+	}
+	return err
+}
+
 func (f *ShardLoadReq) Marshal(w io.Writer, format string) error {
 	var err error
 	if format == FormatJson {
@@ -356,3 +503,29 @@ func (f *ShardPingPong) Marshal(w io.Writer, format string) error {
 	}
 	return err
 }
+
+func (f *RequestEnvelope) Unmarshal(r io.Reader, format string) error {
+	var err error
+	if format == FormatJson {
+		buf := &bytes.Buffer{}
+		buf.ReadFrom(r)
+		err = json.Unmarshal(buf.Bytes(), f)
+	} else {
+		err = f.UnmarshalCBOR(r)
+	}
+	return err
+}
+
+func (f *RequestEnvelope) Marshal(w io.Writer, format string) error {
+	var err error
+	if format == FormatJson {
+		bytes, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes)
+	} else {
+		err = f.MarshalCBOR(w)
+	}
+	return err
+}
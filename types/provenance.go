@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// CommitProvenance is who made a commit of a model and when, as found on chain.
+type CommitProvenance struct {
+	CommitId string
+	Version  string
+	Cid      string
+	OrderId  uint64
+	// height and time of the block the commit completed in
+	Height uint64
+	Time   time.Time
+	// the MsgStore tx of the order, whose proposal is signed by the writer
+	StoreTxHash string
+	StoreHeight int64
+	// did of the writer and the key id of the signature, including the sid document version for a sid
+	Signer     string
+	Kid        string
+	SidVersion string `json:",omitempty"`
+	Verified   bool
+	// why the commit can't be verified
+	Error string `json:",omitempty"`
+}
+
+// ProvenanceReport is the provenance of each commit of a model, oldest first, as verified by a gateway.
+type ProvenanceReport struct {
+	DataId  string
+	Alias   string
+	Owner   string
+	Commits []CommitProvenance
+	// node address of the gateway, and the chain height and the time the report is made at
+	Gateway    string
+	Height     int64
+	ReportedAt time.Time
+}
+
+// Verified returns whether all commits of the report are verified.
+func (r *ProvenanceReport) Verified() bool {
+	for _, commit := range r.Commits {
+		if !commit.Verified {
+			return false
+		}
+	}
+	return len(r.Commits) > 0
+}
+
+// SignedProvenanceReport is a provenance report in json signed by the peer key of the gateway. The peer id is
+// registered on chain for the node address of the gateway, which the consumers should check.
+type SignedProvenanceReport struct {
+	Report    []byte
+	PeerId    string
+	Signature []byte
+}
+
+// SignProvenanceReport signs the report by the peer key.
+func SignProvenanceReport(report *ProvenanceReport, key crypto.PrivKey) (*SignedProvenanceReport, error) {
+	reportBytes, err := json.Marshal(report)
+	if err != nil {
+		return nil, Wrap(ErrMarshalFailed, err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		return nil, Wrap(ErrSignedFailed, err)
+	}
+	sig, err := key.Sign(reportBytes)
+	if err != nil {
+		return nil, Wrap(ErrSignedFailed, err)
+	}
+	return &SignedProvenanceReport{
+		Report:    reportBytes,
+		PeerId:    id.String(),
+		Signature: sig,
+	}, nil
+}
+
+// Open verifies the signature is made by the key of the peer id, and returns the report.
+func (s *SignedProvenanceReport) Open() (*ProvenanceReport, error) {
+	id, err := peer.Decode(s.PeerId)
+	if err != nil {
+		return nil, Wrapf(ErrInvalidSignature, "invalid peer id %s: %v", s.PeerId, err)
+	}
+	pubKey, err := id.ExtractPublicKey()
+	if err != nil {
+		return nil, Wrapf(ErrInvalidSignature, "public key of %s: %v", s.PeerId, err)
+	}
+	ok, err := pubKey.Verify(s.Report, s.Signature)
+	if err != nil || !ok {
+		return nil, Wrapf(ErrInvalidSignature, "the report is not signed by %s", s.PeerId)
+	}
+
+	var report ProvenanceReport
+	if err := json.Unmarshal(s.Report, &report); err != nil {
+		return nil, Wrap(ErrUnMarshalFailed, err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+// A share token is the query request of a model signed by a throwaway did:key, along with the delegation of
+// CapModelLoad on the model to that did:key by the owner. The secret of the did:key is dropped once the request is
+// signed, so whoever holds the token can load the model, but can't make any other request. The nonce of the
+// delegation identifies the share to revoke.
+
+// EncodeShareToken returns the token of the query request, which is safe in a url path.
+func EncodeShareToken(req *MetadataProposal) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", Wrap(ErrMarshalFailed, err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeShareToken returns the query request of the token, which must come with a delegation.
+func DecodeShareToken(token string) (*MetadataProposal, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, Wrapf(ErrInvalidParameters, "invalid share token: %v", err)
+	}
+	var req MetadataProposal
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, Wrapf(ErrInvalidParameters, "invalid share token: %v", err)
+	}
+	if req.Delegation == nil {
+		return nil, Wrapf(ErrInvalidDelegation, "the share token has no delegation")
+	}
+	return &req, nil
+}
+
+// ShareRevoke revokes a delegation of the issuer by its nonce, the one of a share, an upload token or a delegation
+// given to another DID. The gateway keeps the revoked delegation until it expires.
+type ShareRevoke struct {
+	Issuer     string
+	Nonce      string
+	Expiration int64
+}
+
+type ShareRevokeProposal struct {
+	Proposal     ShareRevoke
+	JwsSignature saotypes.JwsSignature
+}
+
+func (r *ShareRevoke) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
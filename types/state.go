@@ -1,6 +1,8 @@
 package types
 
 import (
+	"strings"
+
 	"github.com/ipfs/go-cid"
 )
 
@@ -39,6 +41,14 @@ type OrderInfo struct {
 	Shards      map[string]OrderShardInfo
 
 	ExpireHeight uint64
+	// status of the order on chain when it is closed by the gateway
+	ChainStatus int64
+	// QoS class of the order in the task queues of the gateway and the storage nodes
+	Priority Priority
+	// marshaled client proposal and signature, to store the order again if its storage nodes don't complete
+	Proposal     []byte
+	JwsSignature []byte
+	Republishes  uint64
 
 	State   OrderState
 	Tries   uint64
@@ -57,15 +67,90 @@ const (
 )
 
 var orderStateString = map[OrderState]string{
-	OrderStateStaged:   "Staged",
-	OrderStateReady:    "Ready",
-	OrderStateComplete: "Complete",
+	OrderStateStaged:    "Staged",
+	OrderStateReady:     "Ready",
+	OrderStateComplete:  "Complete",
+	OrderStateTerminate: "Terminate",
+	OrderStateExpired:   "Expired",
 }
 
 func (s OrderState) String() string {
 	return orderStateString[s]
 }
 
+// ParseOrderState returns the state of the name, case insensitive.
+func ParseOrderState(name string) (OrderState, error) {
+	for state, s := range orderStateString {
+		if strings.EqualFold(s, name) {
+			return state, nil
+		}
+	}
+	return 0, Wrapf(ErrInvalidParameters, "unknown order state %s", name)
+}
+
+/**
+ * order filter, the zero values match all orders.
+ */
+type OrderFilter struct {
+	Owner  string
+	States []OrderState
+	// range of the height the order is stored or readied at, the staged orders are at 0
+	FromHeight int64
+	ToHeight   int64
+}
+
+func (f OrderFilter) Match(order OrderInfo) bool {
+	if f.Owner != "" && order.Owner != f.Owner {
+		return false
+	}
+	if f.FromHeight > 0 && order.OrderHeight < f.FromHeight {
+		return false
+	}
+	if f.ToHeight > 0 && order.OrderHeight > f.ToHeight {
+		return false
+	}
+	if len(f.States) == 0 {
+		return true
+	}
+	for _, state := range f.States {
+		if order.State == state {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * QoS class of an order, the tasks of the higher ones are processed first, FIFO within a class.
+ */
+type Priority int64
+
+const (
+	PriorityArchival    Priority = -1
+	PriorityNormal      Priority = 0
+	PriorityInteractive Priority = 1
+)
+
+var priorityString = map[Priority]string{
+	PriorityArchival:    "archival",
+	PriorityNormal:      "normal",
+	PriorityInteractive: "interactive",
+}
+
+func (p Priority) String() string {
+	return priorityString[p]
+}
+
+// ParsePriority returns the priority of the name, case insensitive.
+func ParsePriority(name string) (Priority, error) {
+	for priority, s := range priorityString {
+		if strings.EqualFold(s, name) {
+			return priority, nil
+		}
+	}
+	return 0, Wrapf(ErrInvalidParameters, "unknown priority %s", name)
+}
+
 /**
  * shard state in order
  */
@@ -120,6 +205,7 @@ type ShardInfo struct {
 	CompleteHash   string
 	CompleteHeight int64
 	Size           uint64
+	Priority       Priority
 
 	Tries        uint64
 	ExpireHeight uint64
@@ -169,11 +255,14 @@ type MigrateState uint64
 const (
 	MigrateStateTxSent MigrateState = iota
 	MigrateStateComplete
+	// the complete tx of the new provider is not deep enough on chain yet
+	MigrateStateUnconfirmed
 )
 
 var migrateStateString = map[MigrateState]string{
-	MigrateStateTxSent:   "txSent",
-	MigrateStateComplete: "complete",
+	MigrateStateTxSent:      "txSent",
+	MigrateStateComplete:    "complete",
+	MigrateStateUnconfirmed: "unconfirmed",
 }
 
 func (m MigrateState) String() string {
@@ -188,3 +277,43 @@ type MigrateKey struct {
 type MigrateIndex struct {
 	All []MigrateKey
 }
+
+// CompleteRecord remembers a shard complete request handled by the gateway, so
+// retries with the same order id and tx hash are not processed twice.
+type CompleteRecord struct {
+	OrderId   uint64
+	TxHash    string
+	HandledAt int64
+}
+
+// AuditInfo is the result of the last availability audit of an order placed by the gateway. The providers of the
+// completed shards are challenged to return the content of their shards, the order is under replicated once fewer
+// of them pass than its replica.
+type AuditInfo struct {
+	DataId  string
+	OrderId uint64
+	Replica uint64
+	Results []AuditResult
+
+	UnderReplicated bool
+	AuditedAt       int64
+}
+
+// AuditResult is the challenge of a provider, Reason tells why it failed.
+type AuditResult struct {
+	Provider string
+	Cid      string
+	Passed   bool
+	Reason   string
+}
+
+// Passed returns the number of the providers which passed the audit.
+func (a AuditInfo) Passed() int {
+	passed := 0
+	for _, r := range a.Results {
+		if r.Passed {
+			passed++
+		}
+	}
+	return passed
+}
@@ -53,3 +53,59 @@ type RpcResp struct {
 	Data  string
 	Error string
 }
+
+type ProgressPhase string
+
+const (
+	// the content is uploaded to the staging path of the gateway, the chunks are the transport chunks
+	ProgressPhaseStaging ProgressPhase = "staging"
+	// the shards are stored by the storage nodes, the chunks are the shards completed out of the replicas
+	ProgressPhaseDistribution ProgressPhase = "distribution"
+)
+
+// ProgressEvent is the progress of a content in a phase, keyed by the content cid.
+type ProgressEvent struct {
+	Cid     string
+	Phase   ProgressPhase
+	Percent float64
+	Bytes   uint64
+	Total   uint64
+	Chunk   int
+	Chunks  int
+	Time    int64
+}
+
+// Done reports whether the content is stored by all storage nodes, the last event of the content.
+func (e ProgressEvent) Done() bool {
+	return e.Phase == ProgressPhaseDistribution && e.Chunks > 0 && e.Chunk >= e.Chunks
+}
+
+type ModelEventKind string
+
+const (
+	// the order of a commit of the model moved to another state on the gateway
+	ModelEventOrder ModelEventKind = "order"
+	// the model or its permission changed on chain
+	ModelEventUpdate ModelEventKind = "update"
+)
+
+// ModelEvent is something that happened to a data model, the gateway sends them to the subscribers of the model or
+// of its owner.
+type ModelEvent struct {
+	Kind   ModelEventKind
+	DataId string
+	Owner  string
+	// set for ModelEventOrder, the commit is stored once State is OrderStateComplete
+	OrderId uint64
+	State   OrderState
+	// set for ModelEventUpdate
+	Alias  string
+	Height int64
+	TxHash string
+	Time   int64
+}
+
+// Completed reports whether the event is the completion of an order of the model.
+func (e ModelEvent) Completed() bool {
+	return e.Kind == ModelEventOrder && e.State == OrderStateComplete
+}
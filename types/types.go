@@ -59,6 +59,13 @@ type OrderMeta struct {
 type MetadataProposal struct {
 	Proposal     saotypes.QueryProposal
 	JwsSignature saotypes.JwsSignature
+	// set when the proposal is signed by a DID the owner delegated to, the proposal owner is the audience then
+	Delegation *SignedDelegation `json:",omitempty"`
+	// set to the issuer when the proposal owner is a session key registered by SessionRegister, the session is not
+	// applied otherwise
+	Session string `json:",omitempty"`
+	// how many levels of the {"$ref": "sao://<dataId>"} links in the content ModelLoad resolves, it is not signed
+	ResolveDepth int `json:",omitempty"`
 }
 
 type MetadataProposalCbor struct {
@@ -100,6 +107,8 @@ type PermissionProposal struct {
 type OrderStoreProposal struct {
 	Proposal     saotypes.Proposal
 	JwsSignature saotypes.JwsSignature
+	// not signed, only a scheduling hint for the gateway and the storage nodes
+	Priority Priority
 }
 
 type OrderRenewProposal struct {
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// An upload token lets an application server hand the upload of a file to an end user, whose browser puts the
+// bytes to the gateway without a DID. Like a share token, the query request is signed by a throwaway did:key, which
+// the owner delegates CapModelCreate on the data id of the model to, and the order proposal is signed by the owner
+// for the cid and the size of the file. The gateway commits the upload only if its bytes are the ones of the order
+// proposal. The nonce of the delegation identifies the upload to revoke, and the upload is accepted once, the replays
+// are rejected before their bytes are staged as the data id is taken or its order in process by then.
+
+// UploadToken is the pre-signed upload of a file.
+type UploadToken struct {
+	Request MetadataProposal
+	Order   OrderStoreProposal
+}
+
+// EncodeUploadToken returns the token of the pre-signed upload, which is safe in a url path.
+func EncodeUploadToken(token *UploadToken) (string, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", Wrap(ErrMarshalFailed, err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeUploadToken returns the pre-signed upload of the token, the query request must come with a delegation and
+// be of the model of the order proposal.
+func DecodeUploadToken(encoded string) (*UploadToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, Wrapf(ErrInvalidParameters, "invalid upload token: %v", err)
+	}
+	var token UploadToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, Wrapf(ErrInvalidParameters, "invalid upload token: %v", err)
+	}
+	if token.Request.Delegation == nil {
+		return nil, Wrapf(ErrInvalidDelegation, "the upload token has no delegation")
+	}
+	if token.Request.Proposal.Keyword != token.Order.Proposal.DataId {
+		return nil, Wrapf(ErrInvalidParameters, "the upload token is of %s, but its order of %s",
+			token.Request.Proposal.Keyword, token.Order.Proposal.DataId)
+	}
+	return &token, nil
+}
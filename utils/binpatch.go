@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"sao-node/types"
+)
+
+// A binary patch turns the bytes of an origin into a target, like bsdiff and xdelta do for the files the json
+// patches can't handle. It is a list of operations, either adding the bytes it holds or copying a range of the
+// origin, deflated after a magic. The copies are found by hashing the blocks of the origin, and extended byte by byte
+// on both sides, so a change only costs about the bytes changed.
+
+const binaryPatchMagic = "SAOBDIF1"
+
+const (
+	binaryPatchAdd byte = iota
+	binaryPatchCopy
+)
+
+// base of the rolling hash of the blocks
+const binaryPatchPrime = 1099511628211
+
+// IsBinaryPatch returns whether the data is a binary patch.
+func IsBinaryPatch(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(binaryPatchMagic))
+}
+
+// GenerateBinaryPatch returns the binary patch turning origin into target, origin is empty for a patch holding the
+// whole target.
+func GenerateBinaryPatch(origin []byte, target []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(binaryPatchMagic)
+	zw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreatePatchFailed, err)
+	}
+	w := bufio.NewWriter(zw)
+	scratch := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(v uint64) {
+		w.Write(scratch[:binary.PutUvarint(scratch, v)])
+	}
+	writeUvarint(uint64(len(target)))
+
+	// the origin offset the last copy ends at, the copies are encoded relative to it
+	var last int64
+	add := func(data []byte) {
+		if len(data) > 0 {
+			w.WriteByte(binaryPatchAdd)
+			writeUvarint(uint64(len(data)))
+			w.Write(data)
+		}
+	}
+	copyRange := func(offset int, length int) {
+		w.WriteByte(binaryPatchCopy)
+		w.Write(scratch[:binary.PutVarint(scratch, int64(offset)-last)])
+		writeUvarint(uint64(length))
+		last = int64(offset + length)
+	}
+
+	block := binaryPatchBlockSize(len(origin))
+	index := make(map[uint64]int, len(origin)/block)
+	for offset := 0; offset+block <= len(origin); offset += block {
+		h := blockHash(origin[offset : offset+block])
+		if _, exists := index[h]; !exists {
+			index[h] = offset
+		}
+	}
+	// the weight of the byte leaving the window of the rolling hash
+	var outWeight uint64 = 1
+	for i := 1; i < block; i++ {
+		outWeight *= binaryPatchPrime
+	}
+
+	// the target bytes from pending on are not encoded yet
+	pending := 0
+	i := 0
+	var h uint64
+	if len(target) >= block && len(index) > 0 {
+		h = blockHash(target[:block])
+	}
+	for len(index) > 0 && i+block <= len(target) {
+		if offset, ok := index[h]; ok && bytes.Equal(origin[offset:offset+block], target[i:i+block]) {
+			start, from := i, offset
+			for start > pending && from > 0 && target[start-1] == origin[from-1] {
+				start--
+				from--
+			}
+			end, to := i+block, offset+block
+			for end < len(target) && to < len(origin) && target[end] == origin[to] {
+				end++
+				to++
+			}
+			add(target[pending:start])
+			copyRange(from, end-start)
+			pending, i = end, end
+			if i+block <= len(target) {
+				h = blockHash(target[i : i+block])
+			}
+			continue
+		}
+		if i+block < len(target) {
+			h = (h-uint64(target[i])*outWeight)*binaryPatchPrime + uint64(target[i+block])
+		}
+		i++
+	}
+	add(target[pending:])
+
+	if err := w.Flush(); err != nil {
+		return nil, types.Wrap(types.ErrCreatePatchFailed, err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, types.Wrap(types.ErrCreatePatchFailed, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ApplyBinaryPatch writes the target of the binary patch made on origin to w and returns its size, the origin is
+// read at the ranges copied only.
+func ApplyBinaryPatch(origin io.ReaderAt, patch []byte, w io.Writer) (int64, error) {
+	if !IsBinaryPatch(patch) {
+		return 0, types.Wrapf(types.ErrApplyPatchFailed, "not a binary patch")
+	}
+	zr := flate.NewReader(bytes.NewReader(patch[len(binaryPatchMagic):]))
+	defer zr.Close()
+	r := bufio.NewReader(zr)
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, types.Wrap(types.ErrApplyPatchFailed, err)
+	}
+	var written, last int64
+	for uint64(written) < size {
+		op, err := r.ReadByte()
+		if err != nil {
+			return written, types.Wrap(types.ErrApplyPatchFailed, err)
+		}
+		var src io.Reader
+		var length uint64
+		switch op {
+		case binaryPatchAdd:
+			length, err = binary.ReadUvarint(r)
+			src = r
+		case binaryPatchCopy:
+			var delta int64
+			delta, err = binary.ReadVarint(r)
+			if err == nil {
+				length, err = binary.ReadUvarint(r)
+			}
+			if err == nil && last+delta < 0 {
+				return written, types.Wrapf(types.ErrApplyPatchFailed, "invalid origin offset %d", last+delta)
+			}
+			src = io.NewSectionReader(origin, last+delta, int64(length))
+			last += delta + int64(length)
+		default:
+			return written, types.Wrapf(types.ErrApplyPatchFailed, "invalid operation %d", op)
+		}
+		if err != nil {
+			return written, types.Wrap(types.ErrApplyPatchFailed, err)
+		}
+		if length == 0 || uint64(written)+length > size {
+			return written, types.Wrapf(types.ErrApplyPatchFailed, "invalid length %d", length)
+		}
+
+		n, err := io.CopyN(w, src, int64(length))
+		written += n
+		if err == io.EOF {
+			return written, types.Wrapf(types.ErrApplyPatchFailed, "the patch or its origin is truncated")
+		}
+		if err != nil {
+			return written, types.Wrap(types.ErrApplyPatchFailed, err)
+		}
+	}
+	return written, nil
+}
+
+// binaryPatchBlockSize returns the size of the blocks of the origin indexed, larger for the larger origins so the
+// index stays small.
+func binaryPatchBlockSize(originSize int) int {
+	size := 32
+	for originSize/size > 1<<22 {
+		size *= 2
+	}
+	return size
+}
+
+func blockHash(block []byte) uint64 {
+	var h uint64
+	for _, b := range block {
+		h = h*binaryPatchPrime + uint64(b)
+	}
+	return h
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryPatch(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	origin := make([]byte, 1<<20)
+	rnd.Read(origin)
+
+	// a few bytes overwritten, a range inserted and a range removed
+	target := append([]byte(nil), origin[:1000]...)
+	target = append(target, []byte("inserted")...)
+	target = append(target, origin[1000:500000]...)
+	target = append(target, origin[600000:]...)
+	copy(target[700000:], []byte("changed"))
+
+	apply := func(origin []byte, patch []byte) []byte {
+		var buf bytes.Buffer
+		n, err := ApplyBinaryPatch(bytes.NewReader(origin), patch, &buf)
+		require.NoError(t, err)
+		require.Equal(t, int64(buf.Len()), n)
+		return buf.Bytes()
+	}
+
+	patch, err := GenerateBinaryPatch(origin, target)
+	require.NoError(t, err)
+	require.True(t, IsBinaryPatch(patch))
+	require.Less(t, len(patch), 1024)
+	require.Equal(t, target, apply(origin, patch))
+
+	// without an origin the patch holds the whole target
+	patch, err = GenerateBinaryPatch(nil, target)
+	require.NoError(t, err)
+	require.Equal(t, target, apply(nil, patch))
+	patch, err = GenerateBinaryPatch(origin, nil)
+	require.NoError(t, err)
+	require.Empty(t, apply(origin, patch))
+
+	// shorter than a block
+	patch, err = GenerateBinaryPatch([]byte("abc"), []byte("abcd"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("abcd"), apply([]byte("abc"), patch))
+
+	// the origin must be the one the patch is made on
+	patch, err = GenerateBinaryPatch(origin, target)
+	require.NoError(t, err)
+	_, err = ApplyBinaryPatch(bytes.NewReader(origin[:1000]), patch, &bytes.Buffer{})
+	require.True(t, types.ErrApplyPatchFailed.Is(err))
+	_, err = ApplyBinaryPatch(bytes.NewReader(origin), patch[:len(patch)/2], &bytes.Buffer{})
+	require.True(t, types.ErrApplyPatchFailed.Is(err))
+	_, err = ApplyBinaryPatch(bytes.NewReader(origin), []byte(`[{"op":"add"}]`), &bytes.Buffer{})
+	require.True(t, types.ErrApplyPatchFailed.Is(err))
+}
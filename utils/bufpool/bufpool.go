@@ -0,0 +1,86 @@
+// Package bufpool reuses the byte slices the shard contents are copied and buffered in, so the transfers under
+// load don't leave a large slice behind for the GC at every shard.
+package bufpool
+
+import (
+	"io"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// the slices are pooled by their capacity, a power of two from 4 KiB to 16 MiB
+	minShift = 12
+	maxShift = 24
+
+	// CopySize is the size of the buffers of Copy, the one io.Copy allocates
+	CopySize = 32 << 10
+)
+
+var pools [maxShift - minShift + 1]sync.Pool
+
+// class returns the index of the pool of the slices of size bytes, -1 if they are not pooled.
+func class(size int) int {
+	if size > 1<<maxShift {
+		return -1
+	}
+	shift := minShift
+	if size > 1<<minShift {
+		shift = bits.Len(uint(size - 1))
+	}
+	return shift - minShift
+}
+
+// Get returns a slice of size bytes, its content is undefined. The slice is returned to the pool by Put once it
+// is not used anymore, the slices larger than 16 MiB are allocated and left to the GC.
+func Get(size int) []byte {
+	c := class(size)
+	if c < 0 {
+		return make([]byte, size)
+	}
+	if p, ok := pools[c].Get().(*[]byte); ok {
+		return (*p)[:size]
+	}
+	return make([]byte, size, 1<<(c+minShift))
+}
+
+// Put returns a slice got from Get to the pool, the slices of other capacities are dropped.
+func Put(buf []byte) {
+	c := class(cap(buf))
+	if c < 0 || cap(buf) != 1<<(c+minShift) {
+		return
+	}
+	buf = buf[:cap(buf)]
+	pools[c].Put(&buf)
+}
+
+// Copy works like io.Copy with a pooled buffer.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := Get(CopySize)
+	defer Put(buf)
+	return io.CopyBuffer(dst, src, buf)
+}
+
+// ReadAll works like io.ReadAll with a pooled slice. The content must not be used after release is called, which
+// returns the slice to the pool.
+func ReadAll(r io.Reader) ([]byte, func(), error) {
+	buf := Get(CopySize)
+	n := 0
+	for {
+		if n == len(buf) {
+			grown := Get(2 * len(buf))
+			copy(grown, buf)
+			Put(buf)
+			buf = grown
+		}
+		m, err := r.Read(buf[n:])
+		n += m
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			Put(buf)
+			return nil, func() {}, err
+		}
+	}
+	return buf[:n], func() { Put(buf) }, nil
+}
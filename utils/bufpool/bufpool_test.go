@@ -0,0 +1,51 @@
+package bufpool
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPut(t *testing.T) {
+	for _, c := range []struct {
+		size     int
+		capacity int
+	}{
+		{size: 1, capacity: 4 << 10},
+		{size: 4 << 10, capacity: 4 << 10},
+		{size: 4<<10 + 1, capacity: 8 << 10},
+		{size: 1 << 20, capacity: 1 << 20},
+		{size: 16 << 20, capacity: 16 << 20},
+		{size: 16<<20 + 1, capacity: 16<<20 + 1},
+	} {
+		buf := Get(c.size)
+		require.Len(t, buf, c.size)
+		require.Equal(t, c.capacity, cap(buf), c.size)
+		Put(buf)
+	}
+
+	// not from the pool
+	Put(make([]byte, 5000))
+	require.Equal(t, 8<<10, cap(Get(5000)))
+}
+
+func TestReadAll(t *testing.T) {
+	for _, size := range []int{0, 100, CopySize, 3*CopySize + 7} {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		require.NoError(t, err)
+
+		data, release, err := ReadAll(bytes.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+		release()
+
+		var out bytes.Buffer
+		n, err := Copy(&out, bytes.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, int64(size), n)
+		require.Equal(t, content, append([]byte{}, out.Bytes()...))
+	}
+}
@@ -0,0 +1,258 @@
+// Package car packs the models into CARv2 files and reads them back, so the datasets can be backed up
+// offline and exchanged with the Filecoin/IPFS tooling.
+//
+// The root block of a file is a dag-cbor dataset linking the dag-cbor model blocks, each model links the
+// content of all its commits as raw blocks.
+package car
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sao-node/types"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/multiformats/go-multihash"
+	"github.com/multiformats/go-varint"
+)
+
+// Model is a model with the content of its commits, oldest first.
+type Model struct {
+	DataId  string
+	Alias   string
+	GroupId string
+	Commits []Commit
+}
+
+type Commit struct {
+	CommitId string
+	Version  string
+	Content  []byte
+}
+
+type datasetNode struct {
+	Owner  string
+	Models []cid.Cid
+}
+
+type modelNode struct {
+	DataId  string
+	Alias   string
+	GroupId string
+	Commits []commitNode
+}
+
+type commitNode struct {
+	CommitId string
+	Version  string
+	Content  cid.Cid
+}
+
+type carHeader struct {
+	Roots   []cid.Cid `refmt:"roots"`
+	Version uint64    `refmt:"version"`
+}
+
+func init() {
+	cbor.RegisterCborType(datasetNode{})
+	cbor.RegisterCborType(modelNode{})
+	cbor.RegisterCborType(commitNode{})
+	cbor.RegisterCborType(carHeader{})
+}
+
+var pragmaV2 = []byte{0x0a, 0xa1, 0x67, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x02}
+
+const (
+	headerV2Size = 40
+	// a section is a block, larger sizes are corrupted
+	maxSectionSize = 1 << 30
+)
+
+// Write packs the models of the owner as a CARv2 file without index.
+func Write(w io.Writer, owner string, models []Model) error {
+	var blocks []block
+	dataset := datasetNode{Owner: owner}
+	for _, m := range models {
+		node := modelNode{DataId: m.DataId, Alias: m.Alias, GroupId: m.GroupId}
+		for _, c := range m.Commits {
+			contentCid, err := cid.V1Builder{Codec: cid.Raw, MhType: multihash.SHA2_256}.Sum(c.Content)
+			if err != nil {
+				return types.Wrap(types.ErrCalculateCidFailed, err)
+			}
+			blocks = append(blocks, block{contentCid, c.Content})
+			node.Commits = append(node.Commits, commitNode{CommitId: c.CommitId, Version: c.Version, Content: contentCid})
+		}
+
+		b, err := cborBlock(node)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, b)
+		dataset.Models = append(dataset.Models, b.cid)
+	}
+	root, err := cborBlock(dataset)
+	if err != nil {
+		return err
+	}
+
+	var payload bytes.Buffer
+	header, err := cbor.DumpObject(carHeader{Roots: []cid.Cid{root.cid}, Version: 1})
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	payload.Write(varint.ToUvarint(uint64(len(header))))
+	payload.Write(header)
+	for _, b := range append([]block{root}, blocks...) {
+		payload.Write(varint.ToUvarint(uint64(b.cid.ByteLen() + len(b.data))))
+		payload.Write(b.cid.Bytes())
+		payload.Write(b.data)
+	}
+
+	// characteristics, data offset, data size and index offset, 0 for no index
+	headerV2 := make([]byte, headerV2Size)
+	binary.LittleEndian.PutUint64(headerV2[16:], uint64(len(pragmaV2)+headerV2Size))
+	binary.LittleEndian.PutUint64(headerV2[24:], uint64(payload.Len()))
+
+	for _, data := range [][]byte{pragmaV2, headerV2, payload.Bytes()} {
+		if _, err := w.Write(data); err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+	}
+	return nil
+}
+
+// Read reads the models from a CARv2 or CARv1 file written by Write, the blocks are verified against
+// their cids.
+func Read(r io.Reader) (string, []Model, error) {
+	br := bufio.NewReader(r)
+	prefix, err := br.Peek(len(pragmaV2))
+	if err != nil {
+		return "", nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	payload := br
+	if bytes.Equal(prefix, pragmaV2) {
+		headerV2 := make([]byte, len(pragmaV2)+headerV2Size)
+		if _, err := io.ReadFull(br, headerV2); err != nil {
+			return "", nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		offset := binary.LittleEndian.Uint64(headerV2[len(pragmaV2)+16:])
+		size := binary.LittleEndian.Uint64(headerV2[len(pragmaV2)+24:])
+		if offset < uint64(len(headerV2)) {
+			return "", nil, types.Wrapf(types.ErrInvalidParameters, "invalid car data offset %d", offset)
+		}
+		if _, err := io.CopyN(io.Discard, br, int64(offset)-int64(len(headerV2))); err != nil {
+			return "", nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		payload = bufio.NewReader(io.LimitReader(br, int64(size)))
+	}
+
+	root, blocks, err := readV1(payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var dataset datasetNode
+	if err := decodeBlock(blocks, root, &dataset); err != nil {
+		return "", nil, err
+	}
+	var models []Model
+	for _, c := range dataset.Models {
+		var node modelNode
+		if err := decodeBlock(blocks, c, &node); err != nil {
+			return "", nil, err
+		}
+		model := Model{DataId: node.DataId, Alias: node.Alias, GroupId: node.GroupId}
+		for _, commit := range node.Commits {
+			content, ok := blocks[commit.Content]
+			if !ok {
+				return "", nil, types.Wrapf(types.ErrInvalidParameters, "content %s of %s missing", commit.Content, node.DataId)
+			}
+			model.Commits = append(model.Commits, Commit{CommitId: commit.CommitId, Version: commit.Version, Content: content})
+		}
+		models = append(models, model)
+	}
+	return dataset.Owner, models, nil
+}
+
+type block struct {
+	cid  cid.Cid
+	data []byte
+}
+
+func cborBlock(obj interface{}) (block, error) {
+	node, err := cbor.WrapObject(obj, multihash.SHA2_256, -1)
+	if err != nil {
+		return block{}, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return block{node.Cid(), node.RawData()}, nil
+}
+
+func decodeBlock(blocks map[cid.Cid][]byte, c cid.Cid, obj interface{}) error {
+	data, ok := blocks[c]
+	if !ok {
+		return types.Wrapf(types.ErrInvalidParameters, "block %s missing", c)
+	}
+	if err := cbor.DecodeInto(data, obj); err != nil {
+		return types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	return nil
+}
+
+// readV1 reads the root and all blocks of a CARv1 payload.
+func readV1(r *bufio.Reader) (cid.Cid, map[cid.Cid][]byte, error) {
+	data, err := readSection(r)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	var header carHeader
+	if err := cbor.DecodeInto(data, &header); err != nil {
+		return cid.Undef, nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	if header.Version != 1 || len(header.Roots) != 1 {
+		return cid.Undef, nil, types.Wrapf(types.ErrInvalidParameters, "unsupported car version %d with %d roots", header.Version, len(header.Roots))
+	}
+
+	blocks := make(map[cid.Cid][]byte)
+	for {
+		data, err := readSection(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return cid.Undef, nil, err
+		}
+
+		n, c, err := cid.CidFromBytes(data)
+		if err != nil {
+			return cid.Undef, nil, types.Wrap(types.ErrInvalidCid, err)
+		}
+		sum, err := c.Prefix().Sum(data[n:])
+		if err != nil {
+			return cid.Undef, nil, types.Wrap(types.ErrCalculateCidFailed, err)
+		}
+		if !sum.Equals(c) {
+			return cid.Undef, nil, types.Wrapf(types.ErrInvalidCid, "block %s doesn't match its content", c)
+		}
+		blocks[c] = data[n:]
+	}
+	return header.Roots[0], blocks, nil
+}
+
+// readSection returns io.EOF only if there is no more section.
+func readSection(r *bufio.Reader) ([]byte, error) {
+	size, err := varint.ReadUvarint(r)
+	if err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	if size > maxSectionSize {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "car section of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,40 @@
+package car
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRead(t *testing.T) {
+	models := []Model{
+		{
+			DataId:  "data1",
+			Alias:   "profile",
+			GroupId: "app",
+			Commits: []Commit{
+				{CommitId: "commit1", Version: "v0", Content: []byte(`{"name":"alice"}`)},
+				{CommitId: "commit2", Version: "v1", Content: []byte(`{"name":"bob"}`)},
+			},
+		},
+		{
+			DataId:  "data2",
+			Alias:   "notes",
+			Commits: []Commit{{CommitId: "commit3", Version: "v0", Content: []byte("notes")}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "did:key:alice", models))
+
+	owner, read, err := Read(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, "did:key:alice", owner)
+	require.Equal(t, models, read)
+
+	// a changed block is rejected
+	data := bytes.Replace(buf.Bytes(), []byte("alice"), []byte("carol"), 1)
+	_, _, err = Read(bytes.NewReader(data))
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"io"
 	"regexp"
 	"sao-node/types"
 	"strings"
@@ -78,3 +79,13 @@ func CalculateCid(content []byte) (cid.Cid, error) {
 
 	return contentCid, nil
 }
+
+// CalculateCidFrom returns the cid CalculateCid returns for the content read from reader, without holding it
+// in memory.
+func CalculateCidFrom(reader io.Reader) (cid.Cid, error) {
+	hash, err := multihash.SumStream(reader, multihash.SHA2_256, -1)
+	if err != nil {
+		return cid.Undef, types.Wrap(types.ErrCalculateCidFailed, err)
+	}
+	return cid.NewCidV0(hash), nil
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sao-node/types"
+)
+
+// absent is the value of a field a document doesn't have.
+type absent struct{}
+
+// MergeJSON three-way merges ours and theirs, the json documents changed concurrently from base. The changes of
+// either side are kept: the objects are merged by keys and the arrays by indexes, the items appended to an array
+// on both sides are all kept, theirs first. A value changed differently on both sides is a conflict resolved by
+// ours, conflicts are the json pointers of those values.
+func MergeJSON(base []byte, ours []byte, theirs []byte) ([]byte, []string, error) {
+	var docs [3]interface{}
+	for i, doc := range [][]byte{base, ours, theirs} {
+		decoder := json.NewDecoder(bytes.NewReader(doc))
+		decoder.UseNumber()
+		if err := decoder.Decode(&docs[i]); err != nil {
+			return nil, nil, types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+	}
+
+	var conflicts []string
+	merged, err := json.Marshal(mergeValue("", docs[0], docs[1], docs[2], &conflicts))
+	if err != nil {
+		return nil, nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	sort.Strings(conflicts)
+	return merged, conflicts, nil
+}
+
+func mergeValue(pointer string, base interface{}, ours interface{}, theirs interface{}, conflicts *[]string) interface{} {
+	switch {
+	case reflect.DeepEqual(ours, theirs), reflect.DeepEqual(base, theirs):
+		return ours
+	case reflect.DeepEqual(base, ours):
+		return theirs
+	}
+
+	switch o := ours.(type) {
+	case map[string]interface{}:
+		if t, ok := theirs.(map[string]interface{}); ok {
+			b, _ := base.(map[string]interface{})
+			return mergeObject(pointer, b, o, t, conflicts)
+		}
+	case []interface{}:
+		if t, ok := theirs.([]interface{}); ok {
+			if b, ok := base.([]interface{}); ok {
+				if merged, ok := mergeArray(pointer, b, o, t, conflicts); ok {
+					return merged
+				}
+			}
+		}
+	}
+
+	*conflicts = append(*conflicts, pointer)
+	return ours
+}
+
+func mergeObject(pointer string, base map[string]interface{}, ours map[string]interface{}, theirs map[string]interface{}, conflicts *[]string) map[string]interface{} {
+	merged := make(map[string]interface{}, len(ours))
+	seen := make(map[string]bool, len(ours))
+	for _, doc := range []map[string]interface{}{base, ours, theirs} {
+		for key := range doc {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			value := mergeValue(pointer+"/"+escapePointer(key), field(base, key), field(ours, key), field(theirs, key), conflicts)
+			if _, ok := value.(absent); !ok {
+				merged[key] = value
+			}
+		}
+	}
+	return merged
+}
+
+// mergeArray merges the arrays of the same length by indexes, or the arrays both sides appended items to.
+func mergeArray(pointer string, base []interface{}, ours []interface{}, theirs []interface{}, conflicts *[]string) ([]interface{}, bool) {
+	if len(base) == len(ours) && len(base) == len(theirs) {
+		merged := make([]interface{}, len(base))
+		for i := range base {
+			merged[i] = mergeValue(pointer+"/"+strconv.Itoa(i), base[i], ours[i], theirs[i], conflicts)
+		}
+		return merged, true
+	}
+
+	if len(ours) < len(base) || len(theirs) < len(base) {
+		return nil, false
+	}
+	merged := make([]interface{}, len(base), len(ours)+len(theirs)-len(base))
+	for i := range base {
+		merged[i] = mergeValue(pointer+"/"+strconv.Itoa(i), base[i], ours[i], theirs[i], conflicts)
+	}
+	merged = append(merged, theirs[len(base):]...)
+	return append(merged, ours[len(base):]...), true
+}
+
+func field(doc map[string]interface{}, key string) interface{} {
+	value, ok := doc[key]
+	if !ok {
+		return absent{}
+	}
+	return value
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeJSON(t *testing.T) {
+	base := []byte(`{"title": "Notes", "tags": ["a"], "owner": {"name": "Ada", "age": 36}, "draft": true, "size": 12345678901234567890}`)
+	ours := []byte(`{"title": "My notes", "tags": ["a", "b"], "owner": {"name": "Ada", "age": 37}, "size": 12345678901234567890}`)
+	theirs := []byte(`{"title": "Our notes", "tags": ["a", "c"], "owner": {"name": "Ada L.", "age": 36}, "draft": true, "pinned": true, "size": 12345678901234567890}`)
+
+	merged, conflicts, err := MergeJSON(base, ours, theirs)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"title": "My notes",
+		"tags": ["a", "c", "b"],
+		"owner": {"name": "Ada L.", "age": 37},
+		"pinned": true,
+		"size": 12345678901234567890
+	}`, string(merged))
+	require.Equal(t, []string{"/title"}, conflicts)
+
+	// a field removed on one side and changed on the other conflicts
+	merged, conflicts, err = MergeJSON([]byte(`{"a/b": 1, "c": [1, 2]}`), []byte(`{"c": [1]}`), []byte(`{"a/b": 2, "c": [1, 2]}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"c": [1]}`, string(merged))
+	require.Equal(t, []string{"/a~1b"}, conflicts)
+
+	_, _, err = MergeJSON([]byte(`{}`), []byte(`not json`), []byte(`{}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// ExtendInfoContentType is the key of the mime type in the extend info of the file models.
+const ExtendInfoContentType = "contentType"
+
+// DetectContentType returns the mime type of the file name by its extension, head is sniffed if the extension
+// is unknown. head is the beginning of the content, http.DetectContentType reads at most 512 bytes. Empty is
+// returned if neither tells the type.
+func DetectContentType(name string, head []byte) string {
+	if contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(name))); contentType != "" {
+		return contentType
+	}
+	if len(head) == 0 {
+		return ""
+	}
+	return http.DetectContentType(head)
+}
+
+// SetContentType returns the extend info with the mime type added. The extend info is free text, so it is
+// returned unchanged if it is neither empty nor a json object.
+func SetContentType(extendInfo string, contentType string) string {
+	if contentType == "" {
+		return extendInfo
+	}
+
+	info := make(map[string]interface{})
+	if strings.TrimSpace(extendInfo) != "" {
+		if err := json.Unmarshal([]byte(extendInfo), &info); err != nil || info == nil {
+			return extendInfo
+		}
+	}
+	info[ExtendInfoContentType] = contentType
+	data, err := json.Marshal(info)
+	if err != nil {
+		return extendInfo
+	}
+	return string(data)
+}
+
+// ContentType returns the mime type in the extend info, empty if there is none.
+func ContentType(extendInfo string) string {
+	var info map[string]interface{}
+	if err := json.Unmarshal([]byte(extendInfo), &info); err != nil {
+		return ""
+	}
+	contentType, _ := info[ExtendInfoContentType].(string)
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return ""
+	}
+	return contentType
+}
@@ -0,0 +1,183 @@
+// Package redact removes the secrets from the log lines: mnemonics, private keys, JWS signatures and tokens. Some
+// handlers log whole requests and responses, the secrets they carry must not end up in the log files.
+package redact
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cosmos/go-bip39"
+	logging "github.com/ipfs/go-log/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const Redacted = "[REDACTED]"
+
+// the fewest words of a BIP39 mnemonic
+const mnemonicWords = 12
+
+var (
+	pemKey = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+	// a secret field of a struct printed with %+v, a json object or key=value, the separator is not followed by
+	// a space so the messages like "invalid signature: ..." are kept
+	secretField = regexp.MustCompile(`(?i)("?\b(?:signature|priv_?key|private_?key|secret|mnemonic|passphrase|password|token)"?[:=])("[^"]*"|\[[^\]]*\]|[^\s"',})\]]+)`)
+	bearer      = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	// a compact JWS or a JWT, the header and the payload are kept
+	compactJws = regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*\.)[A-Za-z0-9_-]+`)
+	// a JwsSignature printed with %v, {protected signature}
+	jwsStruct = regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\s+)[A-Za-z0-9_-]{40,}`)
+	word      = regexp.MustCompile(`[a-z]+`)
+)
+
+// String returns s with the secrets replaced by Redacted.
+func String(s string) string {
+	s = pemKey.ReplaceAllString(s, Redacted)
+	s = secretField.ReplaceAllStringFunc(s, func(field string) string {
+		m := secretField.FindStringSubmatch(field)
+		if strings.HasPrefix(m[2], `"`) {
+			return m[1] + `"` + Redacted + `"`
+		}
+		return m[1] + Redacted
+	})
+	s = bearer.ReplaceAllString(s, "${1}"+Redacted)
+	s = compactJws.ReplaceAllString(s, "${1}"+Redacted)
+	s = jwsStruct.ReplaceAllString(s, "${1}"+Redacted)
+	return redactMnemonics(s)
+}
+
+// redactMnemonics replaces the runs of at least 12 BIP39 words separated by spaces.
+func redactMnemonics(s string) string {
+	words := word.FindAllStringIndex(s, -1)
+	if len(words) < mnemonicWords {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(words); {
+		j := i
+		for j < len(words) && isMnemonicWord(s[words[j][0]:words[j][1]]) &&
+			(j == i || strings.TrimSpace(s[words[j-1][1]:words[j][0]]) == "") {
+			j++
+		}
+		if j-i >= mnemonicWords {
+			b.WriteString(s[last:words[i][0]])
+			b.WriteString(Redacted)
+			last = words[j-1][1]
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func isMnemonicWord(w string) bool {
+	_, ok := bip39.ReverseWordMap[w]
+	return ok
+}
+
+// core redacts the messages and the string fields of the entries before they are written by the wrapped core.
+type core struct {
+	zapcore.Core
+}
+
+// NewCore wraps c so the entries it writes are redacted.
+func NewCore(c zapcore.Core) zapcore.Core {
+	return &core{Core: c}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = String(ent.Message)
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = String(f.String)
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok {
+				f = zap.String(f.Key, String(err.Error()))
+			}
+		case zapcore.StringerType:
+			if s, ok := f.Interface.(fmt.Stringer); ok {
+				f = zap.String(f.Key, String(s.String()))
+			}
+		case zapcore.ReflectType:
+			f = zap.String(f.Key, String(fmt.Sprintf("%+v", f.Interface)))
+		}
+		redacted[i] = f
+	}
+	return redacted
+}
+
+// Install replaces the primary core of the loggers by one writing to the same outputs with the redaction.
+func Install() error {
+	cfg := logging.GetConfig()
+
+	var outputs []string
+	if cfg.Stderr {
+		outputs = append(outputs, "stderr")
+	}
+	if cfg.Stdout {
+		outputs = append(outputs, "stdout")
+	}
+	if cfg.File != "" {
+		path, err := filepath.Abs(cfg.File)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, path)
+	}
+	if cfg.URL != "" {
+		outputs = append(outputs, cfg.URL)
+	}
+	ws, _, err := zap.Open(outputs...)
+	if err != nil {
+		return err
+	}
+
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case logging.PlaintextOutput:
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encCfg)
+	case logging.JSONOutput:
+		encoder = zapcore.NewJSONEncoder(encCfg)
+	default:
+		encCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encCfg)
+	}
+
+	// the levels of the subsystems are set on the loggers, the primary core logs everything
+	var c zapcore.Core = zapcore.NewCore(encoder, ws, zapcore.DebugLevel)
+	for k, v := range cfg.Labels {
+		c = c.With([]zapcore.Field{zap.String(k, v)})
+	}
+	logging.SetPrimaryCore(NewCore(c))
+	return nil
+}
@@ -3,23 +3,70 @@ package utils
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sao-node/types"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
 )
 
 const (
-	ORDER_INDEX_KEY   = "order-index"
-	ORDER_KEY         = "order-%s"
-	SHARD_INDEX_KEY   = "shard-index"
-	SHARD_KEY         = "order-%d-shard-%v"
-	MIGRATE_INDEX_KEY = "migrate-index"
-	MIGRATE_KEY       = "migrate-dataid-%s-from-%s"
+	ORDER_PREFIX    = "orders"
+	ORDER_KEY       = ORDER_PREFIX + "/%s"
+	SHARD_PREFIX    = "shards"
+	SHARD_KEY       = SHARD_PREFIX + "/%020d/%v"
+	MIGRATE_PREFIX  = "migrates"
+	MIGRATE_KEY     = MIGRATE_PREFIX + "/%s/%s"
+	COMPLETE_PREFIX = "complete"
+	COMPLETE_KEY    = COMPLETE_PREFIX + "/%d-%s"
+	AUDIT_PREFIX    = "audits"
+	AUDIT_KEY       = AUDIT_PREFIX + "/%s"
+
+	// the keys before the records were stored under prefixes, the records were listed by an index rewritten on
+	// every new record. They are only read by MigrateStateKeys.
+	LEGACY_ORDER_INDEX_KEY   = "order-index"
+	LEGACY_ORDER_KEY         = "order-%s"
+	LEGACY_SHARD_INDEX_KEY   = "shard-index"
+	LEGACY_SHARD_KEY         = "order-%d-shard-%v"
+	LEGACY_MIGRATE_INDEX_KEY = "migrate-index"
+	LEGACY_MIGRATE_KEY       = "migrate-dataid-%s-from-%s"
 )
 
+// the records put in a batch before it is committed
+const batchSize = 1000
+
+// ErrStopIteration is returned by the callbacks of the ForEach functions to stop the iteration early, it is
+// not returned to the caller.
+var ErrStopIteration = errors.New("stop iteration")
+
+/**
+ * Stream the values of the records under the prefix to fn.
+ */
+func forEach(ctx context.Context, ds datastore.Batching, prefix string, fn func(key string, value []byte) error) error {
+	results, err := ds.Query(ctx, query.Query{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		err = fn(r.Key, r.Value)
+		if err == ErrStopIteration {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // -----
 // order
 // -----
@@ -35,47 +82,23 @@ func orderDatastoreKey(id string) datastore.Key {
  * Save order state in datastore.
  */
 func SaveOrder(ctx context.Context, ds datastore.Batching, order types.OrderInfo) error {
-	key := orderDatastoreKey(order.DataId)
-
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return err
-	}
-
 	buf := new(bytes.Buffer)
-	err = order.MarshalCBOR(buf)
-	if err != nil {
-		return err
-	}
-	err = ds.Put(ctx, key, buf.Bytes())
+	err := order.MarshalCBOR(buf)
 	if err != nil {
 		return err
 	}
-
-	if !exists {
-		err = UpdateOrderIndex(ctx, ds, order.DataId)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return ds.Put(ctx, orderDatastoreKey(order.DataId), buf.Bytes())
 }
 
 /**
- * Get order state from datastore.
+ * Get order state from datastore, an empty order is returned if not found.
  */
 func GetOrder(ctx context.Context, ds datastore.Batching, id string) (types.OrderInfo, error) {
-	key := orderDatastoreKey(id)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return types.OrderInfo{}, err
-	}
-	if !exists {
-		return types.OrderInfo{}, nil
-	}
-
-	bs, err := ds.Get(ctx, key)
+	bs, err := ds.Get(ctx, orderDatastoreKey(id))
 	if err != nil {
+		if err == datastore.ErrNotFound {
+			return types.OrderInfo{}, nil
+		}
 		return types.OrderInfo{}, err
 	}
 
@@ -88,60 +111,16 @@ func GetOrder(ctx context.Context, ds datastore.Batching, id string) (types.Orde
 }
 
 /**
- * update order index.
+ * Stream all orders in datastore to fn.
  */
-func UpdateOrderIndex(ctx context.Context, ds datastore.Batching, id string) error {
-	key := datastore.NewKey(ORDER_INDEX_KEY)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return err
-	}
-	var index types.OrderIndex
-	if exists {
-		data, err := ds.Get(ctx, key)
-		if err != nil {
-			return err
+func ForEachOrder(ctx context.Context, ds datastore.Batching, fn func(types.OrderInfo) error) error {
+	return forEach(ctx, ds, "/"+ORDER_PREFIX+"/", func(key string, value []byte) error {
+		var orderInfo types.OrderInfo
+		if err := orderInfo.UnmarshalCBOR(bytes.NewReader(value)); err != nil {
+			return types.Wrapf(types.ErrUnMarshalFailed, "order %s: %v", key, err)
 		}
-		err = index.UnmarshalCBOR(bytes.NewReader(data))
-		if err != nil {
-			return err
-		}
-	}
-	index.Alls = append(index.Alls, types.OrderKey{DataId: id})
-
-	buf := new(bytes.Buffer)
-	err = index.MarshalCBOR(buf)
-	if err != nil {
-		return err
-	}
-	err = ds.Put(ctx, key, buf.Bytes())
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-/**
- * Get order index.
- */
-func GetOrderIndex(ctx context.Context, ds datastore.Batching) (types.OrderIndex, error) {
-	key := datastore.NewKey(ORDER_INDEX_KEY)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return types.OrderIndex{}, err
-	}
-	if !exists {
-		return types.OrderIndex{}, nil
-	}
-
-	data, err := ds.Get(ctx, key)
-	if err != nil {
-		return types.OrderIndex{}, err
-	}
-
-	var index types.OrderIndex
-	err = index.UnmarshalCBOR(bytes.NewReader(data))
-	return index, err
+		return fn(orderInfo)
+	})
 }
 
 // -----
@@ -152,42 +131,20 @@ func migrateDatastoreKey(dataId string, from string) datastore.Key {
 }
 
 func SaveMigrate(ctx context.Context, ds datastore.Batching, migrate types.MigrateInfo) error {
-	key := migrateDatastoreKey(migrate.DataId, migrate.FromProvider)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return err
-	}
-
 	buf := new(bytes.Buffer)
-	err = migrate.MarshalCBOR(buf)
-	if err != nil {
-		return err
-	}
-	err = ds.Put(ctx, key, buf.Bytes())
+	err := migrate.MarshalCBOR(buf)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		err = UpdateMigrateIndex(ctx, ds, migrate.DataId, migrate.FromProvider)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return ds.Put(ctx, migrateDatastoreKey(migrate.DataId, migrate.FromProvider), buf.Bytes())
 }
 
 func GetMigrate(ctx context.Context, ds datastore.Batching, dataId string, from string) (types.MigrateInfo, error) {
-	key := migrateDatastoreKey(dataId, from)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return types.MigrateInfo{}, err
-	}
-	if !exists {
-		return types.MigrateInfo{}, nil
-	}
-
-	bs, err := ds.Get(ctx, key)
+	bs, err := ds.Get(ctx, migrateDatastoreKey(dataId, from))
 	if err != nil {
+		if err == datastore.ErrNotFound {
+			return types.MigrateInfo{}, nil
+		}
 		return types.MigrateInfo{}, err
 	}
 
@@ -199,71 +156,76 @@ func GetMigrate(ctx context.Context, ds datastore.Batching, dataId string, from
 	return migrateInfo, nil
 }
 
-func UpdateMigrateIndex(
-	ctx context.Context,
-	ds datastore.Batching,
-	dataId string,
-	from string,
-) error {
-	key := datastore.NewKey(MIGRATE_INDEX_KEY)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return err
-	}
-
-	var index types.MigrateIndex
-	if exists {
-		data, err := ds.Get(ctx, key)
-		if err != nil {
-			return err
-		}
-		err = index.UnmarshalCBOR(bytes.NewReader(data))
-		if err != nil {
-			return err
+/**
+ * Stream all migrate jobs in datastore to fn.
+ */
+func ForEachMigrate(ctx context.Context, ds datastore.Batching, fn func(types.MigrateInfo) error) error {
+	return forEach(ctx, ds, "/"+MIGRATE_PREFIX+"/", func(key string, value []byte) error {
+		var migrateInfo types.MigrateInfo
+		if err := migrateInfo.UnmarshalCBOR(bytes.NewReader(value)); err != nil {
+			return types.Wrapf(types.ErrUnMarshalFailed, "migrate %s: %v", key, err)
 		}
-	}
-	index.All = append(index.All, types.MigrateKey{
-		DataId:       dataId,
-		FromProvider: from,
+		return fn(migrateInfo)
 	})
+}
 
+// -----
+// audit
+// -----
+func auditDatastoreKey(dataId string) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf(AUDIT_KEY, dataId))
+}
+
+/**
+ * Save the last audit of an order in datastore.
+ */
+func SaveAudit(ctx context.Context, ds datastore.Batching, audit types.AuditInfo) error {
 	buf := new(bytes.Buffer)
-	err = index.MarshalCBOR(buf)
-	if err != nil {
-		return err
-	}
-	err = ds.Put(ctx, key, buf.Bytes())
+	err := audit.MarshalCBOR(buf)
 	if err != nil {
 		return err
 	}
-	return nil
+	return ds.Put(ctx, auditDatastoreKey(audit.DataId), buf.Bytes())
 }
 
-func GetMigrateIndex(ctx context.Context, ds datastore.Batching) (types.MigrateIndex, error) {
-	key := datastore.NewKey(MIGRATE_INDEX_KEY)
-	exists, err := ds.Has(ctx, key)
+/**
+ * Get the last audit of an order from datastore, an empty audit is returned if the order is not audited yet.
+ */
+func GetAudit(ctx context.Context, ds datastore.Batching, dataId string) (types.AuditInfo, error) {
+	bs, err := ds.Get(ctx, auditDatastoreKey(dataId))
 	if err != nil {
-		return types.MigrateIndex{}, err
-	}
-	if !exists {
-		return types.MigrateIndex{}, nil
+		if err == datastore.ErrNotFound {
+			return types.AuditInfo{}, nil
+		}
+		return types.AuditInfo{}, err
 	}
 
-	data, err := ds.Get(ctx, key)
+	var audit types.AuditInfo
+	err = audit.UnmarshalCBOR(bytes.NewReader(bs))
 	if err != nil {
-		return types.MigrateIndex{}, err
+		return types.AuditInfo{}, err
 	}
+	return audit, nil
+}
 
-	var index types.MigrateIndex
-	err = index.UnmarshalCBOR(bytes.NewReader(data))
-	return index, err
+/**
+ * Stream all audits in datastore to fn.
+ */
+func ForEachAudit(ctx context.Context, ds datastore.Batching, fn func(types.AuditInfo) error) error {
+	return forEach(ctx, ds, "/"+AUDIT_PREFIX+"/", func(key string, value []byte) error {
+		var audit types.AuditInfo
+		if err := audit.UnmarshalCBOR(bytes.NewReader(value)); err != nil {
+			return types.Wrapf(types.ErrUnMarshalFailed, "audit %s: %v", key, err)
+		}
+		return fn(audit)
+	})
 }
 
 // -----
 // shard
 // -----
 /**
- * get shard key in datastore.
+ * get shard key in datastore, the order id is padded so the backends sorting the keys keep the shards in the order of their orders.
  */
 func orderShardDatastoreKey(orderId uint64, cid cid.Cid) datastore.Key {
 	return datastore.NewKey(fmt.Sprintf(SHARD_KEY, orderId, cid))
@@ -273,46 +235,45 @@ func orderShardDatastoreKey(orderId uint64, cid cid.Cid) datastore.Key {
  * save order shard state.
  */
 func SaveShard(ctx context.Context, ds datastore.Batching, shard types.ShardInfo) error {
-	key := orderShardDatastoreKey(shard.OrderId, shard.Cid)
-
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return err
-	}
-
 	buf := new(bytes.Buffer)
-	err = shard.MarshalCBOR(buf)
+	err := shard.MarshalCBOR(buf)
 	if err != nil {
 		return err
 	}
-	err = ds.Put(ctx, key, buf.Bytes())
+	return ds.Put(ctx, orderShardDatastoreKey(shard.OrderId, shard.Cid), buf.Bytes())
+}
+
+/**
+ * save the shards in one batch.
+ */
+func SaveShards(ctx context.Context, ds datastore.Batching, shards []types.ShardInfo) error {
+	batch, err := ds.Batch(ctx)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		err = UpdateShardIndex(ctx, ds, shard.OrderId, shard.Cid)
+	for _, shard := range shards {
+		buf := new(bytes.Buffer)
+		err = shard.MarshalCBOR(buf)
+		if err != nil {
+			return err
+		}
+		err = batch.Put(ctx, orderShardDatastoreKey(shard.OrderId, shard.Cid), buf.Bytes())
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return batch.Commit(ctx)
 }
 
 /**
- * Get shard state from datastore.
+ * Get shard state from datastore, an empty shard is returned if not found.
  */
 func GetShard(ctx context.Context, ds datastore.Batching, orderId uint64, cid cid.Cid) (types.ShardInfo, error) {
-	key := orderShardDatastoreKey(orderId, cid)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return types.ShardInfo{}, err
-	}
-	if !exists {
-		return types.ShardInfo{}, nil
-	}
-
-	bs, err := ds.Get(ctx, key)
+	bs, err := ds.Get(ctx, orderShardDatastoreKey(orderId, cid))
 	if err != nil {
+		if err == datastore.ErrNotFound {
+			return types.ShardInfo{}, nil
+		}
 		return types.ShardInfo{}, err
 	}
 
@@ -325,69 +286,164 @@ func GetShard(ctx context.Context, ds datastore.Batching, orderId uint64, cid ci
 }
 
 /**
- * update shard index
+ * Stream all shards in datastore to fn.
  */
-func UpdateShardIndex(
-	ctx context.Context,
-	ds datastore.Batching,
-	orderId uint64,
-	cid cid.Cid,
-) error {
-	key := datastore.NewKey(SHARD_INDEX_KEY)
-	exists, err := ds.Has(ctx, key)
+func ForEachShard(ctx context.Context, ds datastore.Batching, fn func(types.ShardInfo) error) error {
+	return forEachShard(ctx, ds, "/"+SHARD_PREFIX+"/", fn)
+}
+
+/**
+ * Stream the shards of the order to fn.
+ */
+func ForEachOrderShard(ctx context.Context, ds datastore.Batching, orderId uint64, fn func(types.ShardInfo) error) error {
+	return forEachShard(ctx, ds, fmt.Sprintf("/%s/%020d/", SHARD_PREFIX, orderId), fn)
+}
+
+func forEachShard(ctx context.Context, ds datastore.Batching, prefix string, fn func(types.ShardInfo) error) error {
+	return forEach(ctx, ds, prefix, func(key string, value []byte) error {
+		var shardInfo types.ShardInfo
+		if err := shardInfo.UnmarshalCBOR(bytes.NewReader(value)); err != nil {
+			return types.Wrapf(types.ErrUnMarshalFailed, "shard %s: %v", key, err)
+		}
+		return fn(shardInfo)
+	})
+}
+
+// ---------
+// migration
+// ---------
+
+/**
+ * Move the orders, shards and migrate jobs listed by the legacy index records to the prefixed keys, and remove
+ * the index records. The records are moved in batches, a datastore partly migrated is migrated again from the
+ * indexes left. It returns the number of records moved.
+ */
+func MigrateStateKeys(ctx context.Context, ds datastore.Batching) (int, error) {
+	moved := 0
+
+	var orders types.OrderIndex
+	exists, err := getLegacyIndex(ctx, ds, LEGACY_ORDER_INDEX_KEY, &orders)
 	if err != nil {
-		return err
+		return moved, err
 	}
-
-	var index types.ShardIndex
 	if exists {
-		data, err := ds.Get(ctx, key)
-		if err != nil {
-			return err
+		keys := make([][2]datastore.Key, len(orders.Alls))
+		for i, k := range orders.Alls {
+			keys[i] = [2]datastore.Key{datastore.NewKey(fmt.Sprintf(LEGACY_ORDER_KEY, k.DataId)), orderDatastoreKey(k.DataId)}
 		}
-		err = index.UnmarshalCBOR(bytes.NewReader(data))
+		n, err := moveRecords(ctx, ds, datastore.NewKey(LEGACY_ORDER_INDEX_KEY), keys)
+		moved += n
 		if err != nil {
-			return err
+			return moved, err
 		}
 	}
-	index.All = append(index.All, types.ShardKey{
-		OrderId: orderId,
-		Cid:     cid,
-	})
 
-	buf := new(bytes.Buffer)
-	err = index.MarshalCBOR(buf)
+	var shards types.ShardIndex
+	exists, err = getLegacyIndex(ctx, ds, LEGACY_SHARD_INDEX_KEY, &shards)
 	if err != nil {
-		return err
+		return moved, err
 	}
-	err = ds.Put(ctx, key, buf.Bytes())
+	if exists {
+		keys := make([][2]datastore.Key, len(shards.All))
+		for i, k := range shards.All {
+			keys[i] = [2]datastore.Key{
+				datastore.NewKey(fmt.Sprintf(LEGACY_SHARD_KEY, k.OrderId, k.Cid)),
+				orderShardDatastoreKey(k.OrderId, k.Cid),
+			}
+		}
+		n, err := moveRecords(ctx, ds, datastore.NewKey(LEGACY_SHARD_INDEX_KEY), keys)
+		moved += n
+		if err != nil {
+			return moved, err
+		}
+	}
+
+	var migrates types.MigrateIndex
+	exists, err = getLegacyIndex(ctx, ds, LEGACY_MIGRATE_INDEX_KEY, &migrates)
 	if err != nil {
-		return err
+		return moved, err
 	}
-	return nil
+	if exists {
+		keys := make([][2]datastore.Key, len(migrates.All))
+		for i, k := range migrates.All {
+			keys[i] = [2]datastore.Key{
+				datastore.NewKey(fmt.Sprintf(LEGACY_MIGRATE_KEY, k.DataId, k.FromProvider)),
+				migrateDatastoreKey(k.DataId, k.FromProvider),
+			}
+		}
+		n, err := moveRecords(ctx, ds, datastore.NewKey(LEGACY_MIGRATE_INDEX_KEY), keys)
+		moved += n
+		if err != nil {
+			return moved, err
+		}
+	}
+
+	return moved, nil
 }
 
-/**
- * Get shard index from data store.
- */
-func GetShardIndex(ctx context.Context, ds datastore.Batching) (types.ShardIndex, error) {
-	key := datastore.NewKey(SHARD_INDEX_KEY)
-	exists, err := ds.Has(ctx, key)
-	if err != nil {
-		return types.ShardIndex{}, err
+func getLegacyIndex(ctx context.Context, ds datastore.Batching, key string, index interface {
+	UnmarshalCBOR(io.Reader) error
+}) (bool, error) {
+	data, err := ds.Get(ctx, datastore.NewKey(key))
+	if err == datastore.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
 	}
-	if !exists {
-		return types.ShardIndex{}, nil
+	if err := index.UnmarshalCBOR(bytes.NewReader(data)); err != nil {
+		return false, types.Wrapf(types.ErrUnMarshalFailed, "%s: %v", key, err)
 	}
+	return true, nil
+}
 
-	data, err := ds.Get(ctx, key)
+// moveRecords moves the records from the first key of each pair to the second one, then deletes the index. The
+// legacy indexes may list a record more than once, or a record already moved by an interrupted migration.
+func moveRecords(ctx context.Context, ds datastore.Batching, index datastore.Key, keys [][2]datastore.Key) (int, error) {
+	moved := 0
+	batch, err := ds.Batch(ctx)
 	if err != nil {
-		return types.ShardIndex{}, err
+		return moved, err
 	}
+	pending := 0
+	seen := make(map[datastore.Key]bool)
+	for _, k := range keys {
+		if seen[k[0]] {
+			continue
+		}
+		seen[k[0]] = true
 
-	var index types.ShardIndex
-	err = index.UnmarshalCBOR(bytes.NewReader(data))
-	return index, err
+		value, err := ds.Get(ctx, k[0])
+		if err == datastore.ErrNotFound {
+			continue
+		} else if err != nil {
+			return moved, err
+		}
+		if err := batch.Put(ctx, k[1], value); err != nil {
+			return moved, err
+		}
+		if err := batch.Delete(ctx, k[0]); err != nil {
+			return moved, err
+		}
+		moved++
+
+		pending++
+		if pending >= batchSize {
+			if err := batch.Commit(ctx); err != nil {
+				return moved - pending, err
+			}
+			if batch, err = ds.Batch(ctx); err != nil {
+				return moved, err
+			}
+			pending = 0
+		}
+	}
+	if err := batch.Delete(ctx, index); err != nil {
+		return moved - pending, err
+	}
+	if err := batch.Commit(ctx); err != nil {
+		return moved - pending, err
+	}
+	return moved, nil
 }
 
 const RetryIntervalCoeff time.Duration = 3
@@ -402,3 +458,75 @@ func GetRetryAt(tries uint64) int64 {
 	}
 	return time.Now().Add(retryInterval).Unix()
 }
+
+// --------
+// complete
+// --------
+
+/**
+ * get complete record key in datastore.
+ */
+func completeDatastoreKey(orderId uint64, txHash string) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf(COMPLETE_KEY, orderId, txHash))
+}
+
+/**
+ * Save handled shard complete request in datastore.
+ */
+func SaveCompleteRecord(ctx context.Context, ds datastore.Batching, record types.CompleteRecord) error {
+	buf := new(bytes.Buffer)
+	err := record.MarshalCBOR(buf)
+	if err != nil {
+		return err
+	}
+	return ds.Put(ctx, completeDatastoreKey(record.OrderId, record.TxHash), buf.Bytes())
+}
+
+/**
+ * Get handled shard complete request from datastore, an empty record is returned if not found.
+ */
+func GetCompleteRecord(ctx context.Context, ds datastore.Batching, orderId uint64, txHash string) (types.CompleteRecord, error) {
+	bs, err := ds.Get(ctx, completeDatastoreKey(orderId, txHash))
+	if err != nil {
+		if err == datastore.ErrNotFound {
+			return types.CompleteRecord{}, nil
+		}
+		return types.CompleteRecord{}, err
+	}
+
+	var record types.CompleteRecord
+	err = record.UnmarshalCBOR(bytes.NewReader(bs))
+	if err != nil {
+		return types.CompleteRecord{}, err
+	}
+	return record, nil
+}
+
+/**
+ * Remove complete records handled before the given unix time.
+ */
+func PruneCompleteRecords(ctx context.Context, ds datastore.Batching, before int64) (int, error) {
+	results, err := ds.Query(ctx, query.Query{Prefix: "/" + COMPLETE_PREFIX})
+	if err != nil {
+		return 0, err
+	}
+	defer results.Close()
+
+	pruned := 0
+	for r := range results.Next() {
+		if r.Error != nil {
+			return pruned, r.Error
+		}
+
+		var record types.CompleteRecord
+		err = record.UnmarshalCBOR(bytes.NewReader(r.Value))
+		if err != nil || record.HandledAt < before {
+			err = ds.Delete(ctx, datastore.NewKey(r.Key))
+			if err != nil {
+				return pruned, err
+			}
+			pruned++
+		}
+	}
+	return pruned, nil
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"sao-node/types"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardPrefixQueries(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	c1 := cid.MustParse("bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	c2 := cid.MustParse("bafkreib3fzl5bnzhgvl7hdcxvyxetnwr5cbl3uxqrflgeoz6sf5pndxzrm")
+	require.NoError(t, SaveShards(ctx, ds, []types.ShardInfo{
+		{OrderId: 10, Cid: c1, State: types.ShardStateComplete},
+		{OrderId: 10, Cid: c2},
+		{OrderId: 2, Cid: c1},
+	}))
+	require.NoError(t, SaveOrder(ctx, ds, types.OrderInfo{DataId: "data1", Cid: c1}))
+
+	var orderIds []uint64
+	require.NoError(t, ForEachShard(ctx, ds, func(shard types.ShardInfo) error {
+		orderIds = append(orderIds, shard.OrderId)
+		return nil
+	}))
+	require.ElementsMatch(t, []uint64{2, 10, 10}, orderIds)
+
+	var shards []types.ShardInfo
+	require.NoError(t, ForEachOrderShard(ctx, ds, 10, func(shard types.ShardInfo) error {
+		shards = append(shards, shard)
+		return ErrStopIteration
+	}))
+	require.Len(t, shards, 1)
+	require.Equal(t, uint64(10), shards[0].OrderId)
+
+	shard, err := GetShard(ctx, ds, 10, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardStateComplete, shard.State)
+	shard, err = GetShard(ctx, ds, 3, c1)
+	require.NoError(t, err)
+	require.Equal(t, types.ShardInfo{}, shard)
+}
+
+func TestMigrateStateKeys(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	c1 := cid.MustParse("bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	orderIndex := types.OrderIndex{}
+	for i := 0; i < 3; i++ {
+		dataId := fmt.Sprintf("data%d", i)
+		putCBOR(t, ds, fmt.Sprintf(LEGACY_ORDER_KEY, dataId), &types.OrderInfo{DataId: dataId, Cid: c1})
+		orderIndex.Alls = append(orderIndex.Alls, types.OrderKey{DataId: dataId})
+	}
+	// listed twice
+	orderIndex.Alls = append(orderIndex.Alls, types.OrderKey{DataId: "data0"})
+	putCBOR(t, ds, LEGACY_ORDER_INDEX_KEY, &orderIndex)
+
+	putCBOR(t, ds, fmt.Sprintf(LEGACY_SHARD_KEY, 7, c1), &types.ShardInfo{OrderId: 7, Cid: c1})
+	putCBOR(t, ds, LEGACY_SHARD_INDEX_KEY, &types.ShardIndex{All: []types.ShardKey{{OrderId: 7, Cid: c1}}})
+
+	putCBOR(t, ds, fmt.Sprintf(LEGACY_MIGRATE_KEY, "data0", "from"), &types.MigrateInfo{DataId: "data0", FromProvider: "from"})
+	putCBOR(t, ds, LEGACY_MIGRATE_INDEX_KEY, &types.MigrateIndex{All: []types.MigrateKey{{DataId: "data0", FromProvider: "from"}}})
+
+	moved, err := MigrateStateKeys(ctx, ds)
+	require.NoError(t, err)
+	require.Equal(t, 5, moved)
+
+	var dataIds []string
+	require.NoError(t, ForEachOrder(ctx, ds, func(order types.OrderInfo) error {
+		dataIds = append(dataIds, order.DataId)
+		return nil
+	}))
+	require.ElementsMatch(t, []string{"data0", "data1", "data2"}, dataIds)
+
+	shard, err := GetShard(ctx, ds, 7, c1)
+	require.NoError(t, err)
+	require.Equal(t, c1, shard.Cid)
+	migrate, err := GetMigrate(ctx, ds, "data0", "from")
+	require.NoError(t, err)
+	require.Equal(t, "from", migrate.FromProvider)
+
+	for _, key := range []string{
+		LEGACY_ORDER_INDEX_KEY, LEGACY_SHARD_INDEX_KEY, LEGACY_MIGRATE_INDEX_KEY,
+		fmt.Sprintf(LEGACY_ORDER_KEY, "data1"), fmt.Sprintf(LEGACY_SHARD_KEY, 7, c1),
+	} {
+		exists, err := ds.Has(ctx, datastore.NewKey(key))
+		require.NoError(t, err)
+		require.False(t, exists, key)
+	}
+
+	// nothing left to migrate
+	moved, err = MigrateStateKeys(ctx, ds)
+	require.NoError(t, err)
+	require.Zero(t, moved)
+}
+
+func putCBOR(t *testing.T, ds datastore.Batching, key string, v interface{ MarshalCBOR(io.Writer) error }) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, v.MarshalCBOR(buf))
+	require.NoError(t, ds.Put(context.Background(), datastore.NewKey(key), buf.Bytes()))
+}
+
+func TestPruneCompleteRecords(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	require.NoError(t, SaveCompleteRecord(ctx, ds, types.CompleteRecord{OrderId: 1, TxHash: "old", HandledAt: 100}))
+	require.NoError(t, SaveCompleteRecord(ctx, ds, types.CompleteRecord{OrderId: 1, TxHash: "recent", HandledAt: 200}))
+	require.NoError(t, SaveCompleteRecord(ctx, ds, types.CompleteRecord{OrderId: 2, TxHash: "at", HandledAt: 150}))
+	// a record failing to decode is dropped too
+	require.NoError(t, ds.Put(ctx, completeDatastoreKey(3, "corrupt"), []byte("not cbor")))
+	contentCid, err := CalculateCid([]byte("content"))
+	require.NoError(t, err)
+	require.NoError(t, SaveOrder(ctx, ds, types.OrderInfo{DataId: "data", OrderId: 1, Cid: contentCid}))
+
+	pruned, err := PruneCompleteRecords(ctx, ds, 150)
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+
+	for _, r := range []struct {
+		orderId uint64
+		txHash  string
+		kept    bool
+	}{
+		{1, "old", false},
+		{1, "recent", true},
+		{2, "at", true},
+		{3, "corrupt", false},
+	} {
+		exists, err := ds.Has(ctx, completeDatastoreKey(r.orderId, r.txHash))
+		require.NoError(t, err)
+		require.Equal(t, r.kept, exists, r.txHash)
+	}
+	order, err := GetOrder(ctx, ds, "data")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), order.OrderId)
+
+	pruned, err = PruneCompleteRecords(ctx, ds, 150)
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+}